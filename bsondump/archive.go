@@ -0,0 +1,191 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package bsondump
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/archive"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// errNamespaceRead stops parsing an archive once the namespace to dump was
+// read to its EOF.
+var errNamespaceRead = errors.New("namespace read")
+
+// validateArchive checks the options that read a namespace of an archive.
+func (oo *OutputOptions) validateArchive() error {
+	if oo.Archive == "" {
+		if oo.Namespace != "" || oo.Gzip {
+			return fmt.Errorf("--namespace and --gzip can only be used with --archive")
+		}
+		return nil
+	}
+	switch {
+	case oo.Namespace == "":
+		return fmt.Errorf("--archive requires --namespace")
+	case !strings.Contains(oo.Namespace, "."):
+		return fmt.Errorf("--namespace must be <database>.<collection>, got %v", oo.Namespace)
+	case oo.BSONFileName != "":
+		return fmt.Errorf("cannot specify both --archive and a BSON file")
+	case oo.StartOffset > 0 || oo.MaxBytes > 0:
+		return fmt.Errorf(
+			"--startOffset and --maxBytes cannot be used with --archive, " +
+				"whose offsets are not those of the namespace's documents",
+		)
+	}
+	return nil
+}
+
+// getArchiveReader opens the archive of --archive, or stdin if it is "-",
+// and returns a reader of the BSON documents of --namespace in it.
+func (oo *OutputOptions) getArchiveReader() (io.ReadCloser, error) {
+	var in io.ReadCloser = ReadNopCloser{os.Stdin}
+	if oo.Archive != "-" {
+		file, err := os.Open(util.ToUniversalPath(oo.Archive))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open archive: %v", err)
+		}
+		in = file
+	}
+	var reader io.Reader = in
+	if oo.Gzip {
+		gzipReader, err := gzip.NewReader(in)
+		if err != nil {
+			_ = in.Close()
+			return nil, fmt.Errorf("error reading gzipped archive: %v", err)
+		}
+		reader = gzipReader
+	}
+	namespace, err := newNamespaceReader(bufio.NewReader(reader), oo.Namespace)
+	if err != nil {
+		_ = in.Close()
+		return nil, err
+	}
+	return archiveReader{namespace, in}, nil
+}
+
+// archiveReader reads a namespace of an archive. Closing it stops reading the
+// archive and closes it.
+type archiveReader struct {
+	*io.PipeReader
+	archive io.Closer
+}
+
+func (r archiveReader) Close() error {
+	_ = r.PipeReader.Close()
+	return r.archive.Close()
+}
+
+// newNamespaceReader reads the prelude of the archive in, and returns a
+// reader of the BSON documents of namespace, as in the .bson file mongodump
+// writes for it. The documents of a timeseries collection are those of its
+// buckets collection.
+func newNamespaceReader(in io.Reader, namespace string) (*io.PipeReader, error) {
+	prelude := &archive.Prelude{}
+	if err := prelude.Read(in); err != nil {
+		return nil, fmt.Errorf("error reading archive: %v", err)
+	}
+
+	var names []string
+	dataNamespace := ""
+	for _, metadata := range prelude.NamespaceMetadatas {
+		name := metadata.Database + "." + metadata.Collection
+		names = append(names, name)
+		if name != namespace {
+			continue
+		}
+		if metadata.Type == "view" {
+			return nil, fmt.Errorf("namespace %v is a view, which has no documents", namespace)
+		}
+		dataNamespace = name
+		if metadata.Type == "timeseries" {
+			dataNamespace = metadata.Database + ".system.buckets." + metadata.Collection
+		}
+	}
+	if dataNamespace == "" {
+		return nil, fmt.Errorf(
+			"namespace %v is not in the archive, which has: %v",
+			namespace,
+			strings.Join(names, ", "),
+		)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		parser := archive.Parser{In: in}
+		consumer := &namespaceConsumer{
+			namespace: dataNamespace,
+			out:       writer,
+			crc:       crc64.New(crc64.MakeTable(crc64.ECMA)),
+		}
+		err := parser.ReadAllBlocks(consumer)
+		switch {
+		case consumer.done:
+			err = nil
+		case err == nil:
+			err = fmt.Errorf("the archive ended before the EOF of namespace %v", dataNamespace)
+		}
+		writer.CloseWithError(err)
+	}()
+	return reader, nil
+}
+
+// namespaceConsumer is the ParserConsumer that writes the documents of one
+// namespace of an archive to out.
+type namespaceConsumer struct {
+	namespace string
+	out       io.Writer
+	crc       hash.Hash64
+
+	// current is set while reading a block of the namespace, and done once
+	// its EOF was read.
+	current bool
+	done    bool
+}
+
+func (c *namespaceConsumer) HeaderBSON(buf []byte) error {
+	var header archive.NamespaceHeader
+	if err := bson.Unmarshal(buf, &header); err != nil {
+		return fmt.Errorf("invalid block header: %v", err)
+	}
+	// the trailer of an archive with checksums has no collection
+	c.current = header.Collection != "" &&
+		header.Database+"."+header.Collection == c.namespace
+	if !c.current || !header.EOF {
+		return nil
+	}
+	c.current = false
+	if crc := int64(c.crc.Sum64()); crc != header.CRC {
+		return fmt.Errorf("CRC mismatch for namespace %v, %v!=%v", c.namespace, crc, header.CRC)
+	}
+	c.done = true
+	return errNamespaceRead
+}
+
+func (c *namespaceConsumer) BodyBSON(buf []byte) error {
+	if !c.current {
+		return nil
+	}
+	// Writes to the hash never return an error.
+	c.crc.Write(buf)
+	_, err := c.out.Write(buf)
+	return err
+}
+
+func (c *namespaceConsumer) End() error {
+	return nil
+}
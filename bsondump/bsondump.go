@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/mongodb/mongo-tools/common/bsonutil"
+	"github.com/mongodb/mongo-tools/common/csvexport"
 	"github.com/mongodb/mongo-tools/common/db"
 	"github.com/mongodb/mongo-tools/common/failpoint"
 	"github.com/mongodb/mongo-tools/common/json"
@@ -39,6 +40,23 @@ type BSONDump struct {
 	OutputWriter io.WriteCloser
 
 	InputSource *db.BSONSource
+
+	// bytes read since --startOffset, and documents dropped for --startDoc
+	// and dumped for --numDocs
+	bytesRead   int64
+	docsSkipped int64
+	docsDumped  int64
+
+	// sorts the output for --sortBy and --uniqueBy
+	sorter     *externalSort
+	sortErr    error
+	sortDone   bool
+	lastKey    bson.RawValue
+	duplicates int64
+
+	// sanitizes the documents for --redact, if set
+	redactor  *redactor
+	redactErr error
 }
 
 type ReadNopCloser struct {
@@ -70,6 +88,9 @@ func (oo *OutputOptions) GetWriter() (io.WriteCloser, error) {
 // GetBSONReader opens and returns an io.ReadCloser for the BSONFileName in OutputOptions
 // or nil if none is set. The caller is responsible for closing it.
 func (oo *OutputOptions) GetBSONReader() (io.ReadCloser, error) {
+	if oo.Archive != "" {
+		return oo.getArchiveReader()
+	}
 	if oo.BSONFileName != "" {
 		file, err := os.Open(util.ToUniversalPath(oo.BSONFileName))
 		if err != nil {
@@ -87,16 +108,30 @@ func New(opts Options) (*BSONDump, error) {
 		ToolOptions:   opts.ToolOptions,
 		OutputOptions: opts.OutputOptions,
 	}
-
-	reader, err := opts.GetBSONReader()
+	var err error
+	dumper.redactor, err = newRedactor(opts.Redact, opts.RedactSalt)
 	if err != nil {
-		return nil, fmt.Errorf("getting BSON reader failed: %v", err)
+		return nil, err
 	}
-	dumper.InputSource = db.NewBSONSource(reader)
 
 	// 16kb + 16mb - This is the maximum size we would get when dumping the
 	// oplog itself. See https://jira.mongodb.org/browse/TOOLS-3001.
 	maxBSONSize := (16 * 1024) + (16 * math.Pow(1024, 2))
+
+	reader, err := opts.GetBSONReader()
+	if err != nil {
+		return nil, fmt.Errorf("getting BSON reader failed: %v", err)
+	}
+	if opts.StartOffset > 0 {
+		sliced, skipped, err := seekToDocument(reader, opts.StartOffset, int(maxBSONSize))
+		if err != nil {
+			_ = reader.Close()
+			return nil, err
+		}
+		reader = sliced
+		dumper.bytesRead = skipped
+	}
+	dumper.InputSource = db.NewBSONSource(reader)
 	dumper.InputSource.SetMaxBSONSize(int32(maxBSONSize))
 
 	writer, err := opts.GetWriter()
@@ -113,6 +148,11 @@ func New(opts Options) (*BSONDump, error) {
 // after Close is called.
 func (bd *BSONDump) Close() error {
 	_ = bd.InputSource.Close()
+	if bd.sorter != nil {
+		if err := bd.sorter.close(); err != nil {
+			log.Logvf(log.Always, "error removing temporary sort files: %v", err)
+		}
+	}
 	return bd.OutputWriter.Close()
 }
 
@@ -145,7 +185,7 @@ func (bd *BSONDump) JSON() (int, error) {
 	}
 
 	for {
-		result := bson.Raw(bd.InputSource.LoadNext())
+		result := bson.Raw(bd.loadNext())
 		if result == nil {
 			break
 		}
@@ -169,7 +209,93 @@ func (bd *BSONDump) JSON() (int, error) {
 			time.Sleep(2 * time.Second)
 		}
 	}
-	if err := bd.InputSource.Err(); err != nil {
+	if err := bd.inputErr(); err != nil {
+		return numFound, err
+	}
+
+	return numFound, nil
+}
+
+// BSON iterates through the BSON file and writes each document it finds as
+// BSON, e.g. to write a sanitized copy of the file with --redact.
+// It returns the number of documents processed and a non-nil error if one is
+// encountered before the end of the file is reached.
+func (bd *BSONDump) BSON() (int, error) {
+	numFound := 0
+
+	if bd.InputSource == nil {
+		panic("Tried to call BSON() before opening file")
+	}
+
+	for {
+		result := bson.Raw(bd.loadNext())
+		if result == nil {
+			break
+		}
+
+		if bd.OutputOptions.ObjCheck {
+			if err := result.Validate(); err != nil {
+				return numFound, fmt.Errorf("failed to validate bson during objcheck: %v", err)
+			}
+		}
+		if _, err := bd.OutputWriter.Write(result); err != nil {
+			return numFound, err
+		}
+		numFound++
+	}
+	if err := bd.inputErr(); err != nil {
+		return numFound, err
+	}
+
+	return numFound, nil
+}
+
+// CSV iterates through the BSON file and writes each document it finds as a
+// CSV row with the columns given by --fields or --fieldFile, in the same
+// format as mongoexport --type=csv.
+// It returns the number of documents processed and a non-nil error if one is
+// encountered before the end of the file is reached.
+func (bd *BSONDump) CSV() (int, error) {
+	numFound := 0
+
+	if bd.InputSource == nil {
+		panic("Tried to call CSV() before opening file")
+	}
+
+	fields, err := bd.OutputOptions.GetFields()
+	if err != nil {
+		return numFound, err
+	}
+	csvOutput := csvexport.NewOutput(fields, bd.OutputOptions.NoHeaderLine, bd.OutputWriter)
+	if err := csvOutput.WriteHeader(); err != nil {
+		return numFound, err
+	}
+
+	for {
+		result := bson.Raw(bd.loadNext())
+		if result == nil {
+			break
+		}
+
+		var doc bson.D
+		err := bson.Unmarshal(result, &doc)
+		if err == nil {
+			err = csvOutput.ExportDocument(doc)
+		}
+		if err != nil {
+			log.Logvf(log.Always, "unable to dump document %v: %v", numFound+1, err)
+
+			//if objcheck is turned on, stop now. otherwise keep on dumpin'
+			if bd.OutputOptions.ObjCheck {
+				return numFound, err
+			}
+		}
+		numFound++
+	}
+	if err := csvOutput.Flush(); err != nil {
+		return numFound, err
+	}
+	if err := bd.inputErr(); err != nil {
 		return numFound, err
 	}
 
@@ -189,7 +315,7 @@ func (bd *BSONDump) Debug() (int, error) {
 	}
 
 	for {
-		result := bson.Raw(bd.InputSource.LoadNext())
+		result := bson.Raw(bd.loadNext())
 		if result == nil {
 			break
 		}
@@ -209,7 +335,7 @@ func (bd *BSONDump) Debug() (int, error) {
 		numFound++
 	}
 
-	if err := bd.InputSource.Err(); err != nil {
+	if err := bd.inputErr(); err != nil {
 		// This error indicates the BSON document header is corrupted;
 		// either the 4-byte header couldn't be read in full, or
 		// the size in the header would require reading more bytes
@@ -8,7 +8,11 @@ package bsondump
 
 import (
 	"bytes"
+	"compress/gzip"
 	"crypto/rand"
+	"hash"
+	"hash/crc64"
+	"io"
 	"math"
 	"os"
 	"os/exec"
@@ -16,10 +20,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/mongodb/mongo-tools/common/archive"
 	"github.com/mongodb/mongo-tools/common/testtype"
 	"github.com/mongodb/mongo-tools/common/testutil"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestBsondump(t *testing.T) {
@@ -356,3 +362,666 @@ func runBsondump(args ...string) (string, error) {
 	out, err := exec.Command(cmd[0], cmd[1:]...).CombinedOutput()
 	return string(out), err
 }
+
+func TestBsondumpCSV(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	input := &bytes.Buffer{}
+	for _, doc := range []bson.D{
+		{{"name", "alice"}, {"age", int32(30)}, {"address", bson.D{{"city", "NYC"}}}},
+		{{"name", "bob, jr."}, {"tags", bson.A{"a", "b"}}},
+	} {
+		raw, err := bson.Marshal(doc)
+		require.NoError(t, err)
+		input.Write(raw)
+	}
+
+	t.Run("with --fields", func(t *testing.T) {
+		cmd := bsondumpCommand("--type=csv", "--fields", "name,age,address.city,tags")
+		cmd.Stdin = bytes.NewReader(input.Bytes())
+		out := &bytes.Buffer{}
+		cmd.Stdout = out
+		require.NoError(t, cmd.Run())
+		require.Equal(
+			t,
+			"name,age,address.city,tags\n"+
+				"alice,30,NYC,\n"+
+				"\"bob, jr.\",,,\"[\"\"a\"\",\"\"b\"\"]\"\n",
+			out.String(),
+		)
+	})
+
+	t.Run("with --fieldFile and --noHeaderLine", func(t *testing.T) {
+		dir, cleanup := testutil.MakeTempDir(t)
+		defer cleanup()
+		fieldFile := filepath.Join(dir, "fields.txt")
+		require.NoError(t, os.WriteFile(fieldFile, []byte("name\nage\n"), 0644))
+
+		cmd := bsondumpCommand("--type=csv", "--fieldFile", fieldFile, "--noHeaderLine")
+		cmd.Stdin = bytes.NewReader(input.Bytes())
+		out := &bytes.Buffer{}
+		cmd.Stdout = out
+		require.NoError(t, cmd.Run())
+		require.Equal(t, "alice,30\n\"bob, jr.\",\n", out.String())
+	})
+
+	t.Run("without fields", func(t *testing.T) {
+		cmd := bsondumpCommand("--type=csv")
+		cmd.Stdin = bytes.NewReader(input.Bytes())
+		out, err := cmd.CombinedOutput()
+		require.Error(t, err)
+		require.Contains(t, string(out), "--type=csv requires --fields or --fieldFile")
+	})
+
+	t.Run("fields without --type=csv", func(t *testing.T) {
+		cmd := bsondumpCommand("--fields", "name")
+		cmd.Stdin = bytes.NewReader(input.Bytes())
+		out, err := cmd.CombinedOutput()
+		require.Error(t, err)
+		require.Contains(t, string(out), "can only be used with --type=csv")
+	})
+}
+
+func TestBsondumpSlice(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	input := &bytes.Buffer{}
+	for i := 0; i < 5; i++ {
+		raw, err := bson.Marshal(bson.D{{"_id", int32(i)}})
+		require.NoError(t, err)
+		input.Write(raw)
+	}
+	// every document is 14 bytes
+	const docSize = 14
+	require.Equal(t, 5*docSize, input.Len())
+
+	dir, cleanup := testutil.MakeTempDir(t)
+	defer cleanup()
+	bsonFile := filepath.Join(dir, "in.bson")
+	require.NoError(t, os.WriteFile(bsonFile, input.Bytes(), 0644))
+
+	dumpIDs := func(t *testing.T, oo OutputOptions) string {
+		oo.BSONFileName = bsonFile
+		oo.OutFileName = filepath.Join(dir, "out.json")
+		dumper, err := New(Options{OutputOptions: &oo})
+		require.NoError(t, err)
+		_, err = dumper.JSON()
+		require.NoError(t, err)
+		require.NoError(t, dumper.Close())
+		out, err := os.ReadFile(oo.OutFileName)
+		require.NoError(t, err)
+		return strings.ReplaceAll(string(out), "\n", " ")
+	}
+
+	tests := []struct {
+		name     string
+		options  OutputOptions
+		expected string
+	}{
+		{
+			"offset at a document",
+			OutputOptions{StartOffset: 3 * docSize},
+			`{"_id":{"$numberInt":"3"}} {"_id":{"$numberInt":"4"}} `,
+		},
+		{
+			"offset inside a document",
+			OutputOptions{StartOffset: 2*docSize + 3},
+			`{"_id":{"$numberInt":"3"}} {"_id":{"$numberInt":"4"}} `,
+		},
+		{
+			"offset inside the last document",
+			OutputOptions{StartOffset: 4*docSize + 1},
+			"",
+		},
+		{
+			"max bytes",
+			OutputOptions{MaxBytes: 2*docSize + 5},
+			`{"_id":{"$numberInt":"0"}} {"_id":{"$numberInt":"1"}} `,
+		},
+		{
+			"max bytes count the bytes skipped to the next document",
+			OutputOptions{StartOffset: docSize + 1, MaxBytes: 2 * docSize},
+			`{"_id":{"$numberInt":"2"}} `,
+		},
+		{
+			"documents",
+			OutputOptions{StartDoc: 1, NumDocs: 2},
+			`{"_id":{"$numberInt":"1"}} {"_id":{"$numberInt":"2"}} `,
+		},
+		{
+			"documents from an offset",
+			OutputOptions{StartOffset: docSize, StartDoc: 1, NumDocs: 1},
+			`{"_id":{"$numberInt":"2"}} `,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, dumpIDs(t, test.options))
+		})
+	}
+
+	t.Run("offset of a stream", func(t *testing.T) {
+		stream := ReadNopCloser{bytes.NewReader(input.Bytes())}
+		reader, skipped, err := seekToDocument(stream, 3*docSize+2, 1024)
+		require.NoError(t, err)
+		require.Equal(t, int64(docSize-2), skipped)
+		rest, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, input.Bytes()[4*docSize:], rest)
+
+		_, _, err = seekToDocument(stream, 100, 1024)
+		require.ErrorContains(t, err, "past the end of the input")
+	})
+
+	t.Run("negative values", func(t *testing.T) {
+		_, err := ParseOptions([]string{"--numDocs=-1"}, "", "")
+		require.ErrorContains(t, err, "--numDocs must not be negative")
+	})
+}
+
+func TestBsondumpSort(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	docs := []bson.D{
+		{{"_id", int32(0)}, {"k", "b"}},
+		{{"_id", int32(1)}, {"k", int64(2)}},
+		{{"_id", int32(2)}},
+		{{"_id", int32(3)}, {"k", "a"}},
+		{{"_id", int32(4)}, {"k", 2.0}},
+		{{"_id", int32(5)}, {"k", bson.D{{"x", 1}}}},
+		{{"_id", int32(6)}, {"k", "b"}},
+		{{"_id", int32(7)}, {"k", int32(1)}},
+		{{"_id", int32(8)}, {"k", nil}},
+	}
+	input := &bytes.Buffer{}
+	for _, doc := range docs {
+		raw, err := bson.Marshal(doc)
+		require.NoError(t, err)
+		input.Write(raw)
+	}
+
+	dir, cleanup := testutil.MakeTempDir(t)
+	defer cleanup()
+	bsonFile := filepath.Join(dir, "in.bson")
+	require.NoError(t, os.WriteFile(bsonFile, input.Bytes(), 0644))
+
+	dumpIDs := func(t *testing.T, oo OutputOptions) []int32 {
+		oo.BSONFileName = bsonFile
+		oo.OutFileName = filepath.Join(dir, "out.json")
+		dumper, err := New(Options{OutputOptions: &oo})
+		require.NoError(t, err)
+		_, err = dumper.JSON()
+		require.NoError(t, err)
+		require.NoError(t, dumper.Close())
+
+		out, err := os.ReadFile(oo.OutFileName)
+		require.NoError(t, err)
+		var ids []int32
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			var doc struct {
+				ID int32 `bson:"_id"`
+			}
+			require.NoError(t, bson.UnmarshalExtJSON([]byte(line), true, &doc))
+			ids = append(ids, doc.ID)
+		}
+		return ids
+	}
+
+	sorted := []int32{2, 8, 7, 1, 4, 3, 0, 6, 5}
+	t.Run("--sortBy in memory", func(t *testing.T) {
+		require.Equal(t, sorted, dumpIDs(t, OutputOptions{SortBy: "k"}))
+	})
+
+	t.Run("--sortBy spilling to temporary files", func(t *testing.T) {
+		require.Equal(t, sorted, dumpIDs(t, OutputOptions{SortBy: "k", SortMemoryBytes: 40}))
+	})
+
+	t.Run("--uniqueBy drops duplicates", func(t *testing.T) {
+		expected := []int32{2, 7, 1, 3, 0, 5}
+		require.Equal(t, expected, dumpIDs(t, OutputOptions{UniqueBy: "k"}))
+		require.Equal(t, expected, dumpIDs(t, OutputOptions{UniqueBy: "k", SortMemoryBytes: 40}))
+	})
+
+	t.Run("--sortBy sorts the window of the input", func(t *testing.T) {
+		ids := dumpIDs(t, OutputOptions{SortBy: "k", StartDoc: 1, NumDocs: 4})
+		require.Equal(t, []int32{2, 1, 4, 3}, ids)
+	})
+
+	t.Run("--sortBy a dotted field keeps the order of equal values", func(t *testing.T) {
+		require.Equal(t, []int32{0, 1, 2, 3, 4, 6, 7, 8, 5}, dumpIDs(t, OutputOptions{SortBy: "k.x"}))
+	})
+
+	t.Run("--uniqueBy with another --sortBy", func(t *testing.T) {
+		_, err := ParseOptions([]string{"--uniqueBy=a", "--sortBy=b"}, "", "")
+		require.ErrorContains(t, err, "cannot be used with another --sortBy")
+	})
+}
+
+func TestCompareValues(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	value := func(v interface{}) bson.RawValue {
+		raw, err := bson.Marshal(bson.D{{"v", v}})
+		require.NoError(t, err)
+		return bson.Raw(raw).Lookup("v")
+	}
+	decimal, err := primitive.ParseDecimal128("1.5")
+	require.NoError(t, err)
+
+	ascending := []interface{}{
+		primitive.MinKey{},
+		nil,
+		math.NaN(),
+		int32(-1),
+		decimal,
+		int64(2),
+		"",
+		"a",
+		bson.D{},
+		bson.D{{"a", 1}},
+		bson.A{},
+		primitive.Binary{Data: []byte{1}},
+		primitive.NewObjectID(),
+		false,
+		true,
+		primitive.DateTime(0),
+		primitive.Timestamp{T: 1},
+		primitive.Regex{Pattern: "a"},
+		primitive.MaxKey{},
+	}
+	for i := 1; i < len(ascending); i++ {
+		a, b := value(ascending[i-1]), value(ascending[i])
+		require.Equal(t, -1, compareValues(a, b), "%v < %v", a, b)
+		require.Equal(t, 1, compareValues(b, a), "%v > %v", b, a)
+	}
+	require.Equal(t, 0, compareValues(value(int32(2)), value(2.0)))
+	require.Equal(t, 0, compareValues(value(int64(1<<62)), value(int64(1<<62))))
+	require.Equal(t, -1, compareValues(value(int64(1<<62)), value(int64(1<<62+1))))
+}
+
+func TestBsondumpOplog(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	lsid := bson.D{{"id", primitive.Binary{
+		Subtype: bson.TypeBinaryUUID,
+		Data: []byte{
+			0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0,
+			0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0,
+		},
+	}}}
+	input := &bytes.Buffer{}
+	for _, doc := range []bson.D{
+		{
+			{"ts", primitive.Timestamp{T: 1709634600, I: 1}},
+			{"op", "i"},
+			{"ns", "test.c"},
+			{"o", bson.D{{"_id", int32(1)}, {"name", strings.Repeat("x", 300)}}},
+		},
+		{
+			{"ts", primitive.Timestamp{T: 1709634601, I: 2}},
+			{"op", "u"},
+			{"ns", "test.c"},
+			{"o", bson.D{{"$v", int32(2)}, {"diff", bson.D{{"u", bson.D{{"a", int32(1)}}}}}}},
+			{"o2", bson.D{{"_id", int32(1)}}},
+			{"lsid", lsid},
+			{"txnNumber", int64(4)},
+		},
+		{
+			{"ts", primitive.Timestamp{T: 1709634602, I: 1}},
+			{"op", "c"},
+			{"ns", "admin.$cmd"},
+			{"o", bson.D{{"applyOps", bson.A{
+				bson.D{{"op", "d"}, {"ns", "test.c"}, {"o", bson.D{{"_id", int32(1)}}}},
+				bson.D{{"op", "i"}, {"ns", "test.d"}, {"o", bson.D{{"_id", int32(2)}}}},
+			}}}},
+			{"lsid", lsid},
+			{"txnNumber", int64(5)},
+		},
+		{{"not", "an oplog entry"}},
+	} {
+		raw, err := bson.Marshal(doc)
+		require.NoError(t, err)
+		input.Write(raw)
+	}
+
+	dir, cleanup := testutil.MakeTempDir(t)
+	defer cleanup()
+	oo := OutputOptions{
+		Oplog:        true,
+		BSONFileName: filepath.Join(dir, "oplog.bson"),
+		OutFileName:  filepath.Join(dir, "out.txt"),
+	}
+	require.NoError(t, os.WriteFile(oo.BSONFileName, input.Bytes(), 0644))
+	dumper, err := New(Options{OutputOptions: &oo})
+	require.NoError(t, err)
+	numFound, err := dumper.Oplog()
+	require.NoError(t, err)
+	require.Equal(t, 4, numFound)
+	require.NoError(t, dumper.Close())
+
+	out, err := os.ReadFile(oo.OutFileName)
+	require.NoError(t, err)
+	const id = "12345678-9abc-def0-1234-56789abcdef0"
+	require.Equal(
+		t,
+		"2024-03-05T10:30:00Z (1709634600,1)\tinsert\ttest.c\to="+
+			`{"_id":1,"name":"`+strings.Repeat("x", maxOplogSummaryLength-17)+"...\n"+
+			"2024-03-05T10:30:01Z (1709634601,2)\tupdate\ttest.c\t"+
+			`o={"$v":2,"diff":{"u":{"a":1}}}`+"\t"+`o2={"_id":1}`+"\ttxnNumber=4\tlsid="+id+"\n"+
+			"2024-03-05T10:30:02Z (1709634602,1)\tapplyOps\tadmin.$cmd\t2 operation(s)"+
+			"\ttxnNumber=5\tlsid="+id+"\n"+
+			"\tdelete\ttest.c\t"+`o={"_id":1}`+"\n"+
+			"\tinsert\ttest.d\t"+`o={"_id":2}`+"\n",
+		string(out),
+	)
+
+	t.Run("with another output type", func(t *testing.T) {
+		_, err := ParseOptions([]string{"--oplog", "--type=debug"}, "", "")
+		require.ErrorContains(t, err, "--oplog cannot be used with --type=debug")
+	})
+}
+
+func TestBsondumpRedact(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	docs := []bson.D{
+		{
+			{"_id", int32(0)},
+			{"email", "ann@example.com"},
+			{"ssn", "123-45-6789"},
+			{"notes", "private"},
+			{"orders", bson.A{
+				bson.D{{"card", "4111"}, {"sku", "a"}},
+				bson.D{{"card", int64(4242)}, {"sku", "b"}},
+			}},
+			{"address", bson.D{{"city", "Paris"}, {"zip", int32(75001)}}},
+		},
+		{{"_id", int32(1)}, {"email", "ann@example.com"}, {"tags", bson.A{"x", "y"}}},
+	}
+	input := &bytes.Buffer{}
+	for _, doc := range docs {
+		raw, err := bson.Marshal(doc)
+		require.NoError(t, err)
+		input.Write(raw)
+	}
+
+	dir, cleanup := testutil.MakeTempDir(t)
+	defer cleanup()
+	bsonFile := filepath.Join(dir, "in.bson")
+	require.NoError(t, os.WriteFile(bsonFile, input.Bytes(), 0644))
+
+	redact := func(t *testing.T, salt string, rules ...string) []bson.D {
+		oo := OutputOptions{
+			BSONFileName: bsonFile,
+			OutFileName:  filepath.Join(dir, "out.bson"),
+			Redact:       rules,
+			RedactSalt:   salt,
+		}
+		require.NoError(t, oo.validateRedact())
+		dumper, err := New(Options{OutputOptions: &oo})
+		require.NoError(t, err)
+		n, err := dumper.BSON()
+		require.NoError(t, err)
+		require.Equal(t, len(docs), n)
+		require.NoError(t, dumper.Close())
+
+		out, err := os.ReadFile(oo.OutFileName)
+		require.NoError(t, err)
+		var redacted []bson.D
+		for len(out) > 0 {
+			doc, err := bson.NewFromIOReader(bytes.NewReader(out))
+			require.NoError(t, err)
+			var d bson.D
+			require.NoError(t, bson.Unmarshal(doc, &d))
+			redacted = append(redacted, d)
+			out = out[len(doc):]
+		}
+		return redacted
+	}
+
+	t.Run("rules", func(t *testing.T) {
+		out := redact(t, "",
+			"email=hash", "ssn=mask", "notes=drop", "orders.card=hash", "address=mask", "tags=mask")
+		require.Len(t, out, 2)
+
+		first := out[0].Map()
+		email, ok := first["email"].(string)
+		require.True(t, ok)
+		require.Len(t, email, 64)
+		require.Equal(t, email, out[1].Map()["email"], "equal values hash alike")
+		require.Equal(t, "***********", first["ssn"])
+		require.NotContains(t, first, "notes")
+
+		orders := first["orders"].(bson.A)
+		require.Len(t, orders, 2)
+		for _, order := range orders {
+			card := order.(bson.D).Map()["card"]
+			require.IsType(t, "", card)
+			require.Len(t, card, 64)
+			require.NotEqual(t, "4111", card)
+		}
+		require.Equal(t, "a", orders[0].(bson.D).Map()["sku"])
+
+		require.Equal(t, bson.D{{"city", "*****"}, {"zip", nil}}, first["address"])
+		require.Equal(t, bson.A{"*", "*"}, out[1].Map()["tags"])
+	})
+
+	t.Run("the salt changes the hashes", func(t *testing.T) {
+		unsalted := redact(t, "", "email=hash")[0].Map()["email"]
+		salted := redact(t, "pepper", "email=hash")[0].Map()["email"]
+		require.NotEqual(t, unsalted, salted)
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--redact=email"},
+			{"--redact=email=blur"},
+			{"--redact=a..b=drop"},
+			{"--redact=email=hash", "--redact=email=mask"},
+			{"--redactSalt=x"},
+			{"--redact=o=drop", "--oplog"},
+		} {
+			_, err := ParseOptions(args, "", "")
+			require.Error(t, err, args)
+		}
+	})
+}
+
+func TestBsondumpArchive(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	// an archive of a collection with two blocks, interleaved with another
+	// collection, and a view
+	content := &bytes.Buffer{}
+	prelude := &archive.Prelude{
+		Header: &archive.Header{FormatVersion: "0.1", ServerVersion: "8.0.0"},
+		NamespaceMetadatas: []*archive.CollectionMetadata{
+			{Database: "shop", Collection: "orders", Type: "collection"},
+			{Database: "shop", Collection: "users", Type: "collection"},
+			{Database: "shop", Collection: "recent", Type: "view"},
+		},
+	}
+	require.NoError(t, prelude.Write(content))
+	crcs := map[string]hash.Hash64{}
+	writeBlock := func(collection string, eof bool, ids ...int32) {
+		sum, ok := crcs[collection]
+		if !ok {
+			sum = crc64.New(crc64.MakeTable(crc64.ECMA))
+			crcs[collection] = sum
+		}
+		header := archive.NamespaceHeader{Database: "shop", Collection: collection, EOF: eof}
+		if eof {
+			header.CRC = int64(sum.Sum64())
+		}
+		raw, err := bson.Marshal(header)
+		require.NoError(t, err)
+		content.Write(raw)
+		for _, id := range ids {
+			raw, err := bson.Marshal(bson.D{{"_id", id}, {"in", collection}})
+			require.NoError(t, err)
+			sum.Write(raw)
+			content.Write(raw)
+		}
+		content.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF})
+	}
+	writeBlock("orders", false, 1, 2)
+	writeBlock("users", false, 10)
+	writeBlock("orders", false, 3)
+	writeBlock("users", true)
+	writeBlock("orders", true)
+
+	dir, cleanup := testutil.MakeTempDir(t)
+	defer cleanup()
+	archiveFile := filepath.Join(dir, "dump.archive")
+	require.NoError(t, os.WriteFile(archiveFile, content.Bytes(), 0644))
+
+	gzipped := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(gzipped)
+	_, err := gzipWriter.Write(content.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+	gzipFile := filepath.Join(dir, "dump.archive.gz")
+	require.NoError(t, os.WriteFile(gzipFile, gzipped.Bytes(), 0644))
+
+	dump := func(t *testing.T, args ...string) (string, error) {
+		args = append(args, "--outFile", filepath.Join(dir, "out.json"))
+		opts, err := ParseOptions(args, "", "")
+		require.NoError(t, err)
+		dumper, err := New(opts)
+		if err != nil {
+			return "", err
+		}
+		_, err = dumper.JSON()
+		require.NoError(t, dumper.Close())
+		out, readErr := os.ReadFile(opts.OutFileName)
+		require.NoError(t, readErr)
+		return strings.ReplaceAll(string(out), "\n", " "), err
+	}
+	orders := `{"_id":{"$numberInt":"1"},"in":"orders"} ` +
+		`{"_id":{"$numberInt":"2"},"in":"orders"} ` +
+		`{"_id":{"$numberInt":"3"},"in":"orders"} `
+
+	t.Run("the documents of the namespace", func(t *testing.T) {
+		out, err := dump(t, "--archive", archiveFile, "--namespace", "shop.orders")
+		require.NoError(t, err)
+		require.Equal(t, orders, out)
+
+		out, err = dump(t, "--archive", archiveFile, "--namespace", "shop.users", "--numDocs=1")
+		require.NoError(t, err)
+		require.Equal(t, `{"_id":{"$numberInt":"10"},"in":"users"} `, out)
+	})
+
+	t.Run("gzipped archives", func(t *testing.T) {
+		out, err := dump(t, "--archive", gzipFile, "--gzip", "--namespace", "shop.orders")
+		require.NoError(t, err)
+		require.Equal(t, orders, out)
+	})
+
+	t.Run("namespaces without documents", func(t *testing.T) {
+		_, err := dump(t, "--archive", archiveFile, "--namespace", "shop.missing")
+		require.ErrorContains(t, err, "which has: shop.orders, shop.users, shop.recent")
+		_, err = dump(t, "--archive", archiveFile, "--namespace", "shop.recent")
+		require.ErrorContains(t, err, "is a view")
+	})
+
+	t.Run("truncated archives", func(t *testing.T) {
+		truncated := filepath.Join(dir, "truncated.archive")
+		require.NoError(t, os.WriteFile(truncated, content.Bytes()[:content.Len()-30], 0644))
+		_, err := dump(t, "--archive", truncated, "--namespace", "shop.orders")
+		require.Error(t, err)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--archive", archiveFile},
+			{"--archive", archiveFile, "--namespace", "orders"},
+			{"--archive", archiveFile, "--namespace", "shop.orders", "in.bson"},
+			{"--archive", archiveFile, "--namespace", "shop.orders", "--startOffset=10"},
+			{"--namespace", "shop.orders"},
+		} {
+			_, err := ParseOptions(args, "", "")
+			require.Error(t, err, args)
+		}
+	})
+}
+
+func TestBsondumpInferSchema(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	input := &bytes.Buffer{}
+	for i := 0; i < 4; i++ {
+		doc := bson.D{
+			{"_id", int32(i)},
+			{"name", strings.Repeat("x", i+1)},
+			{"tags", bson.A{"a", int64(i)}},
+			{"address", bson.D{{"zip", int64(10000 + i)}}},
+		}
+		if i < 3 {
+			doc = append(doc, bson.E{"score", 0.5 * float64(i)})
+		} else {
+			doc = append(doc, bson.E{"score", nil})
+			doc = doc[1:]
+		}
+		raw, err := bson.Marshal(doc)
+		require.NoError(t, err)
+		input.Write(raw)
+	}
+
+	dir, cleanup := testutil.MakeTempDir(t)
+	defer cleanup()
+	oo := OutputOptions{
+		InferSchema:       true,
+		RequiredThreshold: 0.75,
+		BSONFileName:      filepath.Join(dir, "input.bson"),
+		OutFileName:       filepath.Join(dir, "schema.json"),
+	}
+	require.NoError(t, os.WriteFile(oo.BSONFileName, input.Bytes(), 0644))
+	dumper, err := New(Options{OutputOptions: &oo})
+	require.NoError(t, err)
+	numFound, err := dumper.InferSchema()
+	require.NoError(t, err)
+	require.Equal(t, 4, numFound)
+	require.NoError(t, dumper.Close())
+
+	out, err := os.ReadFile(oo.OutFileName)
+	require.NoError(t, err)
+	var validator bson.D
+	require.NoError(t, bson.UnmarshalExtJSON(out, false, &validator))
+	schema := validator.Map()["$jsonSchema"].(bson.D).Map()
+	require.Equal(t, "object", schema["bsonType"])
+	require.Equal(t, bson.A{"_id", "name", "tags", "address", "score"}, schema["required"])
+
+	properties := schema["properties"].(bson.D).Map()
+	id := properties["_id"].(bson.D).Map()
+	require.Equal(t, "int", id["bsonType"])
+	require.Equal(t, "in 75% of 4 documents: int 100%", id["description"])
+	require.EqualValues(t, 0, id["minimum"])
+	require.EqualValues(t, 2, id["maximum"])
+
+	score := properties["score"].(bson.D).Map()
+	require.Equal(t, bson.A{"double", "null"}, score["bsonType"])
+	require.Equal(t, "in 100% of 4 documents: double 75%, null 25%", score["description"])
+	require.EqualValues(t, 1, score["maximum"])
+
+	name := properties["name"].(bson.D).Map()
+	require.EqualValues(t, 1, name["minLength"])
+	require.EqualValues(t, 4, name["maxLength"])
+
+	tags := properties["tags"].(bson.D).Map()
+	require.EqualValues(t, 2, tags["maxItems"])
+	items := tags["items"].(bson.D).Map()
+	require.Equal(t, bson.A{"long", "string"}, items["bsonType"])
+
+	zip := properties["address"].(bson.D).Map()["properties"].(bson.D).Map()["zip"].(bson.D).Map()
+	require.Equal(t, "long", zip["bsonType"])
+	require.EqualValues(t, 10003, zip["maximum"])
+
+	t.Run("with another output type", func(t *testing.T) {
+		_, err := ParseOptions([]string{"--inferSchema", "--type=csv", "-f", "a"}, "", "")
+		require.ErrorContains(t, err, "--inferSchema cannot be used with --type=csv")
+		_, err = ParseOptions([]string{"--inferSchema", "--requiredThreshold", "1.5"}, "", "")
+		require.Error(t, err)
+		_, err = ParseOptions([]string{"--requiredThreshold", "0.5"}, "", "")
+		require.Error(t, err)
+	})
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/mongodb/mongo-tools/bsondump"
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/signals"
+	"github.com/mongodb/mongo-tools/common/telemetry"
 	"github.com/mongodb/mongo-tools/common/util"
 )
 
@@ -40,33 +41,48 @@ func main() {
 		return
 	}
 
+	if err := telemetry.Init(opts.AppName, opts.VersionStr, opts.OTelEndpoint); err != nil {
+		log.Logvf(log.Always, "error starting telemetry: %v", err)
+		os.Exit(util.ExitFailure)
+	}
+	defer telemetry.Shutdown()
+
 	signals.Handle()
 
 	dumper, err := bsondump.New(opts)
 	if err != nil {
 		log.Logv(log.Always, err.Error())
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 	defer func() {
 		err := dumper.Close()
 		if err != nil {
 			log.Logvf(log.Always, "error cleaning up: %v", err)
-			os.Exit(util.ExitFailure)
+			telemetry.Exit(util.ExitFailure)
 		}
 	}()
 
 	log.Logvf(log.DebugLow, "running bsondump with --objcheck: %v", opts.ObjCheck)
 
 	var numFound int
-	if opts.Type == bsondump.DebugOutputType {
+	switch {
+	case opts.Oplog:
+		numFound, err = dumper.Oplog()
+	case opts.InferSchema:
+		numFound, err = dumper.InferSchema()
+	case opts.Type == bsondump.DebugOutputType:
 		numFound, err = dumper.Debug()
-	} else {
+	case opts.Type == bsondump.CSVOutputType:
+		numFound, err = dumper.CSV()
+	case opts.Type == bsondump.BSONOutputType:
+		numFound, err = dumper.BSON()
+	default:
 		numFound, err = dumper.JSON()
 	}
 
 	log.Logvf(log.Always, "%v objects found", numFound)
 	if err != nil {
 		log.Logv(log.Always, err.Error())
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 }
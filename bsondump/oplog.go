@@ -0,0 +1,201 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package bsondump
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxOplogSummaryLength is how much of the o and o2 fields of an oplog entry
+// the --oplog timeline shows.
+const maxOplogSummaryLength = 200
+
+// oplogEntry is the part of an oplog entry that the --oplog timeline shows. The
+// entries of an applyOps have no ts.
+type oplogEntry struct {
+	Timestamp primitive.Timestamp `bson:"ts"`
+	Operation string              `bson:"op"`
+	Namespace string              `bson:"ns"`
+	Object    bson.Raw            `bson:"o"`
+	Query     bson.Raw            `bson:"o2,omitempty"`
+	LSID      bson.Raw            `bson:"lsid,omitempty"`
+	TxnNumber *int64              `bson:"txnNumber,omitempty"`
+}
+
+// applyOpsCommand is the o field of an applyOps oplog entry, which holds the
+// operations of a transaction.
+type applyOpsCommand struct {
+	ApplyOps   []oplogEntry `bson:"applyOps"`
+	PartialTxn bool         `bson:"partialTxn"`
+	Prepare    bool         `bson:"prepare"`
+}
+
+// validateOplog checks that --oplog is not combined with another output type.
+func (oo *OutputOptions) validateOplog() error {
+	if oo.Oplog && oo.Type != "" && oo.Type != JSONOutputType {
+		return fmt.Errorf("--oplog cannot be used with --type=%v", oo.Type)
+	}
+	return nil
+}
+
+// Oplog iterates through a BSON file of oplog entries, like the oplog.bson of
+// a mongodump --oplog, and writes a line for each entry with its timestamp,
+// operation, namespace, the start of its o and o2 fields, and its txnNumber and
+// lsid. The operations of an applyOps are listed under it, indented.
+// It returns the number of entries processed and a non-nil error if one is
+// encountered before the end of the file is reached.
+func (bd *BSONDump) Oplog() (int, error) {
+	numFound := 0
+
+	if bd.InputSource == nil {
+		panic("Tried to call Oplog() before opening file")
+	}
+
+	for {
+		result := bson.Raw(bd.loadNext())
+		if result == nil {
+			break
+		}
+
+		if err := writeOplogEntry(bd.OutputWriter, result); err != nil {
+			log.Logvf(log.Always, "unable to dump oplog entry %v: %v", numFound+1, err)
+
+			//if objcheck is turned on, stop now. otherwise keep on dumpin'
+			if bd.OutputOptions.ObjCheck {
+				return numFound, err
+			}
+		}
+		numFound++
+	}
+	if err := bd.inputErr(); err != nil {
+		return numFound, err
+	}
+
+	return numFound, nil
+}
+
+// writeOplogEntry writes the timeline lines of an oplog entry.
+func writeOplogEntry(out io.Writer, raw bson.Raw) error {
+	var entry oplogEntry
+	if err := bson.Unmarshal(raw, &entry); err != nil {
+		return err
+	}
+	if entry.Operation == "" {
+		return fmt.Errorf("not an oplog entry: it has no op field")
+	}
+
+	ts := time.Unix(int64(entry.Timestamp.T), 0).UTC().Format(time.RFC3339)
+	fields := []string{fmt.Sprintf("%v (%v,%v)", ts, entry.Timestamp.T, entry.Timestamp.I)}
+
+	var applyOps applyOpsCommand
+	isApplyOps := entry.Operation == "c" && entry.Object.Lookup("applyOps").Type == bson.TypeArray
+	if isApplyOps {
+		if err := bson.Unmarshal(entry.Object, &applyOps); err != nil {
+			return fmt.Errorf("invalid applyOps: %v", err)
+		}
+		fields = append(fields, "applyOps", entry.Namespace, applyOpsSummary(applyOps))
+	} else {
+		fields = append(fields, entryFields(entry)...)
+	}
+	fields = append(fields, sessionFields(entry)...)
+	if _, err := fmt.Fprintln(out, strings.Join(fields, "\t")); err != nil {
+		return err
+	}
+
+	for _, op := range applyOps.ApplyOps {
+		opFields := append([]string{""}, entryFields(op)...)
+		if _, err := fmt.Fprintln(out, strings.Join(opFields, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryFields returns the operation, namespace and summarized o and o2 of an
+// oplog entry.
+func entryFields(entry oplogEntry) []string {
+	fields := []string{operationName(entry.Operation), entry.Namespace}
+	if len(entry.Object) > 0 {
+		fields = append(fields, "o="+summarize(entry.Object))
+	}
+	if len(entry.Query) > 0 {
+		fields = append(fields, "o2="+summarize(entry.Query))
+	}
+	return fields
+}
+
+func applyOpsSummary(applyOps applyOpsCommand) string {
+	summary := fmt.Sprintf("%v operation(s)", len(applyOps.ApplyOps))
+	if applyOps.PartialTxn {
+		summary += ", partial transaction"
+	}
+	if applyOps.Prepare {
+		summary += ", prepared"
+	}
+	return summary
+}
+
+// sessionFields returns the txnNumber and lsid of an oplog entry written by a
+// retryable write or a transaction.
+func sessionFields(entry oplogEntry) []string {
+	var fields []string
+	if entry.TxnNumber != nil {
+		fields = append(fields, fmt.Sprintf("txnNumber=%v", *entry.TxnNumber))
+	}
+	if len(entry.LSID) > 0 {
+		fields = append(fields, "lsid="+sessionID(entry.LSID))
+	}
+	return fields
+}
+
+// sessionID returns the UUID of an lsid, or the whole lsid as extended JSON if
+// it has none.
+func sessionID(lsid bson.Raw) string {
+	if subtype, data, ok := lsid.Lookup("id").BinaryOK(); ok && subtype == bson.TypeBinaryUUID &&
+		len(data) == 16 {
+		h := hex.EncodeToString(data)
+		return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+	}
+	return summarize(lsid)
+}
+
+func operationName(op string) string {
+	switch op {
+	case "i":
+		return "insert"
+	case "u":
+		return "update"
+	case "d":
+		return "delete"
+	case "c":
+		return "command"
+	case "n":
+		return "noop"
+	}
+	return op
+}
+
+// summarize returns a document as relaxed extended JSON, cut to
+// maxOplogSummaryLength.
+func summarize(doc bson.Raw) string {
+	out, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return fmt.Sprintf("<invalid BSON: %v>", err)
+	}
+	if runes := []rune(string(out)); len(runes) > maxOplogSummaryLength {
+		return string(runes[:maxOplogSummaryLength]) + "..."
+	}
+	return string(out)
+}
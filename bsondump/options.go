@@ -8,14 +8,16 @@ package bsondump
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/util"
 )
 
 var Usage = `<options> <file>
 
-View and debug .bson files.
+View and debug .bson files, or the collections of archives written by mongodump --archive.
 
 See http://docs.mongodb.com/database-tools/bsondump/ for more information.`
 
@@ -29,11 +31,13 @@ type Options struct {
 const (
 	DebugOutputType = "debug"
 	JSONOutputType  = "json"
+	CSVOutputType   = "csv"
+	BSONOutputType  = "bson"
 )
 
 type OutputOptions struct {
 	// Format to display the BSON data file
-	Type string `long:"type" value-name:"<type>" default:"json" default-mask:"-" description:"type of output: debug, json"`
+	Type string `long:"type" value-name:"<type>" default:"json" default-mask:"-" description:"type of output: debug, json, csv, bson (e.g. for a sanitized copy of the input with --redact)"`
 
 	// Validate each BSON document before displaying
 	ObjCheck bool `long:"objcheck" description:"validate BSON during processing"`
@@ -41,11 +45,63 @@ type OutputOptions struct {
 	// Display JSON data with indents
 	Pretty bool `long:"pretty" description:"output JSON formatted to be human-readable"`
 
+	// Fields to output as the columns of CSV data
+	Fields string `long:"fields" value-name:"<field>[,<field>]*" short:"f" description:"comma separated list of field names (required for --type=csv) e.g. -f \"name,age\""`
+
+	// File with the fields to output as the columns of CSV data
+	FieldFile string `long:"fieldFile" value-name:"<filename>" description:"file with field names for --type=csv - 1 per line"`
+
+	// Omit the list of field names from CSV output
+	NoHeaderLine bool `long:"noHeaderLine" description:"output CSV data without a list of field names at the first line"`
+
 	// Path to input BSON file
 	BSONFileName string `long:"bsonFile" description:"path to BSON file to dump to JSON; default is stdin"`
 
+	// Path to an archive of mongodump to read a namespace of
+	Archive string `long:"archive" value-name:"<filename>" description:"path to an archive written by mongodump --archive, or - for stdin, to dump the documents of --namespace from instead of a BSON file"`
+
+	// Namespace of the archive to dump
+	Namespace string `long:"namespace" value-name:"<database>.<collection>" description:"namespace of the collection in --archive to dump"`
+
+	// Decompress the archive
+	Gzip bool `long:"gzip" description:"decompress a gzipped --archive"`
+
 	// Path to output file
 	OutFileName string `long:"outFile" description:"path to output file to dump BSON to; default is stdout"`
+
+	// Byte offset to start reading at
+	StartOffset int64 `long:"startOffset" value-name:"<bytes>" description:"start reading at this byte offset; an offset inside a document skips ahead to the next document"`
+
+	// Number of bytes to read from StartOffset
+	MaxBytes int64 `long:"maxBytes" value-name:"<bytes>" description:"stop before the first document that ends more than this many bytes past --startOffset"`
+
+	// Number of documents to skip
+	StartDoc int64 `long:"startDoc" value-name:"<count>" description:"number of documents to skip before dumping, counted from --startOffset"`
+
+	// Number of documents to output
+	NumDocs int64 `long:"numDocs" value-name:"<count>" description:"maximum number of documents to dump"`
+
+	// Field to sort the output by
+	SortBy string `long:"sortBy" value-name:"<field>" description:"output the documents in ascending order of this (dotted) field, in the order the server sorts values in; documents without it sort first. Documents that do not fit in --sortMemoryBytes are sorted in temporary files"`
+
+	// Field whose values must be unique
+	UniqueBy string `long:"uniqueBy" value-name:"<field>" description:"sort by this (dotted) field, and drop and report every document with the same value as an earlier one; documents without the field count as having a null value, as with a unique index"`
+
+	// Show the documents as a timeline of oplog entries
+	Oplog bool `long:"oplog" description:"read the input as oplog entries, e.g. the oplog.bson of mongodump --oplog, and output a tab-separated timeline with the ts, operation, namespace, o and o2 (cut short), txnNumber and lsid of each, with the operations of each applyOps transaction listed under it"`
+
+	// Infer a $jsonSchema of the documents
+	InferSchema bool `long:"inferSchema" description:"output a draft $jsonSchema validator of the documents instead of the documents, e.g. for collMod, with the types of every field and their frequencies, the ranges of numbers and of the lengths of strings and arrays, and the fields that at least --requiredThreshold of the documents have as required"`
+
+	// Share of the documents that must have a field for --inferSchema to require it
+	RequiredThreshold float64 `long:"requiredThreshold" value-name:"<fraction>" default:"1" default-mask:"-" description:"share of the documents, from 0 to 1, that must have a field for --inferSchema to require it (default: 1, i.e. every document)"`
+
+	// Bytes of documents to sort in memory
+	SortMemoryBytes int `long:"sortMemoryBytes" value-name:"<bytes>" description:"bytes of documents to sort in memory before spilling them to temporary files in --tempDir, for --sortBy and --uniqueBy (default 64MB)"`
+
+	// Sanitizes the values of fields before they are output.
+	Redact     []string `long:"redact" value-name:"<field>=<rule>" description:"sanitize the values of a (dotted) field, including those in arrays along it, before they are output, with one of the rules: hash (replace them by the hex HMAC-SHA256 of their type and bytes, so equal values still match), mask (replace every character of a string by *, and other values by null), drop (remove the field). May be specified multiple times, e.g. --redact email=hash --redact ssn=mask --redact notes=drop"`
+	RedactSalt string   `long:"redactSalt" value-name:"<string>" description:"key of the HMAC of --redact=<field>=hash, without which hashes of guessable values, like email addresses, can be reversed by hashing guesses"`
 }
 
 func (*OutputOptions) Name() string {
@@ -60,7 +116,7 @@ func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, erro
 		gitCommit,
 		Usage,
 		false,
-		options.EnabledOptions{},
+		options.EnabledOptions{TempFiles: true},
 	)
 	outputOpts := &OutputOptions{}
 	toolOpts.AddOptions(outputOpts)
@@ -85,15 +141,55 @@ func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, erro
 		outputOpts.BSONFileName = args[0]
 	}
 
+	if err := outputOpts.validateSlice(); err != nil {
+		return Options{}, err
+	}
+	if err := outputOpts.validateSort(); err != nil {
+		return Options{}, err
+	}
+	if err := outputOpts.validateOplog(); err != nil {
+		return Options{}, err
+	}
+	if err := outputOpts.validateInferSchema(); err != nil {
+		return Options{}, err
+	}
+	if err := outputOpts.validateRedact(); err != nil {
+		return Options{}, err
+	}
+	if err := outputOpts.validateArchive(); err != nil {
+		return Options{}, err
+	}
+
 	switch outputOpts.Type {
-	case "", DebugOutputType, JSONOutputType:
+	case "", DebugOutputType, JSONOutputType, BSONOutputType:
+		if outputOpts.Fields != "" || outputOpts.FieldFile != "" {
+			return Options{}, fmt.Errorf("--fields and --fieldFile can only be used with --type=csv")
+		}
+		return Options{toolOpts, outputOpts}, nil
+	case CSVOutputType:
+		if outputOpts.Fields == "" && outputOpts.FieldFile == "" {
+			return Options{}, fmt.Errorf("--type=csv requires --fields or --fieldFile")
+		}
+		if outputOpts.Fields != "" && outputOpts.FieldFile != "" {
+			return Options{}, fmt.Errorf("cannot specify both --fields and --fieldFile")
+		}
 		return Options{toolOpts, outputOpts}, nil
 	default:
 		return Options{}, fmt.Errorf(
-			"unsupported output type '%v'. Must be either '%v' or '%v'",
+			"unsupported output type '%v'. Must be one of '%v', '%v', '%v' or '%v'",
+			outputOpts.Type,
 			DebugOutputType,
 			JSONOutputType,
-			outputOpts.Type,
+			CSVOutputType,
+			BSONOutputType,
 		)
 	}
 }
+
+// GetFields returns the fields to output with --type=csv.
+func (oo *OutputOptions) GetFields() ([]string, error) {
+	if oo.FieldFile != "" {
+		return util.GetFieldsFromFile(oo.FieldFile)
+	}
+	return strings.Split(oo.Fields, ","), nil
+}
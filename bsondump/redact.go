@@ -0,0 +1,194 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package bsondump
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Rules of --redact.
+const (
+	redactHash = "hash"
+	redactMask = "mask"
+	redactDrop = "drop"
+)
+
+// fieldRedaction applies a rule of --redact to the values of a field.
+type fieldRedaction struct {
+	path []string
+	rule string
+}
+
+// redactor sanitizes the documents of the input with the rules of --redact,
+// before they are written in any output format. The rules apply to every
+// value at their field path, including the values in arrays along it:
+//
+//   - hash replaces a value by the hex HMAC-SHA256 of its BSON type and
+//     bytes, keyed by --redactSalt, so that equal values still match, e.g.
+//     to join collections on a hashed field.
+//   - mask replaces every character of a string by *, keeping its length,
+//     and every other value by null. The values in a subdocument are masked
+//     one by one.
+//   - drop removes the field.
+type redactor struct {
+	redactions []fieldRedaction
+	salt       []byte
+}
+
+// validateRedact checks the rules of --redact.
+func (oo *OutputOptions) validateRedact() error {
+	if len(oo.Redact) == 0 {
+		if oo.RedactSalt != "" {
+			return fmt.Errorf("cannot use --redactSalt without --redact")
+		}
+		return nil
+	}
+	if oo.Oplog {
+		return fmt.Errorf("--redact cannot be used with --oplog")
+	}
+	_, err := newRedactor(oo.Redact, oo.RedactSalt)
+	return err
+}
+
+// newRedactor parses the <field>=<rule> specs of --redact, or returns nil if
+// there are none.
+func newRedactor(specs []string, salt string) (*redactor, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	r := &redactor{salt: []byte(salt)}
+	seen := map[string]bool{}
+	for _, spec := range specs {
+		field, rule, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --redact %q: expected <field>=<rule>", spec)
+		}
+		switch rule {
+		case redactHash, redactMask, redactDrop:
+		default:
+			return nil, fmt.Errorf(
+				"invalid --redact rule %q for %v: must be one of %v, %v or %v",
+				rule, field, redactHash, redactMask, redactDrop,
+			)
+		}
+		path := strings.Split(field, ".")
+		for _, part := range path {
+			if part == "" {
+				return nil, fmt.Errorf("invalid --redact field %q", field)
+			}
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("--redact is given more than one rule for %v", field)
+		}
+		seen[field] = true
+		r.redactions = append(r.redactions, fieldRedaction{path: path, rule: rule})
+	}
+	return r, nil
+}
+
+// redact returns doc with the rules applied.
+func (r *redactor) redact(doc bson.Raw) (bson.Raw, error) {
+	var d bson.D
+	if err := bson.Unmarshal(doc, &d); err != nil {
+		return nil, fmt.Errorf("error redacting document: %v", err)
+	}
+	for _, redaction := range r.redactions {
+		var err error
+		if d, err = r.redactPath(d, redaction.path, redaction.rule); err != nil {
+			return nil, err
+		}
+	}
+	return bson.Marshal(d)
+}
+
+// redactPath applies rule to the value at path in doc, or to every element of
+// the arrays along it, and returns the redacted doc.
+func (r *redactor) redactPath(doc bson.D, path []string, rule string) (bson.D, error) {
+	for i := range doc {
+		if doc[i].Key != path[0] {
+			continue
+		}
+		if len(path) > 1 {
+			value, err := r.redactNested(doc[i].Value, path[1:], rule)
+			doc[i].Value = value
+			return doc, err
+		}
+		if rule == redactDrop {
+			return append(doc[:i], doc[i+1:]...), nil
+		}
+		value, err := r.redactValue(doc[i].Value, rule)
+		doc[i].Value = value
+		return doc, err
+	}
+	return doc, nil
+}
+
+// redactNested follows the rest of a path into a subdocument or array.
+func (r *redactor) redactNested(
+	value interface{},
+	path []string,
+	rule string,
+) (interface{}, error) {
+	switch v := value.(type) {
+	case bson.D:
+		return r.redactPath(v, path, rule)
+	case bson.A:
+		for i := range v {
+			var err error
+			if v[i], err = r.redactNested(v[i], path, rule); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+// redactValue returns the hashed or masked value, or the values in an array
+// each hashed or masked.
+func (r *redactor) redactValue(value interface{}, rule string) (interface{}, error) {
+	if arr, ok := value.(bson.A); ok {
+		for i := range arr {
+			var err error
+			if arr[i], err = r.redactValue(arr[i], rule); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	}
+	if rule == redactMask {
+		switch v := value.(type) {
+		case string:
+			return strings.Repeat("*", utf8.RuneCountInString(v)), nil
+		case bson.D:
+			for i := range v {
+				var err error
+				if v[i].Value, err = r.redactValue(v[i].Value, rule); err != nil {
+					return nil, err
+				}
+			}
+			return v, nil
+		}
+		return nil, nil
+	}
+
+	t, data, err := bson.MarshalValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing value: %v", err)
+	}
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write([]byte{byte(t)})
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
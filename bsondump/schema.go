@@ -0,0 +1,337 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package bsondump
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// bsonTypeAliases are the names of the BSON types in the bsonType keyword of
+// $jsonSchema.
+var bsonTypeAliases = map[bsontype.Type]string{
+	bson.TypeDouble:           "double",
+	bson.TypeString:           "string",
+	bson.TypeEmbeddedDocument: "object",
+	bson.TypeArray:            "array",
+	bson.TypeBinary:           "binData",
+	bson.TypeUndefined:        "undefined",
+	bson.TypeObjectID:         "objectId",
+	bson.TypeBoolean:          "bool",
+	bson.TypeDateTime:         "date",
+	bson.TypeNull:             "null",
+	bson.TypeRegex:            "regex",
+	bson.TypeDBPointer:        "dbPointer",
+	bson.TypeJavaScript:       "javascript",
+	bson.TypeSymbol:           "symbol",
+	bson.TypeCodeWithScope:    "javascriptWithScope",
+	bson.TypeInt32:            "int",
+	bson.TypeTimestamp:        "timestamp",
+	bson.TypeInt64:            "long",
+	bson.TypeDecimal128:       "decimal",
+	bson.TypeMinKey:           "minKey",
+	bson.TypeMaxKey:           "maxKey",
+}
+
+// validateInferSchema checks that --inferSchema is not combined with another
+// output.
+func (oo *OutputOptions) validateInferSchema() error {
+	if !oo.InferSchema {
+		if oo.RequiredThreshold != 1 {
+			return fmt.Errorf("--requiredThreshold can only be used with --inferSchema")
+		}
+		return nil
+	}
+	switch {
+	case oo.Type != "" && oo.Type != JSONOutputType:
+		return fmt.Errorf("--inferSchema cannot be used with --type=%v", oo.Type)
+	case oo.Oplog:
+		return fmt.Errorf("--inferSchema cannot be used with --oplog")
+	case oo.RequiredThreshold <= 0 || oo.RequiredThreshold > 1:
+		return fmt.Errorf("--requiredThreshold must be more than 0 and at most 1")
+	}
+	return nil
+}
+
+// schemaNode gathers the values seen at one path of the documents: the field
+// of a document, or the elements of the arrays of a field.
+type schemaNode struct {
+	count int
+	types map[bsontype.Type]int
+
+	// the ranges of the int and long values, and of the double values
+	integers       int
+	intMin, intMax int64
+	doubles        int
+	min, max       float64
+	// the range of the lengths of the strings, in characters
+	strings        int
+	minLen, maxLen int
+	// the range of the lengths of the arrays, and their elements
+	arrays             int
+	minItems, maxItems int
+	items              *schemaNode
+	// the fields of the embedded documents, in the order they were first seen
+	objects int
+	fields  map[string]*schemaNode
+	order   []string
+}
+
+func newSchemaNode() *schemaNode {
+	return &schemaNode{types: map[bsontype.Type]int{}}
+}
+
+// addDocument adds the fields of doc to the node of an embedded document.
+func (n *schemaNode) addDocument(doc bson.Raw) error {
+	elements, err := doc.Elements()
+	if err != nil {
+		return err
+	}
+	n.objects++
+	if n.fields == nil {
+		n.fields = map[string]*schemaNode{}
+	}
+	for _, elem := range elements {
+		key := elem.Key()
+		field, ok := n.fields[key]
+		if !ok {
+			field = newSchemaNode()
+			n.fields[key] = field
+			n.order = append(n.order, key)
+		}
+		if err := field.add(elem.Value()); err != nil {
+			return fmt.Errorf("%v: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// add adds a value seen at the path of n.
+func (n *schemaNode) add(value bson.RawValue) error {
+	n.count++
+	n.types[value.Type]++
+	switch value.Type {
+	case bson.TypeInt32, bson.TypeInt64:
+		i, _ := value.AsInt64OK()
+		if n.integers == 0 || i < n.intMin {
+			n.intMin = i
+		}
+		if n.integers == 0 || i > n.intMax {
+			n.intMax = i
+		}
+		n.integers++
+	case bson.TypeDouble:
+		f := value.Double()
+		if math.IsNaN(f) {
+			// no range includes it
+			return nil
+		}
+		if n.doubles == 0 || f < n.min {
+			n.min = f
+		}
+		if n.doubles == 0 || f > n.max {
+			n.max = f
+		}
+		n.doubles++
+	case bson.TypeString:
+		length := utf8.RuneCountInString(value.StringValue())
+		if n.strings == 0 || length < n.minLen {
+			n.minLen = length
+		}
+		if n.strings == 0 || length > n.maxLen {
+			n.maxLen = length
+		}
+		n.strings++
+	case bson.TypeArray:
+		values, err := value.Array().Values()
+		if err != nil {
+			return err
+		}
+		if n.arrays == 0 || len(values) < n.minItems {
+			n.minItems = len(values)
+		}
+		if n.arrays == 0 || len(values) > n.maxItems {
+			n.maxItems = len(values)
+		}
+		n.arrays++
+		if n.items == nil {
+			n.items = newSchemaNode()
+		}
+		for _, v := range values {
+			if err := n.items.add(v); err != nil {
+				return err
+			}
+		}
+	case bson.TypeEmbeddedDocument:
+		return n.addDocument(value.Document())
+	}
+	return nil
+}
+
+// schema returns the $jsonSchema of the values of n. The type frequencies go
+// in the description, as servers reject unknown keywords.
+func (n *schemaNode) schema(threshold float64) bson.D {
+	var types []bsontype.Type
+	for t := range n.types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if n.types[types[i]] != n.types[types[j]] {
+			return n.types[types[i]] > n.types[types[j]]
+		}
+		return bsonTypeAliases[types[i]] < bsonTypeAliases[types[j]]
+	})
+	aliases := make(bson.A, len(types))
+	frequencies := make([]string, len(types))
+	for i, t := range types {
+		aliases[i] = bsonTypeAliases[t]
+		frequencies[i] = fmt.Sprintf("%v %v", bsonTypeAliases[t], percent(n.types[t], n.count))
+	}
+
+	var schema bson.D
+	if len(aliases) == 1 {
+		schema = append(schema, bson.E{"bsonType", aliases[0]})
+	} else {
+		schema = append(schema, bson.E{"bsonType", aliases})
+	}
+	schema = append(schema, bson.E{"description", strings.Join(frequencies, ", ")})
+	if minimum, maximum, ok := n.numberRange(); ok {
+		schema = append(schema, bson.E{"minimum", minimum}, bson.E{"maximum", maximum})
+	}
+	if n.strings > 0 {
+		schema = append(schema, bson.E{"minLength", n.minLen}, bson.E{"maxLength", n.maxLen})
+	}
+	if n.arrays > 0 {
+		schema = append(schema, bson.E{"minItems", n.minItems}, bson.E{"maxItems", n.maxItems})
+		if n.items.count > 0 {
+			schema = append(schema, bson.E{"items", n.items.schema(threshold)})
+		}
+	}
+	if n.objects > 0 {
+		schema = append(schema, n.objectSchema(threshold)...)
+	}
+	return schema
+}
+
+// numberRange returns the range of the numbers of n, exactly if they are all
+// integers.
+func (n *schemaNode) numberRange() (interface{}, interface{}, bool) {
+	switch {
+	case n.doubles == 0 && n.integers == 0:
+		return nil, nil, false
+	case n.doubles == 0:
+		return n.intMin, n.intMax, true
+	case n.integers == 0:
+		if math.IsInf(n.min, -1) || math.IsInf(n.max, 1) {
+			return nil, nil, false
+		}
+		return n.min, n.max, true
+	}
+	minimum := math.Min(n.min, float64(n.intMin))
+	maximum := math.Max(n.max, float64(n.intMax))
+	if math.IsInf(minimum, -1) || math.IsInf(maximum, 1) {
+		return nil, nil, false
+	}
+	return minimum, maximum, true
+}
+
+// objectSchema returns the required fields and the properties of the
+// embedded documents of n. A field is required if at least threshold of the
+// documents have it.
+func (n *schemaNode) objectSchema(threshold float64) bson.D {
+	required := bson.A{}
+	properties := bson.D{}
+	for _, key := range n.order {
+		field := n.fields[key]
+		if float64(field.count) >= threshold*float64(n.objects) {
+			required = append(required, key)
+		}
+		schema := field.schema(threshold)
+		schema[1].Value = fmt.Sprintf(
+			"in %v of %v documents: %v",
+			percent(field.count, n.objects),
+			n.objects,
+			schema[1].Value,
+		)
+		properties = append(properties, bson.E{key, schema})
+	}
+	var schema bson.D
+	if len(required) > 0 {
+		schema = append(schema, bson.E{"required", required})
+	}
+	return append(schema, bson.E{"properties", properties})
+}
+
+func percent(count, total int) string {
+	return fmt.Sprintf("%.4g%%", 100*float64(count)/float64(total))
+}
+
+// InferSchema iterates through the BSON file and writes a draft $jsonSchema
+// validator of its documents, e.g. for the validator of a collMod command. It
+// describes the types of every field with their frequencies, requires the
+// fields that at least --requiredThreshold of the documents have, and gives
+// the ranges of the numbers and of the lengths of the strings and arrays.
+// It returns the number of documents processed and a non-nil error if one is
+// encountered before the end of the file is reached.
+func (bd *BSONDump) InferSchema() (int, error) {
+	numFound := 0
+
+	if bd.InputSource == nil {
+		panic("Tried to call InferSchema() before opening file")
+	}
+
+	root := newSchemaNode()
+	for {
+		result := bson.Raw(bd.loadNext())
+		if result == nil {
+			break
+		}
+
+		if err := root.addDocument(result); err != nil {
+			log.Logvf(log.Always, "unable to read document %v: %v", numFound+1, err)
+
+			//if objcheck is turned on, stop now. otherwise keep on going
+			if bd.OutputOptions.ObjCheck {
+				return numFound, err
+			}
+		}
+		numFound++
+	}
+	if err := bd.inputErr(); err != nil {
+		return numFound, err
+	}
+	if root.objects == 0 {
+		return numFound, fmt.Errorf("cannot infer a schema without documents")
+	}
+
+	schema := append(
+		bson.D{{"bsonType", "object"}},
+		root.objectSchema(bd.OutputOptions.RequiredThreshold)...,
+	)
+	extendedJSON, err := bson.MarshalExtJSON(bson.D{{"$jsonSchema", schema}}, false, false)
+	if err != nil {
+		return numFound, fmt.Errorf("error converting the schema to extended JSON: %v", err)
+	}
+	if bd.OutputOptions.Pretty {
+		var jsonFormatted bytes.Buffer
+		if err := json.Indent(&jsonFormatted, extendedJSON, "", "\t"); err != nil {
+			return numFound, fmt.Errorf("error prettifying extended JSON: %v", err)
+		}
+		extendedJSON = jsonFormatted.Bytes()
+	}
+	_, err = bd.OutputWriter.Write(append(extendedJSON, '\n'))
+	return numFound, err
+}
@@ -0,0 +1,183 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package bsondump
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// minBSONSize is the size of an empty document.
+const minBSONSize = 5
+
+// validateSlice checks the options that select a window of the input.
+func (oo *OutputOptions) validateSlice() error {
+	for _, opt := range []struct {
+		name  string
+		value int64
+	}{
+		{"--startOffset", oo.StartOffset},
+		{"--maxBytes", oo.MaxBytes},
+		{"--startDoc", oo.StartDoc},
+		{"--numDocs", oo.NumDocs},
+	} {
+		if opt.value < 0 {
+			return fmt.Errorf("%v must not be negative, got %v", opt.name, opt.value)
+		}
+	}
+	return nil
+}
+
+// readCloser reads from a buffered reader over a file, and closes the file.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// seekToDocument moves the input past offset bytes, then to the next
+// document boundary. The returned reader starts with that document, and
+// skipped is the number of bytes between offset and it.
+func seekToDocument(
+	input io.ReadCloser,
+	offset int64,
+	maxBSONSize int,
+) (reader io.ReadCloser, skipped int64, err error) {
+	if seeker, ok := input.(io.Seeker); ok {
+		_, err = seeker.Seek(offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(io.Discard, input, offset)
+	}
+	if err == io.EOF {
+		return nil, 0, fmt.Errorf("--startOffset %v is past the end of the input", offset)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("error moving to --startOffset %v: %v", offset, err)
+	}
+
+	// the buffer holds the largest document and the size of the next one
+	buffered := bufio.NewReaderSize(input, maxBSONSize+4)
+	skipped, err = resync(buffered, maxBSONSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error finding a document after --startOffset %v: %v", offset, err)
+	}
+	if skipped > 0 {
+		log.Logvf(
+			log.Always,
+			"--startOffset %v is inside a document; skipped %v bytes to the next document at offset %v",
+			offset,
+			skipped,
+			offset+skipped,
+		)
+	}
+	return readCloser{buffered, input}, skipped, nil
+}
+
+// resync discards bytes until the reader is at the start of a document,
+// and returns how many bytes it discarded. A position is taken as a
+// document boundary if a valid document starts there and is followed by the
+// end of the input or by a plausible document size. Running out of input is
+// not an error; the reader is simply left empty.
+func resync(r *bufio.Reader, maxBSONSize int) (int64, error) {
+	var skipped int64
+	for {
+		if isDocumentBoundary(r, maxBSONSize) {
+			return skipped, nil
+		}
+		if _, err := r.ReadByte(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return skipped, nil
+			}
+			return skipped, err
+		}
+		skipped++
+	}
+}
+
+func isDocumentBoundary(r *bufio.Reader, maxBSONSize int) bool {
+	header, err := r.Peek(4)
+	if err != nil {
+		return false
+	}
+	size := int(int32(binary.LittleEndian.Uint32(header)))
+	if size < minBSONSize || size > maxBSONSize {
+		return false
+	}
+	buf, _ := r.Peek(size + 4)
+	switch {
+	case len(buf) < size:
+		return false
+	case len(buf) == size:
+		// the document ends the input
+	case len(buf) < size+4:
+		// a partial size cannot follow a complete document
+		return false
+	default:
+		next := int(int32(binary.LittleEndian.Uint32(buf[size:])))
+		if next < minBSONSize || next > maxBSONSize {
+			return false
+		}
+	}
+	return buf[size-1] == 0 && bson.Raw(buf[:size]).Validate() == nil
+}
+
+// loadNext returns the next document to dump, or nil after the last one.
+func (bd *BSONDump) loadNext() []byte {
+	var doc []byte
+	if bd.OutputOptions.sortField() != "" {
+		doc = bd.loadSorted()
+	} else {
+		doc = bd.loadWindowed()
+	}
+	if doc == nil || bd.redactor == nil {
+		return doc
+	}
+	// a document that can't be redacted ends the input, so that it is
+	// never output as it is
+	redacted, err := bd.redactor.redact(doc)
+	if err != nil {
+		bd.redactErr = fmt.Errorf("document %v: %v", bd.docsDumped, err)
+		return nil
+	}
+	return redacted
+}
+
+// loadWindowed returns the next document of the input, or nil once the input
+// or the window selected by --maxBytes and --numDocs is exhausted. Documents
+// before --startDoc are read and dropped.
+func (bd *BSONDump) loadWindowed() []byte {
+	oo := bd.OutputOptions
+	for {
+		if oo.NumDocs > 0 && bd.docsDumped >= oo.NumDocs {
+			return nil
+		}
+		doc := bd.InputSource.LoadNext()
+		if doc == nil {
+			return nil
+		}
+		if oo.MaxBytes > 0 && bd.bytesRead+int64(len(doc)) > oo.MaxBytes {
+			log.Logvf(
+				log.DebugLow,
+				"stopping before the document at offset %v, which ends past --maxBytes",
+				oo.StartOffset+bd.bytesRead,
+			)
+			return nil
+		}
+		bd.bytesRead += int64(len(doc))
+		if bd.docsSkipped < oo.StartDoc {
+			bd.docsSkipped++
+			continue
+		}
+		bd.docsDumped++
+		return doc
+	}
+}
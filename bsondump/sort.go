@@ -0,0 +1,491 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package bsondump
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/tempfile"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// defaultSortMemoryBytes is how many bytes of documents --sortBy holds in
+// memory before spilling them to a temporary file.
+const defaultSortMemoryBytes = 64 * 1024 * 1024
+
+// validateSort checks the options that sort and deduplicate the output.
+func (oo *OutputOptions) validateSort() error {
+	if oo.SortMemoryBytes < 0 {
+		return fmt.Errorf("--sortMemoryBytes must not be negative, got %v", oo.SortMemoryBytes)
+	}
+	if oo.UniqueBy != "" && oo.SortBy != "" && oo.SortBy != oo.UniqueBy {
+		return fmt.Errorf("--uniqueBy sorts by its field, so it cannot be used with another --sortBy")
+	}
+	return nil
+}
+
+// sortField returns the field the output is sorted by, if any.
+func (oo *OutputOptions) sortField() string {
+	if oo.UniqueBy != "" {
+		return oo.UniqueBy
+	}
+	return oo.SortBy
+}
+
+// sortKey returns the value of a dotted field in doc. A missing field sorts
+// like null, as it does in the server.
+func sortKey(doc bson.Raw, field string) bson.RawValue {
+	value, err := doc.LookupErr(strings.Split(field, ".")...)
+	if err != nil {
+		return bson.RawValue{Type: bson.TypeNull}
+	}
+	return value
+}
+
+// externalSort sorts the documents of the input by a field with a bounded
+// amount of memory. Documents are buffered up to a number of bytes, then
+// sorted and spilled to a temporary file; the spilled runs are then merged.
+// Documents with equal keys keep the order of the input.
+type externalSort struct {
+	field     string
+	maxMemory int
+
+	docs     []bson.Raw
+	docBytes int
+
+	files *tempfile.Manager
+	runs  []*tempfile.File
+
+	merger *runMerger
+}
+
+func newExternalSort(field string, maxMemory int, files *tempfile.Manager) *externalSort {
+	if maxMemory <= 0 {
+		maxMemory = defaultSortMemoryBytes
+	}
+	return &externalSort{field: field, maxMemory: maxMemory, files: files}
+}
+
+// add adds a copy of doc to the documents to sort.
+func (s *externalSort) add(doc []byte) error {
+	s.docs = append(s.docs, append(bson.Raw(nil), doc...))
+	s.docBytes += len(doc)
+	if s.docBytes < s.maxMemory {
+		return nil
+	}
+	return s.spill()
+}
+
+func (s *externalSort) sortDocs() {
+	sort.SliceStable(s.docs, func(i, j int) bool {
+		return compareValues(sortKey(s.docs[i], s.field), sortKey(s.docs[j], s.field)) < 0
+	})
+}
+
+// spill writes the buffered documents, sorted, to a new run.
+func (s *externalSort) spill() error {
+	file, err := s.files.Create("bsondump-sort-*.bson")
+	if err != nil {
+		return fmt.Errorf("error creating a sort run: %v", err)
+	}
+	s.runs = append(s.runs, file)
+
+	s.sortDocs()
+	w := bufio.NewWriter(file)
+	for _, doc := range s.docs {
+		if _, err := w.Write(doc); err != nil {
+			return fmt.Errorf("error writing a sort run: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing a sort run: %v", err)
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return fmt.Errorf("error reading a sort run: %v", err)
+	}
+	log.Logvf(log.DebugLow, "spilled %v sorted documents to %v", len(s.docs), file.Name())
+	s.docs = nil
+	s.docBytes = 0
+	return nil
+}
+
+// finish is called once every document is added, and prepares next to
+// return them in order.
+func (s *externalSort) finish() error {
+	if len(s.runs) == 0 {
+		s.sortDocs()
+		return nil
+	}
+	if len(s.docs) > 0 {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	sources := make([]*db.BSONSource, len(s.runs))
+	for i, run := range s.runs {
+		sources[i] = db.NewBufferlessBSONSource(run)
+	}
+	merger, err := newRunMerger(s.field, sources)
+	if err != nil {
+		return err
+	}
+	s.merger = merger
+	return nil
+}
+
+// next returns the next document in order, or nil after the last one.
+func (s *externalSort) next() (bson.Raw, error) {
+	if s.merger != nil {
+		return s.merger.next()
+	}
+	if len(s.docs) == 0 {
+		return nil, nil
+	}
+	doc := s.docs[0]
+	s.docs = s.docs[1:]
+	return doc, nil
+}
+
+// close removes the temporary files of the sort.
+func (s *externalSort) close() error {
+	for _, run := range s.runs {
+		_ = run.Remove()
+	}
+	return s.files.Cleanup()
+}
+
+// runMerger merges sorted runs. Runs hold the input in order, so ties are
+// broken by the index of the run to keep the sort stable.
+type runMerger struct {
+	field   string
+	sources []*db.BSONSource
+	heads   []runHead
+}
+
+type runHead struct {
+	doc bson.Raw
+	key bson.RawValue
+	run int
+}
+
+func newRunMerger(field string, sources []*db.BSONSource) (*runMerger, error) {
+	m := &runMerger{field: field, sources: sources}
+	for i := range sources {
+		head, ok, err := m.read(i)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m.heads = append(m.heads, head)
+		}
+	}
+	heap.Init(m)
+	return m, nil
+}
+
+// read returns the next document of run, if it has one.
+func (m *runMerger) read(run int) (runHead, bool, error) {
+	doc := m.sources[run].LoadNext()
+	if doc == nil {
+		if err := m.sources[run].Err(); err != nil {
+			return runHead{}, false, fmt.Errorf("error reading a sort run: %v", err)
+		}
+		return runHead{}, false, nil
+	}
+	return runHead{doc, sortKey(doc, m.field), run}, true, nil
+}
+
+func (m *runMerger) next() (bson.Raw, error) {
+	if len(m.heads) == 0 {
+		return nil, nil
+	}
+	head := heap.Pop(m).(runHead)
+	next, ok, err := m.read(head.run)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		heap.Push(m, next)
+	}
+	return head.doc, nil
+}
+
+func (m *runMerger) Len() int { return len(m.heads) }
+
+func (m *runMerger) Less(i, j int) bool {
+	if c := compareValues(m.heads[i].key, m.heads[j].key); c != 0 {
+		return c < 0
+	}
+	return m.heads[i].run < m.heads[j].run
+}
+
+func (m *runMerger) Swap(i, j int) { m.heads[i], m.heads[j] = m.heads[j], m.heads[i] }
+
+func (m *runMerger) Push(x interface{}) { m.heads = append(m.heads, x.(runHead)) }
+
+func (m *runMerger) Pop() interface{} {
+	last := m.heads[len(m.heads)-1]
+	m.heads = m.heads[:len(m.heads)-1]
+	return last
+}
+
+// typeRank returns the place of a BSON type in the order the server sorts
+// values of different types in.
+func typeRank(t bsontype.Type) int {
+	switch t {
+	case bson.TypeMinKey:
+		return 0
+	case bson.TypeNull, bson.TypeUndefined:
+		return 1
+	case bson.TypeInt32, bson.TypeInt64, bson.TypeDouble, bson.TypeDecimal128:
+		return 2
+	case bson.TypeString, bson.TypeSymbol:
+		return 3
+	case bson.TypeEmbeddedDocument:
+		return 4
+	case bson.TypeArray:
+		return 5
+	case bson.TypeBinary:
+		return 6
+	case bson.TypeObjectID:
+		return 7
+	case bson.TypeBoolean:
+		return 8
+	case bson.TypeDateTime:
+		return 9
+	case bson.TypeTimestamp:
+		return 10
+	case bson.TypeRegex:
+		return 11
+	case bson.TypeMaxKey:
+		return 13
+	default:
+		return 12
+	}
+}
+
+// compareValues compares two BSON values in the order the server sorts
+// them, except that arrays compare element by element rather than by their
+// smallest element.
+func compareValues(a, b bson.RawValue) int {
+	if c := compareInts(typeRank(a.Type), typeRank(b.Type)); c != 0 {
+		return c
+	}
+	switch a.Type {
+	case bson.TypeMinKey, bson.TypeMaxKey, bson.TypeNull, bson.TypeUndefined:
+		return 0
+	case bson.TypeInt32, bson.TypeInt64, bson.TypeDouble, bson.TypeDecimal128:
+		return compareNumbers(a, b)
+	case bson.TypeString, bson.TypeSymbol:
+		return strings.Compare(stringValue(a), stringValue(b))
+	case bson.TypeEmbeddedDocument, bson.TypeArray:
+		return compareDocuments(a.Value, b.Value)
+	case bson.TypeBinary:
+		subtypeA, dataA := a.Binary()
+		subtypeB, dataB := b.Binary()
+		if c := compareInts(len(dataA), len(dataB)); c != 0 {
+			return c
+		}
+		if c := compareInts(int(subtypeA), int(subtypeB)); c != 0 {
+			return c
+		}
+		return bytes.Compare(dataA, dataB)
+	case bson.TypeBoolean:
+		return compareInts(btoi(a.Boolean()), btoi(b.Boolean()))
+	case bson.TypeDateTime:
+		return compareInt64s(a.DateTime(), b.DateTime())
+	case bson.TypeTimestamp:
+		tA, iA := a.Timestamp()
+		tB, iB := b.Timestamp()
+		if c := compareInt64s(int64(tA), int64(tB)); c != 0 {
+			return c
+		}
+		return compareInt64s(int64(iA), int64(iB))
+	case bson.TypeRegex:
+		patternA, optionsA := a.Regex()
+		patternB, optionsB := b.Regex()
+		if c := strings.Compare(patternA, patternB); c != 0 {
+			return c
+		}
+		return strings.Compare(optionsA, optionsB)
+	default:
+		// object ids, and types the server doesn't sort meaningfully
+		if c := compareInts(int(a.Type), int(b.Type)); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.Value, b.Value)
+	}
+}
+
+// compareDocuments compares documents, or arrays, element by element: by
+// type, then field name, then value.
+func compareDocuments(a, b bson.Raw) int {
+	elementsA, _ := a.Elements()
+	elementsB, _ := b.Elements()
+	for i := 0; i < len(elementsA) && i < len(elementsB); i++ {
+		valueA, valueB := elementsA[i].Value(), elementsB[i].Value()
+		if c := compareInts(typeRank(valueA.Type), typeRank(valueB.Type)); c != 0 {
+			return c
+		}
+		if c := strings.Compare(elementsA[i].Key(), elementsB[i].Key()); c != 0 {
+			return c
+		}
+		if c := compareValues(valueA, valueB); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(elementsA), len(elementsB))
+}
+
+// compareNumbers compares numbers of any type. Integers compare exactly;
+// other numbers compare as doubles, with NaN below every other number.
+func compareNumbers(a, b bson.RawValue) int {
+	intA, okA := a.AsInt64OK()
+	intB, okB := b.AsInt64OK()
+	if okA && okB && a.Type != bson.TypeDouble && b.Type != bson.TypeDouble {
+		return compareInt64s(intA, intB)
+	}
+	floatA, floatB := floatValue(a), floatValue(b)
+	switch nanA, nanB := math.IsNaN(floatA), math.IsNaN(floatB); {
+	case nanA && nanB:
+		return 0
+	case nanA:
+		return -1
+	case nanB:
+		return 1
+	case floatA < floatB:
+		return -1
+	case floatA > floatB:
+		return 1
+	}
+	return 0
+}
+
+func floatValue(v bson.RawValue) float64 {
+	switch v.Type {
+	case bson.TypeDouble:
+		return v.Double()
+	case bson.TypeDecimal128:
+		f, err := strconv.ParseFloat(v.Decimal128().String(), 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return f
+	}
+	i, _ := v.AsInt64OK()
+	return float64(i)
+}
+
+func stringValue(v bson.RawValue) string {
+	if v.Type == bson.TypeSymbol {
+		return v.Symbol()
+	}
+	return v.StringValue()
+}
+
+func compareInts(a, b int) int {
+	return compareInt64s(int64(a), int64(b))
+}
+
+func compareInt64s(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// loadSorted returns the next document of the input in the order of
+// --sortBy or --uniqueBy, or nil after the last one. The whole input is read
+// and sorted on the first call.
+func (bd *BSONDump) loadSorted() []byte {
+	oo := bd.OutputOptions
+	field := oo.sortField()
+	if bd.sortErr != nil || bd.sortDone {
+		return nil
+	}
+	if bd.sorter == nil {
+		var tempFiles *options.TempFiles
+		if bd.ToolOptions != nil {
+			tempFiles = bd.ToolOptions.TempFiles
+		}
+		bd.sorter = newExternalSort(field, oo.SortMemoryBytes, tempFiles.Manager())
+		for doc := bd.loadWindowed(); doc != nil; doc = bd.loadWindowed() {
+			if bd.sortErr = bd.sorter.add(doc); bd.sortErr != nil {
+				return nil
+			}
+		}
+		if bd.InputSource.Err() != nil {
+			return nil
+		}
+		if bd.sortErr = bd.sorter.finish(); bd.sortErr != nil {
+			return nil
+		}
+	}
+
+	for {
+		var doc bson.Raw
+		doc, bd.sortErr = bd.sorter.next()
+		if bd.sortErr != nil {
+			return nil
+		}
+		if doc == nil {
+			bd.sortDone = true
+			if bd.duplicates > 0 {
+				log.Logvf(log.Always, "dropped %v %v with a duplicate %v",
+					bd.duplicates, util.Pluralize(int(bd.duplicates), "document", "documents"), field)
+			}
+			return nil
+		}
+		if oo.UniqueBy == "" {
+			return doc
+		}
+		key := sortKey(doc, field)
+		if bd.lastKey.Type == 0 || compareValues(key, bd.lastKey) != 0 {
+			bd.lastKey = key
+			return doc
+		}
+		bd.duplicates++
+		if id, err := doc.LookupErr("_id"); err == nil {
+			log.Logvf(log.Always, "dropping the document with _id %v: duplicate %v %v", id, field, key)
+		} else {
+			log.Logvf(log.Always, "dropping a document without an _id: duplicate %v %v", field, key)
+		}
+	}
+}
+
+// inputErr returns the error that ended the input, if any.
+func (bd *BSONDump) inputErr() error {
+	if bd.sortErr != nil {
+		return bd.sortErr
+	}
+	if bd.redactErr != nil {
+		return bd.redactErr
+	}
+	return bd.InputSource.Err()
+}
@@ -6,7 +6,34 @@
 
 package archive
 
-import "io"
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Archive layouts, recorded in the Header.
+const (
+	// InterleavedLayout archives mix the documents of the collections that
+	// were dumped concurrently.
+	InterleavedLayout = "interleaved"
+
+	// ContiguousLayout archives store all of the data of each collection in
+	// one range of the archive, located by the Offset and Length of its
+	// CollectionMetadata, so that readers of an archive file can seek past
+	// the collections they do not need.
+	ContiguousLayout = "contiguous"
+)
+
+// CRC32CChecksum is the Checksum recorded in the Header of archives whose
+// blocks have checksums and that end with a Trailer.
+const CRC32CChecksum = "crc32c"
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
 
 // NamespaceHeader is a data structure that, as BSON, is found in archives where it indicates
 // that either the subsequent stream of BSON belongs to this new namespace, or that the
@@ -16,6 +43,10 @@ type NamespaceHeader struct {
 	Collection string `bson:"collection"`
 	EOF        bool   `bson:"EOF"`
 	CRC        int64  `bson:"CRC"`
+
+	// BlockCRC is the CRC-32C of the documents of the block that the header
+	// starts, in archives with checksums.
+	BlockCRC *int64 `bson:"blockCRC,omitempty"`
 }
 
 // CollectionMetadata is a data structure that, as BSON, is found in the prelude of the archive.
@@ -29,6 +60,28 @@ type CollectionMetadata struct {
 	Metadata   string `bson:"metadata"`
 	Size       int    `bson:"size"`
 	Type       string `bson:"type"`
+
+	// Offset and Length locate the data of the collection in an archive with
+	// the contiguous layout. They are -1 for collections without data, and
+	// are not set in archives with the interleaved layout.
+	Offset int64 `bson:"offset,omitempty"`
+	Length int64 `bson:"length,omitempty"`
+}
+
+// dataNamespace returns the namespace that the collection's data is stored
+// under in the archive.
+func (cm *CollectionMetadata) dataNamespace() string {
+	if cm.Type == "timeseries" {
+		return cm.Database + ".system.buckets." + cm.Collection
+	}
+	return cm.Database + "." + cm.Collection
+}
+
+// BlockRange is the location of the data of a namespace in an archive with
+// the contiguous layout.
+type BlockRange struct {
+	Offset int64
+	Length int64
 }
 
 // Header is a data structure that, as BSON, is found immediately after the magic
@@ -38,6 +91,32 @@ type Header struct {
 	FormatVersion         string `bson:"version"`
 	ServerVersion         string `bson:"server_version"`
 	ToolVersion           string `bson:"tool_version"`
+	Layout                string `bson:"layout,omitempty"`
+	Checksum              string `bson:"checksum,omitempty"`
+
+	// the oplog captured with mongodump --oplog
+	Oplog *OplogRange `bson:"oplog,omitempty"`
+}
+
+// OplogRange holds the timestamps of the first and last oplog entries
+// captured with mongodump --oplog. The last one is only known at the end of
+// the dump, so it is only recorded in archive files, whose prelude is
+// rewritten then; until that, and in other archives, it is zero.
+type OplogRange struct {
+	Start primitive.Timestamp `bson:"start"`
+	End   primitive.Timestamp `bson:"end"`
+}
+
+// Trailer is a data structure that, as BSON, is the last block of an archive
+// with checksums. It holds the CRC-32C of the prelude, as last written, and
+// of everything written after it, so that a truncated archive, or one with
+// missing or damaged blocks, can be told from an intact one.
+type Trailer struct {
+	Trailer    bool  `bson:"trailer"`
+	PreludeCRC int64 `bson:"prelude_crc"`
+	DataCRC    int64 `bson:"data_crc"`
+	DataLength int64 `bson:"data_length"`
+	Blocks     int64 `bson:"blocks"`
 }
 
 const minBSONSize = 4 + 1 // an empty BSON document should be exactly five bytes long
@@ -57,6 +136,79 @@ type Writer struct {
 	Mux     *Multiplexer
 }
 
+// Seekable returns true if the archive is written to a file, whose prelude
+// can be rewritten.
+func (w *Writer) Seekable() bool {
+	_, ok := w.Out.(io.WriteSeeker)
+	return ok
+}
+
+// RewritePrelude writes the prelude again at the start of an archive file,
+// with the location of the data of each collection filled in for the
+// contiguous layout, and with the changes made to its Header since it was
+// written. It must be called after the multiplexer has completed, and before
+// Out is closed.
+func (w *Writer) RewritePrelude() error {
+	out, ok := w.Out.(io.WriteSeeker)
+	if !ok {
+		return fmt.Errorf("archives with the %v layout must be written to a file", ContiguousLayout)
+	}
+
+	// The offsets and lengths were written as -1 placeholders, and are
+	// int64s either way, and the header only changes fixed-size values, so
+	// the prelude must keep its size.
+	original := &bytes.Buffer{}
+	if err := w.Prelude.Write(original); err != nil {
+		return err
+	}
+	w.Prelude.setBlocks(w.Mux.Blocks())
+	rewritten := &bytes.Buffer{}
+	if err := w.Prelude.Write(rewritten); err != nil {
+		return err
+	}
+	if rewritten.Len() != original.Len() {
+		return fmt.Errorf(
+			"archive prelude changed size from %v to %v bytes",
+			original.Len(),
+			rewritten.Len(),
+		)
+	}
+
+	end, err := out.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := out.Write(rewritten.Bytes()); err != nil {
+		return err
+	}
+	_, err = out.Seek(end, io.SeekStart)
+	return err
+}
+
+// WriteTrailer ends an archive with checksums with its Trailer. It must be
+// called after the multiplexer has completed, and after RewritePrelude.
+func (w *Writer) WriteTrailer() error {
+	prelude := &bytes.Buffer{}
+	if err := w.Prelude.Write(prelude); err != nil {
+		return err
+	}
+	trailer := w.Mux.trailer()
+	trailer.PreludeCRC = int64(crc32.Checksum(prelude.Bytes(), crc32cTable))
+	buf, err := bson.Marshal(trailer)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Out.Write(buf); err != nil {
+		return err
+	}
+	_, err = w.Out.Write(terminatorBytes)
+	return err
+}
+
 // Reader is the top level object to contain information about archives in mongorestore.
 type Reader struct {
 	In      io.ReadCloser
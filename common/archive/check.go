@@ -0,0 +1,226 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package archive
+
+import (
+	"bufio"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CheckReport describes an archive read by Check.
+type CheckReport struct {
+	Header *Header
+	// Namespaces is the number of namespaces listed in the prelude.
+	Namespaces int
+	Blocks     int64
+	Documents  int64
+	Bytes      int64
+	// Checksums is set if the archive has block checksums and a trailer.
+	Checksums bool
+	// Problems describes everything found wrong with the archive, which is
+	// intact if there are none.
+	Problems []string
+}
+
+func (report *CheckReport) problem(format string, args ...interface{}) {
+	report.Problems = append(report.Problems, fmt.Sprintf(format, args...))
+}
+
+// Check reads a whole archive without restoring it, and verifies that every
+// block parses and that the CRC-64 of each namespace matches its EOF header.
+// In an archive with checksums, it also verifies the CRC-32C of each block, and
+// that the trailer matches the prelude and the blocks. A block that does not
+// parse is skipped, so that the blocks after it are still checked. Check only
+// returns an error if the prelude can't be read; any other problem is in the
+// report.
+func Check(in io.Reader) (*CheckReport, error) {
+	reader := &checkedReader{in: bufio.NewReader(in), hash: crc32.New(crc32cTable)}
+	prelude := &Prelude{}
+	if err := prelude.Read(reader); err != nil {
+		return nil, err
+	}
+	report := &CheckReport{
+		Header:     prelude.Header,
+		Namespaces: len(prelude.NamespaceMetadatas),
+		Checksums:  prelude.Header.Checksum != "",
+	}
+	if report.Checksums && prelude.Header.Checksum != CRC32CChecksum {
+		return nil, fmt.Errorf("unsupported archive checksum %q", prelude.Header.Checksum)
+	}
+	preludeCRC, preludeLength := int64(reader.hash.Sum32()), reader.n
+	reader.hash.Reset()
+
+	checker := &archiveChecker{
+		report:     report,
+		namespaces: map[string]hash.Hash64{},
+		closed:     map[string]bool{},
+		block:      crc32.New(crc32cTable),
+	}
+	parser := Parser{In: reader}
+	for {
+		start := reader.n
+		dataCRC := int64(reader.hash.Sum32())
+		err := parser.ReadBlock(checker)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			report.problem("block at byte %v: %v", start, err)
+			checker.current = ""
+			if _, err := parser.skipToTerminator(); err != nil {
+				break
+			}
+			continue
+		}
+		if checker.trailer == nil {
+			report.Blocks++
+			checker.endBlock(start)
+			continue
+		}
+
+		trailer := checker.trailer
+		if trailer.PreludeCRC != preludeCRC {
+			report.problem("the checksum of the prelude does not match the trailer")
+		}
+		if length := start - preludeLength; trailer.DataLength != length {
+			report.problem("%v bytes of blocks were read, but the trailer lists %v",
+				length, trailer.DataLength)
+		} else if trailer.DataCRC != dataCRC {
+			report.problem("the checksum of the blocks does not match the trailer")
+		}
+		if trailer.Blocks != report.Blocks {
+			report.problem("%v blocks were read, but the trailer lists %v",
+				report.Blocks, trailer.Blocks)
+		}
+		if _, err := reader.Read(make([]byte, 1)); err != io.EOF {
+			report.problem("the archive continues after its trailer at byte %v", reader.n-1)
+		}
+		break
+	}
+	if report.Checksums && checker.trailer == nil {
+		report.problem("the archive has no trailer, so it may be truncated")
+	}
+	for ns := range checker.namespaces {
+		report.problem("the archive ended before the EOF of namespace %v", ns)
+	}
+	report.Bytes = reader.n
+	return report, nil
+}
+
+// isTrailer returns true if the header of a block is a Trailer.
+func isTrailer(buf []byte) bool {
+	_, err := bson.Raw(buf).LookupErr("trailer")
+	return err == nil
+}
+
+// checkedReader keeps the CRC-32C of what was read.
+type checkedReader struct {
+	in   io.Reader
+	hash hash.Hash32
+	n    int64
+}
+
+func (r *checkedReader) Read(p []byte) (int, error) {
+	n, err := r.in.Read(p)
+	// Writes to the hash never return an error.
+	r.hash.Write(p[:n])
+	r.n += int64(n)
+	return n, err
+}
+
+// archiveChecker is the ParserConsumer of Check.
+type archiveChecker struct {
+	report *CheckReport
+
+	// namespaces holds the CRC-64 of each namespace whose EOF was not read
+	// yet, and closed the namespaces whose EOF was.
+	namespaces map[string]hash.Hash64
+	closed     map[string]bool
+
+	// current is the namespace of the block being read, which has the
+	// CRC-32C block if blockCRC is set.
+	current  string
+	block    hash.Hash32
+	blockCRC *int64
+
+	trailer *Trailer
+}
+
+func (c *archiveChecker) HeaderBSON(buf []byte) error {
+	c.current = ""
+	if isTrailer(buf) {
+		c.trailer = &Trailer{}
+		return bson.Unmarshal(buf, c.trailer)
+	}
+	header := NamespaceHeader{}
+	if err := bson.Unmarshal(buf, &header); err != nil {
+		return newCorruptionError("header bson doesn't unmarshal as a collection header", err)
+	}
+	if header.Collection == "" {
+		return newCorruptionError("collection header is missing a Collection", nil)
+	}
+	ns := header.Database + "." + header.Collection
+	if c.closed[ns] {
+		c.report.problem("namespace %v continues after its EOF", ns)
+		return nil
+	}
+
+	sum, ok := c.namespaces[ns]
+	if !ok {
+		sum = crc64.New(crc64.MakeTable(crc64.ECMA))
+		c.namespaces[ns] = sum
+	}
+	if header.EOF {
+		if crc := int64(sum.Sum64()); crc != header.CRC {
+			c.report.problem("CRC mismatch for namespace %v, %v!=%v", ns, crc, header.CRC)
+		}
+		delete(c.namespaces, ns)
+		c.closed[ns] = true
+		return nil
+	}
+
+	c.current = ns
+	c.block.Reset()
+	c.blockCRC = header.BlockCRC
+	if c.report.Checksums && c.blockCRC == nil {
+		c.report.problem("a block of namespace %v has no checksum", ns)
+	}
+	return nil
+}
+
+func (c *archiveChecker) BodyBSON(buf []byte) error {
+	if c.current == "" {
+		return newCorruptionError("collection data without a collection header", nil)
+	}
+	c.report.Documents++
+	// Writes to the hashes never return an error.
+	c.namespaces[c.current].Write(buf)
+	c.block.Write(buf)
+	return nil
+}
+
+func (c *archiveChecker) End() error {
+	return nil
+}
+
+// endBlock verifies the checksum of the block that started at byte start,
+// once all of it was read.
+func (c *archiveChecker) endBlock(start int64) {
+	if c.current == "" || c.blockCRC == nil {
+		return
+	}
+	if crc := int64(c.block.Sum32()); crc != *c.blockCRC {
+		c.report.problem("checksum mismatch in the block of namespace %v at byte %v", c.current, start)
+	}
+	c.current = ""
+}
@@ -0,0 +1,206 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package archive
+
+import (
+	"bytes"
+	"hash"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeArchive writes testIntents, in parallel, to an archive in out, with
+// checksums if checksums is set.
+func writeArchive(t *testing.T, out *closingBuffer, checksums bool) {
+	writer := &Writer{
+		Out: out,
+		Mux: NewMultiplexer(out, new(testNotifier)),
+	}
+	writer.Mux.Checksums = checksums
+	go writer.Mux.Run()
+
+	var err error
+	writer.Prelude, err = NewPreludeForIntents(testIntents, 4, "8.0.0", "100.0.0")
+	require.NoError(t, err)
+	if checksums {
+		writer.Prelude.Header.Checksum = CRC32CChecksum
+	}
+	require.NoError(t, writer.Prelude.Write(out))
+
+	errChan := make(chan error)
+	makeIns(
+		testIntents,
+		writer.Mux,
+		map[string]hash.Hash{},
+		map[string]*MuxIn{},
+		map[string]*int{},
+		errChan,
+	)
+	for range testIntents {
+		require.NoError(t, <-errChan)
+	}
+	close(writer.Mux.Control)
+	require.NoError(t, <-writer.Mux.Completed)
+	if checksums {
+		require.NoError(t, writer.WriteTrailer())
+	}
+}
+
+func TestCheckArchive(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	out := &closingBuffer{}
+	writeArchive(t, out, true)
+	content := out.Bytes()
+
+	t.Run("intact", func(t *testing.T) {
+		report, err := Check(bytes.NewReader(content))
+		require.NoError(t, err)
+		assert.Empty(t, report.Problems)
+		assert.True(t, report.Checksums)
+		assert.Equal(t, len(testIntents), report.Namespaces)
+		assert.EqualValues(t, len(testIntents)*testDocCount, report.Documents)
+		assert.GreaterOrEqual(t, report.Blocks, int64(2*len(testIntents)))
+		assert.EqualValues(t, len(content), report.Bytes)
+	})
+
+	Convey("An archive with checksums should be restorable", t, func() {
+		in := bytes.NewReader(content)
+		prelude := &Prelude{}
+		So(prelude.Read(in), ShouldBeNil)
+		demux := CreateDemux(prelude.NamespaceMetadatas, in, false)
+		outLengths := map[string]*int{}
+		errChan := make(chan error)
+		makeOuts(
+			testIntents,
+			demux,
+			map[string]hash.Hash{},
+			map[string]*RegularCollectionReceiver{},
+			outLengths,
+			errChan,
+		)
+		So(demux.Run(), ShouldBeNil)
+		for range testIntents {
+			So(<-errChan, ShouldBeNil)
+		}
+		for _, intent := range testIntents {
+			So(*outLengths[intent.Namespace()], ShouldBeGreaterThan, 0)
+		}
+	})
+
+	t.Run("damaged document", func(t *testing.T) {
+		damaged := append([]byte(nil), content...)
+		// a byte of the last document before the last EOF
+		i := bytes.LastIndex(damaged, []byte("crow.bar"))
+		require.Greater(t, i, 0)
+		damaged[i] = 'C'
+
+		report, err := Check(bytes.NewReader(damaged))
+		require.NoError(t, err)
+		assert.Contains(t, report.Problems,
+			"checksum mismatch in the block of namespace crow.bar at byte "+
+				blockStart(t, damaged, i))
+		assert.Contains(t, report.Problems, "the checksum of the blocks does not match the trailer")
+	})
+
+	t.Run("damaged prelude", func(t *testing.T) {
+		damaged := append([]byte(nil), content...)
+		i := bytes.Index(damaged, []byte("100.0.0"))
+		require.Greater(t, i, 0)
+		damaged[i] = '2'
+
+		report, err := Check(bytes.NewReader(damaged))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"the checksum of the prelude does not match the trailer"},
+			report.Problems)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		report, err := Check(bytes.NewReader(content[:len(content)-100]))
+		require.NoError(t, err)
+		assert.Contains(t, report.Problems, "the archive has no trailer, so it may be truncated")
+	})
+
+	t.Run("without checksums", func(t *testing.T) {
+		plain := &closingBuffer{}
+		writeArchive(t, plain, false)
+		report, err := Check(bytes.NewReader(plain.Bytes()))
+		require.NoError(t, err)
+		assert.False(t, report.Checksums)
+		assert.Empty(t, report.Problems)
+
+		damaged := plain.Bytes()
+		i := bytes.LastIndex(damaged, []byte("crow.bar"))
+		damaged[i] = 'C'
+		report, err = Check(bytes.NewReader(damaged))
+		require.NoError(t, err)
+		require.Len(t, report.Problems, 1)
+		assert.Contains(t, report.Problems[0], "CRC mismatch for namespace crow.bar")
+	})
+}
+
+// blockStart returns the offset, as text, of the header of the block that
+// byte i of an archive is in.
+func blockStart(t *testing.T, archive []byte, i int) string {
+	end := bytes.LastIndex(archive[:i], terminatorBytes)
+	require.Greater(t, end, 0)
+	return strconv.Itoa(end + len(terminatorBytes))
+}
+
+func TestCheckContiguousArchive(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	path := filepath.Join(t.TempDir(), "archive")
+	out, err := os.Create(path)
+	require.NoError(t, err)
+	writer := &Writer{
+		Out: out,
+		Mux: NewMultiplexer(out, new(testNotifier)),
+	}
+	writer.Mux.Contiguous = true
+	writer.Mux.Checksums = true
+	go writer.Mux.Run()
+
+	writer.Prelude, err = NewPreludeForIntents(testIntents, 1, "8.0.0", "100.0.0")
+	require.NoError(t, err)
+	writer.Prelude.SetLayout(ContiguousLayout)
+	writer.Prelude.Header.Checksum = CRC32CChecksum
+	require.NoError(t, writer.Prelude.Write(out))
+	errChan := make(chan error)
+	for _, intent := range testIntents {
+		makeIns(
+			[]*intents.Intent{intent},
+			writer.Mux,
+			map[string]hash.Hash{},
+			map[string]*MuxIn{},
+			map[string]*int{},
+			errChan,
+		)
+		require.NoError(t, <-errChan)
+	}
+	close(writer.Mux.Control)
+	require.NoError(t, <-writer.Mux.Completed)
+	require.NoError(t, writer.RewritePrelude())
+	require.NoError(t, writer.WriteTrailer())
+	require.NoError(t, out.Close())
+
+	in, err := os.Open(path)
+	require.NoError(t, err)
+	defer in.Close()
+	report, err := Check(in)
+	require.NoError(t, err)
+	assert.Empty(t, report.Problems)
+	assert.Equal(t, ContiguousLayout, report.Header.Layout)
+}
@@ -0,0 +1,152 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package archive
+
+import (
+	"bytes"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeContiguousArchive writes testIntents one at a time to an archive with
+// the contiguous layout, and returns its path.
+func writeContiguousArchive(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "archive")
+	out, err := os.Create(path)
+	require.NoError(t, err)
+
+	writer := &Writer{
+		Out: out,
+		Mux: NewMultiplexer(out, new(testNotifier)),
+	}
+	writer.Mux.Contiguous = true
+	go writer.Mux.Run()
+
+	writer.Prelude, err = NewPreludeForIntents(testIntents, 1, "8.0.0", "100.0.0")
+	require.NoError(t, err)
+	writer.Prelude.SetLayout(ContiguousLayout)
+	require.NoError(t, writer.Prelude.Write(out))
+
+	errChan := make(chan error)
+	for _, intent := range testIntents {
+		makeIns(
+			[]*intents.Intent{intent},
+			writer.Mux,
+			map[string]hash.Hash{},
+			map[string]*MuxIn{},
+			map[string]*int{},
+			errChan,
+		)
+		require.NoError(t, <-errChan)
+	}
+	close(writer.Mux.Control)
+	require.NoError(t, <-writer.Mux.Completed)
+	require.NoError(t, writer.RewritePrelude())
+	require.NoError(t, out.Close())
+	return path
+}
+
+func TestContiguousArchive(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	path := writeContiguousArchive(t)
+
+	Convey("With an archive with the contiguous layout", t, func() {
+		in, err := os.Open(path)
+		So(err, ShouldBeNil)
+		defer in.Close()
+
+		prelude := &Prelude{}
+		So(prelude.Read(in), ShouldBeNil)
+		So(prelude.Header.Layout, ShouldEqual, ContiguousLayout)
+
+		Convey("the blocks of each namespace should follow each other", func() {
+			info, err := in.Stat()
+			So(err, ShouldBeNil)
+			end, err := in.Seek(0, io.SeekCurrent)
+			So(err, ShouldBeNil)
+			for _, cm := range prelude.NamespaceMetadatas {
+				So(cm.Offset, ShouldEqual, end)
+				So(cm.Length, ShouldBeGreaterThan, 0)
+				end += cm.Length
+			}
+			So(end, ShouldEqual, info.Size())
+		})
+
+		Convey("the demux should seek past the data of muted namespaces", func() {
+			// Corrupt the data of the muted namespace, which the demux then
+			// must not read.
+			muted := prelude.NamespaceMetadatas[1]
+			corrupted, err := os.OpenFile(path, os.O_WRONLY, 0)
+			So(err, ShouldBeNil)
+			garbage := bytes.Repeat([]byte{0x42}, int(muted.Length))
+			_, err = corrupted.WriteAt(garbage, muted.Offset)
+			So(err, ShouldBeNil)
+			So(corrupted.Close(), ShouldBeNil)
+
+			demux := CreateDemux(prelude.NamespaceMetadatas, in, false)
+			var restored []*intents.Intent
+			for _, intent := range testIntents {
+				if intent.Namespace() == muted.Database+"."+muted.Collection {
+					demux.Open(intent.Namespace(), &MutedCollection{Intent: intent, Demux: demux})
+				} else {
+					restored = append(restored, intent)
+				}
+			}
+			outLengths := map[string]*int{}
+			errChan := make(chan error)
+			makeOuts(
+				restored,
+				demux,
+				map[string]hash.Hash{},
+				map[string]*RegularCollectionReceiver{},
+				outLengths,
+				errChan,
+			)
+
+			So(demux.Run(), ShouldBeNil)
+			for range restored {
+				So(<-errChan, ShouldBeNil)
+			}
+			for _, intent := range restored {
+				So(*outLengths[intent.Namespace()], ShouldBeGreaterThan, 0)
+			}
+			for _, status := range demux.NamespaceStatus {
+				So(status, ShouldEqual, NamespaceClosed)
+			}
+		})
+	})
+}
+
+func TestContiguousMuxRejectsInterleaving(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	out, err := os.Create(filepath.Join(t.TempDir(), "archive"))
+	require.NoError(t, err)
+	defer out.Close()
+
+	mux := NewMultiplexer(out, new(testNotifier))
+	mux.Contiguous = true
+	require.NoError(t, mux.startBlock("foo.bar"))
+
+	mux.currentNamespace = "foo.bar"
+	assert.ErrorContains(t, mux.startBlock("ding.bats"), "interleaved")
+	require.NoError(t, mux.endBlock("foo.bar"))
+
+	mux.currentNamespace = ""
+	assert.ErrorContains(t, mux.startBlock("foo.bar"), "split")
+	assert.ErrorContains(t, mux.endBlock("ding.bats"), "without any data")
+}
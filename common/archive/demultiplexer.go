@@ -7,6 +7,7 @@
 package archive
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"hash"
@@ -19,6 +20,7 @@ import (
 	"github.com/mongodb/mongo-tools/common/db"
 	"github.com/mongodb/mongo-tools/common/intents"
 	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/telemetry"
 	"github.com/mongodb/mongo-tools/common/util"
 	"go.mongodb.org/mongo-driver/bson"
 )
@@ -54,6 +56,29 @@ type Demultiplexer struct {
 
 	NamespaceStatus map[string]int
 	IsAtlasProxy    bool
+
+	// Tolerant makes the demultiplexer skip over corrupted or truncated
+	// blocks instead of failing. Namespaces that lost data as a result are
+	// recorded in Incomplete.
+	Tolerant bool
+
+	// Incomplete maps each namespace that could not be fully read from the
+	// archive to the reason why. It is only populated when Tolerant is set.
+	Incomplete map[string]string
+
+	// SkippedBytes is the amount of corrupted data skipped in Tolerant mode.
+	SkippedBytes int64
+
+	// blocks locates the data of each namespace, by offset, in an archive
+	// with the contiguous layout.
+	blocks map[int64]namespaceBlock
+}
+
+// namespaceBlock is the data of a namespace in an archive with the contiguous
+// layout.
+type namespaceBlock struct {
+	ns     string
+	length int64
 }
 
 func CreateDemux(
@@ -65,6 +90,7 @@ func CreateDemux(
 		NamespaceStatus: make(map[string]int),
 		In:              in,
 		IsAtlasProxy:    isAtlasProxy,
+		Incomplete:      make(map[string]string),
 	}
 	for _, cm := range namespaceMetadatas {
 		// For atlas proxy archive restores, ignore collections from the admin DB.
@@ -72,21 +98,34 @@ func CreateDemux(
 			continue
 		}
 
-		var ns string
-		if cm.Type == "timeseries" {
-			ns = cm.Database + ".system.buckets." + cm.Collection
-		} else {
-			ns = cm.Database + "." + cm.Collection
-		}
+		ns := cm.dataNamespace()
 		demux.NamespaceStatus[ns] = NamespaceUnopened
+		if cm.Offset > 0 && cm.Length > 0 {
+			if demux.blocks == nil {
+				demux.blocks = make(map[int64]namespaceBlock)
+			}
+			demux.blocks[cm.Offset] = namespaceBlock{ns: ns, length: cm.Length}
+		}
 	}
 	return demux
 }
 
 // Run creates and runs a parser with the Demultiplexer as a consumer.
 func (demux *Demultiplexer) Run() error {
-	parser := Parser{In: demux.In}
-	err := parser.ReadAllBlocks(demux)
+	var err error
+	span := telemetry.Start("archive.demux", telemetry.Bool("tolerant", demux.Tolerant))
+	defer func() { span.End(err) }()
+	if demux.Tolerant {
+		// recovering from corruption scans the archive a byte at a time
+		parser := Parser{In: bufio.NewReader(demux.In)}
+		err = demux.readAllBlocksTolerant(&parser)
+	} else if seeker, ok := demux.In.(io.Seeker); ok && demux.blocks != nil {
+		parser := Parser{In: demux.In}
+		err = demux.readAllBlocksSeeking(&parser, seeker)
+	} else {
+		parser := Parser{In: demux.In}
+		err = parser.ReadAllBlocks(demux)
+	}
 	if len(demux.outs) > 0 {
 		log.Logvf(log.Always, "demux finishing when there are still outs (%v)", len(demux.outs))
 	}
@@ -95,9 +134,114 @@ func (demux *Demultiplexer) Run() error {
 	return err
 }
 
+// readAllBlocksTolerant is like Parser.ReadAllBlocks, but when a block turns
+// out to be corrupted it skips ahead to the next terminator and carries on
+// with the following block. Reading stops without an error if the archive is
+// truncated.
+func (demux *Demultiplexer) readAllBlocksTolerant(parser *Parser) error {
+	for {
+		err := parser.ReadBlock(demux)
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			continue
+		}
+		if !isCorruption(err) {
+			//nolint:errcheck
+			demux.End()
+			return err
+		}
+
+		log.Logvf(log.Always, "skipping corrupted archive block: %v", err)
+		if demux.currentNamespace != "" {
+			demux.markIncomplete(demux.currentNamespace, err.Error())
+			demux.currentNamespace = ""
+		}
+		skipped, err := parser.skipToTerminator()
+		demux.SkippedBytes += skipped
+		if err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				log.Logvf(log.Always, "stopped reading archive after error: %v", err)
+			}
+			break
+		}
+		log.Logvf(log.Info, "resuming after skipping %v bytes of the archive", skipped)
+	}
+	return demux.End()
+}
+
+// readAllBlocksSeeking is like Parser.ReadAllBlocks, but seeks past the data
+// of the namespaces that are not being restored in an archive with the
+// contiguous layout, instead of reading it.
+func (demux *Demultiplexer) readAllBlocksSeeking(parser *Parser, seeker io.Seeker) error {
+	var err error
+	for err == nil {
+		var skipped bool
+		skipped, err = demux.seekPastMutedBlock(seeker)
+		if err != nil {
+			//nolint:errcheck
+			demux.End()
+			return err
+		}
+		if !skipped {
+			err = parser.ReadBlock(demux)
+		}
+	}
+	endError := demux.End()
+	if err == io.EOF {
+		return endError
+	}
+	return err
+}
+
+// seekPastMutedBlock seeks past the data of a namespace if it starts at the
+// current position of the archive and is not being restored. It returns true
+// if it did.
+func (demux *Demultiplexer) seekPastMutedBlock(seeker io.Seeker) (bool, error) {
+	pos, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, newWrappedError("error getting position in archive", err)
+	}
+	block, ok := demux.blocks[pos]
+	if !ok || demux.NamespaceStatus[block.ns] != NamespaceUnopened {
+		return false, nil
+	}
+	out, muted := demux.outs[block.ns].(*MutedCollection)
+	if !muted {
+		return false, nil
+	}
+
+	_, err = seeker.Seek(pos+block.length, io.SeekStart)
+	if err != nil {
+		return false, newWrappedError("error seeking in archive", err)
+	}
+	log.Logvf(log.DebugLow, "demux skipped %v bytes of namespace %v", block.length, block.ns)
+	out.End()
+	demux.NamespaceStatus[block.ns] = NamespaceClosed
+	delete(demux.outs, block.ns)
+	delete(demux.lengths, block.ns)
+	return true, nil
+}
+
+// markIncomplete records that ns could not be fully read from the archive.
+// Only the first reason for a namespace is kept, and namespaces that are not
+// being restored are ignored.
+func (demux *Demultiplexer) markIncomplete(ns, reason string) {
+	if _, muted := demux.outs[ns].(*MutedCollection); muted {
+		return
+	}
+	if _, ok := demux.Incomplete[ns]; !ok {
+		demux.Incomplete[ns] = reason
+	}
+}
+
 type demuxError struct {
 	Err error
 	Msg string
+
+	// corrupt is set when the error was caused by malformed archive data.
+	corrupt bool
 }
 
 // Error is part of the Error interface. It formats a demuxError for human readability.
@@ -124,17 +268,31 @@ func newWrappedError(msg string, err error) error {
 	}
 }
 
+// newCorruptionError creates a demuxError for malformed archive data.
+func newCorruptionError(msg string, err error) error {
+	return &demuxError{
+		Err:     err,
+		Msg:     msg,
+		corrupt: true,
+	}
+}
+
 // HeaderBSON is part of the ParserConsumer interface and receives headers from parser.
 // Its main role is to implement opens and EOFs of the embedded stream.
 func (demux *Demultiplexer) HeaderBSON(buf []byte) error {
+	if isTrailer(buf) {
+		// the checksums of the trailer are only verified by Check
+		demux.currentNamespace = ""
+		return nil
+	}
 	colHeader := NamespaceHeader{}
 	err := bson.Unmarshal(buf, &colHeader)
 	if err != nil {
-		return newWrappedError("header bson doesn't unmarshal as a collection header", err)
+		return newCorruptionError("header bson doesn't unmarshal as a collection header", err)
 	}
 	log.Logvf(log.DebugHigh, "demux namespaceHeader: %v", colHeader)
 	if colHeader.Collection == "" {
-		return newError("collection header is missing a Collection")
+		return newCorruptionError("collection header is missing a Collection", nil)
 	}
 	demux.currentNamespace = colHeader.Database + "." + colHeader.Collection
 
@@ -173,16 +331,20 @@ func (demux *Demultiplexer) HeaderBSON(buf []byte) error {
 		crcUInt64, ok := demux.outs[demux.currentNamespace].Sum64()
 		if ok {
 			crc := int64(crcUInt64)
-			if crc != colHeader.CRC {
+			switch {
+			case crc == colHeader.CRC:
+				log.Logvf(log.DebugHigh,
+					"demux checksum for namespace %v is correct (%v), %v bytes",
+					demux.currentNamespace, crc, length)
+			case demux.Tolerant:
+				demux.markIncomplete(demux.currentNamespace, "checksum mismatch")
+			default:
 				return fmt.Errorf("CRC mismatch for namespace %v, %v!=%v",
 					demux.currentNamespace,
 					crc,
 					colHeader.CRC,
 				)
 			}
-			log.Logvf(log.DebugHigh,
-				"demux checksum for namespace %v is correct (%v), %v bytes",
-				demux.currentNamespace, crc, length)
 		} else {
 			log.Logvf(log.DebugHigh,
 				"demux checksum for namespace %v was not calculated.",
@@ -201,7 +363,23 @@ func (demux *Demultiplexer) HeaderBSON(buf []byte) error {
 func (demux *Demultiplexer) End() error {
 	log.Logvf(log.DebugHigh, "demux End")
 	var err error
-	if len(demux.outs) != 0 {
+	if demux.Tolerant {
+		// let the restore of whatever was read finish normally, and report
+		// everything that didn't make it through intact
+		for ns, status := range demux.NamespaceStatus {
+			if status == NamespaceUnopened {
+				demux.markIncomplete(ns, "no data found in archive")
+			}
+		}
+		for ns, out := range demux.outs {
+			demux.markIncomplete(ns, "archive ended before the end of the collection")
+			if rcr, ok := out.(*RegularCollectionReceiver); ok {
+				rcr.err = io.EOF
+			}
+			out.End()
+		}
+		demux.outs = nil
+	} else if len(demux.outs) != 0 {
 		openNss := []string{}
 		for ns := range demux.outs {
 			openNss = append(openNss, ns)
@@ -232,7 +410,7 @@ func (demux *Demultiplexer) End() error {
 // Its main role is to dispatch the body to the Read() function of the current DemuxOut.
 func (demux *Demultiplexer) BodyBSON(buf []byte) error {
 	if demux.currentNamespace == "" {
-		return newError("collection data without a collection header")
+		return newCorruptionError("collection data without a collection header", nil)
 	}
 
 	// For atlas proxy archive restores, ignore collections from the admin DB.
@@ -468,10 +646,13 @@ func (cache *SpecialCollectionCache) Sum64() (uint64, bool) {
 }
 
 // MutedCollection implements both DemuxOut as well as intents.file. It serves as a way to
-// let the demutiplexer ignore certain embedded streams.
+// let the demutiplexer ignore certain embedded streams. It counts the documents it ignores,
+// except for those of blocks that the demultiplexer seeks past without reading.
 type MutedCollection struct {
 	Intent *intents.Intent
 	Demux  *Demultiplexer
+
+	documents int64
 }
 
 // Read is part of the intents.file interface, and does nothing.
@@ -480,11 +661,17 @@ func (*MutedCollection) Read([]byte) (int, error) {
 	return 0, io.EOF
 }
 
-// Write is part of the intents.file interface, and does nothing.
-func (*MutedCollection) Write(b []byte) (int, error) {
+// Write is part of the intents.file interface, and only counts the document it is given.
+func (muted *MutedCollection) Write(b []byte) (int, error) {
+	atomic.AddInt64(&muted.documents, 1)
 	return len(b), nil
 }
 
+// Documents returns the number of documents written to the MutedCollection.
+func (muted *MutedCollection) Documents() int64 {
+	return atomic.LoadInt64(&muted.documents)
+}
+
 // Close is part of the intents.file interface, and does nothing.
 func (*MutedCollection) Close() error {
 	return nil
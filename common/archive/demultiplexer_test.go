@@ -0,0 +1,107 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package archive
+
+import (
+	"bytes"
+	"hash/crc64"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// damagedArchive builds the body of an archive (everything after the prelude)
+// for namespaces a.x, b.y, c.z and d.w, where the only block of b.y is
+// corrupted, c.z never appears and the archive is truncated in the middle of
+// d.w.
+func damagedArchive(t *testing.T) []byte {
+	buf := &bytes.Buffer{}
+	write := func(v interface{}) []byte {
+		b, err := bson.Marshal(v)
+		require.NoError(t, err)
+		buf.Write(b)
+		return b
+	}
+	crc := crc64.New(crc64.MakeTable(crc64.ECMA))
+
+	write(NamespaceHeader{Database: "a", Collection: "x"})
+	crc.Write(write(testDoc{Bar: 1}))
+	crc.Write(write(testDoc{Bar: 2}))
+	buf.Write(terminatorBytes)
+
+	write(NamespaceHeader{Database: "b", Collection: "y"})
+	write(testDoc{Bar: 3})
+	buf.Write([]byte{0x03, 0x00, 0x00, 0x00, 0x42, 0x42})
+	buf.Write(terminatorBytes)
+
+	write(NamespaceHeader{Database: "a", Collection: "x"})
+	crc.Write(write(testDoc{Bar: 4}))
+	buf.Write(terminatorBytes)
+
+	write(NamespaceHeader{Database: "a", Collection: "x", EOF: true, CRC: int64(crc.Sum64())})
+	buf.Write(terminatorBytes)
+
+	write(NamespaceHeader{Database: "d", Collection: "w"})
+	doc := write(testDoc{Bar: 5})
+	buf.Truncate(buf.Len() - len(doc)/2)
+	return buf.Bytes()
+}
+
+func newDamagedArchiveDemux(
+	t *testing.T,
+	tolerant bool,
+) (*Demultiplexer, map[string]*SpecialCollectionCache) {
+	metadata := []*CollectionMetadata{
+		{Database: "a", Collection: "x"},
+		{Database: "b", Collection: "y"},
+		{Database: "c", Collection: "z"},
+		{Database: "d", Collection: "w"},
+	}
+	demux := CreateDemux(metadata, bytes.NewReader(damagedArchive(t)), false)
+	demux.Tolerant = tolerant
+	caches := map[string]*SpecialCollectionCache{}
+	for _, cm := range metadata {
+		ns := cm.Database + "." + cm.Collection
+		caches[ns] = NewSpecialCollectionCache(&intents.Intent{DB: cm.Database, C: cm.Collection}, demux)
+		demux.Open(ns, caches[ns])
+	}
+	return demux, caches
+}
+
+func TestTolerantDemux(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	t.Run("fails without --tolerant", func(t *testing.T) {
+		demux, _ := newDamagedArchiveDemux(t, false)
+		assert.Error(t, demux.Run())
+	})
+
+	t.Run("restores what it can with --tolerant", func(t *testing.T) {
+		demux, caches := newDamagedArchiveDemux(t, true)
+		require.NoError(t, demux.Run())
+
+		var bars []int
+		source := db.NewDecodedBSONSource(db.NewBufferlessBSONSource(caches["a.x"]))
+		var doc testDoc
+		for source.Next(&doc) {
+			bars = append(bars, doc.Bar)
+		}
+		require.NoError(t, source.Err())
+		assert.Equal(t, []int{1, 2, 4}, bars, "blocks after the corrupted one are still read")
+
+		assert.NotContains(t, demux.Incomplete, "a.x")
+		assert.Contains(t, demux.Incomplete, "b.y")
+		assert.Equal(t, "no data found in archive", demux.Incomplete["c.z"])
+		assert.Contains(t, demux.Incomplete, "d.w", "the archive is truncated in the middle of d.w")
+		assert.Positive(t, demux.SkippedBytes)
+	})
+}
@@ -0,0 +1,159 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package archive
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/text"
+)
+
+// streamCloseTimeout is how long the writer of an archive to a UNIX domain
+// socket waits for the reader to close its end after the end of the archive.
+var streamCloseTimeout = time.Minute
+
+type streamKind int
+
+const (
+	notAStream streamKind = iota
+	namedPipe
+	unixSocket
+)
+
+func (kind streamKind) String() string {
+	if kind == unixSocket {
+		return "UNIX domain socket"
+	}
+	return "named pipe"
+}
+
+func streamKindOf(path string) streamKind {
+	if isWindowsNamedPipe(path) {
+		return namedPipe
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return notAStream
+	}
+	switch mode := info.Mode(); {
+	case mode&os.ModeNamedPipe != 0:
+		return namedPipe
+	case mode&os.ModeSocket != 0:
+		return unixSocket
+	}
+	return notAStream
+}
+
+// IsStreamEndpoint returns whether path is a named pipe or a UNIX domain
+// socket, through which an archive is streamed to or from another process on
+// the same host rather than stored in a file. Stream endpoints cannot seek.
+func IsStreamEndpoint(path string) bool {
+	return streamKindOf(path) != notAStream
+}
+
+// streamEndpoint is an open named pipe or UNIX domain socket. It counts the
+// bytes of the archive so that they can be reported when it is closed.
+type streamEndpoint struct {
+	path  string
+	kind  streamKind
+	conn  io.ReadWriteCloser
+	verb  string
+	bytes int64
+}
+
+// OpenStreamWriter opens the named pipe or UNIX domain socket at path to write
+// an archive to it. It blocks until the process at the other end is ready to
+// read. Closing the writer signals the end of the archive, and for a socket
+// waits for the reader to close its end, so that a successful dump means that
+// the reader received the whole archive.
+func OpenStreamWriter(path string) (io.WriteCloser, error) {
+	return openStream(path, os.O_WRONLY, "wrote")
+}
+
+// OpenStreamReader opens the named pipe or UNIX domain socket at path to read
+// an archive from it. It blocks until the process at the other end is ready
+// to write.
+func OpenStreamReader(path string) (io.ReadCloser, error) {
+	return openStream(path, os.O_RDONLY, "read")
+}
+
+func openStream(path string, flag int, verb string) (*streamEndpoint, error) {
+	kind := streamKindOf(path)
+	if kind == notAStream {
+		return nil, fmt.Errorf("%v is not a named pipe or a UNIX domain socket", path)
+	}
+	log.Logvf(log.Info, "waiting for the other end of %v %v", kind, path)
+
+	endpoint := &streamEndpoint{path: path, kind: kind, verb: verb}
+	var err error
+	if kind == unixSocket {
+		endpoint.conn, err = net.Dial("unix", path)
+	} else {
+		endpoint.conn, err = os.OpenFile(path, flag, 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening %v %v: %v", kind, path, err)
+	}
+	log.Logvf(log.DebugLow, "connected to %v %v", kind, path)
+	return endpoint, nil
+}
+
+func (endpoint *streamEndpoint) Read(p []byte) (int, error) {
+	n, err := endpoint.conn.Read(p)
+	atomic.AddInt64(&endpoint.bytes, int64(n))
+	return n, err
+}
+
+func (endpoint *streamEndpoint) Write(p []byte) (int, error) {
+	n, err := endpoint.conn.Write(p)
+	atomic.AddInt64(&endpoint.bytes, int64(n))
+	return n, err
+}
+
+// Close closes the endpoint. When writing to a socket, it first shuts down
+// the writing side of the connection and waits for the reader to close the
+// connection, which acknowledges that it read the whole archive.
+func (endpoint *streamEndpoint) Close() error {
+	var ackErr error
+	if conn, ok := endpoint.conn.(*net.UnixConn); ok && endpoint.verb == "wrote" {
+		ackErr = awaitClose(conn)
+	}
+	err := endpoint.conn.Close()
+	log.Logvf(
+		log.Info,
+		"%v %v through %v %v",
+		endpoint.verb,
+		text.FormatByteAmount(atomic.LoadInt64(&endpoint.bytes)),
+		endpoint.kind,
+		endpoint.path,
+	)
+	if ackErr != nil {
+		return ackErr
+	}
+	return err
+}
+
+// awaitClose shuts down the writing side of conn and waits until the reader
+// closes its end.
+func awaitClose(conn *net.UnixConn) error {
+	if err := conn.CloseWrite(); err != nil {
+		return fmt.Errorf("error signaling the end of the archive: %v", err)
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(streamCloseTimeout)); err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.Discard, conn); err != nil {
+		return fmt.Errorf("the reader did not acknowledge the end of the archive: %v", err)
+	}
+	return nil
+}
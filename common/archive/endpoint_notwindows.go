@@ -0,0 +1,16 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+//go:build !windows
+// +build !windows
+
+package archive
+
+// isWindowsNamedPipe returns false: named pipes are recognized by their file
+// mode on other platforms.
+func isWindowsNamedPipe(string) bool {
+	return false
+}
@@ -0,0 +1,147 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+//go:build !windows
+// +build !windows
+
+package archive
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var endpointTestData = bytes.Repeat([]byte("archive data "), 10000)
+
+func TestStreamEndpointKinds(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	assert.False(t, IsStreamEndpoint(file))
+	assert.False(t, IsStreamEndpoint(dir))
+	assert.False(t, IsStreamEndpoint(filepath.Join(dir, "missing")))
+
+	_, err := OpenStreamReader(file)
+	assert.Error(t, err)
+}
+
+func TestNamedPipeEndpoint(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	path := filepath.Join(t.TempDir(), "pipe")
+	require.NoError(t, syscall.Mkfifo(path, 0o600))
+	require.True(t, IsStreamEndpoint(path))
+
+	received := make(chan []byte)
+	go func() {
+		reader, err := OpenStreamReader(path)
+		if !assert.NoError(t, err) {
+			close(received)
+			return
+		}
+		data, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.NoError(t, reader.Close())
+		received <- data
+	}()
+
+	writer, err := OpenStreamWriter(path)
+	require.NoError(t, err)
+	_, err = writer.Write(endpointTestData)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	assert.Equal(t, endpointTestData, <-received)
+}
+
+func TestUnixSocketEndpoint(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	// socket paths are limited to about 100 bytes, which t.TempDir can exceed
+	dir, err := os.MkdirTemp("", "sock")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "s")
+
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer listener.Close()
+	require.True(t, IsStreamEndpoint(path))
+
+	t.Run("the writer waits for the reader to acknowledge the end", func(t *testing.T) {
+		acked := make(chan time.Time, 1)
+		go func() {
+			conn, err := listener.Accept()
+			if !assert.NoError(t, err) {
+				return
+			}
+			data, err := io.ReadAll(conn)
+			assert.NoError(t, err)
+			assert.Equal(t, endpointTestData, data)
+			time.Sleep(50 * time.Millisecond)
+			acked <- time.Now()
+			assert.NoError(t, conn.Close())
+		}()
+
+		writer, err := OpenStreamWriter(path)
+		require.NoError(t, err)
+		_, err = writer.Write(endpointTestData)
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		closed := time.Now()
+		assert.False(t, closed.Before(<-acked), "Close should return after the reader closes")
+	})
+
+	t.Run("the reader reads the archive until the writer closes", func(t *testing.T) {
+		go func() {
+			conn, err := listener.Accept()
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, err = conn.Write(endpointTestData)
+			assert.NoError(t, err)
+			assert.NoError(t, conn.Close())
+		}()
+
+		reader, err := OpenStreamReader(path)
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, endpointTestData, data)
+		require.NoError(t, reader.Close())
+	})
+
+	t.Run("the writer fails if the reader never acknowledges the end", func(t *testing.T) {
+		defer func(timeout time.Duration) { streamCloseTimeout = timeout }(streamCloseTimeout)
+		streamCloseTimeout = 50 * time.Millisecond
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			conn, err := listener.Accept()
+			if !assert.NoError(t, err) {
+				return
+			}
+			<-done
+			conn.Close()
+		}()
+
+		writer, err := OpenStreamWriter(path)
+		require.NoError(t, err)
+		assert.ErrorContains(t, writer.Close(), "did not acknowledge")
+	})
+}
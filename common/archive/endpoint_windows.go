@@ -0,0 +1,20 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+//go:build windows
+// +build windows
+
+package archive
+
+import "strings"
+
+// isWindowsNamedPipe returns whether path names a named pipe, e.g.
+// \\.\pipe\backup. Named pipes are not in the file system, so they cannot be
+// recognized by their file mode.
+func isWindowsNamedPipe(path string) bool {
+	path = strings.ToLower(strings.ReplaceAll(path, "/", `\`))
+	return strings.HasPrefix(path, `\\.\pipe\`)
+}
@@ -9,6 +9,7 @@ package archive
 import (
 	"fmt"
 	"hash"
+	"hash/crc32"
 	"hash/crc64"
 	"io"
 	"reflect"
@@ -16,6 +17,7 @@ import (
 	"github.com/mongodb/mongo-tools/common/db"
 	"github.com/mongodb/mongo-tools/common/intents"
 	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/telemetry"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -36,6 +38,29 @@ type Multiplexer struct {
 	ins              []*MuxIn
 	selectCases      []reflect.SelectCase
 	currentNamespace string
+
+	// Contiguous makes the mux record the location of each namespace's data,
+	// and fail if the data of different namespaces is interleaved. It must
+	// be set before Run is called, and Out must be a file. Out is then left
+	// open when the mux finishes, so that the prelude can be rewritten.
+	Contiguous bool
+	blocks     map[string]BlockRange
+
+	// Checksums makes the mux start a new block, whose header has the
+	// CRC-32C of its documents, for every buffer it writes, and keep the
+	// checksum of everything it writes for the Trailer. It must be set
+	// before Run is called. Out is then left open when the mux finishes, so
+	// that the trailer can be written.
+	Checksums bool
+
+	// LeaveOpen leaves Out open when the mux finishes, e.g. so that the
+	// header of the prelude can be rewritten. It must be set before Run is
+	// called.
+	LeaveOpen bool
+
+	dataHash   hash.Hash32
+	dataLength int64
+	blockCount int64
 }
 
 type notifier interface {
@@ -69,13 +94,20 @@ func NewMultiplexer(out io.WriteCloser, shutdownInputs notifier) *Multiplexer {
 // Run multiplexes until its Control chan closes.
 func (mux *Multiplexer) Run() {
 	var err, completionErr error
+	span := telemetry.Start("archive.mux")
+	defer func() { span.End(completionErr) }()
+	if mux.Checksums {
+		mux.dataHash = crc32.New(crc32cTable)
+	}
 	for {
 		index, value, notEOF := reflect.Select(mux.selectCases)
 		EOF := !notEOF
 		if index == 0 { //Control index
 			if EOF {
 				log.Logvf(log.DebugLow, "Mux finish")
-				mux.Out.Close()
+				if !mux.Contiguous && !mux.Checksums && !mux.LeaveOpen {
+					mux.Out.Close()
+				}
 				if completionErr != nil {
 					mux.Completed <- completionErr
 				} else if len(mux.selectCases) != 1 {
@@ -92,6 +124,10 @@ func (mux *Multiplexer) Run() {
 				return
 			}
 			log.Logvf(log.DebugLow, "Mux open namespace %v", muxIn.Intent.DataNamespace())
+			muxIn.span = span.Start(
+				"archive.muxNamespace",
+				telemetry.String("db.namespace", muxIn.Intent.DataNamespace()),
+			)
 			mux.selectCases = append(mux.selectCases, reflect.SelectCase{
 				Dir:  reflect.SelectRecv,
 				Chan: reflect.ValueOf(muxIn.writeChan),
@@ -109,10 +145,13 @@ func (mux *Multiplexer) Run() {
 				mux.ins[index].writeCloseFinishedChan <- struct{}{}
 
 				err = mux.formatEOF(mux.ins[index])
+				mux.ins[index].endSpan(err)
 				if err != nil {
 					mux.shutdownInputs.Notify()
 					mux.Out = &nopCloseNopWriter{}
-					completionErr = err
+					if completionErr == nil {
+						completionErr = err
+					}
 				}
 				log.Logvf(log.DebugLow, "Mux close namespace %v", mux.ins[index].Intent.DataNamespace())
 				mux.currentNamespace = ""
@@ -124,11 +163,14 @@ func (mux *Multiplexer) Run() {
 					mux.Completed <- fmt.Errorf("multiplexer received a value that wasn't a []byte")
 					return
 				}
+				mux.ins[index].bytes += int64(len(bsonBytes))
 				err = mux.formatBody(mux.ins[index], bsonBytes)
 				if err != nil {
 					mux.shutdownInputs.Notify()
 					mux.Out = &nopCloseNopWriter{}
-					completionErr = err
+					if completionErr == nil {
+						completionErr = err
+					}
 				}
 			}
 		}
@@ -140,6 +182,46 @@ type nopCloseNopWriter struct{}
 func (*nopCloseNopWriter) Close() error                { return nil }
 func (*nopCloseNopWriter) Write(p []byte) (int, error) { return len(p), nil }
 
+// write writes p to the archive, and adds what was written to the checksum
+// of the data.
+func (mux *Multiplexer) write(p []byte) (int, error) {
+	n, err := mux.Out.Write(p)
+	if mux.dataHash != nil {
+		// Writes to the hash never return an error.
+		mux.dataHash.Write(p[:n])
+		mux.dataLength += int64(n)
+	}
+	return n, err
+}
+
+// writeHeader writes the header of a block to the archive.
+func (mux *Multiplexer) writeHeader(header NamespaceHeader) error {
+	buf, err := bson.Marshal(header)
+	if err != nil {
+		return err
+	}
+	l, err := mux.write(buf)
+	if err != nil {
+		return err
+	}
+	if l != len(buf) {
+		return io.ErrShortWrite
+	}
+	mux.blockCount++
+	return nil
+}
+
+// trailer returns the Trailer of the data written by a mux with Checksums,
+// once it has completed.
+func (mux *Multiplexer) trailer() Trailer {
+	return Trailer{
+		Trailer:    true,
+		DataCRC:    int64(mux.dataHash.Sum32()),
+		DataLength: mux.dataLength,
+		Blocks:     mux.blockCount,
+	}
+}
+
 // formatBody writes the BSON in to the archive, potentially writing a new header
 // if the document belongs to a different namespace from the last header.
 func (mux *Multiplexer) formatBody(in *MuxIn, bsonBytes []byte) error {
@@ -148,11 +230,12 @@ func (mux *Multiplexer) formatBody(in *MuxIn, bsonBytes []byte) error {
 	defer func() {
 		in.writeLenChan <- length
 	}()
-	if in.Intent.DataNamespace() != mux.currentNamespace {
+	changed := in.Intent.DataNamespace() != mux.currentNamespace
+	if changed || mux.Checksums {
 		// Handle the change of which DB/Collection we're writing docs for
 		// If mux.currentNamespace then we need to terminate the current block
 		if mux.currentNamespace != "" {
-			l, err := mux.Out.Write(terminatorBytes)
+			l, err := mux.write(terminatorBytes)
 			if err != nil {
 				return err
 			}
@@ -160,23 +243,25 @@ func (mux *Multiplexer) formatBody(in *MuxIn, bsonBytes []byte) error {
 				return io.ErrShortWrite
 			}
 		}
-		header, err := bson.Marshal(NamespaceHeader{
+		if mux.Contiguous && changed {
+			if err := mux.startBlock(in.Intent.DataNamespace()); err != nil {
+				return err
+			}
+		}
+		header := NamespaceHeader{
 			Database:   in.Intent.DB,
 			Collection: in.Intent.DataCollection(),
-		})
-		if err != nil {
-			return err
 		}
-		l, err := mux.Out.Write(header)
-		if err != nil {
-			return err
+		if mux.Checksums {
+			crc := int64(crc32.Checksum(bsonBytes, crc32cTable))
+			header.BlockCRC = &crc
 		}
-		if l != len(header) {
-			return io.ErrShortWrite
+		if err := mux.writeHeader(header); err != nil {
+			return err
 		}
 	}
 	mux.currentNamespace = in.Intent.DataNamespace()
-	length, err = mux.Out.Write(bsonBytes)
+	length, err = mux.write(bsonBytes)
 	if err != nil {
 		return err
 	}
@@ -187,7 +272,7 @@ func (mux *Multiplexer) formatBody(in *MuxIn, bsonBytes []byte) error {
 func (mux *Multiplexer) formatEOF(in *MuxIn) error {
 	var err error
 	if mux.currentNamespace != "" {
-		l, err := mux.Out.Write(terminatorBytes)
+		l, err := mux.write(terminatorBytes)
 		if err != nil {
 			return err
 		}
@@ -195,7 +280,7 @@ func (mux *Multiplexer) formatEOF(in *MuxIn) error {
 			return io.ErrShortWrite
 		}
 	}
-	eofHeader, err := bson.Marshal(NamespaceHeader{
+	err = mux.writeHeader(NamespaceHeader{
 		Database:   in.Intent.DB,
 		Collection: in.Intent.DataCollection(),
 		EOF:        true,
@@ -204,23 +289,74 @@ func (mux *Multiplexer) formatEOF(in *MuxIn) error {
 	if err != nil {
 		return err
 	}
-	l, err := mux.Out.Write(eofHeader)
+	l, err := mux.write(terminatorBytes)
 	if err != nil {
 		return err
 	}
-	if l != len(eofHeader) {
+	if l != len(terminatorBytes) {
 		return io.ErrShortWrite
 	}
-	l, err = mux.Out.Write(terminatorBytes)
+	if mux.Contiguous {
+		return mux.endBlock(in.Intent.DataNamespace())
+	}
+	return nil
+}
+
+// startBlock records the start of the data of ns in a contiguous archive.
+func (mux *Multiplexer) startBlock(ns string) error {
+	if mux.currentNamespace != "" {
+		return fmt.Errorf(
+			"data of %v is interleaved with %v in a %v archive",
+			ns,
+			mux.currentNamespace,
+			ContiguousLayout,
+		)
+	}
+	if _, ok := mux.blocks[ns]; ok {
+		return fmt.Errorf("data of %v is split across a %v archive", ns, ContiguousLayout)
+	}
+	offset, err := mux.position()
 	if err != nil {
 		return err
 	}
-	if l != len(terminatorBytes) {
-		return io.ErrShortWrite
+	if mux.blocks == nil {
+		mux.blocks = map[string]BlockRange{}
 	}
+	mux.blocks[ns] = BlockRange{Offset: offset}
 	return nil
 }
 
+// endBlock records the end of the data of ns, including its EOF header, in a
+// contiguous archive.
+func (mux *Multiplexer) endBlock(ns string) error {
+	block, ok := mux.blocks[ns]
+	if !ok {
+		return fmt.Errorf("EOF of %v without any data in a %v archive", ns, ContiguousLayout)
+	}
+	end, err := mux.position()
+	if err != nil {
+		return err
+	}
+	block.Length = end - block.Offset
+	mux.blocks[ns] = block
+	return nil
+}
+
+// position returns the offset in the archive that the mux writes to next.
+func (mux *Multiplexer) position() (int64, error) {
+	seeker, ok := mux.Out.(io.Seeker)
+	if !ok {
+		return 0, fmt.Errorf("archives with the %v layout must be written to a file", ContiguousLayout)
+	}
+	return seeker.Seek(0, io.SeekCurrent)
+}
+
+// Blocks returns the location of the data of each namespace, by namespace,
+// once a Contiguous mux has completed.
+func (mux *Multiplexer) Blocks() map[string]BlockRange {
+	return mux.blocks
+}
+
 // MuxIn is an implementation of the intents.file interface.
 // They live in the intents, and are potentially owned by different threads than
 // the thread owning the Multiplexer.
@@ -233,6 +369,21 @@ type MuxIn struct {
 	hash                   hash.Hash64
 	Intent                 *intents.Intent
 	Mux                    *Multiplexer
+
+	// span and bytes trace the writing of the namespace to the archive.
+	span  *telemetry.Span
+	bytes int64
+}
+
+// endSpan ends the span of the namespace once it is written to the archive.
+func (muxIn *MuxIn) endSpan(err error) {
+	muxIn.span.SetAttributes(telemetry.Int("bytes", muxIn.bytes))
+	muxIn.span.End(err)
+	telemetry.Add(
+		"archive.bytesWritten",
+		muxIn.bytes,
+		telemetry.String("db.namespace", muxIn.Intent.DataNamespace()),
+	)
 }
 
 // Read does nothing for MuxIns.
@@ -40,6 +40,10 @@ type Parser struct {
 type parserError struct {
 	Err error
 	Msg string
+
+	// corrupt is set when the error was caused by reading malformed or
+	// truncated archive data, rather than by the ParserConsumer.
+	corrupt bool
 }
 
 // Error is part of the Error interface. It formats a parserError for human readability.
@@ -54,7 +58,8 @@ func (pe *parserError) Error() string {
 // newParserError creates a parserError with just a message.
 func newParserError(msg string) error {
 	return &parserError{
-		Msg: msg,
+		Msg:     msg,
+		corrupt: true,
 	}
 }
 
@@ -71,6 +76,18 @@ func newParserWrappedError(msg string, err error) error {
 	}
 }
 
+// newParserReadError creates a parserError for an error reading the archive.
+func newParserReadError(msg string, err error) error {
+	if err == errInterrupted {
+		return err
+	}
+	return &parserError{
+		Err:     err,
+		Msg:     msg,
+		corrupt: true,
+	}
+}
+
 // readBSONOrTerminator reads at least four bytes, determines
 // if the first four bytes are a terminator, a bson length, or something else.
 // If they are a terminator, true,nil are returned. If they are a BSON length,
@@ -83,7 +100,7 @@ func (parse *Parser) readBSONOrTerminator() (isTerminator bool, err error) {
 		return false, err
 	}
 	if err != nil {
-		return false, newParserWrappedError("I/O error reading length or terminator", err)
+		return false, newParserReadError("I/O error reading length or terminator", err)
 	}
 	size := int32(
 		(uint32(parse.buf[0]) << 0) |
@@ -105,7 +122,7 @@ func (parse *Parser) readBSONOrTerminator() (isTerminator bool, err error) {
 	_, err = io.ReadFull(parse.In, parse.buf[4:size])
 	if err != nil {
 		// any error, including EOF is an error so we wrap it up
-		return false, newParserWrappedError("read bson", err)
+		return false, newParserReadError("read bson", err)
 	}
 	if parse.buf[size-1] != 0x00 {
 		return false, newParserError(
@@ -166,3 +183,39 @@ func (parse *Parser) ReadBlock(consumer ParserConsumer) (err error) {
 		}
 	}
 }
+
+// skipToTerminator discards input up to and including the next terminator,
+// so that parsing can resume at the start of the next block after a
+// corrupted one. It returns the number of bytes discarded, and io.EOF if the
+// input ended before a terminator was found.
+func (parse *Parser) skipToTerminator() (int64, error) {
+	var skipped int64
+	matched := 0
+	for matched < len(terminatorBytes) {
+		_, err := io.ReadFull(parse.In, parse.buf[:1])
+		if err != nil {
+			return skipped, err
+		}
+		skipped++
+		// every byte of the terminator is 0xFF
+		if parse.buf[0] == terminatorBytes[matched] {
+			matched++
+		} else {
+			matched = 0
+		}
+	}
+	return skipped, nil
+}
+
+// isCorruption returns true if err was returned while parsing because the
+// archive data was malformed or truncated, as opposed to an error from the
+// consumer of the data.
+func isCorruption(err error) bool {
+	switch e := err.(type) {
+	case *parserError:
+		return e.corrupt || isCorruption(e.Err)
+	case *demuxError:
+		return e.corrupt
+	}
+	return false
+}
@@ -94,6 +94,15 @@ func NewPrelude(
 	manager *intents.Manager,
 	concurrentColls int,
 	serverVersion, toolVersion string,
+) (*Prelude, error) {
+	return NewPreludeForIntents(manager.Intents(), concurrentColls, serverVersion, toolVersion)
+}
+
+// NewPreludeForIntents generates a Prelude for the given intents.
+func NewPreludeForIntents(
+	allIntents []*intents.Intent,
+	concurrentColls int,
+	serverVersion, toolVersion string,
 ) (*Prelude, error) {
 	prelude := Prelude{
 		Header: &Header{
@@ -104,7 +113,6 @@ func NewPrelude(
 		},
 		NamespaceMetadatasByDB: make(map[string][]*CollectionMetadata, 0),
 	}
-	allIntents := manager.Intents()
 	for _, intent := range allIntents {
 		if intent.MetadataFile != nil {
 			archiveMetadata, ok := intent.MetadataFile.(*MetadataFile)
@@ -145,6 +153,32 @@ func (prelude *Prelude) AddMetadata(cm *CollectionMetadata) {
 	log.Logvf(log.Info, "archive prelude %v.%v", cm.Database, cm.Collection)
 }
 
+// SetLayout records the layout of the archive in the header. For the
+// contiguous layout, it also writes placeholders for the locations of the
+// data of each collection, which are filled in when the prelude is
+// rewritten.
+func (prelude *Prelude) SetLayout(layout string) {
+	prelude.Header.Layout = layout
+	if layout != ContiguousLayout {
+		return
+	}
+	for _, cm := range prelude.NamespaceMetadatas {
+		cm.Offset = -1
+		cm.Length = -1
+	}
+}
+
+// setBlocks sets the location of the data of each collection from the
+// blocks written by the multiplexer.
+func (prelude *Prelude) setBlocks(blocks map[string]BlockRange) {
+	for _, cm := range prelude.NamespaceMetadatas {
+		if block, ok := blocks[cm.dataNamespace()]; ok {
+			cm.Offset = block.Offset
+			cm.Length = block.Length
+		}
+	}
+}
+
 // Write writes the archive header.
 func (prelude *Prelude) Write(out io.Writer) error {
 	magicNumberBytes := make([]byte, 4)
@@ -39,6 +39,12 @@ var validIndexOptions = map[string]bool{
 
 const epsilon = 1e-9
 
+// IsValidIndexOption returns whether option is an index option that current
+// servers know.
+func IsValidIndexOption(option string) bool {
+	return validIndexOptions[option]
+}
+
 func IsIndexKeysEqual(indexKey1 bson.D, indexKey2 bson.D) bool {
 	if len(indexKey1) != len(indexKey2) {
 		// two indexes have different number of keys
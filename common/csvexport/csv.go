@@ -4,7 +4,9 @@
 // not use this file except in compliance with the License. You may obtain
 // a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
 
-package mongoexport
+// Package csvexport writes documents as CSV, as mongoexport --type=csv and
+// bsondump --type=csv do.
+package csvexport
 
 import (
 	"encoding/csv"
@@ -22,8 +24,8 @@ import (
 // type for reflect code.
 var marshalDType = reflect.TypeOf(bsonutil.MarshalD{})
 
-// CSVExportOutput is an implementation of ExportOutput that writes documents to the output in CSV format.
-type CSVExportOutput struct {
+// Output writes documents to the output in CSV format.
+type Output struct {
 	// Fields is a list of field names in the bson documents to be exported.
 	// A field can also use dot-delimited modifiers to address nested structures,
 	// for example "location.city" or "addresses.0".
@@ -35,22 +37,25 @@ type CSVExportOutput struct {
 	// NoHeaderLine, if set, will export CSV data without a list of field names at the first line
 	NoHeaderLine bool
 
+	// FieldFormats, if set, holds how the values of each field are written.
+	// The format of AllFieldsFormat applies to the fields without their own.
+	FieldFormats map[string]*FieldFormat
+
 	csvWriter *csv.Writer
 }
 
-// NewCSVExportOutput returns a CSVExportOutput configured to write output to the
-// given io.Writer, extracting the specified fields only.
-func NewCSVExportOutput(fields []string, noHeaderLine bool, out io.Writer) *CSVExportOutput {
-	return &CSVExportOutput{
-		fields,
-		0,
-		noHeaderLine,
-		csv.NewWriter(out),
+// NewOutput returns an Output configured to write output to the given
+// io.Writer, extracting the specified fields only.
+func NewOutput(fields []string, noHeaderLine bool, out io.Writer) *Output {
+	return &Output{
+		Fields:       fields,
+		NoHeaderLine: noHeaderLine,
+		csvWriter:    csv.NewWriter(out),
 	}
 }
 
 // WriteHeader writes a comma-delimited list of fields as the output header row.
-func (csvExporter *CSVExportOutput) WriteHeader() error {
+func (csvExporter *Output) WriteHeader() error {
 	if !csvExporter.NoHeaderLine {
 		if err := csvExporter.csvWriter.Write(csvExporter.Fields); err != nil {
 			return err
@@ -61,19 +66,19 @@ func (csvExporter *CSVExportOutput) WriteHeader() error {
 }
 
 // WriteFooter is a no-op for CSV export formats.
-func (_ *CSVExportOutput) WriteFooter() error {
+func (_ *Output) WriteFooter() error {
 	// no CSV footer
 	return nil
 }
 
 // Flush writes any pending data to the underlying I/O stream.
-func (csvExporter *CSVExportOutput) Flush() error {
+func (csvExporter *Output) Flush() error {
 	csvExporter.csvWriter.Flush()
 	return csvExporter.csvWriter.Error()
 }
 
 // ExportDocument writes a line to output with the CSV representation of a document.
-func (csvExporter *CSVExportOutput) ExportDocument(document bson.D) error {
+func (csvExporter *Output) ExportDocument(document bson.D) error {
 	rowOut := make([]string, 0, len(csvExporter.Fields))
 	extendedDoc, err := bsonutil.ConvertBSONValueToLegacyExtJSON(document)
 	if err != nil {
@@ -82,7 +87,9 @@ func (csvExporter *CSVExportOutput) ExportDocument(document bson.D) error {
 
 	for _, fieldName := range csvExporter.Fields {
 		fieldVal := extractFieldByName(fieldName, extendedDoc)
-		if fieldVal == nil {
+		if cell, ok := csvExporter.formatField(fieldName, fieldVal); ok {
+			rowOut = append(rowOut, cell)
+		} else if fieldVal == nil {
 			rowOut = append(rowOut, "")
 		} else if reflect.TypeOf(fieldVal) == reflect.TypeOf(bson.M{}) ||
 			reflect.TypeOf(fieldVal) == reflect.TypeOf(bson.D{}) ||
@@ -105,6 +112,22 @@ func (csvExporter *CSVExportOutput) ExportDocument(document bson.D) error {
 	return csvExporter.csvWriter.Error()
 }
 
+// formatField writes the value of a field according to its FieldFormat, or
+// the FieldFormat for all fields. It returns false if neither applies.
+func (csvExporter *Output) formatField(
+	fieldName string,
+	value interface{},
+) (string, bool) {
+	format, ok := csvExporter.FieldFormats[fieldName]
+	if !ok {
+		format, ok = csvExporter.FieldFormats[AllFieldsFormat]
+	}
+	if !ok {
+		return "", false
+	}
+	return format.formatValue(value)
+}
+
 // extractFieldByName takes a field name and document, and returns a value representing
 // the value of that field in the document in a format that can be printed as a string.
 // It will also handle dot-delimited field names for nested arrays or documents.
@@ -4,7 +4,7 @@
 // not use this file except in compliance with the License. You may obtain
 // a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
 
-package mongoexport
+package csvexport
 
 import (
 	"bytes"
@@ -26,7 +26,7 @@ func TestWriteCSV(t *testing.T) {
 		out := &bytes.Buffer{}
 
 		Convey("Headers should be written correctly", func() {
-			csvExporter := NewCSVExportOutput(fields, false, out)
+			csvExporter := NewOutput(fields, false, out)
 			err := csvExporter.WriteHeader()
 			So(err, ShouldBeNil)
 			err = csvExporter.ExportDocument(bson.D{{"_id", "12345"}})
@@ -41,7 +41,7 @@ func TestWriteCSV(t *testing.T) {
 		})
 
 		Convey("Headers should not be written", func() {
-			csvExporter := NewCSVExportOutput(fields, true, out)
+			csvExporter := NewOutput(fields, true, out)
 			err := csvExporter.WriteHeader()
 			So(err, ShouldBeNil)
 			err = csvExporter.ExportDocument(bson.D{{"_id", "12345"}})
@@ -56,7 +56,7 @@ func TestWriteCSV(t *testing.T) {
 		})
 
 		Convey("Exported document with missing fields should print as blank", func() {
-			csvExporter := NewCSVExportOutput(fields, true, out)
+			csvExporter := NewOutput(fields, true, out)
 			err := csvExporter.ExportDocument(bson.D{{"_id", "12345"}})
 			So(err, ShouldBeNil)
 			err = csvExporter.WriteFooter()
@@ -69,7 +69,7 @@ func TestWriteCSV(t *testing.T) {
 		})
 
 		Convey("Exported document with index into nested objects should print correctly", func() {
-			csvExporter := NewCSVExportOutput(fields, true, out)
+			csvExporter := NewOutput(fields, true, out)
 			z := []interface{}{"x", bson.D{{"a", "T"}, {"B", 1}}}
 			err := csvExporter.ExportDocument(bson.D{{Key: "z", Value: z}})
 			So(err, ShouldBeNil)
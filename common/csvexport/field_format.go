@@ -0,0 +1,100 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package csvexport
+
+import (
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/json"
+)
+
+// AllFieldsFormat is the field name of a FieldFormat that applies to every
+// field without a FieldFormat of its own.
+const AllFieldsFormat = "*"
+
+// FieldFormat is how the values of a field are written to a CSV cell. A value
+// that it sets no format for is written as without a FieldFormat.
+type FieldFormat struct {
+	// DateLayout is the Go time layout of dates, like 2006-01-02, in UTC, or
+	// unix or unixms to write them as seconds or milliseconds since the epoch.
+	DateLayout string
+	// Precision is the number of digits after the decimal point, or -1 to
+	// write numbers as they are.
+	Precision int
+	// True and False are written for booleans if HasBool is set, and Null for
+	// null values if HasNull is set.
+	True, False string
+	HasBool     bool
+	Null        string
+	HasNull     bool
+}
+
+// formatValue writes value, converted to legacy extended JSON, according to
+// format. It returns false for values that format does not apply to.
+func (format *FieldFormat) formatValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return format.Null, format.HasNull
+	case bool:
+		if !format.HasBool {
+			return "", false
+		}
+		if v {
+			return format.True, true
+		}
+		return format.False, true
+	case json.Date:
+		return format.formatDate(int64(v))
+	case json.NumberInt:
+		return format.formatFloat(float64(v))
+	case json.NumberLong:
+		if format.Precision < 0 {
+			return "", false
+		}
+		return new(big.Float).SetInt64(int64(v)).Text('f', format.Precision), true
+	case json.NumberFloat:
+		return format.formatFloat(float64(v))
+	case float64:
+		return format.formatFloat(v)
+	case json.Decimal128:
+		if format.Precision < 0 {
+			return "", false
+		}
+		f, _, err := big.ParseFloat(v.String(), 10, 256, big.ToNearestEven)
+		if err != nil {
+			// NaN and Infinity are written as they are
+			return "", false
+		}
+		return f.Text('f', format.Precision), true
+	}
+	return "", false
+}
+
+func (format *FieldFormat) formatFloat(f float64) (string, bool) {
+	if format.Precision < 0 {
+		return "", false
+	}
+	return strconv.FormatFloat(f, 'f', format.Precision, 64), true
+}
+
+func (format *FieldFormat) formatDate(ms int64) (string, bool) {
+	switch format.DateLayout {
+	case "":
+		return "", false
+	case "unix":
+		seconds := ms / 1e3
+		if ms%1e3 < 0 {
+			seconds--
+		}
+		return strconv.FormatInt(seconds, 10), true
+	case "unixms":
+		return strconv.FormatInt(ms, 10), true
+	}
+	return time.UnixMilli(ms).UTC().Format(format.DateLayout), true
+}
@@ -8,8 +8,11 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/telemetry"
 	"github.com/samber/lo"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -29,10 +32,17 @@ type BufferedBulkInserter struct {
 	writeModels   []mongo.WriteModel
 	docLimit      int
 	docCount      int
+	added         uint64
 	byteCount     int
 	byteLimit     int
 	bulkWriteOpts *options.BulkWriteOptions
 	upsert        bool
+	retries       int
+	// replaceByID writes the inserts as upserts of their documents by _id.
+	replaceByID bool
+	// onReplace is called with the _ids of the documents that the upserts
+	// of replaceByID replaced.
+	onReplace func(ids []bson.RawValue)
 }
 
 func newBufferedBulkInserter(
@@ -75,6 +85,13 @@ func NewUnorderedBufferedBulkInserter(
 	return newBufferedBulkInserter(collection, docLimit, serverVersion, false)
 }
 
+// SetDocLimit changes the number of documents buffered before a bulk write is
+// performed. It takes effect from the next document added.
+func (bb *BufferedBulkInserter) SetDocLimit(docLimit int) *BufferedBulkInserter {
+	bb.docLimit = docLimit
+	return bb
+}
+
 func (bb *BufferedBulkInserter) SetOrdered(ordered bool) *BufferedBulkInserter {
 	bb.bulkWriteOpts.SetOrdered(ordered)
 	return bb
@@ -90,6 +107,47 @@ func (bb *BufferedBulkInserter) SetUpsert(upsert bool) *BufferedBulkInserter {
 	return bb
 }
 
+// SetIdempotentRetries makes a bulk write that fails with a network error be
+// retried up to retries times, with every insert turned into an upsert of
+// the document by _id. Documents that reached the server before the error
+// are then replaced instead of inserted twice, so the inserted documents
+// must have deterministic _ids.
+func (bb *BufferedBulkInserter) SetIdempotentRetries(retries int) *BufferedBulkInserter {
+	bb.retries = retries
+	return bb
+}
+
+// SetReplaceByID makes the inserts be written as upserts of their documents
+// by _id, which replace the documents with the same _id that already exist
+// instead of failing. The inserted documents must have an _id.
+func (bb *BufferedBulkInserter) SetReplaceByID(replace bool) *BufferedBulkInserter {
+	bb.replaceByID = replace
+	return bb
+}
+
+// SetOnReplace makes every bulk write of SetReplaceByID call onReplace with
+// the _ids of the documents that replaced a document that already existed.
+// Bulk writes that were retried don't call it, since their upserts may have
+// matched the documents of the attempts that failed.
+func (bb *BufferedBulkInserter) SetOnReplace(
+	onReplace func(ids []bson.RawValue),
+) *BufferedBulkInserter {
+	bb.onReplace = onReplace
+	return bb
+}
+
+// Buffered returns the number of documents waiting for the next bulk write.
+func (bb *BufferedBulkInserter) Buffered() int {
+	return bb.docCount
+}
+
+// Added returns the number of documents added to the buffer since the
+// inserter was created, so that a caller can tell whether a document it
+// passed on was buffered.
+func (bb *BufferedBulkInserter) Added() uint64 {
+	return bb.added
+}
+
 // throw away the old bulk and init a new one.
 func (bb *BufferedBulkInserter) ResetBulk() {
 	bb.writeModels = bb.writeModels[:0]
@@ -122,6 +180,26 @@ func (bb *BufferedBulkInserter) Update(selector, update bson.D) (*mongo.BulkWrit
 	)
 }
 
+// UpdateWithPipeline adds an update of the document matching selector by an
+// aggregation pipeline to the buffer. If the buffer becomes full, the bulk
+// write is performed, returning any error that occurs.
+func (bb *BufferedBulkInserter) UpdateWithPipeline(
+	selector bson.D,
+	pipeline mongo.Pipeline,
+) (*mongo.BulkWriteResult, error) {
+	for _, stage := range pipeline {
+		rawBytes, err := bson.Marshal(stage)
+		if err != nil {
+			return nil, err
+		}
+		bb.byteCount += len(rawBytes)
+	}
+
+	return bb.addModel(
+		mongo.NewUpdateOneModel().SetFilter(selector).SetUpdate(pipeline).SetUpsert(bb.upsert),
+	)
+}
+
 // Replace adds a document to the buffer for bulk replacement. If the buffer becomes full, the bulk write is performed, returning
 // any error that occurs.
 func (bb *BufferedBulkInserter) Replace(
@@ -161,6 +239,7 @@ func (bb *BufferedBulkInserter) Delete(
 // that occurs.
 func (bb *BufferedBulkInserter) addModel(model mongo.WriteModel) (*mongo.BulkWriteResult, error) {
 	bb.docCount++
+	bb.added++
 	bb.writeModels = append(bb.writeModels, model)
 
 	if bb.docCount >= bb.docLimit || bb.byteCount >= bb.byteLimit {
@@ -186,5 +265,154 @@ func (bb *BufferedBulkInserter) flush() (*mongo.BulkWriteResult, error) {
 		return nil, nil
 	}
 
-	return bb.collection.BulkWrite(context.Background(), bb.writeModels, bb.bulkWriteOpts)
+	namespace := telemetry.String(
+		"db.namespace",
+		bb.collection.Database().Name()+"."+bb.collection.Name(),
+	)
+	span := telemetry.Start(
+		"bulkWrite",
+		namespace,
+		telemetry.Int("operations", int64(len(bb.writeModels))),
+		telemetry.Int("bytes", int64(bb.byteCount)),
+	)
+	result, err := bb.bulkWrite()
+	span.End(err)
+	if result != nil {
+		telemetry.Add("bulkWrite.documents", result.InsertedCount+result.UpsertedCount, namespace)
+	}
+	return result, err
+}
+
+// bulkWrite writes the buffered documents, retrying the inserts as upserts
+// after a network error if retries are enabled.
+func (bb *BufferedBulkInserter) bulkWrite() (*mongo.BulkWriteResult, error) {
+	models := bb.writeModels
+	if bb.replaceByID {
+		var err error
+		if models, err = insertsAsUpserts(models); err != nil {
+			return nil, err
+		}
+	}
+	result, err := bb.collection.BulkWrite(context.Background(), models, bb.bulkWriteOpts)
+	if bb.retries == 0 || !mongo.IsNetworkError(err) {
+		if bb.replaceByID {
+			if bb.onReplace != nil {
+				if ids := replacedIDs(models, result, err, bb.ordered()); len(ids) > 0 {
+					bb.onReplace(ids)
+				}
+			}
+			countUpsertsAsInserts(result)
+		}
+		return result, err
+	}
+
+	if !bb.replaceByID {
+		if models, err = insertsAsUpserts(models); err != nil {
+			return nil, err
+		}
+	}
+	for attempt := 1; attempt <= bb.retries; attempt++ {
+		log.Logvf(
+			log.Always,
+			"retrying the write of %v documents as upserts (attempt %v of %v) after: %v",
+			len(models),
+			attempt,
+			bb.retries,
+			err,
+		)
+		result, err = bb.collection.BulkWrite(context.Background(), models, bb.bulkWriteOpts)
+		if err == nil || !mongo.IsNetworkError(err) {
+			break
+		}
+	}
+	// every upserted or matched document was inserted, by this attempt or by
+	// one that failed
+	countUpsertsAsInserts(result)
+	return result, err
+}
+
+func (bb *BufferedBulkInserter) ordered() bool {
+	return bb.bulkWriteOpts.Ordered == nil || *bb.bulkWriteOpts.Ordered
+}
+
+// replacedIDs returns the _ids of the upserts of insertsAsUpserts in models
+// that matched an existing document, according to the result and error of
+// their bulk write.
+func replacedIDs(
+	models []mongo.WriteModel,
+	result *mongo.BulkWriteResult,
+	err error,
+	ordered bool,
+) []bson.RawValue {
+	if result == nil || result.MatchedCount == 0 {
+		return nil
+	}
+	failed := map[int]bool{}
+	executed := len(models)
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) {
+		for _, writeErr := range bwe.WriteErrors {
+			failed[writeErr.Index] = true
+			if ordered {
+				// an ordered bulk write stops at its first error
+				executed = min(executed, writeErr.Index)
+			}
+		}
+	}
+	var ids []bson.RawValue
+	for i, model := range models[:executed] {
+		upsert, ok := model.(*mongo.ReplaceOneModel)
+		if !ok || upsert.Upsert == nil || !*upsert.Upsert || failed[i] {
+			continue
+		}
+		if _, upserted := result.UpsertedIDs[int64(i)]; upserted {
+			continue
+		}
+		filter, ok := upsert.Filter.(bson.D)
+		if !ok || len(filter) != 1 || filter[0].Key != "_id" {
+			continue
+		}
+		if id, ok := filter[0].Value.(bson.RawValue); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// countUpsertsAsInserts counts the documents that the upserts of
+// insertsAsUpserts upserted or matched as inserted.
+func countUpsertsAsInserts(result *mongo.BulkWriteResult) {
+	if result == nil {
+		return
+	}
+	result.InsertedCount += result.UpsertedCount + result.MatchedCount
+	result.UpsertedCount = 0
+	result.MatchedCount = 0
+	result.ModifiedCount = 0
+}
+
+// insertsAsUpserts returns models with every insert replaced by an upsert of
+// its document by _id.
+func insertsAsUpserts(models []mongo.WriteModel) ([]mongo.WriteModel, error) {
+	upserts := make([]mongo.WriteModel, len(models))
+	for i, model := range models {
+		insert, ok := model.(*mongo.InsertOneModel)
+		if !ok {
+			upserts[i] = model
+			continue
+		}
+		raw, ok := insert.Document.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("cannot retry the insert of a %T", insert.Document)
+		}
+		id, err := bson.Raw(raw).LookupErr("_id")
+		if err != nil {
+			return nil, fmt.Errorf("cannot retry the insert of a document without an _id")
+		}
+		upserts[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: id}}).
+			SetReplacement(raw).
+			SetUpsert(true)
+	}
+	return upserts, nil
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/mongodb/mongo-tools/common/testtype"
 	. "github.com/smartystreets/goconvey/convey"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func TestBufferedBulkInserterInserts(t *testing.T) {
@@ -164,3 +165,75 @@ func TestBufferedBulkInserterInserts(t *testing.T) {
 	})
 
 }
+
+func TestInsertsAsUpserts(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("Retried inserts become upserts by _id", t, func() {
+		raw, err := bson.Marshal(bson.D{{"_id", "abc"}, {"x", 1}})
+		So(err, ShouldBeNil)
+		del := mongo.NewDeleteOneModel().SetFilter(bson.D{{"y", 1}})
+
+		models, err := insertsAsUpserts([]mongo.WriteModel{
+			mongo.NewInsertOneModel().SetDocument(raw),
+			del,
+		})
+		So(err, ShouldBeNil)
+		So(models, ShouldHaveLength, 2)
+		upsert, ok := models[0].(*mongo.ReplaceOneModel)
+		So(ok, ShouldBeTrue)
+		So(*upsert.Upsert, ShouldBeTrue)
+		So(upsert.Replacement, ShouldResemble, raw)
+		filter := upsert.Filter.(bson.D)
+		So(filter[0].Key, ShouldEqual, "_id")
+		So(filter[0].Value.(bson.RawValue).StringValue(), ShouldEqual, "abc")
+		So(models[1], ShouldEqual, del)
+
+		Convey("which the documents must have", func() {
+			raw, err := bson.Marshal(bson.D{{"x", 1}})
+			So(err, ShouldBeNil)
+			_, err = insertsAsUpserts([]mongo.WriteModel{mongo.NewInsertOneModel().SetDocument(raw)})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestReplacedIDs(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("The upserts that matched a document replaced it", t, func() {
+		var inserts []mongo.WriteModel
+		for _, id := range []string{"a", "b", "c", "d"} {
+			raw, err := bson.Marshal(bson.D{{"_id", id}})
+			So(err, ShouldBeNil)
+			inserts = append(inserts, mongo.NewInsertOneModel().SetDocument(raw))
+		}
+		models, err := insertsAsUpserts(inserts)
+		So(err, ShouldBeNil)
+		// a was upserted, b and d replaced a document and c failed
+		result := &mongo.BulkWriteResult{
+			MatchedCount: 2,
+			UpsertedIDs:  map[int64]interface{}{0: "a"},
+		}
+		bulkErr := mongo.BulkWriteException{
+			WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Index: 2}}},
+		}
+
+		ids := replacedIDs(models, result, bulkErr, false)
+		So(ids, ShouldHaveLength, 2)
+		So(ids[0].StringValue(), ShouldEqual, "b")
+		So(ids[1].StringValue(), ShouldEqual, "d")
+
+		Convey("up to the first error of an ordered bulk write", func() {
+			result.MatchedCount = 1
+			ids := replacedIDs(models, result, bulkErr, true)
+			So(ids, ShouldHaveLength, 1)
+			So(ids[0].StringValue(), ShouldEqual, "b")
+		})
+
+		Convey("and none if no upsert matched", func() {
+			result.MatchedCount = 0
+			So(replacedIDs(models, result, nil, false), ShouldBeEmpty)
+		})
+	})
+}
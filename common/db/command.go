@@ -122,7 +122,11 @@ func (sp *SessionProvider) ServerVersionArray() (Version, error) {
 // DatabaseNames returns a slice containing the names of all the databases on the
 // connected server.
 func (sp *SessionProvider) DatabaseNames() ([]string, error) {
-	return sp.client.ListDatabaseNames(context.TODO(), bson.D{})
+	session, err := sp.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	return session.ListDatabaseNames(context.TODO(), bson.D{})
 }
 
 // CollectionNames returns the names of all the collections in the dbName database.
@@ -16,6 +16,7 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"strings"
 	"sync"
@@ -28,6 +29,7 @@ import (
 	"github.com/mongodb/mongo-tools/common/options"
 	"github.com/youmark/pkcs8"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	mopt "go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readconcern"
@@ -49,7 +51,8 @@ const (
 
 // MongoDB enforced limits.
 const (
-	MaxBSONSize = 16 * 1024 * 1024 // 16MB - maximum BSON document size
+	MaxBSONSize       = 16 * 1024 * 1024 // 16MB - maximum BSON document size
+	MaxWriteBatchSize = 100000           // maximum number of operations in a write batch
 )
 
 // Default port for integration tests.
@@ -65,6 +68,21 @@ const (
 
 	// ErrCannotInsertTimeseriesBucketsWithMixedSchema can be handled by turning TimeseriesBucketsWithMixedSchema off.
 	ErrCannotInsertTimeseriesBucketsWithMixedSchema = 408
+
+	// rate limiting errors, returned by serverless and Atlas Flex instances when a client
+	// exceeds its operation quota.
+	ErrIngressRequestRateLimitExceeded = 462
+	ErrAtlasError                      = 8000
+
+	// errors returned when the node serving a read is shutting down or changed
+	// replica set state, so the read must be retried elsewhere.
+	ErrCursorNotFound                  = 43
+	ErrShutdownInProgress              = 91
+	ErrPrimarySteppedDown              = 189
+	ErrInterruptedAtShutdown           = 11600
+	ErrInterruptedDueToReplStateChange = 11602
+	ErrNotPrimaryNoSecondaryOk         = 13435
+	ErrNotPrimaryOrSecondary           = 13436
 )
 
 var ignorableWriteErrorCodes = mapset.NewSet(
@@ -72,6 +90,18 @@ var ignorableWriteErrorCodes = mapset.NewSet(
 	ErrFailedDocumentValidation,
 )
 
+// nodeUnavailableErrorCodes are returned when the node serving a read went away
+// or changed state.
+var nodeUnavailableErrorCodes = mapset.NewSet(
+	ErrCursorNotFound,
+	ErrShutdownInProgress,
+	ErrPrimarySteppedDown,
+	ErrInterruptedAtShutdown,
+	ErrInterruptedDueToReplStateChange,
+	ErrNotPrimaryNoSecondaryOk,
+	ErrNotPrimaryOrSecondary,
+)
+
 const (
 	continueThroughErrorFormat = "continuing through error: %v"
 )
@@ -82,6 +112,14 @@ type SessionProvider struct {
 
 	// the master client used for operations
 	client *mongo.Client
+
+	// the connections of the client's pools
+	poolStats *PoolStats
+
+	// srv replaces the client when the hosts of a mongodb+srv connection
+	// string change, and retired holds the clients it replaced
+	srv     *srvRefresher
+	retired []*mongo.Client
 }
 
 // Returns a mongo.Client connected to the database server for which the
@@ -99,22 +137,51 @@ func (sp *SessionProvider) GetSession() (*mongo.Client, error) {
 
 // Close closes the master session in the connection pool.
 func (sp *SessionProvider) Close() {
+	if sp.srv != nil {
+		sp.srv.Stop()
+	}
 	sp.Lock()
 	defer sp.Unlock()
 	if sp.client != nil {
+		if sp.poolStats != nil {
+			log.Logvf(log.Info, "connection pool: %v", sp.poolStats)
+		}
 		_ = sp.client.Disconnect(context.Background())
 		sp.client = nil
 	}
+	for _, client := range sp.retired {
+		_ = client.Disconnect(context.Background())
+	}
+	sp.retired = nil
+}
+
+// PoolStats returns the connection pool counts of the client, or nil if the
+// provider wasn't created by NewSessionProvider.
+func (sp *SessionProvider) PoolStats() *PoolStats {
+	return sp.poolStats
 }
 
 // DB provides a database with the default read preference.
 func (sp *SessionProvider) DB(name string) *mongo.Database {
+	sp.Lock()
+	defer sp.Unlock()
 	return sp.client.Database(name)
 }
 
 // NewSessionProvider constructs a session provider, including a connected client.
 func NewSessionProvider(opts options.ToolOptions) (*SessionProvider, error) {
-	client, err := configureClient(opts)
+	var slowWait time.Duration
+	if opts.Connection != nil {
+		slowWait = time.Duration(opts.Connection.PoolWaitWarningMS) * time.Millisecond
+	}
+	poolStats := newPoolStats(slowWait)
+	monitor := poolStats.Monitor()
+	srv := newSRVRefresher(opts, monitor)
+	clientMonitor := monitor
+	if srv != nil {
+		clientMonitor = trackInFlight(monitor, srv.inFlight)
+	}
+	client, err := configureClient(opts, clientMonitor)
 	if err != nil {
 		return nil, fmt.Errorf("error configuring the connector: %v", err)
 	}
@@ -124,11 +191,31 @@ func NewSessionProvider(opts options.ToolOptions) (*SessionProvider, error) {
 	}
 	err = client.Ping(context.Background(), nil)
 	if err != nil {
+		// the driver only reports that no server could be selected, so probe
+		// the hosts to explain why
+		if report, preflightErr := Preflight(opts); preflightErr == nil {
+			return nil, fmt.Errorf(
+				"failed to connect to %s: %v\n%v",
+				opts.URI.ParsedConnString(),
+				err,
+				report,
+			)
+		}
 		return nil, fmt.Errorf("failed to connect to %s: %v", opts.URI.ParsedConnString(), err)
 	}
 
 	// create the provider
-	return &SessionProvider{client: client}, nil
+	sp := &SessionProvider{client: client, poolStats: poolStats}
+	if srv != nil {
+		// the driver polls the SRV record of sharded clusters itself
+		if isMongos, err := sp.IsMongos(); err == nil && isMongos {
+			log.Logv(log.Info, "the driver refreshes the SRV record of a sharded cluster")
+		} else {
+			sp.srv = srv
+			go sp.srv.run(sp.useClient)
+		}
+	}
+	return sp, nil
 }
 
 // addClientCertFromFile adds a client certificate to the configuration given a path to the
@@ -317,7 +404,23 @@ func AKSCallback(
 }
 
 // configure the client according to the options set in the uri and in the provided ToolOptions, with ToolOptions having precedence.
-func configureClient(opts options.ToolOptions) (*mongo.Client, error) {
+func configureClient(
+	opts options.ToolOptions,
+	poolMonitor *event.PoolMonitor,
+) (*mongo.Client, error) {
+	clientopt, err := configureClientOptions(opts, poolMonitor)
+	if err != nil {
+		return nil, err
+	}
+	return mongo.NewClient(clientopt)
+}
+
+// configureClientOptions returns the driver options for the connection of
+// opts.
+func configureClientOptions(
+	opts options.ToolOptions,
+	poolMonitor *event.PoolMonitor,
+) (*mopt.ClientOptions, error) {
 	if opts.URI == nil || opts.URI.ConnectionString == "" {
 		// XXX Normal operations shouldn't ever reach here because a URI should
 		// be created in options parsing, but tests still manually construct
@@ -396,6 +499,14 @@ func configureClient(opts options.ToolOptions) (*mongo.Client, error) {
 		clientopt.SetMinPoolSize(cs.MinPoolSize)
 	}
 
+	if cs.MaxConnectingSet {
+		clientopt.SetMaxConnecting(cs.MaxConnecting)
+	}
+
+	if poolMonitor != nil {
+		clientopt.SetPoolMonitor(poolMonitor)
+	}
+
 	if cs.LoadBalancedSet {
 		clientopt.SetLoadBalanced(cs.LoadBalanced)
 	}
@@ -506,6 +617,18 @@ func configureClient(opts options.ToolOptions) (*mongo.Client, error) {
 		clientopt.SetAuth(cred)
 	}
 
+	var dialer mopt.ContextDialer
+	if opts.Connection.Proxy != "" {
+		proxy, err := newProxyDialer(opts.Connection.Proxy, time.Duration(opts.Timeout)*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("error configuring client, invalid --proxy: %v", err)
+		}
+		dialer = proxy
+	}
+	if opts.SSL != nil && opts.SSLHostsFile != "" && !opts.UseSSL {
+		return nil, fmt.Errorf("--sslHostsFile requires --ssl")
+	}
+
 	if opts.SSL != nil && opts.UseSSL {
 		// Error on unsupported features
 		if opts.SSLFipsMode {
@@ -558,14 +681,28 @@ func configureClient(opts options.ToolOptions) (*mongo.Client, error) {
 			clientopt.Auth.Username = extractX509UsernameFromSubject(x509Subject)
 		}
 
-		clientopt.SetTLSConfig(tlsConfig)
+		if opts.SSLHostsFile != "" {
+			hosts, err := loadTLSHosts(opts.SSLHostsFile, tlsConfig)
+			if err != nil {
+				return nil, fmt.Errorf("error configuring client, can't load --sslHostsFile: %v", err)
+			}
+			if dialer == nil {
+				dialer = &net.Dialer{Timeout: time.Duration(opts.Timeout) * time.Second}
+			}
+			dialer = &tlsHostsDialer{next: dialer, base: tlsConfig, hosts: hosts}
+		} else {
+			clientopt.SetTLSConfig(tlsConfig)
+		}
+	}
+	if dialer != nil {
+		clientopt.SetDialer(dialer)
 	}
 
 	if cs.SSLDisableOCSPEndpointCheckSet {
 		clientopt.SetDisableOCSPEndpointCheck(cs.SSLDisableOCSPEndpointCheck)
 	}
 
-	return mongo.NewClient(clientopt)
+	return clientopt, nil
 }
 
 // FilterError determines whether an error needs to be propagated back to the user or can be continued through. If an
@@ -618,6 +755,49 @@ func TimeseriesBucketNeedsMixedSchema(err error) bool {
 		mongoErr.HasErrorCode(ErrCannotInsertTimeseriesBucketsWithMixedSchema)
 }
 
+// IsRateLimitError returns whether the given error indicates that the server rejected the
+// operation because the client exceeded its request quota. Such errors are transient and the
+// operation can be retried after backing off.
+func IsRateLimitError(err error) bool {
+	var mongoErr mongo.ServerError
+	if !errors.As(err, &mongoErr) {
+		return false
+	}
+
+	if mongoErr.HasErrorCode(ErrIngressRequestRateLimitExceeded) {
+		return true
+	}
+
+	// Atlas reports all of its proxy-level errors with the generic AtlasError code, so we
+	// need to look at the message to tell quota errors apart from the rest.
+	if mongoErr.HasErrorCode(ErrAtlasError) {
+		msg := strings.ToLower(mongoErr.Error())
+		return strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests")
+	}
+
+	return false
+}
+
+// IsNodeUnavailableError returns whether the given error indicates that the node
+// a read was sent to has become unreachable or can no longer serve the read, so
+// that the read may be retried against another eligible member of the replica set.
+func IsNodeUnavailableError(err error) bool {
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+
+	var mongoErr mongo.ServerError
+	if errors.As(err, &mongoErr) {
+		for code := range nodeUnavailableErrorCodes.Iter() {
+			if mongoErr.HasErrorCode(code) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // IsMMAPV1 returns whether the storage engine is MMAPV1. Also returns false
 // if the storage engine type cannot be determined for some reason.
 func IsMMAPV1(database *mongo.Database, collectionName string) (bool, error) {
@@ -8,6 +8,7 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"testing"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/mongodb/mongo-tools/common/testtype"
 	. "github.com/smartystreets/goconvey/convey"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
 )
 
@@ -133,7 +135,7 @@ func TestConfigureClientForSRV(t *testing.T) {
 		)
 		So(err, ShouldBeNil)
 
-		_, err = configureClient(*toolOptions)
+		_, err = configureClient(*toolOptions, nil)
 		So(err, ShouldBeNil)
 	})
 
@@ -448,7 +450,7 @@ func TestConfigureClientMultipleHosts(t *testing.T) {
 		)
 		So(err, ShouldBeNil)
 
-		_, err = configureClient(*toolOptions)
+		_, err = configureClient(*toolOptions, nil)
 		So(err, ShouldBeNil)
 	})
 }
@@ -479,7 +481,7 @@ func TestConfigureClientAKS(t *testing.T) {
 			)
 			So(err, ShouldBeNil)
 
-			_, err = configureClient(*toolOptions)
+			_, err = configureClient(*toolOptions, nil)
 			So(err, ShouldBeNil)
 			So(toolOptions.Auth.Mechanism, ShouldEqual, "MONGODB-OIDC")
 			os.Unsetenv("AZURE_APP_CLIENT_ID")
@@ -515,7 +517,7 @@ func TestMissConfigureClientAKS(t *testing.T) {
 			)
 			So(err, ShouldBeNil)
 
-			_, err = configureClient(*toolOptions)
+			_, err = configureClient(*toolOptions, nil)
 			So(err, ShouldNotBeNil)
 			os.Unsetenv("AZURE_APP_CLIENT_ID")
 			os.Unsetenv("AZURE_IDENTITY_CLIENT_ID")
@@ -523,3 +525,35 @@ func TestMissConfigureClientAKS(t *testing.T) {
 		},
 	)
 }
+
+func TestIsRateLimitError(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With various server errors", t, func() {
+		Convey("IngressRequestRateLimitExceeded should be a rate limit error", func() {
+			err := mongo.CommandError{Code: ErrIngressRequestRateLimitExceeded}
+			So(IsRateLimitError(err), ShouldBeTrue)
+		})
+
+		Convey("AtlasError about rate limits should be a rate limit error", func() {
+			err := mongo.CommandError{
+				Code:    ErrAtlasError,
+				Message: "Rate limit exceeded for this cluster, please retry later",
+			}
+			So(IsRateLimitError(err), ShouldBeTrue)
+
+			err.Message = "Too many requests"
+			So(IsRateLimitError(fmt.Errorf("error reading collection: %w", err)), ShouldBeTrue)
+		})
+
+		Convey("Other AtlasErrors should not be rate limit errors", func() {
+			err := mongo.CommandError{Code: ErrAtlasError, Message: "user is not allowed"}
+			So(IsRateLimitError(err), ShouldBeFalse)
+		})
+
+		Convey("Non-server errors should not be rate limit errors", func() {
+			So(IsRateLimitError(nil), ShouldBeFalse)
+			So(IsRateLimitError(fmt.Errorf("rate limit")), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,128 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package db
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// MaxDocumentDepth is the number of levels of embedded documents and arrays
+// the server lets a document have.
+const MaxDocumentDepth = 100
+
+// DocumentValidator checks that documents can be written before they are
+// sent, so that a document the server would reject fails on its own, with
+// the same error in every tool, instead of failing the batch it is sent in.
+type DocumentValidator struct {
+	// MaxSize is the size in bytes of the largest document; zero means
+	// MaxBSONSize.
+	MaxSize int
+	// MaxDepth is the deepest a document may nest; zero means
+	// MaxDocumentDepth.
+	MaxDepth int
+}
+
+// InvalidDocumentError is the error for a document that a DocumentValidator
+// rejects.
+type InvalidDocumentError struct {
+	// ID is the _id of the document, if it has one.
+	ID interface{}
+	// Reason describes what is wrong with the document.
+	Reason string
+}
+
+func (e InvalidDocumentError) Error() string {
+	if e.ID == nil {
+		return fmt.Sprintf("invalid document: %v", e.Reason)
+	}
+	return fmt.Sprintf("invalid document with _id %v: %v", e.ID, e.Reason)
+}
+
+// Validate returns an InvalidDocumentError if doc is too large, nests too
+// deeply, has a field name that isn't UTF-8, or has an _id that the server
+// doesn't allow.
+func (v DocumentValidator) Validate(doc bson.Raw) error {
+	invalid := InvalidDocumentError{}
+	id, err := doc.LookupErr("_id")
+	hasID := err == nil
+	if hasID {
+		//nolint:errcheck
+		id.Unmarshal(&invalid.ID)
+	}
+
+	maxSize := v.MaxSize
+	if maxSize == 0 {
+		maxSize = MaxBSONSize
+	}
+	if len(doc) > maxSize {
+		invalid.Reason = fmt.Sprintf(
+			"its size of %v bytes is over the maximum of %v bytes",
+			len(doc),
+			maxSize,
+		)
+		return invalid
+	}
+
+	if err := doc.Validate(); err != nil {
+		invalid.Reason = fmt.Sprintf("it is not valid BSON: %v", err)
+		return invalid
+	}
+
+	if hasID {
+		switch id.Type {
+		case bsontype.Array, bsontype.Regex, bsontype.Undefined:
+			invalid.Reason = fmt.Sprintf("its _id cannot be of type %v", id.Type)
+			return invalid
+		}
+	}
+
+	maxDepth := v.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = MaxDocumentDepth
+	}
+	if reason := validateFields(doc, 0, maxDepth); reason != "" {
+		invalid.Reason = reason
+		return invalid
+	}
+	return nil
+}
+
+// validateFields checks the field names of a document nested depth levels
+// deep, and the documents and arrays in it, and returns what is wrong with
+// them.
+func validateFields(doc bson.Raw, depth, maxDepth int) string {
+	if depth > maxDepth {
+		return fmt.Sprintf("it nests deeper than %v levels", maxDepth)
+	}
+	elements, err := doc.Elements()
+	if err != nil {
+		return fmt.Sprintf("it is not valid BSON: %v", err)
+	}
+	for _, element := range elements {
+		if key := element.Key(); !utf8.ValidString(key) {
+			return fmt.Sprintf("field name %q is not valid UTF-8", key)
+		}
+		value := element.Value()
+		var nested bson.Raw
+		switch value.Type {
+		case bsontype.EmbeddedDocument:
+			nested = value.Document()
+		case bsontype.Array:
+			nested = bson.Raw(value.Array())
+		default:
+			continue
+		}
+		if reason := validateFields(nested, depth+1, maxDepth); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
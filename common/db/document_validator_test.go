@@ -0,0 +1,109 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// nestedDocument returns a document with depth levels of embedded documents
+// and arrays.
+func nestedDocument(depth int) bson.D {
+	var value interface{} = 1
+	for i := 0; i < depth; i++ {
+		if i%2 == 0 {
+			value = bson.D{{"d", value}}
+		} else {
+			value = bson.A{value}
+		}
+	}
+	return bson.D{{"v", value}}
+}
+
+func TestDocumentValidator(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	validate := func(v DocumentValidator, doc interface{}) error {
+		raw, err := bson.Marshal(doc)
+		require.NoError(t, err)
+		return v.Validate(raw)
+	}
+
+	t.Run("valid documents", func(t *testing.T) {
+		for _, doc := range []bson.D{
+			{},
+			{{"_id", primitive.NewObjectID()}, {"a", "b"}},
+			{{"$set", 1}, {"a.b", bson.D{{"$c", 1}}}},
+			nestedDocument(MaxDocumentDepth),
+		} {
+			assert.NoError(t, validate(DocumentValidator{}, doc))
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		doc := bson.D{{"_id", 7}, {"s", strings.Repeat("x", 100)}}
+		assert.NoError(t, validate(DocumentValidator{MaxSize: 200}, doc))
+		err := validate(DocumentValidator{MaxSize: 100}, doc)
+		assert.EqualError(
+			t,
+			err,
+			"invalid document with _id 7: its size of 122 bytes is over the maximum of 100 bytes",
+		)
+		assert.IsType(t, InvalidDocumentError{}, err)
+
+		big := bson.D{{"s", strings.Repeat("x", MaxBSONSize)}}
+		assert.EqualError(
+			t,
+			validate(DocumentValidator{}, big),
+			"invalid document: its size of 16777229 bytes is over the maximum of 16777216 bytes",
+		)
+	})
+
+	t.Run("depth", func(t *testing.T) {
+		assert.EqualError(
+			t,
+			validate(DocumentValidator{}, nestedDocument(MaxDocumentDepth+1)),
+			"invalid document: it nests deeper than 100 levels",
+		)
+		assert.NoError(t, validate(DocumentValidator{MaxDepth: 3}, nestedDocument(3)))
+		assert.Error(t, validate(DocumentValidator{MaxDepth: 3}, nestedDocument(4)))
+	})
+
+	t.Run("_id", func(t *testing.T) {
+		for _, id := range []interface{}{
+			bson.A{1},
+			primitive.Regex{Pattern: "a"},
+			primitive.Undefined{},
+		} {
+			err := validate(DocumentValidator{}, bson.D{{"_id", id}})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "its _id cannot be of type")
+		}
+		assert.NoError(t, validate(DocumentValidator{}, bson.D{{"_id", bson.D{{"a", 1}}}}))
+	})
+
+	t.Run("field names", func(t *testing.T) {
+		err := validate(DocumentValidator{}, bson.D{{"a", bson.D{{"b\xff", 1}}}})
+		assert.EqualError(t, err, `invalid document: field name "b\xff" is not valid UTF-8`)
+	})
+
+	t.Run("malformed BSON", func(t *testing.T) {
+		raw, err := bson.Marshal(bson.D{{"a", "b"}})
+		require.NoError(t, err)
+		raw[7] = 100
+		err = DocumentValidator{}.Validate(raw)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "it is not valid BSON")
+	})
+}
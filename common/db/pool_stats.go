@@ -0,0 +1,145 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package db
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// slowCheckoutLogInterval is the least time between two warnings about
+// slow connection checkouts.
+const slowCheckoutLogInterval = 10 * time.Second
+
+// PoolStats counts the connections of a client's pools, summed over all the
+// servers it talks to. It is updated from the driver's pool events, so that
+// workers that stall waiting for a connection can be told apart from slow
+// servers.
+type PoolStats struct {
+	mu sync.Mutex
+
+	// Open is the number of open connections.
+	Open int64
+	// InUse is the number of connections checked out by operations.
+	InUse int64
+	// MaxInUse is the highest InUse so far.
+	MaxInUse int64
+	// Waiting is the number of operations waiting to check out a connection.
+	Waiting int64
+	// CheckoutFailures is the number of checkouts that failed.
+	CheckoutFailures int64
+	// SlowCheckouts is the number of checkouts that took longer than the
+	// --poolWaitWarningMS threshold.
+	SlowCheckouts int64
+	// MaxWait is the longest time a checkout took.
+	MaxWait time.Duration
+
+	slowWait    time.Duration
+	lastWarning time.Time
+}
+
+// newPoolStats returns a PoolStats that warns about checkouts that take
+// longer than slowWait, unless it is zero.
+func newPoolStats(slowWait time.Duration) *PoolStats {
+	return &PoolStats{slowWait: slowWait}
+}
+
+// Monitor returns the driver pool monitor that updates stats.
+func (stats *PoolStats) Monitor() *event.PoolMonitor {
+	return &event.PoolMonitor{Event: stats.handleEvent}
+}
+
+func (stats *PoolStats) handleEvent(evt *event.PoolEvent) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	switch evt.Type {
+	case event.ConnectionCreated:
+		stats.Open++
+	case event.ConnectionClosed:
+		stats.Open--
+	case event.GetStarted:
+		stats.Waiting++
+	case event.GetFailed:
+		stats.Waiting--
+		stats.CheckoutFailures++
+		stats.recordWait(evt)
+	case event.GetSucceeded:
+		stats.Waiting--
+		stats.InUse++
+		if stats.InUse > stats.MaxInUse {
+			stats.MaxInUse = stats.InUse
+		}
+		stats.recordWait(evt)
+	case event.ConnectionReturned:
+		stats.InUse--
+	}
+}
+
+// recordWait must be called with the lock held.
+func (stats *PoolStats) recordWait(evt *event.PoolEvent) {
+	if evt.Duration > stats.MaxWait {
+		stats.MaxWait = evt.Duration
+	}
+	if stats.slowWait == 0 || evt.Duration < stats.slowWait {
+		return
+	}
+	stats.SlowCheckouts++
+	if time.Since(stats.lastWarning) < slowCheckoutLogInterval {
+		return
+	}
+	stats.lastWarning = time.Now()
+	log.Logvf(
+		log.Always,
+		"waited %v for a connection to %v (%v); "+
+			"consider raising --maxPoolSize or lowering the number of workers",
+		evt.Duration.Round(time.Millisecond),
+		evt.Address,
+		stats.summary(),
+	)
+}
+
+// Snapshot returns a copy of the current counts.
+func (stats *PoolStats) Snapshot() PoolStats {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	return PoolStats{
+		Open:             stats.Open,
+		InUse:            stats.InUse,
+		MaxInUse:         stats.MaxInUse,
+		Waiting:          stats.Waiting,
+		CheckoutFailures: stats.CheckoutFailures,
+		SlowCheckouts:    stats.SlowCheckouts,
+		MaxWait:          stats.MaxWait,
+	}
+}
+
+// String describes the current counts.
+func (stats *PoolStats) String() string {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	return stats.summary()
+}
+
+// summary must be called with the lock held.
+func (stats *PoolStats) summary() string {
+	return fmt.Sprintf(
+		"connections open: %v, in use: %v (max %v), waiting: %v, "+
+			"failed checkouts: %v, slow checkouts: %v, longest wait: %v",
+		stats.Open,
+		stats.InUse,
+		stats.MaxInUse,
+		stats.Waiting,
+		stats.CheckoutFailures,
+		stats.SlowCheckouts,
+		stats.MaxWait.Round(time.Millisecond),
+	)
+}
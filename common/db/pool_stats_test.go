@@ -0,0 +1,53 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func TestPoolStats(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	stats := newPoolStats(time.Second)
+	monitor := stats.Monitor()
+	for _, evt := range []event.PoolEvent{
+		{Type: event.ConnectionCreated},
+		{Type: event.ConnectionCreated},
+		{Type: event.GetStarted},
+		{Type: event.GetStarted},
+		{Type: event.GetStarted},
+		{Type: event.GetSucceeded, Duration: time.Millisecond},
+		{Type: event.GetSucceeded, Duration: 2 * time.Second},
+		{Type: event.ConnectionReturned},
+		{Type: event.GetFailed, Duration: 3 * time.Millisecond},
+		{Type: event.ConnectionClosed},
+	} {
+		monitor.Event(&evt)
+	}
+
+	assert.Equal(t, PoolStats{
+		Open:             1,
+		InUse:            1,
+		MaxInUse:         2,
+		Waiting:          0,
+		CheckoutFailures: 1,
+		SlowCheckouts:    1,
+		MaxWait:          2 * time.Second,
+	}, stats.Snapshot())
+	assert.Equal(
+		t,
+		"connections open: 1, in use: 1 (max 2), waiting: 0, "+
+			"failed checkouts: 1, slow checkouts: 1, longest wait: 2s",
+		stats.String(),
+	)
+}
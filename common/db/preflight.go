@@ -0,0 +1,460 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package db
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/options"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// preflightTimeout bounds each step of probing a host, so that the report is
+// ready well before the tools would have given up on the server themselves.
+const preflightTimeout = 5 * time.Second
+
+// saslSupportedMechsWireVersion is the wire version of 4.0, the first server
+// version that reports the authentication mechanisms of a user.
+const saslSupportedMechsWireVersion = 7
+
+// PreflightReport is what probing each host of a connection string found:
+// whether its name resolves, whether it accepts connections, the details of
+// the TLS handshake and the server's answer to isMaster. Problems explains
+// what stands in the way of connecting, and what to do about it.
+type PreflightReport struct {
+	Hosts    []HostProbe
+	Problems []string
+}
+
+// HostProbe is what probing one host found. The probe stops at the first step
+// that fails, which is described by Problem.
+type HostProbe struct {
+	Host      string
+	Addresses []string
+	TLS       *TLSProbe
+	Hello     *HelloProbe
+	Problem   string
+}
+
+// TLSProbe describes the TLS handshake with a host.
+type TLSProbe struct {
+	Version     string
+	CipherSuite string
+	Subject     string
+	Issuer      string
+	NotAfter    time.Time
+}
+
+// HelloProbe is the part of a server's isMaster response that tells how the
+// tools can connect to it.
+type HelloProbe struct {
+	Me                 string   `bson:"me"`
+	SetName            string   `bson:"setName"`
+	IsMaster           bool     `bson:"ismaster"`
+	Secondary          bool     `bson:"secondary"`
+	Msg                string   `bson:"msg"`
+	Hosts              []string `bson:"hosts"`
+	MaxWireVersion     int32    `bson:"maxWireVersion"`
+	SASLSupportedMechs []string `bson:"saslSupportedMechs"`
+}
+
+// prober probes the hosts of a connection string. Its network operations can
+// be replaced in tests.
+type prober struct {
+	clientopt *mopt.ClientOptions
+	// replicaSet is the replica set the connection string names, if any
+	replicaSet string
+	direct     bool
+
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+	dial       func(ctx context.Context, network, address string) (net.Conn, error)
+	hello      func(host string) (*HelloProbe, error)
+}
+
+// Preflight probes the hosts that opts connects to, one step at a time, to
+// explain why connecting fails where the driver only reports that no server
+// could be selected.
+func Preflight(opts options.ToolOptions) (*PreflightReport, error) {
+	clientopt, err := configureClientOptions(opts, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring the connector: %v", err)
+	}
+	p := newProber(clientopt)
+	return p.run(), nil
+}
+
+func newProber(clientopt *mopt.ClientOptions) *prober {
+	p := &prober{
+		clientopt:  clientopt,
+		direct:     clientopt.Direct != nil && *clientopt.Direct,
+		lookupHost: net.DefaultResolver.LookupHost,
+		dial:       (&net.Dialer{Timeout: preflightTimeout}).DialContext,
+	}
+	if clientopt.ReplicaSet != nil {
+		p.replicaSet = *clientopt.ReplicaSet
+	}
+	p.hello = p.runHello
+	return p
+}
+
+// run probes every host at once and looks for problems in what they answer.
+func (p *prober) run() *PreflightReport {
+	report := &PreflightReport{Hosts: make([]HostProbe, len(p.clientopt.Hosts))}
+	var wg sync.WaitGroup
+	for i, host := range p.clientopt.Hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Hosts[i] = p.probeHost(host)
+		}()
+	}
+	wg.Wait()
+	for _, probe := range report.Hosts {
+		if probe.Problem != "" {
+			report.Problems = append(report.Problems, probe.Problem)
+		}
+	}
+	report.Problems = append(report.Problems, p.diagnose(report.Hosts)...)
+	return report
+}
+
+// probeHost resolves host, connects to it, makes the TLS handshake if the
+// connection uses TLS, and runs isMaster on it.
+func (p *prober) probeHost(host string) HostProbe {
+	probe := HostProbe{Host: host}
+	network, address, name := "tcp", host, host
+	if strings.HasSuffix(host, ".sock") {
+		network = "unix"
+	} else {
+		var port string
+		var err error
+		if name, port, err = net.SplitHostPort(host); err != nil {
+			name, port = host, "27017"
+		}
+		address = net.JoinHostPort(name, port)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+	defer cancel()
+	if network == "tcp" && net.ParseIP(name) == nil {
+		addresses, err := p.lookupHost(ctx, name)
+		if err != nil {
+			probe.Problem = fmt.Sprintf(
+				"%v: cannot resolve the host name: %v. Check the host name in the connection "+
+					"string, and that the DNS of this machine can resolve it",
+				host,
+				err,
+			)
+			return probe
+		}
+		probe.Addresses = addresses
+	}
+
+	conn, err := p.dial(ctx, network, address)
+	if err != nil {
+		probe.Problem = dialProblem(host, err)
+		return probe
+	}
+	defer conn.Close()
+
+	if p.clientopt.TLSConfig != nil {
+		config := p.clientopt.TLSConfig.Clone()
+		if config.ServerName == "" && network == "tcp" {
+			config.ServerName = name
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			probe.Problem = tlsProblem(host, err)
+			return probe
+		}
+		probe.TLS = newTLSProbe(tlsConn.ConnectionState())
+	}
+
+	if probe.Hello, err = p.hello(host); err != nil {
+		probe.Problem = fmt.Sprintf("%v: the server did not answer isMaster: %v", host, err)
+		if p.clientopt.TLSConfig == nil {
+			probe.Problem += ". If the server requires TLS, connect with --tls"
+		}
+	}
+	return probe
+}
+
+// runHello runs isMaster on host alone, without authenticating, asking for
+// the authentication mechanisms of the user of the connection.
+func (p *prober) runHello(host string) (*HelloProbe, error) {
+	clientopt := *p.clientopt
+	clientopt.Hosts = []string{host}
+	clientopt.ReplicaSet = nil
+	clientopt.LoadBalanced = nil
+	clientopt.Auth = nil
+	clientopt.SetDirect(true)
+	clientopt.SetServerSelectionTimeout(preflightTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+	defer cancel()
+	client, err := mongo.Connect(ctx, &clientopt)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Disconnect(context.Background()) //nolint:errcheck
+
+	command := bson.D{{"isMaster", 1}}
+	if auth := p.clientopt.Auth; auth != nil && auth.Username != "" {
+		command = append(command, bson.E{"saslSupportedMechs", authSource(auth) + "." + auth.Username})
+	}
+	var hello HelloProbe
+	if err := client.Database("admin").RunCommand(ctx, command).Decode(&hello); err != nil {
+		return nil, err
+	}
+	return &hello, nil
+}
+
+// diagnose finds the problems between the hosts' answers and the
+// connection string: a replica set that doesn't match, members that can't be
+// resolved, no primary, and a user the server can't authenticate as asked.
+func (p *prober) diagnose(probes []HostProbe) []string {
+	var problems []string
+	var primary bool
+	var setName string
+	var members []string
+	var hellos []HostProbe
+	for _, probe := range probes {
+		if probe.Hello == nil {
+			continue
+		}
+		hellos = append(hellos, probe)
+		hello := probe.Hello
+		primary = primary || hello.IsMaster
+		if hello.SetName != "" && setName == "" {
+			setName, members = hello.SetName, hello.Hosts
+		}
+
+		switch {
+		case p.replicaSet == "":
+		case hello.Msg == "isdbgrid":
+			problems = append(problems, fmt.Sprintf(
+				"%v is a mongos, which is not a member of replica set %v. "+
+					"Remove replicaSet from the connection string",
+				probe.Host, p.replicaSet))
+		case hello.SetName == "":
+			problems = append(problems, fmt.Sprintf(
+				"%v is not a member of a replica set, but the connection string names "+
+					"replica set %v. Remove replicaSet from the connection string",
+				probe.Host, p.replicaSet))
+		case hello.SetName != p.replicaSet:
+			problems = append(problems, fmt.Sprintf(
+				"%v is a member of replica set %v, not %v. Fix replicaSet in the connection string",
+				probe.Host, hello.SetName, p.replicaSet))
+		}
+	}
+	if len(hellos) == 0 {
+		return problems
+	}
+
+	if setName != "" && !p.direct {
+		if unresolved := p.unresolvedMembers(members, probes); len(unresolved) > 0 {
+			problems = append(problems, fmt.Sprintf(
+				"the members of replica set %v are configured as %v, which this machine "+
+					"cannot resolve, and the tools connect to the members by those names. "+
+					"Connect to a single member with directConnection=true, or fix the host "+
+					"names in the replica set configuration",
+				setName, strings.Join(unresolved, ", ")))
+		}
+		if !primary {
+			problems = append(problems, fmt.Sprintf(
+				"none of the hosts is the primary of replica set %v, so operations that need "+
+					"the primary will time out. Check the health of the replica set, or use a "+
+					"--readPreference that allows secondaries",
+				setName))
+		}
+	}
+
+	if problem := p.authProblem(hellos[0].Hello); problem != "" {
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+// unresolvedMembers returns the members of the replica set that aren't among
+// the hosts already probed and whose names don't resolve.
+func (p *prober) unresolvedMembers(members []string, probes []HostProbe) []string {
+	var unresolved []string
+	for _, member := range members {
+		if slices.ContainsFunc(probes, func(probe HostProbe) bool { return probe.Host == member }) {
+			continue
+		}
+		name, _, err := net.SplitHostPort(member)
+		if err != nil {
+			name = member
+		}
+		if net.ParseIP(name) != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+		_, err = p.lookupHost(ctx, name)
+		cancel()
+		if err != nil {
+			unresolved = append(unresolved, member)
+		}
+	}
+	return unresolved
+}
+
+// authProblem returns why the user of the connection can't authenticate with
+// the SCRAM mechanism asked for, from the mechanisms the server reports for
+// the user.
+func (p *prober) authProblem(hello *HelloProbe) string {
+	auth := p.clientopt.Auth
+	if auth == nil || auth.Username == "" || hello.MaxWireVersion < saslSupportedMechsWireVersion {
+		return ""
+	}
+	mechanism := strings.ToUpper(auth.AuthMechanism)
+	if mechanism != "" && !strings.HasPrefix(mechanism, "SCRAM-") {
+		return ""
+	}
+	if len(hello.SASLSupportedMechs) == 0 {
+		return fmt.Sprintf(
+			"the server has no user %v in the authentication database %v. "+
+				"Check --username and --authenticationDatabase",
+			auth.Username, authSource(auth))
+	}
+	if mechanism != "" && !slices.Contains(hello.SASLSupportedMechs, mechanism) {
+		return fmt.Sprintf(
+			"user %v cannot authenticate with %v; the server supports %v for them. "+
+				"Use --authenticationMechanism=%v",
+			auth.Username, mechanism, strings.Join(hello.SASLSupportedMechs, ", "),
+			hello.SASLSupportedMechs[0])
+	}
+	return ""
+}
+
+func authSource(auth *mopt.Credential) string {
+	if auth.AuthSource == "" {
+		return "admin"
+	}
+	return auth.AuthSource
+}
+
+// dialProblem describes an error connecting to host.
+func dialProblem(host string, err error) string {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Sprintf(
+			"%v: connecting timed out: %v. Check that firewalls, security groups and IP access "+
+				"lists allow connections from this machine",
+			host, err)
+	case strings.Contains(err.Error(), "connection refused"):
+		return fmt.Sprintf(
+			"%v: nothing accepts connections on this port: %v. Check the port, and that the "+
+				"server is running",
+			host, err)
+	}
+	return fmt.Sprintf("%v: cannot connect: %v", host, err)
+}
+
+// tlsProblem describes an error in the TLS handshake with host.
+func tlsProblem(host string, err error) string {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var recordHeader tls.RecordHeaderError
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return fmt.Sprintf(
+			"%v: the server's certificate is signed by an unknown authority: %v. "+
+				"Pass the certificate of the authority with --tlsCAFile",
+			host, err)
+	case errors.As(err, &hostname):
+		return fmt.Sprintf(
+			"%v: the server's certificate is not valid for this host name: %v. Connect with a "+
+				"name the certificate lists, or use --tlsAllowInvalidHostnames",
+			host, err)
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		return fmt.Sprintf(
+			"%v: the server's certificate is expired or not yet valid: %v. "+
+				"Check the certificate, and the clock of this machine",
+			host, err)
+	case errors.As(err, &recordHeader):
+		return fmt.Sprintf(
+			"%v: the server did not answer the TLS handshake, so it may not use TLS: %v. "+
+				"Connect without --tls",
+			host, err)
+	}
+	return fmt.Sprintf("%v: the TLS handshake failed: %v", host, err)
+}
+
+func newTLSProbe(state tls.ConnectionState) *TLSProbe {
+	probe := &TLSProbe{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+	}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		probe.Subject = cert.Subject.String()
+		probe.Issuer = cert.Issuer.String()
+		probe.NotAfter = cert.NotAfter
+	}
+	return probe
+}
+
+// String returns the report, one line per host and per problem.
+func (r *PreflightReport) String() string {
+	var b strings.Builder
+	b.WriteString("preflight checks of the connection:")
+	for _, probe := range r.Hosts {
+		fmt.Fprintf(&b, "\n  %v", probe.Host)
+		var details []string
+		if len(probe.Addresses) > 0 {
+			details = append(details, "resolves to "+strings.Join(probe.Addresses, ", "))
+		}
+		if t := probe.TLS; t != nil {
+			details = append(details, fmt.Sprintf(
+				"%v with %v, certificate of %v issued by %v, valid until %v",
+				t.Version, t.CipherSuite, t.Subject, t.Issuer, t.NotAfter.Format(time.DateOnly)))
+		}
+		if h := probe.Hello; h != nil {
+			details = append(details, h.role()+fmt.Sprintf(", wire version %v", h.MaxWireVersion))
+		}
+		if probe.Problem != "" {
+			details = append(details, "failed")
+		}
+		if len(details) > 0 {
+			b.WriteString(": " + strings.Join(details, "; "))
+		}
+	}
+	for _, problem := range r.Problems {
+		fmt.Fprintf(&b, "\n  - %v", problem)
+	}
+	return b.String()
+}
+
+// role describes what the server is in its topology.
+func (h *HelloProbe) role() string {
+	switch {
+	case h.Msg == "isdbgrid":
+		return "mongos"
+	case h.SetName == "":
+		return "standalone"
+	case h.IsMaster:
+		return "primary of replica set " + h.SetName
+	case h.Secondary:
+		return "secondary of replica set " + h.SetName
+	}
+	return "member of replica set " + h.SetName + " that is neither primary nor secondary"
+}
@@ -0,0 +1,205 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package db
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// newTestProber returns a prober of hosts whose isMaster answers are hellos,
+// and whose names resolve unless they start with "unknown".
+func newTestProber(clientopt *mopt.ClientOptions, hellos map[string]*HelloProbe) *prober {
+	p := newProber(clientopt)
+	p.lookupHost = func(_ context.Context, host string) ([]string, error) {
+		if strings.HasPrefix(host, "unknown") {
+			return nil, errors.New("no such host")
+		}
+		return []string{"10.0.0.1"}, nil
+	}
+	p.hello = func(host string) (*HelloProbe, error) {
+		if hello, ok := hellos[host]; ok {
+			return hello, nil
+		}
+		return nil, errors.New("connection reset by peer")
+	}
+	return p
+}
+
+func TestPreflightProbeHost(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	handler := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	t.Run("host names that don't resolve", func(t *testing.T) {
+		p := newTestProber(mopt.Client().SetHosts([]string{"unknown.example.com"}), nil)
+		probe := p.probeHost("unknown.example.com")
+		assert.Contains(t, probe.Problem, "cannot resolve the host name")
+	})
+
+	t.Run("ports that refuse connections", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		host := listener.Addr().String()
+		require.NoError(t, listener.Close())
+
+		probe := newTestProber(mopt.Client(), nil).probeHost(host)
+		assert.Contains(t, probe.Problem, "nothing accepts connections")
+	})
+
+	t.Run("servers that don't answer isMaster without TLS", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		host := server.Listener.Addr().String()
+
+		probe := newTestProber(mopt.Client(), nil).probeHost(host)
+		assert.Contains(t, probe.Problem, "did not answer isMaster")
+		assert.Contains(t, probe.Problem, "--tls")
+	})
+
+	t.Run("certificates of an unknown authority", func(t *testing.T) {
+		server := httptest.NewTLSServer(handler)
+		defer server.Close()
+		host := server.Listener.Addr().String()
+
+		clientopt := mopt.Client().SetTLSConfig(&tls.Config{})
+		probe := newTestProber(clientopt, nil).probeHost(host)
+		assert.Contains(t, probe.Problem, "--tlsCAFile")
+		assert.Nil(t, probe.TLS)
+	})
+
+	t.Run("servers that don't use TLS", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		host := server.Listener.Addr().String()
+
+		clientopt := mopt.Client().SetTLSConfig(&tls.Config{})
+		probe := newTestProber(clientopt, nil).probeHost(host)
+		assert.Contains(t, probe.Problem, "Connect without --tls")
+	})
+
+	t.Run("successful handshakes are described", func(t *testing.T) {
+		server := httptest.NewTLSServer(handler)
+		defer server.Close()
+		host := server.Listener.Addr().String()
+
+		roots := x509.NewCertPool()
+		roots.AddCert(server.Certificate())
+		clientopt := mopt.Client().SetTLSConfig(&tls.Config{RootCAs: roots})
+		hello := &HelloProbe{IsMaster: true, MaxWireVersion: 21}
+		probe := newTestProber(clientopt, map[string]*HelloProbe{host: hello}).probeHost(host)
+		assert.Empty(t, probe.Problem)
+		require.NotNil(t, probe.TLS)
+		assert.NotEmpty(t, probe.TLS.Version)
+		assert.Equal(t, hello, probe.Hello)
+	})
+}
+
+func TestPreflightDiagnose(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	primary := &HelloProbe{
+		SetName:            "rs0",
+		IsMaster:           true,
+		Hosts:              []string{"a:27017", "b:27017"},
+		MaxWireVersion:     21,
+		SASLSupportedMechs: []string{"SCRAM-SHA-256"},
+	}
+	secondary := &HelloProbe{SetName: "rs0", Secondary: true, Hosts: primary.Hosts}
+	probes := func(hellos ...*HelloProbe) []HostProbe {
+		var probes []HostProbe
+		for i, hello := range hellos {
+			probes = append(probes, HostProbe{Host: string(rune('a'+i)) + ":27017", Hello: hello})
+		}
+		return probes
+	}
+	diagnose := func(clientopt *mopt.ClientOptions, probes []HostProbe) []string {
+		return newTestProber(clientopt, nil).diagnose(probes)
+	}
+
+	assert.Empty(t, diagnose(mopt.Client().SetReplicaSet("rs0"), probes(primary, secondary)))
+
+	t.Run("replica sets that don't match", func(t *testing.T) {
+		problems := diagnose(mopt.Client().SetReplicaSet("rs1"), probes(primary))
+		require.Len(t, problems, 1)
+		assert.Contains(t, problems[0], "is a member of replica set rs0, not rs1")
+
+		standalone := &HelloProbe{MaxWireVersion: 21}
+		problems = diagnose(mopt.Client().SetReplicaSet("rs0"), probes(standalone))
+		require.Len(t, problems, 1)
+		assert.Contains(t, problems[0], "not a member of a replica set")
+
+		mongos := &HelloProbe{Msg: "isdbgrid", MaxWireVersion: 21}
+		problems = diagnose(mopt.Client().SetReplicaSet("rs0"), probes(mongos))
+		require.Len(t, problems, 1)
+		assert.Contains(t, problems[0], "is a mongos")
+	})
+
+	t.Run("replica sets without a primary", func(t *testing.T) {
+		problems := diagnose(mopt.Client(), probes(secondary, secondary))
+		require.Len(t, problems, 1)
+		assert.Contains(t, problems[0], "none of the hosts is the primary")
+
+		assert.Empty(t, diagnose(mopt.Client().SetDirect(true), probes(secondary)))
+	})
+
+	t.Run("members that don't resolve", func(t *testing.T) {
+		internal := *primary
+		internal.Hosts = []string{"a:27017", "unknown-1.internal:27017", "10.0.0.2:27017"}
+		problems := diagnose(mopt.Client(), probes(&internal))
+		require.Len(t, problems, 1)
+		assert.Contains(t, problems[0], "configured as unknown-1.internal:27017, which")
+	})
+
+	t.Run("users the server can't authenticate", func(t *testing.T) {
+		auth := mopt.Credential{Username: "app", AuthSource: "admin", AuthMechanism: "SCRAM-SHA-1"}
+		problems := diagnose(mopt.Client().SetAuth(auth), probes(primary))
+		require.Len(t, problems, 1)
+		assert.Contains(t, problems[0], "--authenticationMechanism=SCRAM-SHA-256")
+
+		noUser := *primary
+		noUser.SASLSupportedMechs = nil
+		auth.AuthMechanism = ""
+		problems = diagnose(mopt.Client().SetAuth(auth), probes(&noUser))
+		require.Len(t, problems, 1)
+		assert.Contains(t, problems[0], "has no user app in the authentication database admin")
+
+		auth.AuthMechanism = "MONGODB-X509"
+		assert.Empty(t, diagnose(mopt.Client().SetAuth(auth), probes(&noUser)))
+	})
+}
+
+func TestPreflightRun(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closed := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	p := newTestProber(mopt.Client().SetHosts([]string{"unknown.example.com", closed}), nil)
+	report := p.run()
+	require.Len(t, report.Hosts, 2)
+	require.Len(t, report.Problems, 2)
+
+	text := report.String()
+	assert.Contains(t, text, "preflight checks of the connection")
+	assert.Contains(t, text, "unknown.example.com: failed")
+	assert.Contains(t, text, "- "+closed+": nothing accepts connections")
+}
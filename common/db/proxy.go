@@ -0,0 +1,242 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package db
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// proxyDialer connects to the servers through the SOCKS5 or HTTP CONNECT
+// proxy of --proxy, e.g. the proxy of a bastion host. The proxy resolves the
+// host names of the servers.
+type proxyDialer struct {
+	proxy *url.URL
+	next  mopt.ContextDialer
+}
+
+// newProxyDialer returns a proxyDialer for the proxy URL rawURL, which must be
+// socks5://[<user>:<password>@]<host>:<port> or
+// http://[<user>:<password>@]<host>:<port>.
+func newProxyDialer(rawURL string, timeout time.Duration) (*proxyDialer, error) {
+	proxy, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch proxy.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme '%v', expected socks5 or http", proxy.Scheme)
+	}
+	if proxy.Hostname() == "" || proxy.Port() == "" {
+		return nil, fmt.Errorf("the proxy URL must include a host and a port")
+	}
+	if proxy.Path != "" && proxy.Path != "/" {
+		return nil, fmt.Errorf("the proxy URL must not include a path")
+	}
+	return &proxyDialer{proxy: proxy, next: &net.Dialer{Timeout: timeout}}, nil
+}
+
+// DialContext connects to the proxy, and asks it for a connection to address.
+func (d *proxyDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.next.DialContext(ctx, network, d.proxy.Host)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to proxy %v: %v", d.proxy.Host, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if d.proxy.Scheme == "http" {
+		err = d.connectHTTP(conn, address)
+	} else {
+		err = d.connectSOCKS5(conn, address)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %v could not connect to %v: %v", d.proxy.Host, address, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// connectHTTP asks an HTTP proxy for a tunnel to address with the CONNECT
+// method.
+func (d *proxyDialer) connectHTTP(conn net.Conn, address string) error {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	if user := d.proxy.User; user != nil {
+		pass, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("CONNECT failed: %v", resp.Status)
+	}
+	if reader.Buffered() > 0 {
+		// the server only speaks once the client has, so nothing can follow
+		return fmt.Errorf("unexpected data after the CONNECT response")
+	}
+	return nil
+}
+
+// The SOCKS5 protocol of RFC 1928 and its username authentication of RFC
+// 1929.
+const (
+	socks5Version        = 0x05
+	socks5NoAuth         = 0x00
+	socks5UserPassAuth   = 0x02
+	socks5NoAcceptable   = 0xff
+	socks5Connect        = 0x01
+	socks5IPv4           = 0x01
+	socks5DomainName     = 0x03
+	socks5IPv6           = 0x04
+	socks5Succeeded      = 0x00
+	socks5UserPassAuthV1 = 0x01
+)
+
+var socks5Errors = map[byte]string{
+	0x01: "general SOCKS server failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// connectSOCKS5 asks a SOCKS5 proxy for a connection to address.
+func (d *proxyDialer) connectSOCKS5(conn net.Conn, address string) error {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port in %v", address)
+	}
+
+	methods := []byte{socks5NoAuth}
+	if d.proxy.User != nil {
+		methods = []byte{socks5UserPassAuth}
+	}
+	greeting := append([]byte{socks5Version, byte(len(methods))}, methods...)
+	if _, err := conn.Write(greeting); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("not a SOCKS5 proxy")
+	}
+	switch reply[1] {
+	case socks5NoAuth:
+	case socks5UserPassAuth:
+		if err := d.authenticateSOCKS5(conn); err != nil {
+			return err
+		}
+	case socks5NoAcceptable:
+		return fmt.Errorf("the proxy accepts none of the authentication methods")
+	default:
+		return fmt.Errorf("unexpected SOCKS5 authentication method %v", reply[1])
+	}
+
+	req := []byte{socks5Version, socks5Connect, 0x00}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("host name %v is too long", host)
+		}
+		req = append(req, socks5DomainName, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(append(req, socks5IPv4), ip4...)
+	} else {
+		req = append(append(req, socks5IPv6), ip.To16()...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != socks5Succeeded {
+		if msg, ok := socks5Errors[header[1]]; ok {
+			return fmt.Errorf("%v", msg)
+		}
+		return fmt.Errorf("SOCKS5 error %v", header[1])
+	}
+	// skip the address that the proxy bound
+	var length int
+	switch header[3] {
+	case socks5IPv4:
+		length = net.IPv4len
+	case socks5IPv6:
+		length = net.IPv6len
+	case socks5DomainName:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return err
+		}
+		length = int(size[0])
+	default:
+		return fmt.Errorf("unexpected SOCKS5 address type %v", header[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, length+2))
+	return err
+}
+
+func (d *proxyDialer) authenticateSOCKS5(conn net.Conn) error {
+	user := d.proxy.User.Username()
+	pass, _ := d.proxy.User.Password()
+	if len(user) > 255 || len(pass) > 255 {
+		return fmt.Errorf("the proxy user name and password must be at most 255 bytes")
+	}
+	req := []byte{socks5UserPassAuthV1, byte(len(user))}
+	req = append(req, user...)
+	req = append(req, byte(len(pass)))
+	req = append(req, pass...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != socks5Succeeded {
+		return fmt.Errorf("the proxy rejected the user name and password")
+	}
+	return nil
+}
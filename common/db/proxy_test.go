@@ -0,0 +1,157 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package db
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveProxy accepts one connection on a new listener, and runs handle on it.
+func serveProxy(t *testing.T, handle func(conn net.Conn)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle(conn)
+	}()
+	return listener.Addr().String()
+}
+
+func TestNewProxyDialer(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	for _, valid := range []string{"socks5://bastion:1080", "http://u:p@bastion:3128"} {
+		_, err := newProxyDialer(valid, time.Second)
+		assert.NoError(t, err, valid)
+	}
+	for _, invalid := range []string{
+		"https://bastion:443",
+		"socks5://bastion",
+		"http://bastion:80/x",
+	} {
+		_, err := newProxyDialer(invalid, time.Second)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	requested := make(chan string, 1)
+	address := serveProxy(t, func(conn net.Conn) {
+		greeting := make([]byte, 3)
+		if _, err := io.ReadFull(conn, greeting); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte{socks5Version, socks5UserPassAuth})
+		auth := make([]byte, 2)
+		_, _ = io.ReadFull(conn, auth)
+		user := make([]byte, auth[1]+1)
+		_, _ = io.ReadFull(conn, user)
+		pass := make([]byte, user[len(user)-1])
+		_, _ = io.ReadFull(conn, pass)
+		_, _ = conn.Write([]byte{socks5UserPassAuthV1, socks5Succeeded})
+
+		req := make([]byte, 5)
+		_, _ = io.ReadFull(conn, req)
+		host := make([]byte, req[4]+2)
+		_, _ = io.ReadFull(conn, host)
+		port := binary.BigEndian.Uint16(host[len(host)-2:])
+		requested <- string(user[:len(user)-1]) + ":" + string(pass) + "@" +
+			net.JoinHostPort(string(host[:len(host)-2]), strconv.Itoa(int(port)))
+		_, _ = conn.Write([]byte{socks5Version, socks5Succeeded, 0, socks5IPv4, 10, 0, 0, 1, 0, 1})
+		_, _ = conn.Write([]byte("hello"))
+	})
+
+	d, err := newProxyDialer("socks5://admin:secret@"+address, time.Second)
+	require.NoError(t, err)
+	conn, err := d.DialContext(context.Background(), "tcp", "db1.internal:27017")
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "admin:secret@db1.internal:27017", <-requested)
+
+	data := make([]byte, 5)
+	_, err = io.ReadFull(conn, data)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data), "the connection should carry the data after the reply")
+}
+
+func TestSOCKS5ProxyRefused(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	address := serveProxy(t, func(conn net.Conn) {
+		_, _ = io.ReadFull(conn, make([]byte, 3))
+		_, _ = conn.Write([]byte{socks5Version, socks5NoAuth})
+		_, _ = io.ReadFull(conn, make([]byte, 10))
+		_, _ = conn.Write([]byte{socks5Version, 0x05, 0, socks5IPv4, 0, 0, 0, 0, 0, 0})
+	})
+
+	d, err := newProxyDialer("socks5://"+address, time.Second)
+	require.NoError(t, err)
+	_, err = d.DialContext(context.Background(), "tcp", "10.0.0.2:27017")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+}
+
+func TestHTTPProxy(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	requests := make(chan *http.Request, 1)
+	address := serveProxy(t, func(conn net.Conn) {
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		requests <- req
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+	})
+
+	d, err := newProxyDialer("http://admin:secret@"+address, time.Second)
+	require.NoError(t, err)
+	conn, err := d.DialContext(context.Background(), "tcp", "db1.internal:27017")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	req := <-requests
+	assert.Equal(t, http.MethodConnect, req.Method)
+	assert.Equal(t, "db1.internal:27017", req.Host)
+	assert.Equal(t, "Basic YWRtaW46c2VjcmV0", req.Header.Get("Proxy-Authorization"))
+}
+
+func TestHTTPProxyForbidden(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	address := serveProxy(t, func(conn net.Conn) {
+		if _, err := http.ReadRequest(bufio.NewReader(conn)); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte("HTTP/1.1 403 Forbidden\r\nContent-Length: 0\r\n\r\n"))
+	})
+
+	d, err := newProxyDialer("http://"+address, time.Second)
+	require.NoError(t, err)
+	_, err = d.DialContext(context.Background(), "tcp", "db1.internal:27017")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403 Forbidden")
+}
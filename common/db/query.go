@@ -13,7 +13,11 @@ type DeferredQuery struct {
 	Coll      *mongo.Collection
 	Filter    interface{}
 	Hint      interface{}
+	Min       interface{}
+	BatchSize int32
 	LogReplay bool
+	// Session, if set, is the session the query runs in.
+	Session mongo.Session
 }
 
 // Count issues a EstimatedDocumentCount command when there is no Filter in the query and a CountDocuments command otherwise.
@@ -47,6 +51,12 @@ func (q *DeferredQuery) Iter() (*mongo.Cursor, error) {
 	if q.Hint != nil {
 		opts.SetHint(q.Hint)
 	}
+	if q.Min != nil {
+		opts.SetMin(q.Min)
+	}
+	if q.BatchSize > 0 {
+		opts.SetBatchSize(q.BatchSize)
+	}
 	if q.LogReplay {
 		opts.SetOplogReplay(true)
 	}
@@ -54,5 +64,9 @@ func (q *DeferredQuery) Iter() (*mongo.Cursor, error) {
 	if filter == nil {
 		filter = bson.D{}
 	}
-	return q.Coll.Find(context.TODO(), filter, opts)
+	ctx := context.TODO()
+	if q.Session != nil {
+		ctx = mongo.NewSessionContext(ctx, q.Session)
+	}
+	return q.Coll.Find(ctx, filter, opts)
 }
@@ -0,0 +1,305 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/options"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+// srvConnectTimeout is how long a client for the new hosts of an SRV record
+// has to reach one of them before the current client is kept.
+const srvConnectTimeout = 30 * time.Second
+
+// srvIdleCheckInterval is how often a refresher with a client for new hosts
+// checks whether the operations of the current client finished.
+const srvIdleCheckInterval = 100 * time.Millisecond
+
+// srvRefresher resolves the SRV record of a mongodb+srv connection string
+// every interval. The driver only polls the record of sharded clusters, so
+// the refresher is only used for the other deployments, where an operation
+// that runs for hours keeps using the hosts the record listed when the tools
+// connected, even after maintenance rotated them out. When the hosts change,
+// the refresher connects a new client to them, waits until no operation is
+// using a connection of the current client, and then makes the
+// SessionProvider hand out the new client instead. The current client is
+// kept open, so that the cursors already using it are not cut off, and is
+// only disconnected when the SessionProvider is closed.
+type srvRefresher struct {
+	name     string
+	service  string
+	maxHosts int
+	interval time.Duration
+	hosts    []string
+	// inFlight counts the connections of the current client that are
+	// checked out by operations in progress.
+	inFlight *atomic.Int64
+
+	lookupSRV func(service, proto, name string) (string, []*net.SRV, error)
+	connect   func(hosts []string, inFlight *atomic.Int64) (*mongo.Client, error)
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// newSRVRefresher returns a refresher for the SRV record of opts, or nil if
+// it isn't a mongodb+srv connection string or refreshing is disabled.
+func newSRVRefresher(opts options.ToolOptions, monitor *event.PoolMonitor) *srvRefresher {
+	if opts.URI == nil || opts.Connection == nil || opts.Connection.SRVRefreshInterval <= 0 {
+		return nil
+	}
+	cs := opts.URI.ParsedConnString()
+	if cs == nil || cs.Scheme != connstring.SchemeMongoDBSRV || opts.Direct {
+		return nil
+	}
+	name := srvHostName(cs.Original)
+	if name == "" {
+		return nil
+	}
+	service := cs.SRVServiceName
+	if service == "" {
+		service = "mongodb"
+	}
+	return &srvRefresher{
+		name:      name,
+		service:   service,
+		maxHosts:  cs.SRVMaxHosts,
+		interval:  time.Duration(opts.Connection.SRVRefreshInterval) * time.Second,
+		hosts:     sortedHosts(cs.Hosts),
+		inFlight:  &atomic.Int64{},
+		lookupSRV: net.LookupSRV,
+		connect: func(hosts []string, inFlight *atomic.Int64) (*mongo.Client, error) {
+			return connectToHosts(opts, trackInFlight(monitor, inFlight), hosts)
+		},
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// trackInFlight returns a pool monitor that counts the checked out
+// connections of a client in inFlight, and passes the events on to next.
+func trackInFlight(next *event.PoolMonitor, inFlight *atomic.Int64) *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.GetSucceeded:
+				inFlight.Add(1)
+			case event.ConnectionReturned:
+				inFlight.Add(-1)
+			}
+			if next != nil && next.Event != nil {
+				next.Event(e)
+			}
+		},
+	}
+}
+
+// srvHostName returns the host name of a mongodb+srv connection string.
+func srvHostName(uri string) string {
+	rest := strings.TrimPrefix(uri, connstring.SchemeMongoDBSRV+"://")
+	if end := strings.IndexAny(rest, "/?"); end >= 0 {
+		rest = rest[:end]
+	}
+	if at := strings.LastIndex(rest, "@"); at >= 0 {
+		rest = rest[at+1:]
+	}
+	return rest
+}
+
+func sortedHosts(hosts []string) []string {
+	sorted := append([]string(nil), hosts...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// connectToHosts connects a client configured like opts to hosts.
+func connectToHosts(
+	opts options.ToolOptions,
+	monitor *event.PoolMonitor,
+	hosts []string,
+) (*mongo.Client, error) {
+	cs := *opts.URI.ConnString
+	cs.Hosts = hosts
+	uri := *opts.URI
+	uri.ConnString = &cs
+	opts.URI = &uri
+
+	client, err := configureClient(opts, monitor)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), srvConnectTimeout)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		return nil, err
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, err
+	}
+	return client, nil
+}
+
+// resolve returns all the hosts the SRV record lists now. Like the driver, it
+// rejects targets outside the domain of the record's name.
+func (r *srvRefresher) resolve() ([]string, error) {
+	_, records, err := r.lookupSRV(r.service, "tcp", r.name)
+	if err != nil {
+		return nil, err
+	}
+	domain := r.name
+	if dot := strings.Index(domain, "."); dot >= 0 {
+		domain = domain[dot:]
+	}
+	var hosts []string
+	for _, record := range records {
+		target := strings.TrimSuffix(record.Target, ".")
+		if !strings.HasSuffix(target, domain) {
+			return nil, fmt.Errorf("SRV target %v is not in the domain %v", target, domain[1:])
+		}
+		hosts = append(hosts, net.JoinHostPort(target, strconv.Itoa(int(record.Port))))
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("the SRV record lists no hosts")
+	}
+	return sortedHosts(hosts), nil
+}
+
+// changed returns whether the hosts the SRV record lists now differ from the
+// ones in use. With srvMaxHosts, only a subset of the hosts is in use, so the
+// hosts only change when one of that subset is no longer listed.
+func (r *srvRefresher) changed(hosts []string) bool {
+	if r.maxHosts <= 0 {
+		return strings.Join(hosts, ",") != strings.Join(r.hosts, ",")
+	}
+	listed := map[string]bool{}
+	for _, host := range hosts {
+		listed[host] = true
+	}
+	for _, host := range r.hosts {
+		if !listed[host] {
+			return true
+		}
+	}
+	return false
+}
+
+// refresh resolves the SRV record, and returns a client connected to its
+// hosts, with the counter of its checked out connections, if they changed.
+// Failures are logged and retried at the next interval, keeping the current
+// client.
+func (r *srvRefresher) refresh() (*mongo.Client, *atomic.Int64) {
+	hosts, err := r.resolve()
+	if err != nil {
+		log.Logvf(log.Info, "error resolving the SRV record of %v, will retry: %v", r.name, err)
+		return nil, nil
+	}
+	if !r.changed(hosts) {
+		return nil, nil
+	}
+	if r.maxHosts > 0 && len(hosts) > r.maxHosts {
+		hosts = hosts[:r.maxHosts]
+	}
+	log.Logvf(
+		log.Always,
+		"the SRV record of %v now lists %v instead of %v; reconnecting",
+		r.name,
+		strings.Join(hosts, ","),
+		strings.Join(r.hosts, ","),
+	)
+	inFlight := &atomic.Int64{}
+	client, err := r.connect(hosts, inFlight)
+	if err != nil {
+		log.Logvf(
+			log.Always,
+			"error connecting to the new hosts of %v, will retry with the current ones: %v",
+			r.name,
+			err,
+		)
+		return nil, nil
+	}
+	r.hosts = hosts
+	return client, inFlight
+}
+
+// waitIdle waits until no connection of the current client is checked out,
+// and returns false if Stop is called first.
+func (r *srvRefresher) waitIdle() bool {
+	if r.inFlight.Load() > 0 {
+		log.Logvf(
+			log.Info,
+			"waiting for the operations in progress to finish before using the new hosts of %v",
+			r.name,
+		)
+	}
+	for r.inFlight.Load() > 0 {
+		select {
+		case <-r.stop:
+			return false
+		case <-time.After(srvIdleCheckInterval):
+		}
+	}
+	return true
+}
+
+// run refreshes the SRV record every interval until Stop is called, and
+// passes every new client to use once the current one is idle.
+func (r *srvRefresher) run(use func(*mongo.Client)) {
+	defer close(r.stopped)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+		client, inFlight := r.refresh()
+		if client == nil {
+			continue
+		}
+		if !r.waitIdle() {
+			_ = client.Disconnect(context.Background())
+			return
+		}
+		r.inFlight = inFlight
+		use(client)
+	}
+}
+
+// Stop stops refreshing, and waits for a refresh in progress to finish.
+func (r *srvRefresher) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
+	<-r.stopped
+}
+
+// useClient makes the SessionProvider hand out client from now on. The
+// client it replaces stays open until Close.
+func (sp *SessionProvider) useClient(client *mongo.Client) {
+	sp.Lock()
+	defer sp.Unlock()
+	if sp.client == nil {
+		_ = client.Disconnect(context.Background())
+		return
+	}
+	sp.retired = append(sp.retired, sp.client)
+	sp.client = client
+}
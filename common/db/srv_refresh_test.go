@@ -0,0 +1,192 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package db
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestSRVHostName(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	for uri, expected := range map[string]string{
+		"mongodb+srv://cluster0.abc.mongodb.net":                   "cluster0.abc.mongodb.net",
+		"mongodb+srv://cluster0.abc.mongodb.net/?retryWrites=true": "cluster0.abc.mongodb.net",
+		"mongodb+srv://user:p@ss@cluster0.abc.mongodb.net/db":      "cluster0.abc.mongodb.net",
+		"mongodb+srv://cluster0.abc.mongodb.net?tls=true":          "cluster0.abc.mongodb.net",
+	} {
+		assert.Equal(t, expected, srvHostName(uri), uri)
+	}
+}
+
+// disconnectedClient returns a client that is never used to reach a server.
+func disconnectedClient(t *testing.T) *mongo.Client {
+	client, err := mongo.Connect(context.Background(), mopt.Client().SetHosts([]string{"x:1"}))
+	require.NoError(t, err)
+	return client
+}
+
+func TestSRVRefresher(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	var records []*net.SRV
+	var lookupErr error
+	var connected [][]string
+	newRefresher := func(maxHosts int, hosts ...string) *srvRefresher {
+		return &srvRefresher{
+			name:     "cluster0.abc.mongodb.net",
+			service:  "mongodb",
+			maxHosts: maxHosts,
+			hosts:    hosts,
+			lookupSRV: func(service, proto, name string) (string, []*net.SRV, error) {
+				assert.Equal(t, "mongodb", service)
+				assert.Equal(t, "tcp", proto)
+				assert.Equal(t, "cluster0.abc.mongodb.net", name)
+				return "", records, lookupErr
+			},
+			connect: func(hosts []string, _ *atomic.Int64) (*mongo.Client, error) {
+				connected = append(connected, hosts)
+				return disconnectedClient(t), nil
+			},
+		}
+	}
+	current := []string{"a.abc.mongodb.net:27017", "b.abc.mongodb.net:27017"}
+	setRecords := func(targets ...string) {
+		records = nil
+		for _, target := range targets {
+			records = append(records, &net.SRV{Target: target + ".", Port: 27017})
+		}
+	}
+
+	t.Run("unchanged hosts keep the current client", func(t *testing.T) {
+		connected = nil
+		setRecords("b.abc.mongodb.net", "a.abc.mongodb.net")
+		client, _ := newRefresher(0, current...).refresh()
+		assert.Nil(t, client)
+		assert.Empty(t, connected)
+	})
+
+	t.Run("changed hosts get a new client", func(t *testing.T) {
+		connected = nil
+		setRecords("b.abc.mongodb.net", "c.abc.mongodb.net")
+		r := newRefresher(0, current...)
+		client, inFlight := r.refresh()
+		require.NotNil(t, client)
+		assert.NotNil(t, inFlight)
+		_ = client.Disconnect(context.Background())
+		expected := []string{"b.abc.mongodb.net:27017", "c.abc.mongodb.net:27017"}
+		assert.Equal(t, [][]string{expected}, connected)
+		assert.Equal(t, expected, r.hosts)
+		client, _ = r.refresh()
+		assert.Nil(t, client)
+	})
+
+	t.Run("with srvMaxHosts, only losing a host in use reconnects", func(t *testing.T) {
+		connected = nil
+		setRecords("a.abc.mongodb.net", "b.abc.mongodb.net", "c.abc.mongodb.net")
+		r := newRefresher(1, "b.abc.mongodb.net:27017")
+		client, _ := r.refresh()
+		assert.Nil(t, client)
+		setRecords("a.abc.mongodb.net", "c.abc.mongodb.net")
+		client, _ = r.refresh()
+		require.NotNil(t, client)
+		_ = client.Disconnect(context.Background())
+		assert.Equal(t, [][]string{{"a.abc.mongodb.net:27017"}}, connected)
+	})
+
+	t.Run("failed lookups and foreign targets are retried later", func(t *testing.T) {
+		connected = nil
+		lookupErr = errors.New("no such host")
+		client, _ := newRefresher(0, current...).refresh()
+		assert.Nil(t, client)
+		lookupErr = nil
+
+		setRecords("a.evil.example.com")
+		_, err := newRefresher(0, current...).resolve()
+		assert.ErrorContains(t, err, "is not in the domain abc.mongodb.net")
+		setRecords()
+		_, err = newRefresher(0, current...).resolve()
+		assert.ErrorContains(t, err, "lists no hosts")
+		assert.Empty(t, connected)
+	})
+}
+
+func TestSRVRefresherWaitsForIdle(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	var forwarded []string
+	inFlight := &atomic.Int64{}
+	monitor := trackInFlight(&event.PoolMonitor{Event: func(e *event.PoolEvent) {
+		forwarded = append(forwarded, e.Type)
+	}}, inFlight)
+	monitor.Event(&event.PoolEvent{Type: event.GetSucceeded})
+	monitor.Event(&event.PoolEvent{Type: event.GetSucceeded})
+	monitor.Event(&event.PoolEvent{Type: event.ConnectionReturned})
+	assert.EqualValues(t, 1, inFlight.Load())
+	assert.Len(t, forwarded, 3)
+
+	r := &srvRefresher{name: "cluster0.abc.mongodb.net", inFlight: inFlight, stop: make(chan struct{})}
+	idle := make(chan bool, 1)
+	go func() { idle <- r.waitIdle() }()
+	select {
+	case <-idle:
+		require.FailNow(t, "an operation is still in progress")
+	case <-time.After(3 * srvIdleCheckInterval):
+	}
+	monitor.Event(&event.PoolEvent{Type: event.ConnectionReturned})
+	assert.True(t, <-idle)
+
+	inFlight.Add(1)
+	go func() { idle <- r.waitIdle() }()
+	close(r.stop)
+	assert.False(t, <-idle, "stopping gives up waiting")
+}
+
+func TestSessionProviderUseClient(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	first, second := disconnectedClient(t), disconnectedClient(t)
+	sp := &SessionProvider{client: first}
+	sp.useClient(second)
+	client, err := sp.GetSession()
+	require.NoError(t, err)
+	assert.Same(t, second, client)
+	assert.Equal(t, []*mongo.Client{first}, sp.retired)
+
+	sp.Close()
+	assert.Empty(t, sp.retired)
+	_, err = sp.GetSession()
+	assert.Error(t, err)
+	// a client that comes in after Close is disconnected right away
+	sp.useClient(disconnectedClient(t))
+	assert.Empty(t, sp.retired)
+}
+
+func TestNewSRVRefresher(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	opts := options.ToolOptions{
+		Connection: &options.Connection{SRVRefreshInterval: 60},
+		URI:        &options.URI{},
+	}
+	assert.Nil(t, newSRVRefresher(opts, nil), "not a mongodb+srv connection string")
+	opts.URI = nil
+	assert.Nil(t, newSRVRefresher(opts, nil))
+}
@@ -0,0 +1,143 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package db
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/mongodb/mongo-tools/common/password"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/yaml.v2"
+)
+
+// tlsHostsFile is the format of an --sslHostsFile, e.g.
+//
+//	hosts:
+//	  - host: db1.internal:27017
+//	    pemKeyFile: /etc/certs/db1-client.pem
+//	    pemKeyPasswordFile: /etc/certs/db1-client.pass
+//	    caFile: /etc/certs/db1-ca.pem
+//	    serverName: db1.example.com
+//
+// where host is either a host and a port, or a host for all of its ports.
+// Every other field is optional, and defaults to the --ssl options. The
+// password of the key is either pemKeyPassword, or read from the file of
+// pemKeyPasswordFile.
+type tlsHostsFile struct {
+	Hosts []struct {
+		Host               string `yaml:"host"`
+		PEMKeyFile         string `yaml:"pemKeyFile"`
+		PEMKeyPassword     string `yaml:"pemKeyPassword"`
+		PEMKeyPasswordFile string `yaml:"pemKeyPasswordFile"`
+		CAFile             string `yaml:"caFile"`
+		ServerName         string `yaml:"serverName"`
+	} `yaml:"hosts"`
+}
+
+// loadTLSHosts reads the --sslHostsFile at path, and returns the TLS
+// configuration of each of its hosts, starting from base.
+func loadTLSHosts(path string, base *tls.Config) (map[string]*tls.Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file tlsHostsFile
+	if err := yaml.UnmarshalStrict(content, &file); err != nil {
+		return nil, fmt.Errorf("error parsing %v: %v", path, err)
+	}
+
+	hosts := map[string]*tls.Config{}
+	for _, host := range file.Hosts {
+		if host.Host == "" {
+			return nil, fmt.Errorf("every host of %v needs a host", path)
+		}
+		if _, ok := hosts[host.Host]; ok {
+			return nil, fmt.Errorf("host %v is listed twice in %v", host.Host, path)
+		}
+		cfg := base.Clone()
+		cfg.ServerName = host.ServerName
+		if host.PEMKeyFile != "" {
+			keyPassword := host.PEMKeyPassword
+			if host.PEMKeyPasswordFile != "" {
+				if keyPassword != "" {
+					return nil, fmt.Errorf(
+						"host %v of %v cannot have both a pemKeyPassword and a pemKeyPasswordFile",
+						host.Host,
+						path,
+					)
+				}
+				var err error
+				keyPassword, err = password.ReadSecretFile(host.PEMKeyPasswordFile)
+				if err != nil {
+					return nil, fmt.Errorf("error reading the pemKeyPassword of %v: %v", host.Host, err)
+				}
+			}
+			cfg.Certificates = nil
+			if _, err := addClientCertFromFile(cfg, host.PEMKeyFile, keyPassword); err != nil {
+				return nil, fmt.Errorf("can't load the client certificate of %v: %v", host.Host, err)
+			}
+		}
+		if host.CAFile != "" {
+			cfg.RootCAs = nil
+			if err := addCACertsFromFile(cfg, host.CAFile); err != nil {
+				return nil, fmt.Errorf("can't load the CA file of %v: %v", host.Host, err)
+			}
+		}
+		hosts[host.Host] = cfg
+	}
+	return hosts, nil
+}
+
+// tlsHostsDialer performs the TLS handshakes of the connections itself,
+// instead of the driver, so that each host of --sslHostsFile is connected to
+// with its own client certificate and CA.
+type tlsHostsDialer struct {
+	next  mopt.ContextDialer
+	base  *tls.Config
+	hosts map[string]*tls.Config
+}
+
+// configFor returns the TLS configuration of a connection to address.
+func (d *tlsHostsDialer) configFor(address string) *tls.Config {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	cfg, ok := d.hosts[address]
+	if !ok {
+		cfg, ok = d.hosts[host]
+	}
+	if !ok {
+		cfg = d.base
+	}
+	cfg = cfg.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	return cfg
+}
+
+// DialContext connects to address, and performs the TLS handshake with it.
+func (d *tlsHostsDialer) DialContext(
+	ctx context.Context,
+	network, address string,
+) (net.Conn, error) {
+	conn, err := d.next.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, d.configFor(address))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
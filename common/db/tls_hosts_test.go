@@ -0,0 +1,107 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package db
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTLSHostsFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "hosts.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadTLSHosts(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	path := writeTLSHostsFile(t, `
+hosts:
+  - host: db1.internal:27017
+    pemKeyFile: testdata/test-client.pem
+    caFile: testdata/ca.pem
+    serverName: db1.example.com
+  - host: db2.internal
+    caFile: testdata/ca.pem
+`)
+	base := &tls.Config{MinVersion: tls.VersionTLS12}
+	hosts, err := loadTLSHosts(path, base)
+	require.NoError(t, err)
+	require.Len(t, hosts, 2)
+	assert.Len(t, hosts["db1.internal:27017"].Certificates, 1)
+	assert.NotNil(t, hosts["db2.internal"].RootCAs)
+	assert.Nil(t, base.RootCAs, "the base configuration should not change")
+
+	d := &tlsHostsDialer{base: base, hosts: hosts}
+	cfg := d.configFor("db1.internal:27017")
+	assert.Equal(t, "db1.example.com", cfg.ServerName)
+	assert.Len(t, cfg.Certificates, 1)
+
+	cfg = d.configFor("db2.internal:27018")
+	assert.Equal(t, "db2.internal", cfg.ServerName, "a host should match all of its ports")
+	assert.NotNil(t, cfg.RootCAs)
+
+	cfg = d.configFor("db3.internal:27017")
+	assert.Equal(t, "db3.internal", cfg.ServerName)
+	assert.Nil(t, cfg.RootCAs, "other hosts should use the --ssl options")
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+
+	for name, content := range map[string]string{
+		"missing host": "hosts:\n  - caFile: testdata/ca.pem\n",
+		"duplicate":    "hosts:\n  - host: a\n  - host: a\n",
+		"unknown key":  "hosts:\n  - host: a\n    crlFile: x\n",
+		"bad CA file":  "hosts:\n  - host: a\n    caFile: testdata/missing.pem\n",
+	} {
+		_, err := loadTLSHosts(writeTLSHostsFile(t, content), base)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestConfigureClientDialers(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	enabled := options.EnabledOptions{Connection: true, URI: true}
+
+	toolOptions := options.New("test", "", "", "", true, enabled)
+	_, err := toolOptions.ParseArgs([]string{"--proxy", "socks5://bastion:1080"})
+	require.NoError(t, err)
+	clientopt, err := configureClientOptions(*toolOptions, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &proxyDialer{}, clientopt.Dialer)
+
+	toolOptions = options.New("test", "", "", "", true, enabled)
+	_, err = toolOptions.ParseArgs([]string{"--proxy", "ftp://bastion:21"})
+	require.NoError(t, err)
+	_, err = configureClientOptions(*toolOptions, nil)
+	assert.Error(t, err)
+
+	toolOptions = options.New("test", "", "", "", true, enabled)
+	_, err = toolOptions.ParseArgs([]string{
+		"--ssl",
+		"--sslCAFile", "testdata/ca.pem",
+		"--sslHostsFile", writeTLSHostsFile(t, "hosts:\n  - host: localhost\n"),
+	})
+	require.NoError(t, err)
+	clientopt, err = configureClientOptions(*toolOptions, nil)
+	require.NoError(t, err)
+	assert.Nil(t, clientopt.TLSConfig, "the dialer should perform the TLS handshakes")
+	assert.IsType(t, &tlsHostsDialer{}, clientopt.Dialer)
+
+	toolOptions = options.New("test", "", "", "", true, enabled)
+	_, err = toolOptions.ParseArgs([]string{"--sslHostsFile", "hosts.yaml"})
+	require.NoError(t, err)
+	_, err = configureClientOptions(*toolOptions, nil)
+	assert.Error(t, err, "--sslHostsFile should require --ssl")
+}
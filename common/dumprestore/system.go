@@ -0,0 +1,56 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package dumprestore
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SystemJS is the collection of the stored JavaScript functions of a
+// database. Unlike the other system collections outside of the admin and
+// config databases, it is always dumped and restored.
+const SystemJS = "system.js"
+
+// managedSystemCollections are the system collections that the server
+// manages, or that the tools handle on their own, so they can't be named by
+// --systemCollection.
+var managedSystemCollections = []string{
+	"system.indexes",
+	"system.keys",
+	"system.namespaces",
+	"system.profile",
+	"system.roles",
+	"system.sessions",
+	"system.users",
+	"system.version",
+	"system.views",
+}
+
+// ValidateSystemCollections returns an error if one of the names given to
+// --systemCollection is not a system collection that can be dumped and
+// restored.
+func ValidateSystemCollections(names []string) error {
+	for _, name := range names {
+		switch {
+		case !strings.HasPrefix(name, "system.") || name == "system.":
+			return fmt.Errorf("--systemCollection %v is not the name of a system collection", name)
+		case strings.HasPrefix(name, "system.buckets."),
+			slices.Contains(managedSystemCollections, name):
+			return fmt.Errorf("--systemCollection cannot be %v, which is managed by the server", name)
+		}
+	}
+	return nil
+}
+
+// IncludesSystemCollection returns true if the system collection is dumped
+// and restored along with the other collections of a database outside of
+// admin and config: it is either system.js, or named by --systemCollection.
+func IncludesSystemCollection(names []string, collection string) bool {
+	return collection == SystemJS || slices.Contains(names, collection)
+}
@@ -0,0 +1,217 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+// Package fieldcrypt encrypts and decrypts selected field values of documents
+// with AES-256-GCM and a local key, so that exported files do not hold those
+// values in plaintext.
+package fieldcrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// KeySize is the size in bytes of the keys, which select AES-256.
+const KeySize = 32
+
+// encryptedPrefix starts every encrypted value. The rest of the value is the
+// base64 encoding of the nonce followed by the sealed BSON value.
+const encryptedPrefix = "enc:aes256gcm:"
+
+// LoadKey reads a key from a file holding its base64 encoding, as created by
+// e.g. `openssl rand -base64 32`.
+func LoadKey(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading encryption key file: %v", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(content)))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key file %v is not base64 encoded: %v", path, err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf(
+			"encryption key in %v must be %v bytes, got %v",
+			path,
+			KeySize,
+			len(key),
+		)
+	}
+	return key, nil
+}
+
+// Cipher encrypts and decrypts the values of a set of fields. The values keep
+// their BSON type through encryption, and the name of the field is
+// authenticated with the value, so an encrypted value cannot be moved to
+// another field. A Cipher is safe for concurrent use.
+type Cipher struct {
+	aead   cipher.AEAD
+	fields []string
+}
+
+// New returns a Cipher for the given fields, which may be dotted paths into
+// embedded documents. A path that crosses an array applies to each document
+// of the array, so that "addresses.street" is the street of every address.
+func New(key []byte, fields []string) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		if field == "" || strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") {
+			return nil, fmt.Errorf("invalid field to encrypt: '%v'", field)
+		}
+	}
+	return &Cipher{aead: aead, fields: fields}, nil
+}
+
+// NewFromFlags returns a Cipher for the comma separated fields, with the key
+// in keyFile, or nil if neither is set. fieldsFlag and keyFlag name the
+// options that set them, for error messages.
+func NewFromFlags(fields, keyFile, fieldsFlag, keyFlag string) (*Cipher, error) {
+	switch {
+	case fields == "" && keyFile == "":
+		return nil, nil
+	case fields == "":
+		return nil, fmt.Errorf("%v requires %v", keyFlag, fieldsFlag)
+	case keyFile == "":
+		return nil, fmt.Errorf("%v requires %v", fieldsFlag, keyFlag)
+	}
+	key, err := LoadKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return New(key, strings.Split(fields, ","))
+}
+
+// IsEncrypted returns whether value is a value encrypted by a Cipher.
+func IsEncrypted(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.HasPrefix(s, encryptedPrefix)
+}
+
+// EncryptDocument replaces the values of the fields of the Cipher in doc with
+// their encryption. Fields that doc does not have are ignored.
+func (c *Cipher) EncryptDocument(doc bson.D) error {
+	for _, field := range c.fields {
+		for _, elem := range lookup(doc, strings.Split(field, ".")) {
+			encrypted, err := c.encrypt(field, elem.Value)
+			if err != nil {
+				return fmt.Errorf("error encrypting field '%v': %v", field, err)
+			}
+			elem.Value = encrypted
+		}
+	}
+	return nil
+}
+
+// DecryptDocument replaces the encrypted values of the fields of the Cipher in
+// doc with their decryption. Fields that doc does not have, or whose value is
+// null or empty, are ignored; any other value must be encrypted.
+func (c *Cipher) DecryptDocument(doc bson.D) error {
+	for _, field := range c.fields {
+		for _, elem := range lookup(doc, strings.Split(field, ".")) {
+			if elem.Value == nil || elem.Value == "" {
+				continue
+			}
+			if !IsEncrypted(elem.Value) {
+				return fmt.Errorf("field '%v' is not encrypted", field)
+			}
+			decrypted, err := c.decrypt(field, elem.Value.(string))
+			if err != nil {
+				return fmt.Errorf("error decrypting field '%v': %v", field, err)
+			}
+			elem.Value = decrypted
+		}
+	}
+	return nil
+}
+
+func (c *Cipher) encrypt(field string, value interface{}) (string, error) {
+	plaintext, err := bson.Marshal(bson.D{{Key: "v", Value: value}})
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, plaintext, []byte(field))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *Cipher) decrypt(field, value string) (interface{}, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted value is too short")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, []byte(field))
+	if err != nil {
+		return nil, fmt.Errorf("wrong key or corrupt value: %v", err)
+	}
+	var wrapper bson.D
+	if err := bson.Unmarshal(plaintext, &wrapper); err != nil {
+		return nil, err
+	}
+	if len(wrapper) != 1 {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+	return wrapper[0].Value, nil
+}
+
+// lookup returns the elements of doc at the path, descending into embedded
+// documents and into each document of the arrays on the way.
+func lookup(doc bson.D, path []string) []*bson.E {
+	for i := range doc {
+		if doc[i].Key != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return []*bson.E{&doc[i]}
+		}
+		return lookupValue(doc[i].Value, path[1:])
+	}
+	return nil
+}
+
+// lookupValue returns the elements at the path of value, if it is a document
+// or an array. CSV imports build arrays as []interface{}.
+func lookupValue(value interface{}, path []string) []*bson.E {
+	var items []interface{}
+	switch sub := value.(type) {
+	case bson.D:
+		return lookup(sub, path)
+	case *bson.D:
+		return lookup(*sub, path)
+	case bson.A:
+		items = sub
+	case []interface{}:
+		items = sub
+	default:
+		return nil
+	}
+	var elems []*bson.E
+	for _, item := range items {
+		elems = append(elems, lookupValue(item, path)...)
+	}
+	return elems
+}
@@ -0,0 +1,174 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var testKey = bytes.Repeat([]byte{7}, KeySize)
+
+func TestRoundTrip(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	c, err := New(testKey, []string{"ssn", "contact.email", "dob", "missing", "contact.missing"})
+	require.NoError(t, err)
+
+	dob := primitive.NewDateTimeFromTime(time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC))
+	doc := bson.D{
+		{"_id", int32(1)},
+		{"ssn", "123-45-6789"},
+		{"contact", bson.D{{"email", "a@example.com"}, {"phone", "555"}}},
+		{"dob", dob},
+	}
+	require.NoError(t, c.EncryptDocument(doc))
+
+	assert.Equal(t, int32(1), doc[0].Value)
+	assert.True(t, IsEncrypted(doc[1].Value))
+	assert.NotContains(t, doc[1].Value, "123-45-6789")
+	contact := doc[2].Value.(bson.D)
+	assert.True(t, IsEncrypted(contact[0].Value))
+	assert.Equal(t, "555", contact[1].Value)
+	assert.True(t, IsEncrypted(doc[3].Value))
+
+	require.NoError(t, c.DecryptDocument(doc))
+	assert.Equal(t, bson.D{
+		{"_id", int32(1)},
+		{"ssn", "123-45-6789"},
+		{"contact", bson.D{{"email", "a@example.com"}, {"phone", "555"}}},
+		{"dob", dob},
+	}, doc)
+}
+
+func TestRoundTripInArrays(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	c, err := New(testKey, []string{"addresses.street", "tags.name"})
+	require.NoError(t, err)
+
+	original := func() bson.D {
+		return bson.D{
+			{"addresses", bson.A{
+				bson.D{{"street", "1 Main St"}, {"city", "Springfield"}},
+				bson.D{{"city", "Shelbyville"}},
+				bson.A{bson.D{{"street", "2 Elm St"}}},
+				"unknown",
+			}},
+			{"tags", bson.A{"a", "b"}},
+		}
+	}
+	doc := original()
+	require.NoError(t, c.EncryptDocument(doc))
+
+	addresses := doc[0].Value.(bson.A)
+	first := addresses[0].(bson.D)
+	assert.True(t, IsEncrypted(first[0].Value))
+	assert.Equal(t, "Springfield", first[1].Value)
+	assert.Equal(t, bson.D{{"city", "Shelbyville"}}, addresses[1])
+	assert.True(t, IsEncrypted(addresses[2].(bson.A)[0].(bson.D)[0].Value))
+	assert.Equal(t, "unknown", addresses[3])
+	assert.Equal(t, bson.A{"a", "b"}, doc[1].Value)
+
+	require.NoError(t, c.DecryptDocument(doc))
+	assert.Equal(t, original(), doc)
+}
+
+func TestDecryptDocumentFromImport(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	c, err := New(testKey, []string{"a.b", "c", "d", "e.f"})
+	require.NoError(t, err)
+
+	encrypted, err := c.encrypt("a.b", int64(42))
+	require.NoError(t, err)
+	inArray, err := c.encrypt("e.f", "x")
+	require.NoError(t, err)
+	// CSV imports build embedded documents as *bson.D and arrays as
+	// []interface{}, and leave empty fields
+	doc := bson.D{
+		{"a", &bson.D{{"b", encrypted}}},
+		{"c", ""},
+		{"d", nil},
+		{"e", []interface{}{&bson.D{{"f", inArray}}}},
+	}
+	require.NoError(t, c.DecryptDocument(doc))
+	assert.Equal(t, bson.D{
+		{"a", &bson.D{{"b", int64(42)}}},
+		{"c", ""},
+		{"d", nil},
+		{"e", []interface{}{&bson.D{{"f", "x"}}}},
+	}, doc)
+}
+
+func TestDecryptErrors(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	c, err := New(testKey, []string{"a", "b"})
+	require.NoError(t, err)
+	encrypted, err := c.encrypt("a", "secret")
+	require.NoError(t, err)
+
+	err = c.DecryptDocument(bson.D{{"a", "secret"}})
+	assert.ErrorContains(t, err, "field 'a' is not encrypted")
+
+	err = c.DecryptDocument(bson.D{{"b", encrypted}})
+	assert.ErrorContains(t, err, "error decrypting field 'b'", "values cannot move between fields")
+
+	other, err := New(bytes.Repeat([]byte{8}, KeySize), []string{"a"})
+	require.NoError(t, err)
+	err = other.DecryptDocument(bson.D{{"a", encrypted}})
+	assert.ErrorContains(t, err, "wrong key")
+
+	err = c.DecryptDocument(bson.D{{"a", encryptedPrefix + "AAAA"}})
+	assert.ErrorContains(t, err, "too short")
+}
+
+func TestNewFromFlags(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	encoded := base64.StdEncoding.EncodeToString(testKey)
+	require.NoError(t, os.WriteFile(keyFile, []byte(encoded+"\n"), 0o600))
+
+	c, err := NewFromFlags("", "", "--fields", "--key")
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+
+	_, err = NewFromFlags("a", "", "--fields", "--key")
+	assert.ErrorContains(t, err, "--fields requires --key")
+	_, err = NewFromFlags("", keyFile, "--fields", "--key")
+	assert.ErrorContains(t, err, "--key requires --fields")
+	_, err = NewFromFlags("a,,b", keyFile, "--fields", "--key")
+	assert.ErrorContains(t, err, "invalid field")
+
+	c, err = NewFromFlags("a,b.c", keyFile, "--fields", "--key")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b.c"}, c.fields)
+
+	shortKey := filepath.Join(dir, "short")
+	require.NoError(t, os.WriteFile(shortKey, []byte(encoded[:20]), 0o600))
+	_, err = LoadKey(shortKey)
+	assert.Error(t, err)
+
+	notBase64 := filepath.Join(dir, "raw")
+	require.NoError(t, os.WriteFile(notBase64, []byte(strings.Repeat("!", 44)), 0o600))
+	_, err = LoadKey(notBase64)
+	assert.ErrorContains(t, err, "not base64")
+}
@@ -0,0 +1,234 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+// Package fieldmap renames the fields of documents as they are exported or
+// imported, with a map from the field names of the database to the field
+// names of the files that one file lists for both directions.
+package fieldmap
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v2"
+)
+
+// Map renames the fields of documents. The map of a file is read as a map of
+// the names of the database to the names of the file, e.g.
+//
+//	_id: id
+//	firstName: first_name
+//	address.zipCode: address.postal_code
+//
+// where each dotted path renames each of its parts, so that both a field and
+// the fields embedded in it can be renamed. JSON files are read as well, as
+// they are YAML. A Map is safe for concurrent use.
+type Map struct {
+	root *level
+}
+
+// level holds the renames of the fields of an embedded document.
+type level struct {
+	fields map[string]*rename
+}
+
+type rename struct {
+	name string
+	// sub renames the fields embedded in the field, if any.
+	sub *level
+}
+
+// Load reads the map of the file at path, to rename the fields of the
+// database to those of the file, or the other way around if reverse is set.
+func Load(path string, reverse bool) (*Map, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading field map: %v", err)
+	}
+	paths := map[string]string{}
+	if err := yaml.UnmarshalStrict(content, &paths); err != nil {
+		return nil, fmt.Errorf("error parsing field map %v: %v", path, err)
+	}
+	m, err := New(paths, reverse)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field map %v: %v", path, err)
+	}
+	return m, nil
+}
+
+// New returns the Map of the paths of the database to the paths of the file,
+// reversed if reverse is set.
+func New(paths map[string]string, reverse bool) (*Map, error) {
+	from := make([]string, 0, len(paths))
+	for path := range paths {
+		from = append(from, path)
+	}
+	sort.Strings(from)
+
+	m := &Map{root: &level{fields: map[string]*rename{}}}
+	for _, path := range from {
+		source, target := strings.Split(path, "."), strings.Split(paths[path], ".")
+		for _, part := range append(source, target...) {
+			if part == "" {
+				return nil, fmt.Errorf("invalid path in '%v: %v'", path, paths[path])
+			}
+		}
+		if len(source) != len(target) {
+			return nil, fmt.Errorf(
+				"'%v' and '%v' must have the same number of parts",
+				path,
+				paths[path],
+			)
+		}
+		if reverse {
+			source, target = target, source
+		}
+		if err := m.root.add(source, target); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.root.check(""); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// add adds the renames of each part of source to the part of target at the
+// same depth.
+func (l *level) add(source, target []string) error {
+	r, ok := l.fields[source[0]]
+	if !ok {
+		r = &rename{name: target[0]}
+		l.fields[source[0]] = r
+	} else if r.name != target[0] {
+		return fmt.Errorf("'%v' is renamed to both '%v' and '%v'", source[0], r.name, target[0])
+	}
+	if len(source) == 1 {
+		return nil
+	}
+	if r.sub == nil {
+		r.sub = &level{fields: map[string]*rename{}}
+	}
+	return r.sub.add(source[1:], target[1:])
+}
+
+// check checks that no two fields of a level are renamed to the same name.
+func (l *level) check(prefix string) error {
+	renamed := map[string]string{}
+	for field, r := range l.fields {
+		if other, ok := renamed[r.name]; ok {
+			if other > field {
+				other, field = field, other
+			}
+			return fmt.Errorf(
+				"'%v%v' and '%v%v' are both renamed to '%v'",
+				prefix, other, prefix, field, r.name,
+			)
+		}
+		renamed[r.name] = field
+		if r.sub != nil {
+			if err := r.sub.check(prefix + field + "."); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Rename renames the fields of doc in place, including the fields of the
+// documents embedded in it and in its arrays. It fails if a field is renamed
+// to the name of another field of the same document that is kept.
+func (m *Map) Rename(doc bson.D) error {
+	return m.root.rename(doc, "")
+}
+
+func (l *level) rename(doc bson.D, prefix string) error {
+	renamed := false
+	for i := range doc {
+		r, ok := l.fields[doc[i].Key]
+		if !ok {
+			continue
+		}
+		renamed = renamed || r.name != doc[i].Key
+		path := prefix + doc[i].Key
+		doc[i].Key = r.name
+		if r.sub != nil {
+			if err := r.sub.renameValue(doc[i].Value, path+"."); err != nil {
+				return err
+			}
+		}
+	}
+	if !renamed {
+		return nil
+	}
+	keys := make(map[string]struct{}, len(doc))
+	for _, elem := range doc {
+		if _, ok := keys[elem.Key]; ok {
+			return fmt.Errorf("cannot rename a field to '%v%v', which already exists", prefix, elem.Key)
+		}
+		keys[elem.Key] = struct{}{}
+	}
+	return nil
+}
+
+// renameValue renames the fields of value if it is a document, or of the
+// documents of value if it is an array.
+func (l *level) renameValue(value interface{}, prefix string) error {
+	switch v := value.(type) {
+	case bson.D:
+		return l.rename(v, prefix)
+	case *bson.D:
+		return l.rename(*v, prefix)
+	case bson.A:
+		return l.renameArray(v, prefix)
+	case []interface{}:
+		return l.renameArray(v, prefix)
+	}
+	return nil
+}
+
+func (l *level) renameArray(values []interface{}, prefix string) error {
+	for _, value := range values {
+		if err := l.renameValue(value, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Path returns the dotted path that the field at path is renamed to.
+func (m *Map) Path(path string) string {
+	parts := strings.Split(path, ".")
+	l := m.root
+	for i, part := range parts {
+		if l == nil {
+			break
+		}
+		r, ok := l.fields[part]
+		if !ok && isIndex(part) {
+			// the index of an element of an array of documents
+			continue
+		}
+		if !ok {
+			break
+		}
+		parts[i] = r.name
+		l = r.sub
+	}
+	return strings.Join(parts, ".")
+}
+
+func isIndex(part string) bool {
+	for _, c := range part {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package fieldmap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRename(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	paths := map[string]string{
+		"_id":             "id",
+		"firstName":       "first_name",
+		"address.zipCode": "addr.postal_code",
+		"items.sku":       "items.SKU",
+		"a":               "b",
+		"b":               "a",
+	}
+	export, err := New(paths, false)
+	require.NoError(t, err)
+	imp, err := New(paths, true)
+	require.NoError(t, err)
+
+	original := func() bson.D {
+		return bson.D{
+			{"_id", 1},
+			{"firstName", "Ann"},
+			{"address", bson.D{{"zipCode", "10001"}, {"city", "NYC"}}},
+			{"items", bson.A{bson.D{{"sku", "x"}}, "plain", bson.D{{"qty", 2}}}},
+			{"a", 1},
+			{"b", 2},
+		}
+	}
+	doc := original()
+	require.NoError(t, export.Rename(doc))
+	assert.Equal(t, bson.D{
+		{"id", 1},
+		{"first_name", "Ann"},
+		{"addr", bson.D{{"postal_code", "10001"}, {"city", "NYC"}}},
+		{"items", bson.A{bson.D{{"SKU", "x"}}, "plain", bson.D{{"qty", 2}}}},
+		{"b", 1},
+		{"a", 2},
+	}, doc)
+
+	require.NoError(t, imp.Rename(doc))
+	assert.Equal(t, original(), doc)
+
+	assert.Equal(t, "addr.postal_code", export.Path("address.zipCode"))
+	assert.Equal(t, "items.0.SKU", export.Path("items.0.sku"))
+	assert.Equal(t, "address.zipCode", imp.Path("addr.postal_code"))
+	assert.Equal(t, "other.zipCode", export.Path("other.zipCode"))
+
+	assert.Error(t, export.Rename(bson.D{{"_id", 1}, {"id", 2}}))
+}
+
+func TestNewErrors(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	for _, paths := range []map[string]string{
+		{"a.b": "c"},
+		{"a.": "b.c"},
+		{"a": ""},
+		{"a": "x", "a.b": "y.b"},
+		{"a": "x", "b": "x"},
+	} {
+		_, err := New(paths, false)
+		assert.Error(t, err, "%v", paths)
+	}
+
+	// two fields renamed to the same name are only accepted one way
+	_, err := New(map[string]string{"a": "x", "b": "x"}, true)
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "map.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte("firstName: first_name\n"), 0o600))
+	jsonPath := filepath.Join(dir, "map.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"firstName": "first_name"}`), 0o600))
+
+	for _, path := range []string{yamlPath, jsonPath} {
+		m, err := Load(path, false)
+		require.NoError(t, err, path)
+		assert.Equal(t, "first_name", m.Path("firstName"))
+	}
+
+	badPath := filepath.Join(dir, "bad.yaml")
+	require.NoError(t, os.WriteFile(badPath, []byte("firstName: [1, 2]\n"), 0o600))
+	_, err := Load(badPath, false)
+	assert.Error(t, err)
+}
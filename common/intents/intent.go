@@ -528,3 +528,8 @@ func (mgr *Manager) Finalize(pType PriorityType) {
 func (mgr *Manager) UsePrioritizer(prioritizer IntentPrioritizer) {
 	mgr.prioritizer = prioritizer
 }
+
+// Prioritizer returns the prioritizer set by Finalize or UsePrioritizer.
+func (mgr *Manager) Prioritizer() IntentPrioritizer {
+	return mgr.prioritizer
+}
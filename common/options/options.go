@@ -16,12 +16,14 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	flags "github.com/jessevdk/go-flags"
 	"github.com/mongodb/mongo-tools/common/failpoint"
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/password"
+	"github.com/mongodb/mongo-tools/common/tempfile"
 	"github.com/mongodb/mongo-tools/common/util"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -73,6 +75,7 @@ type ToolOptions struct {
 	*Auth
 	*Kerberos
 	*Namespace
+	*TempFiles
 
 	// Force direct connection to the server and disable the
 	// drivers automatic repl set discovery logic.
@@ -101,8 +104,16 @@ type ToolOptions struct {
 
 	// Will attempt to parse positional arguments as connection strings if true
 	parsePositionalArgsAsURI bool
+
+	// NoPasswordPrompt, if set, makes NormalizeOptionsAndURI return
+	// ErrPasswordPrompt instead of prompting for a missing password.
+	NoPasswordPrompt bool
 }
 
+// ErrPasswordPrompt is the error of the options that would prompt for a
+// password when NoPasswordPrompt is set.
+var ErrPasswordPrompt = errors.New("a password would have to be entered at a prompt")
+
 type Namespace struct {
 	// Specified database and collection
 	DB         string `short:"d" long:"db" value-name:"<database-name>" description:"database to use"`
@@ -119,6 +130,8 @@ type General struct {
 	Version    bool   `long:"version" description:"print the tool version and exit"`
 	ConfigPath string `long:"config" description:"path to a configuration file"`
 
+	OTelEndpoint string `long:"otelEndpoint" value-name:"<url>" description:"export OpenTelemetry traces and metrics of the run to the OTLP/HTTP endpoint at this URL, e.g. http://localhost:4318"`
+
 	MaxProcs   int    `long:"numThreads" hidden:"true"`
 	Failpoints string `long:"failpoints" hidden:"true"`
 	Trace      bool   `long:"trace" hidden:"true"`
@@ -140,6 +153,32 @@ func (v Verbosity) IsQuiet() bool {
 	return v.Quiet
 }
 
+// Struct holding the options of the temporary files that a tool spills data
+// to, like the runs of an external sort.
+type TempFiles struct {
+	TempDir      string `long:"tempDir" value-name:"<directory-path>" description:"directory to create temporary files in, like the runs of external sorts and the prefetch buffer of an archive; the files of a run are removed when it ends, and the ones left by a run that crashed are removed by the next one (default: the directory for temporary files of the system)"`
+	MaxTempBytes int64  `long:"maxTempBytes" value-name:"<bytes>" description:"most bytes that the temporary files may use on disk together; a write past it fails the run (default 0, which is unlimited)"`
+
+	mu      sync.Mutex
+	manager *tempfile.Manager
+}
+
+// Manager returns the manager of temporary files for --tempDir and
+// --maxTempBytes, which is shared by all of its callers so that
+// --maxTempBytes limits the temporary files of the whole run. It is safe to
+// call on a nil *TempFiles, which returns a new unlimited manager each time.
+func (t *TempFiles) Manager() *tempfile.Manager {
+	if t == nil {
+		return tempfile.NewManager("", 0)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.manager == nil {
+		t.manager = tempfile.NewManager(t.TempDir, t.MaxTempBytes)
+	}
+	return t.manager
+}
+
 type URI struct {
 	ConnectionString string `long:"uri" value-name:"mongodb-uri" description:"mongodb uri connection string"`
 
@@ -157,25 +196,37 @@ type Connection struct {
 	TCPKeepAliveSeconds    int    `long:"TCPKeepAliveSeconds" default:"30" hidden:"true" description:"seconds between TCP keep alives"`
 	ServerSelectionTimeout int    `long:"serverSelectionTimeout" hidden:"true" description:"seconds to wait for server selection; 0 means driver default"`
 	Compressors            string `long:"compressors" default:"none" hidden:"true" value-name:"<snappy,...>" description:"comma-separated list of compressors to enable. Use 'none' to disable."`
+	MaxPoolSize            uint64 `long:"maxPoolSize" value-name:"<number>" description:"maximum number of connections to each server; 0 means the driver default of 100. Raise it when running more workers than that"`
+	MaxConnecting          uint64 `long:"maxConnecting" value-name:"<number>" description:"maximum number of connections each server's pool establishes at once; 0 means the driver default of 2"`
+	PoolWaitWarningMS      int    `long:"poolWaitWarningMS" value-name:"<milliseconds>" default:"5000" description:"warn when an operation waits this long for a connection from the pool, which means the workers outnumber --maxPoolSize; 0 disables the warning"`
+	Proxy                  string `long:"proxy" value-name:"<url>" description:"connect to the servers through a SOCKS5 or HTTP CONNECT proxy, given as socks5://[<user>:<password>@]<host>:<port> or http://[<user>:<password>@]<host>:<port>, e.g. to run from a network that only reaches the cluster through a bastion host"`
+	SRVRefreshInterval     int    `long:"srvRefreshInterval" value-name:"<seconds>" default:"0" description:"with a mongodb+srv connection string to a deployment that is not a sharded cluster, whose SRV record the driver refreshes itself, resolve its SRV record again this often, and reconnect to the hosts it lists when they change, e.g. during cluster maintenance, once no operation is in progress; 0 disables refreshing"`
 }
 
 // Struct holding ssl-related options.
 type SSL struct {
-	UseSSL              bool   `long:"ssl" description:"connect to a mongod or mongos that has ssl enabled"`
-	SSLCAFile           string `long:"sslCAFile" value-name:"<filename>" description:"the .pem file containing the root certificate chain from the certificate authority"`
-	SSLPEMKeyFile       string `long:"sslPEMKeyFile" value-name:"<filename>" description:"the .pem file containing the certificate and key"`
-	SSLPEMKeyPassword   string `long:"sslPEMKeyPassword" value-name:"<password>" description:"the password to decrypt the sslPEMKeyFile, if necessary"`
-	SSLCRLFile          string `long:"sslCRLFile" value-name:"<filename>" description:"the .pem file containing the certificate revocation list"`
-	SSLAllowInvalidCert bool   `long:"sslAllowInvalidCertificates" hidden:"true" description:"bypass the validation for server certificates"`
-	SSLAllowInvalidHost bool   `long:"sslAllowInvalidHostnames" hidden:"true" description:"bypass the validation for server name"`
-	SSLFipsMode         bool   `long:"sslFIPSMode" description:"use FIPS mode of the installed openssl library"`
-	TLSInsecure         bool   `long:"tlsInsecure" description:"bypass the validation for server's certificate chain and host name"`
+	UseSSL                   bool   `long:"ssl" description:"connect to a mongod or mongos that has ssl enabled"`
+	SSLCAFile                string `long:"sslCAFile" value-name:"<filename>" description:"the .pem file containing the root certificate chain from the certificate authority"`
+	SSLPEMKeyFile            string `long:"sslPEMKeyFile" value-name:"<filename>" description:"the .pem file containing the certificate and key"`
+	SSLPEMKeyPassword        string `long:"sslPEMKeyPassword" value-name:"<password>" description:"the password to decrypt the sslPEMKeyFile, if necessary"`
+	SSLPEMKeyPasswordFile    string `long:"sslPEMKeyPasswordFile" value-name:"<filename>" description:"read the password of --sslPEMKeyPassword from a file, without its trailing newline"`
+	SSLPEMKeyPasswordCommand string `long:"sslPEMKeyPasswordCommand" value-name:"<command>" description:"read the password of --sslPEMKeyPassword from the output of a shell command, e.g. a vault CLI, without its trailing newline"`
+	SSLPEMKeyPasswordEnv     string `long:"sslPEMKeyPasswordEnv" value-name:"<variable>" description:"read the password of --sslPEMKeyPassword from an environment variable"`
+	SSLCRLFile               string `long:"sslCRLFile" value-name:"<filename>" description:"the .pem file containing the certificate revocation list"`
+	SSLAllowInvalidCert      bool   `long:"sslAllowInvalidCertificates" hidden:"true" description:"bypass the validation for server certificates"`
+	SSLAllowInvalidHost      bool   `long:"sslAllowInvalidHostnames" hidden:"true" description:"bypass the validation for server name"`
+	SSLFipsMode              bool   `long:"sslFIPSMode" description:"use FIPS mode of the installed openssl library"`
+	TLSInsecure              bool   `long:"tlsInsecure" description:"bypass the validation for server's certificate chain and host name"`
+	SSLHostsFile             string `long:"sslHostsFile" value-name:"<filename>" description:"path to a YAML file listing hosts that need another client certificate, CA file or server name than the other ssl options, e.g. the members of a cluster that each have their own certificate authority"`
 }
 
 // Struct holding auth-related options.
 type Auth struct {
 	Username        string `short:"u" value-name:"<username>" long:"username" description:"username for authentication"`
 	Password        string `short:"p" value-name:"<password>" long:"password" description:"password for authentication"`
+	PasswordFile    string `long:"passwordFile" value-name:"<filename>" description:"read the password for authentication from a file, without its trailing newline"`
+	PasswordCommand string `long:"passwordCommand" value-name:"<command>" description:"read the password for authentication from the output of a shell command, e.g. a vault CLI, without its trailing newline"`
+	PasswordEnv     string `long:"passwordEnv" value-name:"<variable>" description:"read the password for authentication from an environment variable"`
 	Source          string `long:"authenticationDatabase" value-name:"<database-name>" description:"database that holds the user's credentials"`
 	Mechanism       string `long:"authenticationMechanism" value-name:"<mechanism>" description:"authentication mechanism to use"`
 	AWSSessionToken string `long:"awsSessionToken" value-name:"<aws-session-token>" description:"session token to authenticate via AWS IAM"`
@@ -201,6 +252,7 @@ type EnabledOptions struct {
 	Connection bool
 	Namespace  bool
 	URI        bool
+	TempFiles  bool
 }
 
 func parseVal(val string) int {
@@ -231,6 +283,7 @@ func New(
 		Auth:       &Auth{},
 		Namespace:  &Namespace{},
 		Kerberos:   &Kerberos{},
+		TempFiles:  &TempFiles{},
 		parser: flags.NewNamedParser(
 			fmt.Sprintf("%v %v", appName, usageStr), flags.None),
 		enabledOptions:           enabled,
@@ -298,6 +351,11 @@ func New(
 			panic(fmt.Errorf("couldn't register URI options"))
 		}
 	}
+	if enabled.TempFiles {
+		if _, err := opts.parser.AddGroup("temporary file options", "", opts.TempFiles); err != nil {
+			panic(fmt.Errorf("couldn't register temporary file options"))
+		}
+	}
 	if opts.MaxProcs <= 0 {
 		opts.MaxProcs = runtime.NumCPU()
 	}
@@ -523,11 +581,20 @@ func (opts *ToolOptions) ParseArgs(args []string) ([]string, error) {
 
 	failpoint.ParseFailpoints(opts.Failpoints)
 
+	err = opts.readSecretOptions()
+	if err != nil {
+		return []string{}, err
+	}
+
 	err = opts.NormalizeOptionsAndURI()
 	if err != nil {
 		return []string{}, err
 	}
 
+	if opts.MaxTempBytes < 0 {
+		return []string{}, fmt.Errorf("--maxTempBytes must not be negative")
+	}
+
 	return args, err
 }
 
@@ -581,10 +648,80 @@ func LogSensitiveOptionWarnings(args []string) {
 	}
 }
 
+// readSecretOptions sets --password and --sslPEMKeyPassword from the file,
+// the command or the environment variable of their --*File, --*Command and
+// --*Env options, if set.
+func (opts *ToolOptions) readSecretOptions() error {
+	if opts.Auth != nil {
+		pass, err := readSecret(
+			"--password",
+			opts.Auth.Password,
+			opts.Auth.PasswordFile,
+			opts.Auth.PasswordCommand,
+			opts.Auth.PasswordEnv,
+		)
+		if err != nil {
+			return err
+		}
+		opts.Auth.Password = pass
+	}
+	if opts.SSL != nil {
+		pass, err := readSecret(
+			"--sslPEMKeyPassword",
+			opts.SSL.SSLPEMKeyPassword,
+			opts.SSL.SSLPEMKeyPasswordFile,
+			opts.SSL.SSLPEMKeyPasswordCommand,
+			opts.SSL.SSLPEMKeyPasswordEnv,
+		)
+		if err != nil {
+			return err
+		}
+		opts.SSL.SSLPEMKeyPassword = pass
+	}
+	return nil
+}
+
+// readSecret returns the secret of the option name, which is either its
+// value, or read from file, from the output of command or from the
+// environment variable env.
+func readSecret(name, value, file, command, env string) (string, error) {
+	set := 0
+	for _, v := range []string{value, file, command, env} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", fmt.Errorf(
+			"only one of %v, %vFile, %vCommand and %vEnv can be set",
+			name,
+			name,
+			name,
+			name,
+		)
+	}
+	var secret string
+	var err error
+	switch {
+	case file != "":
+		secret, err = password.ReadSecretFile(file)
+	case command != "":
+		secret, err = password.RunSecretCommand(command)
+	case env != "":
+		secret, err = password.ReadSecretEnv(env)
+	default:
+		return value, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading %v: %v", name, err)
+	}
+	return secret, nil
+}
+
 // ParseConfigFile iterates over args to find a --config option. If not found, we return.
 // If found, we read the contents of the specified config file in YAML format. We parse
-// any values corresponding to --password, --uri and --sslPEMKeyPassword, and store them
-// in the opts.
+// any values corresponding to --password, --passwordEnv, --uri, --sslPEMKeyPassword and
+// --sslPEMKeyPasswordEnv, and store them in the opts.
 // This also applies to --destinationPassword for mongomirror only.
 func (opts *ToolOptions) ParseConfigFile(args []string) error {
 	// Get config file path from the arguments, if specified.
@@ -606,10 +743,12 @@ func (opts *ToolOptions) ParseConfigFile(args []string) error {
 
 	// Unmarshal the config file as a top-level YAML file.
 	var config struct {
-		Password            string `yaml:"password"`
-		ConnectionString    string `yaml:"uri"`
-		SSLPEMKeyPassword   string `yaml:"sslPEMKeyPassword"`
-		DestinationPassword string `yaml:"destinationPassword"`
+		Password             string `yaml:"password"`
+		PasswordEnv          string `yaml:"passwordEnv"`
+		ConnectionString     string `yaml:"uri"`
+		SSLPEMKeyPassword    string `yaml:"sslPEMKeyPassword"`
+		SSLPEMKeyPasswordEnv string `yaml:"sslPEMKeyPasswordEnv"`
+		DestinationPassword  string `yaml:"destinationPassword"`
 	}
 	err = yaml.UnmarshalStrict(configBytes, &config)
 	if err != nil {
@@ -618,8 +757,10 @@ func (opts *ToolOptions) ParseConfigFile(args []string) error {
 
 	// Assign each parsed value to its respective ToolOptions field.
 	opts.Auth.Password = config.Password
+	opts.Auth.PasswordEnv = config.PasswordEnv
 	opts.URI.ConnectionString = config.ConnectionString
 	opts.SSL.SSLPEMKeyPassword = config.SSLPEMKeyPassword
+	opts.SSL.SSLPEMKeyPasswordEnv = config.SSLPEMKeyPasswordEnv
 
 	// Mongomirror has an extra option to set.
 	for _, extraOpt := range opts.URI.extraOptionsRegistry {
@@ -697,6 +838,9 @@ func (opts *ToolOptions) NormalizeOptionsAndURI() error {
 
 	// finalize auth options, filling in missing passwords
 	if opts.Auth.ShouldAskForPassword() {
+		if opts.NoPasswordPrompt {
+			return fmt.Errorf("error reading password for mongo user: %w", ErrPasswordPrompt)
+		}
 		pass, err := password.Prompt("mongo user")
 		if err != nil {
 			return fmt.Errorf("error reading password: %v", err)
@@ -710,6 +854,9 @@ func (opts *ToolOptions) NormalizeOptionsAndURI() error {
 		return fmt.Errorf("error determining whether client cert needs password: %v", err)
 	}
 	if shouldAskForSSLPassword {
+		if opts.NoPasswordPrompt {
+			return fmt.Errorf("error reading password for client certificate: %w", ErrPasswordPrompt)
+		}
 		pass, err := password.Prompt("client certificate")
 		if err != nil {
 			return fmt.Errorf("error reading password: %v", err)
@@ -920,6 +1067,56 @@ func (opts *ToolOptions) setOptionsFromURI(cs *connstring.ConnString) error {
 			opts.Connection.SocketTimeout = int(cs.SocketTimeout / time.Millisecond)
 		}
 
+		if opts.Connection.MaxPoolSize != 0 && cs.MaxPoolSizeSet {
+			if opts.Connection.MaxPoolSize != cs.MaxPoolSize {
+				return ConflictingArgsErrorFormat(
+					"maxPoolSize",
+					strconv.FormatUint(cs.MaxPoolSize, 10),
+					strconv.FormatUint(opts.Connection.MaxPoolSize, 10),
+					"--maxPoolSize",
+				)
+			}
+		}
+		if opts.Connection.MaxPoolSize != 0 && !cs.MaxPoolSizeSet {
+			cs.MaxPoolSize = opts.Connection.MaxPoolSize
+			cs.MaxPoolSizeSet = true
+		}
+		if opts.Connection.MaxPoolSize == 0 && cs.MaxPoolSizeSet {
+			opts.Connection.MaxPoolSize = cs.MaxPoolSize
+		}
+
+		if opts.Connection.MaxConnecting != 0 && cs.MaxConnectingSet {
+			if opts.Connection.MaxConnecting != cs.MaxConnecting {
+				return ConflictingArgsErrorFormat(
+					"maxConnecting",
+					strconv.FormatUint(cs.MaxConnecting, 10),
+					strconv.FormatUint(opts.Connection.MaxConnecting, 10),
+					"--maxConnecting",
+				)
+			}
+		}
+		if opts.Connection.MaxConnecting != 0 && !cs.MaxConnectingSet {
+			cs.MaxConnecting = opts.Connection.MaxConnecting
+			cs.MaxConnectingSet = true
+		}
+		if opts.Connection.MaxConnecting == 0 && cs.MaxConnectingSet {
+			opts.Connection.MaxConnecting = cs.MaxConnecting
+		}
+
+		if opts.Connection.PoolWaitWarningMS < 0 {
+			return fmt.Errorf(
+				"--poolWaitWarningMS must not be negative, got %v",
+				opts.Connection.PoolWaitWarningMS,
+			)
+		}
+
+		if opts.Connection.SRVRefreshInterval < 0 {
+			return fmt.Errorf(
+				"--srvRefreshInterval must not be negative, got %v",
+				opts.Connection.SRVRefreshInterval,
+			)
+		}
+
 		if len(cs.Compressors) != 0 {
 			if opts.Connection.Compressors != "none" &&
 				opts.Connection.Compressors != strings.Join(cs.Compressors, ",") {
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
@@ -40,7 +41,7 @@ func TestLogUnsupportedOptions(t *testing.T) {
 	t.Run("no warning should be logged if there are no unsupported options", func(t *testing.T) {
 		args := []string{"mongodb://mongodb.test.com:27017"}
 
-		enabled := EnabledOptions{true, true, true, true}
+		enabled := EnabledOptions{true, true, true, true, false}
 		opts := New("", "", "", "", true, enabled)
 
 		_, err := opts.ParseArgs(args)
@@ -55,7 +56,7 @@ func TestLogUnsupportedOptions(t *testing.T) {
 	t.Run("a warning should be logged if there is an unsupported option", func(t *testing.T) {
 		args := []string{"mongodb://mongodb.test.com:27017/?foo=bar"}
 
-		enabled := EnabledOptions{true, true, true, true}
+		enabled := EnabledOptions{true, true, true, true, false}
 		opts := New("", "", "", "", true, enabled)
 
 		_, err := opts.ParseArgs(args)
@@ -73,7 +74,7 @@ func TestLogUnsupportedOptions(t *testing.T) {
 func TestVerbosityFlag(t *testing.T) {
 	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
 
-	enabled := EnabledOptions{false, false, false, false}
+	enabled := EnabledOptions{false, false, false, false, false}
 	optPtr := New("", "", "", "", true, enabled)
 	require.NotNil(t, optPtr)
 	require.NotNil(t, optPtr.parser)
@@ -163,7 +164,7 @@ func TestParseAndSetOptions(t *testing.T) {
 
 	FalseValue := false
 
-	enabledURIOnly := EnabledOptions{false, false, false, true}
+	enabledURIOnly := EnabledOptions{false, false, false, true, false}
 	testCases := []uriTester{
 		{
 			Name: "built with ssl",
@@ -652,7 +653,7 @@ func runConfigFileTestCases(t *testing.T, testCases []configTester) {
 			if err := os.WriteFile(configFilePath, testCase.yamlBytes, 0644); err != nil {
 				require.NoError(t, err)
 			}
-			opts := New("test", "", "", "", false, EnabledOptions{true, true, true, true})
+			opts := New("test", "", "", "", false, EnabledOptions{true, true, true, true, false})
 			err := opts.ParseConfigFile(args)
 
 			if testCase.outcome == ShouldSucceed {
@@ -676,7 +677,7 @@ func runConfigFileTestCases(t *testing.T, testCases []configTester) {
 }
 
 func createExpectedOpts(pw string, uri string, ssl string) *ToolOptions {
-	opts := New("test", "", "", "", false, EnabledOptions{true, true, true, true})
+	opts := New("test", "", "", "", false, EnabledOptions{true, true, true, true, false})
 	opts.Auth.Password = pw
 	opts.URI.ConnectionString = uri
 	opts.SSL.SSLPEMKeyPassword = ssl
@@ -750,6 +751,12 @@ func TestParseConfigFile(t *testing.T) {
 				createExpectedOpts("abc123", "def456", "ghi789"),
 				ShouldSucceed,
 			},
+			{
+				"containing the environment variables of the passwords",
+				[]byte("passwordEnv: A\nsslPEMKeyPasswordEnv: B"),
+				createExpectedOpts("", "", ""),
+				ShouldSucceed,
+			},
 			{
 				"containing a duplicate field",
 				[]byte("password: abc123\npassword: def456"),
@@ -982,6 +989,8 @@ func TestOptionsParsing(t *testing.T) {
 		{"--serverSelectionTimeout", "serverSelectionTimeoutMS", "1000", "2000"},
 		{"--dialTimeout", "connectTimeoutMS", "1000", "2000"},
 		{"--socketTimeout", "socketTimeoutMS", "1000", "2000"},
+		{"--maxPoolSize", "maxPoolSize", "200", "300"},
+		{"--maxConnecting", "maxConnecting", "4", "8"},
 
 		{"--authenticationMechanism", "authMechanism", "SCRAM-SHA-1", "GSSAPI"},
 
@@ -1163,6 +1172,18 @@ func TestNamespace_String(t *testing.T) {
 
 }
 
+func TestTempFilesManager(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	tempFiles := &TempFiles{TempDir: t.TempDir(), MaxTempBytes: 100}
+	manager := tempFiles.Manager()
+	require.Equal(t, tempFiles.TempDir, manager.Dir())
+	require.Same(t, manager, tempFiles.Manager(), "the callers share the quota")
+
+	var unset *TempFiles
+	require.NotNil(t, unset.Manager())
+}
+
 func TestDeprecationWarning(t *testing.T) {
 	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
 	t.Run("deprecate message", func(t *testing.T) {
@@ -1273,6 +1294,125 @@ func TestPasswordPrompt(t *testing.T) {
 		require.Regexp(t, expectPrompt, string(prompt))
 		require.Equal(t, pw, opts.ConnString.Password)
 	})
+
+	t.Run("no prompt when prompting is disabled", func(t *testing.T) {
+		stderr, cleanupStderr := mockStderr(t)
+		defer cleanupStderr()
+
+		cleanup := mockStdin(t, pw)
+		defer cleanup()
+
+		opts := newTestOpts(t)
+		opts.Auth.Username = "someuser"
+		opts.NoPasswordPrompt = true
+		err := opts.NormalizeOptionsAndURI()
+		require.ErrorIs(t, err, ErrPasswordPrompt)
+
+		prompt, err := os.ReadFile(stderr.Name())
+		require.NoError(t, err)
+		require.Empty(t, string(prompt))
+
+		opts = newTestOpts(t)
+		opts.Auth.Username = "someuser"
+		opts.Auth.Password = pw
+		opts.NoPasswordPrompt = true
+		require.NoError(t, opts.NormalizeOptionsAndURI())
+	})
+}
+
+func TestPasswordFileAndCommand(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	enabled := EnabledOptions{Auth: true, Connection: true, URI: true}
+	path := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(path, []byte("some-password\n"), 0600))
+
+	opts := New("test", "", "", "", true, enabled)
+	_, err := opts.ParseArgs([]string{"--username", "someuser", "--passwordFile", path})
+	require.NoError(t, err)
+	require.Equal(t, "some-password", opts.Auth.Password)
+	require.Equal(t, "some-password", opts.ConnString.Password)
+
+	// passwords that look like the prefixes of secret references are kept
+	opts = New("test", "", "", "", true, enabled)
+	_, err = opts.ParseArgs([]string{"--username", "someuser", "--password", "exec:echo x"})
+	require.NoError(t, err)
+	require.Equal(t, "exec:echo x", opts.Auth.Password)
+
+	if runtime.GOOS != "windows" {
+		opts = New("test", "", "", "", true, enabled)
+		_, err = opts.ParseArgs(
+			[]string{"--username", "someuser", "--passwordCommand", "echo other-password"},
+		)
+		require.NoError(t, err)
+		require.Equal(t, "other-password", opts.Auth.Password)
+	}
+
+	opts = New("test", "", "", "", true, enabled)
+	_, err = opts.ParseArgs([]string{"--passwordFile", path + ".missing"})
+	require.ErrorContains(t, err, "error reading --password")
+
+	opts = New("test", "", "", "", true, enabled)
+	_, err = opts.ParseArgs([]string{"--password", "x", "--passwordFile", path})
+	require.ErrorContains(t, err, "only one of --password")
+}
+
+func TestPasswordEnv(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	enabled := EnabledOptions{Auth: true, Connection: true, URI: true}
+	t.Setenv("MONGO_TOOLS_TEST_PASSWORD", "some-password")
+	t.Setenv("MONGO_TOOLS_TEST_KEY_PASSWORD", "key-password")
+
+	opts := New("test", "", "", "", true, enabled)
+	_, err := opts.ParseArgs([]string{
+		"--username", "someuser",
+		"--passwordEnv", "MONGO_TOOLS_TEST_PASSWORD",
+		"--sslPEMKeyPasswordEnv", "MONGO_TOOLS_TEST_KEY_PASSWORD",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "some-password", opts.Auth.Password)
+	require.Equal(t, "some-password", opts.ConnString.Password)
+	require.Equal(t, "key-password", opts.SSL.SSLPEMKeyPassword)
+
+	// the username can be in the connection string
+	opts = New("test", "", "", "", true, enabled)
+	_, err = opts.ParseArgs([]string{
+		"mongodb://someuser@localhost:27017/",
+		"--passwordEnv", "MONGO_TOOLS_TEST_PASSWORD",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "some-password", opts.ConnString.Password)
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(
+		configPath,
+		[]byte("passwordEnv: MONGO_TOOLS_TEST_PASSWORD\n"+
+			"sslPEMKeyPasswordEnv: MONGO_TOOLS_TEST_KEY_PASSWORD\n"),
+		0600,
+	))
+	opts = New("test", "", "", "", true, enabled)
+	_, err = opts.ParseArgs([]string{"--username", "someuser", "--config", configPath})
+	require.NoError(t, err)
+	require.Equal(t, "some-password", opts.Auth.Password)
+	require.Equal(t, "key-password", opts.SSL.SSLPEMKeyPassword)
+
+	opts = New("test", "", "", "", true, enabled)
+	_, err = opts.ParseArgs([]string{"--passwordEnv", "MONGO_TOOLS_TEST_PASSWORD_MISSING"})
+	require.ErrorContains(t, err, "error reading --password")
+
+	opts = New("test", "", "", "", true, enabled)
+	_, err = opts.ParseArgs(
+		[]string{"--password", "x", "--passwordEnv", "MONGO_TOOLS_TEST_PASSWORD"},
+	)
+	require.ErrorContains(t, err, "only one of --password")
+
+	opts = New("test", "", "", "", true, enabled)
+	_, err = opts.ParseArgs([]string{
+		"--sslPEMKeyPasswordCommand", "echo x",
+		"--sslPEMKeyPasswordEnv", "MONGO_TOOLS_TEST_KEY_PASSWORD",
+	})
+	require.ErrorContains(t, err, "only one of --sslPEMKeyPassword")
 }
 
 func newTestOpts(t *testing.T) *ToolOptions {
@@ -2,6 +2,9 @@ package password
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/mongodb/mongo-tools/common/testtype"
@@ -25,3 +28,44 @@ func TestPasswordFromNonTerminal(t *testing.T) {
 		So(pass, ShouldEqual, testPwd)
 	})
 }
+
+func TestReadSecrets(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+	Convey("Reading secrets", t, func() {
+		Convey("a file is read without its trailing newline", func() {
+			path := filepath.Join(t.TempDir(), "secret")
+			So(os.WriteFile(path, []byte(testPwd+"\n"), 0600), ShouldBeNil)
+
+			pass, err := ReadSecretFile(path)
+			So(err, ShouldBeNil)
+			So(pass, ShouldEqual, testPwd)
+
+			_, err = ReadSecretFile(path + ".missing")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("an environment variable is read as it is", func() {
+			t.Setenv("MONGO_TOOLS_TEST_SECRET", testPwd+"\n")
+
+			pass, err := ReadSecretEnv("MONGO_TOOLS_TEST_SECRET")
+			So(err, ShouldBeNil)
+			So(pass, ShouldEqual, testPwd+"\n")
+
+			_, err = ReadSecretEnv("MONGO_TOOLS_TEST_SECRET_MISSING")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("a command's output is read without its trailing newline", func() {
+			if runtime.GOOS == "windows" {
+				SkipSo("secret commands are tested with a POSIX shell")
+				return
+			}
+			pass, err := RunSecretCommand("echo " + testPwd)
+			So(err, ShouldBeNil)
+			So(pass, ShouldEqual, testPwd)
+
+			_, err = RunSecretCommand("exit 3")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
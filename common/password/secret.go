@@ -0,0 +1,61 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package password
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ReadSecretFile returns the secret stored in the file at path, so that it
+// never appears in process listings. A single trailing newline is trimmed.
+func ReadSecretFile(path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading secret from file %#q: %v", path, err)
+	}
+	return trimNewline(string(contents)), nil
+}
+
+// ReadSecretEnv returns the secret stored in the environment variable name,
+// which must be set.
+func ReadSecretEnv(name string) (string, error) {
+	secret, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %v is not set", name)
+	}
+	return secret, nil
+}
+
+// RunSecretCommand runs command with the system shell, e.g. a vault CLI, and
+// returns its standard output without a single trailing newline. The
+// command's standard error is passed through so that it can report problems
+// or prompt the user.
+func RunSecretCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	stdout := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("error running secret command %#q: %v", command, err)
+	}
+	return trimNewline(stdout.String()), nil
+}
+
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
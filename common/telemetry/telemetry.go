@@ -0,0 +1,383 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+// Package telemetry exports OpenTelemetry traces and metrics of a tool run to
+// the OTLP/HTTP endpoint of --otelEndpoint, in the JSON encoding of OTLP, so
+// that long jobs can be followed in standard tracing backends. Every span is
+// part of one trace, under a root span that lasts for the whole run. The
+// package does nothing unless Init is called with an endpoint, and all its
+// functions and the methods of a nil *Span are safe to call when it isn't.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+)
+
+const (
+	// flushInterval is how often the ended spans and the metrics are sent.
+	flushInterval = 5 * time.Second
+	// exportTimeout is how long a request to the endpoint may take.
+	exportTimeout = 10 * time.Second
+	// maxPendingSpans is how many ended spans are kept while the endpoint
+	// can't be reached; newer spans are dropped.
+	maxPendingSpans = 10000
+
+	scopeName = "github.com/mongodb/mongo-tools"
+
+	spanKindInternal = 1
+	statusCodeOK     = 1
+	statusCodeError  = 2
+	// cumulative aggregation temporality, for sums that only grow
+	temporalityCumulative = 2
+)
+
+var current atomic.Pointer[exporter]
+
+// Attribute is a key and value describing a span or a metric.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{key, value}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int64) Attribute {
+	return Attribute{key, value}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{key, value}
+}
+
+// Span is an operation of the run, from Start to End.
+type Span struct {
+	exporter *exporter
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	start    time.Time
+
+	mu    sync.Mutex
+	attrs []Attribute
+	ended bool
+}
+
+type exporter struct {
+	endpoint string
+	client   *http.Client
+	resource []Attribute
+	start    time.Time
+	root     *Span
+
+	mu       sync.Mutex
+	spans    []otlpSpan
+	dropped  int
+	counters map[string]*counter
+	failed   bool
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// counter is the running total of a metric for one set of attributes.
+type counter struct {
+	name  string
+	attrs []Attribute
+	value int64
+}
+
+// Init starts exporting the spans and metrics of the tool named service to
+// endpoint, the base URL of an OTLP/HTTP receiver such as
+// http://localhost:4318. It does nothing if endpoint is empty.
+func Init(service, version, endpoint string) error {
+	if endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid --otelEndpoint %q: must be an http or https URL", endpoint)
+	}
+	e := &exporter{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: exportTimeout},
+		resource: []Attribute{
+			String("service.name", service),
+			String("service.version", version),
+		},
+		start:    time.Now(),
+		counters: map[string]*counter{},
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	e.root = e.newSpan(service, nil, nil)
+	current.Store(e)
+	go e.run()
+	return nil
+}
+
+// Shutdown ends the root span and sends everything that was not sent yet.
+func Shutdown() {
+	shutdown(nil)
+}
+
+// Exit calls Shutdown and then exits with code, so that the spans of a tool
+// that exits early are still sent. The root span gets an error status if
+// code is not 0.
+func Exit(code int) {
+	var err error
+	if code != 0 {
+		err = fmt.Errorf("exited with code %v", code)
+	}
+	shutdown(err)
+	os.Exit(code)
+}
+
+func shutdown(err error) {
+	e := current.Swap(nil)
+	if e == nil {
+		return
+	}
+	e.root.End(err)
+	close(e.quit)
+	<-e.done
+}
+
+// Start starts a span under the root span of the run. It returns nil if
+// telemetry is not enabled.
+func Start(name string, attrs ...Attribute) *Span {
+	e := current.Load()
+	if e == nil {
+		return nil
+	}
+	return e.newSpan(name, e.root, attrs)
+}
+
+// Add adds value to the metric called name for attrs.
+func Add(name string, value int64, attrs ...Attribute) {
+	e := current.Load()
+	if e == nil {
+		return
+	}
+	key := name
+	for _, attr := range attrs {
+		key += fmt.Sprintf("\x00%v=%v", attr.Key, attr.Value)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, ok := e.counters[key]
+	if !ok {
+		c = &counter{name: name, attrs: attrs}
+		e.counters[key] = c
+	}
+	c.value += value
+}
+
+// Start starts a span under s.
+func (s *Span) Start(name string, attrs ...Attribute) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.exporter.newSpan(name, s, attrs)
+}
+
+// SetAttributes adds attributes to s.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// End ends s, with an error status if err is not nil. Only the first call
+// has an effect.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: unixNano(s.start),
+		EndTimeUnixNano:   unixNano(time.Now()),
+		Attributes:        otlpAttributes(s.attrs),
+		Status:            otlpStatus{Code: statusCodeOK},
+	}
+	s.mu.Unlock()
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	if err != nil {
+		span.Status = otlpStatus{Code: statusCodeError, Message: err.Error()}
+	}
+
+	e := s.exporter
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.spans) >= maxPendingSpans {
+		e.dropped++
+		return
+	}
+	e.spans = append(e.spans, span)
+}
+
+func (e *exporter) newSpan(name string, parent *Span, attrs []Attribute) *Span {
+	s := &Span{
+		exporter: e,
+		name:     name,
+		start:    time.Now(),
+		attrs:    append([]Attribute(nil), attrs...),
+	}
+	//nolint:errcheck // crypto/rand.Read does not fail
+	rand.Read(s.spanID[:])
+	if parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		//nolint:errcheck
+		rand.Read(s.traceID[:])
+	}
+	return s
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.quit:
+			e.flush()
+			return
+		case <-ticker.C:
+			e.flush()
+		}
+	}
+}
+
+// flush sends the ended spans and the current totals of the metrics.
+func (e *exporter) flush() {
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	dropped := e.dropped
+	e.dropped = 0
+	var metrics []otlpMetric
+	now := unixNano(time.Now())
+	keys := make([]string, 0, len(e.counters))
+	for key := range e.counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		c := e.counters[key]
+		metrics = append(metrics, otlpMetric{
+			Name: c.name,
+			Unit: "1",
+			Sum: otlpSum{
+				AggregationTemporality: temporalityCumulative,
+				IsMonotonic:            true,
+				DataPoints: []otlpDataPoint{{
+					Attributes:        otlpAttributes(c.attrs),
+					StartTimeUnixNano: unixNano(e.start),
+					TimeUnixNano:      now,
+					AsInt:             strconv.FormatInt(c.value, 10),
+				}},
+			},
+		})
+	}
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		log.Logvf(log.Info, "dropped %v telemetry spans that could not be sent", dropped)
+	}
+	resource := otlpResource{Attributes: otlpAttributes(e.resource)}
+	scope := otlpScope{Name: scopeName}
+	if len(spans) > 0 {
+		e.post("/v1/traces", otlpTraces{ResourceSpans: []otlpResourceSpans{{
+			Resource:   resource,
+			ScopeSpans: []otlpScopeSpans{{Scope: scope, Spans: spans}},
+		}}})
+	}
+	if len(metrics) > 0 {
+		e.post("/v1/metrics", otlpMetrics{ResourceMetrics: []otlpResourceMetrics{{
+			Resource:     resource,
+			ScopeMetrics: []otlpScopeMetrics{{Scope: scope, Metrics: metrics}},
+		}}})
+	}
+}
+
+// post sends body to path of the endpoint. Failures are logged once, since
+// telemetry must not get in the way of the tool.
+func (e *exporter) post(path string, body interface{}) {
+	content, err := json.Marshal(body)
+	if err == nil {
+		err = e.send(e.endpoint+path, content)
+	}
+	if err == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.failed {
+		e.failed = true
+		log.Logvf(log.Always, "error sending telemetry to %v: %v", e.endpoint, err)
+	}
+}
+
+func (e *exporter) send(url string, content []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%v returned %v", url, resp.Status)
+	}
+	return nil
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
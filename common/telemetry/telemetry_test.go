@@ -0,0 +1,154 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver records the OTLP requests sent to it.
+type receiver struct {
+	mu      sync.Mutex
+	traces  []otlpTraces
+	metrics []otlpMetrics
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil || req.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch req.URL.Path {
+	case "/v1/traces":
+		var traces otlpTraces
+		err = json.Unmarshal(body, &traces)
+		r.traces = append(r.traces, traces)
+	case "/v1/metrics":
+		var metrics otlpMetrics
+		err = json.Unmarshal(body, &metrics)
+		r.metrics = append(r.metrics, metrics)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (r *receiver) spans() map[string]otlpSpan {
+	spans := map[string]otlpSpan{}
+	for _, traces := range r.traces {
+		for _, rs := range traces.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					spans[span.Name] = span
+				}
+			}
+		}
+	}
+	return spans
+}
+
+func attribute(attrs []otlpAttribute, key string) *otlpValue {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return &attr.Value
+		}
+	}
+	return nil
+}
+
+func TestExport(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	r := &receiver{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	require.NoError(t, Init("mongodump", "100.0.0", server.URL+"/"))
+	span := Start("mongodump.dumpCollection", String("db.namespace", "test.c"))
+	child := span.Start("bulkWrite", Int("operations", 3))
+	child.End(fmt.Errorf("write failed"))
+	span.SetAttributes(Bool("done", true))
+	span.End(nil)
+	span.End(fmt.Errorf("ignored"))
+	Add("mongodump.documents", 2, String("db.namespace", "test.c"))
+	Add("mongodump.documents", 3, String("db.namespace", "test.c"))
+	Add("mongodump.documents", 1, String("db.namespace", "test.d"))
+	Shutdown()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := r.spans()
+	require.Len(t, spans, 3)
+	root, span1, child1 := spans["mongodump"], spans["mongodump.dumpCollection"], spans["bulkWrite"]
+
+	assert.Empty(t, root.ParentSpanID)
+	assert.Equal(t, root.SpanID, span1.ParentSpanID)
+	assert.Equal(t, span1.SpanID, child1.ParentSpanID)
+	assert.Len(t, root.TraceID, 32)
+	assert.Len(t, root.SpanID, 16)
+	assert.Equal(t, root.TraceID, span1.TraceID)
+	assert.Equal(t, root.TraceID, child1.TraceID)
+
+	assert.Equal(t, statusCodeOK, span1.Status.Code)
+	assert.Equal(t, otlpStatus{Code: statusCodeError, Message: "write failed"}, child1.Status)
+	assert.Equal(t, "test.c", *attribute(span1.Attributes, "db.namespace").StringValue)
+	assert.True(t, *attribute(span1.Attributes, "done").BoolValue)
+	assert.Equal(t, "3", *attribute(child1.Attributes, "operations").IntValue)
+
+	resource := r.traces[0].ResourceSpans[0].Resource.Attributes
+	assert.Equal(t, "mongodump", *attribute(resource, "service.name").StringValue)
+	assert.Equal(t, "100.0.0", *attribute(resource, "service.version").StringValue)
+
+	require.NotEmpty(t, r.metrics)
+	metrics := r.metrics[len(r.metrics)-1].ResourceMetrics[0].ScopeMetrics[0].Metrics
+	totals := map[string]string{}
+	for _, metric := range metrics {
+		assert.Equal(t, "mongodump.documents", metric.Name)
+		assert.True(t, metric.Sum.IsMonotonic)
+		point := metric.Sum.DataPoints[0]
+		totals[*attribute(point.Attributes, "db.namespace").StringValue] = point.AsInt
+	}
+	assert.Equal(t, map[string]string{"test.c": "5", "test.d": "1"}, totals)
+}
+
+func TestDisabled(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	require.NoError(t, Init("mongodump", "100.0.0", ""))
+	span := Start("mongodump.dumpCollection")
+	assert.Nil(t, span)
+	span.Start("bulkWrite").End(nil)
+	span.SetAttributes(Int("documents", 1))
+	span.End(nil)
+	Add("mongodump.documents", 1)
+	Shutdown()
+}
+
+func TestInvalidEndpoint(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	for _, endpoint := range []string{"localhost:4318", "ftp://localhost", "http://"} {
+		assert.Error(t, Init("mongodump", "100.0.0", endpoint), endpoint)
+	}
+	assert.Nil(t, current.Load())
+}
@@ -0,0 +1,23 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+//go:build windows
+// +build windows
+
+package tempfile
+
+import "os"
+
+// processRunning returns whether a process with the given ID exists:
+// os.FindProcess opens the process on Windows, which fails if there is none.
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
@@ -0,0 +1,262 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+// Package tempfile manages the temporary files that the tools spill data to,
+// like the runs of an external sort or the prefetch buffer of an archive. The
+// files of a run live in one session directory under --tempDir and count
+// against --maxTempBytes. The session directory is removed by Cleanup, and
+// the ones left behind by tools that crashed or were killed are removed the
+// next time a Manager of the same host creates its own in the same directory.
+// The session directories of other hosts, which may share the directory over
+// a network file system, are left alone since their processes can't be seen.
+package tempfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/text"
+	"github.com/mongodb/mongo-tools/common/util"
+)
+
+// sessionPrefix starts the names of the session directories, which go on
+// with the process ID and the host name of the tool that owns them.
+const sessionPrefix = "mongo-tools-"
+
+// ErrQuotaExceeded is returned, wrapped, by the writes that would make the
+// temporary files of a Manager use more than its limit.
+var ErrQuotaExceeded = errors.New("temporary files quota exceeded")
+
+// Manager creates the temporary files of a tool run and keeps track of the
+// bytes they use. Its methods are safe for concurrent use.
+type Manager struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	session string
+	used    int64
+	files   map[*File]struct{}
+}
+
+// NewManager returns a Manager for temporary files in dir, or in the default
+// directory for temporary files if dir is empty, that may use up to maxBytes
+// on disk, without a limit if maxBytes is 0.
+func NewManager(dir string, maxBytes int64) *Manager {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return &Manager{
+		dir:      util.ToUniversalPath(dir),
+		maxBytes: maxBytes,
+		files:    map[*File]struct{}{},
+	}
+}
+
+// Dir returns the directory the session directory is created in.
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// Used returns the number of bytes the temporary files use.
+func (m *Manager) Used() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.used
+}
+
+// Create creates a temporary file whose name is made from pattern like
+// os.CreateTemp does.
+func (m *Manager) Create(pattern string) (*File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.session == "" {
+		if err := os.MkdirAll(m.dir, 0o700); err != nil {
+			return nil, fmt.Errorf("error creating temporary directory %v: %v", m.dir, err)
+		}
+		host := sessionHost()
+		removeAbandoned(m.dir, host)
+		session, err := os.MkdirTemp(m.dir, sessionPrefix+strconv.Itoa(os.Getpid())+"-"+host+"-")
+		if err != nil {
+			return nil, fmt.Errorf("error creating temporary directory in %v: %v", m.dir, err)
+		}
+		m.session = session
+	}
+	file, err := os.CreateTemp(m.session, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary file: %v", err)
+	}
+	f := &File{File: file, manager: m}
+	m.files[f] = struct{}{}
+	return f, nil
+}
+
+// reserve accounts for n more bytes used by the temporary files.
+func (m *Manager) reserve(n int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.maxBytes > 0 && m.used+n > m.maxBytes {
+		return fmt.Errorf(
+			"%w: the temporary files in %v would use more than %v",
+			ErrQuotaExceeded,
+			m.dir,
+			text.FormatByteAmount(m.maxBytes),
+		)
+	}
+	m.used += n
+	return nil
+}
+
+func (m *Manager) release(f *File) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[f]; ok {
+		delete(m.files, f)
+		m.used -= f.size
+	}
+}
+
+// Cleanup closes and removes the temporary files that are left, and the
+// session directory. The Manager may be used again afterwards.
+func (m *Manager) Cleanup() error {
+	m.mu.Lock()
+	files := make([]*File, 0, len(m.files))
+	for f := range m.files {
+		files = append(files, f)
+	}
+	m.mu.Unlock()
+
+	for _, f := range files {
+		_ = f.Remove()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.session == "" {
+		return nil
+	}
+	err := os.RemoveAll(m.session)
+	m.session = ""
+	if err != nil {
+		return fmt.Errorf("error removing temporary files: %v", err)
+	}
+	return nil
+}
+
+// removeAbandoned removes the session directories in dir of the processes of
+// host that are not running anymore.
+func removeAbandoned(dir, host string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		pid, owner, ok := parseSession(entry.Name())
+		if !entry.IsDir() || !ok || owner != host || pid == os.Getpid() || processRunning(pid) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			log.Logvf(log.DebugLow, "error removing abandoned temporary files %v: %v", path, err)
+			continue
+		}
+		log.Logvf(log.DebugLow, "removed temporary files %v left by process %v", path, pid)
+	}
+}
+
+// sessionHost returns the host name in the names of the session directories
+// created by this process, without the characters that can't be part of a
+// file name.
+func sessionHost() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, host)
+}
+
+// parseSession returns the process ID and the host name in the name of a
+// session directory, which is followed by the random part of the name.
+func parseSession(name string) (int, string, bool) {
+	rest, ok := strings.CutPrefix(name, sessionPrefix)
+	if !ok {
+		return 0, "", false
+	}
+	pid, rest, _ := strings.Cut(rest, "-")
+	end := strings.LastIndex(rest, "-")
+	if end <= 0 {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(pid)
+	return n, rest[:end], err == nil && n > 0
+}
+
+// File is a temporary file created by a Manager. The writes that make it
+// grow fail if the Manager's limit would be exceeded.
+type File struct {
+	*os.File
+	manager *Manager
+
+	mu sync.Mutex
+	// size is the largest size the file has had.
+	size int64
+}
+
+// grow accounts for the file being written up to end.
+func (f *File) grow(end int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if end <= f.size {
+		return nil
+	}
+	if err := f.manager.reserve(end - f.size); err != nil {
+		return err
+	}
+	f.size = end
+	return nil
+}
+
+// Write writes p at the current offset of the file.
+func (f *File) Write(p []byte) (int, error) {
+	offset, err := f.File.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.grow(offset + int64(len(p))); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
+
+// WriteAt writes p at offset.
+func (f *File) WriteAt(p []byte, offset int64) (int, error) {
+	if err := f.grow(offset + int64(len(p))); err != nil {
+		return 0, err
+	}
+	return f.File.WriteAt(p, offset)
+}
+
+// Remove closes and removes the file, and releases the bytes it used.
+func (f *File) Remove() error {
+	f.manager.release(f)
+	_ = f.File.Close()
+	if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package tempfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	t.Run("counts the bytes of the files against the limit", func(t *testing.T) {
+		files := NewManager(t.TempDir(), 100)
+		defer files.Cleanup()
+
+		first, err := files.Create("first-")
+		require.NoError(t, err)
+		_, err = first.Write(make([]byte, 60))
+		require.NoError(t, err)
+		_, err = first.WriteAt(make([]byte, 10), 0)
+		require.NoError(t, err, "overwriting does not use more bytes")
+
+		second, err := files.Create("second-")
+		require.NoError(t, err)
+		_, err = second.WriteAt(make([]byte, 40), 0)
+		require.NoError(t, err)
+		assert.EqualValues(t, 100, files.Used())
+
+		_, err = second.WriteAt(make([]byte, 1), 40)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+		require.NoError(t, first.Remove())
+		assert.EqualValues(t, 40, files.Used())
+		_, err = second.WriteAt(make([]byte, 1), 40)
+		assert.NoError(t, err)
+	})
+
+	t.Run("removes its files on cleanup", func(t *testing.T) {
+		dir := t.TempDir()
+		files := NewManager(dir, 0)
+		file, err := files.Create("run-*.bson")
+		require.NoError(t, err)
+		_, err = file.Write([]byte("data"))
+		require.NoError(t, err)
+		assert.Equal(t, "run-", filepath.Base(file.Name())[:4])
+
+		require.NoError(t, files.Cleanup())
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+		assert.Zero(t, files.Used())
+	})
+
+	t.Run("removes the files of processes that are gone", func(t *testing.T) {
+		dir := t.TempDir()
+		host := sessionHost()
+		// no process has the largest process ID
+		abandoned := filepath.Join(dir, sessionPrefix+"2147483647-"+host+"-123")
+		require.NoError(t, os.Mkdir(abandoned, 0o700))
+		running := filepath.Join(dir, sessionPrefix+"1-"+host+"-123")
+		require.NoError(t, os.Mkdir(running, 0o700))
+		// the process may be running on the other host
+		remote := filepath.Join(dir, sessionPrefix+"2147483647-other-host.example.net-123")
+		require.NoError(t, os.Mkdir(remote, 0o700))
+		other := filepath.Join(dir, "mongo-tools-cache")
+		require.NoError(t, os.Mkdir(other, 0o700))
+
+		files := NewManager(dir, 0)
+		defer files.Cleanup()
+		_, err := files.Create("run-")
+		require.NoError(t, err)
+
+		assert.NoDirExists(t, abandoned)
+		assert.DirExists(t, running)
+		assert.DirExists(t, remote)
+		assert.DirExists(t, other)
+	})
+
+	t.Run("names its session directory after the process and the host", func(t *testing.T) {
+		files := NewManager(t.TempDir(), 0)
+		defer files.Cleanup()
+		file, err := files.Create("run-")
+		require.NoError(t, err)
+
+		pid, host, ok := parseSession(filepath.Base(filepath.Dir(file.Name())))
+		require.True(t, ok)
+		assert.Equal(t, os.Getpid(), pid)
+		assert.Equal(t, sessionHost(), host)
+
+		_, host, ok = parseSession(sessionPrefix + "12-my-host-345")
+		require.True(t, ok)
+		assert.Equal(t, "my-host", host)
+		_, _, ok = parseSession(sessionPrefix + "12-345")
+		assert.False(t, ok, "the session directories of older versions have no host")
+	})
+}
@@ -0,0 +1,197 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+//go:build !solaris
+// +build !solaris
+
+package tui
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Supported is whether the terminal UI is available on this platform.
+const Supported = true
+
+// Screen draws a View on the terminal and passes it the keys pressed.
+type Screen struct {
+	view *View
+
+	drawMu   sync.Mutex
+	quit     chan struct{}
+	quitOnce sync.Once
+	polling  chan struct{}
+}
+
+// NewScreen takes over the terminal to show view, until Close is called.
+func NewScreen(view *View) (*Screen, error) {
+	if err := termbox.Init(); err != nil {
+		return nil, err
+	}
+	s := &Screen{view: view, quit: make(chan struct{}), polling: make(chan struct{})}
+	go s.pollEvents()
+	s.draw()
+	return s, nil
+}
+
+// Show shows a new sample.
+func (s *Screen) Show(table Table) {
+	s.view.Update(table)
+	s.draw()
+}
+
+// Quit returns a channel that is closed when the user quits.
+func (s *Screen) Quit() <-chan struct{} {
+	return s.quit
+}
+
+// Close gives the terminal back.
+func (s *Screen) Close() {
+	termbox.Interrupt()
+	<-s.polling
+	termbox.Close()
+}
+
+func (s *Screen) pollEvents() {
+	defer close(s.polling)
+	for {
+		ev := termbox.PollEvent()
+		switch ev.Type {
+		case termbox.EventInterrupt, termbox.EventError:
+			return
+		case termbox.EventKey:
+			ch := ev.Ch
+			if ev.Key == termbox.KeySpace {
+				ch = ' '
+			}
+			if s.view.HandleKey(ch, translateKey(ev.Key)) {
+				s.quitOnce.Do(func() { close(s.quit) })
+			}
+		}
+		s.draw()
+	}
+}
+
+func translateKey(key termbox.Key) Key {
+	switch key {
+	case termbox.KeyArrowLeft:
+		return KeyLeft
+	case termbox.KeyArrowRight:
+		return KeyRight
+	case termbox.KeyArrowUp:
+		return KeyUp
+	case termbox.KeyArrowDown:
+		return KeyDown
+	case termbox.KeyEnter:
+		return KeyEnter
+	case termbox.KeyEsc:
+		return KeyEsc
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		return KeyBackspace
+	case termbox.KeyCtrlC:
+		return KeyCtrlC
+	}
+	return KeyNone
+}
+
+func writeString(x, y int, text string, fg, bg termbox.Attribute) {
+	for i, ch := range []rune(text) {
+		termbox.SetCell(x+i, y, ch, fg, bg)
+	}
+}
+
+func (s *Screen) draw() {
+	s.drawMu.Lock()
+	defer s.drawMu.Unlock()
+	frame := s.view.Frame()
+	_, height := termbox.Size()
+	//nolint:errcheck
+	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+
+	headers := make([]string, len(frame.Columns))
+	widths := make([]int, len(frame.Columns))
+	for i, column := range frame.Columns {
+		headers[i] = column
+		if i == frame.SortColumn {
+			if frame.SortDesc {
+				headers[i] += "↓"
+			} else {
+				headers[i] += "↑"
+			}
+		}
+		widths[i] = len([]rune(headers[i]))
+	}
+	for _, row := range frame.Rows {
+		for i := 0; i < len(row.Cells) && i < len(widths); i++ {
+			if w := len([]rune(row.Cells[i])); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	x := 0
+	for i, header := range headers {
+		fg := termbox.ColorWhite | termbox.AttrBold | termbox.AttrUnderline
+		if i == frame.Col {
+			fg |= termbox.AttrReverse
+		}
+		writeString(x+widths[i]-len([]rune(header)), 0, header, fg, termbox.ColorDefault)
+		x += widths[i] + 1
+	}
+
+	// leave room for the header, the errors and the status line
+	maxRows := height - 2 - len(frame.Errors)
+	if frame.Prompt != "" {
+		maxRows--
+	}
+	first := 0
+	if frame.Row >= maxRows && maxRows > 0 {
+		first = frame.Row - maxRows + 1
+	}
+	y := 1
+	for j := first; j < len(frame.Rows) && y <= maxRows; j++ {
+		row := frame.Rows[j]
+		fg, bg := termbox.ColorWhite, termbox.ColorDefault
+		if row.Alert {
+			bg = termbox.ColorRed
+		}
+		if j == frame.Row {
+			fg, bg = termbox.ColorBlack, termbox.ColorWhite
+		}
+		x = 0
+		for i := 0; i < len(row.Cells) && i < len(widths); i++ {
+			text := row.Cells[i]
+			padding := widths[i] - len([]rune(text))
+			writeString(x, y, strings.Repeat(" ", padding)+text, fg, bg)
+			x += widths[i] + 1
+		}
+		y++
+	}
+	for _, err := range frame.Errors {
+		writeString(0, y, err, termbox.ColorRed, termbox.ColorDefault)
+		y++
+	}
+
+	y = height - 1
+	if frame.Prompt != "" {
+		writeString(0, y-1, frame.Prompt, termbox.ColorWhite|termbox.AttrBold, termbox.ColorDefault)
+		termbox.SetCursor(len([]rune(frame.Prompt)), y-1)
+	} else {
+		termbox.HideCursor()
+	}
+	writeString(0, y, frame.Status, termbox.ColorWhite, termbox.ColorDefault)
+	if frame.Help {
+		lines := strings.Split(helpMessage, "\n")
+		for i, line := range lines {
+			writeString(2, y-len(lines)-1+i, line, termbox.ColorBlack, termbox.ColorWhite)
+		}
+	}
+	//nolint:errcheck
+	termbox.Flush()
+}
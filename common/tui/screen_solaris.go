@@ -0,0 +1,34 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+//go:build solaris
+// +build solaris
+
+package tui
+
+import "fmt"
+
+// Supported is whether the terminal UI is available on this platform.
+const Supported = false
+
+// Screen is not available on this platform.
+type Screen struct{}
+
+// NewScreen always fails on this platform.
+func NewScreen(_ *View) (*Screen, error) {
+	return nil, fmt.Errorf("the terminal UI is not supported on this platform")
+}
+
+// Show does nothing on this platform.
+func (s *Screen) Show(_ Table) {}
+
+// Quit returns a channel that is never closed on this platform.
+func (s *Screen) Quit() <-chan struct{} {
+	return nil
+}
+
+// Close does nothing on this platform.
+func (s *Screen) Close() {}
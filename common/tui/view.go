@@ -0,0 +1,381 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+// Package tui implements the interactive terminal UI that mongostat and
+// mongotop show with --watch: a live table whose rows can be sorted by any
+// column, paused, narrowed down to the rows of one host, and filtered.
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Key is a key without a character.
+type Key int
+
+const (
+	KeyNone Key = iota
+	KeyLeft
+	KeyRight
+	KeyUp
+	KeyDown
+	KeyEnter
+	KeyEsc
+	KeyBackspace
+	KeyCtrlC
+)
+
+// historySize is how many rows of each host a View keeps for a Table with
+// History set.
+const historySize = 500
+
+// Row is a row of a Table.
+type Row struct {
+	// Host is the host the row comes from, and can be drilled down into.
+	Host  string
+	Cells []string
+	// Alert highlights the row.
+	Alert bool
+}
+
+// Table is a sample shown by a View.
+type Table struct {
+	Columns []string
+	Rows    []Row
+	// Errors are shown under the rows, e.g. for the hosts that could not be
+	// polled.
+	Errors []string
+	// FilterColumn is the column that the filter is matched against, e.g.
+	// the namespace.
+	FilterColumn int
+	// History makes drilling down into a host show the rows of its previous
+	// samples, for tables with one row per host, instead of the rows of the
+	// current sample from that host.
+	History bool
+}
+
+// View holds the latest Table and the state of the UI that shows it. It is
+// safe for concurrent use.
+type View struct {
+	mu sync.Mutex
+
+	table   Table
+	pending *Table
+	history map[string][]Row
+
+	sortColumn string
+	sortDesc   bool
+	row, col   int
+	paused     bool
+	host       string
+	filter     string
+	editing    bool
+	input      string
+	help       bool
+}
+
+// NewView returns a View that sorts the rows by the column named sortColumn,
+// in descending order if sortDesc is set. If sortColumn is empty the rows are
+// shown in the order of the Table.
+func NewView(sortColumn string, sortDesc bool) *View {
+	return &View{sortColumn: sortColumn, sortDesc: sortDesc, history: map[string][]Row{}}
+}
+
+// Update shows a new sample. A paused View keeps showing the sample it was
+// paused on, and shows the latest one once it is resumed.
+func (v *View) Update(table Table) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if table.History {
+		for _, row := range table.Rows {
+			rows := append(v.history[row.Host], row)
+			if len(rows) > historySize {
+				rows = rows[len(rows)-historySize:]
+			}
+			v.history[row.Host] = rows
+		}
+	}
+	if v.paused {
+		v.pending = &table
+		return
+	}
+	v.table = table
+}
+
+// HandleKey applies a key press, given as a character or a Key. It returns
+// true if the user quit.
+func (v *View) HandleKey(ch rune, key Key) (quit bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key == KeyCtrlC {
+		return true
+	}
+	if v.editing {
+		v.editFilter(ch, key)
+		return false
+	}
+
+	switch {
+	case ch == 'q':
+		return true
+	case key == KeyLeft || ch == 'h':
+		if v.col > 0 {
+			v.col--
+		}
+	case key == KeyRight || ch == 'l':
+		if v.col+1 < len(v.table.Columns) {
+			v.col++
+		}
+	case key == KeyUp || ch == 'k':
+		if v.row > 0 {
+			v.row--
+		}
+	case key == KeyDown || ch == 'j':
+		v.row++
+	case ch == 's':
+		if v.col < len(v.table.Columns) {
+			column := v.table.Columns[v.col]
+			if v.sortColumn == column {
+				v.sortDesc = !v.sortDesc
+			} else {
+				v.sortColumn, v.sortDesc = column, true
+			}
+		}
+	case ch == 'p' || ch == ' ':
+		v.paused = !v.paused
+		if !v.paused && v.pending != nil {
+			v.table = *v.pending
+			v.pending = nil
+		}
+	case key == KeyEnter:
+		if rows := v.visibleRows(); v.host == "" && v.row < len(rows) {
+			v.host = rows[v.row].Host
+			v.row = 0
+		}
+	case key == KeyEsc || key == KeyBackspace:
+		v.host = ""
+		v.row = 0
+	case ch == '/':
+		v.editing = true
+		v.input = v.filter
+	case ch == '?':
+		v.help = !v.help
+	}
+	v.clampRow()
+	return false
+}
+
+// editFilter applies a key press to the filter being typed.
+func (v *View) editFilter(ch rune, key Key) {
+	switch {
+	case key == KeyEnter:
+		v.filter = v.input
+		v.editing = false
+		v.row = 0
+	case key == KeyEsc:
+		v.editing = false
+	case key == KeyBackspace:
+		if runes := []rune(v.input); len(runes) > 0 {
+			v.input = string(runes[:len(runes)-1])
+		}
+	case ch != 0:
+		v.input += string(ch)
+	}
+}
+
+func (v *View) clampRow() {
+	if n := len(v.visibleRows()); v.row >= n {
+		v.row = n - 1
+	}
+	if v.row < 0 {
+		v.row = 0
+	}
+}
+
+// visibleRows returns the rows of the host drilled down into, or of all
+// hosts, that match the filter, sorted.
+func (v *View) visibleRows() []Row {
+	source := v.table.Rows
+	if v.host != "" && v.table.History {
+		source = v.history[v.host]
+	}
+	var rows []Row
+	for _, row := range source {
+		if v.host != "" && row.Host != v.host {
+			continue
+		}
+		if v.filter != "" {
+			col := v.table.FilterColumn
+			if col >= len(row.Cells) || !strings.Contains(row.Cells[col], v.filter) {
+				continue
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	sortIndex := v.sortIndex()
+	if sortIndex < 0 {
+		if v.host != "" && v.table.History {
+			// newest first
+			for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+				rows[i], rows[j] = rows[j], rows[i]
+			}
+		}
+		return rows
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := cell(rows[i], sortIndex), cell(rows[j], sortIndex)
+		if v.sortDesc {
+			return compareCells(b, a) < 0
+		}
+		return compareCells(a, b) < 0
+	})
+	return rows
+}
+
+func (v *View) sortIndex() int {
+	if v.sortColumn == "" {
+		return -1
+	}
+	for i, column := range v.table.Columns {
+		if column == v.sortColumn {
+			return i
+		}
+	}
+	return -1
+}
+
+func cell(row Row, i int) string {
+	if i < len(row.Cells) {
+		return row.Cells[i]
+	}
+	return ""
+}
+
+// compareCells orders cells by the numbers they show, e.g. 12ms, 1.5k or
+// *0, before the cells without a number, which are ordered as text.
+func compareCells(a, b string) int {
+	x, xOK := cellNumber(a)
+	y, yOK := cellNumber(b)
+	switch {
+	case xOK && yOK:
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case xOK:
+		return -1
+	case yOK:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+var unitMultipliers = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"ms", 1},
+	{"%", 1},
+	{"b", 1},
+	{"k", 1e3},
+	{"m", 1e6},
+	{"g", 1e9},
+	{"t", 1e12},
+}
+
+// cellNumber parses the number shown in a cell. Of the values like 0|1 that
+// mongostat shows, only the first is used, and the * of the values of a
+// replica set member that it replicated is ignored.
+func cellNumber(text string) (float64, bool) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "*")
+	if i := strings.IndexByte(text, '|'); i >= 0 {
+		text = text[:i]
+	}
+	multiplier := 1.0
+	lower := strings.ToLower(text)
+	for _, unit := range unitMultipliers {
+		if strings.HasSuffix(lower, unit.suffix) {
+			text = text[:len(text)-len(unit.suffix)]
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n * multiplier, true
+}
+
+// Frame is what a View shows at one moment.
+type Frame struct {
+	Columns []string
+	Rows    []Row
+	Errors  []string
+
+	// Row and Col are the selected row and column.
+	Row, Col int
+	// SortColumn is the index of the sorted column, or -1.
+	SortColumn int
+	SortDesc   bool
+
+	// Status describes the state of the View, e.g. that it is paused.
+	Status string
+	// Prompt is the filter being typed, if one is.
+	Prompt string
+	Help   bool
+}
+
+// Frame returns what the View shows.
+func (v *View) Frame() Frame {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.clampRow()
+	frame := Frame{
+		Columns:    v.table.Columns,
+		Rows:       v.visibleRows(),
+		Errors:     v.table.Errors,
+		Row:        v.row,
+		Col:        v.col,
+		SortColumn: v.sortIndex(),
+		SortDesc:   v.sortDesc,
+		Help:       v.help,
+	}
+
+	var status []string
+	if v.paused {
+		status = append(status, "PAUSED")
+	}
+	if v.host != "" {
+		status = append(status, "host: "+v.host)
+	}
+	if v.filter != "" {
+		status = append(status, fmt.Sprintf("filter: %q", v.filter))
+	}
+	status = append(status, helpPrompt)
+	frame.Status = strings.Join(status, "  ")
+	if v.editing {
+		frame.Prompt = "/" + v.input
+	}
+	return frame
+}
+
+const (
+	helpPrompt  = `Press '?' to toggle help`
+	helpMessage = `Exit: 'q' or <Ctrl-C>
+Navigation: arrow keys or 'h', 'j', 'k' and 'l'
+Sort: 's' to sort by the selected column, again to reverse the order
+Pause: 'p' or <Space> to pause and resume
+Drill down: <Enter> to show only the host of the selected row, <Esc> to show all hosts
+Filter: '/' to type a filter and <Enter> to apply it; an empty filter shows all rows`
+)
@@ -0,0 +1,150 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package tui
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func cellsOf(frame Frame, col int) []string {
+	var cells []string
+	for _, row := range frame.Rows {
+		cells = append(cells, row.Cells[col])
+	}
+	return cells
+}
+
+func typeKeys(v *View, text string) {
+	for _, ch := range text {
+		v.HandleKey(ch, KeyNone)
+	}
+}
+
+func TestView(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	table := Table{
+		Columns:      []string{"host", "ns", "total"},
+		FilterColumn: 1,
+		Rows: []Row{
+			{Host: "a", Cells: []string{"a", "test.x", "9ms"}},
+			{Host: "a", Cells: []string{"a", "test.y", "12ms"}},
+			{Host: "b", Cells: []string{"b", "prod.x", "100ms"}},
+			{Host: "b", Cells: []string{"b", "prod.y", "0ms"}},
+		},
+	}
+
+	Convey("With a view sorted by total", t, func() {
+		v := NewView("total", true)
+		v.Update(table)
+
+		Convey("the rows should be sorted by the numbers in the column", func() {
+			frame := v.Frame()
+			So(cellsOf(frame, 2), ShouldResemble, []string{"100ms", "12ms", "9ms", "0ms"})
+			So(frame.SortColumn, ShouldEqual, 2)
+		})
+
+		Convey("'s' should sort by the selected column and then reverse it", func() {
+			v.HandleKey(0, KeyRight)
+			v.HandleKey('s', KeyNone)
+			So(cellsOf(v.Frame(), 1), ShouldResemble, []string{"test.y", "test.x", "prod.y", "prod.x"})
+			v.HandleKey('s', KeyNone)
+			So(cellsOf(v.Frame(), 1), ShouldResemble, []string{"prod.x", "prod.y", "test.x", "test.y"})
+		})
+
+		Convey("a paused view should keep showing its sample", func() {
+			v.HandleKey(' ', KeyNone)
+			v.Update(Table{Columns: table.Columns})
+			frame := v.Frame()
+			So(frame.Rows, ShouldHaveLength, 4)
+			So(frame.Status, ShouldContainSubstring, "PAUSED")
+			v.HandleKey('p', KeyNone)
+			So(v.Frame().Rows, ShouldBeEmpty)
+		})
+
+		Convey("<Enter> should drill down into the host of the selected row", func() {
+			v.HandleKey(0, KeyDown)
+			v.HandleKey(0, KeyDown)
+			v.HandleKey(0, KeyEnter)
+			frame := v.Frame()
+			So(cellsOf(frame, 1), ShouldResemble, []string{"test.y", "test.x"})
+			So(frame.Status, ShouldContainSubstring, "host: a")
+			v.HandleKey(0, KeyEsc)
+			So(v.Frame().Rows, ShouldHaveLength, 4)
+		})
+
+		Convey("the filter should match the filter column", func() {
+			typeKeys(v, "/prodq")
+			So(v.Frame().Prompt, ShouldEqual, "/prodq")
+			v.HandleKey(0, KeyBackspace)
+			v.HandleKey(0, KeyEnter)
+			frame := v.Frame()
+			So(cellsOf(frame, 1), ShouldResemble, []string{"prod.x", "prod.y"})
+			So(frame.Prompt, ShouldEqual, "")
+			So(frame.Status, ShouldContainSubstring, `filter: "prod"`)
+
+			typeKeys(v, "/")
+			v.HandleKey(0, KeyBackspace)
+			v.HandleKey(0, KeyBackspace)
+			v.HandleKey(0, KeyEsc)
+			So(v.Frame().Rows, ShouldHaveLength, 2)
+		})
+
+		Convey("'q' and <Ctrl-C> should quit, except while typing a filter", func() {
+			So(v.HandleKey('q', KeyNone), ShouldBeTrue)
+			So(v.HandleKey(0, KeyCtrlC), ShouldBeTrue)
+			v.HandleKey('/', KeyNone)
+			So(v.HandleKey('q', KeyNone), ShouldBeFalse)
+			So(v.HandleKey(0, KeyCtrlC), ShouldBeTrue)
+		})
+
+		Convey("the selected row should stay within the rows", func() {
+			for i := 0; i < 10; i++ {
+				v.HandleKey('j', KeyNone)
+			}
+			So(v.Frame().Row, ShouldEqual, 3)
+			v.Update(Table{Columns: table.Columns, Rows: table.Rows[:1]})
+			So(v.Frame().Row, ShouldEqual, 0)
+		})
+	})
+
+	Convey("With a table that keeps the history of each host", t, func() {
+		v := NewView("", false)
+		for _, sample := range []string{"1", "2", "3"} {
+			v.Update(Table{
+				Columns: []string{"host", "insert"},
+				History: true,
+				Rows: []Row{
+					{Host: "a", Cells: []string{"a", sample}},
+					{Host: "b", Cells: []string{"b", "0"}},
+				},
+			})
+		}
+		So(cellsOf(v.Frame(), 0), ShouldResemble, []string{"a", "b"})
+
+		v.HandleKey(0, KeyEnter)
+		So(cellsOf(v.Frame(), 1), ShouldResemble, []string{"3", "2", "1"})
+	})
+}
+
+func TestCompareCells(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("Cells should be ordered by the numbers they show", t, func() {
+		So(compareCells("9ms", "12ms"), ShouldBeLessThan, 0)
+		So(compareCells("1.5k", "900"), ShouldBeGreaterThan, 0)
+		So(compareCells("343M", "1.2G"), ShouldBeLessThan, 0)
+		So(compareCells("*3", "2"), ShouldBeGreaterThan, 0)
+		So(compareCells("0|5", "1|0"), ShouldBeLessThan, 0)
+		So(compareCells("12%", "12%"), ShouldEqual, 0)
+		So(compareCells("7", "PRI"), ShouldBeLessThan, 0)
+		So(compareCells("PRI", "SEC"), ShouldBeLessThan, 0)
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	minRateLimitBackoff = 500 * time.Millisecond
+	maxRateLimitBackoff = 30 * time.Second
+
+	// initialRateLimitBatchSize is the batch size used after the first rate limit error for a
+	// collection that was being read with the server's default batch size.
+	initialRateLimitBatchSize = 1000
+	minRateLimitBatchSize     = 16
+)
+
+// rateLimitBackoff tracks the retry state for a single collection that is being dumped from a
+// server which throttles clients that exceed their request quota.
+type rateLimitBackoff struct {
+	maxRetries int
+	retries    int
+	delay      time.Duration
+}
+
+func newRateLimitBackoff(maxRetries int) *rateLimitBackoff {
+	return &rateLimitBackoff{maxRetries: maxRetries}
+}
+
+// next reduces the cursor batch size of the given query and returns how long to wait before
+// retrying it. It returns false once the retries are exhausted.
+func (b *rateLimitBackoff) next(query *db.DeferredQuery) (time.Duration, bool) {
+	if b.retries >= b.maxRetries {
+		return 0, false
+	}
+	b.retries++
+
+	switch {
+	case query.BatchSize == 0:
+		query.BatchSize = initialRateLimitBatchSize
+	case query.BatchSize/2 >= minRateLimitBatchSize:
+		query.BatchSize /= 2
+	default:
+		query.BatchSize = minRateLimitBatchSize
+	}
+
+	if b.delay == 0 {
+		b.delay = minRateLimitBackoff
+	} else {
+		b.delay *= 2
+	}
+	if b.delay > maxRateLimitBackoff {
+		b.delay = maxRateLimitBackoff
+	}
+
+	// add up to 25% jitter so that parallel collection dumps don't all retry in lockstep
+	jitter := time.Duration(rand.Int63n(int64(b.delay)/4 + 1))
+	return b.delay + jitter, true
+}
+
+// idTracker records the _id of the last document written for a collection that is read in
+// _id index order, so that a dump interrupted by rate limiting can resume where it stopped.
+type idTracker struct {
+	lastID bson.RawValue
+
+	// skipLastID is set when a resumed query starts at (and therefore includes) lastID.
+	skipLastID bool
+}
+
+// resume updates the query to start from the last document written.
+func (t *idTracker) resume(query *db.DeferredQuery) {
+	query.Min = bson.D{{Key: "_id", Value: t.lastID}}
+	t.skipLastID = true
+}
+
+// hintsIDIndex returns whether a query hint forces a walk of the _id index.
+func hintsIDIndex(hint interface{}) bool {
+	d, ok := hint.(bson.D)
+	return ok && len(d) == 1 && d[0].Key == "_id"
+}
@@ -0,0 +1,57 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRateLimitBackoff(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	query := &db.DeferredQuery{}
+	backoff := newRateLimitBackoff(8)
+
+	var lastDelay int64
+	expectedBatchSizes := []int32{1000, 500, 250, 125, 62, 31, 16, 16}
+	for _, expected := range expectedBatchSizes {
+		delay, ok := backoff.next(query)
+		require.True(t, ok)
+		assert.Equal(t, expected, query.BatchSize)
+		assert.GreaterOrEqual(t, delay, minRateLimitBackoff)
+		assert.LessOrEqual(t, delay, maxRateLimitBackoff+maxRateLimitBackoff/4)
+		assert.GreaterOrEqual(t, int64(delay), lastDelay*3/4, "delay should not shrink")
+		lastDelay = int64(delay)
+	}
+
+	_, ok := backoff.next(query)
+	assert.False(t, ok, "retries should be exhausted")
+}
+
+func TestIDTrackerResume(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	doc, err := bson.Marshal(bson.D{{Key: "_id", Value: 42}})
+	require.NoError(t, err)
+
+	tracker := &idTracker{lastID: bson.Raw(doc).Lookup("_id")}
+	query := &db.DeferredQuery{Hint: bson.D{{Key: "_id", Value: 1}}}
+	require.True(t, hintsIDIndex(query.Hint))
+
+	tracker.resume(query)
+	assert.True(t, tracker.skipLastID)
+	assert.Equal(t, bson.D{{Key: "_id", Value: tracker.lastID}}, query.Min)
+
+	assert.False(t, hintsIDIndex(nil))
+	assert.False(t, hintsIDIndex(bson.D{{Key: "a", Value: 1}}))
+}
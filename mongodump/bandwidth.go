@@ -0,0 +1,211 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/text"
+)
+
+const (
+	// bandwidthSampleInterval is how often the bandwidth scheduler measures
+	// how fast the dump reads documents.
+	bandwidthSampleInterval = 10 * time.Second
+	// bandwidthGain is how much faster the dump must get for another large
+	// collection stream to be kept.
+	bandwidthGain = 1.1
+	// bandwidthCooldownSamples is how many samples the scheduler waits after
+	// an extra stream did not help before trying one again.
+	bandwidthCooldownSamples = 6
+)
+
+// bandwidthScheduler replaces the prioritizer of the intent manager for
+// --bandwidthScheduling. It hands out the small collections first, smallest
+// first, so that most collections are done early instead of waiting behind
+// the large ones. It only lets a number of large collections, of at least
+// --largeCollectionBytes, be dumped at once, starting with one, and adapts
+// that number to the measured bandwidth: it tries one more stream, keeps it
+// if the dump gets at least 10% faster, and otherwise goes back, since over
+// a saturated link more streams only make each of them slower.
+type bandwidthScheduler struct {
+	bytes    *atomic.Int64
+	maxLarge int
+
+	mu           sync.Mutex
+	cond         *sync.Cond
+	small        []*intents.Intent
+	large        []*intents.Intent
+	runningLarge map[*intents.Intent]bool
+	limit        int
+
+	lastBytes  int64
+	lastSample time.Time
+	lastRate   float64
+	probing    bool
+	cooldown   int
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// newBandwidthScheduler takes the intents of next, and splits them into the
+// small and large collections. bytes is the number of bytes dumped so far,
+// and maxLarge the most large collections that can be dumped at once.
+func newBandwidthScheduler(
+	next intents.IntentPrioritizer,
+	largeBytes int64,
+	maxLarge int,
+	bytes *atomic.Int64,
+) *bandwidthScheduler {
+	bs := &bandwidthScheduler{
+		bytes:        bytes,
+		maxLarge:     maxLarge,
+		runningLarge: map[*intents.Intent]bool{},
+		limit:        1,
+	}
+	bs.cond = sync.NewCond(&bs.mu)
+	for intent := next.Get(); intent != nil; intent = next.Get() {
+		if intent.Size >= largeBytes && !intent.IsView() {
+			bs.large = append(bs.large, intent)
+		} else {
+			bs.small = append(bs.small, intent)
+		}
+	}
+	sort.SliceStable(bs.small, func(i, j int) bool { return bs.small[i].Size < bs.small[j].Size })
+	sort.SliceStable(bs.large, func(i, j int) bool { return bs.large[i].Size > bs.large[j].Size })
+	log.Logvf(
+		log.Info,
+		"bandwidth scheduling: %v small and %v large collections",
+		len(bs.small),
+		len(bs.large),
+	)
+	return bs
+}
+
+// Get returns the smallest collection left, and then the largest of the
+// large collections once fewer of them are being dumped than the current
+// limit. While the limit is reached, Get blocks until it changes or a large
+// collection is finished.
+func (bs *bandwidthScheduler) Get() *intents.Intent {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	for {
+		if len(bs.small) > 0 {
+			intent := bs.small[0]
+			bs.small = bs.small[1:]
+			return intent
+		}
+		if len(bs.large) == 0 {
+			return nil
+		}
+		if len(bs.runningLarge) < bs.limit {
+			intent := bs.large[0]
+			bs.large = bs.large[1:]
+			bs.runningLarge[intent] = true
+			return intent
+		}
+		bs.cond.Wait()
+	}
+}
+
+// Finish frees the stream of a large collection.
+func (bs *bandwidthScheduler) Finish(intent *intents.Intent) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	delete(bs.runningLarge, intent)
+	bs.cond.Broadcast()
+}
+
+// start measures the bandwidth every bandwidthSampleInterval until stop.
+func (bs *bandwidthScheduler) start() {
+	bs.quit = make(chan struct{})
+	bs.done = make(chan struct{})
+	bs.lastSample = time.Now()
+	bs.lastBytes = bs.bytes.Load()
+	go func() {
+		defer close(bs.done)
+		ticker := time.NewTicker(bandwidthSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-bs.quit:
+				return
+			case now := <-ticker.C:
+				total := bs.bytes.Load()
+				seconds := now.Sub(bs.lastSample).Seconds()
+				rate := float64(total-bs.lastBytes) / seconds
+				bs.lastBytes, bs.lastSample = total, now
+				bs.adjust(rate)
+			}
+		}
+	}()
+}
+
+// stop stops measuring the bandwidth.
+func (bs *bandwidthScheduler) stop() {
+	if bs.quit == nil {
+		return
+	}
+	close(bs.quit)
+	<-bs.done
+}
+
+// adjust changes the number of large collection streams after a bandwidth
+// sample of rate bytes per second.
+func (bs *bandwidthScheduler) adjust(rate float64) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if len(bs.runningLarge) == 0 {
+		// only the small collections are being dumped, which doesn't say
+		// anything about the streams of the large ones
+		bs.lastRate, bs.probing = rate, false
+		return
+	}
+
+	switch {
+	case bs.probing && rate >= bs.lastRate*bandwidthGain:
+		log.Logvf(
+			log.Info,
+			"bandwidth scheduling: %v/s with %v large collection streams, keeping them",
+			text.FormatByteAmount(int64(rate)),
+			bs.limit,
+		)
+		bs.probing = false
+	case bs.probing:
+		bs.limit--
+		log.Logvf(
+			log.Info,
+			"bandwidth scheduling: %v/s is not faster, going back to %v large collection streams",
+			text.FormatByteAmount(int64(rate)),
+			bs.limit,
+		)
+		bs.probing = false
+		bs.cooldown = bandwidthCooldownSamples
+		// the previous rate is kept, as the extra stream may still be running
+		return
+	case bs.cooldown > 0:
+		bs.cooldown--
+	case bs.limit < bs.maxLarge && len(bs.large) > 0 && len(bs.runningLarge) >= bs.limit:
+		bs.limit++
+		bs.probing = true
+		log.Logvf(
+			log.Info,
+			"bandwidth scheduling: %v/s with %v large collection streams, trying %v",
+			text.FormatByteAmount(int64(rate)),
+			bs.limit-1,
+			bs.limit,
+		)
+		bs.cond.Broadcast()
+	}
+	bs.lastRate = rate
+}
@@ -0,0 +1,88 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBandwidthScheduler(maxLarge int) *bandwidthScheduler {
+	manager := intents.NewIntentManager()
+	for i, size := range []int64{5000, 30, 10, 9000, 20, 7000} {
+		manager.Put(&intents.Intent{DB: "db", C: fmt.Sprintf("c%d", i), Size: size})
+	}
+	manager.Finalize(intents.LongestTaskFirst)
+	return newBandwidthScheduler(manager.Prioritizer(), 1000, maxLarge, &atomic.Int64{})
+}
+
+func TestBandwidthSchedulerOrder(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	bs := newTestBandwidthScheduler(3)
+	var sizes []int64
+	for i := 0; i < 4; i++ {
+		sizes = append(sizes, bs.Get().Size)
+	}
+	assert.Equal(t, []int64{10, 20, 30, 9000}, sizes, "small collections should come first")
+
+	got := make(chan *intents.Intent)
+	go func() { got <- bs.Get() }()
+	select {
+	case intent := <-got:
+		t.Fatalf("got %v while the only large collection stream is in use", intent.Namespace())
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	bs.adjust(100)
+	select {
+	case intent := <-got:
+		assert.EqualValues(t, 7000, intent.Size)
+	case <-time.After(time.Second):
+		t.Fatal("a second stream should be tried")
+	}
+}
+
+func TestBandwidthSchedulerAdjust(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	bs := newTestBandwidthScheduler(3)
+	for i := 0; i < 4; i++ {
+		require.NotNil(t, bs.Get())
+	}
+
+	bs.adjust(100)
+	assert.Equal(t, 2, bs.limit)
+	require.NotNil(t, bs.Get())
+
+	// the second stream made the dump faster, so it is kept
+	bs.adjust(150)
+	assert.Equal(t, 2, bs.limit)
+	assert.False(t, bs.probing)
+
+	// a large collection is still waiting, so a third stream is tried
+	bs.adjust(150)
+	assert.Equal(t, 3, bs.limit)
+	require.NotNil(t, bs.Get())
+
+	// the third stream did not help, so the scheduler goes back to two and
+	// waits before trying again
+	bs.adjust(155)
+	assert.Equal(t, 2, bs.limit)
+	for i := 0; i < bandwidthCooldownSamples; i++ {
+		bs.adjust(155)
+		assert.Equal(t, 2, bs.limit)
+	}
+	assert.Nil(t, bs.Get())
+}
@@ -0,0 +1,280 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CollectionStatsManifest is the content of stats.json, written with
+// --collectionStats, which records how big each dumped collection was and how
+// it was spread over the shards of the cluster, so that the target of a
+// restore can be sized from the dump alone.
+type CollectionStatsManifest struct {
+	Collections []CollectionStats `json:"collections"`
+}
+
+// CollectionStats holds the statistics of one collection, summed over its
+// shards. Sizes are in bytes; Size is that of the uncompressed documents.
+type CollectionStats struct {
+	Namespace      string           `json:"namespace"`
+	Count          int64            `json:"count"`
+	Size           int64            `json:"size"`
+	AvgObjSize     int64            `json:"avgObjSize"`
+	StorageSize    int64            `json:"storageSize"`
+	TotalIndexSize int64            `json:"totalIndexSize"`
+	IndexSizes     map[string]int64 `json:"indexSizes,omitempty"`
+
+	Sharded bool `json:"sharded"`
+	// ShardKey is the shard key of a sharded collection, in relaxed
+	// extended JSON.
+	ShardKey json.RawMessage `json:"shardKey,omitempty"`
+	Unique   bool            `json:"unique,omitempty"`
+	Chunks   int64           `json:"chunks,omitempty"`
+	// Shards holds the share of each shard, on a mongos.
+	Shards []ShardStats `json:"shards,omitempty"`
+
+	// Error is why the statistics could not be read, if they could not.
+	Error string `json:"error,omitempty"`
+}
+
+// ShardStats holds the share of one shard of a collection.
+type ShardStats struct {
+	Shard          string `json:"shard"`
+	Chunks         int64  `json:"chunks"`
+	Count          int64  `json:"count"`
+	Size           int64  `json:"size"`
+	StorageSize    int64  `json:"storageSize"`
+	TotalIndexSize int64  `json:"totalIndexSize"`
+}
+
+// collStatsResult is a document of a $collStats stage. On a mongos there is
+// one for each shard of the collection.
+type collStatsResult struct {
+	Shard        string `bson:"shard"`
+	StorageStats bson.M `bson:"storageStats"`
+}
+
+// shardingMetadata is the shard key of a sharded collection, from its
+// config.collections document, and the number of its chunks on each shard.
+type shardingMetadata struct {
+	Key    bson.D
+	Unique bool
+	chunks map[string]int64
+}
+
+// DumpCollectionStats writes stats.json next to prelude.json with the
+// statistics of the dumped collections, in the order of their namespaces.
+// A collection whose statistics can't be read is recorded with the error,
+// and does not fail the dump.
+func (dump *MongoDump) DumpCollectionStats() error {
+	if !dump.OutputOptions.CollectionStats {
+		return nil
+	}
+	session, err := dump.SessionProvider.GetSession()
+	if err != nil {
+		return err
+	}
+
+	var collections []*intents.Intent
+	for _, intent := range dump.manager.NormalIntents() {
+		if intent.IsView() || intent.IsSpecialCollection() || intent.IsOplog() ||
+			intent.IsUsers() || intent.IsRoles() || intent.IsAuthVersion() {
+			continue
+		}
+		collections = append(collections, intent)
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		return collections[i].Namespace() < collections[j].Namespace()
+	})
+
+	manifest := CollectionStatsManifest{Collections: []CollectionStats{}}
+	for _, intent := range collections {
+		manifest.Collections = append(manifest.Collections, dump.collectionStats(session, intent))
+	}
+	return dump.writeTopLevelJSON("stats.json", manifest)
+}
+
+// collectionStats reads the statistics of the collection of intent, and on a
+// mongos its sharding metadata.
+func (dump *MongoDump) collectionStats(
+	client *mongo.Client,
+	intent *intents.Intent,
+) CollectionStats {
+	// the data of a timeseries collection is in, and sharded as, its buckets
+	collection := intent.C
+	if intent.IsTimeseries() {
+		collection = "system.buckets." + intent.C
+	}
+	stats := CollectionStats{Namespace: intent.Namespace()}
+	fail := func(err error) CollectionStats {
+		log.Logvf(log.Always, "error reading the statistics of %v: %v", stats.Namespace, err)
+		stats.Error = err.Error()
+		return stats
+	}
+
+	cursor, err := client.Database(intent.DB).Collection(collection).Aggregate(
+		context.TODO(),
+		mongo.Pipeline{{{"$collStats", bson.D{{"storageStats", bson.D{}}}}}},
+	)
+	if err != nil {
+		return fail(err)
+	}
+	var results []collStatsResult
+	if err := cursor.All(context.TODO(), &results); err != nil {
+		return fail(err)
+	}
+
+	var sharding *shardingMetadata
+	if dump.isMongos {
+		sharding, err = readShardingMetadata(client, intent.DB+"."+collection)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	filled, err := buildCollectionStats(stats, results, sharding)
+	if err != nil {
+		return fail(err)
+	}
+	return filled
+}
+
+// readShardingMetadata returns the sharding metadata of the namespace ns, or
+// nil if it isn't sharded.
+func readShardingMetadata(client *mongo.Client, ns string) (*shardingMetadata, error) {
+	config := client.Database("config")
+	var doc struct {
+		Key     bson.D      `bson:"key"`
+		Unique  bool        `bson:"unique"`
+		UUID    interface{} `bson:"uuid"`
+		Dropped bool        `bson:"dropped"`
+	}
+	err := config.Collection("collections").
+		FindOne(context.TODO(), bson.D{{"_id", ns}}).
+		Decode(&doc)
+	if err == mongo.ErrNoDocuments || (err == nil && doc.Dropped) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// chunks refer to their collection by its uuid since 5.0, and by its
+	// namespace before
+	match := bson.D{{"ns", ns}}
+	if doc.UUID != nil {
+		match = bson.D{{"$or", bson.A{bson.D{{"uuid", doc.UUID}}, bson.D{{"ns", ns}}}}}
+	}
+	cursor, err := config.Collection("chunks").Aggregate(context.TODO(), mongo.Pipeline{
+		{{"$match", match}},
+		{{"$group", bson.D{{"_id", "$shard"}, {"chunks", bson.D{{"$sum", 1}}}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		Shard  string `bson:"_id"`
+		Chunks int64  `bson:"chunks"`
+	}
+	if err := cursor.All(context.TODO(), &counts); err != nil {
+		return nil, err
+	}
+	sharding := shardingMetadata{Key: doc.Key, Unique: doc.Unique, chunks: map[string]int64{}}
+	for _, count := range counts {
+		sharding.chunks[count.Shard] = count.Chunks
+	}
+	return &sharding, nil
+}
+
+// buildCollectionStats fills stats from the $collStats results of a
+// collection and its sharding metadata, if it is sharded.
+func buildCollectionStats(
+	stats CollectionStats,
+	results []collStatsResult,
+	sharding *shardingMetadata,
+) (CollectionStats, error) {
+	shards := map[string]*ShardStats{}
+	for _, result := range results {
+		shard := ShardStats{Shard: result.Shard}
+		var err error
+		for field, out := range map[string]*int64{
+			"count":          &shard.Count,
+			"size":           &shard.Size,
+			"storageSize":    &shard.StorageSize,
+			"totalIndexSize": &shard.TotalIndexSize,
+		} {
+			if *out, err = statNumber(result.StorageStats[field]); err != nil {
+				return stats, err
+			}
+		}
+		stats.Count += shard.Count
+		stats.Size += shard.Size
+		stats.StorageSize += shard.StorageSize
+		stats.TotalIndexSize += shard.TotalIndexSize
+
+		if indexSizes, ok := result.StorageStats["indexSizes"].(bson.M); ok {
+			if stats.IndexSizes == nil {
+				stats.IndexSizes = map[string]int64{}
+			}
+			for name, size := range indexSizes {
+				n, err := statNumber(size)
+				if err != nil {
+					return stats, err
+				}
+				stats.IndexSizes[name] += n
+			}
+		}
+		if result.Shard != "" {
+			shards[result.Shard] = &shard
+		}
+	}
+	if stats.Count > 0 {
+		stats.AvgObjSize = stats.Size / stats.Count
+	}
+
+	if sharding == nil {
+		return stats, nil
+	}
+	stats.Sharded = true
+	stats.Unique = sharding.Unique
+	key, err := bson.MarshalExtJSON(sharding.Key, false, false)
+	if err != nil {
+		return stats, err
+	}
+	stats.ShardKey = key
+	for shard, chunks := range sharding.chunks {
+		stats.Chunks += chunks
+		if shards[shard] == nil {
+			shards[shard] = &ShardStats{Shard: shard}
+		}
+		shards[shard].Chunks = chunks
+	}
+	for _, shard := range shards {
+		stats.Shards = append(stats.Shards, *shard)
+	}
+	sort.Slice(stats.Shards, func(i, j int) bool {
+		return stats.Shards[i].Shard < stats.Shards[j].Shard
+	})
+	return stats, nil
+}
+
+// statNumber converts a number of storageStats, which servers return as an
+// int32, an int64 or a double, to an int64. A missing number is 0.
+func statNumber(value interface{}) (int64, error) {
+	if value == nil {
+		return 0, nil
+	}
+	n, err := util.ToInt(value)
+	return int64(n), err
+}
@@ -0,0 +1,98 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestBuildCollectionStats(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	t.Run("unsharded", func(t *testing.T) {
+		stats, err := buildCollectionStats(
+			CollectionStats{Namespace: "app.users"},
+			[]collStatsResult{{StorageStats: bson.M{
+				"count":          int32(4),
+				"size":           int64(1000),
+				"storageSize":    float64(4096),
+				"totalIndexSize": int32(8192),
+				"indexSizes":     bson.M{"_id_": int32(8192)},
+			}}},
+			nil,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, CollectionStats{
+			Namespace:      "app.users",
+			Count:          4,
+			Size:           1000,
+			AvgObjSize:     250,
+			StorageSize:    4096,
+			TotalIndexSize: 8192,
+			IndexSizes:     map[string]int64{"_id_": 8192},
+		}, stats)
+	})
+
+	t.Run("sharded", func(t *testing.T) {
+		stats, err := buildCollectionStats(
+			CollectionStats{Namespace: "app.orders"},
+			[]collStatsResult{
+				{Shard: "shard1", StorageStats: bson.M{
+					"count":      int32(3),
+					"size":       int32(300),
+					"indexSizes": bson.M{"_id_": int32(10), "sku_1": int32(5)},
+				}},
+				{Shard: "shard0", StorageStats: bson.M{
+					"count":      int32(1),
+					"size":       int32(100),
+					"indexSizes": bson.M{"_id_": int32(20)},
+				}},
+			},
+			&shardingMetadata{
+				Key:    bson.D{{"sku", "hashed"}},
+				chunks: map[string]int64{"shard0": 2, "shard1": 3, "shard2": 1},
+			},
+		)
+		require.NoError(t, err)
+		assert.True(t, stats.Sharded)
+		assert.JSONEq(t, `{"sku": "hashed"}`, string(stats.ShardKey))
+		assert.EqualValues(t, 4, stats.Count)
+		assert.EqualValues(t, 100, stats.AvgObjSize)
+		assert.EqualValues(t, 6, stats.Chunks)
+		assert.Equal(t, map[string]int64{"_id_": 30, "sku_1": 5}, stats.IndexSizes)
+		assert.Equal(t, []ShardStats{
+			{Shard: "shard0", Chunks: 2, Count: 1, Size: 100},
+			{Shard: "shard1", Chunks: 3, Count: 3, Size: 300},
+			{Shard: "shard2", Chunks: 1},
+		}, stats.Shards)
+	})
+
+	t.Run("invalid number", func(t *testing.T) {
+		_, err := buildCollectionStats(
+			CollectionStats{Namespace: "app.users"},
+			[]collStatsResult{{StorageStats: bson.M{"count": "four"}}},
+			nil,
+		)
+		assert.Error(t, err)
+	})
+}
+
+func TestCollectionStatsValidation(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	md := simpleMongoDumpInstance()
+	md.OutputOptions.CollectionStats = true
+	assert.NoError(t, md.ValidateOptions())
+
+	md.OutputOptions.Archive = "dump.archive"
+	assert.ErrorContains(t, md.ValidateOptions(), "--collectionStats")
+}
@@ -0,0 +1,134 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"sort"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+)
+
+// validReadConcernLevels are the read concern levels a NamespaceTuning can
+// read its collections with.
+var validReadConcernLevels = map[string]bool{
+	"local":        true,
+	"available":    true,
+	"majority":     true,
+	"linearizable": true,
+	"snapshot":     true,
+}
+
+// ConsistencyManifest is the content of consistency.json, which records for
+// each dumped collection the read concern it was read with and the times of
+// the causally consistent session it was read in, so that incremental tools
+// can pick up exactly where the dump of each collection left off.
+type ConsistencyManifest struct {
+	Namespaces []NamespaceConsistency `json:"namespaces"`
+}
+
+// NamespaceConsistency holds the consistency tokens of one collection. The
+// times are in the <seconds>:<ordinal> form of PreludeData's OplogStart, and
+// are empty on a standalone server, which does not report them.
+type NamespaceConsistency struct {
+	Namespace   string `json:"namespace"`
+	ReadConcern string `json:"readConcern,omitempty"`
+	// OperationTime is the time of the last read of the collection, e.g. for
+	// the afterClusterTime of a later read that must see every change the
+	// dump saw.
+	OperationTime string `json:"operationTime,omitempty"`
+	// ClusterTime is the highest cluster time the server reported while the
+	// collection was read.
+	ClusterTime string `json:"clusterTime,omitempty"`
+}
+
+// readConcernLevel returns the read concern level intent is read with: that
+// of its entry in the --tuningFile, or else that of the connection string.
+func (dump *MongoDump) readConcernLevel(intent *intents.Intent) string {
+	if nt := dump.tuning.ForNamespace(intent.Namespace()); nt != nil && nt.ReadConcern != "" {
+		return nt.ReadConcern
+	}
+	if dump.ToolOptions.URI != nil && dump.ToolOptions.URI.ConnString != nil {
+		return dump.ToolOptions.URI.ConnString.ReadConcernLevel
+	}
+	return ""
+}
+
+// collectionOptions returns the options of the collection intent is read
+// from, which set the read concern of its entry in the --tuningFile.
+func (dump *MongoDump) collectionOptions(intent *intents.Intent) *mopt.CollectionOptions {
+	collOpts := mopt.Collection()
+	if nt := dump.tuning.ForNamespace(intent.Namespace()); nt != nil && nt.ReadConcern != "" {
+		log.Logvf(
+			log.DebugLow,
+			"reading %v with read concern %v for %v",
+			intent.Namespace(),
+			nt.ReadConcern,
+			nt.Name,
+		)
+		collOpts.SetReadConcern(readconcern.New(readconcern.Level(nt.ReadConcern)))
+	}
+	return collOpts
+}
+
+// recordConsistency records the consistency tokens of intent, which was read
+// in session.
+func (dump *MongoDump) recordConsistency(intent *intents.Intent, session mongo.Session) {
+	entry := NamespaceConsistency{
+		Namespace:   intent.Namespace(),
+		ReadConcern: dump.readConcernLevel(intent),
+	}
+	if ts := session.OperationTime(); ts != nil {
+		entry.OperationTime = formatOplogTimestamp(*ts)
+	}
+	if ts, ok := clusterTimeOf(session.ClusterTime()); ok {
+		entry.ClusterTime = formatOplogTimestamp(ts)
+	}
+	log.Logvf(
+		log.DebugLow,
+		"read %v at operation time %v and cluster time %v",
+		entry.Namespace,
+		entry.OperationTime,
+		entry.ClusterTime,
+	)
+
+	dump.consistencyMu.Lock()
+	defer dump.consistencyMu.Unlock()
+	dump.consistency = append(dump.consistency, entry)
+}
+
+// clusterTimeOf returns the timestamp of a $clusterTime document.
+func clusterTimeOf(clusterTime bson.Raw) (primitive.Timestamp, bool) {
+	if clusterTime == nil {
+		return primitive.Timestamp{}, false
+	}
+	value, err := clusterTime.LookupErr("$clusterTime", "clusterTime")
+	if err != nil {
+		return primitive.Timestamp{}, false
+	}
+	t, i, ok := value.TimestampOK()
+	return primitive.Timestamp{T: t, I: i}, ok
+}
+
+// DumpConsistencyManifest writes consistency.json next to prelude.json, with
+// the collections in the order of their namespaces.
+func (dump *MongoDump) DumpConsistencyManifest() error {
+	dump.consistencyMu.Lock()
+	manifest := ConsistencyManifest{
+		Namespaces: append([]NamespaceConsistency{}, dump.consistency...),
+	}
+	dump.consistencyMu.Unlock()
+	sort.Slice(manifest.Namespaces, func(i, j int) bool {
+		return manifest.Namespaces[i].Namespace < manifest.Namespaces[j].Namespace
+	})
+	return dump.writeTopLevelJSON("consistency.json", manifest)
+}
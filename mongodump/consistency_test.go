@@ -0,0 +1,68 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestClusterTimeOf(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	raw, err := bson.Marshal(bson.D{{"$clusterTime", bson.D{
+		{"clusterTime", primitive.Timestamp{T: 1700000000, I: 7}},
+		{"signature", bson.D{}},
+	}}})
+	require.NoError(t, err)
+	ts, ok := clusterTimeOf(raw)
+	assert.True(t, ok)
+	assert.Equal(t, primitive.Timestamp{T: 1700000000, I: 7}, ts)
+
+	_, ok = clusterTimeOf(nil)
+	assert.False(t, ok)
+}
+
+func TestDumpConsistencyManifest(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	tuning, err := LoadTuningFile(writeTuningFile(t, testTuningFile))
+	require.NoError(t, err)
+	out := t.TempDir()
+	dump := &MongoDump{
+		ToolOptions:   &options.ToolOptions{Namespace: &options.Namespace{}},
+		OutputOptions: &OutputOptions{Out: out},
+		tuning:        tuning,
+	}
+	assert.Equal(t, "majority", dump.readConcernLevel(&intents.Intent{DB: "app", C: "sessions"}))
+	assert.Empty(t, dump.readConcernLevel(&intents.Intent{DB: "app", C: "users"}))
+
+	dump.consistency = []NamespaceConsistency{
+		{Namespace: "b.c", OperationTime: "1700000000:2", ClusterTime: "1700000000:3"},
+		{Namespace: "a.c", ReadConcern: "majority", OperationTime: "1700000000:1"},
+	}
+	require.NoError(t, dump.DumpConsistencyManifest())
+
+	content, err := os.ReadFile(filepath.Join(out, "consistency.json"))
+	require.NoError(t, err)
+	var manifest ConsistencyManifest
+	require.NoError(t, json.Unmarshal(content, &manifest))
+	require.Len(t, manifest.Namespaces, 2)
+	assert.Equal(t, "a.c", manifest.Namespaces[0].Namespace)
+	assert.Equal(t, "majority", manifest.Namespaces[0].ReadConcern)
+	assert.Equal(t, "1700000000:3", manifest.Namespaces[1].ClusterTime)
+}
@@ -0,0 +1,142 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"sync"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+)
+
+// databasePrioritizer wraps the prioritizer of the intent manager for
+// --numParallelDatabases. At most that many databases have collections being
+// dumped at the same time, and the workers are split between them in pools of
+// equal size, so that the large collections of one database cannot take every
+// worker while the other databases wait. The pools grow as fewer databases are
+// left to dump than --numParallelDatabases.
+type databasePrioritizer struct {
+	next      intents.IntentPrioritizer
+	databases int
+	jobs      int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	waiting []*intents.Intent
+	// remaining counts the collections of each database that have not been
+	// started, and running those being dumped.
+	remaining map[string]int
+	running   map[string]int
+	// finished counts the calls to Finish, so that Get can tell whether one
+	// happened while it was not holding mu.
+	finished int
+}
+
+func newDatabasePrioritizer(
+	next intents.IntentPrioritizer,
+	all []*intents.Intent,
+	databases, jobs int,
+) *databasePrioritizer {
+	dp := &databasePrioritizer{
+		next:      next,
+		databases: databases,
+		jobs:      jobs,
+		remaining: map[string]int{},
+		running:   map[string]int{},
+	}
+	for _, intent := range all {
+		dp.remaining[intent.DB]++
+	}
+	dp.cond = sync.NewCond(&dp.mu)
+	return dp
+}
+
+// poolSize returns how many collections of one database can be dumped at the
+// same time.
+func (dp *databasePrioritizer) poolSize() int {
+	left := 0
+	for db, n := range dp.remaining {
+		if n > 0 || dp.running[db] > 0 {
+			left++
+		}
+	}
+	if left > dp.databases {
+		left = dp.databases
+	}
+	if left == 0 || dp.jobs <= left {
+		return 1
+	}
+	return dp.jobs / left
+}
+
+// start returns whether intent can be dumped now, and if so counts it as
+// running.
+func (dp *databasePrioritizer) start(intent *intents.Intent) bool {
+	if running := dp.running[intent.DB]; running > 0 {
+		if running >= dp.poolSize() {
+			return false
+		}
+	} else if len(dp.running) >= dp.databases {
+		return false
+	}
+	dp.running[intent.DB]++
+	dp.remaining[intent.DB]--
+	return true
+}
+
+// Get returns the next intent that the pool of its database, or a free pool,
+// lets be dumped now, in the order of the wrapped prioritizer.
+//
+// The wrapped prioritizer is called without holding mu, since it may block
+// until an intent is finished, like the tuningPrioritizer does.
+func (dp *databasePrioritizer) Get() *intents.Intent {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	for {
+		for i, intent := range dp.waiting {
+			if dp.start(intent) {
+				dp.waiting = append(dp.waiting[:i], dp.waiting[i+1:]...)
+				return intent
+			}
+		}
+		finished := dp.finished
+		dp.mu.Unlock()
+		intent := dp.next.Get()
+		dp.mu.Lock()
+		if intent == nil {
+			if len(dp.waiting) == 0 {
+				return nil
+			}
+			if dp.finished == finished {
+				dp.cond.Wait()
+			}
+			continue
+		}
+		if dp.start(intent) {
+			return intent
+		}
+		log.Logvf(
+			log.DebugHigh,
+			"holding back %v until a worker of its database is free",
+			intent.Namespace(),
+		)
+		dp.waiting = append(dp.waiting, intent)
+	}
+}
+
+// Finish frees the place of intent in the pool of its database, and the pool
+// itself once no collection of the database is being dumped.
+func (dp *databasePrioritizer) Finish(intent *intents.Intent) {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	if dp.running[intent.DB]--; dp.running[intent.DB] <= 0 {
+		delete(dp.running, intent.DB)
+	}
+	dp.finished++
+	dp.next.Finish(intent)
+	dp.cond.Broadcast()
+}
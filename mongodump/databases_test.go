@@ -0,0 +1,147 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabasePrioritizer(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	manager := intents.NewIntentManager()
+	for i := 0; i < 16; i++ {
+		manager.Put(&intents.Intent{DB: "tenant1", C: fmt.Sprintf("c%d", i), Size: int64(1000 - i)})
+	}
+	for _, db := range []string{"tenant2", "tenant3"} {
+		for i := 0; i < 3; i++ {
+			manager.Put(&intents.Intent{DB: db, C: fmt.Sprintf("c%d", i), Size: int64(10 - i)})
+		}
+	}
+	collections := manager.NormalIntents()
+	manager.Finalize(intents.LongestTaskFirst)
+	manager.UsePrioritizer(newDatabasePrioritizer(manager.Prioritizer(), collections, 2, 4))
+
+	var mu sync.Mutex
+	running := map[string]int{}
+	maxRunning := map[string]int{}
+	maxDatabases := 0
+	var order []string
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for intent := manager.Pop(); intent != nil; intent = manager.Pop() {
+				mu.Lock()
+				running[intent.DB]++
+				if running[intent.DB] > maxRunning[intent.DB] {
+					maxRunning[intent.DB] = running[intent.DB]
+				}
+				databases := 0
+				for _, n := range running {
+					if n > 0 {
+						databases++
+					}
+				}
+				if databases > maxDatabases {
+					maxDatabases = databases
+				}
+				order = append(order, intent.DB)
+				mu.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+
+				mu.Lock()
+				running[intent.DB]--
+				mu.Unlock()
+				manager.Finish(intent)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, order, 22)
+	assert.Equal(t, 2, maxDatabases, "no more than 2 databases should be dumped at a time")
+	assert.Contains(t, order[:4], "tenant2", "a small database should not wait for the large one")
+	assert.Equal(t, 4, maxRunning["tenant1"], "the last database left should use every worker")
+	for _, db := range []string{"tenant2", "tenant3"} {
+		assert.LessOrEqual(t, maxRunning[db], 2, "a database should be limited to its pool")
+	}
+}
+
+func TestDatabasePrioritizerWithTuning(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	tuning, err := LoadTuningFile(writeTuningFile(t, testTuningFile))
+	require.NoError(t, err)
+
+	manager := intents.NewIntentManager()
+	for i := 0; i < 6; i++ {
+		manager.Put(&intents.Intent{DB: "logs", C: fmt.Sprintf("c%d", i), Size: int64(100 - i)})
+	}
+	for i := 0; i < 4; i++ {
+		manager.Put(&intents.Intent{DB: "app", C: fmt.Sprintf("c%d", i), Size: int64(10 - i)})
+	}
+	collections := manager.NormalIntents()
+	manager.Finalize(intents.LongestTaskFirst)
+	manager.UsePrioritizer(newTuningPrioritizer(manager.Prioritizer(), tuning))
+	manager.UsePrioritizer(newDatabasePrioritizer(manager.Prioritizer(), collections, 1, 4))
+
+	var mu sync.Mutex
+	running := 0
+	maxRunning := 0
+	dumped := 0
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for intent := manager.Pop(); intent != nil; intent = manager.Pop() {
+				mu.Lock()
+				if intent.DB == "logs" {
+					running++
+					maxRunning = max(maxRunning, running)
+				}
+				dumped++
+				mu.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+
+				mu.Lock()
+				if intent.DB == "logs" {
+					running--
+				}
+				mu.Unlock()
+				manager.Finish(intent)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "the workers are deadlocked")
+	}
+	assert.Equal(t, 10, dumped)
+	assert.Equal(t, 2, maxRunning, "the tuning limit should hold within the pool of a database")
+}
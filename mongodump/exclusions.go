@@ -0,0 +1,80 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/dumprestore"
+	"github.com/mongodb/mongo-tools/mongorestore/ns"
+)
+
+// defaultExclusion is a namespace pattern that mongodump skips unless
+// --includeSystemCollections is set, since the collections it matches are
+// rebuilt by the server or only live for the duration of an operation.
+type defaultExclusion struct {
+	pattern string
+	reason  string
+	matcher *ns.Matcher
+}
+
+// defaultExclusions are the namespaces that a dump skips by default. The
+// definitions of views, which live in system.views, are always dumped with
+// the views themselves rather than as a collection.
+var defaultExclusions = newDefaultExclusions([]defaultExclusion{
+	{pattern: "*.system.profile", reason: "profiler output"},
+	{pattern: "*.tmp.agg_out.*", reason: "temporary collection of $out or $merge"},
+	{pattern: "*.tmp.mr.*", reason: "temporary collection of mapReduce"},
+	{pattern: "config.cache.*", reason: "routing cache of a shard"},
+	{pattern: "local.*", reason: "data local to the member"},
+})
+
+func newDefaultExclusions(exclusions []defaultExclusion) []defaultExclusion {
+	for i := range exclusions {
+		matcher, err := ns.NewMatcher([]string{exclusions[i].pattern})
+		if err != nil {
+			panic(err)
+		}
+		exclusions[i].matcher = matcher
+	}
+	return exclusions
+}
+
+// defaultExclusionOf returns why the built-in exclusions skip dbName.collName,
+// or "" if they don't. They don't apply with --includeSystemCollections, to
+// a system collection named by --systemCollection, nor, for a pattern of a
+// specific database such as local.*, when that database is named by --db.
+func (dump *MongoDump) defaultExclusionOf(dbName, collName string) string {
+	if dump.OutputOptions.IncludeSystemCollections ||
+		dumprestore.IncludesSystemCollection(dump.OutputOptions.SystemCollections, collName) {
+		return ""
+	}
+	for _, exclusion := range defaultExclusions {
+		patternDB, _, _ := strings.Cut(exclusion.pattern, ".")
+		if patternDB == dump.ToolOptions.DB {
+			continue
+		}
+		if exclusion.matcher.Has(dbName + "." + collName) {
+			return exclusion.reason
+		}
+	}
+	return ""
+}
+
+// excludesDatabase returns whether a dump of every database skips dbName
+// because the built-in exclusions skip all of its collections.
+func (dump *MongoDump) excludesDatabase(dbName string) bool {
+	if dump.OutputOptions.IncludeSystemCollections {
+		return false
+	}
+	for _, exclusion := range defaultExclusions {
+		if exclusion.pattern == dbName+".*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDefaultExclusions(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With the built-in exclusions of mongodump", t, func() {
+		md := &MongoDump{
+			ToolOptions:   &options.ToolOptions{Namespace: &options.Namespace{}},
+			OutputOptions: &OutputOptions{},
+		}
+
+		Convey("ephemeral namespaces should be skipped", func() {
+			So(md.defaultExclusionOf("admin", "system.profile"), ShouldEqual, "profiler output")
+			So(md.defaultExclusionOf("app", "tmp.agg_out.0f3c"), ShouldNotEqual, "")
+			So(md.defaultExclusionOf("app", "tmp.mr.orders_1"), ShouldNotEqual, "")
+			So(md.defaultExclusionOf("config", "cache.chunks.app.orders"), ShouldNotEqual, "")
+			So(md.defaultExclusionOf("local", "startup_log"), ShouldNotEqual, "")
+			So(md.excludesDatabase("local"), ShouldBeTrue)
+		})
+
+		Convey("other namespaces should be dumped", func() {
+			So(md.defaultExclusionOf("app", "orders"), ShouldEqual, "")
+			So(md.defaultExclusionOf("app", "tmp"), ShouldEqual, "")
+			So(md.defaultExclusionOf("config", "settings"), ShouldEqual, "")
+			So(md.excludesDatabase("app"), ShouldBeFalse)
+		})
+
+		Convey("naming the database with --db should dump its patterns", func() {
+			md.ToolOptions.DB = "local"
+			So(md.defaultExclusionOf("local", "startup_log"), ShouldEqual, "")
+			So(md.defaultExclusionOf("local", "system.profile"), ShouldNotEqual, "")
+		})
+
+		Convey("--systemCollection should dump system.profile", func() {
+			md.OutputOptions.SystemCollections = []string{"system.profile"}
+			So(md.defaultExclusionOf("app", "system.profile"), ShouldEqual, "")
+			So(md.defaultExclusionOf("app", "tmp.mr.orders_1"), ShouldNotEqual, "")
+		})
+
+		Convey("--includeSystemCollections should dump everything", func() {
+			md.OutputOptions.IncludeSystemCollections = true
+			So(md.defaultExclusionOf("admin", "system.profile"), ShouldEqual, "")
+			So(md.defaultExclusionOf("config", "cache.collections"), ShouldEqual, "")
+			So(md.excludesDatabase("local"), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"sync"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/intents"
+)
+
+// defaultMetadataJobs is the number of collections whose intents and
+// metadata are built in parallel when --numParallelMetadata isn't set.
+const defaultMetadataJobs = 16
+
+// metadataJobs returns the number of collections whose counts and indexes
+// are fetched in parallel.
+func (dump *MongoDump) metadataJobs() int {
+	if dump.OutputOptions.NumParallelMetadata > 0 {
+		return dump.OutputOptions.NumParallelMetadata
+	}
+	return defaultMetadataJobs
+}
+
+// intentBuilder builds the intents of a database's collections with a pool of
+// workers while the collections are still being listed. Building an intent
+// takes a round trip to count the collection's documents, which dominates the
+// time it takes to prepare a dump of many small collections when done one at
+// a time.
+type intentBuilder struct {
+	build func(*db.CollectionInfo) (*intents.Intent, error)
+	work  chan intentJob
+	wg    sync.WaitGroup
+	once  sync.Once
+
+	// failed is closed once a build fails.
+	failed chan struct{}
+
+	mu      sync.Mutex
+	intents []*intents.Intent
+	err     error
+}
+
+type intentJob struct {
+	index    int
+	collInfo *db.CollectionInfo
+}
+
+// newIntentBuilder starts jobs workers that build intents with build.
+func newIntentBuilder(
+	jobs int,
+	build func(*db.CollectionInfo) (*intents.Intent, error),
+) *intentBuilder {
+	b := &intentBuilder{
+		build:  build,
+		work:   make(chan intentJob, jobs),
+		failed: make(chan struct{}),
+	}
+	b.wg.Add(jobs)
+	for i := 0; i < jobs; i++ {
+		go b.worker()
+	}
+	return b
+}
+
+func (b *intentBuilder) worker() {
+	defer b.wg.Done()
+	for job := range b.work {
+		select {
+		case <-b.failed:
+			continue
+		default:
+		}
+		intent, err := b.build(job.collInfo)
+
+		b.mu.Lock()
+		if err != nil && b.err == nil {
+			b.err = err
+			close(b.failed)
+		}
+		b.intents[job.index] = intent
+		b.mu.Unlock()
+	}
+}
+
+// add queues the intent of collInfo to be built. It returns false, without
+// queuing it, once a build has failed.
+func (b *intentBuilder) add(collInfo *db.CollectionInfo) bool {
+	b.mu.Lock()
+	index := len(b.intents)
+	b.intents = append(b.intents, nil)
+	b.mu.Unlock()
+
+	select {
+	case b.work <- intentJob{index, collInfo}:
+		return true
+	case <-b.failed:
+		return false
+	}
+}
+
+// wait stops the workers once the queued intents are built, and returns the
+// intents in the order they were added, or the first error.
+func (b *intentBuilder) wait() ([]*intents.Intent, error) {
+	b.once.Do(func() { close(b.work) })
+	b.wg.Wait()
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.intents, nil
+}
+
+// stop stops the workers, for callers that give up before calling wait.
+func (b *intentBuilder) stop() {
+	//nolint:errcheck
+	b.wait()
+}
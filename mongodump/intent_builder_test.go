@@ -0,0 +1,92 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntentBuilder(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	t.Run("intents keep the order of the collections", func(t *testing.T) {
+		var running, maxRunning int32
+		builder := newIntentBuilder(4, func(ci *db.CollectionInfo) (*intents.Intent, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			// finish the builds out of order
+			if ci.Name[len(ci.Name)-1]%2 == 0 {
+				time.Sleep(5 * time.Millisecond)
+			}
+			atomic.AddInt32(&running, -1)
+			return &intents.Intent{DB: "test", C: ci.Name}, nil
+		})
+
+		var names []string
+		for i := 0; i < 20; i++ {
+			name := fmt.Sprintf("coll%v", i)
+			names = append(names, name)
+			require.True(t, builder.add(&db.CollectionInfo{Name: name}))
+		}
+		built, err := builder.wait()
+		require.NoError(t, err)
+		require.Len(t, built, len(names))
+		for i, intent := range built {
+			assert.Equal(t, names[i], intent.C)
+		}
+		assert.LessOrEqual(t, maxRunning, int32(4))
+		assert.Greater(t, maxRunning, int32(1), "builds should run in parallel")
+	})
+
+	t.Run("the first error stops the build", func(t *testing.T) {
+		var builds int32
+		builder := newIntentBuilder(2, func(ci *db.CollectionInfo) (*intents.Intent, error) {
+			atomic.AddInt32(&builds, 1)
+			if ci.Name == "bad" {
+				return nil, fmt.Errorf("cannot count %v", ci.Name)
+			}
+			return &intents.Intent{C: ci.Name}, nil
+		})
+
+		require.True(t, builder.add(&db.CollectionInfo{Name: "bad"}))
+		stopped := false
+		for i := 0; i < 1000 && !stopped; i++ {
+			stopped = !builder.add(&db.CollectionInfo{Name: "good"})
+			time.Sleep(time.Millisecond)
+		}
+		assert.True(t, stopped, "add should fail once a build failed")
+
+		built, err := builder.wait()
+		assert.EqualError(t, err, "cannot count bad")
+		assert.Nil(t, built)
+		assert.Less(t, atomic.LoadInt32(&builds), int32(1000))
+	})
+
+	t.Run("stop after wait is harmless", func(t *testing.T) {
+		builder := newIntentBuilder(2, func(ci *db.CollectionInfo) (*intents.Intent, error) {
+			return &intents.Intent{C: ci.Name}, nil
+		})
+		built, err := builder.wait()
+		require.NoError(t, err)
+		assert.Empty(t, built)
+		builder.stop()
+	})
+}
@@ -0,0 +1,184 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/text"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// listedNamespace is a namespace that --list found would be dumped.
+type listedNamespace struct {
+	namespace string
+	kind      string
+	// known is unset if the statistics of the namespace were not read, like
+	// for views, or could not be.
+	known       bool
+	documents   int64
+	size        int64
+	storageSize int64
+}
+
+// ListNamespaces prints, for --list, the namespaces that the namespace and
+// query options select, with their estimated number of documents and their
+// sizes, instead of dumping them. With --query or --timeWindow, the documents
+// that match them are counted.
+func (dump *MongoDump) ListNamespaces() error {
+	if err := dump.createIntents(); err != nil {
+		return fmt.Errorf("error creating intents to dump: %v", err)
+	}
+	session, err := dump.SessionProvider.GetSession()
+	if err != nil {
+		return err
+	}
+
+	var listed []listedNamespace
+	for _, intent := range dump.manager.NormalIntents() {
+		if intent.IsUsers() || intent.IsRoles() || intent.IsAuthVersion() {
+			continue
+		}
+		entry := listedNamespace{namespace: intent.Namespace(), kind: intentKind(intent)}
+		if !intent.IsView() {
+			stats := dump.collectionStats(session, intent)
+			entry.known = stats.Error == ""
+			entry.documents = stats.Count
+			entry.size = stats.Size
+			entry.storageSize = stats.StorageSize
+		}
+		if entry.known && (dump.query != nil || dump.timeWindow != nil) {
+			entry.documents, err = dump.countListed(session, intent, entry.documents)
+			if err != nil {
+				return fmt.Errorf("error counting the documents of %v: %v", intent.Namespace(), err)
+			}
+		}
+		listed = append(listed, entry)
+	}
+	sort.Slice(listed, func(i, j int) bool {
+		return listed[i].namespace < listed[j].namespace
+	})
+	if dump.OutputOptions.Oplog {
+		if err := dump.determineOplogCollectionName(); err != nil {
+			return fmt.Errorf("error finding oplog: %v", err)
+		}
+		// only the entries written during the dump would be dumped
+		listed = append(listed, listedNamespace{
+			namespace: "local." + dump.oplogCollection,
+			kind:      "oplog",
+		})
+	}
+
+	writeNamespaceList(dump.OutputWriter, listed)
+	return nil
+}
+
+// createIntents creates the intents of the namespaces that the namespace
+// options select.
+func (dump *MongoDump) createIntents() error {
+	switch {
+	case dump.ToolOptions.DB == "" && dump.ToolOptions.Collection == "":
+		return dump.CreateAllIntents()
+	case dump.ToolOptions.DB != "" && dump.ToolOptions.Collection == "":
+		return dump.CreateIntentsForDatabase(dump.ToolOptions.DB)
+	case dump.ToolOptions.DB != "" && dump.ToolOptions.Collection != "":
+		return dump.CreateCollectionIntent(dump.ToolOptions.DB, dump.ToolOptions.Collection)
+	}
+	return nil
+}
+
+// countListed counts the documents of intent that match --query and
+// --timeWindow, or returns the estimate of its statistics if neither applies.
+func (dump *MongoDump) countListed(
+	session *mongo.Client,
+	intent *intents.Intent,
+	estimate int64,
+) (int64, error) {
+	database := session.Database(intent.DB)
+	if intent.IsTimeseries() && dump.query != nil {
+		// a query on the metaField is counted on the measurements of the view
+		filter := dump.query
+		if dump.timeWindow != nil {
+			timeField, err := timeseriesTimeField(intent)
+			if err != nil {
+				return 0, err
+			}
+			filter = andFilters(filter, dump.timeWindow.timeFilter(timeField))
+		}
+		return database.Collection(intent.C).CountDocuments(context.Background(), filter)
+	}
+
+	coll := database.Collection(intent.C)
+	if intent.IsTimeseries() {
+		coll = database.Collection("system.buckets." + intent.C)
+	}
+	window, err := dump.timeWindowFilter(intent, coll)
+	if err != nil {
+		return 0, err
+	}
+	filter := andFilters(dump.query, window)
+	if filter == nil {
+		return estimate, nil
+	}
+	return coll.CountDocuments(context.Background(), filter)
+}
+
+func intentKind(intent *intents.Intent) string {
+	switch {
+	case intent.IsView():
+		return "view"
+	case intent.IsTimeseries():
+		return "timeseries"
+	default:
+		return "collection"
+	}
+}
+
+// writeNamespaceList writes listed to out as a table, followed by the totals
+// of the namespaces whose statistics are known.
+func writeNamespaceList(out io.Writer, listed []listedNamespace) {
+	grid := &text.GridWriter{ColumnPadding: 2}
+	grid.WriteCells("namespace", "type", "documents", "size", "storage size")
+	grid.EndRow()
+	var documents, size int64
+	for _, entry := range listed {
+		grid.WriteCells(entry.namespace, entry.kind)
+		if entry.known {
+			documents += entry.documents
+			size += entry.size
+			grid.WriteCells(
+				strconv.FormatInt(entry.documents, 10),
+				text.FormatByteAmount(entry.size),
+				text.FormatByteAmount(entry.storageSize),
+			)
+		} else {
+			grid.WriteCells("-", "-", "-")
+		}
+		grid.EndRow()
+	}
+	grid.Flush(out)
+
+	_, err := fmt.Fprintf(
+		out,
+		"%v %v would be dumped: %v %v, %v\n",
+		len(listed),
+		util.Pluralize(len(listed), "namespace", "namespaces"),
+		documents,
+		util.Pluralize(int(documents), "document", "documents"),
+		text.FormatByteAmount(size),
+	)
+	if err != nil {
+		log.Logvf(log.Always, "error writing the list of namespaces: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteNamespaceList(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("The list should have a row per namespace and the totals", t, func() {
+		var out bytes.Buffer
+		writeNamespaceList(&out, []listedNamespace{
+			{
+				namespace:   "shop.orders",
+				kind:        "collection",
+				known:       true,
+				documents:   1500,
+				size:        2048,
+				storageSize: 4096,
+			},
+			{namespace: "shop.recent", kind: "view"},
+			{
+				namespace: "shop.metrics",
+				kind:      "timeseries",
+				known:     true,
+				documents: 10,
+				size:      1024,
+			},
+		})
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		So(lines, ShouldHaveLength, 5)
+		So(strings.Fields(lines[0]), ShouldResemble,
+			[]string{"namespace", "type", "documents", "size", "storage", "size"})
+		So(strings.Fields(lines[1]), ShouldResemble,
+			[]string{"shop.orders", "collection", "1500", "2.00KB", "4.00KB"})
+		So(strings.Fields(lines[2]), ShouldResemble, []string{"shop.recent", "view", "-", "-", "-"})
+		So(lines[4], ShouldEqual, "3 namespaces would be dumped: 1510 documents, 3.00KB")
+	})
+}
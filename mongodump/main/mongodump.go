@@ -14,6 +14,7 @@ import (
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/progress"
 	"github.com/mongodb/mongo-tools/common/signals"
+	"github.com/mongodb/mongo-tools/common/telemetry"
 	"github.com/mongodb/mongo-tools/common/util"
 	"github.com/mongodb/mongo-tools/mongodump"
 )
@@ -47,6 +48,12 @@ func main() {
 		return
 	}
 
+	if err := telemetry.Init(opts.AppName, opts.VersionStr, opts.OTelEndpoint); err != nil {
+		log.Logvf(log.Always, "error starting telemetry: %v", err)
+		os.Exit(util.ExitFailure)
+	}
+	defer telemetry.Shutdown()
+
 	// init logger
 	log.SetVerbosity(opts.Verbosity)
 
@@ -75,11 +82,11 @@ func main() {
 
 	if err = dump.Init(); err != nil {
 		log.Logvf(log.Always, "Failed: %v", err)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 
 	if err = dump.Dump(); err != nil {
 		log.Logvf(log.Always, "Failed: %v", err)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 }
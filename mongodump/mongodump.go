@@ -18,6 +18,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mongodb/mongo-tools/common"
@@ -25,16 +26,19 @@ import (
 	"github.com/mongodb/mongo-tools/common/auth"
 	"github.com/mongodb/mongo-tools/common/bsonutil"
 	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/dumprestore"
 	"github.com/mongodb/mongo-tools/common/failpoint"
 	"github.com/mongodb/mongo-tools/common/intents"
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/options"
 	"github.com/mongodb/mongo-tools/common/progress"
+	"github.com/mongodb/mongo-tools/common/telemetry"
 	"github.com/mongodb/mongo-tools/common/util"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
@@ -63,6 +67,7 @@ type MongoDump struct {
 	SessionProvider *db.SessionProvider
 	manager         *intents.Manager
 	query           bson.D
+	timeWindow      *timeWindow
 	oplogCollection string
 	oplogStart      primitive.Timestamp
 	oplogEnd        primitive.Timestamp
@@ -71,11 +76,31 @@ type MongoDump struct {
 	storageEngine   storageEngineType
 	serverVersion   string
 	authVersion     int
+	tuning          *TuningFile
 	archive         *archive.Writer
+	// dbArchives holds the archive for each database with --archivePerDB,
+	// in which case archive is nil
+	dbArchives map[string]*archive.Writer
+	// consistency holds the consistency tokens of the collections dumped so
+	// far, for consistency.json
+	consistencyMu sync.Mutex
+	consistency   []NamespaceConsistency
+	// bytesDumped is the number of bytes of documents dumped so far
+	bytesDumped atomic.Int64
 	// shutdownIntentsNotifier is provided to the multiplexer
 	// as well as the signal handler, and allows them to notify
 	// the intent dumpers that they should shutdown
 	shutdownIntentsNotifier *notifier
+	// the temporary mongod serving a --dbpath dump, if any
+	offline *offlineMongod
+	// interrupted is notified by HandleInterrupt, so that an interrupt also
+	// stops the start of the temporary mongod of a --dbpath dump. It is
+	// created by interruptNotifier.
+	interruptMu sync.Mutex
+	interrupted *notifier
+	// picks the member each namespace is read from with
+	// --readPreferenceFallback, if set
+	readFallback *readFallback
 	// Writer to take care of BSON output when not writing to the local filesystem.
 	// This is initialized to os.Stdout if unset.
 	OutputWriter io.Writer
@@ -130,24 +155,84 @@ func (dump *MongoDump) ValidateOptions() error {
 		return fmt.Errorf("--db is required when --excludeCollectionsWithPrefix is specified")
 	case dump.OutputOptions.Out != "" && dump.OutputOptions.Archive != "":
 		return fmt.Errorf("--out not allowed when --archive is specified")
+	case dump.OutputOptions.CollectionStats &&
+		(dump.OutputOptions.Archive != "" || dump.OutputOptions.Out == "-"):
+		return fmt.Errorf("--collectionStats can only be used when dumping to a directory")
+	case dump.OutputOptions.ArchiveChecksums && dump.OutputOptions.Archive == "":
+		return fmt.Errorf("--archiveChecksums requires --archive")
+	case dump.OutputOptions.ArchivePerDB && dump.OutputOptions.Archive == "":
+		return fmt.Errorf("--archivePerDB requires --archive=<directory-path>")
+	case dump.OutputOptions.ArchivePerDB && dump.OutputOptions.Archive == "-":
+		return fmt.Errorf("--archivePerDB cannot write archives to stdout")
+	case dump.contiguousArchive() &&
+		(dump.OutputOptions.Archive == "" || dump.OutputOptions.Archive == "-"):
+		return fmt.Errorf("--archiveLayout=%v requires --archive=<file-path>", archive.ContiguousLayout)
+	case dump.contiguousArchive() && dump.OutputOptions.Gzip:
+		return fmt.Errorf("--archiveLayout=%v cannot be used with --gzip", archive.ContiguousLayout)
+	case dump.contiguousArchive() && archive.IsStreamEndpoint(dump.OutputOptions.Archive):
+		return fmt.Errorf(
+			"--archiveLayout=%v cannot write to a named pipe or a UNIX domain socket",
+			archive.ContiguousLayout,
+		)
+	case dump.OutputOptions.ArchivePerDB && dump.OutputOptions.Oplog:
+		return fmt.Errorf("--oplog not allowed when --archivePerDB is specified")
+	case dump.InputOptions.TimeWindow != "" && dump.OutputOptions.Oplog:
+		return fmt.Errorf("cannot use --timeWindow with --oplog, whose entries are not filtered")
+	case dump.OutputOptions.RequireOplogWindow != 0 && !dump.OutputOptions.Oplog:
+		return fmt.Errorf("cannot use --requireOplogWindow without --oplog")
+	case dump.OutputOptions.RequireOplogWindow < 0:
+		return fmt.Errorf("--requireOplogWindow must be a positive number of seconds")
 	case dump.OutputOptions.Out == "-" && dump.OutputOptions.Gzip:
 		return fmt.Errorf(
 			"compression can't be used when dumping a single collection to standard output",
 		)
 	case dump.OutputOptions.NumParallelCollections <= 0:
 		return fmt.Errorf("numParallelCollections must be positive")
+	case dump.OutputOptions.NumParallelDatabases < 0:
+		return fmt.Errorf("numParallelDatabases cannot be negative")
+	case dump.OutputOptions.NumParallelDatabases > dump.OutputOptions.NumParallelCollections:
+		return fmt.Errorf("--numParallelDatabases cannot be larger than --numParallelCollections")
+	case dump.OutputOptions.NumParallelDatabases > 0 && dump.OutputOptions.BandwidthScheduling:
+		return fmt.Errorf("--numParallelDatabases cannot be used with --bandwidthScheduling")
+	case dump.OutputOptions.NumParallelMetadata < 0:
+		return fmt.Errorf("numParallelMetadata cannot be negative")
+	case dump.OutputOptions.BandwidthScheduling && dump.OutputOptions.TuningFile != "":
+		return fmt.Errorf("--bandwidthScheduling cannot be used with --tuningFile")
+	case dump.OutputOptions.BandwidthScheduling && dump.OutputOptions.LargeCollectionBytes <= 0:
+		return fmt.Errorf("--largeCollectionBytes must be positive")
 	case dump.isAtlasProxy && (dump.OutputOptions.DumpDBUsersAndRoles || dump.ToolOptions.DB == "admin"):
 		return fmt.Errorf(
 			"can't dump from admin database when connecting to a MongoDB Atlas free or shared cluster",
 		)
 	}
-	return nil
+	if err := dump.validateReadPreferenceFallback(); err != nil {
+		return err
+	}
+	if dump.InputOptions.TimeWindow != "" {
+		window, err := parseTimeWindow(dump.InputOptions.TimeWindow)
+		if err != nil {
+			return err
+		}
+		dump.timeWindow = window
+	}
+	return dumprestore.ValidateSystemCollections(dump.OutputOptions.SystemCollections)
 }
 
 // Init performs preliminary setup operations for MongoDump.
-func (dump *MongoDump) Init() error {
+func (dump *MongoDump) Init() (err error) {
 	log.Logvf(log.DebugHigh, "initializing mongodump object")
 
+	if dump.InputOptions.DBPath != "" {
+		defer func() {
+			if err != nil {
+				dump.stopOfflineMongod()
+			}
+		}()
+		if err = dump.startOfflineMongod(); err != nil {
+			return err
+		}
+	}
+
 	// this would be default, but explicit setting protects us from any
 	// redefinition of the constants.
 	dump.storageEngine = storageEngineUnknown
@@ -159,6 +244,11 @@ func (dump *MongoDump) Init() error {
 	if err != nil {
 		return fmt.Errorf("error parsing --readPreference : %v", err)
 	}
+	if dump.InputOptions.ReadPreferenceFallback != "" {
+		// --readPreferenceFallback picks the member each namespace is read
+		// from, and the rest is read from whichever member is there
+		pref = readpref.PrimaryPreferred()
+	}
 	dump.ToolOptions.ReadPreference = pref
 
 	dump.SessionProvider, err = db.NewSessionProvider(*dump.ToolOptions)
@@ -180,6 +270,15 @@ func (dump *MongoDump) Init() error {
 	if err != nil {
 		return fmt.Errorf("bad option: %v", err)
 	}
+	if dump.readFallback, err = dump.newReadFallback(); err != nil {
+		return err
+	}
+	if dump.OutputOptions.TuningFile != "" {
+		dump.tuning, err = LoadTuningFile(dump.OutputOptions.TuningFile)
+		if err != nil {
+			return err
+		}
+	}
 	if dump.OutputWriter == nil {
 		dump.OutputWriter = os.Stdout
 	}
@@ -217,7 +316,9 @@ func (dump *MongoDump) verifyCollectionExists() (bool, error) {
 
 // Dump handles some final options checking and executes MongoDump.
 func (dump *MongoDump) Dump() (err error) {
+	defer dump.stopOfflineMongod()
 	defer dump.SessionProvider.Close()
+	defer dump.readFallback.close()
 
 	if !dump.OutputOptions.Oplog && (dump.InputOptions.SourceWritesDoneBarrier != "") {
 		// Wait for tests to stop writes before dumping any collections.
@@ -268,6 +369,10 @@ func (dump *MongoDump) Dump() (err error) {
 		dump.query = query
 	}
 
+	if dump.OutputOptions.List {
+		return dump.ListNamespaces()
+	}
+
 	// If we enter this case, then we're not connected to an atlas proxy otherwise
 	// mongodump would have errored earlier.
 	if !dump.SkipUsersAndRoles && dump.OutputOptions.DumpDBUsersAndRoles {
@@ -287,24 +392,24 @@ func (dump *MongoDump) Dump() (err error) {
 	}
 
 	if dump.OutputOptions.Archive != "" {
-		//getArchiveOut gives us a WriteCloser to which we should write the archive
-		var archiveOut io.WriteCloser
-		archiveOut, err = dump.getArchiveOut()
-		if err != nil {
-			return err
-		}
-		dump.archive = &archive.Writer{
-			// The archive.Writer needs its own copy of archiveOut because things
-			// like the prelude are not written by the multiplexer.
-			Out: archiveOut,
-			Mux: archive.NewMultiplexer(archiveOut, dump.shutdownIntentsNotifier),
+		if dump.OutputOptions.ArchivePerDB {
+			// the archives are created as intents for each database are found
+			err = os.MkdirAll(dump.OutputOptions.Archive, 0755)
+			if err != nil {
+				return fmt.Errorf("error creating archive directory: %v", err)
+			}
+			dump.dbArchives = map[string]*archive.Writer{}
+		} else {
+			//getArchiveOut gives us a WriteCloser to which we should write the archive
+			var archiveOut io.WriteCloser
+			archiveOut, err = dump.getArchiveOut()
+			if err != nil {
+				return err
+			}
+			dump.archive = dump.startArchiveWriter(archiveOut)
 		}
-		go dump.archive.Mux.Run()
 		defer func() {
-			// The Mux runs until its Control is closed
-			close(dump.archive.Mux.Control)
-			muxErr := <-dump.archive.Mux.Completed
-			archiveOut.Close()
+			muxErr := dump.stopArchiveWriters()
 			if muxErr != nil {
 				if err != nil {
 					err = fmt.Errorf("archive writer: %v / %v", err, muxErr)
@@ -342,6 +447,15 @@ func (dump *MongoDump) Dump() (err error) {
 		if err != nil {
 			return fmt.Errorf("error getting oplog start: %v", err)
 		}
+
+		oldest, err := dump.getOldestOplogTime()
+		if err != nil {
+			return err
+		}
+		err = dump.checkOplogWindow(oldest, dump.oplogStart, dump.oplogStart)
+		if err != nil {
+			return err
+		}
 	}
 
 	if failpoint.Enabled(failpoint.PauseBeforeDumping) {
@@ -349,15 +463,7 @@ func (dump *MongoDump) Dump() (err error) {
 		time.Sleep(15 * time.Second)
 	}
 
-	// switch on what kind of execution to do
-	switch {
-	case dump.ToolOptions.DB == "" && dump.ToolOptions.Collection == "":
-		err = dump.CreateAllIntents()
-	case dump.ToolOptions.DB != "" && dump.ToolOptions.Collection == "":
-		err = dump.CreateIntentsForDatabase(dump.ToolOptions.DB)
-	case dump.ToolOptions.DB != "" && dump.ToolOptions.Collection != "":
-		err = dump.CreateCollectionIntent(dump.ToolOptions.DB, dump.ToolOptions.Collection)
-	}
+	err = dump.createIntents()
 	if err != nil {
 		return fmt.Errorf("error creating intents to dump: %v", err)
 	}
@@ -397,18 +503,9 @@ func (dump *MongoDump) Dump() (err error) {
 	}
 
 	if dump.OutputOptions.Archive != "" {
-		dump.archive.Prelude, err = archive.NewPrelude(
-			dump.manager,
-			dump.OutputOptions.NumParallelCollections,
-			dump.serverVersion,
-			dump.ToolOptions.VersionStr,
-		)
-		if err != nil {
-			return fmt.Errorf("creating archive prelude: %v", err)
-		}
-		err = dump.archive.Prelude.Write(dump.archive.Out)
+		err = dump.writeArchivePreludes()
 		if err != nil {
-			return fmt.Errorf("error writing metadata into archive: %v", err)
+			return err
 		}
 	}
 
@@ -485,6 +582,15 @@ func (dump *MongoDump) Dump() (err error) {
 		}
 		log.Logvf(log.DebugHigh, "oplog entry %v still exists", dump.oplogStart)
 
+		oldest, err := dump.getOldestOplogTime()
+		if err != nil {
+			return err
+		}
+		err = dump.checkOplogWindow(oldest, dump.oplogStart, dump.oplogEnd)
+		if err != nil {
+			return err
+		}
+
 		log.Logvf(log.Always, "writing captured oplog to %v", dump.manager.Oplog().Location)
 
 		err = dump.DumpOplogBetweenTimestamps(dump.oplogStart, dump.oplogEnd)
@@ -506,11 +612,25 @@ func (dump *MongoDump) Dump() (err error) {
 			return fmt.Errorf("unable to check oplog for overflow: %v", err)
 		}
 		log.Logvf(log.DebugHigh, "oplog entry %v still exists", dump.oplogStart)
+
+		log.Logvf(
+			log.Always,
+			"captured oplog entries from %v to %v",
+			formatOplogTimestamp(dump.oplogStart),
+			formatOplogTimestamp(dump.oplogEnd),
+		)
+		dump.recordOplogEnd()
 	}
 
 	if dump.OutputOptions.Archive == "" && dump.OutputOptions.Out != "-" {
 		log.Logvf(log.DebugLow, "dump phase IV: top level metadata json")
 		err = dump.DumpPreludeMetadata()
+		if err == nil {
+			err = dump.DumpConsistencyManifest()
+		}
+		if err == nil {
+			err = dump.DumpCollectionStats()
+		}
 		if err != nil {
 			return fmt.Errorf("failed to dump top level metadata: %v", err)
 		}
@@ -549,16 +669,49 @@ func (dump *MongoDump) getResettableOutputBuffer() resettableOutputBuffer {
 func (dump *MongoDump) DumpIntents() error {
 	resultChan := make(chan error)
 
+	// the prioritizers need the collections to dump, which Finalize releases
+	collections := dump.manager.NormalIntents()
 	jobs := dump.OutputOptions.NumParallelCollections
 	if numIntents := len(dump.manager.Intents()); jobs > numIntents {
 		jobs = numIntents
 	}
+	if dump.contiguousArchive() && jobs > 1 {
+		log.Logvf(
+			log.DebugLow,
+			"dumping one collection at a time for --archiveLayout=%v",
+			archive.ContiguousLayout,
+		)
+		jobs = 1
+	}
 
 	if jobs > 1 {
 		dump.manager.Finalize(intents.LongestTaskFirst)
 	} else {
 		dump.manager.Finalize(intents.Legacy)
 	}
+	if dump.tuning != nil && jobs > 1 {
+		dump.manager.UsePrioritizer(newTuningPrioritizer(dump.manager.Prioritizer(), dump.tuning))
+	}
+	if databases := dump.OutputOptions.NumParallelDatabases; databases > 0 && jobs > 1 {
+		dump.manager.UsePrioritizer(newDatabasePrioritizer(
+			dump.manager.Prioritizer(),
+			collections,
+			databases,
+			jobs,
+		))
+		log.Logvf(log.Info, "dumping the collections of up to %v databases at a time", databases)
+	}
+	if dump.OutputOptions.BandwidthScheduling && jobs > 1 {
+		scheduler := newBandwidthScheduler(
+			dump.manager.Prioritizer(),
+			dump.OutputOptions.LargeCollectionBytes,
+			jobs,
+			&dump.bytesDumped,
+		)
+		dump.manager.UsePrioritizer(scheduler)
+		scheduler.start()
+		defer scheduler.stop()
+	}
 
 	log.Logvf(log.Info, "dumping up to %v collections in parallel", jobs)
 
@@ -602,16 +755,17 @@ func (dump *MongoDump) DumpIntents() error {
 
 // DumpIntent dumps the specified database's collection.
 func (dump *MongoDump) DumpIntent(intent *intents.Intent, buffer resettableOutputBuffer) error {
-	session, err := dump.SessionProvider.GetSession()
+	source, err := dump.readSourceFor(intent.Namespace())
 	if err != nil {
 		return err
 	}
-	intendedDB := session.Database(intent.DB)
+	session := source.client
+	intendedDB := session.Database(intent.DB, mopt.Database().SetReadPreference(source.readPref))
 	var coll *mongo.Collection
 	if intent.IsTimeseries() {
-		coll = intendedDB.Collection("system.buckets." + intent.C)
+		coll = intendedDB.Collection("system.buckets."+intent.C, dump.collectionOptions(intent))
 	} else {
-		coll = intendedDB.Collection(intent.C)
+		coll = intendedDB.Collection(intent.C, dump.collectionOptions(intent))
 	}
 
 	// it is safer to assume that a collection is a view, if we cannot determine that it is not.
@@ -648,7 +802,15 @@ func (dump *MongoDump) DumpIntent(intent *intents.Intent, buffer resettableOutpu
 		}
 	}
 
-	findQuery := &db.DeferredQuery{Coll: coll}
+	// reading in a causally consistent session gives the times to record in
+	// consistency.json
+	querySession, err := session.StartSession(mopt.Session().SetCausalConsistency(true))
+	if err != nil {
+		return err
+	}
+	defer querySession.EndSession(context.Background())
+
+	findQuery := &db.DeferredQuery{Coll: coll, Session: querySession}
 	switch {
 	case len(dump.query) > 0:
 		if intent.IsTimeseries() {
@@ -691,6 +853,31 @@ func (dump *MongoDump) DumpIntent(intent *intents.Intent, buffer resettableOutpu
 		if err != nil || autoIndexId == true {
 			findQuery.Hint = bson.D{{"_id", 1}}
 		}
+	// deployments behind the Atlas proxy may throttle us partway through a collection. Walking
+	// the _id index lets us resume from the last document written rather than fail the dump.
+	case dump.isAtlasProxy && dump.InputOptions.MaxRateLimitRetries > 0 &&
+		!dump.InputOptions.TableScan && !isView && !intent.IsSpecialCollection() &&
+		!intent.IsOplog() && !intent.IsTimeseries():
+		findQuery.Hint = bson.D{{Key: "_id", Value: 1}}
+	}
+
+	window, err := dump.timeWindowFilter(intent, coll)
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		findQuery.Filter = andFilters(dump.query, window)
+	}
+
+	if nt := dump.tuning.ForNamespace(intent.Namespace()); nt != nil && nt.BatchSize > 0 {
+		log.Logvf(
+			log.DebugLow,
+			"reading %v in batches of %v documents for %v",
+			intent.Namespace(),
+			nt.BatchSize,
+			nt.Name,
+		)
+		findQuery.BatchSize = int32(nt.BatchSize)
 	}
 
 	var dumpCount int64
@@ -709,6 +896,9 @@ func (dump *MongoDump) DumpIntent(intent *intents.Intent, buffer resettableOutpu
 	if dumpCount, err = dump.dumpQueryToIntent(findQuery, intent, buffer); err != nil {
 		return err
 	}
+	if !intent.IsView() || dump.OutputOptions.ViewsAsCollections {
+		dump.recordConsistency(intent, querySession)
+	}
 
 	log.Logvf(
 		log.Always,
@@ -738,7 +928,7 @@ func (dump *MongoDump) dumpQueryToIntent(
 // getCount counts the number of documents in the namespace for the given intent. It does not run the count for
 // the oplog collection to avoid the performance issue in TOOLS-2068.
 func (dump *MongoDump) getCount(query *db.DeferredQuery, intent *intents.Intent) (int64, error) {
-	if len(dump.query) != 0 || intent.IsOplog() {
+	if len(dump.query) != 0 || query.Filter != nil || intent.IsOplog() {
 		log.Logvf(log.DebugLow, "not counting query on %v", intent.Namespace())
 		return 0, nil
 	}
@@ -777,6 +967,13 @@ func (dump *MongoDump) dumpValidatedQueryToIntent(
 	buffer resettableOutputBuffer,
 	validator documentValidator,
 ) (dumpCount int64, err error) {
+	namespace := telemetry.String("db.namespace", intent.Namespace())
+	span := telemetry.Start("mongodump.dumpCollection", namespace)
+	defer func() {
+		span.SetAttributes(telemetry.Int("documents", dumpCount))
+		span.End(err)
+		telemetry.Add("mongodump.documents", dumpCount, namespace)
+	}()
 
 	// restore of views from archives require an empty collection as the trigger to create the view
 	// so, we open here before the early return if IsView so that we write an empty collection to the archive
@@ -827,15 +1024,47 @@ func (dump *MongoDump) dumpValidatedQueryToIntent(
 		}()
 	}
 
-	cursor, err := query.Iter()
-	if err != nil {
-		return
+	var tracker *idTracker
+	if hintsIDIndex(query.Hint) {
+		tracker = &idTracker{}
+	}
+	backoff := newRateLimitBackoff(dump.InputOptions.MaxRateLimitRetries)
+	for {
+		var cursor *mongo.Cursor
+		cursor, err = query.Iter()
+		if err == nil {
+			err = dump.dumpValidatedIterToWriter(cursor, f, dumpProgressor, validator, tracker)
+		}
+		if !db.IsRateLimitError(err) {
+			break
+		}
+
+		// without a known ordering we cannot tell which documents have been written already
+		written, _ := dumpProgressor.Progress()
+		if written > 0 && tracker == nil {
+			break
+		}
+		delay, ok := backoff.next(query)
+		if !ok {
+			break
+		}
+		if written > 0 {
+			tracker.resume(query)
+		}
+		log.Logvf(
+			log.Always,
+			"rate limited while dumping %v, retrying in %v with batch size %v: %v",
+			intent.DataNamespace(),
+			delay.Round(time.Millisecond),
+			query.BatchSize,
+			err,
+		)
+		time.Sleep(delay)
 	}
-	err = dump.dumpValidatedIterToWriter(cursor, f, dumpProgressor, validator)
 	dumpCount, _ = dumpProgressor.Progress()
 	if err != nil {
 		err = fmt.Errorf(
-			"error writing data for collection `%v` to disk: %v",
+			"error writing data for collection `%v` to disk: %w",
 			intent.Namespace(),
 			err,
 		)
@@ -844,12 +1073,14 @@ func (dump *MongoDump) dumpValidatedQueryToIntent(
 }
 
 // dumpValidatedIterToWriter takes a cursor, a writer, an Updateable object, and a documentValidator and validates and
-// dumps the iterator's contents to the writer.
+// dumps the iterator's contents to the writer. If tracker is non-nil, it is updated with the _id of each document
+// written.
 func (dump *MongoDump) dumpValidatedIterToWriter(
 	iter *mongo.Cursor,
 	writer io.Writer,
 	progressCount progress.Updateable,
 	validator documentValidator,
+	tracker *idTracker,
 ) error {
 	defer iter.Close(context.Background())
 	var termErr error
@@ -897,14 +1128,25 @@ func (dump *MongoDump) dumpValidatedIterToWriter(
 		buff, alive := <-buffChan
 		if !alive {
 			if iter.Err() != nil {
-				return fmt.Errorf("error reading collection: %v", iter.Err())
+				return fmt.Errorf("error reading collection: %w", iter.Err())
 			}
 			break
 		}
+		if tracker != nil {
+			id := bson.Raw(buff).Lookup("_id")
+			if tracker.skipLastID {
+				tracker.skipLastID = false
+				if id.Equal(tracker.lastID) {
+					continue
+				}
+			}
+			tracker.lastID = id
+		}
 		_, err := writer.Write(buff)
 		if err != nil {
 			return fmt.Errorf("error writing to file: %v", err)
 		}
+		dump.bytesDumped.Add(int64(len(buff)))
 		progressCount.Inc(1)
 	}
 	return termErr
@@ -978,23 +1220,62 @@ func (dump *MongoDump) DumpUsersAndRoles() error {
 
 // DumpMetadata dumps the metadata for each intent in the manager
 // that has metadata.
+// Reading the indexes takes a round trip for each collection, so the intents
+// are dumped by a pool of workers.
 func (dump *MongoDump) DumpMetadata() error {
-	allIntents := dump.manager.Intents()
-	buffer := dump.getResettableOutputBuffer()
-	for _, intent := range allIntents {
-		if intent.MetadataFile != nil {
-			err := dump.dumpMetadata(intent, buffer)
-			if err != nil {
-				return err
+	work := make(chan *intents.Intent)
+	failed := make(chan struct{})
+	resultChan := make(chan error)
+
+	jobs := dump.metadataJobs()
+	for i := 0; i < jobs; i++ {
+		go func() {
+			buffer := dump.getResettableOutputBuffer()
+			for intent := range work {
+				if err := dump.dumpMetadata(intent, buffer); err != nil {
+					resultChan <- err
+					return
+				}
 			}
+			resultChan <- nil
+		}()
+	}
+
+	go func() {
+		defer close(work)
+		for _, intent := range dump.manager.Intents() {
+			if intent.MetadataFile == nil {
+				continue
+			}
+			select {
+			case work <- intent:
+			case <-failed:
+				return
+			}
+		}
+	}()
+
+	// wait until all workers are done, and return the first error
+	var firstErr error
+	for i := 0; i < jobs; i++ {
+		if err := <-resultChan; err != nil && firstErr == nil {
+			firstErr = err
+			close(failed)
 		}
 	}
-	return nil
+	return firstErr
 }
 
 type PreludeData struct {
 	ServerVersion string `json:"ServerVersion"`
 	ToolVersion   string `json:"ToolVersion"`
+
+	// The timestamps of the first and last captured oplog entries with
+	// --oplog, in the <seconds>:<ordinal> form accepted by mongorestore's
+	// --oplogLimit. mongorestore reads this file as a map of strings, so
+	// these must stay strings.
+	OplogStart string `json:"OplogStart,omitempty"`
+	OplogEnd   string `json:"OplogEnd,omitempty"`
 }
 
 // DumpPreludeMetadata dumps information about the server and the dump in json format
@@ -1004,8 +1285,16 @@ func (dump *MongoDump) DumpPreludeMetadata() error {
 		ServerVersion: dump.serverVersion,
 		ToolVersion:   dump.ToolOptions.VersionStr,
 	}
+	if dump.OutputOptions.Oplog {
+		preludeData.OplogStart = formatOplogTimestamp(dump.oplogStart)
+		preludeData.OplogEnd = formatOplogTimestamp(dump.oplogEnd)
+	}
+	return dump.writeTopLevelJSON("prelude.json", preludeData)
+}
 
-	filename := "prelude.json"
+// writeTopLevelJSON writes data as JSON to the file with the name at the top
+// of the dump directory, or of the directory of the dumped database.
+func (dump *MongoDump) writeTopLevelJSON(filename string, data interface{}) error {
 
 	if dump.ToolOptions.Namespace.DB != "" {
 		filename = filepath.Join(dump.ToolOptions.Namespace.DB, filename)
@@ -1019,11 +1308,11 @@ func (dump *MongoDump) DumpPreludeMetadata() error {
 		filename += ".gz"
 	}
 
-	log.Logvf(log.DebugLow, "dumping prelude metadata to file %#q", filename)
+	log.Logvf(log.DebugLow, "dumping top level metadata to file %#q", filename)
 
 	file, err := os.Create(filename)
 	if errors.Is(err, os.ErrNotExist) {
-		// if parent directory doesn't exist, there was no data to dump, don't write prelude.json
+		// if parent directory doesn't exist, there was no data to dump, don't write the file
 		log.Logvf(log.DebugLow, "parent directory does not exist, not writing %#q", filename)
 		return nil
 	} else if err != nil {
@@ -1036,14 +1325,14 @@ func (dump *MongoDump) DumpPreludeMetadata() error {
 		writer = gzip.NewWriter(file)
 		defer writer.Close()
 	}
-	bytes, err := json.Marshal(preludeData)
+	bytes, err := json.Marshal(data)
 	if err != nil {
-		return fmt.Errorf("error marshaling prelude data: %w", err)
+		return fmt.Errorf("error marshaling %#q: %w", filename, err)
 	}
 
 	_, err = writer.Write(bytes)
 	if err != nil {
-		return fmt.Errorf("failed to write prelude metadata to file %#q: %w", filename, err)
+		return fmt.Errorf("failed to write top level metadata to file %#q: %w", filename, err)
 	}
 
 	return nil
@@ -1062,6 +1351,11 @@ func (*nopCloseWriter) Close() error {
 func (dump *MongoDump) getArchiveOut() (out io.WriteCloser, err error) {
 	if dump.OutputOptions.Archive == "-" {
 		out = &nopCloseWriter{dump.OutputWriter}
+	} else if archive.IsStreamEndpoint(dump.OutputOptions.Archive) {
+		out, err = archive.OpenStreamWriter(dump.OutputOptions.Archive)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		targetStat, err := os.Stat(dump.OutputOptions.Archive)
 		if err == nil && targetStat.IsDir() {
@@ -1087,6 +1381,178 @@ func (dump *MongoDump) getArchiveOut() (out io.WriteCloser, err error) {
 	return out, nil
 }
 
+// startArchiveWriter returns an archive.Writer for out whose multiplexer is
+// running.
+func (dump *MongoDump) startArchiveWriter(out io.WriteCloser) *archive.Writer {
+	writer := &archive.Writer{
+		// The archive.Writer needs its own copy of out because things
+		// like the prelude are not written by the multiplexer.
+		Out: out,
+		Mux: archive.NewMultiplexer(out, dump.shutdownIntentsNotifier),
+	}
+	writer.Mux.Contiguous = dump.contiguousArchive()
+	writer.Mux.Checksums = dump.OutputOptions.ArchiveChecksums
+	// the end of the captured oplog is recorded in the prelude of a file
+	writer.Mux.LeaveOpen = dump.OutputOptions.Oplog && writer.Seekable()
+	go writer.Mux.Run()
+	return writer
+}
+
+// stopArchiveWriters waits for the multiplexers of all archives to finish and
+// closes the archives, returning the first error encountered.
+func (dump *MongoDump) stopArchiveWriters() error {
+	writers := []*archive.Writer{}
+	if dump.archive != nil {
+		writers = append(writers, dump.archive)
+	}
+	for _, writer := range dump.dbArchives {
+		writers = append(writers, writer)
+	}
+
+	var firstErr error
+	for _, writer := range writers {
+		// The Mux runs until its Control is closed
+		close(writer.Mux.Control)
+		muxErr := <-writer.Mux.Completed
+		if muxErr == nil && writer.Prelude != nil && (writer.Mux.Contiguous || writer.Mux.LeaveOpen) {
+			muxErr = writer.RewritePrelude()
+		}
+		if muxErr == nil && writer.Mux.Checksums && writer.Prelude != nil {
+			muxErr = writer.WriteTrailer()
+		}
+		writer.Out.Close()
+		if firstErr == nil {
+			firstErr = muxErr
+		}
+	}
+	return firstErr
+}
+
+// dbArchivePath returns the path of the archive for dbName with --archivePerDB.
+func (dump *MongoDump) dbArchivePath(dbName string) string {
+	return nameGz(
+		dump.OutputOptions.Gzip,
+		filepath.Join(dump.OutputOptions.Archive, dbName+".archive"),
+	)
+}
+
+// archiveFor returns the archive that the intents of dbName are written to.
+// With --archivePerDB, the archive is created the first time it is needed.
+func (dump *MongoDump) archiveFor(dbName string) (*archive.Writer, error) {
+	if dump.dbArchives == nil {
+		return dump.archive, nil
+	}
+	if writer, ok := dump.dbArchives[dbName]; ok {
+		return writer, nil
+	}
+
+	path := dump.dbArchivePath(dbName)
+	var out io.WriteCloser
+	out, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating archive for database %v: %v", dbName, err)
+	}
+	if dump.OutputOptions.Gzip {
+		out = &util.WrappedWriteCloser{gzip.NewWriter(out), out}
+	}
+	log.Logvf(log.DebugLow, "writing database %v to archive '%v'", dbName, path)
+	dump.dbArchives[dbName] = dump.startArchiveWriter(out)
+	return dump.dbArchives[dbName], nil
+}
+
+// archiveLocation describes where the intents of dbName are written to in
+// archive mode.
+func (dump *MongoDump) archiveLocation(dbName string) string {
+	switch {
+	case dump.OutputOptions.Archive == "-":
+		return "archive on stdout"
+	case dump.dbArchives != nil:
+		return fmt.Sprintf("archive '%v'", dump.dbArchivePath(dbName))
+	default:
+		return fmt.Sprintf("archive '%v'", dump.OutputOptions.Archive)
+	}
+}
+
+// writeArchivePreludes writes the prelude of each archive, which lists the
+// namespaces, and their metadata, that the archive contains.
+func (dump *MongoDump) writeArchivePreludes() error {
+	allIntents := dump.manager.Intents()
+	if dump.dbArchives == nil {
+		return dump.writeArchivePrelude(dump.archive, allIntents)
+	}
+
+	intentsByDB := map[string][]*intents.Intent{}
+	for _, intent := range allIntents {
+		intentsByDB[intent.DB] = append(intentsByDB[intent.DB], intent)
+	}
+	for dbName, dbIntents := range intentsByDB {
+		writer, err := dump.archiveFor(dbName)
+		if err != nil {
+			return err
+		}
+		if err := dump.writeArchivePrelude(writer, dbIntents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dump *MongoDump) writeArchivePrelude(
+	writer *archive.Writer,
+	archiveIntents []*intents.Intent,
+) error {
+	var err error
+	concurrentColls := dump.OutputOptions.NumParallelCollections
+	if dump.contiguousArchive() {
+		concurrentColls = 1
+	}
+	writer.Prelude, err = archive.NewPreludeForIntents(
+		archiveIntents,
+		concurrentColls,
+		dump.serverVersion,
+		dump.ToolOptions.VersionStr,
+	)
+	if err != nil {
+		return fmt.Errorf("creating archive prelude: %v", err)
+	}
+	if dump.OutputOptions.ArchiveLayout != "" {
+		writer.Prelude.SetLayout(dump.OutputOptions.ArchiveLayout)
+	}
+	if dump.OutputOptions.ArchiveChecksums {
+		writer.Prelude.Header.Checksum = archive.CRC32CChecksum
+	}
+	if dump.OutputOptions.Oplog {
+		// the end is filled in by recordOplogEnd
+		writer.Prelude.Header.Oplog = &archive.OplogRange{Start: dump.oplogStart}
+		if !writer.Seekable() {
+			log.Logvf(
+				log.Info,
+				"the archive is not a file, so only the start of the captured oplog is recorded in it",
+			)
+		}
+	}
+	err = writer.Prelude.Write(writer.Out)
+	if err != nil {
+		return fmt.Errorf("error writing metadata into archive: %v", err)
+	}
+	return nil
+}
+
+// recordOplogEnd sets the end of the captured oplog in the prelude of the
+// archive, if it is a file, to be written when the archive is finished.
+func (dump *MongoDump) recordOplogEnd() {
+	if dump.archive == nil || !dump.archive.Mux.LeaveOpen {
+		return
+	}
+	dump.archive.Prelude.Header.Oplog.End = dump.oplogEnd
+}
+
+// contiguousArchive returns true if the archive is written with the
+// contiguous layout.
+func (dump *MongoDump) contiguousArchive() bool {
+	return dump.OutputOptions.ArchiveLayout == archive.ContiguousLayout
+}
+
 // docPlural returns "document" or "documents" depending on the
 // count of documents passed in.
 func docPlural(count int64) string {
@@ -1094,7 +1560,18 @@ func docPlural(count int64) string {
 }
 
 func (dump *MongoDump) HandleInterrupt() {
+	dump.interruptNotifier().Notify()
 	if dump.shutdownIntentsNotifier != nil {
 		dump.shutdownIntentsNotifier.Notify()
 	}
 }
+
+// interruptNotifier returns the notifier that HandleInterrupt notifies.
+func (dump *MongoDump) interruptNotifier() *notifier {
+	dump.interruptMu.Lock()
+	defer dump.interruptMu.Unlock()
+	if dump.interrupted == nil {
+		dump.interrupted = newNotifier()
+	}
+	return dump.interrupted
+}
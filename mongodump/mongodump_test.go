@@ -26,6 +26,7 @@ import (
 	"github.com/mongodb/mongo-tools/common/bsonutil"
 	"github.com/mongodb/mongo-tools/common/db"
 	"github.com/mongodb/mongo-tools/common/failpoint"
+	"github.com/mongodb/mongo-tools/common/intents"
 	"github.com/mongodb/mongo-tools/common/json"
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/options"
@@ -689,9 +690,176 @@ func TestMongoDumpValidateOptions(t *testing.T) {
 			)
 		})
 
+		Convey("we have to dump to an archive directory with --archivePerDB", func() {
+			md.OutputOptions.ArchivePerDB = true
+
+			err := md.ValidateOptions()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "--archivePerDB requires --archive")
+
+			md.OutputOptions.Archive = "-"
+			err = md.ValidateOptions()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "cannot write archives to stdout")
+		})
+
+		Convey("we have to capture the oplog with --requireOplogWindow", func() {
+			md.OutputOptions.RequireOplogWindow = 3600
+
+			err := md.ValidateOptions()
+			So(err, ShouldNotBeNil)
+			So(
+				err.Error(),
+				ShouldContainSubstring,
+				"cannot use --requireOplogWindow without --oplog",
+			)
+		})
+
+		Convey("we have to dump to an archive file with --archiveLayout=contiguous", func() {
+			md.OutputOptions.ArchiveLayout = archive.ContiguousLayout
+
+			err := md.ValidateOptions()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "requires --archive=<file-path>")
+
+			md.OutputOptions.Archive = "-"
+			err = md.ValidateOptions()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "requires --archive=<file-path>")
+
+			md.OutputOptions.Archive = "dump.archive"
+			md.OutputOptions.Gzip = true
+			err = md.ValidateOptions()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "cannot be used with --gzip")
+		})
+
 	})
 }
 
+func TestArchivePerDB(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	archiveDir := t.TempDir()
+	md := &MongoDump{
+		ToolOptions: &options.ToolOptions{},
+		OutputOptions: &OutputOptions{
+			Archive:                archiveDir,
+			ArchivePerDB:           true,
+			NumParallelCollections: 1,
+		},
+		manager:                 intents.NewIntentManager(),
+		shutdownIntentsNotifier: newNotifier(),
+		dbArchives:              map[string]*archive.Writer{},
+	}
+
+	namespaces := [][2]string{{"db1", "a"}, {"db1", "b"}, {"db2", "c"}}
+	for _, namespace := range namespaces {
+		intent := &intents.Intent{DB: namespace[0], C: namespace[1]}
+		writer, err := md.archiveFor(intent.DB)
+		require.NoError(t, err)
+		intent.BSONFile = &archive.MuxIn{Intent: intent, Mux: writer.Mux}
+		md.manager.Put(intent)
+	}
+	require.NoError(t, md.writeArchivePreludes())
+
+	for _, intent := range md.manager.Intents() {
+		doc, err := bson.Marshal(bson.D{{"ns", intent.Namespace()}})
+		require.NoError(t, err)
+		require.NoError(t, intent.BSONFile.Open())
+		_, err = intent.BSONFile.Write(doc)
+		require.NoError(t, err)
+		require.NoError(t, intent.BSONFile.Close())
+	}
+	require.NoError(t, md.stopArchiveWriters())
+
+	expected := map[string][]string{"db1": {"a", "b"}, "db2": {"c"}}
+	for dbName, collections := range expected {
+		file, err := os.Open(filepath.Join(archiveDir, dbName+".archive"))
+		require.NoError(t, err)
+		defer file.Close()
+
+		prelude := &archive.Prelude{}
+		require.NoError(t, prelude.Read(file))
+		assert.Equal(t, []string{dbName}, prelude.DBS)
+		var got []string
+		for _, cm := range prelude.NamespaceMetadatasByDB[dbName] {
+			got = append(got, cm.Collection)
+		}
+		assert.ElementsMatch(t, collections, got)
+	}
+}
+
+func TestArchiveOplogRange(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	dumpArchive := func(t *testing.T, md *MongoDump) {
+		md.ToolOptions = &options.ToolOptions{}
+		md.OutputOptions.Oplog = true
+		md.OutputOptions.NumParallelCollections = 1
+		md.manager = intents.NewIntentManager()
+		md.shutdownIntentsNotifier = newNotifier()
+		md.oplogStart = primitive.Timestamp{T: 100, I: 2}
+
+		out, err := md.getArchiveOut()
+		require.NoError(t, err)
+		md.archive = md.startArchiveWriter(out)
+		intent := &intents.Intent{DB: "db", C: "c"}
+		intent.BSONFile = &archive.MuxIn{Intent: intent, Mux: md.archive.Mux}
+		md.manager.Put(intent)
+		require.NoError(t, md.writeArchivePreludes())
+
+		doc, err := bson.Marshal(bson.D{{"a", 1}})
+		require.NoError(t, err)
+		require.NoError(t, intent.BSONFile.Open())
+		_, err = intent.BSONFile.Write(doc)
+		require.NoError(t, err)
+		require.NoError(t, intent.BSONFile.Close())
+
+		md.oplogEnd = primitive.Timestamp{T: 200, I: 1}
+		md.recordOplogEnd()
+		require.NoError(t, md.stopArchiveWriters())
+	}
+
+	for _, checksums := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "dump.archive")
+		dumpArchive(t, &MongoDump{OutputOptions: &OutputOptions{
+			Archive:          path,
+			ArchiveChecksums: checksums,
+		}})
+
+		file, err := os.Open(path)
+		require.NoError(t, err)
+		defer file.Close()
+		prelude := &archive.Prelude{}
+		require.NoError(t, prelude.Read(file))
+		assert.Equal(t, &archive.OplogRange{
+			Start: primitive.Timestamp{T: 100, I: 2},
+			End:   primitive.Timestamp{T: 200, I: 1},
+		}, prelude.Header.Oplog)
+
+		if checksums {
+			_, err = file.Seek(0, io.SeekStart)
+			require.NoError(t, err)
+			report, err := archive.Check(file)
+			require.NoError(t, err)
+			assert.True(t, report.Checksums)
+			assert.Empty(t, report.Problems)
+		}
+	}
+
+	// the end of the oplog is only known once a stream is written
+	out := &bytes.Buffer{}
+	dumpArchive(t, &MongoDump{OutputOptions: &OutputOptions{Archive: "-"}, OutputWriter: out})
+	prelude := &archive.Prelude{}
+	require.NoError(t, prelude.Read(out))
+	assert.Equal(
+		t,
+		&archive.OplogRange{Start: primitive.Timestamp{T: 100, I: 2}},
+		prelude.Header.Oplog,
+	)
+}
+
 func TestMongoDumpConnectedToAtlasProxy(t *testing.T) {
 	testtype.SkipUnlessTestType(t, testtype.IntegrationTestType)
 	log.SetWriter(io.Discard)
@@ -1692,6 +1860,47 @@ func TestMongoDumpViews(t *testing.T) {
 	})
 }
 
+func TestMongoDumpIncludeSystemCollectionsIntents(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.IntegrationTestType)
+	log.SetWriter(io.Discard)
+
+	Convey("With a database that has a profile and a view", t, func() {
+		So(setUpMongoDumpTestData(), ShouldBeNil)
+		So(setUpDBView(testDB, testCollectionNames[0]), ShouldBeNil)
+		So(turnOnProfiling(testDB), ShouldBeNil)
+		session, err := testutil.GetBareSession()
+		So(err, ShouldBeNil)
+		_, err = session.Database(testDB).
+			Collection(testCollectionNames[0]).
+			CountDocuments(context.Background(), bson.D{})
+		So(err, ShouldBeNil)
+
+		intentsOf := func(includeSystem bool) *intents.Manager {
+			md := simpleMongoDumpInstance()
+			md.OutputOptions.IncludeSystemCollections = includeSystem
+			So(md.Init(), ShouldBeNil)
+			md.manager = intents.NewIntentManager()
+			So(md.CreateIntentsForDatabase(testDB), ShouldBeNil)
+			return md.manager
+		}
+
+		Convey("system.profile is only dumped with --includeSystemCollections", func() {
+			So(intentsOf(false).IntentForNamespace(testDB+".system.profile"), ShouldBeNil)
+			manager := intentsOf(true)
+			So(manager.IntentForNamespace(testDB+".system.profile"), ShouldNotBeNil)
+			So(manager.IntentForNamespace(testDB+".test view"), ShouldNotBeNil)
+		})
+
+		Convey("system.views is never dumped as a collection", func() {
+			So(intentsOf(true).IntentForNamespace(testDB+".system.views"), ShouldBeNil)
+		})
+
+		Reset(func() {
+			So(tearDownMongoDumpTestData(), ShouldBeNil)
+		})
+	})
+}
+
 func TestMongoDumpCollectionOutputPath(t *testing.T) {
 	// Disabled: see TOOLS-2658
 	t.Skip()
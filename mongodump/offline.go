@@ -0,0 +1,302 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/util"
+)
+
+// Command line flags for dumping a data directory.
+const (
+	DBPathOption     = "--dbpath"
+	MongodPathOption = "--mongodPath"
+)
+
+const (
+	// offlineStartTimeout is how long the temporary mongod of a --dbpath
+	// dump has to start accepting connections.
+	offlineStartTimeout = 2 * time.Minute
+	// offlineStopTimeout is how long it has to shut down before it is killed.
+	offlineStopTimeout = 30 * time.Second
+	// offlineLogTailBytes is how much of its log is shown if it fails.
+	offlineLogTailBytes = 4096
+	// offlineStartAttempts is how many times it is started on a new port if
+	// something else took the free port before it could listen on it.
+	offlineStartAttempts = 3
+)
+
+// errOfflinePortInUse is returned by waitForConnections if the temporary
+// mongod could not listen on its port.
+var errOfflinePortInUse = errors.New("the port of the temporary mongod is already in use")
+
+// offlinePortInUseMessages are what mongod logs when its port is taken, on
+// Unix and on Windows.
+var offlinePortInUseMessages = [][]byte{
+	[]byte("Address already in use"),
+	[]byte("Only one usage of each socket address"),
+}
+
+// offlineMongod is a temporary mongod that serves a --dbpath dump. It opens
+// the data directory read-only, listens only on the loopback interface, and
+// keeps its log in a temporary directory, so the dump leaves no trace in the
+// data directory.
+type offlineMongod struct {
+	cmd     *exec.Cmd
+	tempDir string
+	port    int
+	exited  chan error
+}
+
+// validateOfflineOptions checks that a --dbpath dump doesn't also name a
+// server to connect to.
+func validateOfflineOptions(opts Options) error {
+	if opts.InputOptions.DBPath == "" {
+		if opts.InputOptions.MongodPath != "" {
+			return fmt.Errorf("cannot use %v without %v", MongodPathOption, DBPathOption)
+		}
+		return nil
+	}
+	connected := opts.URI != nil && opts.URI.ConnectionString != util.BuildURI("", "")
+	if connected || opts.Auth.Username != "" {
+		return fmt.Errorf(
+			"%v dumps a data directory without a running server, so it cannot be used "+
+				"with a connection string, --host, --port or credentials",
+			DBPathOption,
+		)
+	}
+	if opts.OutputOptions.Oplog {
+		return fmt.Errorf("cannot use --oplog with %v", DBPathOption)
+	}
+	return nil
+}
+
+// mongodArgs returns the arguments of the temporary mongod.
+func (m *offlineMongod) mongodArgs(dbPath string) []string {
+	return []string{
+		"--dbpath", dbPath,
+		"--queryableBackupMode",
+		"--bind_ip", "127.0.0.1",
+		"--port", strconv.Itoa(m.port),
+		"--nounixsocket",
+		"--logpath", m.logPath(),
+	}
+}
+
+func (m *offlineMongod) logPath() string {
+	return filepath.Join(m.tempDir, "mongod.log")
+}
+
+// startOfflineMongod starts a temporary mongod on the --dbpath data
+// directory, and points the connection options of the dump at it. If it
+// fails or is interrupted, the mongod is killed and its temporary directory
+// removed.
+func (dump *MongoDump) startOfflineMongod() (err error) {
+	dbPath := dump.InputOptions.DBPath
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return fmt.Errorf("error reading %v: %v", DBPathOption, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%v %v is not a directory", DBPathOption, dbPath)
+	}
+	mongodPath := dump.InputOptions.MongodPath
+	if mongodPath == "" {
+		mongodPath, err = exec.LookPath("mongod")
+		if err != nil {
+			return fmt.Errorf(
+				"%v requires a mongod binary; put one on the PATH or use %v: %v",
+				DBPathOption,
+				MongodPathOption,
+				err,
+			)
+		}
+	}
+
+	defer func() {
+		if err != nil {
+			dump.killOfflineMongod()
+		}
+	}()
+	interrupted := dump.interruptNotifier().notified
+	for attempt := 1; ; attempt++ {
+		if err = dump.launchOfflineMongod(mongodPath, dbPath); err != nil {
+			return err
+		}
+		err = dump.offline.waitForConnections(interrupted)
+		if !errors.Is(err, errOfflinePortInUse) || attempt == offlineStartAttempts {
+			break
+		}
+		log.Logvf(log.Always, "%v; starting it on another port", err)
+		dump.killOfflineMongod()
+	}
+	if err != nil {
+		return err
+	}
+
+	port := dump.offline.port
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	dump.ToolOptions.URI, err = options.NewURI("mongodb://" + address + "/")
+	if err != nil {
+		return err
+	}
+	dump.ToolOptions.Host = "127.0.0.1"
+	dump.ToolOptions.Port = strconv.Itoa(port)
+	dump.ToolOptions.Direct = true
+	return nil
+}
+
+// launchOfflineMongod starts a temporary mongod on dbPath, on a free port,
+// and sets it as the offline mongod of the dump.
+func (dump *MongoDump) launchOfflineMongod(mongodPath, dbPath string) error {
+	port, err := freeLoopbackPort()
+	if err != nil {
+		return err
+	}
+	tempDir, err := os.MkdirTemp("", "mongodump-dbpath-")
+	if err != nil {
+		return fmt.Errorf("error creating a directory for the temporary mongod: %v", err)
+	}
+	m := &offlineMongod{tempDir: tempDir, port: port, exited: make(chan error, 1)}
+	m.cmd = exec.Command(mongodPath, m.mongodArgs(dbPath)...)
+
+	log.Logvf(
+		log.Always,
+		"experimental: starting a temporary read-only mongod on port %v to dump %v",
+		port,
+		dbPath,
+	)
+	if err := m.cmd.Start(); err != nil {
+		_ = os.RemoveAll(tempDir)
+		return fmt.Errorf("error starting %v: %v", mongodPath, err)
+	}
+	go func() { m.exited <- m.cmd.Wait() }()
+	dump.offline = m
+	return nil
+}
+
+// waitForConnections waits until the temporary mongod accepts connections,
+// or until interrupted is closed. It returns errOfflinePortInUse if the
+// mongod exited because its port was taken.
+func (m *offlineMongod) waitForConnections(interrupted <-chan struct{}) error {
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(m.port))
+	deadline := time.Now().Add(offlineStartTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-m.exited:
+			m.exited <- err
+			logTail := m.logTail()
+			for _, message := range offlinePortInUseMessages {
+				if bytes.Contains(logTail, message) {
+					return fmt.Errorf("%w (port %v)", errOfflinePortInUse, m.port)
+				}
+			}
+			return fmt.Errorf(
+				"the temporary mongod exited before accepting connections (%v); its log ends with:\n%s",
+				err,
+				logTail,
+			)
+		case <-interrupted:
+			return fmt.Errorf("%w while starting the temporary mongod", util.ErrTerminated)
+		default:
+		}
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			_ = conn.Close()
+			return nil
+		}
+		select {
+		case <-interrupted:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return fmt.Errorf(
+		"the temporary mongod did not accept connections within %v; its log ends with:\n%s",
+		offlineStartTimeout,
+		m.logTail(),
+	)
+}
+
+// logTail returns the end of the log of the temporary mongod.
+func (m *offlineMongod) logTail() []byte {
+	content, err := os.ReadFile(m.logPath())
+	if err != nil {
+		return []byte(err.Error())
+	}
+	if len(content) > offlineLogTailBytes {
+		content = content[len(content)-offlineLogTailBytes:]
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			content = content[i+1:]
+		}
+	}
+	return content
+}
+
+// stopOfflineMongod shuts down the temporary mongod of a --dbpath dump, if
+// there is one, and removes its temporary directory.
+func (dump *MongoDump) stopOfflineMongod() {
+	m := dump.offline
+	if m == nil {
+		return
+	}
+	dump.offline = nil
+	defer os.RemoveAll(m.tempDir)
+
+	// Windows processes cannot be sent SIGTERM, so they are killed
+	if err := m.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		_ = m.cmd.Process.Kill()
+	}
+	select {
+	case <-m.exited:
+	case <-time.After(offlineStopTimeout):
+		log.Logvf(log.Always, "the temporary mongod did not shut down in %v; killing it",
+			offlineStopTimeout)
+		_ = m.cmd.Process.Kill()
+		<-m.exited
+	}
+	log.Logvf(log.Info, "stopped the temporary mongod")
+}
+
+// killOfflineMongod kills the temporary mongod of a --dbpath dump, if there
+// is one, and removes its temporary directory. It is used instead of
+// stopOfflineMongod when the mongod failed to start or the start was
+// interrupted, since a second interrupt exits right away; the mongod only
+// reads the data directory, so killing it is safe.
+func (dump *MongoDump) killOfflineMongod() {
+	m := dump.offline
+	if m == nil {
+		return
+	}
+	dump.offline = nil
+	_ = m.cmd.Process.Kill()
+	<-m.exited
+	_ = os.RemoveAll(m.tempDir)
+}
+
+// freeLoopbackPort returns a TCP port on the loopback interface that nothing
+// listens on. Something else may take it before the temporary mongod listens
+// on it, in which case startOfflineMongod retries on another port.
+func freeLoopbackPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("error finding a free port for the temporary mongod: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,144 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/mongodb/mongo-tools/common/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineOptions(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	_, err := ParseOptions([]string{"--dbpath", "/data/db"}, "", "")
+	assert.NoError(t, err)
+	_, err = ParseOptions([]string{"--dbpath", "/data/db", "--mongodPath", "/bin/mongod"}, "", "")
+	assert.NoError(t, err)
+
+	for _, args := range [][]string{
+		{"--dbpath", "/data/db", "--host", "localhost:27018"},
+		{"--dbpath", "/data/db", "--port", "27018"},
+		{"--dbpath", "/data/db", "mongodb://localhost:27018"},
+		{"--dbpath", "/data/db", "--username", "u", "--password", "p"},
+	} {
+		_, err := ParseOptions(args, "", "")
+		assert.ErrorContains(t, err, "--dbpath dumps a data directory without a running server")
+	}
+
+	_, err = ParseOptions([]string{"--dbpath", "/data/db", "--oplog"}, "", "")
+	assert.ErrorContains(t, err, "cannot use --oplog with --dbpath")
+	_, err = ParseOptions([]string{"--mongodPath", "/bin/mongod"}, "", "")
+	assert.ErrorContains(t, err, "cannot use --mongodPath without --dbpath")
+}
+
+func TestOfflineMongod(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	t.Run("the temporary mongod is read-only and local", func(t *testing.T) {
+		m := &offlineMongod{tempDir: "/tmp/x", port: 12345}
+		assert.Equal(
+			t,
+			[]string{
+				"--dbpath", "/data/db",
+				"--queryableBackupMode",
+				"--bind_ip", "127.0.0.1",
+				"--port", "12345",
+				"--nounixsocket",
+				"--logpath", filepath.Join("/tmp/x", "mongod.log"),
+			},
+			m.mongodArgs("/data/db"),
+		)
+	})
+
+	t.Run("a mongod that fails to start reports its log", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("the fake mongod is a shell script")
+		}
+		dir := t.TempDir()
+		mongod := filepath.Join(dir, "mongod")
+		script := "#!/bin/sh\n" +
+			"while [ $# -gt 0 ]; do\n" +
+			"  if [ \"$1\" = --logpath ]; then echo 'Unable to lock the data directory' > \"$2\"; fi\n" +
+			"  shift\n" +
+			"done\n" +
+			"exit 100\n"
+		require.NoError(t, os.WriteFile(mongod, []byte(script), 0o755))
+
+		dump := simpleMongoDumpInstance()
+		dump.InputOptions.DBPath = dir
+		dump.InputOptions.MongodPath = mongod
+		err := dump.Init()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exited before accepting connections")
+		assert.Contains(t, err.Error(), "Unable to lock the data directory")
+		assert.Nil(t, dump.offline)
+	})
+
+	t.Run("a mongod whose port is taken is started on another port", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("the fake mongod is a shell script")
+		}
+		dir := t.TempDir()
+		mongod := filepath.Join(dir, "mongod")
+		runs := filepath.Join(dir, "runs")
+		script := "#!/bin/sh\n" +
+			"echo run >> " + runs + "\n" +
+			"while [ $# -gt 0 ]; do\n" +
+			"  if [ \"$1\" = --logpath ]; then echo 'Address already in use' > \"$2\"; fi\n" +
+			"  shift\n" +
+			"done\n" +
+			"exit 48\n"
+		require.NoError(t, os.WriteFile(mongod, []byte(script), 0o755))
+
+		dump := simpleMongoDumpInstance()
+		dump.InputOptions.DBPath = dir
+		dump.InputOptions.MongodPath = mongod
+		err := dump.Init()
+		assert.ErrorIs(t, err, errOfflinePortInUse)
+		assert.Nil(t, dump.offline)
+		content, err := os.ReadFile(runs)
+		require.NoError(t, err)
+		assert.Equal(t, strings.Repeat("run\n", offlineStartAttempts), string(content))
+	})
+
+	t.Run("an interrupt stops the start and kills the mongod", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("the fake mongod is a shell script")
+		}
+		dir := t.TempDir()
+		mongod := filepath.Join(dir, "mongod")
+		require.NoError(t, os.WriteFile(mongod, []byte("#!/bin/sh\nexec sleep 60\n"), 0o755))
+
+		dump := simpleMongoDumpInstance()
+		dump.InputOptions.DBPath = dir
+		dump.InputOptions.MongodPath = mongod
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			dump.HandleInterrupt()
+		}()
+		started := time.Now()
+		err := dump.Init()
+		assert.ErrorIs(t, err, util.ErrTerminated)
+		assert.Less(t, time.Since(started), 10*time.Second)
+		assert.Nil(t, dump.offline)
+	})
+
+	t.Run("the data directory must exist", func(t *testing.T) {
+		dump := simpleMongoDumpInstance()
+		dump.InputOptions.DBPath = filepath.Join(t.TempDir(), "missing")
+		assert.ErrorContains(t, dump.Init(), "error reading --dbpath")
+	})
+}
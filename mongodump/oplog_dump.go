@@ -10,6 +10,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/mongodb/mongo-tools/common/db"
 	"github.com/mongodb/mongo-tools/common/log"
@@ -116,6 +117,23 @@ func (dump *MongoDump) getOplogCopyStartTime() (primitive.Timestamp, error) {
 // still in the database and making sure it happened at or before the timestamp
 // captured at the start of the dump.
 func (dump *MongoDump) checkOplogTimestampExists(ts primitive.Timestamp) (bool, error) {
+	oldest, err := dump.getOldestOplogTime()
+	if err != nil {
+		return false, err
+	}
+
+	log.Logvf(log.DebugHigh, "oldest oplog entry has timestamp %v", oldest)
+	if util.TimestampGreaterThan(oldest, ts) {
+		log.Logvf(log.Info, "oldest oplog entry of timestamp %v is newer than %v",
+			oldest, ts)
+		return false, nil
+	}
+	return true, nil
+}
+
+// getOldestOplogTime returns the timestamp of the oldest entry still in the
+// oplog.
+func (dump *MongoDump) getOldestOplogTime() (primitive.Timestamp, error) {
 	oldestOplogEntry := db.Oplog{}
 	var tempBSON bson.Raw
 
@@ -129,20 +147,51 @@ func (dump *MongoDump) checkOplogTimestampExists(ts primitive.Timestamp) (bool,
 		0,
 	)
 	if err != nil {
-		return false, fmt.Errorf("unable to read entry from oplog: %v", err)
+		return primitive.Timestamp{}, fmt.Errorf("unable to read entry from oplog: %v", err)
 	}
 	err = bson.Unmarshal(tempBSON, &oldestOplogEntry)
 	if err != nil {
-		return false, err
+		return primitive.Timestamp{}, err
 	}
+	return oldestOplogEntry.Timestamp, nil
+}
 
-	log.Logvf(log.DebugHigh, "oldest oplog entry has timestamp %v", oldestOplogEntry.Timestamp)
-	if util.TimestampGreaterThan(oldestOplogEntry.Timestamp, ts) {
-		log.Logvf(log.Info, "oldest oplog entry of timestamp %v is newer than %v",
-			oldestOplogEntry.Timestamp, ts)
-		return false, nil
+// checkOplogWindow checks how much history the oplog still keeps before
+// start, the first entry the dump needs, given the oldest entry in the oplog
+// and end, the last entry the dump needs. It fails if that is less than
+// --requireOplogWindow. Otherwise, if it is less than the time between start
+// and end, a dump taking twice as long would have found the oplog rolled over,
+// so it only warns.
+func (dump *MongoDump) checkOplogWindow(oldest, start, end primitive.Timestamp) error {
+	window := time.Duration(int64(start.T)-int64(oldest.T)) * time.Second
+	elapsed := time.Duration(int64(end.T)-int64(start.T)) * time.Second
+	log.Logvf(log.Info, "the oplog keeps %v of history before %v", window, formatOplogTimestamp(start))
+
+	required := time.Duration(dump.OutputOptions.RequireOplogWindow) * time.Second
+	if required > 0 && window < required {
+		return fmt.Errorf(
+			"oplog window too short: the oplog keeps %v of history before the start of the dump, "+
+				"less than the %v required by --requireOplogWindow",
+			window,
+			required,
+		)
 	}
-	return true, nil
+	if elapsed > 0 && window < elapsed {
+		log.Logvf(
+			log.Always,
+			"warning: the oplog keeps only %v of history before the start of the dump, "+
+				"which took %v; a longer dump may not be able to capture all oplog entries",
+			window,
+			elapsed,
+		)
+	}
+	return nil
+}
+
+// formatOplogTimestamp formats ts as <seconds>:<ordinal>, which is how
+// mongorestore's --oplogLimit takes timestamps.
+func formatOplogTimestamp(ts primitive.Timestamp) string {
+	return fmt.Sprintf("%v:%v", ts.T, ts.I)
 }
 
 func oplogDocumentValidator(in []byte) error {
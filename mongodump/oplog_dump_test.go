@@ -21,6 +21,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -37,6 +38,40 @@ func TestErrorOnImportCollection(t *testing.T) {
 	})
 }
 
+func TestCheckOplogWindow(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With a MongoDump capturing the oplog", t, func() {
+		md := simpleMongoDumpInstance()
+		md.OutputOptions.Oplog = true
+		ts := func(seconds uint32) primitive.Timestamp { return primitive.Timestamp{T: seconds} }
+		oldest := ts(1000)
+
+		Convey("any window is accepted without --requireOplogWindow", func() {
+			So(md.checkOplogWindow(oldest, ts(1010), ts(5000)), ShouldBeNil)
+		})
+
+		Convey("with --requireOplogWindow", func() {
+			md.OutputOptions.RequireOplogWindow = 3600
+
+			Convey("a long enough window is accepted", func() {
+				So(md.checkOplogWindow(oldest, ts(4600), ts(4600)), ShouldBeNil)
+			})
+
+			Convey("a short window fails the dump", func() {
+				err := md.checkOplogWindow(oldest, ts(4599), ts(4700))
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "oplog window too short")
+				So(err.Error(), ShouldContainSubstring, "59m59s")
+			})
+		})
+	})
+
+	Convey("Oplog timestamps are formatted for --oplogLimit", t, func() {
+		So(formatOplogTimestamp(primitive.Timestamp{T: 1700000000, I: 3}), ShouldEqual, "1700000000:3")
+	})
+}
+
 // TestOplogDumpVectoredInsertsOplog tests dumping oplogs that are from vectored inserts.
 // They have a special oplog format.
 func TestOplogDumpVectoredInsertsOplog(t *testing.T) {
@@ -28,8 +28,13 @@ type InputOptions struct {
 	Query                   string `long:"query" short:"q" description:"query filter, as a v2 Extended JSON string, e.g., '{\"x\":{\"$gt\":1}}'"`
 	QueryFile               string `long:"queryFile" description:"path to a file containing a query filter (v2 Extended JSON)"`
 	ReadPreference          string `long:"readPreference" value-name:"<string>|<json>" description:"specify either a preference mode (e.g. 'nearest') or a preference json object (e.g. '{mode: \"nearest\", tagSets: [{a: \"b\"}], maxStalenessSeconds: 123}')"`
+	ReadPreferenceFallback  string `long:"readPreferenceFallback" value-name:"<step>[,<step>]*" description:"read each namespace from the first available member of a chain of steps, checked again for every namespace, instead of failing when the preferred kind of member is missing, e.g. 'tag:workload=analytics,hidden,secondary,primary'. A step is a read preference mode, 'hidden' for the hidden members of the replica set, connected to directly, or 'tag:<name>=<value>[:<name>=<value>]*' for the secondaries with those tags. Cannot be used with --readPreference, --oplog or a mongos"`
+	TimeWindow              string `long:"timeWindow" value-name:"<start>/<end>" description:"only dump the documents of clustered and timeseries collections from start, included, to end, excluded, e.g. '2024-01-01/2024-02-01T12:00:00Z'. Either bound may be empty to leave that side open. The window is read as a range of the _ids, which must be ObjectIDs or dates, of clustered collections, and of the times of the buckets of timeseries collections, whose buckets with measurements in the window are dumped whole. Other collections are dumped whole"`
 	TableScan               bool   `long:"forceTableScan" description:"force a table scan (do not use $snapshot or hint _id). Deprecated since this is default behavior on WiredTiger"`
+	MaxRateLimitRetries     int    `long:"maxRateLimitRetries" value-name:"<count>" default:"10" default-mask:"-" description:"number of times to back off and retry a collection when the server reports that requests are being rate limited, e.g. on serverless or Atlas Flex instances; 0 disables retrying (default: 10)"`
 	SourceWritesDoneBarrier string `long:"internalOnlySourceWritesDoneBarrier" hidden:"true"`
+	DBPath                  string `long:"dbpath" value-name:"<directory-path>" description:"(experimental) dump the data directory of a server that is not running, e.g. for recovery or forensics, by starting a temporary read-only mongod (queryableBackupMode) on it that listens only on 127.0.0.1. The data directory is not modified, so it must have been shut down cleanly; copy a crashed server's directory and let a normal mongod recover it first"`
+	MongodPath              string `long:"mongodPath" value-name:"<file-path>" description:"path of the mongod binary to start for --dbpath; it must be able to read the data files (default: mongod on the PATH)"`
 }
 
 // Name returns a human-readable group name for input options.
@@ -59,12 +64,25 @@ type OutputOptions struct {
 	Out                        string   `long:"out" value-name:"<directory-path>" short:"o" description:"output directory, or '-' for stdout (default: 'dump')"`
 	Gzip                       bool     `long:"gzip" description:"compress archive or collection output with Gzip"`
 	Oplog                      bool     `long:"oplog" description:"for taking a point-in-time snapshot on a replica set that is not part of a sharded cluster."`
-	Archive                    string   `long:"archive" value-name:"<file-path>" optional:"true" optional-value:"-" description:"dump as an archive to the specified path. If flag is specified without a value, archive is written to stdout"`
+	RequireOplogWindow         int      `long:"requireOplogWindow" value-name:"<seconds>" description:"with --oplog, fail unless the source's oplog keeps at least this many seconds of history before the start of the dump, both before and after dumping"`
+	Archive                    string   `long:"archive" value-name:"<file-path>" optional:"true" optional-value:"-" description:"dump as an archive to the specified path, which may be a named pipe or a UNIX domain socket opened by another process. If flag is specified without a value, archive is written to stdout"`
+	ArchiveLayout              string   `long:"archiveLayout" value-name:"<layout>" choice:"interleaved" choice:"contiguous" default:"interleaved" description:"interleaved: mix the documents of collections dumped in parallel. contiguous: dump one collection at a time, so that restoring some of the collections of an archive file reads only their data. contiguous requires --archive=<file-path> and no --gzip"`
+	ArchiveChecksums           bool     `long:"archiveChecksums" description:"add a CRC-32C checksum to every block of the archive, and end it with a trailer of checksums of the whole archive, so that 'mongorestore --checkArchive' can verify it without a server. Such archives cannot be restored by versions of mongorestore without --checkArchive"`
+	ArchivePerDB               bool     `long:"archivePerDB" description:"with --archive=<directory-path>, write one archive per database to <database>.archive in that directory (<database>.archive.gz with --gzip)"`
 	DumpDBUsersAndRoles        bool     `long:"dumpDbUsersAndRoles" description:"dump user and role definitions for the specified database"`
 	ExcludedCollections        []string `long:"excludeCollection" value-name:"<collection-name>" description:"collection to exclude from the dump (may be specified multiple times to exclude additional collections)"`
 	ExcludedCollectionPrefixes []string `long:"excludeCollectionsWithPrefix" value-name:"<collection-prefix>" description:"exclude all collections from the dump that have the given prefix (may be specified multiple times to exclude additional prefixes)"`
+	SystemCollections          []string `long:"systemCollection" value-name:"<collection-name>" description:"also dump the system collection of this name, e.g. system.myapp, in every database other than admin and config, which are otherwise skipped except for system.js (may be specified multiple times)"`
+	IncludeSystemCollections   bool     `long:"includeSystemCollections" description:"also dump the namespaces that are skipped by default because the server rebuilds them or they only last for an operation: <db>.system.profile, <db>.tmp.agg_out.*, <db>.tmp.mr.*, config.cache.* and the local database. Without it, local and config.cache.* are still dumped when their database is named by --db, and system.profile when it is named by --systemCollection"`
 	NumParallelCollections     int      `long:"numParallelCollections" short:"j" description:"number of collections to dump in parallel" default:"4" default-mask:"-"`
+	NumParallelDatabases       int      `long:"numParallelDatabases" value-name:"<count>" description:"number of databases whose collections are dumped at the same time. The --numParallelCollections workers are split evenly between them, so that the large collections of one database do not delay the others"`
+	NumParallelMetadata        int      `long:"numParallelMetadata" value-name:"<count>" description:"number of collections whose document counts and indexes are read in parallel while preparing the dump, which speeds up dumping databases with many small collections (default: 16)"`
+	TuningFile                 string   `long:"tuningFile" value-name:"<file-path>" description:"path to a YAML file setting the cursor batch size and the number of collections dumped in parallel for namespace patterns, e.g. to read giant collections in large batches and latency-sensitive ones gently, or to read some collections with another read concern level"`
+	BandwidthScheduling        bool     `long:"bandwidthScheduling" description:"when dumping over a slow link, dump the small collections first, and only as many collections of at least --largeCollectionBytes at once as make the dump faster, measured as it runs, instead of --numParallelCollections of them"`
+	LargeCollectionBytes       int64    `long:"largeCollectionBytes" value-name:"<bytes>" description:"size in bytes from which --bandwidthScheduling counts a collection as large" default:"1073741824" default-mask:"-"`
 	ViewsAsCollections         bool     `long:"viewsAsCollections" description:"dump views as normal collections with their produced data, omitting standard collections"`
+	List                       bool     `long:"list" description:"print the namespaces that would be dumped with the other options, with their estimated number of documents, or the number that match --query, and their sizes, without dumping anything"`
+	CollectionStats            bool     `long:"collectionStats" description:"write stats.json next to prelude.json, with the document count, data, storage and index sizes of every dumped collection and, on a mongos, its shard key, number of chunks and share of each shard, e.g. to size the target of a restore. Only for dumps to a directory"`
 }
 
 // Name returns a human-readable group name for output options.
@@ -105,5 +123,9 @@ func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, erro
 		)
 	}
 
-	return Options{opts, inputOpts, outputOpts}, nil
+	parsed := Options{opts, inputOpts, outputOpts}
+	if err := validateOfflineOptions(parsed); err != nil {
+		return Options{}, err
+	}
+	return parsed, nil
 }
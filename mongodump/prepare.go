@@ -134,7 +134,15 @@ func (f *stdoutFile) Close() error {
 // If you set --db=config --collection=foo, then shouldSkipSystemNamespace() is
 // never hit since CreateCollectionIntent() is run directly. In this case
 // config.foo will be the olny collection dumped.
+// With --includeSystemCollections, the namespaces of defaultExclusions that
+// are system namespaces, <db>.system.profile and config.cache.*, are dumped.
+// system.views is never dumped as a collection, since the views it defines
+// are dumped with their own metadata.
 func (dump *MongoDump) shouldSkipSystemNamespace(dbName, collName string) bool {
+	if collName == "system.views" {
+		return true
+	}
+	includeSystem := dump.OutputOptions.IncludeSystemCollections
 	// ignore <db>.system.* except for admin; ignore other specific
 	// collections in config and admin databases used for 3.6 features.
 	switch dbName {
@@ -143,12 +151,13 @@ func (dump *MongoDump) shouldSkipSystemNamespace(dbName, collName string) bool {
 			return true
 		}
 	case "config":
-		if dump.ToolOptions.DB == "config" {
+		if dump.ToolOptions.DB == "config" || includeSystem && strings.HasPrefix(collName, "cache.") {
 			return false
 		}
 		return !slices.Contains(dumprestore.ConfigCollectionsToKeep, collName)
 	default:
-		if collName == "system.js" {
+		if includeSystem && collName == "system.profile" ||
+			dumprestore.IncludesSystemCollection(dump.OutputOptions.SystemCollections, collName) {
 			return false
 		}
 		if strings.HasPrefix(collName, "system.") {
@@ -257,9 +266,13 @@ func (dump *MongoDump) CreateUsersRolesVersionIntentsForDB(db string) error {
 		C:  "$admin.system.version",
 	}
 	if dump.OutputOptions.Archive != "" {
-		usersIntent.BSONFile = &archive.MuxIn{Intent: usersIntent, Mux: dump.archive.Mux}
-		rolesIntent.BSONFile = &archive.MuxIn{Intent: rolesIntent, Mux: dump.archive.Mux}
-		versionIntent.BSONFile = &archive.MuxIn{Intent: versionIntent, Mux: dump.archive.Mux}
+		writer, err := dump.archiveFor(db)
+		if err != nil {
+			return err
+		}
+		usersIntent.BSONFile = &archive.MuxIn{Intent: usersIntent, Mux: writer.Mux}
+		rolesIntent.BSONFile = &archive.MuxIn{Intent: rolesIntent, Mux: writer.Mux}
+		versionIntent.BSONFile = &archive.MuxIn{Intent: versionIntent, Mux: writer.Mux}
 	} else {
 		usersIntent.BSONFile = &realBSONFile{path: filepath.Join(outDir, nameGz(dump.OutputOptions.Gzip, "$admin.system.users.bson")), intent: usersIntent}
 		rolesIntent.BSONFile = &realBSONFile{path: filepath.Join(outDir, nameGz(dump.OutputOptions.Gzip, "$admin.system.roles.bson")), intent: rolesIntent}
@@ -315,19 +328,21 @@ func (dump *MongoDump) NewIntentFromOptions(
 	intent.UUID = ci.GetUUID()
 
 	// Setup output location
-	if dump.OutputOptions.Out == "-" { // regular standard output
+	if dump.OutputOptions.List {
+		// --list only prints the namespaces
+	} else if dump.OutputOptions.Out == "-" { // regular standard output
 		intent.BSONFile = &stdoutFile{Writer: dump.OutputWriter}
 	} else {
 		// Set the BSONFile path.
 		if dump.OutputOptions.Archive != "" {
 			// if archive mode, then the output should be written using an output
 			// muxer.
-			intent.BSONFile = &archive.MuxIn{Intent: intent, Mux: dump.archive.Mux}
-			if dump.OutputOptions.Archive == "-" {
-				intent.Location = "archive on stdout"
-			} else {
-				intent.Location = fmt.Sprintf("archive '%v'", dump.OutputOptions.Archive)
+			writer, err := dump.archiveFor(dbName)
+			if err != nil {
+				return nil, err
 			}
+			intent.BSONFile = &archive.MuxIn{Intent: intent, Mux: writer.Mux}
+			intent.Location = dump.archiveLocation(dbName)
 		} else if ci.IsTimeseries() {
 			path := nameGz(dump.OutputOptions.Gzip, dump.outputPath(dbName, "system.buckets."+ci.Name)+".bson")
 			intent.BSONFile = &realBSONFile{path: path, intent: intent}
@@ -397,6 +412,14 @@ func (dump *MongoDump) CreateIntentsForDatabase(dbName string) error {
 	}
 	defer colsIter.Close(context.Background())
 
+	builder := newIntentBuilder(
+		dump.metadataJobs(),
+		func(ci *db.CollectionInfo) (*intents.Intent, error) {
+			return dump.NewIntentFromOptions(dbName, ci)
+		},
+	)
+	defer builder.stop()
+
 	for colsIter.Next(context.TODO()) {
 		collInfo := &db.CollectionInfo{}
 		err = colsIter.Decode(collInfo)
@@ -422,6 +445,17 @@ func (dump *MongoDump) CreateIntentsForDatabase(dbName string) error {
 			continue
 		}
 
+		if reason := dump.defaultExclusionOf(dbName, collInfo.Name); reason != "" {
+			log.Logvf(
+				log.DebugLow,
+				"skipping dump of %v.%v (%v), use --includeSystemCollections to dump it",
+				dbName,
+				collInfo.Name,
+				reason,
+			)
+			continue
+		}
+
 		if dump.shouldSkipCollection(collInfo.Name) {
 			log.Logvf(log.DebugLow, "skipping dump of %v.%v, it is excluded", dbName, collInfo.Name)
 			continue
@@ -436,13 +470,29 @@ func (dump *MongoDump) CreateIntentsForDatabase(dbName string) error {
 			)
 			continue
 		}
-		intent, err := dump.NewIntentFromOptions(dbName, collInfo)
-		if err != nil {
-			return err
+		if dump.OutputOptions.Archive != "" && !dump.OutputOptions.List {
+			// with --archivePerDB, create the database's archive here so
+			// that the workers building intents only look it up
+			if _, err := dump.archiveFor(dbName); err != nil {
+				return err
+			}
 		}
+		if !builder.add(collInfo) {
+			break
+		}
+	}
+
+	dbIntents, err := builder.wait()
+	if err != nil {
+		return err
+	}
+	if err := colsIter.Err(); err != nil {
+		return err
+	}
+	for _, intent := range dbIntents {
 		dump.manager.Put(intent)
 	}
-	return colsIter.Err()
+	return nil
 }
 
 func (dump *MongoDump) GetValidDbs() ([]string, error) {
@@ -458,8 +508,8 @@ func (dump *MongoDump) GetValidDbs() ([]string, error) {
 	log.Logvf(log.DebugHigh, "found databases: %v", strings.Join(dbs, ", "))
 
 	for _, dbName := range dbs {
-		if dbName == "local" {
-			// local can only be explicitly dumped
+		if dump.excludesDatabase(dbName) {
+			// local can only be explicitly dumped, or with --includeSystemCollections
 			continue
 		}
 		if dbName == "admin" && dump.isAtlasProxy {
@@ -59,10 +59,11 @@ func TestSkipCollection(t *testing.T) {
 }
 
 type testTable struct {
-	db       string
-	coll     string
-	output   bool
-	dbOption string
+	db            string
+	coll          string
+	output        bool
+	dbOption      string
+	includeSystem bool
 }
 
 func TestShouldSkipSystemNamespace(t *testing.T) {
@@ -192,6 +193,32 @@ func TestShouldSkipSystemNamespace(t *testing.T) {
 		},
 	}
 
+	for _, includeSystem := range []bool{false, true} {
+		tests = append(tests,
+			testTable{
+				db:            "test",
+				coll:          "system.profile",
+				output:        !includeSystem,
+				includeSystem: includeSystem,
+			},
+			testTable{
+				db:            "config",
+				coll:          "cache.collections",
+				output:        !includeSystem,
+				includeSystem: includeSystem,
+			},
+			testTable{db: "test", coll: "system.nonsense", output: true, includeSystem: includeSystem},
+			testTable{db: "test", coll: "system.views", output: true, includeSystem: includeSystem},
+			testTable{
+				db:            "config",
+				coll:          "system.views",
+				output:        true,
+				dbOption:      "config",
+				includeSystem: includeSystem,
+			},
+		)
+	}
+
 	for _, collName := range dumprestore.ConfigCollectionsToKeep {
 		tests = append(tests, testTable{
 			db:     "config",
@@ -204,6 +231,7 @@ func TestShouldSkipSystemNamespace(t *testing.T) {
 
 	for _, testVals := range tests {
 		md.ToolOptions.DB = testVals.dbOption
+		md.OutputOptions.IncludeSystemCollections = testVals.includeSystem
 
 		if md.shouldSkipSystemNamespace(testVals.db, testVals.coll) != testVals.output {
 			t.Errorf(
@@ -0,0 +1,365 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/options"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/tag"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+// ReadPreferenceFallbackOption is the command line flag for
+// InputOptions.ReadPreferenceFallback.
+const ReadPreferenceFallbackOption = "--readPreferenceFallback"
+
+// Steps of --readPreferenceFallback, besides the read preference modes.
+const (
+	fallbackHidden    = "hidden"
+	fallbackTagPrefix = "tag:"
+)
+
+// fallbackHealthTimeout bounds how long a member of a step may take to be
+// found and to answer its health check.
+const fallbackHealthTimeout = 5 * time.Second
+
+// fallbackStep is a step of --readPreferenceFallback: either a read
+// preference, or the hidden members of the replica set, which no read
+// preference selects.
+type fallbackStep struct {
+	spec     string
+	readPref *readpref.ReadPref
+	hidden   bool
+}
+
+// readSource is where a namespace is read from.
+type readSource struct {
+	client *mongo.Client
+	// readPref is the read preference of the reads, or nil to use that of
+	// client
+	readPref *readpref.ReadPref
+	host     string
+	step     string
+}
+
+// readFallback picks the member that each namespace is read from with the
+// chain of --readPreferenceFallback: the first step with a healthy member,
+// checked again for every namespace, so that the dump carries on from
+// another member if the one it was reading from goes away.
+type readFallback struct {
+	steps []fallbackStep
+	// probe returns the source of a step, or why it has no healthy member
+	probe func(step fallbackStep) (readSource, error)
+
+	client      *mongo.Client
+	toolOptions *options.ToolOptions
+
+	mu          sync.Mutex
+	hiddenHosts []string
+	direct      map[string]*db.SessionProvider
+	last        string
+}
+
+// parseReadPreferenceFallback parses the comma separated steps of
+// --readPreferenceFallback. A step is a read preference mode, hidden, or
+// tag:<name>=<value>[:<name>=<value>]* for the secondaries with those tags.
+func parseReadPreferenceFallback(chain string) ([]fallbackStep, error) {
+	var steps []fallbackStep
+	for _, spec := range strings.Split(chain, ",") {
+		spec = strings.TrimSpace(spec)
+		step := fallbackStep{spec: spec}
+		switch {
+		case spec == fallbackHidden:
+			step.hidden = true
+		case strings.HasPrefix(spec, fallbackTagPrefix):
+			set := tag.Set{}
+			for _, pair := range strings.Split(strings.TrimPrefix(spec, fallbackTagPrefix), ":") {
+				name, value, ok := strings.Cut(pair, "=")
+				if !ok || name == "" {
+					return nil, fmt.Errorf(
+						"invalid %v step %q: tags must be <name>=<value>",
+						ReadPreferenceFallbackOption,
+						spec,
+					)
+				}
+				set = append(set, tag.Tag{Name: name, Value: value})
+			}
+			pref, err := readpref.New(readpref.SecondaryMode, readpref.WithTagSets(set))
+			if err != nil {
+				return nil, err
+			}
+			step.readPref = pref
+		default:
+			mode, err := readpref.ModeFromString(spec)
+			if err != nil || spec == "" {
+				return nil, fmt.Errorf(
+					"invalid %v step %q: must be a read preference mode, %v or %v<name>=<value>",
+					ReadPreferenceFallbackOption,
+					spec,
+					fallbackHidden,
+					fallbackTagPrefix,
+				)
+			}
+			if step.readPref, err = readpref.New(mode); err != nil {
+				return nil, err
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// validateReadPreferenceFallback checks that --readPreferenceFallback is not
+// used with the options that pick a single member for the whole dump.
+func (dump *MongoDump) validateReadPreferenceFallback() error {
+	if dump.InputOptions.ReadPreferenceFallback == "" {
+		return nil
+	}
+	var cs *connstring.ConnString
+	if dump.ToolOptions.URI != nil {
+		cs = dump.ToolOptions.URI.ParsedConnString()
+	}
+	switch {
+	case dump.InputOptions.ReadPreference != "" || (cs != nil && cs.ReadPreference != ""):
+		return fmt.Errorf("cannot use %v with a read preference", ReadPreferenceFallbackOption)
+	case dump.OutputOptions.Oplog:
+		return fmt.Errorf(
+			"cannot use %v with --oplog, since namespaces may be read from different members",
+			ReadPreferenceFallbackOption,
+		)
+	case dump.isMongos:
+		return fmt.Errorf("cannot use %v when dumping from a mongos", ReadPreferenceFallbackOption)
+	}
+	_, err := parseReadPreferenceFallback(dump.InputOptions.ReadPreferenceFallback)
+	return err
+}
+
+// newReadFallback returns the readFallback of --readPreferenceFallback, or
+// nil if it is not set.
+func (dump *MongoDump) newReadFallback() (*readFallback, error) {
+	if dump.InputOptions.ReadPreferenceFallback == "" {
+		return nil, nil
+	}
+	steps, err := parseReadPreferenceFallback(dump.InputOptions.ReadPreferenceFallback)
+	if err != nil {
+		return nil, err
+	}
+	client, err := dump.SessionProvider.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	f := &readFallback{
+		steps:       steps,
+		client:      client,
+		toolOptions: dump.ToolOptions,
+		direct:      map[string]*db.SessionProvider{},
+	}
+	f.probe = f.probeStep
+	return f, nil
+}
+
+// readSourceFor returns where namespace is read from: the
+// source picked by --readPreferenceFallback, or the dump's own connection.
+func (dump *MongoDump) readSourceFor(namespace string) (readSource, error) {
+	if dump.readFallback == nil {
+		client, err := dump.SessionProvider.GetSession()
+		return readSource{client: client}, err
+	}
+	return dump.readFallback.sourceFor(namespace)
+}
+
+// sourceFor returns the source of the first step of the chain with a healthy
+// member.
+func (f *readFallback) sourceFor(namespace string) (readSource, error) {
+	var failures []string
+	for _, step := range f.steps {
+		source, err := f.probe(step)
+		if err != nil {
+			log.Logvf(log.Info, "%v step %v is unavailable for %v: %v",
+				ReadPreferenceFallbackOption, step.spec, namespace, err)
+			failures = append(failures, fmt.Sprintf("%v: %v", step.spec, err))
+			continue
+		}
+		source.step = step.spec
+		f.mu.Lock()
+		changed := f.last != source.step+" "+source.host
+		f.last = source.step + " " + source.host
+		f.mu.Unlock()
+		verbosity := log.Info
+		if changed {
+			verbosity = log.Always
+		}
+		log.Logvf(verbosity, "reading %v from %v (%v)", namespace, source.host, source.step)
+		return source, nil
+	}
+	return readSource{}, fmt.Errorf(
+		"no member of %v can be read from for %v: %v",
+		ReadPreferenceFallbackOption,
+		namespace,
+		strings.Join(failures, "; "),
+	)
+}
+
+// probeStep finds a healthy member for step: one that answers isMaster as a
+// primary or a secondary in time.
+func (f *readFallback) probeStep(step fallbackStep) (readSource, error) {
+	if step.hidden {
+		return f.probeHidden()
+	}
+	host, err := checkMember(f.client, step.readPref)
+	if err != nil {
+		return readSource{}, err
+	}
+	return readSource{client: f.client, readPref: step.readPref, host: host}, nil
+}
+
+// probeHidden returns the first healthy hidden member of the replica set,
+// connected to directly.
+func (f *readFallback) probeHidden() (readSource, error) {
+	hosts, err := f.hidden()
+	if err != nil {
+		return readSource{}, err
+	}
+	if len(hosts) == 0 {
+		return readSource{}, fmt.Errorf("the replica set has no hidden members")
+	}
+	var failures []string
+	for _, host := range hosts {
+		provider, err := f.directProvider(host)
+		if err == nil {
+			var client *mongo.Client
+			if client, err = provider.GetSession(); err == nil {
+				_, err = checkMember(client, nil)
+			}
+			if err == nil {
+				return readSource{client: client, host: host}, nil
+			}
+		}
+		failures = append(failures, fmt.Sprintf("%v: %v", host, err))
+	}
+	return readSource{}, fmt.Errorf("no hidden member is healthy: %v", strings.Join(failures, "; "))
+}
+
+// hidden returns the hosts of the hidden members in the replica set
+// configuration, which is read once.
+func (f *readFallback) hidden() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.hiddenHosts != nil {
+		return f.hiddenHosts, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), fallbackHealthTimeout)
+	defer cancel()
+	var result struct {
+		Config struct {
+			Members []struct {
+				Host   string `bson:"host"`
+				Hidden bool   `bson:"hidden"`
+			} `bson:"members"`
+		} `bson:"config"`
+	}
+	err := f.client.Database("admin").
+		RunCommand(ctx, bson.D{{"replSetGetConfig", 1}}).
+		Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the replica set configuration: %v", err)
+	}
+	f.hiddenHosts = []string{}
+	for _, member := range result.Config.Members {
+		if member.Hidden {
+			f.hiddenHosts = append(f.hiddenHosts, member.Host)
+		}
+	}
+	return f.hiddenHosts, nil
+}
+
+// directProvider returns a connection to host alone, with the options of the
+// dump, made the first time it is needed.
+func (f *readFallback) directProvider(host string) (*db.SessionProvider, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if provider, ok := f.direct[host]; ok {
+		return provider, nil
+	}
+
+	opts := *f.toolOptions
+	connection := *opts.Connection
+	connection.ServerSelectionTimeout = int(fallbackHealthTimeout / time.Second)
+	opts.Connection = &connection
+	opts.Direct = true
+	opts.ReplicaSetName = ""
+	opts.ReadPreference = readpref.PrimaryPreferred()
+
+	cs := *opts.URI.ConnString
+	cs.Hosts = []string{host}
+	cs.ReplicaSet = ""
+	cs.Scheme = connstring.SchemeMongoDB
+	cs.Connect = connstring.SingleConnect
+	cs.DirectConnection = true
+	cs.ReadPreference = ""
+	cs.ReadPreferenceTagSets = nil
+	uri := *opts.URI
+	uri.ConnString = &cs
+	opts.URI = &uri
+
+	provider, err := db.NewSessionProvider(opts)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %v: %v", host, err)
+	}
+	f.direct[host] = provider
+	return provider, nil
+}
+
+// close closes the direct connections to hidden members.
+func (f *readFallback) close() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, provider := range f.direct {
+		provider.Close()
+	}
+}
+
+// checkMember runs isMaster on the member that pref selects, or on the member
+// client is connected to if pref is nil, and returns its host if it is a
+// primary or a secondary.
+func checkMember(client *mongo.Client, pref *readpref.ReadPref) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fallbackHealthTimeout)
+	defer cancel()
+	runOpts := mopt.RunCmd()
+	if pref != nil {
+		runOpts.SetReadPreference(pref)
+	}
+	var hello struct {
+		Me        string `bson:"me"`
+		IsMaster  bool   `bson:"ismaster"`
+		Secondary bool   `bson:"secondary"`
+	}
+	err := client.Database("admin").
+		RunCommand(ctx, bson.D{{"isMaster", 1}}, runOpts).
+		Decode(&hello)
+	if err != nil {
+		return "", err
+	}
+	if !hello.IsMaster && !hello.Secondary {
+		return "", fmt.Errorf("%v is neither a primary nor a secondary", hello.Me)
+	}
+	return hello.Me, nil
+}
@@ -0,0 +1,112 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/tag"
+)
+
+func TestParseReadPreferenceFallback(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	steps, err := parseReadPreferenceFallback(
+		"tag:workload=analytics:dc=east, hidden,secondary,primary",
+	)
+	require.NoError(t, err)
+	require.Len(t, steps, 4)
+
+	assert.Equal(t, "tag:workload=analytics:dc=east", steps[0].spec)
+	assert.Equal(t, readpref.SecondaryMode, steps[0].readPref.Mode())
+	assert.Equal(
+		t,
+		[]tag.Set{{{"workload", "analytics"}, {"dc", "east"}}},
+		steps[0].readPref.TagSets(),
+	)
+	assert.True(t, steps[1].hidden)
+	assert.Nil(t, steps[1].readPref)
+	assert.Equal(t, readpref.SecondaryMode, steps[2].readPref.Mode())
+	assert.Equal(t, readpref.PrimaryMode, steps[3].readPref.Mode())
+
+	for _, chain := range []string{"", "secondary,", "fastest", "tag:", "tag:workload"} {
+		_, err := parseReadPreferenceFallback(chain)
+		assert.Error(t, err, chain)
+	}
+}
+
+func TestReadPreferenceFallbackValidation(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	md := simpleMongoDumpInstance()
+	md.InputOptions.ReadPreferenceFallback = "hidden,secondary,primary"
+	assert.NoError(t, md.ValidateOptions())
+
+	md.InputOptions.ReadPreference = "secondary"
+	assert.ErrorContains(t, md.ValidateOptions(), ReadPreferenceFallbackOption)
+	md.InputOptions.ReadPreference = ""
+
+	md.isMongos = true
+	assert.ErrorContains(t, md.ValidateOptions(), ReadPreferenceFallbackOption)
+}
+
+func TestReadFallbackSourceFor(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	steps, err := parseReadPreferenceFallback("tag:workload=analytics,hidden,primary")
+	require.NoError(t, err)
+	healthy := map[string]string{}
+	var probed []string
+	f := &readFallback{
+		steps: steps,
+		probe: func(step fallbackStep) (readSource, error) {
+			probed = append(probed, step.spec)
+			if host, ok := healthy[step.spec]; ok {
+				return readSource{host: host, readPref: step.readPref}, nil
+			}
+			return readSource{}, fmt.Errorf("no member")
+		},
+	}
+
+	t.Run("the first healthy step is used", func(t *testing.T) {
+		healthy["hidden"] = "h:27017"
+		healthy["primary"] = "p:27017"
+		probed = nil
+		source, err := f.sourceFor("app.users")
+		require.NoError(t, err)
+		assert.Equal(t, "h:27017", source.host)
+		assert.Equal(t, "hidden", source.step)
+		assert.Equal(t, []string{"tag:workload=analytics", "hidden"}, probed)
+	})
+
+	t.Run("each namespace is checked again", func(t *testing.T) {
+		delete(healthy, "hidden")
+		source, err := f.sourceFor("app.orders")
+		require.NoError(t, err)
+		assert.Equal(t, "primary", source.step)
+		assert.Equal(t, readpref.PrimaryMode, source.readPref.Mode())
+
+		healthy["tag:workload=analytics"] = "a:27017"
+		source, err = f.sourceFor("app.items")
+		require.NoError(t, err)
+		assert.Equal(t, "a:27017", source.host)
+	})
+
+	t.Run("a chain without a healthy step fails", func(t *testing.T) {
+		for step := range healthy {
+			delete(healthy, step)
+		}
+		_, err := f.sourceFor("app.users")
+		assert.ErrorContains(t, err, "app.users")
+		assert.ErrorContains(t, err, "hidden: no member")
+	})
+}
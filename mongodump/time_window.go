@@ -0,0 +1,217 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/bsonutil"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// timeWindow is the range of times of --timeWindow, from start included to
+// end excluded. A zero bound leaves that side of the range open.
+type timeWindow struct {
+	start, end time.Time
+}
+
+// parseTimeWindow parses a --timeWindow of the form <start>/<end>, whose
+// bounds are ISO-8601 dates or times, and either of which may be empty.
+func parseTimeWindow(value string) (*timeWindow, error) {
+	start, end, ok := strings.Cut(value, "/")
+	if !ok {
+		return nil, fmt.Errorf("--timeWindow must be of the form <start>/<end>, not '%v'", value)
+	}
+	var window timeWindow
+	var err error
+	if window.start, err = parseWindowBound(start); err != nil {
+		return nil, fmt.Errorf("invalid start of --timeWindow: %v", err)
+	}
+	if window.end, err = parseWindowBound(end); err != nil {
+		return nil, fmt.Errorf("invalid end of --timeWindow: %v", err)
+	}
+	switch {
+	case window.start.IsZero() && window.end.IsZero():
+		return nil, fmt.Errorf("--timeWindow needs a start, an end, or both")
+	case !window.start.IsZero() && !window.end.IsZero() && !window.start.Before(window.end):
+		return nil, fmt.Errorf("the start of --timeWindow must be before its end")
+	}
+	return &window, nil
+}
+
+func parseWindowBound(bound string) (time.Time, error) {
+	if bound == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.Parse("2006-01-02", bound); err == nil {
+		return date, nil
+	}
+	date, err := util.FormatDate(bound)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%v' is not an ISO-8601 date", bound)
+	}
+	return date.(time.Time), nil
+}
+
+// rangeOf returns the predicates on a field within the window, with the bounds
+// converted by value.
+func (w *timeWindow) rangeOf(value func(time.Time, bool) interface{}) bson.D {
+	var predicates bson.D
+	if !w.start.IsZero() {
+		predicates = append(predicates, bson.E{Key: "$gte", Value: value(w.start, false)})
+	}
+	if !w.end.IsZero() {
+		predicates = append(predicates, bson.E{Key: "$lt", Value: value(w.end, true)})
+	}
+	return predicates
+}
+
+// dateBound converts a bound of the window to a BSON date.
+func dateBound(t time.Time, _ bool) interface{} {
+	return primitive.NewDateTimeFromTime(t)
+}
+
+// objectIDBound converts a bound of the window to the smallest ObjectID of
+// its second. ObjectIDs only store seconds, so that an end within a second
+// is rounded up to include that whole second.
+func objectIDBound(t time.Time, end bool) interface{} {
+	if end && !t.Truncate(time.Second).Equal(t) {
+		t = t.Add(time.Second)
+	}
+	var id primitive.ObjectID
+	binary.BigEndian.PutUint32(id[:4], uint32(t.Unix()))
+	return id
+}
+
+// timeFilter returns the filter on the measurements of a timeseries
+// collection whose time field is timeField.
+func (w *timeWindow) timeFilter(timeField string) bson.D {
+	return bson.D{{Key: timeField, Value: w.rangeOf(dateBound)}}
+}
+
+// bucketFilter returns the filter on the buckets of a timeseries collection
+// whose time field is timeField that selects every bucket with measurements
+// in the window. Such buckets are dumped whole, so that they can include
+// measurements outside of the window.
+func (w *timeWindow) bucketFilter(timeField string) bson.D {
+	var filter bson.D
+	if !w.start.IsZero() {
+		filter = append(filter, bson.E{
+			Key:   "control.max." + timeField,
+			Value: bson.D{{Key: "$gte", Value: dateBound(w.start, false)}},
+		})
+	}
+	if !w.end.IsZero() {
+		filter = append(filter, bson.E{
+			Key:   "control.min." + timeField,
+			Value: bson.D{{Key: "$lt", Value: dateBound(w.end, true)}},
+		})
+	}
+	return filter
+}
+
+// idFilter returns the filter on the _ids of a clustered collection, whose
+// _ids are either ObjectIDs or dates, of the type of id.
+func (w *timeWindow) idFilter(id bson.RawValue) (bson.D, error) {
+	switch id.Type {
+	case bson.TypeObjectID:
+		return bson.D{{Key: "_id", Value: w.rangeOf(objectIDBound)}}, nil
+	case bson.TypeDateTime:
+		return bson.D{{Key: "_id", Value: w.rangeOf(dateBound)}}, nil
+	}
+	return nil, fmt.Errorf("its _ids are of type %v, not ObjectIDs or dates", id.Type)
+}
+
+// timeseriesTimeField returns the timeField of the timeseries collection of
+// intent.
+func timeseriesTimeField(intent *intents.Intent) (string, error) {
+	timeseriesOptions, err := bsonutil.FindSubdocumentByKey("timeseries", &intent.Options)
+	if err != nil {
+		return "", fmt.Errorf("could not find timeseries options for %s", intent.Namespace())
+	}
+	timeField, err := bsonutil.FindStringValueByKey("timeField", &timeseriesOptions)
+	if err != nil {
+		return "", fmt.Errorf("could not determine the timeField for %s", intent.Namespace())
+	}
+	return timeField, nil
+}
+
+// isClustered returns whether the collection of intent is clustered by _id.
+func isClustered(intent *intents.Intent) bool {
+	clusteredIndex, err := bsonutil.FindValueByKey("clusteredIndex", &intent.Options)
+	return err == nil && clusteredIndex != nil && clusteredIndex != false
+}
+
+// timeWindowFilter returns the filter that restricts the dump of intent, read
+// from coll, to --timeWindow, or nil if --timeWindow is not set or does not
+// apply to the collection, which is then dumped whole.
+func (dump *MongoDump) timeWindowFilter(
+	intent *intents.Intent,
+	coll *mongo.Collection,
+) (bson.D, error) {
+	if dump.timeWindow == nil {
+		return nil, nil
+	}
+	switch {
+	case intent.IsTimeseries():
+		timeField, err := timeseriesTimeField(intent)
+		if err != nil {
+			return nil, err
+		}
+		return dump.timeWindow.bucketFilter(timeField), nil
+	case isClustered(intent) && !intent.IsView():
+		// the first _id tells whether the _ids are ObjectIDs or dates
+		first, err := coll.FindOne(
+			context.TODO(),
+			bson.D{},
+			mopt.FindOne().SetSort(bson.D{{"_id", 1}}).SetProjection(bson.D{{"_id", 1}}),
+		).Raw()
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading the first _id of %v: %v", intent.Namespace(), err)
+		}
+		filter, err := dump.timeWindow.idFilter(first.Lookup("_id"))
+		if err != nil {
+			return nil, fmt.Errorf(
+				"cannot apply --timeWindow to the clustered collection %v: %v",
+				intent.Namespace(),
+				err,
+			)
+		}
+		return filter, nil
+	}
+	log.Logvf(
+		log.Always,
+		"--timeWindow does not apply to %v, which is neither clustered nor timeseries; "+
+			"dumping all of its documents",
+		intent.Namespace(),
+	)
+	return nil, nil
+}
+
+// andFilters returns a filter that matches the documents that match both a
+// and b, either of which may be empty.
+func andFilters(a, b bson.D) bson.D {
+	switch {
+	case len(a) == 0:
+		return b
+	case len(b) == 0:
+		return a
+	}
+	return bson.D{{Key: "$and", Value: bson.A{a, b}}}
+}
@@ -0,0 +1,129 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseTimeWindow(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	window, err := parseTimeWindow("2024-01-01/2024-02-01T12:30:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), window.start)
+	assert.Equal(t, time.Date(2024, 2, 1, 12, 30, 0, 0, time.UTC), window.end)
+
+	window, err = parseTimeWindow("2024-01-01T00:00:00.500+0100/")
+	require.NoError(t, err)
+	assert.True(t, window.start.Equal(time.Date(2023, 12, 31, 23, 0, 0, 5e8, time.UTC)))
+	assert.True(t, window.end.IsZero())
+
+	for _, value := range []string{
+		"2024-01-01",
+		"/",
+		"yesterday/2024-01-01",
+		"2024-01-01/tomorrow",
+		"2024-02-01/2024-01-01",
+		"2024-01-01/2024-01-01",
+	} {
+		_, err := parseTimeWindow(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestTimeWindowFilters(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 2, 1, 0, 0, 0, 250e6, time.UTC)
+	window := &timeWindow{start: start, end: end}
+
+	assert.Equal(t, bson.D{
+		{"control.max.ts", bson.D{{"$gte", primitive.NewDateTimeFromTime(start)}}},
+		{"control.min.ts", bson.D{{"$lt", primitive.NewDateTimeFromTime(end)}}},
+	}, window.bucketFilter("ts"))
+	assert.Equal(t, bson.D{
+		{"control.min.ts", bson.D{{"$lt", primitive.NewDateTimeFromTime(end)}}},
+	}, (&timeWindow{end: end}).bucketFilter("ts"))
+	assert.Equal(t, bson.D{{"ts", bson.D{
+		{"$gte", primitive.NewDateTimeFromTime(start)},
+		{"$lt", primitive.NewDateTimeFromTime(end)},
+	}}}, window.timeFilter("ts"))
+
+	oid, err := bson.Marshal(bson.D{{"_id", primitive.NewObjectID()}})
+	require.NoError(t, err)
+	filter, err := window.idFilter(bson.Raw(oid).Lookup("_id"))
+	require.NoError(t, err)
+	// the end is rounded up to the next second, which ObjectIDs can represent
+	assert.Equal(t, bson.D{{"_id", bson.D{
+		{"$gte", mustObjectID(t, "659200800000000000000000")},
+		{"$lt", mustObjectID(t, "65badf010000000000000000")},
+	}}}, filter)
+
+	date, err := bson.Marshal(bson.D{{"_id", primitive.NewDateTimeFromTime(start)}})
+	require.NoError(t, err)
+	filter, err = (&timeWindow{start: start}).idFilter(bson.Raw(date).Lookup("_id"))
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{"_id", bson.D{
+		{"$gte", primitive.NewDateTimeFromTime(start)},
+	}}}, filter)
+
+	str, err := bson.Marshal(bson.D{{"_id", "a"}})
+	require.NoError(t, err)
+	_, err = window.idFilter(bson.Raw(str).Lookup("_id"))
+	assert.Error(t, err)
+}
+
+func TestTimeWindowCollections(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	clustered := &intents.Intent{DB: "metrics", C: "events", Options: bson.D{
+		{"clusteredIndex", bson.D{{"key", bson.D{{"_id", 1}}}, {"unique", true}}},
+	}}
+	assert.True(t, isClustered(clustered))
+	assert.False(t, isClustered(&intents.Intent{DB: "metrics", C: "users"}))
+
+	timeseries := &intents.Intent{DB: "metrics", C: "cpu", Type: "timeseries", Options: bson.D{
+		{"timeseries", bson.D{{"timeField", "ts"}, {"metaField", "host"}}},
+	}}
+	timeField, err := timeseriesTimeField(timeseries)
+	require.NoError(t, err)
+	assert.Equal(t, "ts", timeField)
+
+	dump := &MongoDump{timeWindow: &timeWindow{start: time.Unix(1700000000, 0)}}
+	filter, err := dump.timeWindowFilter(timeseries, nil)
+	require.NoError(t, err)
+	assert.Equal(t, dump.timeWindow.bucketFilter("ts"), filter)
+
+	filter, err = dump.timeWindowFilter(&intents.Intent{DB: "metrics", C: "users"}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, filter)
+
+	query := bson.D{{"meta.host", "a"}}
+	assert.Equal(t, query, andFilters(query, nil))
+	assert.Equal(t, filter, andFilters(nil, filter))
+	assert.Equal(
+		t,
+		bson.D{{"$and", bson.A{query, dump.timeWindow.bucketFilter("ts")}}},
+		andFilters(query, dump.timeWindow.bucketFilter("ts")),
+	)
+}
+
+func mustObjectID(t *testing.T, hex string) primitive.ObjectID {
+	id, err := primitive.ObjectIDFromHex(hex)
+	require.NoError(t, err)
+	return id
+}
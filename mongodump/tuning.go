@@ -0,0 +1,202 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/mongorestore/ns"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// TuningFile is the content of a --tuningFile. It sets the cursor batch size,
+// the parallelism and the read concern of the collections matching namespace
+// patterns, which use the same syntax as mongorestore's --nsInclude. For
+// example:
+//
+//	namespaces:
+//	  - name: giant
+//	    match: ["logs.*", "analytics.events"]
+//	    batchSize: 50000
+//	    numParallelCollections: 2
+//	  - name: gentle
+//	    match: ["app.sessions"]
+//	    batchSize: 100
+//	    numParallelCollections: 1
+//	  - name: ledger
+//	    match: ["billing.*"]
+//	    readConcern: majority
+//
+// A collection uses the first entry that matches it. Collections that match no
+// entry use the server's default batch size and the read concern of the
+// connection string, and are only limited by --numParallelCollections.
+type TuningFile struct {
+	Namespaces []*NamespaceTuning `yaml:"namespaces"`
+}
+
+// NamespaceTuning is the tuning of the collections matching its patterns.
+type NamespaceTuning struct {
+	// Name identifies the entry in log messages. It defaults to its patterns.
+	Name  string   `yaml:"name"`
+	Match []string `yaml:"match"`
+	// BatchSize is the number of documents in each batch of the cursors that
+	// read the collections. 0 uses the server's default.
+	BatchSize int `yaml:"batchSize"`
+	// NumParallelCollections is the number of matching collections that can
+	// be dumped at the same time. 0 only limits them by
+	// --numParallelCollections.
+	NumParallelCollections int `yaml:"numParallelCollections"`
+	// ReadConcern is the read concern level the collections are read with,
+	// e.g. majority. An empty level uses the read concern of the connection
+	// string.
+	ReadConcern string `yaml:"readConcern"`
+
+	matcher *ns.Matcher
+}
+
+// LoadTuningFile reads and parses the --tuningFile at path.
+func LoadTuningFile(path string) (*TuningFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening file with --tuningFile")
+	}
+	tuning := &TuningFile{}
+	if err = yaml.UnmarshalStrict(content, tuning); err != nil {
+		return nil, errors.Wrapf(err, "error parsing tuning file %s", path)
+	}
+	for i, nt := range tuning.Namespaces {
+		if nt == nil || len(nt.Match) == 0 {
+			return nil, fmt.Errorf("entry %d of tuning file %s has no namespaces to match", i, path)
+		}
+		if nt.Name == "" {
+			nt.Name = fmt.Sprint(nt.Match)
+		}
+		if nt.BatchSize < 0 || nt.BatchSize > math.MaxInt32 {
+			return nil, fmt.Errorf("invalid batchSize %d for %v", nt.BatchSize, nt.Name)
+		}
+		if nt.NumParallelCollections < 0 {
+			return nil, fmt.Errorf(
+				"invalid numParallelCollections %d for %v",
+				nt.NumParallelCollections,
+				nt.Name,
+			)
+		}
+		if nt.ReadConcern != "" && !validReadConcernLevels[nt.ReadConcern] {
+			return nil, fmt.Errorf("invalid readConcern %q for %v", nt.ReadConcern, nt.Name)
+		}
+		nt.matcher, err = ns.NewMatcher(nt.Match)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid namespace pattern for %v", nt.Name)
+		}
+	}
+	return tuning, nil
+}
+
+// ForNamespace returns the tuning of the namespace, or nil if no entry
+// matches it.
+func (tf *TuningFile) ForNamespace(namespace string) *NamespaceTuning {
+	if tf == nil {
+		return nil
+	}
+	for _, nt := range tf.Namespaces {
+		if nt.matcher.Has(namespace) {
+			return nt
+		}
+	}
+	return nil
+}
+
+// tuningPrioritizer wraps the prioritizer of the intent manager so that no
+// more collections of a NamespaceTuning are dumped at the same time than its
+// NumParallelCollections. While every collection left is held back by its
+// limit, Get blocks until a collection is finished.
+type tuningPrioritizer struct {
+	next   intents.IntentPrioritizer
+	tuning *TuningFile
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	waiting []*intents.Intent
+	running map[*NamespaceTuning]int
+}
+
+func newTuningPrioritizer(
+	next intents.IntentPrioritizer,
+	tuning *TuningFile,
+) *tuningPrioritizer {
+	tp := &tuningPrioritizer{
+		next:    next,
+		tuning:  tuning,
+		running: map[*NamespaceTuning]int{},
+	}
+	tp.cond = sync.NewCond(&tp.mu)
+	return tp
+}
+
+// start returns whether intent can be dumped now, and if so counts it as
+// running.
+func (tp *tuningPrioritizer) start(intent *intents.Intent) bool {
+	nt := tp.tuning.ForNamespace(intent.Namespace())
+	if nt == nil {
+		return true
+	}
+	if nt.NumParallelCollections > 0 && tp.running[nt] >= nt.NumParallelCollections {
+		return false
+	}
+	tp.running[nt]++
+	return true
+}
+
+// Get returns the next intent whose limit lets it be dumped now, in the order
+// of the wrapped prioritizer.
+func (tp *tuningPrioritizer) Get() *intents.Intent {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	for {
+		for i, intent := range tp.waiting {
+			if tp.start(intent) {
+				tp.waiting = append(tp.waiting[:i], tp.waiting[i+1:]...)
+				return intent
+			}
+		}
+		intent := tp.next.Get()
+		if intent == nil {
+			if len(tp.waiting) == 0 {
+				return nil
+			}
+			tp.cond.Wait()
+			continue
+		}
+		if tp.start(intent) {
+			return intent
+		}
+		log.Logvf(
+			log.DebugHigh,
+			"holding back %v until fewer collections of %v are being dumped",
+			intent.Namespace(),
+			tp.tuning.ForNamespace(intent.Namespace()).Name,
+		)
+		tp.waiting = append(tp.waiting, intent)
+	}
+}
+
+// Finish frees the place of intent in its limit.
+func (tp *tuningPrioritizer) Finish(intent *intents.Intent) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	if nt := tp.tuning.ForNamespace(intent.Namespace()); nt != nil {
+		tp.running[nt]--
+	}
+	tp.next.Finish(intent)
+	tp.cond.Broadcast()
+}
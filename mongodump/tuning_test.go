@@ -0,0 +1,125 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongodump
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTuningFile = `
+namespaces:
+  - name: giant
+    match: ["logs.*", "analytics.events"]
+    batchSize: 50000
+    numParallelCollections: 2
+  - match: ["app.sessions"]
+    batchSize: 100
+    readConcern: majority
+`
+
+func writeTuningFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "tuning.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestTuningFile(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	tuning, err := LoadTuningFile(writeTuningFile(t, testTuningFile))
+	require.NoError(t, err)
+
+	giant := tuning.ForNamespace("logs.2024")
+	require.NotNil(t, giant)
+	assert.Equal(t, "giant", giant.Name)
+	assert.Equal(t, 50000, giant.BatchSize)
+	assert.Same(t, giant, tuning.ForNamespace("analytics.events"))
+
+	sessions := tuning.ForNamespace("app.sessions")
+	require.NotNil(t, sessions)
+	assert.Equal(t, "[app.sessions]", sessions.Name)
+	assert.Equal(t, 0, sessions.NumParallelCollections)
+	assert.Equal(t, "majority", sessions.ReadConcern)
+	assert.Empty(t, giant.ReadConcern)
+
+	assert.Nil(t, tuning.ForNamespace("app.users"))
+	assert.Nil(t, (*TuningFile)(nil).ForNamespace("app.users"))
+
+	for _, content := range []string{
+		"namespaces: [{batchSize: 10}]",
+		"namespaces: [{match: [a.b], batchSize: -1}]",
+		"namespaces: [{match: [a.b], numParallelCollections: -1}]",
+		"namespaces: [{match: [a.b], unknown: 1}]",
+		"namespaces: [{match: [a.b], readConcern: strong}]",
+	} {
+		_, err := LoadTuningFile(writeTuningFile(t, content))
+		assert.Error(t, err, content)
+	}
+	_, err = LoadTuningFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestTuningPrioritizer(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	tuning, err := LoadTuningFile(writeTuningFile(t, testTuningFile))
+	require.NoError(t, err)
+
+	manager := intents.NewIntentManager()
+	for i := 0; i < 6; i++ {
+		manager.Put(&intents.Intent{DB: "logs", C: fmt.Sprintf("c%d", i), Size: int64(100 - i)})
+	}
+	for i := 0; i < 4; i++ {
+		manager.Put(&intents.Intent{DB: "app", C: fmt.Sprintf("c%d", i), Size: int64(10 - i)})
+	}
+	manager.Finalize(intents.LongestTaskFirst)
+	manager.UsePrioritizer(newTuningPrioritizer(manager.Prioritizer(), tuning))
+
+	var mu sync.Mutex
+	running := map[string]int{}
+	maxRunning := map[string]int{}
+	dumped := 0
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for intent := manager.Pop(); intent != nil; intent = manager.Pop() {
+				mu.Lock()
+				running[intent.DB]++
+				if running[intent.DB] > maxRunning[intent.DB] {
+					maxRunning[intent.DB] = running[intent.DB]
+				}
+				dumped++
+				mu.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+
+				mu.Lock()
+				running[intent.DB]--
+				mu.Unlock()
+				manager.Finish(intent)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, dumped)
+	assert.Equal(t, 2, maxRunning["logs"], "giant collections should be limited to 2 at a time")
+	assert.Greater(t, maxRunning["app"], 1, "other collections should use the free workers")
+}
@@ -0,0 +1,109 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ExcludeFieldsOption is the command line flag for
+// OutputFormatOptions.ExcludeFields.
+const ExcludeFieldsOption = "--excludeFields"
+
+// excludedFields returns the fields of --excludeFields.
+func (exp *MongoExport) excludedFields() []string {
+	if exp.OutputOpts.ExcludeFields == "" {
+		return nil
+	}
+	return strings.Split(exp.OutputOpts.ExcludeFields, ",")
+}
+
+// excludesID returns whether --excludeFields leaves out the _id. The _id is
+// still read, since a resumed export finds its place by the _id, and is
+// removed from each document before it is written.
+func (exp *MongoExport) excludesID() bool {
+	return slices.Contains(exp.excludedFields(), "_id")
+}
+
+// validateExcludeFields checks the fields of --excludeFields. Only the _id
+// can be excluded along with --fields, since a projection can't both include
+// and exclude other fields.
+func (exp *MongoExport) validateExcludeFields() error {
+	excluded := exp.excludedFields()
+	var included []string
+	if exp.OutputOpts.Fields != "" {
+		included = strings.Split(exp.OutputOpts.Fields, ",")
+	}
+	for i, field := range excluded {
+		switch {
+		case field == "" || strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") ||
+			strings.Contains(field, ".."):
+			return fmt.Errorf("invalid %v field '%v'", ExcludeFieldsOption, field)
+		case strings.Contains(field, "$"):
+			return fmt.Errorf("%v cannot exclude the field '%v'", ExcludeFieldsOption, field)
+		case field != "_id" && (exp.OutputOpts.Fields != "" || exp.OutputOpts.FieldFile != ""):
+			return fmt.Errorf(
+				"cannot use %v with --fields or --fieldFile, except to exclude _id",
+				ExcludeFieldsOption,
+			)
+		case field == "_id" && slices.Contains(included, "_id"):
+			return fmt.Errorf("cannot both include and exclude _id")
+		}
+		if exp.InputOpts != nil && exp.InputOpts.IncrementalField != "" {
+			incremental := exp.InputOpts.IncrementalField
+			if incremental == field || strings.HasPrefix(incremental, field+".") {
+				return fmt.Errorf(
+					"%v cannot exclude the %v '%v'",
+					ExcludeFieldsOption,
+					IncrementalFieldOption,
+					incremental,
+				)
+			}
+		}
+		for _, other := range excluded[:i] {
+			if other == field || strings.HasPrefix(field, other+".") ||
+				strings.HasPrefix(other, field+".") {
+				return fmt.Errorf(
+					"%v fields '%v' and '%v' overlap",
+					ExcludeFieldsOption,
+					other,
+					field,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// projection returns the projection of the documents to export: the fields
+// of --fields, or every field but those of --excludeFields, or nil to read
+// whole documents.
+func (exp *MongoExport) projection() bson.M {
+	if len(exp.OutputOpts.Fields) > 0 {
+		return makeFieldSelector(exp.OutputOpts.Fields)
+	}
+	var selector bson.M
+	for _, field := range exp.excludedFields() {
+		if field == "_id" {
+			continue
+		}
+		if selector == nil {
+			selector = bson.M{}
+		}
+		selector[field] = 0
+	}
+	return selector
+}
+
+// removeID returns doc without its _id.
+func removeID(doc bson.D) bson.D {
+	return slices.DeleteFunc(doc, func(elem bson.E) bool { return elem.Key == "_id" })
+}
@@ -0,0 +1,94 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestExcludeFields(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With --excludeFields", t, func() {
+		exp := &MongoExport{
+			OutputOpts: &OutputFormatOptions{ExcludeFields: "_id,audit.createdBy,secret"},
+			InputOpts:  &InputOptions{},
+		}
+		So(exp.validateExcludeFields(), ShouldBeNil)
+
+		Convey("the fields but _id are projected out", func() {
+			So(exp.projection(), ShouldResemble, bson.M{"audit.createdBy": 0, "secret": 0})
+			So(exp.excludesID(), ShouldBeTrue)
+		})
+
+		Convey("excluding only _id reads whole documents", func() {
+			exp.OutputOpts.ExcludeFields = "_id"
+			So(exp.projection(), ShouldBeNil)
+			So(exp.excludesID(), ShouldBeTrue)
+		})
+
+		Convey("_id can be excluded from --fields", func() {
+			exp.OutputOpts.ExcludeFields = "_id"
+			exp.OutputOpts.Fields = "name,age"
+			So(exp.validateExcludeFields(), ShouldBeNil)
+			So(exp.projection(), ShouldResemble, bson.M{"_id": 1, "name": 1, "age": 1})
+
+			exp.OutputOpts.Fields = "_id,name"
+			So(exp.validateExcludeFields(), ShouldNotBeNil)
+		})
+
+		Convey("other fields cannot be excluded with --fields or --fieldFile", func() {
+			exp.OutputOpts.Fields = "name"
+			So(exp.validateExcludeFields(), ShouldNotBeNil)
+			exp.OutputOpts.Fields = ""
+			exp.OutputOpts.FieldFile = "fields.txt"
+			So(exp.validateExcludeFields(), ShouldNotBeNil)
+		})
+
+		Convey("invalid fields are rejected", func() {
+			for _, fields := range []string{"a,", ".a", "a.", "a..b", "$a", "a.$b"} {
+				exp.OutputOpts.ExcludeFields = fields
+				So(exp.validateExcludeFields(), ShouldNotBeNil)
+			}
+		})
+
+		Convey("overlapping fields are rejected", func() {
+			for _, fields := range []string{"a,a", "a,a.b", "a.b,a"} {
+				exp.OutputOpts.ExcludeFields = fields
+				So(exp.validateExcludeFields(), ShouldNotBeNil)
+			}
+			exp.OutputOpts.ExcludeFields = "a,ab"
+			So(exp.validateExcludeFields(), ShouldBeNil)
+		})
+
+		Convey("the --incrementalField cannot be excluded", func() {
+			exp.InputOpts.IncrementalField = "audit.createdAt"
+			exp.OutputOpts.ExcludeFields = "audit"
+			So(exp.validateExcludeFields(), ShouldNotBeNil)
+			exp.OutputOpts.ExcludeFields = "audit.createdAt"
+			So(exp.validateExcludeFields(), ShouldNotBeNil)
+			exp.OutputOpts.ExcludeFields = "audit.createdBy"
+			So(exp.validateExcludeFields(), ShouldBeNil)
+		})
+	})
+
+	Convey("Without --excludeFields", t, func() {
+		exp := &MongoExport{OutputOpts: &OutputFormatOptions{}}
+		So(exp.validateExcludeFields(), ShouldBeNil)
+		So(exp.projection(), ShouldBeNil)
+		So(exp.excludesID(), ShouldBeFalse)
+	})
+
+	Convey("removeID keeps the other fields in order", t, func() {
+		doc := bson.D{{"a", 1}, {"_id", 2}, {"b", 3}}
+		So(removeID(doc), ShouldResemble, bson.D{{"a", 1}, {"b", 3}})
+	})
+}
@@ -0,0 +1,84 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/csvexport"
+)
+
+// ParseFieldFormats parses the --fieldFormat options, and returns the format
+// of each field. A --fieldFormat has the form
+// <field>:<directive>=<value>[,<directive>=<value>]* with the directives:
+//
+//	date=<layout>     dates in a Go time layout like 2006-01-02, in UTC, or
+//	                  as seconds or milliseconds since the epoch with unix or
+//	                  unixms
+//	precision=<n>     numbers with n digits after the decimal point
+//	bool=<t>/<f>      true and false as t and f, e.g. bool=1/0
+//	null=<text>       null values as text, e.g. null=NULL
+//
+// The field '*' sets the format of the fields without a --fieldFormat of
+// their own.
+func ParseFieldFormats(specs []string) (map[string]*csvexport.FieldFormat, error) {
+	formats := map[string]*csvexport.FieldFormat{}
+	for _, spec := range specs {
+		field, directives, ok := strings.Cut(spec, ":")
+		if !ok || field == "" || directives == "" {
+			return nil, fmt.Errorf(
+				"invalid %v '%v': must be <field>:<directive>=<value>[,<directive>=<value>]*",
+				FieldFormatOption,
+				spec,
+			)
+		}
+		if _, ok := formats[field]; ok {
+			return nil, fmt.Errorf("%v is given more than once for field '%v'", FieldFormatOption, field)
+		}
+		format := &csvexport.FieldFormat{Precision: -1}
+		for _, directive := range strings.Split(directives, ",") {
+			if err := parseFieldFormatDirective(format, directive); err != nil {
+				return nil, fmt.Errorf("invalid %v for field '%v': %v", FieldFormatOption, field, err)
+			}
+		}
+		formats[field] = format
+	}
+	return formats, nil
+}
+
+func parseFieldFormatDirective(format *csvexport.FieldFormat, directive string) error {
+	name, value, ok := strings.Cut(directive, "=")
+	if !ok {
+		return fmt.Errorf("directive '%v' has no value", directive)
+	}
+	switch name {
+	case "date":
+		if value == "" {
+			return fmt.Errorf("date needs a layout")
+		}
+		format.DateLayout = value
+	case "precision":
+		precision, err := strconv.Atoi(value)
+		if err != nil || precision < 0 {
+			return fmt.Errorf("precision must be a number of digits, got '%v'", value)
+		}
+		format.Precision = precision
+	case "bool":
+		t, f, ok := strings.Cut(value, "/")
+		if !ok {
+			return fmt.Errorf("bool must be <true>/<false>, got '%v'", value)
+		}
+		format.True, format.False, format.HasBool = t, f, true
+	case "null":
+		format.Null, format.HasNull = value, true
+	default:
+		return fmt.Errorf("unknown directive '%v'", name)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/csvexport"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseFieldFormats(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("Parsing --fieldFormat", t, func() {
+		Convey("directives should be set on their field", func() {
+			formats, err := ParseFieldFormats([]string{
+				"created:date=2006-01-02T15:04:05Z07:00",
+				"price:precision=2,null=0",
+				"*:bool=yes/no",
+			})
+			So(err, ShouldBeNil)
+			So(formats["created"], ShouldResemble, &csvexport.FieldFormat{
+				DateLayout: "2006-01-02T15:04:05Z07:00",
+				Precision:  -1,
+			})
+			So(formats["price"], ShouldResemble, &csvexport.FieldFormat{
+				Precision: 2,
+				Null:      "0",
+				HasNull:   true,
+			})
+			So(formats["*"], ShouldResemble, &csvexport.FieldFormat{
+				Precision: -1,
+				True:      "yes",
+				False:     "no",
+				HasBool:   true,
+			})
+		})
+
+		Convey("invalid formats should be rejected", func() {
+			for _, spec := range []string{
+				"price",
+				"price:",
+				":precision=2",
+				"price:precision",
+				"price:precision=-1",
+				"price:precision=two",
+				"active:bool=1",
+				"created:date=",
+				"price:round=2",
+			} {
+				_, err := ParseFieldFormats([]string{spec})
+				So(err, ShouldNotBeNil)
+			}
+			_, err := ParseFieldFormats([]string{"a:null=", "a:precision=1"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestWriteCSVWithFieldFormats(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With a CSV export output with field formats", t, func() {
+		out := &bytes.Buffer{}
+		fields := []string{"created", "day", "price", "count", "total", "active", "note", "other"}
+		csvExporter := csvexport.NewOutput(fields, true, out)
+		formats, err := ParseFieldFormats([]string{
+			"created:date=unixms",
+			"day:date=2006-01-02",
+			"price:precision=2",
+			"count:precision=1",
+			"total:precision=3",
+			"*:bool=1/0,null=NULL",
+		})
+		So(err, ShouldBeNil)
+		csvExporter.FieldFormats = formats
+
+		export := func(doc bson.D) []string {
+			So(csvExporter.ExportDocument(doc), ShouldBeNil)
+			So(csvExporter.Flush(), ShouldBeNil)
+			rec, err := csv.NewReader(strings.NewReader(out.String())).Read()
+			So(err, ShouldBeNil)
+			return rec
+		}
+
+		Convey("values should be written in their field's format", func() {
+			created := time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)
+			total, err := primitive.ParseDecimal128("1234.56789")
+			So(err, ShouldBeNil)
+			rec := export(bson.D{
+				{"created", primitive.NewDateTimeFromTime(created)},
+				{"day", primitive.NewDateTimeFromTime(created)},
+				{"price", 3.14159},
+				{"count", int64(7)},
+				{"total", total},
+				{"active", true},
+				{"note", nil},
+				{"other", false},
+			})
+			So(rec, ShouldResemble, []string{
+				"1709634600000", "2024-03-05", "3.14", "7.0", "1234.568", "1", "NULL", "0",
+			})
+		})
+
+		Convey("values the format does not apply to should be written as usual", func() {
+			rec := export(bson.D{
+				{"created", "yesterday"},
+				{"price", "free"},
+				{"active", 1},
+			})
+			// missing fields are not null
+			So(rec, ShouldResemble, []string{"yesterday", "", "free", "", "", "1", "", ""})
+		})
+	})
+}
@@ -0,0 +1,59 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"github.com/mongodb/mongo-tools/common/csvexport"
+	"github.com/mongodb/mongo-tools/common/fieldmap"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// fieldMappingOutput is an ExportOutput that renames the fields of the
+// documents of --fieldMap before exporting them.
+type fieldMappingOutput struct {
+	ExportOutput
+	fieldMap *fieldmap.Map
+}
+
+// ExportDocument renames the fields of document, and exports it.
+func (o *fieldMappingOutput) ExportDocument(document bson.D) error {
+	if err := o.fieldMap.Rename(document); err != nil {
+		return err
+	}
+	return o.ExportOutput.ExportDocument(document)
+}
+
+// renamingFields wraps output to rename the fields of --fieldMap, if set.
+func (exp *MongoExport) renamingFields(output ExportOutput) ExportOutput {
+	if exp.fieldMap == nil {
+		return output
+	}
+	return &fieldMappingOutput{ExportOutput: output, fieldMap: exp.fieldMap}
+}
+
+// renamedColumns returns the CSV fields, and the fields of their formats, as
+// renamed by --fieldMap.
+func (exp *MongoExport) renamedColumns(
+	fields []string,
+	formats map[string]*csvexport.FieldFormat,
+) ([]string, map[string]*csvexport.FieldFormat) {
+	renamed := make([]string, len(fields))
+	for i, field := range fields {
+		renamed[i] = exp.fieldMap.Path(field)
+	}
+	if formats == nil {
+		return renamed, nil
+	}
+	renamedFormats := make(map[string]*csvexport.FieldFormat, len(formats))
+	for field, format := range formats {
+		if field != csvexport.AllFieldsFormat {
+			field = exp.fieldMap.Path(field)
+		}
+		renamedFormats[field] = format
+	}
+	return renamed, renamedFormats
+}
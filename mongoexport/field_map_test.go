@@ -0,0 +1,69 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/fieldmap"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFieldMap(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With --fieldMap", t, func() {
+		fieldMap, err := fieldmap.New(map[string]string{
+			"firstName":       "first_name",
+			"address.zipCode": "address.postal_code",
+		}, false)
+		So(err, ShouldBeNil)
+		doc := bson.D{
+			{"firstName", "Ann"},
+			{"address", bson.D{{"zipCode", "10001"}}},
+			{"age", 40},
+		}
+
+		Convey("JSON output has the renamed fields", func() {
+			var out bytes.Buffer
+			exp := &MongoExport{
+				OutputOpts: &OutputFormatOptions{Type: JSON, JSONFormat: Canonical},
+				fieldMap:   fieldMap,
+			}
+			output, err := exp.getExportOutput(&out)
+			So(err, ShouldBeNil)
+			So(output.ExportDocument(doc), ShouldBeNil)
+			So(output.Flush(), ShouldBeNil)
+			So(out.String(), ShouldContainSubstring,
+				`{"first_name":"Ann","address":{"postal_code":"10001"},`)
+		})
+
+		Convey("CSV columns are named after the renamed fields", func() {
+			var out bytes.Buffer
+			exp := &MongoExport{
+				OutputOpts: &OutputFormatOptions{
+					Type:         CSV,
+					Fields:       "firstName,address.zipCode,age",
+					FieldFormats: []string{"address.zipCode:null=none"},
+				},
+				fieldMap: fieldMap,
+			}
+			output, err := exp.getExportOutput(&out)
+			So(err, ShouldBeNil)
+			So(output.WriteHeader(), ShouldBeNil)
+			So(output.ExportDocument(doc), ShouldBeNil)
+			bob := bson.D{{"firstName", "Bob"}, {"address", bson.D{{"zipCode", nil}}}}
+			So(output.ExportDocument(bob), ShouldBeNil)
+			So(output.Flush(), ShouldBeNil)
+			So(out.String(), ShouldEqual,
+				"first_name,address.postal_code,age\nAnn,10001,40\nBob,none,\n")
+		})
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Command line flags for incremental exports.
+const (
+	IncrementalFieldOption = "--incrementalField"
+	StateFileOption        = "--stateFile"
+)
+
+// exportState is the content of an incremental export's --stateFile. It
+// records the highest value of the incremental field exported so far and the
+// highest _id exported with it, which the next export only exports documents
+// after. Documents are exported in order of both, so that an export cut short
+// by --limit continues with the other documents that have the same value.
+type exportState struct {
+	Field     string        `bson:"field"`
+	Watermark bson.RawValue `bson:"watermark"`
+	// ID is unset if the field is _id, or for the state files of older
+	// exports, which only export the documents above the watermark
+	ID bson.RawValue `bson:"id,omitempty"`
+}
+
+// describe returns the watermark of state for the log, such as "updatedAt 5
+// and _id 7".
+func (state *exportState) describe() string {
+	if state.ID.Type == 0 {
+		return fmt.Sprintf("%v %v", state.Field, state.Watermark)
+	}
+	return fmt.Sprintf("%v %v and _id %v", state.Field, state.Watermark, state.ID)
+}
+
+// readExportState reads the state of previous exports from path. It returns
+// nil if the file does not exist yet, so that the first export exports every
+// document.
+func readExportState(path, field string) (*exportState, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %v: %v", StateFileOption, err)
+	}
+
+	state := &exportState{}
+	err = bson.UnmarshalExtJSON(content, true, state)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %v %#q: %v", StateFileOption, path, err)
+	}
+	if state.Field != field {
+		return nil, fmt.Errorf(
+			"%v %#q is for field '%v', not %v '%v'",
+			StateFileOption,
+			path,
+			state.Field,
+			IncrementalFieldOption,
+			field,
+		)
+	}
+	if state.Watermark.Type == 0 {
+		return nil, fmt.Errorf("%v %#q has no watermark", StateFileOption, path)
+	}
+	return state, nil
+}
+
+// writeExportState atomically replaces the file at path with state, by
+// writing it to a temporary file in the same directory and renaming that over
+// path, so that an interrupted export never leaves a partial state behind.
+func writeExportState(path string, state *exportState) error {
+	content, err := bson.MarshalExtJSON(state, true, false)
+	if err != nil {
+		return fmt.Errorf("error encoding export state: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("error writing %v: %v", StateFileOption, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(content, '\n'))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing %v: %v", StateFileOption, err)
+	}
+	return nil
+}
+
+// incrementalQuery restricts query to the documents after the watermark of
+// state: those whose field is above it, and those whose field is equal to it
+// and whose _id is above the one of state.
+func incrementalQuery(query bson.D, state *exportState) bson.D {
+	above := bson.D{{Key: state.Field, Value: bson.D{{Key: "$gt", Value: state.Watermark}}}}
+	if state.ID.Type != 0 {
+		above = bson.D{{Key: "$or", Value: bson.A{
+			above,
+			bson.D{
+				{Key: state.Field, Value: bson.D{{Key: "$eq", Value: state.Watermark}}},
+				{Key: "_id", Value: bson.D{{Key: "$gt", Value: state.ID}}},
+			},
+		}}}
+	}
+	if len(query) == 0 {
+		return above
+	}
+	return bson.D{{Key: "$and", Value: bson.A{query, above}}}
+}
+
+// incrementalSort returns the order of an incremental export on field, which
+// is also the order of its watermarks.
+func incrementalSort(field string) bson.D {
+	if field == "_id" {
+		return bson.D{{Key: "_id", Value: 1}}
+	}
+	return bson.D{{Key: field, Value: 1}, {Key: "_id", Value: 1}}
+}
+
+// lookupField returns the value of a dotted field in doc, or an empty
+// RawValue if doc does not have it.
+func lookupField(doc bson.Raw, field string) bson.RawValue {
+	value, err := doc.LookupErr(strings.Split(field, ".")...)
+	if err != nil {
+		return bson.RawValue{}
+	}
+	return value
+}
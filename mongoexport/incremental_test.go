@@ -0,0 +1,217 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/mongodb/mongo-tools/common/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestExportState(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With a state file path", t, func() {
+		path := filepath.Join(t.TempDir(), "state.json")
+
+		Convey("a missing state file means there is no watermark yet", func() {
+			state, err := readExportState(path, "updatedAt")
+			So(err, ShouldBeNil)
+			So(state, ShouldBeNil)
+		})
+
+		Convey("a written watermark is read back with its type", func() {
+			_, value, err := bson.MarshalValue(int64(42))
+			So(err, ShouldBeNil)
+			watermark := bson.RawValue{Type: bson.TypeInt64, Value: value}
+			So(writeExportState(path, &exportState{Field: "n", Watermark: watermark}), ShouldBeNil)
+
+			state, err := readExportState(path, "n")
+			So(err, ShouldBeNil)
+			So(state.Watermark.Type, ShouldEqual, bson.TypeInt64)
+			So(state.Watermark.Int64(), ShouldEqual, 42)
+			So(state.ID.IsZero(), ShouldBeTrue)
+			content, err := os.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(content), ShouldNotContainSubstring, `"id"`)
+
+			entries, err := os.ReadDir(filepath.Dir(path))
+			So(err, ShouldBeNil)
+			So(len(entries), ShouldEqual, 1)
+
+			Convey("with the _id it was exported with", func() {
+				_, id, err := bson.MarshalValue("a")
+				So(err, ShouldBeNil)
+				So(writeExportState(path, &exportState{
+					Field:     "n",
+					Watermark: watermark,
+					ID:        bson.RawValue{Type: bson.TypeString, Value: id},
+				}), ShouldBeNil)
+
+				state, err := readExportState(path, "n")
+				So(err, ShouldBeNil)
+				So(state.Watermark.Int64(), ShouldEqual, 42)
+				So(state.ID.StringValue(), ShouldEqual, "a")
+			})
+
+			Convey("but not for another field", func() {
+				_, err := readExportState(path, "updatedAt")
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "is for field 'n'")
+			})
+		})
+	})
+
+	Convey("The watermark is combined with the query", t, func() {
+		_, value, err := bson.MarshalValue(int32(7))
+		So(err, ShouldBeNil)
+		state := &exportState{Field: "n", Watermark: bson.RawValue{Type: bson.TypeInt32, Value: value}}
+		above := bson.D{{"n", bson.D{{"$gt", state.Watermark}}}}
+
+		So(incrementalQuery(bson.D{}, state), ShouldResemble, above)
+		So(
+			incrementalQuery(bson.D{{"a", 1}}, state),
+			ShouldResemble,
+			bson.D{{"$and", bson.A{bson.D{{"a", 1}}, above}}},
+		)
+
+		Convey("and the _id it was exported with", func() {
+			_, id, err := bson.MarshalValue(int32(3))
+			So(err, ShouldBeNil)
+			state.ID = bson.RawValue{Type: bson.TypeInt32, Value: id}
+			So(incrementalQuery(bson.D{}, state), ShouldResemble, bson.D{{"$or", bson.A{
+				above,
+				bson.D{
+					{"n", bson.D{{"$eq", state.Watermark}}},
+					{"_id", bson.D{{"$gt", state.ID}}},
+				},
+			}}})
+		})
+	})
+
+	Convey("Documents are exported in order of the field and _id", t, func() {
+		So(incrementalSort("n"), ShouldResemble, bson.D{{"n", 1}, {"_id", 1}})
+		So(incrementalSort("_id"), ShouldResemble, bson.D{{"_id", 1}})
+	})
+}
+
+func TestIncrementalSettings(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With incremental export options", t, func() {
+		exp := &MongoExport{
+			OutputOpts: &OutputFormatOptions{},
+			InputOpts:  &InputOptions{IncrementalField: "updatedAt", StateFile: "state.json"},
+		}
+		So(exp.validateIncrementalSettings(), ShouldBeNil)
+
+		Convey("both options are required", func() {
+			exp.InputOpts.StateFile = ""
+			So(exp.validateIncrementalSettings(), ShouldNotBeNil)
+			exp.InputOpts.StateFile = "state.json"
+			exp.InputOpts.IncrementalField = ""
+			So(exp.validateIncrementalSettings(), ShouldNotBeNil)
+		})
+
+		Convey("--sort is not allowed", func() {
+			exp.InputOpts.Sort = "{a: 1}"
+			So(exp.validateIncrementalSettings(), ShouldNotBeNil)
+		})
+
+		Convey("--fields must include the field", func() {
+			exp.OutputOpts.Fields = "a,b"
+			So(exp.validateIncrementalSettings(), ShouldNotBeNil)
+			exp.OutputOpts.Fields = "a,updatedAt"
+			So(exp.validateIncrementalSettings(), ShouldBeNil)
+			exp.InputOpts.IncrementalField = "meta.updatedAt"
+			exp.OutputOpts.Fields = "a,meta"
+			So(exp.validateIncrementalSettings(), ShouldBeNil)
+		})
+	})
+}
+
+func TestIncrementalExport(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.IntegrationTestType)
+	log.SetWriter(io.Discard)
+
+	session, err := testutil.GetBareSession()
+	if err != nil {
+		t.Fatalf("No cluster available: %v", err)
+	}
+	coll := session.Database(testDB).Collection("incremental")
+	if err := coll.Drop(context.Background()); err != nil {
+		t.Fatalf("Failed to drop collection: %v", err)
+	}
+	defer coll.Drop(context.Background())
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	export := func(limit int64) []string {
+		opts := simpleMongoExportOpts()
+		opts.Collection = coll.Name()
+		opts.InputOptions.IncrementalField = "n"
+		opts.InputOptions.StateFile = stateFile
+		opts.InputOptions.Limit = limit
+		me, err := New(opts)
+		So(err, ShouldBeNil)
+		defer me.Close()
+
+		out := &bytes.Buffer{}
+		_, err = me.Export(out)
+		So(err, ShouldBeNil)
+		return strings.Fields(out.String())
+	}
+
+	Convey("Repeated incremental exports only export new documents", t, func() {
+		_, err := coll.InsertMany(context.Background(), []interface{}{
+			bson.D{{"_id", 3}, {"n", 30}},
+			bson.D{{"_id", 1}, {"n", 10}},
+			bson.D{{"_id", 2}, {"n", 20}},
+		})
+		So(err, ShouldBeNil)
+		So(len(export(0)), ShouldEqual, 3)
+
+		_, err = coll.InsertOne(context.Background(), bson.D{{"_id", 4}, {"n", 40}})
+		So(err, ShouldBeNil)
+		docs := export(0)
+		So(len(docs), ShouldEqual, 1)
+		So(docs[0], ShouldContainSubstring, `"n":{"$numberInt":"40"}`)
+
+		So(len(export(0)), ShouldEqual, 0)
+		state, err := readExportState(stateFile, "n")
+		So(err, ShouldBeNil)
+		So(state.Watermark.Int32(), ShouldEqual, 40)
+		So(state.ID.Int32(), ShouldEqual, 4)
+
+		Convey("including the rest of the documents with the watermark after a --limit", func() {
+			_, err = coll.InsertMany(context.Background(), []interface{}{
+				bson.D{{"_id", 7}, {"n", 50}},
+				bson.D{{"_id", 5}, {"n", 50}},
+				bson.D{{"_id", 6}, {"n", 50}},
+			})
+			So(err, ShouldBeNil)
+			docs := export(2)
+			So(len(docs), ShouldEqual, 2)
+			So(docs[0], ShouldContainSubstring, `"_id":{"$numberInt":"5"}`)
+			So(docs[1], ShouldContainSubstring, `"_id":{"$numberInt":"6"}`)
+
+			docs = export(2)
+			So(len(docs), ShouldEqual, 1)
+			So(docs[0], ShouldContainSubstring, `"_id":{"$numberInt":"7"}`)
+			So(len(export(2)), ShouldEqual, 0)
+		})
+	})
+}
@@ -16,16 +16,20 @@ import (
 )
 
 // JSONExportOutput is an implementation of ExportOutput that writes documents
-// to the output in JSON format.
+// to the output in JSON format. Every layout ends with a newline: documents
+// are written one per line, as pretty printed documents each followed by a
+// newline, or as the elements of an array followed by a newline.
 type JSONExportOutput struct {
 	// ArrayOutput when set to true indicates that the output should be written
 	// as a JSON array, where each document is an element in the array.
 	ArrayOutput bool
 	// Pretty when set to true indicates that the output will be written in pretty mode.
 	PrettyOutput bool
-	Out          io.Writer
-	NumExported  int64
-	JSONFormat   JSONFormat
+	// Indent is the indentation of pretty output; it defaults to a tab.
+	Indent      string
+	Out         io.Writer
+	NumExported int64
+	JSONFormat  JSONFormat
 }
 
 // NewJSONExportOutput creates a new JSONExportOutput in array mode if specified,
@@ -37,11 +41,11 @@ func NewJSONExportOutput(
 	jsonFormat JSONFormat,
 ) *JSONExportOutput {
 	return &JSONExportOutput{
-		arrayOutput,
-		prettyOutput,
-		out,
-		0,
-		jsonFormat,
+		ArrayOutput:  arrayOutput,
+		PrettyOutput: prettyOutput,
+		Indent:       "\t",
+		Out:          out,
+		JSONFormat:   jsonFormat,
 	}
 }
 
@@ -49,7 +53,6 @@ func NewJSONExportOutput(
 // behaves as a no-op.
 func (jsonExporter *JSONExportOutput) WriteHeader() error {
 	if jsonExporter.ArrayOutput {
-		// TODO check # bytes written?
 		_, err := jsonExporter.Out.Write([]byte{json.ArrayStart})
 		if err != nil {
 			return err
@@ -58,22 +61,18 @@ func (jsonExporter *JSONExportOutput) WriteHeader() error {
 	return nil
 }
 
-// WriteFooter writes the closing square bracket if in array mode, otherwise it
-// behaves as a no-op.
+// WriteFooter writes the closing square bracket and the final newline if in
+// array mode, otherwise it behaves as a no-op.
 func (jsonExporter *JSONExportOutput) WriteFooter() error {
-	if jsonExporter.ArrayOutput {
-		_, err := jsonExporter.Out.Write([]byte{json.ArrayEnd, '\n'})
-		// TODO check # bytes written?
-		if err != nil {
-			return err
-		}
+	if !jsonExporter.ArrayOutput {
+		return nil
 	}
-	if jsonExporter.PrettyOutput {
-		if _, err := jsonExporter.Out.Write([]byte("\n")); err != nil {
-			return err
-		}
+	footer := []byte{json.ArrayEnd, '\n'}
+	if jsonExporter.PrettyOutput && jsonExporter.NumExported > 0 {
+		footer = append([]byte{'\n'}, footer...)
 	}
-	return nil
+	_, err := jsonExporter.Out.Write(footer)
+	return err
 }
 
 // Flush is a no-op for JSON export formats.
@@ -84,50 +83,43 @@ func (jsonExporter *JSONExportOutput) Flush() error {
 // ExportDocument converts the given document to extended JSON, and writes it
 // to the output.
 func (jsonExporter *JSONExportOutput) ExportDocument(document bson.D) error {
-	if jsonExporter.ArrayOutput || jsonExporter.PrettyOutput {
-		if jsonExporter.NumExported >= 1 {
-			if jsonExporter.ArrayOutput {
-				if _, err := jsonExporter.Out.Write([]byte(",")); err != nil {
-					return err
-				}
-			}
-			if jsonExporter.PrettyOutput {
-				if _, err := jsonExporter.Out.Write([]byte("\n")); err != nil {
-					return err
-				}
-			}
-		}
+	jsonOut, err := bsonutil.MarshalExtJSONReversible(
+		document,
+		jsonExporter.JSONFormat == Canonical,
+		false,
+	)
+	if err != nil {
+		return err
+	}
 
-		jsonOut, err := bsonutil.MarshalExtJSONReversible(
-			document,
-			jsonExporter.JSONFormat == Canonical,
-			false,
-		)
-		if err != nil {
-			return err
+	out := &bytes.Buffer{}
+	if jsonExporter.ArrayOutput {
+		if jsonExporter.NumExported > 0 {
+			out.WriteByte(',')
 		}
-
 		if jsonExporter.PrettyOutput {
-			var jsonFormatted bytes.Buffer
-			if err = json.Indent(&jsonFormatted, jsonOut, "", "\t"); err != nil {
-				return err
-			}
-			jsonOut = jsonFormatted.Bytes()
+			// each element goes on its own lines, indented inside the array
+			out.WriteByte('\n')
+			out.WriteString(jsonExporter.Indent)
 		}
-
-		if _, err = jsonExporter.Out.Write(jsonOut); err != nil {
-			return err
+	}
+	if jsonExporter.PrettyOutput {
+		prefix := ""
+		if jsonExporter.ArrayOutput {
+			prefix = jsonExporter.Indent
 		}
-	} else {
-		extendedDoc, err := bsonutil.MarshalExtJSONReversible(document, jsonExporter.JSONFormat == Canonical, false)
-		if err != nil {
+		if err = json.Indent(out, jsonOut, prefix, jsonExporter.Indent); err != nil {
 			return err
 		}
+	} else {
+		out.Write(jsonOut)
+	}
+	if !jsonExporter.ArrayOutput {
+		out.WriteByte('\n')
+	}
 
-		extendedDoc = append(extendedDoc, '\n')
-		if _, err = jsonExporter.Out.Write(extendedDoc); err != nil {
-			return err
-		}
+	if _, err = jsonExporter.Out.Write(out.Bytes()); err != nil {
+		return err
 	}
 	jsonExporter.NumExported++
 	return nil
@@ -100,3 +100,76 @@ func TestJSONArray(t *testing.T) {
 
 	})
 }
+
+func TestJSONLayouts(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	export := func(array, pretty bool, indent string, docs ...bson.D) string {
+		out := &bytes.Buffer{}
+		jsonExporter := NewJSONExportOutput(array, pretty, out, Relaxed)
+		if indent != "" {
+			jsonExporter.Indent = indent
+		}
+		So(jsonExporter.WriteHeader(), ShouldBeNil)
+		for _, doc := range docs {
+			So(jsonExporter.ExportDocument(doc), ShouldBeNil)
+		}
+		So(jsonExporter.WriteFooter(), ShouldBeNil)
+		return out.String()
+	}
+	a := bson.D{{"a", int32(1)}}
+	b := bson.D{{"b", int32(2)}}
+
+	Convey("Every JSON layout ends with a single newline", t, func() {
+		So(export(false, false, "", a, b), ShouldEqual, "{\"a\":1}\n{\"b\":2}\n")
+		So(export(false, false, ""), ShouldEqual, "")
+
+		So(export(true, false, "", a, b), ShouldEqual, "[{\"a\":1},{\"b\":2}]\n")
+		So(export(true, false, ""), ShouldEqual, "[]\n")
+
+		So(export(false, true, "", a, b), ShouldEqual, "{\n\t\"a\": 1\n}\n{\n\t\"b\": 2\n}\n")
+		So(export(false, true, "  ", a), ShouldEqual, "{\n  \"a\": 1\n}\n")
+
+		So(
+			export(true, true, "  ", a, b),
+			ShouldEqual,
+			"[\n  {\n    \"a\": 1\n  },\n  {\n    \"b\": 2\n  }\n]\n",
+		)
+		So(export(true, true, ""), ShouldEqual, "[]\n")
+	})
+
+	Convey("--jsonLayout selects the layout", t, func() {
+		opts := func(layout string) OutputFormatOptions {
+			return OutputFormatOptions{Type: JSON, JSONLayout: layout}
+		}
+
+		o := opts(jsonLayoutArray)
+		So(o.resolveJSONLayout(), ShouldBeNil)
+		So(o.JSONArray, ShouldBeTrue)
+		So(o.Pretty, ShouldBeFalse)
+
+		o = opts(jsonLayoutPretty)
+		o.JSONIndent = 2
+		So(o.resolveJSONLayout(), ShouldBeNil)
+		So(o.Pretty, ShouldBeTrue)
+
+		o = opts(jsonLayoutLines)
+		So(o.resolveJSONLayout(), ShouldBeNil)
+		So(o.JSONArray || o.Pretty, ShouldBeFalse)
+
+		o = opts(jsonLayoutLines)
+		o.Pretty = true
+		So(o.resolveJSONLayout(), ShouldNotBeNil)
+
+		o = opts(jsonLayoutPretty)
+		o.JSONArray = true
+		So(o.resolveJSONLayout(), ShouldNotBeNil)
+
+		o = opts("")
+		o.JSONIndent = 2
+		So(o.resolveJSONLayout(), ShouldNotBeNil)
+
+		o = opts("tree")
+		So(o.resolveJSONLayout(), ShouldNotBeNil)
+	})
+}
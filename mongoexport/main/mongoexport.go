@@ -12,6 +12,7 @@ import (
 
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/signals"
+	"github.com/mongodb/mongo-tools/common/telemetry"
 	"github.com/mongodb/mongo-tools/common/util"
 	"github.com/mongodb/mongo-tools/mongoexport"
 )
@@ -41,6 +42,12 @@ func main() {
 		return
 	}
 
+	if err := telemetry.Init(opts.AppName, opts.VersionStr, opts.OTelEndpoint); err != nil {
+		log.Logvf(log.Always, "error starting telemetry: %v", err)
+		os.Exit(util.ExitFailure)
+	}
+	defer telemetry.Shutdown()
+
 	exporter, err := mongoexport.New(opts)
 	if err != nil {
 		log.Logvf(log.Always, "%v", err)
@@ -49,14 +56,14 @@ func main() {
 			log.Logv(log.Always, se.Message)
 		}
 
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 	defer exporter.Close()
 
 	writer, err := exporter.GetOutputWriter()
 	if err != nil {
 		log.Logvf(log.Always, "error opening output stream: %v", err)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 	if writer == nil {
 		writer = os.Stdout
@@ -67,7 +74,7 @@ func main() {
 	numDocs, err := exporter.Export(writer)
 	if err != nil {
 		log.Logvf(log.Always, "Failed: %v", err)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 
 	if numDocs == 1 {
@@ -13,11 +13,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/mongodb/mongo-tools/common/bsonutil"
+	"github.com/mongodb/mongo-tools/common/csvexport"
 	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/fieldcrypt"
+	"github.com/mongodb/mongo-tools/common/fieldmap"
 	"github.com/mongodb/mongo-tools/common/json"
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/options"
@@ -51,6 +55,26 @@ const (
 	progressBarWaitTime = time.Second
 )
 
+// maxFailovers is how many times a --maxStaleness export resumes on another
+// node before giving up.
+const maxFailovers = 5
+
+// exportPosition tracks how far an export got, so that a resumable export can
+// continue after the last exported document on another node.
+type exportPosition struct {
+	count  int64
+	lastID bson.RawValue
+
+	// watermark is the last exported value of --incrementalField, and
+	// watermarkID the _id of the document it was exported with.
+	watermark   bson.RawValue
+	watermarkID bson.RawValue
+}
+
+func (pos *exportPosition) started() bool {
+	return pos != nil && pos.lastID.Type != 0
+}
+
 // MongoExport is a container for the user-specified options and
 // internal state used for running mongoexport.
 type MongoExport struct {
@@ -70,6 +94,18 @@ type MongoExport struct {
 
 	// Cached version of the collection info
 	collInfo *db.CollectionInfo
+
+	// The state of previous incremental exports, if any
+	incrementalState *exportState
+
+	// encrypts the values of --encryptFields, if set
+	encrypter *fieldcrypt.Cipher
+
+	// the reference fields of --resolveRefs
+	refSpecs []*RefSpec
+
+	// renames the fields of the exported documents, if --fieldMap is set
+	fieldMap *fieldmap.Map
 }
 
 // ExportOutput is an interface that specifies how a document should be formatted
@@ -107,6 +143,23 @@ func New(opts Options) (*MongoExport, error) {
 		}
 	}
 
+	exporter.encrypter, err = fieldcrypt.NewFromFlags(
+		opts.OutputFormatOptions.EncryptFields,
+		opts.OutputFormatOptions.EncryptionKeyFile,
+		EncryptFieldsOption,
+		EncryptionKeyFileOption,
+	)
+	if err != nil {
+		return nil, util.SetupError{Err: err}
+	}
+
+	if opts.OutputFormatOptions.FieldMap != "" {
+		exporter.fieldMap, err = fieldmap.Load(opts.OutputFormatOptions.FieldMap, false)
+		if err != nil {
+			return nil, util.SetupError{Err: err}
+		}
+	}
+
 	provider, err := db.NewSessionProvider(*opts.ToolOptions)
 	if err != nil {
 		return nil, util.SetupError{Err: err}
@@ -189,6 +242,38 @@ func (exp *MongoExport) validateSettings() error {
 		)
 	}
 
+	if err := exp.OutputOpts.resolveJSONLayout(); err != nil {
+		return err
+	}
+
+	if err := exp.validateRefSpecs(); err != nil {
+		return err
+	}
+
+	if len(exp.OutputOpts.FieldFormats) > 0 {
+		if exp.OutputOpts.Type != CSV {
+			return fmt.Errorf("%v can only be used with --type=csv", FieldFormatOption)
+		}
+		if _, err := ParseFieldFormats(exp.OutputOpts.FieldFormats); err != nil {
+			return err
+		}
+	}
+
+	if exp.OutputOpts.MaxFileSize < 0 {
+		return fmt.Errorf(
+			"%v must not be negative, got %v",
+			MaxFileSizeOption,
+			exp.OutputOpts.MaxFileSize,
+		)
+	}
+	if exp.OutputOpts.MaxFileSize > 0 && exp.OutputOpts.OutputFile == "" {
+		return fmt.Errorf("%v requires --out", MaxFileSizeOption)
+	}
+
+	if err := exp.validateParallelSettings(); err != nil {
+		return err
+	}
+
 	if exp.InputOpts.Query != "" && exp.InputOpts.ForceTableScan {
 		return fmt.Errorf("cannot use --forceTableScan when specifying --query")
 	}
@@ -214,13 +299,49 @@ func (exp *MongoExport) validateSettings() error {
 			return err
 		}
 	}
+
+	if exp.InputOpts != nil {
+		if err := exp.validateIncrementalSettings(); err != nil {
+			return err
+		}
+	}
+	if err := exp.validateExcludeFields(); err != nil {
+		return err
+	}
+	return exp.validateSampleSettings()
+}
+
+func (exp *MongoExport) validateIncrementalSettings() error {
+	field := exp.InputOpts.IncrementalField
+	switch {
+	case field == "" && exp.InputOpts.StateFile == "":
+		return nil
+	case field == "":
+		return fmt.Errorf("cannot use %v without %v", StateFileOption, IncrementalFieldOption)
+	case exp.InputOpts.StateFile == "":
+		return fmt.Errorf("cannot use %v without %v", IncrementalFieldOption, StateFileOption)
+	case exp.InputOpts.Sort != "":
+		return fmt.Errorf("cannot use --sort with %v", IncrementalFieldOption)
+	}
+
+	if exp.OutputOpts.Fields != "" {
+		for _, f := range strings.Split(exp.OutputOpts.Fields, ",") {
+			if field == f || strings.HasPrefix(field, f+".") {
+				return nil
+			}
+		}
+		return fmt.Errorf("--fields must include the %v '%v'", IncrementalFieldOption, field)
+	}
 	return nil
 }
 
 // GetOutputWriter opens and returns an io.WriteCloser for the output
 // options or nil if none is set. The caller is responsible for closing it.
+// With --maxFileSize or --numExportWorkers, the export writes its own part
+// files, so it is nil.
 func (exp *MongoExport) GetOutputWriter() (io.WriteCloser, error) {
-	if exp.OutputOpts.OutputFile != "" {
+	if exp.OutputOpts.OutputFile != "" && exp.OutputOpts.MaxFileSize == 0 &&
+		exp.OutputOpts.NumExportWorkers <= 1 {
 		// If the directory in which the output file is to be
 		// written does not exist, create it
 		fileDir := filepath.Dir(exp.OutputOpts.OutputFile)
@@ -275,9 +396,15 @@ func (exp *MongoExport) getCount() (int64, error) {
 	if exp.InputOpts != nil && exp.InputOpts.Limit != 0 {
 		return exp.InputOpts.Limit, nil
 	}
+	if exp.InputOpts != nil && exp.InputOpts.SampleSize != 0 {
+		return exp.InputOpts.SampleSize, nil
+	}
 	if exp.InputOpts != nil && exp.InputOpts.Query != "" {
 		return 0, nil
 	}
+	if exp.incrementalState != nil {
+		return 0, nil
+	}
 	coll := session.Database(exp.ToolOptions.Namespace.DB).
 		Collection(exp.ToolOptions.Namespace.Collection)
 
@@ -308,24 +435,27 @@ func (exp *MongoExport) getCount() (int64, error) {
 	return c, nil
 }
 
-// getCursor returns a cursor that can be iterated over to get all the documents
-// to export, based on the options given to mongoexport. Also returns the
-// associated session, so that it can be closed once the cursor is used up.
-func (exp *MongoExport) getCursor() (*mongo.Cursor, error) {
-	findOpts := mopt.Find()
-
-	if exp.InputOpts != nil && exp.InputOpts.Sort != "" {
-		sortD, err := getSortFromArg(exp.InputOpts.Sort)
-		if err != nil {
-			return nil, err
-		}
-
-		findOpts.SetSort(sortD)
+// canResume returns true if the export can be resumed by _id after the node
+// serving it becomes unavailable. This requires --maxStaleness and a scan of
+// the _id index, so it is not possible with --sort, --incrementalField, views,
+// system collections or collections without an _id index.
+func (exp *MongoExport) canResume() bool {
+	if exp.InputOpts == nil || exp.InputOpts.MaxStaleness == 0 || exp.InputOpts.Sort != "" ||
+		exp.InputOpts.IncrementalField != "" {
+		return false
 	}
+	if exp.collInfo == nil || exp.collInfo.IsView() || exp.collInfo.IsSystemCollection() {
+		return false
+	}
+	autoIndexId, err := bsonutil.FindValueByKey("autoIndexId", &exp.collInfo.Options)
+	return err != nil || autoIndexId == true
+}
 
+// getQuery returns the filter of the documents to export, from --query or
+// --queryFile and the watermark of --incrementalField.
+func (exp *MongoExport) getQuery() (bson.D, error) {
 	query := bson.D{}
 	if exp.InputOpts != nil && exp.InputOpts.HasQuery() {
-		var err error
 		content, err := exp.InputOpts.GetQuery()
 		if err != nil {
 			return nil, err
@@ -335,6 +465,37 @@ func (exp *MongoExport) getCursor() (*mongo.Cursor, error) {
 			return nil, fmt.Errorf("error parsing query as Extended JSON: %v", err)
 		}
 	}
+	if exp.incrementalState != nil {
+		query = incrementalQuery(query, exp.incrementalState)
+	}
+	return query, nil
+}
+
+// getCursor returns a cursor that can be iterated over to get all the documents
+// to export, based on the options given to mongoexport. If pos has started,
+// the cursor continues from the last exported _id, which it returns first.
+func (exp *MongoExport) getCursor(pos *exportPosition) (*mongo.Cursor, error) {
+	findOpts := mopt.Find()
+
+	if exp.InputOpts != nil && exp.InputOpts.Sort != "" {
+		sortD, err := getSortFromArg(exp.InputOpts.Sort)
+		if err != nil {
+			return nil, err
+		}
+
+		findOpts.SetSort(sortD)
+	}
+
+	if exp.InputOpts != nil && exp.InputOpts.IncrementalField != "" {
+		// exporting in order of the field and _id makes the last exported
+		// values the new watermark
+		findOpts.SetSort(incrementalSort(exp.InputOpts.IncrementalField))
+	}
+
+	query, err := exp.getQuery()
+	if err != nil {
+		return nil, err
+	}
 
 	session, err := exp.SessionProvider.GetSession()
 	if err != nil {
@@ -361,6 +522,10 @@ func (exp *MongoExport) getCursor() (*mongo.Cursor, error) {
 	noSorting := exp.InputOpts == nil || exp.InputOpts.Sort == ""
 	coll := intendedDB.Collection(exp.ToolOptions.Namespace.Collection)
 
+	if exp.InputOpts != nil && exp.InputOpts.SampleSize != 0 {
+		return exp.getSampleCursor(coll, query)
+	}
+
 	// we want to hint _id if shouldHintId is true, and there is no query, and
 	// there is no sorting, as hinting is not needed if there is a query or sorting.
 	// we also do not want to hint for system collections or views.
@@ -374,15 +539,24 @@ func (exp *MongoExport) getCursor() (*mongo.Cursor, error) {
 		}
 	}
 
-	if exp.InputOpts != nil {
-		findOpts.SetSkip(exp.InputOpts.Skip)
+	if exp.canResume() {
+		// walking the _id index gives a stable order to resume in
+		findOpts.SetHint(bson.D{{"_id", 1}})
 	}
-	if exp.InputOpts != nil {
+
+	if pos.started() {
+		// min is inclusive and ignores BSON type brackets, unlike $gt
+		findOpts.SetMin(bson.D{{"_id", pos.lastID}})
+		if exp.InputOpts.Limit > 0 {
+			findOpts.SetLimit(exp.InputOpts.Limit - pos.count + 1)
+		}
+	} else if exp.InputOpts != nil {
+		findOpts.SetSkip(exp.InputOpts.Skip)
 		findOpts.SetLimit(exp.InputOpts.Limit)
 	}
 
-	if len(exp.OutputOpts.Fields) > 0 {
-		findOpts.SetProjection(makeFieldSelector(exp.OutputOpts.Fields))
+	if projection := exp.projection(); projection != nil {
+		findOpts.SetProjection(projection)
 	}
 
 	return coll.Find(context.TODO(), query, findOpts)
@@ -428,10 +602,27 @@ func (exp *MongoExport) exportInternal(out io.Writer) (int64, error) {
 		return 0, err
 	}
 
+	if exp.InputOpts != nil && exp.InputOpts.IncrementalField != "" {
+		exp.incrementalState, err = readExportState(
+			exp.InputOpts.StateFile,
+			exp.InputOpts.IncrementalField,
+		)
+		if err != nil {
+			return 0, err
+		}
+		if exp.incrementalState != nil {
+			log.Logvf(log.Always, "exporting documents after %v",
+				exp.incrementalState.describe())
+		}
+	}
+
 	max, err := exp.getCount()
 	if err != nil {
 		return 0, err
 	}
+	if exp.OutputOpts.NumExportWorkers > 1 {
+		return exp.exportParallel(max)
+	}
 
 	watchProgressor := progress.NewCounter(max)
 	if exp.ProgressManager != nil {
@@ -444,55 +635,157 @@ func (exp *MongoExport) exportInternal(out io.Writer) (int64, error) {
 		defer exp.ProgressManager.Detach(name)
 	}
 
-	exportOutput, err := exp.getExportOutput(out)
-	if err != nil {
-		return 0, err
+	var exportOutput ExportOutput
+	if exp.OutputOpts.MaxFileSize > 0 {
+		exportOutput = newSplitOutput(
+			exp.OutputOpts.OutputFile,
+			exp.OutputOpts.MaxFileSize,
+			exp.getExportOutput,
+		)
+	} else {
+		exportOutput, err = exp.getExportOutput(out)
+		if err != nil {
+			return 0, err
+		}
 	}
 
-	cursor, err := exp.getCursor()
+	pos := &exportPosition{}
+	cursor, err := exp.getCursor(pos)
 	if err != nil {
 		return 0, err
 	}
-	defer cursor.Close(context.TODO())
 
 	// Write headers
 	err = exportOutput.WriteHeader()
 	if err != nil {
+		cursor.Close(context.TODO())
 		return 0, err
 	}
 
-	docsCount := int64(0)
-
 	// Write document content
-	for cursor.Next(context.TODO()) {
-		var result bson.D
-		if err := cursor.Decode(&result); err != nil {
-			return docsCount, err
+	resumable := exp.canResume()
+	for failovers := 0; ; failovers++ {
+		err = exp.exportDocuments(cursor, exportOutput, watchProgressor, pos)
+		cursor.Close(context.TODO())
+		if err == nil {
+			break
+		}
+		if !resumable || failovers >= maxFailovers || !db.IsNodeUnavailableError(err) {
+			return pos.count, err
 		}
 
-		err := exportOutput.ExportDocument(result)
-		if err != nil {
-			return docsCount, err
+		if pos.started() {
+			log.Logvf(log.Always,
+				"lost the node serving the export after %v %v (%v); resuming after _id %v",
+				pos.count, util.Pluralize(int(pos.count), "document", "documents"), err, pos.lastID)
+		} else {
+			log.Logvf(log.Always, "lost the node serving the export (%v); restarting", err)
 		}
-		docsCount++
-		if docsCount%watchProgressorUpdateFrequency == 0 {
-			watchProgressor.Set(docsCount)
+		cursor, err = exp.getCursor(pos)
+		if err != nil {
+			return pos.count, err
 		}
 	}
-	watchProgressor.Set(docsCount)
-	if err := cursor.Err(); err != nil {
-		return docsCount, err
-	}
 
 	// Write footers
 	err = exportOutput.WriteFooter()
 	if err != nil {
-		return docsCount, err
+		return pos.count, err
 	}
 	if err = exportOutput.Flush(); err != nil {
-		return docsCount, err
+		return pos.count, err
 	}
-	return docsCount, nil
+
+	if exp.InputOpts != nil && exp.InputOpts.IncrementalField != "" {
+		if err = exp.saveWatermark(pos); err != nil {
+			return pos.count, err
+		}
+	}
+	return pos.count, nil
+}
+
+// saveWatermark records the last exported value of --incrementalField in
+// --stateFile. If no document with the field was exported, the previous
+// watermark stays in place.
+func (exp *MongoExport) saveWatermark(pos *exportPosition) error {
+	if pos.watermark.Type == 0 {
+		log.Logvf(log.Info, "no new %v values exported; leaving %v unchanged",
+			exp.InputOpts.IncrementalField, exp.InputOpts.StateFile)
+		return nil
+	}
+	state := &exportState{
+		Field:     exp.InputOpts.IncrementalField,
+		Watermark: pos.watermark,
+		ID:        pos.watermarkID,
+	}
+	if err := writeExportState(exp.InputOpts.StateFile, state); err != nil {
+		return err
+	}
+	log.Logvf(log.Always, "saved watermark %v to %v", state.describe(), exp.InputOpts.StateFile)
+	return nil
+}
+
+// exportDocuments writes every document of the cursor to exportOutput and
+// advances pos past each one. A cursor resumed from pos returns the last
+// exported document again, so it is skipped.
+func (exp *MongoExport) exportDocuments(
+	cursor *mongo.Cursor,
+	exportOutput ExportOutput,
+	watchProgressor *progress.CountProgressor,
+	pos *exportPosition,
+) error {
+	skipFirst := pos.started()
+	excludeID := exp.excludesID()
+	for cursor.Next(context.TODO()) {
+		id := cursor.Current.Lookup("_id")
+		if skipFirst {
+			skipFirst = false
+			if id.Equal(pos.lastID) {
+				continue
+			}
+		}
+		if pos.started() && exp.InputOpts.Limit > 0 && pos.count >= exp.InputOpts.Limit {
+			break
+		}
+
+		var result bson.D
+		if err := cursor.Decode(&result); err != nil {
+			return err
+		}
+		if excludeID {
+			result = removeID(result)
+		}
+		if exp.encrypter != nil {
+			if err := exp.encrypter.EncryptDocument(result); err != nil {
+				return err
+			}
+		}
+
+		err := exportOutput.ExportDocument(result)
+		if err != nil {
+			return err
+		}
+		// the cursor reuses its buffer, so the _id has to be copied
+		pos.lastID = bson.RawValue{Type: id.Type, Value: append([]byte(nil), id.Value...)}
+		if exp.InputOpts != nil && exp.InputOpts.IncrementalField != "" {
+			value := lookupField(cursor.Current, exp.InputOpts.IncrementalField)
+			if value.Type != 0 && value.Type != bson.TypeNull {
+				pos.watermark = bson.RawValue{
+					Type:  value.Type,
+					Value: append([]byte(nil), value.Value...),
+				}
+				if exp.InputOpts.IncrementalField != "_id" {
+					pos.watermarkID = pos.lastID
+				}
+			}
+		}
+		pos.count++
+		if pos.count%watchProgressorUpdateFrequency == 0 {
+			watchProgressor.Set(pos.count)
+		}
+	}
+	watchProgressor.Set(pos.count)
+	return cursor.Err()
 }
 
 // Export executes the entire export operation. It returns an integer of the count
@@ -500,6 +793,7 @@ func (exp *MongoExport) exportInternal(out io.Writer) (int64, error) {
 // during the export operation.
 func (exp *MongoExport) Export(out io.Writer) (int64, error) {
 	count, err := exp.exportInternal(out)
+	exp.logUnresolvedRefs()
 	return count, err
 }
 
@@ -532,14 +826,41 @@ func (exp *MongoExport) getExportOutput(out io.Writer) (ExportOutput, error) {
 			}
 		}
 
-		return NewCSVExportOutput(exportFields, exp.OutputOpts.NoHeaderLine, out), nil
+		var fieldFormats map[string]*csvexport.FieldFormat
+		if len(exp.OutputOpts.FieldFormats) > 0 {
+			fieldFormats, err = ParseFieldFormats(exp.OutputOpts.FieldFormats)
+			if err != nil {
+				return nil, err
+			}
+			for field := range fieldFormats {
+				if field != csvexport.AllFieldsFormat && !slices.Contains(exportFields, field) {
+					return nil, fmt.Errorf(
+						"%v is given for field '%v', which is not exported",
+						FieldFormatOption,
+						field,
+					)
+				}
+			}
+		}
+		if exp.fieldMap != nil {
+			// the columns are looked up in the renamed documents
+			exportFields, fieldFormats = exp.renamedColumns(exportFields, fieldFormats)
+		}
+
+		csvOutput := csvexport.NewOutput(exportFields, exp.OutputOpts.NoHeaderLine, out)
+		csvOutput.FieldFormats = fieldFormats
+		return exp.resolvingRefs(exp.renamingFields(csvOutput)), nil
 	}
-	return NewJSONExportOutput(
+	jsonOutput := NewJSONExportOutput(
 		exp.OutputOpts.JSONArray,
 		exp.OutputOpts.Pretty,
 		out,
 		exp.OutputOpts.JSONFormat,
-	), nil
+	)
+	if exp.OutputOpts.JSONIndent > 0 {
+		jsonOutput.Indent = strings.Repeat(" ", exp.OutputOpts.JSONIndent)
+	}
+	return exp.resolvingRefs(exp.renamingFields(jsonOutput)), nil
 }
 
 // getObjectFromByteArg takes an object in extended JSON, and converts it to an object that
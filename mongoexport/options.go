@@ -9,10 +9,12 @@ package mongoexport
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/mongodb/mongo-tools/common/db"
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 var Usage = `<options> <connection-string>
@@ -23,6 +25,26 @@ Connection strings must begin with mongodb:// or mongodb+srv://.
 
 See http://docs.mongodb.com/database-tools/mongoexport/ for more information.`
 
+// MaxStalenessOption is the command line flag for InputOptions.MaxStaleness.
+const MaxStalenessOption = "--maxStaleness"
+
+// Layouts of JSON output, selected by --jsonLayout.
+const (
+	jsonLayoutLines  = "lines"
+	jsonLayoutArray  = "array"
+	jsonLayoutPretty = "pretty"
+)
+
+// Command line flags for field encryption.
+const (
+	EncryptFieldsOption     = "--encryptFields"
+	EncryptionKeyFileOption = "--encryptionKeyFile"
+	FieldFormatOption       = "--fieldFormat"
+)
+
+// minMaxStalenessSeconds is the smallest maxStalenessSeconds servers accept.
+const minMaxStalenessSeconds = 90
+
 // OutputFormatOptions defines the set of options to use in formatting exported data.
 type OutputFormatOptions struct {
 	// Fields is an option to directly specify comma-separated fields to export to CSV.
@@ -31,6 +53,9 @@ type OutputFormatOptions struct {
 	// FieldFile is a filename that refers to a list of fields to export, 1 per line.
 	FieldFile string `long:"fieldFile" value-name:"<filename>" description:"file with field names - 1 per line"`
 
+	// ExcludeFields is a comma-separated list of fields to leave out of the export.
+	ExcludeFields string `long:"excludeFields" value-name:"<field>[,<field>]*" description:"comma separated list of field names to leave out of the export, which may be nested e.g. \"_id,audit.createdBy\"; only _id can be excluded with --fields or --fieldFile"`
+
 	// Type selects the type of output to export as (json or csv).
 	Type string `long:"type" value-name:"<type>" default:"json" default-mask:"-" description:"the output format, either json or csv"`
 
@@ -40,6 +65,12 @@ type OutputFormatOptions struct {
 	// OutputFile specifies an output file path.
 	OutputFile string `long:"out" value-name:"<filename>" short:"o" description:"output file; if not specified, stdout is used"`
 
+	// MaxFileSize splits the output into part files of about this many bytes.
+	MaxFileSize int64 `long:"maxFileSize" value-name:"<bytes>" description:"split the output into part files of about this many bytes, named after --out with a part number (e.g. data-00001.json.gz for --out=data.json.gz), and write a manifest with the document count, size and SHA-256 checksum of each part to e.g. data.manifest.json. Each part is a complete CSV or JSON file; parts are gzip compressed if --out ends in .gz"`
+
+	// NumExportWorkers exports ranges of the collection concurrently.
+	NumExportWorkers int `long:"numExportWorkers" value-name:"<number>" description:"split the collection into ranges of its _id, or of its shard key on a mongos, and export them concurrently with this many workers into part files named and described in a manifest like those of --maxFileSize, in the order of the ranges; requires --out"`
+
 	// JSONArray if set will export the documents an array of JSON documents.
 	JSONArray bool `long:"jsonArray" description:"output to a JSON array rather than one object per line"`
 
@@ -49,8 +80,29 @@ type OutputFormatOptions struct {
 	// NoHeaderLine, if set, will export CSV data without a list of field names at the first line.
 	NoHeaderLine bool `long:"noHeaderLine" description:"export CSV data without a list of field names at the first line"`
 
+	// JSONLayout specifies how the JSON documents are laid out in the output.
+	JSONLayout string `long:"jsonLayout" value-name:"<layout>" description:"how to lay out JSON output: 'lines' for one document per line (NDJSON), 'array' for a JSON array, or 'pretty' for indented documents. Use --pretty with 'array' to indent the elements of the array. Defaults to 'lines', or to the layout that --jsonArray and --pretty select"`
+
+	// JSONIndent sets the indentation of pretty JSON output.
+	JSONIndent int `long:"jsonIndent" value-name:"<spaces>" description:"number of spaces to indent pretty JSON output with; 0 indents with tabs (default 0)"`
+
 	// JSONFormat specifies what extended JSON format to export (canonical or relaxed). Defaults to relaxed.
 	JSONFormat JSONFormat `long:"jsonFormat" value-name:"<type>" default:"relaxed" description:"the extended JSON format to output, either canonical or relaxed (defaults to 'relaxed')"`
+
+	// FieldFormats set how the values of CSV fields are written.
+	FieldFormats []string `long:"fieldFormat" value-name:"<field>:<directive>=<value>[,<directive>=<value>]*" description:"how to write the values of a field to CSV (may be specified multiple times, and with '*' as the field for all other fields), with the directives date=<Go time layout, e.g. 2006-01-02, or unix or unixms>, precision=<digits after the decimal point>, bool=<true>/<false> (e.g. bool=1/0) and null=<text>, e.g. --fieldFormat='price:precision=2,null=0'"`
+
+	// ResolveRefs embeds the documents that reference fields refer to.
+	ResolveRefs []string `long:"resolveRefs" value-name:"<field>[:<setting>=<value>[,<setting>=<value>]*]" description:"replace the DBRefs or foreign keys of a field by the documents they refer to, looked up in batches, with the settings from=<collection holding the keys; without it, the field holds DBRefs>, db=<database of from>, key=<field of from matched, default _id>, include=<field to embed, may be given more than once; default the whole document> and as=<field to embed into, default the field itself>, e.g. --resolveRefs='customerId:from=customers,include=name,include=email,as=customer' (may be specified multiple times for different fields)"`
+
+	// EncryptFields lists the fields whose values are encrypted in the output.
+	EncryptFields string `long:"encryptFields" value-name:"<field>[,<field>]*" description:"comma separated list of fields whose values are encrypted with AES-256-GCM and the key in --encryptionKeyFile, where a dotted field applies to every document of the arrays it crosses; mongoimport --decryptFields restores them"`
+
+	// EncryptionKeyFile holds the base64 encoded key for EncryptFields.
+	EncryptionKeyFile string `long:"encryptionKeyFile" value-name:"<filename>" description:"file with the base64 encoded 32 byte key for --encryptFields, e.g. created with 'openssl rand -base64 32'"`
+
+	// FieldMap is a file mapping the field names of the collection to those of the output.
+	FieldMap string `long:"fieldMap" value-name:"<filename>" description:"YAML or JSON file mapping field names of the collection to the names written to the output, e.g. 'address.zipCode: address.postal_code', where each part of a dotted path is renamed; --fields, --fieldFile and the other options use the names of the collection. mongoimport --fieldMap with the same file renames them back"`
 }
 
 // Name returns a human-readable group name for output format options.
@@ -69,6 +121,11 @@ type InputOptions struct {
 	Limit          int64  `long:"limit" value-name:"<count>" description:"limit the number of documents to export"`
 	Sort           string `long:"sort" value-name:"<json>" description:"sort order, as a JSON string, e.g. '{x:1}'"`
 	AssertExists   bool   `long:"assertExists" description:"if specified, export fails if the collection does not exist"`
+	MaxStaleness   int64  `long:"maxStaleness" value-name:"<seconds>" description:"read from a secondary at most this many seconds behind the primary (at least 90); if that node becomes unavailable mid-export, resume from the last exported _id on another eligible secondary"`
+	SampleSize     int64  `long:"sampleSize" value-name:"<count>" description:"export a uniform random sample of this many of the documents matching --query, drawn with $sample, or by reading all of them on servers without $sample. Exports all of the documents if there are fewer"`
+
+	IncrementalField string `long:"incrementalField" value-name:"<field>" description:"only export documents whose value for this field (e.g. updatedAt or _id) is after the watermark in --stateFile, in ascending order of the field and then _id"`
+	StateFile        string `long:"stateFile" value-name:"<filename>" description:"file holding the highest --incrementalField value exported so far and the highest _id exported with it; it is created if missing and replaced once the export succeeds"`
 }
 
 // Name returns a human-readable group name for input options.
@@ -101,6 +158,46 @@ type Options struct {
 	ParsedArgs []string
 }
 
+// withMaxStaleness returns rp with --maxStaleness applied. Without an explicit
+// read preference, --maxStaleness reads from secondaryPreferred.
+func withMaxStaleness(
+	rp *readpref.ReadPref,
+	seconds int64,
+	explicitReadPref bool,
+) (*readpref.ReadPref, error) {
+	if seconds < minMaxStalenessSeconds {
+		return nil, fmt.Errorf(
+			"%v must be at least %v seconds, got %v",
+			MaxStalenessOption,
+			minMaxStalenessSeconds,
+			seconds,
+		)
+	}
+	if _, set := rp.MaxStaleness(); set {
+		return nil, fmt.Errorf(
+			"cannot use %v when the read preference already sets maxStalenessSeconds",
+			MaxStalenessOption,
+		)
+	}
+
+	mode := rp.Mode()
+	if mode == readpref.PrimaryMode {
+		if explicitReadPref {
+			return nil, fmt.Errorf(
+				"cannot use %v with a primary read preference",
+				MaxStalenessOption,
+			)
+		}
+		mode = readpref.SecondaryPreferredMode
+	}
+
+	return readpref.New(
+		mode,
+		readpref.WithTagSets(rp.TagSets()...),
+		readpref.WithMaxStaleness(time.Duration(seconds)*time.Second),
+	)
+}
+
 // ParseOptions reads command line arguments and converts them into options that can be used to configure mongoexport.
 func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, error) {
 	// initialize command-line opts
@@ -149,6 +246,20 @@ func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, erro
 		return Options{}, fmt.Errorf("error parsing --readPreference: %v", err)
 	}
 
+	if inputOpts.MaxStaleness != 0 {
+		cs := opts.URI.ParsedConnString()
+		explicitReadPref := inputOpts.ReadPreference != "" ||
+			(cs != nil && cs.ReadPreference != "")
+		opts.ReadPreference, err = withMaxStaleness(
+			opts.ReadPreference,
+			inputOpts.MaxStaleness,
+			explicitReadPref,
+		)
+		if err != nil {
+			return Options{}, err
+		}
+	}
+
 	return Options{
 		opts,
 		outputOpts,
@@ -156,3 +267,47 @@ func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, erro
 		extraArgs,
 	}, nil
 }
+
+// resolveJSONLayout checks --jsonLayout and --jsonIndent, and sets JSONArray
+// and Pretty to the layout that --jsonLayout selects.
+func (outputOpts *OutputFormatOptions) resolveJSONLayout() error {
+	if outputOpts.Type == CSV {
+		if outputOpts.JSONLayout != "" || outputOpts.JSONIndent != 0 {
+			return fmt.Errorf("--jsonLayout and --jsonIndent can only be used with --type=json")
+		}
+		return nil
+	}
+
+	switch outputOpts.JSONLayout {
+	case "":
+	case jsonLayoutLines:
+		if outputOpts.JSONArray || outputOpts.Pretty {
+			return fmt.Errorf("cannot use --jsonArray or --pretty with --jsonLayout=lines")
+		}
+	case jsonLayoutArray:
+		outputOpts.JSONArray = true
+	case jsonLayoutPretty:
+		if outputOpts.JSONArray {
+			return fmt.Errorf(
+				"cannot use --jsonArray with --jsonLayout=pretty; use --jsonLayout=array --pretty",
+			)
+		}
+		outputOpts.Pretty = true
+	default:
+		return fmt.Errorf(
+			"invalid --jsonLayout '%v', choose '%v', '%v' or '%v'",
+			outputOpts.JSONLayout,
+			jsonLayoutLines,
+			jsonLayoutArray,
+			jsonLayoutPretty,
+		)
+	}
+
+	if outputOpts.JSONIndent < 0 {
+		return fmt.Errorf("--jsonIndent must not be negative, got %v", outputOpts.JSONIndent)
+	}
+	if outputOpts.JSONIndent > 0 && !outputOpts.Pretty {
+		return fmt.Errorf("--jsonIndent requires --pretty or --jsonLayout=pretty")
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ package mongoexport
 
 import (
 	"testing"
+	"time"
 
 	"github.com/mongodb/mongo-tools/common/options"
 	"github.com/mongodb/mongo-tools/common/testtype"
@@ -140,6 +141,83 @@ func TestParseOptions(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("TestMaxStaleness", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			args          []string
+			expectSuccess bool
+			expectedMode  readpref.Mode
+		}{
+			{
+				"defaults to secondaryPreferred",
+				[]string{"--maxStaleness", "120"},
+				true,
+				readpref.SecondaryPreferredMode,
+			},
+			{
+				"keeps an explicit secondary mode",
+				[]string{"--maxStaleness", "120", "--readPreference", "secondary"},
+				true,
+				readpref.SecondaryMode,
+			},
+			{
+				"keeps the mode from the URI",
+				[]string{
+					"--maxStaleness", "120",
+					"--uri", "mongodb://localhost:27017/db?readPreference=nearest",
+				},
+				true,
+				readpref.NearestMode,
+			},
+			{
+				"errors below the minimum",
+				[]string{"--maxStaleness", "30"},
+				false,
+				0,
+			},
+			{
+				"errors with an explicit primary mode",
+				[]string{"--maxStaleness", "120", "--readPreference", "primary"},
+				false,
+				0,
+			},
+			{
+				"errors if the read preference sets maxStalenessSeconds",
+				[]string{
+					"--maxStaleness", "120",
+					"--readPreference", `{mode: "secondary", maxStalenessSeconds: 100}`,
+				},
+				false,
+				0,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				opts, err := ParseOptions(tc.args, "", "")
+				success := err == nil
+				if success != tc.expectSuccess {
+					t.Fatalf("expected err to be nil: %v; got error %v", tc.expectSuccess, err)
+				}
+				if !tc.expectSuccess {
+					return
+				}
+
+				rp := opts.ToolOptions.ReadPreference
+				if rp.Mode() != tc.expectedMode {
+					t.Fatalf(
+						"read preference mode mismatch; expected %v, got %v",
+						tc.expectedMode,
+						rp.Mode(),
+					)
+				}
+				if maxStaleness, _ := rp.MaxStaleness(); maxStaleness != 120*time.Second {
+					t.Fatalf("expected max staleness of 120s, got %v", maxStaleness)
+				}
+			})
+		}
+	})
 }
 
 type PositionalArgumentTestCase struct {
@@ -0,0 +1,346 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mongodb/mongo-tools/common/bsonutil"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/progress"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NumExportWorkersOption is the command line flag for
+// OutputFormatOptions.NumExportWorkers.
+const NumExportWorkersOption = "--numExportWorkers"
+
+const (
+	// rangesPerWorker is how many ranges the collection is split into for
+	// each worker, so that the workers whose ranges are quick to export take
+	// over more of them.
+	rangesPerWorker = 4
+	// samplesPerRange is how many documents $sample reads for each range
+	// when the ranges can't be found with splitVector.
+	samplesPerRange = 20
+)
+
+// exportRange is a range of values of the key pattern the collection is
+// split on, from min, inclusive, to max, exclusive. A nil bound is open.
+type exportRange struct {
+	min, max bson.D
+}
+
+// validateParallelSettings checks that --numExportWorkers can be used with
+// the other options.
+func (exp *MongoExport) validateParallelSettings() error {
+	workers := exp.OutputOpts.NumExportWorkers
+	if workers < 0 {
+		return fmt.Errorf("%v must not be negative, got %v", NumExportWorkersOption, workers)
+	}
+	if workers <= 1 {
+		return nil
+	}
+	switch {
+	case exp.OutputOpts.OutputFile == "":
+		return fmt.Errorf("%v requires --out", NumExportWorkersOption)
+	case exp.OutputOpts.MaxFileSize > 0:
+		return fmt.Errorf("cannot use %v with %v", NumExportWorkersOption, MaxFileSizeOption)
+	case exp.InputOpts == nil:
+		return nil
+	case exp.InputOpts.Sort != "":
+		return fmt.Errorf("cannot use %v with --sort", NumExportWorkersOption)
+	case exp.InputOpts.Skip != 0 || exp.InputOpts.Limit != 0:
+		return fmt.Errorf("cannot use %v with --skip or --limit", NumExportWorkersOption)
+	case exp.InputOpts.IncrementalField != "":
+		return fmt.Errorf("cannot use %v with %v", NumExportWorkersOption, IncrementalFieldOption)
+	case exp.InputOpts.MaxStaleness != 0:
+		return fmt.Errorf("cannot use %v with %v", NumExportWorkersOption, MaxStalenessOption)
+	}
+	return nil
+}
+
+// exportParallel splits the collection into ranges of its _id, or of its
+// shard key, and exports them concurrently with --numExportWorkers workers,
+// each range into its own part file. The parts are named and described in a
+// manifest like the parts of --maxFileSize, in the order of the ranges.
+func (exp *MongoExport) exportParallel(total int64) (int64, error) {
+	if exp.collInfo.IsView() || exp.collInfo.IsTimeseries() {
+		return 0, fmt.Errorf("cannot use %v to export a %v", NumExportWorkersOption,
+			exp.collInfo.Type)
+	}
+	session, err := exp.SessionProvider.GetSession()
+	if err != nil {
+		return 0, err
+	}
+	ns := exp.ToolOptions.Namespace
+	coll := session.Database(ns.DB).Collection(ns.Collection)
+
+	key := exp.splitKey(session)
+	workers := exp.OutputOpts.NumExportWorkers
+	points, err := exp.splitPoints(coll, key, workers*rangesPerWorker)
+	if err != nil {
+		return 0, err
+	}
+	ranges := rangesBetween(points)
+	log.Logvf(
+		log.Always,
+		"exporting %v in %v %v of %v with %v workers",
+		ns.String(),
+		len(ranges),
+		util.Pluralize(len(ranges), "range", "ranges"),
+		bsonutil.CreateExtJSONString(key),
+		workers,
+	)
+
+	out := exp.OutputOpts.OutputFile
+	if err := os.MkdirAll(filepath.Dir(out), 0750); err != nil {
+		return 0, err
+	}
+	parts := make([]manifestPart, len(ranges))
+	err = exportRanges(min(workers, len(ranges)), len(ranges), func(i int) error {
+		name := fmt.Sprintf("%v [%v/%v]", ns.String(), i+1, len(ranges))
+		progressor := progress.NewCounter(total / int64(len(ranges)))
+		if exp.ProgressManager != nil {
+			exp.ProgressManager.Attach(name, progressor)
+			defer exp.ProgressManager.Detach(name)
+		}
+		part, err := exp.exportRange(coll, key, ranges[i], partPath(out, i+1), progressor)
+		parts[i] = part
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	manifest := exportManifest{Parts: parts}
+	for _, part := range parts {
+		manifest.Documents += part.Documents
+	}
+	if err := writeManifest(out, manifest); err != nil {
+		return manifest.Documents, err
+	}
+	return manifest.Documents, nil
+}
+
+// exportRanges calls export for each of count ranges with numWorkers
+// workers, and returns the first error. No more ranges are started once one
+// fails.
+func exportRanges(numWorkers, count int, export func(i int) error) error {
+	indexes := make(chan int)
+	errs := make(chan error, numWorkers)
+	var wg sync.WaitGroup
+	var failed sync.Once
+	stop := make(chan struct{})
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := export(i); err != nil {
+					errs <- err
+					failed.Do(func() { close(stop) })
+					return
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for i := 0; i < count; i++ {
+		select {
+		case indexes <- i:
+		case <-stop:
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// exportRange exports the documents of r to the part file at path.
+func (exp *MongoExport) exportRange(
+	coll *mongo.Collection,
+	key bson.D,
+	r exportRange,
+	path string,
+	progressor *progress.CountProgressor,
+) (manifestPart, error) {
+	query, err := exp.getQuery()
+	if err != nil {
+		return manifestPart{}, err
+	}
+	// min and max ignore BSON type brackets, unlike $gte and $lt, so every
+	// document is in exactly one range
+	findOpts := mopt.Find().SetHint(key)
+	if r.min != nil {
+		findOpts.SetMin(r.min)
+	}
+	if r.max != nil {
+		findOpts.SetMax(r.max)
+	}
+	if projection := exp.projection(); projection != nil {
+		findOpts.SetProjection(projection)
+	}
+	cursor, err := coll.Find(context.TODO(), query, findOpts)
+	if err != nil {
+		return manifestPart{}, err
+	}
+	defer cursor.Close(context.TODO())
+
+	part, err := createPart(path, exp.OutputOpts.OutputFile, exp.getExportOutput)
+	if err != nil {
+		return manifestPart{}, err
+	}
+	pos := &exportPosition{}
+	if err := exp.exportDocuments(cursor, part.output, progressor, pos); err != nil {
+		_, _ = part.close()
+		return manifestPart{}, err
+	}
+	part.part.Documents = pos.count
+	return part.close()
+}
+
+// splitKey returns the key pattern to split the collection on: the shard key
+// of a sharded collection, if it only has ascending fields, so that each
+// range is read from few shards, or else the _id.
+func (exp *MongoExport) splitKey(session *mongo.Client) bson.D {
+	idKey := bson.D{{"_id", 1}}
+	isMongos, err := exp.SessionProvider.IsMongos()
+	if err != nil || !isMongos {
+		return idKey
+	}
+	var config struct {
+		Key bson.D `bson:"key"`
+	}
+	err = session.Database("config").Collection("collections").
+		FindOne(context.TODO(), bson.D{{"_id", exp.ToolOptions.Namespace.String()}}).
+		Decode(&config)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Logvf(log.Info, "error reading the shard key, splitting on _id: %v", err)
+		}
+		return idKey
+	}
+	if len(config.Key) == 0 {
+		return idKey
+	}
+	for _, elem := range config.Key {
+		if direction, err := util.ToInt(elem.Value); err != nil || direction != 1 {
+			log.Logvf(log.Info, "the shard key is not ascending, splitting on _id")
+			return idKey
+		}
+	}
+	return config.Key
+}
+
+// splitPoints returns the values of key at which to split the collection
+// into about n ranges of the same number of documents. They are found with
+// splitVector, or, where it is not available, as on a mongos, from a $sample
+// of the collection.
+func (exp *MongoExport) splitPoints(coll *mongo.Collection, key bson.D, n int) ([]bson.D, error) {
+	count, err := coll.EstimatedDocumentCount(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	if n <= 1 || count == 0 {
+		return nil, nil
+	}
+
+	var result struct {
+		SplitKeys []bson.D `bson:"splitKeys"`
+	}
+	err = coll.Database().RunCommand(context.TODO(), bson.D{
+		{"splitVector", exp.ToolOptions.Namespace.String()},
+		{"keyPattern", key},
+		{"maxChunkObjects", count/int64(n) + 1},
+	}).Decode(&result)
+	if err == nil {
+		return dedupePoints(result.SplitKeys), nil
+	}
+	log.Logvf(log.Info, "splitVector is not available (%v); sampling the collection instead", err)
+
+	project := bson.D{{"_id", 0}}
+	sort := bson.D{}
+	for i, elem := range key {
+		name := fmt.Sprintf("k%v", i)
+		project = append(project, bson.E{name, "$" + elem.Key})
+		sort = append(sort, bson.E{name, 1})
+	}
+	cursor, err := coll.Aggregate(context.TODO(), mongo.Pipeline{
+		{{"$sample", bson.D{{"size", n * samplesPerRange}}}},
+		{{"$project", project}},
+		{{"$sort", sort}},
+	}, mopt.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("error sampling the collection: %v", err)
+	}
+	var samples []bson.D
+	for cursor.Next(context.TODO()) {
+		point := bson.D{}
+		for i, elem := range key {
+			value := cursor.Current.Lookup(fmt.Sprintf("k%v", i))
+			if value.Type == 0 {
+				break
+			}
+			point = append(point, bson.E{elem.Key, value})
+		}
+		// a document missing a field of the key can't bound a range
+		if len(point) == len(key) {
+			samples = append(samples, point)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error sampling the collection: %v", err)
+	}
+	_ = cursor.Close(context.TODO())
+
+	step := max(1, len(samples)/n)
+	var points []bson.D
+	for i := step; i < len(samples); i += step {
+		points = append(points, samples[i])
+	}
+	return dedupePoints(points), nil
+}
+
+// dedupePoints removes the split points equal to the one before them, which
+// would bound empty ranges.
+func dedupePoints(points []bson.D) []bson.D {
+	var deduped []bson.D
+	var last []byte
+	for _, point := range points {
+		raw, err := bson.Marshal(point)
+		if err != nil || bytes.Equal(raw, last) {
+			continue
+		}
+		last = raw
+		deduped = append(deduped, point)
+	}
+	return deduped
+}
+
+// rangesBetween returns the ranges that points split the key into.
+func rangesBetween(points []bson.D) []exportRange {
+	ranges := make([]exportRange, 0, len(points)+1)
+	var lower bson.D
+	for _, point := range points {
+		ranges = append(ranges, exportRange{min: lower, max: point})
+		lower = point
+	}
+	return append(ranges, exportRange{min: lower})
+}
@@ -0,0 +1,102 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestValidateParallelSettings(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With --numExportWorkers", t, func() {
+		exp := &MongoExport{
+			OutputOpts: &OutputFormatOptions{NumExportWorkers: 4, OutputFile: "data.json"},
+			InputOpts:  &InputOptions{},
+		}
+
+		Convey("a valid configuration passes", func() {
+			So(exp.validateParallelSettings(), ShouldBeNil)
+		})
+
+		Convey("one worker exports as usual", func() {
+			exp.OutputOpts.NumExportWorkers = 1
+			exp.OutputOpts.OutputFile = ""
+			So(exp.validateParallelSettings(), ShouldBeNil)
+		})
+
+		Convey("the count must not be negative", func() {
+			exp.OutputOpts.NumExportWorkers = -1
+			So(exp.validateParallelSettings(), ShouldNotBeNil)
+		})
+
+		Convey("--out is required", func() {
+			exp.OutputOpts.OutputFile = ""
+			So(exp.validateParallelSettings(), ShouldNotBeNil)
+		})
+
+		Convey("options that depend on the order of a single cursor are rejected", func() {
+			for _, set := range []func(){
+				func() { exp.OutputOpts.MaxFileSize = 1 << 20 },
+				func() { exp.InputOpts.Sort = "{a: 1}" },
+				func() { exp.InputOpts.Skip = 10 },
+				func() { exp.InputOpts.Limit = 10 },
+				func() { exp.InputOpts.IncrementalField = "updatedAt" },
+				func() { exp.InputOpts.MaxStaleness = 90 },
+			} {
+				exp.OutputOpts.MaxFileSize = 0
+				exp.InputOpts = &InputOptions{}
+				set()
+				So(exp.validateParallelSettings(), ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestExportRanges(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("Split points bound consecutive ranges", t, func() {
+		So(rangesBetween(nil), ShouldResemble, []exportRange{{}})
+
+		a, b := bson.D{{"_id", int32(10)}}, bson.D{{"_id", int32(20)}}
+		So(rangesBetween(dedupePoints([]bson.D{a, a, b})), ShouldResemble, []exportRange{
+			{max: a},
+			{min: a, max: b},
+			{min: b},
+		})
+	})
+
+	Convey("Every range is exported once", t, func() {
+		var mu sync.Mutex
+		exported := map[int]int{}
+		err := exportRanges(3, 10, func(i int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			exported[i]++
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(exported, ShouldHaveLength, 10)
+		for _, n := range exported {
+			So(n, ShouldEqual, 1)
+		}
+	})
+
+	Convey("The first error stops the export", t, func() {
+		err := exportRanges(2, 100, func(i int) error {
+			return fmt.Errorf("range %v failed", i)
+		})
+		So(err, ShouldNotBeNil)
+	})
+}
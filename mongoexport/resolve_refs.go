@@ -0,0 +1,479 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ResolveRefsOption is the command line flag for OutputFormatOptions.ResolveRefs.
+const ResolveRefsOption = "--resolveRefs"
+
+// refBatchSize is the number of exported documents whose references are
+// looked up together.
+const refBatchSize = 1000
+
+// RefSpec is a reference field resolved by a --resolveRefs of the form
+// <field>[:<setting>=<value>[,<setting>=<value>]*] with the settings:
+//
+//	from=<collection>  the collection the values of the field are keys of;
+//	                   without it, the values must be DBRefs
+//	db=<database>      the database of from (default: the database exported)
+//	key=<field>        the field of from the values are matched against
+//	                   (default: _id)
+//	include=<field>    a field of the referenced documents to embed (may be
+//	                   given more than once; default: the whole document)
+//	as=<field>         where to embed the referenced documents (default: in
+//	                   place of the reference)
+//
+// A field holding an array of references is resolved into an array of
+// documents. References to no document are left as they are.
+type RefSpec struct {
+	Field   string
+	From    string
+	DB      string
+	Key     string
+	Include []string
+	As      string
+
+	// the number of references that matched no document
+	unresolved atomic.Int64
+}
+
+// ParseRefSpecs parses the --resolveRefs options. defaultDB is the database
+// of the collections given without db.
+func ParseRefSpecs(args []string, defaultDB string) ([]*RefSpec, error) {
+	var specs []*RefSpec
+	for _, arg := range args {
+		field, settings, hasSettings := strings.Cut(arg, ":")
+		if field == "" || (hasSettings && settings == "") {
+			return nil, fmt.Errorf(
+				"invalid %v '%v': must be <field>[:<setting>=<value>[,<setting>=<value>]*]",
+				ResolveRefsOption,
+				arg,
+			)
+		}
+		spec := &RefSpec{Field: field, Key: "_id", As: field}
+		if hasSettings {
+			for _, setting := range strings.Split(settings, ",") {
+				if err := spec.parseSetting(setting); err != nil {
+					return nil, fmt.Errorf("invalid %v for field '%v': %v", ResolveRefsOption, field, err)
+				}
+			}
+		}
+		switch {
+		case spec.From == "" && (spec.DB != "" || spec.Key != "_id"):
+			return nil, fmt.Errorf(
+				"invalid %v for field '%v': db and key need from; DBRefs name their collection",
+				ResolveRefsOption,
+				field,
+			)
+		case slices.ContainsFunc(specs, func(other *RefSpec) bool { return other.Field == field }):
+			return nil, fmt.Errorf("%v is given more than once for field '%v'", ResolveRefsOption, field)
+		}
+		if spec.DB == "" {
+			spec.DB = defaultDB
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func (spec *RefSpec) parseSetting(setting string) error {
+	name, value, ok := strings.Cut(setting, "=")
+	if !ok || value == "" {
+		return fmt.Errorf("setting '%v' has no value", setting)
+	}
+	switch name {
+	case "from":
+		if err := util.ValidateCollectionGrammar(value); err != nil {
+			return err
+		}
+		spec.From = value
+	case "db":
+		if err := util.ValidateDBName(value); err != nil {
+			return err
+		}
+		spec.DB = value
+	case "key":
+		spec.Key = value
+	case "include":
+		spec.Include = append(spec.Include, value)
+	case "as":
+		spec.As = value
+	default:
+		return fmt.Errorf("unknown setting '%v'", name)
+	}
+	return nil
+}
+
+// validateRefSpecs parses --resolveRefs. The reference fields must not be
+// encrypted, since they are resolved after the document is encrypted.
+func (exp *MongoExport) validateRefSpecs() error {
+	specs, err := ParseRefSpecs(exp.OutputOpts.ResolveRefs, exp.ToolOptions.Namespace.DB)
+	if err != nil {
+		return err
+	}
+	encrypted := strings.Split(exp.OutputOpts.EncryptFields, ",")
+	for _, spec := range specs {
+		if slices.Contains(encrypted, spec.Field) {
+			return fmt.Errorf(
+				"cannot use %v for field '%v', which is in %v",
+				ResolveRefsOption,
+				spec.Field,
+				EncryptFieldsOption,
+			)
+		}
+	}
+	exp.refSpecs = specs
+	return nil
+}
+
+// refTarget is a collection that references point into.
+type refTarget struct {
+	db, collection string
+}
+
+// refResolver looks up the documents that the fields of --resolveRefs refer
+// to and embeds them into the exported documents.
+type refResolver struct {
+	specs []*RefSpec
+	// find returns the documents of target that match filter
+	find func(target refTarget, filter bson.D, opts *options.FindOptions) ([]bson.D, error)
+}
+
+// resolve embeds the documents referenced by docs, looking up the
+// references of each spec with one query per referenced collection.
+func (r *refResolver) resolve(docs []bson.D) error {
+	for _, spec := range r.specs {
+		keys := map[refTarget]map[string]interface{}{}
+		for _, doc := range docs {
+			value, ok := lookupD(doc, spec.Field)
+			if !ok {
+				continue
+			}
+			for _, ref := range refElements(value) {
+				target, key, ok := spec.reference(ref)
+				if !ok {
+					continue
+				}
+				if keys[target] == nil {
+					keys[target] = map[string]interface{}{}
+				}
+				keys[target][refKey(key)] = key
+			}
+		}
+
+		found := map[refTarget]map[string]bson.D{}
+		for target, values := range keys {
+			docsByKey, err := r.lookup(spec, target, values)
+			if err != nil {
+				return err
+			}
+			found[target] = docsByKey
+		}
+
+		for i, doc := range docs {
+			value, ok := lookupD(doc, spec.Field)
+			if !ok {
+				continue
+			}
+			resolveOne := func(ref interface{}) interface{} {
+				target, key, ok := spec.reference(ref)
+				if !ok {
+					return ref
+				}
+				if referenced, ok := found[target][refKey(key)]; ok {
+					return referenced
+				}
+				spec.unresolved.Add(1)
+				return ref
+			}
+			var resolved interface{}
+			if array, ok := value.(bson.A); ok {
+				elements := make(bson.A, len(array))
+				for j, ref := range array {
+					elements[j] = resolveOne(ref)
+				}
+				resolved = elements
+			} else {
+				resolved = resolveOne(value)
+			}
+			docs[i] = setD(doc, spec.As, resolved)
+		}
+	}
+	return nil
+}
+
+// lookup finds the documents of target whose spec.Key is one of values, and
+// returns them by the refKey of their key.
+func (r *refResolver) lookup(
+	spec *RefSpec,
+	target refTarget,
+	values map[string]interface{},
+) (map[string]bson.D, error) {
+	in := make(bson.A, 0, len(values))
+	for _, value := range values {
+		in = append(in, value)
+	}
+	findOpts := options.Find()
+	if len(spec.Include) > 0 {
+		projection := bson.D{}
+		for _, field := range spec.Include {
+			projection = append(projection, bson.E{Key: field, Value: 1})
+		}
+		if !slices.Contains(spec.Include, spec.Key) {
+			projection = append(projection, bson.E{Key: spec.Key, Value: 1})
+		}
+		if spec.Key != "_id" && !slices.Contains(spec.Include, "_id") {
+			projection = append(projection, bson.E{Key: "_id", Value: 0})
+		}
+		findOpts.SetProjection(projection)
+	}
+
+	docs, err := r.find(target, bson.D{{spec.Key, bson.D{{"$in", in}}}}, findOpts)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error resolving %v in %v.%v: %v", spec.Field, target.db, target.collection, err)
+	}
+
+	docsByKey := map[string]bson.D{}
+	for _, doc := range docs {
+		key, ok := lookupD(doc, spec.Key)
+		if !ok {
+			continue
+		}
+		if _, ok := docsByKey[refKey(key)]; ok {
+			continue
+		}
+		if len(spec.Include) > 0 && !slices.Contains(spec.Include, spec.Key) {
+			doc = removeD(doc, spec.Key)
+		}
+		docsByKey[refKey(key)] = doc
+	}
+	return docsByKey, nil
+}
+
+// reference returns the collection and the key that ref refers to. Without
+// From, ref must be a DBRef.
+func (spec *RefSpec) reference(ref interface{}) (refTarget, interface{}, bool) {
+	if spec.From != "" {
+		if ref == nil {
+			return refTarget{}, nil, false
+		}
+		return refTarget{spec.DB, spec.From}, ref, true
+	}
+	dbRef, ok := ref.(bson.D)
+	if !ok {
+		return refTarget{}, nil, false
+	}
+	target := refTarget{db: spec.DB}
+	var id interface{}
+	var hasRef, hasID bool
+	for _, elem := range dbRef {
+		switch elem.Key {
+		case "$ref":
+			target.collection, hasRef = elem.Value.(string)
+		case "$id":
+			id, hasID = elem.Value, true
+		case "$db":
+			if db, ok := elem.Value.(string); ok {
+				target.db = db
+			}
+		}
+	}
+	return target, id, hasRef && hasID
+}
+
+// refElements returns the references held by value, which is either a
+// reference or an array of them.
+func refElements(value interface{}) []interface{} {
+	if array, ok := value.(bson.A); ok {
+		return array
+	}
+	return []interface{}{value}
+}
+
+// refKey identifies a key value, with numbers of different types that the
+// server matches with each other, like 5, int64(5) and 5.0, identified as one.
+func refKey(value interface{}) string {
+	switch v := value.(type) {
+	case int32:
+		return "n" + strconv.FormatInt(int64(v), 10)
+	case int64:
+		return "n" + strconv.FormatInt(v, 10)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+			return "n" + strconv.FormatInt(int64(v), 10)
+		}
+	}
+	valueType, data, err := bson.MarshalValue(value)
+	if err != nil {
+		return fmt.Sprintf("?%v", value)
+	}
+	return string(rune(valueType)) + string(data)
+}
+
+// lookupD returns the value of the dotted field path in doc.
+func lookupD(doc bson.D, path string) (interface{}, bool) {
+	name, rest, nested := strings.Cut(path, ".")
+	for _, elem := range doc {
+		if elem.Key != name {
+			continue
+		}
+		if !nested {
+			return elem.Value, true
+		}
+		if sub, ok := elem.Value.(bson.D); ok {
+			return lookupD(sub, rest)
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// setD returns doc with the dotted field path set to value, adding the
+// field, and the documents on its path, if doc doesn't have them.
+func setD(doc bson.D, path string, value interface{}) bson.D {
+	name, rest, nested := strings.Cut(path, ".")
+	for i, elem := range doc {
+		if elem.Key != name {
+			continue
+		}
+		if !nested {
+			doc[i].Value = value
+			return doc
+		}
+		sub, _ := elem.Value.(bson.D)
+		doc[i].Value = setD(sub, rest, value)
+		return doc
+	}
+	if nested {
+		value = setD(bson.D{}, rest, value)
+	}
+	return append(doc, bson.E{Key: name, Value: value})
+}
+
+// removeD returns doc without its top-level field name.
+func removeD(doc bson.D, name string) bson.D {
+	return slices.DeleteFunc(doc, func(elem bson.E) bool { return elem.Key == name })
+}
+
+// refResolvingOutput is an ExportOutput that resolves the references of the
+// documents it is given in batches before passing them on to its output.
+type refResolvingOutput struct {
+	ExportOutput
+	resolver *refResolver
+	pending  []bson.D
+}
+
+// ExportDocument buffers doc until refBatchSize documents are pending.
+func (o *refResolvingOutput) ExportDocument(doc bson.D) error {
+	o.pending = append(o.pending, doc)
+	if len(o.pending) < refBatchSize {
+		return nil
+	}
+	return o.flushPending()
+}
+
+// flushPending resolves the pending documents and exports them. If the
+// lookups fail, they stay pending.
+func (o *refResolvingOutput) flushPending() error {
+	if len(o.pending) == 0 {
+		return nil
+	}
+	if err := o.resolver.resolve(o.pending); err != nil {
+		return err
+	}
+	pending := o.pending
+	o.pending = nil
+	for _, doc := range pending {
+		if err := o.ExportOutput.ExportDocument(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFooter exports the pending documents before the footer.
+func (o *refResolvingOutput) WriteFooter() error {
+	if err := o.flushPending(); err != nil {
+		return err
+	}
+	return o.ExportOutput.WriteFooter()
+}
+
+// Flush exports the pending documents before flushing the output.
+func (o *refResolvingOutput) Flush() error {
+	if err := o.flushPending(); err != nil {
+		return err
+	}
+	return o.ExportOutput.Flush()
+}
+
+// resolvingRefs wraps output to resolve the references of --resolveRefs, if
+// any.
+func (exp *MongoExport) resolvingRefs(output ExportOutput) ExportOutput {
+	if len(exp.refSpecs) == 0 {
+		return output
+	}
+	return &refResolvingOutput{
+		ExportOutput: output,
+		resolver: &refResolver{
+			specs: exp.refSpecs,
+			find:  exp.findReferenced,
+		},
+	}
+}
+
+// findReferenced returns the documents of target that match filter.
+func (exp *MongoExport) findReferenced(
+	target refTarget,
+	filter bson.D,
+	opts *options.FindOptions,
+) ([]bson.D, error) {
+	session, err := exp.SessionProvider.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	cursor, err := session.Database(target.db).Collection(target.collection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []bson.D
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// logUnresolvedRefs logs the number of references of each --resolveRefs
+// field that matched no document.
+func (exp *MongoExport) logUnresolvedRefs() {
+	for _, spec := range exp.refSpecs {
+		if unresolved := spec.unresolved.Load(); unresolved > 0 {
+			log.Logvf(log.Always, "%v %v of %v matched no document and %v exported unresolved",
+				unresolved,
+				util.Pluralize(int(unresolved), "reference", "references"),
+				spec.Field,
+				util.Pluralize(int(unresolved), "was", "were"),
+			)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestParseRefSpecs(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("Parsing --resolveRefs", t, func() {
+		Convey("settings should be set on their field", func() {
+			specs, err := ParseRefSpecs([]string{
+				"customerId:from=customers,include=name,include=email,as=customer",
+				"owner",
+				"sku:from=products,db=catalog,key=code",
+			}, "shop")
+			So(err, ShouldBeNil)
+			So(specs, ShouldHaveLength, 3)
+			So(specs[0].From, ShouldEqual, "customers")
+			So(specs[0].DB, ShouldEqual, "shop")
+			So(specs[0].Key, ShouldEqual, "_id")
+			So(specs[0].Include, ShouldResemble, []string{"name", "email"})
+			So(specs[0].As, ShouldEqual, "customer")
+			So(specs[1].From, ShouldEqual, "")
+			So(specs[1].As, ShouldEqual, "owner")
+			So(specs[2].DB, ShouldEqual, "catalog")
+			So(specs[2].Key, ShouldEqual, "code")
+		})
+
+		Convey("invalid specs should be rejected", func() {
+			for _, arg := range []string{
+				"",
+				"customerId:",
+				"customerId:from",
+				"customerId:join=customers",
+				"owner:key=code",
+				"customerId:from=customers,db=a/b",
+			} {
+				_, err := ParseRefSpecs([]string{arg}, "shop")
+				So(err, ShouldNotBeNil)
+			}
+			_, err := ParseRefSpecs([]string{"owner", "owner:include=name"}, "shop")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+// fakeCollections answers the $in queries of a refResolver from memory.
+type fakeCollections struct {
+	docs    map[refTarget][]bson.D
+	queries int
+}
+
+func (f *fakeCollections) find(
+	target refTarget,
+	filter bson.D,
+	opts *options.FindOptions,
+) ([]bson.D, error) {
+	f.queries++
+	key := filter[0].Key
+	in := filter[0].Value.(bson.D)[0].Value.(bson.A)
+	var found []bson.D
+	for _, doc := range f.docs[target] {
+		value, _ := lookupD(doc, key)
+		for _, want := range in {
+			if refKey(value) == refKey(want) {
+				found = append(found, project(doc, opts.Projection))
+			}
+		}
+	}
+	return found, nil
+}
+
+// project applies an inclusion projection to doc like the server does.
+func project(doc bson.D, projection interface{}) bson.D {
+	fields, ok := projection.(bson.D)
+	if !ok {
+		return doc
+	}
+	include := map[string]bool{"_id": true}
+	for _, field := range fields {
+		include[field.Key] = field.Value == 1
+	}
+	var projected bson.D
+	for _, elem := range doc {
+		if include[elem.Key] {
+			projected = append(projected, elem)
+		}
+	}
+	return projected
+}
+
+func TestResolveRefs(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With referenced collections", t, func() {
+		collections := &fakeCollections{docs: map[refTarget][]bson.D{
+			{"shop", "customers"}: {
+				{{"_id", int32(1)}, {"name", "Ann"}, {"email", "ann@example.com"}},
+				{{"_id", int32(2)}, {"name", "Bob"}, {"email", "bob@example.com"}},
+			},
+			{"shop", "users"}: {
+				{{"_id", "u1"}, {"login", "ann"}},
+			},
+			{"catalog", "products"}: {
+				{{"code", "A-1"}, {"title", "Pen"}},
+			},
+		}}
+		resolver := func(args ...string) *refResolver {
+			specs, err := ParseRefSpecs(args, "shop")
+			So(err, ShouldBeNil)
+			return &refResolver{specs: specs, find: collections.find}
+		}
+
+		Convey("foreign keys should be replaced by their documents in one query", func() {
+			docs := []bson.D{
+				{{"_id", "o1"}, {"customerId", int64(1)}},
+				{{"_id", "o2"}, {"customerId", 2.0}},
+				{{"_id", "o3"}, {"customerId", int32(3)}},
+				{{"_id", "o4"}},
+			}
+			r := resolver("customerId:from=customers,include=name")
+			So(r.resolve(docs), ShouldBeNil)
+			So(collections.queries, ShouldEqual, 1)
+			So(docs[0], ShouldResemble, bson.D{{"_id", "o1"}, {"customerId", bson.D{{"name", "Ann"}}}})
+			So(docs[1], ShouldResemble, bson.D{{"_id", "o2"}, {"customerId", bson.D{{"name", "Bob"}}}})
+			So(docs[2], ShouldResemble, bson.D{{"_id", "o3"}, {"customerId", int32(3)}})
+			So(docs[3], ShouldResemble, bson.D{{"_id", "o4"}})
+			So(r.specs[0].unresolved.Load(), ShouldEqual, 1)
+		})
+
+		Convey("DBRefs and arrays should be resolved into another field", func() {
+			docs := []bson.D{{
+				{"_id", "o1"},
+				{"owner", bson.D{{"$ref", "users"}, {"$id", "u1"}}},
+				{"items", bson.A{"A-1", "B-2"}},
+			}}
+			r := resolver("owner:as=meta.owner", "items:from=products,db=catalog,key=code")
+			So(r.resolve(docs), ShouldBeNil)
+			So(docs[0], ShouldResemble, bson.D{
+				{"_id", "o1"},
+				{"owner", bson.D{{"$ref", "users"}, {"$id", "u1"}}},
+				{"items", bson.A{bson.D{{"code", "A-1"}, {"title", "Pen"}}, "B-2"}},
+				{"meta", bson.D{{"owner", bson.D{{"_id", "u1"}, {"login", "ann"}}}}},
+			})
+		})
+
+		Convey("the output should export documents once a batch is resolved", func() {
+			var out bytes.Buffer
+			output := &refResolvingOutput{
+				ExportOutput: NewJSONExportOutput(false, false, &out, Relaxed),
+				resolver:     resolver("customerId:from=customers,include=name"),
+			}
+			So(output.WriteHeader(), ShouldBeNil)
+			So(output.ExportDocument(bson.D{{"customerId", int32(1)}}), ShouldBeNil)
+			So(output.ExportDocument(bson.D{{"customerId", int32(2)}}), ShouldBeNil)
+			So(collections.queries, ShouldEqual, 0)
+			So(output.WriteFooter(), ShouldBeNil)
+			So(output.Flush(), ShouldBeNil)
+			So(collections.queries, ShouldEqual, 1)
+			So(out.String(), ShouldEqual,
+				`{"customerId":{"name":"Ann"}}`+"\n"+`{"customerId":{"name":"Bob"}}`+"\n")
+		})
+	})
+}
@@ -0,0 +1,146 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SampleSizeOption is the command line flag for InputOptions.SampleSize.
+const SampleSizeOption = "--sampleSize"
+
+// Error codes of servers, or of server-like services, that don't know the
+// $sample stage.
+const (
+	errUnrecognizedPipelineStage       = 40324
+	errLegacyUnrecognizedPipelineStage = 16436
+)
+
+// validateSampleSettings checks that --sampleSize is not used with the options
+// that decide which documents, or in which order, are exported.
+func (exp *MongoExport) validateSampleSettings() error {
+	if exp.InputOpts == nil || exp.InputOpts.SampleSize == 0 {
+		return nil
+	}
+	switch {
+	case exp.InputOpts.SampleSize < 0:
+		return fmt.Errorf("%v must be positive, got %v", SampleSizeOption, exp.InputOpts.SampleSize)
+	case exp.InputOpts.Sort != "":
+		return fmt.Errorf("cannot use %v with --sort", SampleSizeOption)
+	case exp.InputOpts.Skip != 0 || exp.InputOpts.Limit != 0:
+		return fmt.Errorf("cannot use %v with --skip or --limit", SampleSizeOption)
+	case exp.InputOpts.IncrementalField != "":
+		return fmt.Errorf("cannot use %v with %v", SampleSizeOption, IncrementalFieldOption)
+	case exp.InputOpts.MaxStaleness != 0:
+		return fmt.Errorf("cannot use %v with %v", SampleSizeOption, MaxStalenessOption)
+	case exp.OutputOpts.NumExportWorkers > 1:
+		return fmt.Errorf("cannot use %v with %v", SampleSizeOption, NumExportWorkersOption)
+	}
+	return nil
+}
+
+// getSampleCursor returns a cursor over a uniform random sample of
+// --sampleSize of the documents matching query, drawn by the server with
+// $sample. If the server doesn't know $sample, the sample is drawn by
+// reading all of the matching documents instead.
+func (exp *MongoExport) getSampleCursor(
+	coll *mongo.Collection,
+	query bson.D,
+) (*mongo.Cursor, error) {
+	size := exp.InputOpts.SampleSize
+	var pipeline mongo.Pipeline
+	if len(query) > 0 {
+		pipeline = append(pipeline, bson.D{{"$match", query}})
+	}
+	pipeline = append(pipeline, bson.D{{"$sample", bson.D{{"size", size}}}})
+	if projection := exp.projection(); projection != nil {
+		pipeline = append(pipeline, bson.D{{"$project", projection}})
+	}
+
+	cursor, err := coll.Aggregate(context.TODO(), pipeline, mopt.Aggregate().SetAllowDiskUse(true))
+	if err == nil || !isSampleUnavailable(err) {
+		return cursor, err
+	}
+	log.Logvf(log.Always, "the server does not support $sample (%v); reading all of %v.%v "+
+		"to sample %v documents", err, coll.Database().Name(), coll.Name(), size)
+	return exp.reservoirSample(coll, query)
+}
+
+func isSampleUnavailable(err error) bool {
+	var mongoErr mongo.ServerError
+	return errors.As(err, &mongoErr) &&
+		(mongoErr.HasErrorCode(errUnrecognizedPipelineStage) ||
+			mongoErr.HasErrorCode(errLegacyUnrecognizedPipelineStage))
+}
+
+// reservoirSample reads every document matching query and returns a cursor
+// over a uniform random sample of --sampleSize of them, which are held in
+// memory.
+func (exp *MongoExport) reservoirSample(
+	coll *mongo.Collection,
+	query bson.D,
+) (*mongo.Cursor, error) {
+	findOpts := mopt.Find()
+	if projection := exp.projection(); projection != nil {
+		findOpts.SetProjection(projection)
+	}
+	cursor, err := coll.Find(context.TODO(), query, findOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	r := newReservoir(int(exp.InputOpts.SampleSize), rand.New(rand.NewSource(rand.Int63())))
+	for cursor.Next(context.TODO()) {
+		r.add(cursor.Current)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	log.Logvf(log.Info, "sampled %v of %v documents", len(r.docs), r.seen)
+
+	docs := make([]interface{}, len(r.docs))
+	for i, doc := range r.docs {
+		docs[i] = doc
+	}
+	return mongo.NewCursorFromDocuments(docs, nil, nil)
+}
+
+// reservoir keeps a uniform random sample of the documents added to it,
+// without knowing how many there will be.
+type reservoir struct {
+	size int
+	seen int64
+	docs []bson.Raw
+	rand *rand.Rand
+}
+
+func newReservoir(size int, rand *rand.Rand) *reservoir {
+	return &reservoir{size: size, rand: rand}
+}
+
+// add adds doc to the sample with a probability of size/seen, in place of a
+// random document of the sample once it is full. doc is copied, since
+// cursors reuse their buffers.
+func (r *reservoir) add(doc bson.Raw) {
+	r.seen++
+	if len(r.docs) < r.size {
+		r.docs = append(r.docs, append(bson.Raw(nil), doc...))
+		return
+	}
+	if i := r.rand.Int63n(r.seen); i < int64(r.size) {
+		r.docs[i] = append(bson.Raw(nil), doc...)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestValidateSampleSettings(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With --sampleSize", t, func() {
+		exp := &MongoExport{
+			OutputOpts: &OutputFormatOptions{},
+			InputOpts:  &InputOptions{SampleSize: 100, Query: "{a: 1}"},
+		}
+
+		Convey("a valid configuration passes", func() {
+			So(exp.validateSampleSettings(), ShouldBeNil)
+		})
+
+		Convey("the size must be positive", func() {
+			exp.InputOpts.SampleSize = -1
+			So(exp.validateSampleSettings(), ShouldNotBeNil)
+		})
+
+		Convey("options that pick or order the documents are rejected", func() {
+			for _, set := range []func(){
+				func() { exp.InputOpts.Sort = "{a: 1}" },
+				func() { exp.InputOpts.Skip = 10 },
+				func() { exp.InputOpts.Limit = 10 },
+				func() { exp.InputOpts.IncrementalField = "updatedAt" },
+				func() { exp.InputOpts.MaxStaleness = 90 },
+				func() { exp.OutputOpts.NumExportWorkers = 4 },
+			} {
+				exp.OutputOpts = &OutputFormatOptions{}
+				exp.InputOpts = &InputOptions{SampleSize: 100}
+				set()
+				So(exp.validateSampleSettings(), ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestReservoir(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	doc := func(i int) bson.Raw {
+		raw, err := bson.Marshal(bson.D{{"i", i}})
+		So(err, ShouldBeNil)
+		return raw
+	}
+
+	Convey("A reservoir", t, func() {
+		Convey("keeps every document if there are fewer than its size", func() {
+			r := newReservoir(10, rand.New(rand.NewSource(1)))
+			for i := 0; i < 3; i++ {
+				r.add(doc(i))
+			}
+			So(len(r.docs), ShouldEqual, 3)
+			So(r.seen, ShouldEqual, 3)
+		})
+
+		Convey("keeps size distinct documents and copies them", func() {
+			r := newReservoir(5, rand.New(rand.NewSource(1)))
+			buf := make(bson.Raw, 0, 64)
+			for i := 0; i < 1000; i++ {
+				buf = append(buf[:0], doc(i)...)
+				r.add(buf)
+			}
+			So(len(r.docs), ShouldEqual, 5)
+			seen := map[int32]bool{}
+			for _, d := range r.docs {
+				seen[d.Lookup("i").Int32()] = true
+			}
+			So(len(seen), ShouldEqual, 5)
+		})
+
+		Convey("samples every document with the same probability", func() {
+			counts := make([]int, 10)
+			rng := rand.New(rand.NewSource(1))
+			for trial := 0; trial < 10000; trial++ {
+				r := newReservoir(2, rng)
+				for i := 0; i < 10; i++ {
+					r.add(doc(i))
+				}
+				for _, d := range r.docs {
+					counts[d.Lookup("i").Int32()]++
+				}
+			}
+			// each document is expected 2000 times
+			for _, count := range counts {
+				So(count, ShouldBeBetween, 1800, 2200)
+			}
+		})
+	})
+}
+
+func TestIsSampleUnavailable(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("Only unknown pipeline stage errors fall back to reading everything", t, func() {
+		So(isSampleUnavailable(mongo.CommandError{Code: errUnrecognizedPipelineStage}), ShouldBeTrue)
+		So(isSampleUnavailable(mongo.CommandError{Code: errLegacyUnrecognizedPipelineStage}),
+			ShouldBeTrue)
+		So(isSampleUnavailable(mongo.CommandError{Code: 13}), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,258 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MaxFileSizeOption is the command line flag for OutputFormatOptions.MaxFileSize.
+const MaxFileSizeOption = "--maxFileSize"
+
+// gzipExtension is the extension of an --out whose parts are compressed.
+const gzipExtension = ".gz"
+
+// exportManifest is the content of the manifest of a split export. It is
+// written once every part is, so a manifest means the export is complete.
+type exportManifest struct {
+	Documents int64          `bson:"documents"`
+	Parts     []manifestPart `bson:"parts"`
+}
+
+// manifestPart describes one part file of a split export.
+type manifestPart struct {
+	// File is the name of the part, in the directory of the manifest.
+	File      string `bson:"file"`
+	Documents int64  `bson:"documents"`
+	Bytes     int64  `bson:"bytes"`
+	SHA256    string `bson:"sha256"`
+}
+
+// partPath returns the path of the part numbered n of an export to out, which
+// is numbered before the extension of out: data.json.gz has the parts
+// data-00001.json.gz, data-00002.json.gz and so on.
+func partPath(out string, n int) string {
+	stem, ext := splitExportPath(out)
+	return fmt.Sprintf("%v-%05d%v", stem, n, ext)
+}
+
+// manifestPath returns the path of the manifest of a split export to out.
+func manifestPath(out string) string {
+	stem, _ := splitExportPath(out)
+	return stem + ".manifest.json"
+}
+
+func splitExportPath(out string) (string, string) {
+	stem := strings.TrimSuffix(out, gzipExtension)
+	ext := filepath.Ext(stem)
+	stem = strings.TrimSuffix(stem, ext)
+	return stem, out[len(stem):]
+}
+
+// splitOutput is an ExportOutput that writes the export to part files of
+// about --maxFileSize bytes each, each with the header and footer of its
+// format so that the parts can be loaded independently, and then writes a
+// manifest with the number of documents and the checksum of each part.
+//
+// A part is only closed between documents, so a part can be larger than the
+// limit by its last document and, for compressed parts, by what the
+// compressor buffers.
+type splitOutput struct {
+	out     string
+	maxSize int64
+	// newOutput returns an ExportOutput writing to a part.
+	newOutput func(io.Writer) (ExportOutput, error)
+
+	manifest exportManifest
+	current  *partFile
+}
+
+// partFile writes one part file with an ExportOutput, and describes it for
+// the manifest once it is closed.
+type partFile struct {
+	file   *os.File
+	gzip   *gzip.Writer
+	hash   hash.Hash
+	count  *countingWriter
+	output ExportOutput
+	part   manifestPart
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newSplitOutput(
+	out string,
+	maxSize int64,
+	newOutput func(io.Writer) (ExportOutput, error),
+) *splitOutput {
+	return &splitOutput{out: out, maxSize: maxSize, newOutput: newOutput}
+}
+
+// WriteHeader starts the first part, so that an export without documents
+// still has one.
+func (s *splitOutput) WriteHeader() error {
+	if err := os.MkdirAll(filepath.Dir(s.out), 0750); err != nil {
+		return err
+	}
+	return s.openPart()
+}
+
+// ExportDocument writes document to the current part, after starting the
+// next part if the current one is full.
+func (s *splitOutput) ExportDocument(document bson.D) error {
+	if s.current.part.Documents > 0 && s.current.count.n >= s.maxSize {
+		if err := s.closePart(); err != nil {
+			return err
+		}
+		if err := s.openPart(); err != nil {
+			return err
+		}
+	}
+	if err := s.current.ExportDocument(document); err != nil {
+		return err
+	}
+	// flush buffered formats so that the size of the part is up to date
+	return s.current.output.Flush()
+}
+
+// WriteFooter ends the last part and writes the manifest.
+func (s *splitOutput) WriteFooter() error {
+	if err := s.closePart(); err != nil {
+		return err
+	}
+	return writeManifest(s.out, s.manifest)
+}
+
+// Flush is a no-op, since each part is flushed when it is closed.
+func (s *splitOutput) Flush() error {
+	return nil
+}
+
+func (s *splitOutput) openPart() error {
+	part, err := createPart(partPath(s.out, len(s.manifest.Parts)+1), s.out, s.newOutput)
+	if err != nil {
+		return err
+	}
+	s.current = part
+	return nil
+}
+
+func (s *splitOutput) closePart() error {
+	part, err := s.current.close()
+	if err != nil {
+		return err
+	}
+	s.manifest.Parts = append(s.manifest.Parts, part)
+	s.manifest.Documents += part.Documents
+	return nil
+}
+
+// createPart creates the part file at path of an export to out, which is
+// gzip compressed if out ends in .gz, and writes the header of its format.
+func createPart(
+	path, out string,
+	newOutput func(io.Writer) (ExportOutput, error),
+) (*partFile, error) {
+	file, err := os.Create(util.ToUniversalPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("error creating part file: %v", err)
+	}
+	p := &partFile{
+		file: file,
+		hash: sha256.New(),
+		part: manifestPart{File: filepath.Base(path)},
+	}
+	p.count = &countingWriter{w: io.MultiWriter(file, p.hash)}
+
+	var w io.Writer = p.count
+	if strings.HasSuffix(out, gzipExtension) {
+		p.gzip = gzip.NewWriter(p.count)
+		w = p.gzip
+	}
+	p.output, err = newOutput(w)
+	if err == nil {
+		log.Logvf(log.Info, "writing part file %v", path)
+		err = p.output.WriteHeader()
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// ExportDocument writes document to the part.
+func (p *partFile) ExportDocument(document bson.D) error {
+	if err := p.output.ExportDocument(document); err != nil {
+		return err
+	}
+	p.part.Documents++
+	return nil
+}
+
+// close writes the footer of the part and closes it.
+func (p *partFile) close() (manifestPart, error) {
+	err := p.output.WriteFooter()
+	if err == nil {
+		err = p.output.Flush()
+	}
+	if err == nil && p.gzip != nil {
+		err = p.gzip.Close()
+	}
+	if closeErr := p.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return manifestPart{}, fmt.Errorf("error writing part file %v: %v", p.part.File, err)
+	}
+
+	p.part.Bytes = p.count.n
+	p.part.SHA256 = hex.EncodeToString(p.hash.Sum(nil))
+	return p.part, nil
+}
+
+// writeManifest writes the manifest of a split export to out.
+func writeManifest(out string, manifest exportManifest) error {
+	content, err := bson.MarshalExtJSONIndent(manifest, false, false, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %v", err)
+	}
+	path := manifestPath(out)
+	if err := os.WriteFile(path, append(content, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing manifest: %v", err)
+	}
+	log.Logvf(
+		log.Always,
+		"wrote %v %v and the manifest %v",
+		len(manifest.Parts),
+		util.Pluralize(len(manifest.Parts), "part file", "part files"),
+		path,
+	)
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoexport
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPartPaths(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("Parts are numbered before the extension of --out", t, func() {
+		So(partPath("out/data.json.gz", 1), ShouldEqual, "out/data-00001.json.gz")
+		So(partPath("data.csv", 12), ShouldEqual, "data-00012.csv")
+		So(partPath("my.coll.json", 3), ShouldEqual, "my.coll-00003.json")
+		So(partPath("a.b/data", 2), ShouldEqual, "a.b/data-00002")
+		So(manifestPath("out/data.json.gz"), ShouldEqual, "out/data.manifest.json")
+	})
+}
+
+// readManifest reads the manifest of an export to out.
+func readManifest(out string) exportManifest {
+	content, err := os.ReadFile(manifestPath(out))
+	So(err, ShouldBeNil)
+	manifest := exportManifest{}
+	So(bson.UnmarshalExtJSON(content, false, &manifest), ShouldBeNil)
+	return manifest
+}
+
+func TestSplitOutput(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	newJSONArray := func(w io.Writer) (ExportOutput, error) {
+		return NewJSONExportOutput(true, false, w, Relaxed), nil
+	}
+	export := func(output ExportOutput, n int) {
+		So(output.WriteHeader(), ShouldBeNil)
+		for i := 0; i < n; i++ {
+			So(output.ExportDocument(bson.D{{"_id", int32(i)}}), ShouldBeNil)
+		}
+		So(output.WriteFooter(), ShouldBeNil)
+		So(output.Flush(), ShouldBeNil)
+	}
+
+	Convey("With --maxFileSize", t, func() {
+		out := filepath.Join(t.TempDir(), "dir", "data.json")
+
+		Convey("each part is a complete file of about the size", func() {
+			// each document is 11 bytes, after the opening bracket or a comma
+			export(newSplitOutput(out, 25, newJSONArray), 5)
+
+			manifest := readManifest(out)
+			So(manifest.Documents, ShouldEqual, 5)
+			So(manifest.Parts, ShouldHaveLength, 2)
+			expected := []string{
+				`[{"_id":0},{"_id":1},{"_id":2}]` + "\n",
+				`[{"_id":3},{"_id":4}]` + "\n",
+			}
+			So(manifest.Parts[0].File, ShouldEqual, "data-00001.json")
+			So(manifest.Parts[0].Documents, ShouldEqual, 3)
+			So(manifest.Parts[1].Documents, ShouldEqual, 2)
+			for i, content := range expected {
+				part := manifest.Parts[i]
+				written, err := os.ReadFile(filepath.Join(filepath.Dir(out), part.File))
+				So(err, ShouldBeNil)
+				So(string(written), ShouldEqual, content)
+				So(part.Bytes, ShouldEqual, len(content))
+				sum := sha256.Sum256(written)
+				So(part.SHA256, ShouldEqual, hex.EncodeToString(sum[:]))
+			}
+		})
+
+		Convey("an export without documents has one empty part", func() {
+			export(newSplitOutput(out, 25, newJSONArray), 0)
+			manifest := readManifest(out)
+			So(manifest.Parts, ShouldHaveLength, 1)
+			So(manifest.Parts[0].Documents, ShouldEqual, 0)
+		})
+
+		Convey("an --out ending in .gz has compressed parts", func() {
+			out += ".gz"
+			export(newSplitOutput(out, 1<<20, newJSONArray), 2)
+			manifest := readManifest(out)
+			So(manifest.Parts, ShouldHaveLength, 1)
+			So(manifest.Parts[0].File, ShouldEqual, "data-00001.json.gz")
+
+			file, err := os.Open(filepath.Join(filepath.Dir(out), manifest.Parts[0].File))
+			So(err, ShouldBeNil)
+			defer file.Close()
+			reader, err := gzip.NewReader(file)
+			So(err, ShouldBeNil)
+			content, err := io.ReadAll(reader)
+			So(err, ShouldBeNil)
+			So(string(content), ShouldEqual, `[{"_id":0},{"_id":1}]`+"\n")
+		})
+	})
+}
@@ -0,0 +1,323 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongofiles
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	driverOptions "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// gcFile is the part of a files collection document that gc needs.
+type gcFile struct {
+	ID        bson.RawValue `bson:"_id"`
+	Name      string        `bson:"filename"`
+	Length    int64         `bson:"length"`
+	ChunkSize int64         `bson:"chunkSize"`
+}
+
+// expectedChunks returns the number of chunks a complete file has.
+func (file *gcFile) expectedChunks() int64 {
+	if file.Length <= 0 {
+		return 0
+	}
+	if file.ChunkSize <= 0 {
+		// no number of chunks can make up a file with no chunk size
+		return -1
+	}
+	return (file.Length + file.ChunkSize - 1) / file.ChunkSize
+}
+
+// chunkCount is the result of grouping the chunks collection by files_id.
+type chunkCount struct {
+	FilesID bson.RawValue `bson:"_id"`
+	Count   int64         `bson:"count"`
+}
+
+// gcRateLimiter spaces out gc's deletes so that they average at most
+// maxPerSecond documents per second.
+type gcRateLimiter struct {
+	maxPerSecond int
+	start        time.Time
+	deleted      int64
+}
+
+// wait records that n more documents were deleted and sleeps until deleting
+// them is within the rate limit.
+func (rl *gcRateLimiter) wait(n int64) {
+	if rl.maxPerSecond <= 0 {
+		return
+	}
+	if rl.start.IsZero() {
+		rl.start = time.Now()
+	}
+	rl.deleted += n
+	elapsed := time.Duration(rl.deleted) * time.Second / time.Duration(rl.maxPerSecond)
+	due := rl.start.Add(elapsed)
+	time.Sleep(time.Until(due))
+}
+
+// handleGC contains the logic for the 'gc' command. It reports chunks whose
+// files_id has no files document, and files documents that do not have every
+// one of their chunks. With --gcDelete, it also deletes them, --gcBatchSize
+// files at a time and at most --gcMaxDeletesPerSecond documents per second.
+func (mf *MongoFiles) handleGC() (string, error) {
+	limiter := &gcRateLimiter{maxPerSecond: mf.StorageOptions.GCMaxDeletesPerSecond}
+	report := &strings.Builder{}
+
+	orphans, err := mf.gcOrphanedChunks(report, limiter)
+	if err != nil {
+		return report.String(), err
+	}
+	incomplete, err := mf.gcIncompleteFiles(report, limiter)
+	if err != nil {
+		return report.String(), err
+	}
+
+	action := "found"
+	if mf.StorageOptions.GCDelete {
+		action = "deleted"
+	}
+	log.Logvf(
+		log.Always,
+		"%v %v %v and %v incomplete %v",
+		action,
+		orphans,
+		util.Pluralize(int(orphans), "orphaned chunk", "orphaned chunks"),
+		incomplete,
+		util.Pluralize(int(incomplete), "file", "files"),
+	)
+	return report.String(), nil
+}
+
+// gcOrphanedChunks reports, and with --gcDelete deletes, the chunks whose
+// files_id has no files document. It returns the number of such chunks.
+func (mf *MongoFiles) gcOrphanedChunks(
+	report *strings.Builder,
+	limiter *gcRateLimiter,
+) (int64, error) {
+	ctx := context.Background()
+	chunksColl := mf.bucket.GetChunksCollection()
+	filesColl := mf.bucket.GetFilesCollection()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$files_id"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+	cursor, err := chunksColl.Aggregate(
+		ctx,
+		pipeline,
+		driverOptions.Aggregate().SetAllowDiskUse(true),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error listing chunks: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var orphans int64
+	processBatch := func(batch []chunkCount) error {
+		ids := make(bson.A, len(batch))
+		for i, c := range batch {
+			ids[i] = c.FilesID
+		}
+		existing, err := filesColl.Distinct(
+			ctx,
+			"_id",
+			bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}},
+		)
+		if err != nil {
+			return fmt.Errorf("error looking up files: %v", err)
+		}
+		found := map[string]bool{}
+		for _, id := range existing {
+			found[rawKey(id)] = true
+		}
+
+		var orphanIDs bson.A
+		var orphanChunks int64
+		for _, c := range batch {
+			if found[rawKey(c.FilesID)] {
+				continue
+			}
+			fmt.Fprintf(report, "orphaned chunks\t%v\t%d\n", c.FilesID, c.Count)
+			orphanIDs = append(orphanIDs, c.FilesID)
+			orphanChunks += c.Count
+		}
+		orphans += orphanChunks
+		if !mf.StorageOptions.GCDelete || len(orphanIDs) == 0 {
+			return nil
+		}
+
+		res, err := chunksColl.DeleteMany(
+			ctx,
+			bson.D{{Key: "files_id", Value: bson.D{{Key: "$in", Value: orphanIDs}}}},
+		)
+		if err != nil {
+			return fmt.Errorf("error deleting orphaned chunks: %v", err)
+		}
+		log.Logvf(log.DebugLow, "deleted %v orphaned chunks", res.DeletedCount)
+		limiter.wait(res.DeletedCount)
+		return nil
+	}
+
+	var batch []chunkCount
+	for cursor.Next(ctx) {
+		var c chunkCount
+		if err := cursor.Decode(&c); err != nil {
+			return orphans, fmt.Errorf("error decoding chunk count: %v", err)
+		}
+		batch = append(batch, c)
+		if len(batch) >= mf.StorageOptions.GCBatchSize {
+			if err := processBatch(batch); err != nil {
+				return orphans, err
+			}
+			batch = nil
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return orphans, fmt.Errorf("error listing chunks: %v", err)
+	}
+	if len(batch) > 0 {
+		if err := processBatch(batch); err != nil {
+			return orphans, err
+		}
+	}
+	return orphans, nil
+}
+
+// gcIncompleteFiles reports, and with --gcDelete deletes along with their
+// chunks, the files documents that do not have as many chunks as their length
+// and chunk size call for. It returns the number of such files.
+func (mf *MongoFiles) gcIncompleteFiles(
+	report *strings.Builder,
+	limiter *gcRateLimiter,
+) (int64, error) {
+	ctx := context.Background()
+	chunksColl := mf.bucket.GetChunksCollection()
+	filesColl := mf.bucket.GetFilesCollection()
+
+	projection := bson.D{
+		{Key: "filename", Value: 1},
+		{Key: "length", Value: 1},
+		{Key: "chunkSize", Value: 1},
+	}
+	cursor, err := filesColl.Find(ctx, bson.D{}, driverOptions.Find().SetProjection(projection))
+	if err != nil {
+		return 0, fmt.Errorf("error listing files: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var incomplete int64
+	processBatch := func(batch []gcFile) error {
+		ids := make(bson.A, len(batch))
+		for i, file := range batch {
+			ids[i] = file.ID
+		}
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.D{
+				{Key: "files_id", Value: bson.D{{Key: "$in", Value: ids}}},
+			}}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$files_id"},
+				{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			}}},
+		}
+		counts, err := chunksColl.Aggregate(ctx, pipeline)
+		if err != nil {
+			return fmt.Errorf("error counting chunks: %v", err)
+		}
+		var results []chunkCount
+		if err := counts.All(ctx, &results); err != nil {
+			return fmt.Errorf("error counting chunks: %v", err)
+		}
+		have := map[string]int64{}
+		for _, c := range results {
+			have[rawKey(c.FilesID)] = c.Count
+		}
+
+		for _, file := range batch {
+			got := have[rawKey(file.ID)]
+			expected := file.expectedChunks()
+			if got == expected {
+				continue
+			}
+			fmt.Fprintf(
+				report,
+				"incomplete file\t%s\t%v\t%d/%d chunks\n",
+				file.Name,
+				file.ID,
+				got,
+				expected,
+			)
+			incomplete++
+			if !mf.StorageOptions.GCDelete {
+				continue
+			}
+			if err := mf.bucket.Delete(file.ID); err != nil {
+				return fmt.Errorf("error deleting incomplete file %v: %v", file.ID, err)
+			}
+			limiter.wait(got + 1)
+		}
+		return nil
+	}
+
+	var batch []gcFile
+	for cursor.Next(ctx) {
+		var file gcFile
+		if err := cursor.Decode(&file); err != nil {
+			return incomplete, fmt.Errorf("error decoding file: %v", err)
+		}
+		batch = append(batch, file)
+		if len(batch) >= mf.StorageOptions.GCBatchSize {
+			if err := processBatch(batch); err != nil {
+				return incomplete, err
+			}
+			batch = nil
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return incomplete, fmt.Errorf("error listing files: %v", err)
+	}
+	if len(batch) > 0 {
+		if err := processBatch(batch); err != nil {
+			return incomplete, err
+		}
+	}
+	return incomplete, nil
+}
+
+// rawKey returns a map key identifying a BSON value the way the server
+// compares them, so numbers of different types that are equal have the same
+// key.
+func rawKey(value interface{}) string {
+	raw, ok := value.(bson.RawValue)
+	if !ok {
+		t, data, err := bson.MarshalValue(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		raw = bson.RawValue{Type: t, Value: data}
+	}
+	switch raw.Type {
+	case bson.TypeInt32:
+		return fmt.Sprintf("number %v", float64(raw.Int32()))
+	case bson.TypeInt64:
+		return fmt.Sprintf("number %v", float64(raw.Int64()))
+	case bson.TypeDouble:
+		return fmt.Sprintf("number %v", raw.Double())
+	}
+	return string(raw.Type) + string(raw.Value)
+}
@@ -0,0 +1,42 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongofiles
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGCHelpers(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("The expected number of chunks is derived from the length and chunk size", t, func() {
+		So((&gcFile{Length: 0, ChunkSize: 255}).expectedChunks(), ShouldEqual, 0)
+		So((&gcFile{Length: 1, ChunkSize: 255}).expectedChunks(), ShouldEqual, 1)
+		So((&gcFile{Length: 255, ChunkSize: 255}).expectedChunks(), ShouldEqual, 1)
+		So((&gcFile{Length: 256, ChunkSize: 255}).expectedChunks(), ShouldEqual, 2)
+		So((&gcFile{Length: 10, ChunkSize: 0}).expectedChunks(), ShouldEqual, -1)
+	})
+
+	Convey("Ids are matched the way the server compares them", t, func() {
+		raw := func(v interface{}) bson.RawValue {
+			t, data, err := bson.MarshalValue(v)
+			So(err, ShouldBeNil)
+			return bson.RawValue{Type: t, Value: data}
+		}
+		oid := primitive.NewObjectID()
+		So(rawKey(raw(oid)), ShouldEqual, rawKey(oid))
+		So(rawKey(raw(int32(5))), ShouldEqual, rawKey(int64(5)))
+		So(rawKey(raw(5.0)), ShouldEqual, rawKey(int32(5)))
+		So(rawKey(raw("5")), ShouldNotEqual, rawKey(int32(5)))
+		So(rawKey(raw(primitive.NewObjectID())), ShouldNotEqual, rawKey(oid))
+	})
+}
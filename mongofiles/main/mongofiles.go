@@ -13,6 +13,7 @@ import (
 
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/signals"
+	"github.com/mongodb/mongo-tools/common/telemetry"
 	"github.com/mongodb/mongo-tools/common/util"
 	"github.com/mongodb/mongo-tools/mongofiles"
 )
@@ -42,20 +43,26 @@ func main() {
 		os.Exit(util.ExitSuccess)
 	}
 
+	if err := telemetry.Init(opts.AppName, opts.VersionStr, opts.OTelEndpoint); err != nil {
+		log.Logvf(log.Always, "error starting telemetry: %v", err)
+		os.Exit(util.ExitFailure)
+	}
+	defer telemetry.Shutdown()
+
 	mf, err := mongofiles.New(opts)
 	if err != nil {
 		log.Logv(log.Always, err.Error())
 		if setupErr, ok := err.(util.SetupError); ok && setupErr.Message != "" {
 			log.Logvf(log.Always, setupErr.Message)
 		}
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 	defer mf.Close()
 
 	output, err := mf.Run(true)
 	if err != nil {
 		log.Logvf(log.Always, "Failed: %v", err)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 	fmt.Printf("%s", output)
 }
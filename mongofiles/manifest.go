@@ -0,0 +1,387 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongofiles
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	driverOptions "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxManifestLine is the longest line of a manifest, which holds a files
+// document of at most 16MB in extended JSON.
+const maxManifestLine = 64 * 1024 * 1024
+
+// manifestEntry is a files collection document of a manifest.
+type manifestEntry struct {
+	// line is the line of the manifest the document is on.
+	line int
+	doc  bson.D
+
+	ID        bson.RawValue `bson:"_id"`
+	Name      string        `bson:"filename"`
+	Length    int64         `bson:"length"`
+	ChunkSize int64         `bson:"chunkSize"`
+	MD5       string        `bson:"md5"`
+}
+
+// expectedChunks returns the number of chunks the content of the file has.
+func (entry *manifestEntry) expectedChunks() int64 {
+	file := gcFile{Length: entry.Length, ChunkSize: entry.ChunkSize}
+	return file.expectedChunks()
+}
+
+// handleExportManifest contains the logic for the 'export-manifest' command.
+// It writes every document of the files collection, but none of the chunks,
+// to the manifest file or stdout, as canonical extended JSON, one per line.
+func (mf *MongoFiles) handleExportManifest() (err error) {
+	ctx := context.Background()
+	var out io.Writer = os.Stdout
+	if mf.ManifestFile != "" && mf.ManifestFile != "-" {
+		file, err := os.Create(util.ToUniversalPath(mf.ManifestFile))
+		if err != nil {
+			return fmt.Errorf("error creating manifest '%v': %v", mf.ManifestFile, err)
+		}
+		dc := util.DeferredCloser{Closer: file}
+		defer dc.CloseWithErrorCapture(&err)
+		out = file
+	}
+	writer := bufio.NewWriter(out)
+
+	cursor, err := mf.bucket.GetFilesCollection().Find(
+		ctx,
+		bson.D{},
+		driverOptions.Find().SetSort(bson.D{{Key: "filename", Value: 1}, {Key: "uploadDate", Value: 1}}),
+	)
+	if err != nil {
+		return fmt.Errorf("error listing GridFS files: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var count int
+	for cursor.Next(ctx) {
+		line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return fmt.Errorf("error encoding GridFS file: %v", err)
+		}
+		if _, err := writer.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("error writing manifest: %v", err)
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("error listing GridFS files: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("error writing manifest: %v", err)
+	}
+	log.Logvf(
+		log.Always,
+		"exported %v %v to the manifest",
+		count,
+		util.Pluralize(count, "file", "files"),
+	)
+	return nil
+}
+
+// readManifest reads the files documents of a manifest written by
+// export-manifest.
+func readManifest(in io.Reader) ([]*manifestEntry, error) {
+	var entries []*manifestEntry
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, maxManifestLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		entry := &manifestEntry{line: line}
+		if err := bson.UnmarshalExtJSON([]byte(text), false, &entry.doc); err != nil {
+			return nil, fmt.Errorf("invalid manifest line %v: %v", line, err)
+		}
+		raw, err := bson.Marshal(entry.doc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest line %v: %v", line, err)
+		}
+		if err := bson.Unmarshal(raw, entry); err != nil {
+			return nil, fmt.Errorf("invalid manifest line %v: %v", line, err)
+		}
+		switch {
+		case entry.ID.Type == 0:
+			return nil, fmt.Errorf("invalid manifest line %v: the file has no _id", line)
+		case entry.Name == "":
+			return nil, fmt.Errorf("invalid manifest line %v: the file has no filename", line)
+		case entry.Length < 0:
+			return nil, fmt.Errorf("invalid manifest line %v: the file has a negative length", line)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading manifest: %v", err)
+	}
+	return entries, nil
+}
+
+// handleImportManifest contains the logic for the 'import-manifest' command.
+// It recreates the files documents of a manifest, with their _id, upload
+// date, metadata and any other fields, for GridFS contents that are already
+// on the server: either chunks that still have the _id of the file as their
+// files_id, or a file with the same name and length that was uploaded again,
+// e.g. with put. The chunks of such an upload are moved to the recreated
+// file, and the upload's own files document is deleted.
+func (mf *MongoFiles) handleImportManifest() (err error) {
+	var in io.Reader = os.Stdin
+	if mf.ManifestFile != "-" {
+		file, err := os.Open(util.ToUniversalPath(mf.ManifestFile))
+		if err != nil {
+			return fmt.Errorf("error opening manifest '%v': %v", mf.ManifestFile, err)
+		}
+		dc := util.DeferredCloser{Closer: file}
+		defer dc.CloseWithErrorCapture(&err)
+		in = file
+	}
+	entries, err := readManifest(in)
+	if err != nil {
+		return err
+	}
+
+	// the files of the manifest are not uploads of one another
+	manifestIDs := make(bson.A, len(entries))
+	for i, entry := range entries {
+		manifestIDs[i] = entry.ID
+	}
+
+	var imported, relinked, skipped, missing int
+	for _, entry := range entries {
+		result, err := mf.importManifestEntry(entry, manifestIDs)
+		if err != nil {
+			return fmt.Errorf(
+				"error importing '%v' (_id %v) from manifest line %v: %v",
+				entry.Name,
+				entry.ID,
+				entry.line,
+				err,
+			)
+		}
+		switch result {
+		case manifestImported:
+			imported++
+		case manifestRelinked:
+			relinked++
+		case manifestSkipped:
+			skipped++
+		case manifestMissing:
+			missing++
+		}
+	}
+
+	log.Logvf(
+		log.Always,
+		"imported %v %v: %v with their chunks, %v with the chunks of an upload; %v already existed",
+		imported+relinked,
+		util.Pluralize(imported+relinked, "file", "files"),
+		imported,
+		relinked,
+		skipped,
+	)
+	if missing > 0 {
+		return fmt.Errorf(
+			"%v %v of the manifest have no content in GridFS; put them again and rerun import-manifest",
+			missing,
+			util.Pluralize(missing, "file", "files"),
+		)
+	}
+	return nil
+}
+
+// The results of importing a manifest entry.
+const (
+	manifestImported = iota
+	manifestRelinked
+	manifestSkipped
+	manifestMissing
+)
+
+// importManifestEntry recreates the files document of entry, unless a file
+// with its _id exists and --replace is not set.
+func (mf *MongoFiles) importManifestEntry(entry *manifestEntry, manifestIDs bson.A) (int, error) {
+	ctx := context.Background()
+	filesColl := mf.bucket.GetFilesCollection()
+	chunksColl := mf.bucket.GetChunksCollection()
+
+	exists := true
+	err := filesColl.FindOne(ctx, bson.D{{Key: "_id", Value: entry.ID}}).Err()
+	if err == mongo.ErrNoDocuments {
+		exists = false
+	} else if err != nil {
+		return 0, fmt.Errorf("error looking up the file: %v", err)
+	}
+	if exists && !mf.StorageOptions.Replace {
+		log.Logvf(log.Info, "skipping '%v' (_id %v), which already exists", entry.Name, entry.ID)
+		return manifestSkipped, nil
+	}
+
+	chunks, err := chunksColl.CountDocuments(ctx, bson.D{{Key: "files_id", Value: entry.ID}})
+	if err != nil {
+		return 0, fmt.Errorf("error counting chunks: %v", err)
+	}
+	if chunks == entry.expectedChunks() {
+		return manifestImported, mf.writeManifestFile(entry, exists)
+	}
+	if chunks > 0 {
+		log.Logvf(
+			log.Always,
+			"'%v' (_id %v) has %v of its %v chunks, not importing it",
+			entry.Name,
+			entry.ID,
+			chunks,
+			entry.expectedChunks(),
+		)
+		return manifestMissing, nil
+	}
+
+	upload, err := mf.findUpload(entry, manifestIDs)
+	if err != nil {
+		return 0, err
+	}
+	if upload == nil {
+		log.Logvf(
+			log.Always,
+			"'%v' (_id %v) has no chunks and no upload of %v bytes, not importing it",
+			entry.Name,
+			entry.ID,
+			entry.Length,
+		)
+		return manifestMissing, nil
+	}
+
+	setManifestField(&entry.doc, "chunkSize", upload.ChunkSize)
+	if err := mf.writeManifestFile(entry, exists); err != nil {
+		return 0, err
+	}
+	_, err = chunksColl.UpdateMany(
+		ctx,
+		bson.D{{Key: "files_id", Value: upload.ID}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "files_id", Value: entry.ID}}}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error moving the chunks of upload %v: %v", upload.ID, err)
+	}
+	if _, err := filesColl.DeleteOne(ctx, bson.D{{Key: "_id", Value: upload.ID}}); err != nil {
+		return 0, fmt.Errorf("error deleting upload %v: %v", upload.ID, err)
+	}
+	log.Logvf(
+		log.DebugLow,
+		"moved the chunks of upload %v to '%v' (_id %v)",
+		upload.ID,
+		entry.Name,
+		entry.ID,
+	)
+	return manifestRelinked, nil
+}
+
+// writeManifestFile inserts the files document of entry, or replaces the
+// existing one.
+func (mf *MongoFiles) writeManifestFile(entry *manifestEntry, exists bool) error {
+	ctx := context.Background()
+	filesColl := mf.bucket.GetFilesCollection()
+	var err error
+	if exists {
+		_, err = filesColl.ReplaceOne(ctx, bson.D{{Key: "_id", Value: entry.ID}}, entry.doc)
+	} else {
+		_, err = filesColl.InsertOne(ctx, entry.doc)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing the file: %v", err)
+	}
+	return nil
+}
+
+// findUpload returns the most recent complete upload of the content of
+// entry: a file with its name and length that is not in the manifest, and
+// whose md5, if both have one, is the same. It returns nil if there is
+// none.
+func (mf *MongoFiles) findUpload(entry *manifestEntry, manifestIDs bson.A) (*manifestEntry, error) {
+	ctx := context.Background()
+	cursor, err := mf.bucket.GetFilesCollection().Find(
+		ctx,
+		bson.D{
+			{Key: "filename", Value: entry.Name},
+			{Key: "length", Value: entry.Length},
+			{Key: "_id", Value: bson.D{{Key: "$nin", Value: manifestIDs}}},
+		},
+		driverOptions.Find().SetSort(bson.D{{Key: "uploadDate", Value: -1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up uploads: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var candidates []*manifestEntry
+	for cursor.Next(ctx) {
+		candidate := &manifestEntry{}
+		if err := cursor.Decode(candidate); err != nil {
+			return nil, fmt.Errorf("error decoding upload: %v", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error looking up uploads: %v", err)
+	}
+
+	for _, candidate := range matchingUploads(entry, candidates) {
+		chunks, err := mf.bucket.GetChunksCollection().CountDocuments(
+			ctx,
+			bson.D{{Key: "files_id", Value: candidate.ID}},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error counting chunks: %v", err)
+		}
+		if chunks == candidate.expectedChunks() {
+			return candidate, nil
+		}
+	}
+	return nil, nil
+}
+
+// matchingUploads returns the candidates whose content can be that of
+// entry.
+func matchingUploads(entry *manifestEntry, candidates []*manifestEntry) []*manifestEntry {
+	var matching []*manifestEntry
+	for _, candidate := range candidates {
+		if candidate.Length != entry.Length {
+			continue
+		}
+		if entry.MD5 != "" && candidate.MD5 != "" && !strings.EqualFold(entry.MD5, candidate.MD5) {
+			continue
+		}
+		matching = append(matching, candidate)
+	}
+	return matching
+}
+
+// setManifestField sets the field key of doc to value.
+func setManifestField(doc *bson.D, key string, value interface{}) {
+	for i := range *doc {
+		if (*doc)[i].Key == key {
+			(*doc)[i].Value = value
+			return
+		}
+	}
+	*doc = append(*doc, bson.E{Key: key, Value: value})
+}
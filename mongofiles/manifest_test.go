@@ -0,0 +1,85 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongofiles
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestReadManifest(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("Reading a manifest", t, func() {
+		Convey("parses a files document per line", func() {
+			manifest := `{"_id":{"$oid":"65a000000000000000000001"},"length":{"$numberLong":"300"},` +
+				`"chunkSize":{"$numberInt":"255"},"uploadDate":{"$date":{"$numberLong":"0"}},` +
+				`"filename":"a.txt","metadata":{"owner":"ops"}}` + "\n\n" +
+				`{"_id":"report","length":0,"chunkSize":261120,"filename":"b.txt","md5":"ABC"}` + "\n"
+			entries, err := readManifest(strings.NewReader(manifest))
+			So(err, ShouldBeNil)
+			So(entries, ShouldHaveLength, 2)
+
+			So(entries[0].line, ShouldEqual, 1)
+			So(entries[0].Name, ShouldEqual, "a.txt")
+			So(entries[0].Length, ShouldEqual, 300)
+			So(entries[0].expectedChunks(), ShouldEqual, 2)
+			So(entries[0].doc[5].Key, ShouldEqual, "metadata")
+
+			So(entries[1].line, ShouldEqual, 3)
+			So(entries[1].ID.StringValue(), ShouldEqual, "report")
+			So(entries[1].MD5, ShouldEqual, "ABC")
+			So(entries[1].expectedChunks(), ShouldEqual, 0)
+		})
+
+		Convey("rejects documents that are not files", func() {
+			for _, line := range []string{
+				`{"filename":"a.txt","length":1}`,
+				`{"_id":1,"length":1}`,
+				`{"_id":1,"filename":"a.txt","length":-1}`,
+				`{"_id":1,`,
+			} {
+				_, err := readManifest(strings.NewReader("\n" + line))
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "manifest line 2")
+			}
+		})
+	})
+}
+
+func TestManifestUploads(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("The uploads that can hold the content of a file", t, func() {
+		entry := &manifestEntry{Name: "a.txt", Length: 10, MD5: "abc"}
+		same := &manifestEntry{Length: 10, MD5: "ABC"}
+		noMD5 := &manifestEntry{Length: 10}
+		otherMD5 := &manifestEntry{Length: 10, MD5: "def"}
+		shorter := &manifestEntry{Length: 9}
+
+		matching := matchingUploads(entry, []*manifestEntry{otherMD5, same, shorter, noMD5})
+		So(matching, ShouldResemble, []*manifestEntry{same, noMD5})
+
+		entry.MD5 = ""
+		matching = matchingUploads(entry, []*manifestEntry{otherMD5, shorter})
+		So(matching, ShouldResemble, []*manifestEntry{otherMD5})
+	})
+
+	Convey("The chunk size of an upload replaces that of the manifest", t, func() {
+		doc := bson.D{{"_id", 1}, {"chunkSize", 255}}
+		setManifestField(&doc, "chunkSize", int64(1024))
+		So(doc, ShouldResemble, bson.D{{"_id", 1}, {"chunkSize", int64(1024)}})
+
+		doc = bson.D{{"_id", 1}}
+		setManifestField(&doc, "chunkSize", int64(1024))
+		So(doc, ShouldResemble, bson.D{{"_id", 1}, {"chunkSize", int64(1024)}})
+	})
+}
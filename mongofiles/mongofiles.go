@@ -18,9 +18,11 @@ import (
 	"github.com/mongodb/mongo-tools/common/db"
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/progress"
 	"github.com/mongodb/mongo-tools/common/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	driverOptions "go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -36,6 +38,13 @@ const (
 	GetRegex = "get_regex"
 	Delete   = "delete"
 	DeleteID = "delete_id"
+	Rename   = "rename"
+	GC       = "gc"
+	Tail     = "tail"
+	Serve    = "serve"
+
+	ExportManifest = "export-manifest"
+	ImportManifest = "import-manifest"
 )
 
 // MongoFiles is a container for the user-specified options and
@@ -62,6 +71,9 @@ type MongoFiles struct {
 	// ID to put into GridFS
 	Id string
 
+	// new filename in GridFS for use with rename
+	NewFileName string
+
 	// List of filenames for use as supporting
 	// arguments in put and get commands
 	FileNameList []string
@@ -70,8 +82,14 @@ type MongoFiles struct {
 	// for get_regex
 	FileNameRegex string
 
+	// local file of export-manifest and import-manifest
+	ManifestFile string
+
 	// GridFS bucket to operate on
 	bucket *gridfs.Bucket
+
+	// shows the progress of each file while several are transferred
+	bars *progress.BarWriter
 }
 
 // New constructs a new mongofiles instance from the provided options. Will fail if cannot connect to server or if the
@@ -125,6 +143,17 @@ func (mf *MongoFiles) ValidateCommand(args []string) error {
 	case Put, Get:
 		// monogofiles put ... and mongofiles get ... should work
 		// over a list of files, i.e. by using mf.FileNameList
+		if mf.StorageOptions.FileList != "" {
+			if len(args) > 1 {
+				return fmt.Errorf("cannot use --fileList with file names as arguments")
+			}
+			names, err := readFileList(mf.StorageOptions.FileList)
+			if err != nil {
+				return err
+			}
+			mf.FileNameList = names
+			break
+		}
 		if len(args) == 1 || args[1] == "" {
 			return fmt.Errorf("'%v' argument missing", args[0])
 		}
@@ -173,6 +202,73 @@ func (mf *MongoFiles) ValidateCommand(args []string) error {
 		}
 		mf.FileName = args[1]
 		mf.Id = args[2]
+	case Rename:
+		if len(args) > 3 {
+			return fmt.Errorf(
+				"too many non-URI positional arguments (If you are trying to specify a connection string, it must begin with mongodb:// or mongodb+srv://)",
+			)
+		}
+		if len(args) < 3 || args[1] == "" || args[2] == "" {
+			return fmt.Errorf("'%v' argument(s) missing", args[0])
+		}
+		mf.FileName = args[1]
+		mf.NewFileName = args[2]
+	case GC:
+		if len(args) > 1 {
+			return fmt.Errorf(
+				"too many non-URI positional arguments (If you are trying to specify a connection string, it must begin with mongodb:// or mongodb+srv://)",
+			)
+		}
+		if mf.StorageOptions.GCBatchSize <= 0 {
+			return fmt.Errorf("--gcBatchSize must be positive")
+		}
+		if mf.StorageOptions.GCMaxDeletesPerSecond < 0 {
+			return fmt.Errorf("--gcMaxDeletesPerSecond cannot be negative")
+		}
+	case Tail:
+		if len(args) > 2 {
+			return fmt.Errorf(
+				"too many non-URI positional arguments (If you are trying to specify a connection string, it must begin with mongodb:// or mongodb+srv://)",
+			)
+		}
+		if len(args) == 1 || args[1] == "" {
+			return fmt.Errorf("'%v' argument missing", args[0])
+		}
+		if mf.StorageOptions.TailIntervalMS <= 0 {
+			return fmt.Errorf("--tailIntervalMS must be positive")
+		}
+		if mf.StorageOptions.TailIdleTimeout < 0 {
+			return fmt.Errorf("--tailIdleTimeout cannot be negative")
+		}
+		mf.FileName = args[1]
+	case Serve:
+		if len(args) > 1 {
+			return fmt.Errorf(
+				"too many non-URI positional arguments (If you are trying to specify a connection string, it must begin with mongodb:// or mongodb+srv://)",
+			)
+		}
+		if mf.StorageOptions.ServeAddress == "" {
+			return fmt.Errorf("--serveAddress cannot be blank")
+		}
+	case ExportManifest:
+		if len(args) > 2 {
+			return fmt.Errorf(
+				"too many non-URI positional arguments (If you are trying to specify a connection string, it must begin with mongodb:// or mongodb+srv://)",
+			)
+		}
+		if len(args) == 2 {
+			mf.ManifestFile = args[1]
+		}
+	case ImportManifest:
+		if len(args) > 2 {
+			return fmt.Errorf(
+				"too many non-URI positional arguments (If you are trying to specify a connection string, it must begin with mongodb:// or mongodb+srv://)",
+			)
+		}
+		if len(args) == 1 || args[1] == "" {
+			return fmt.Errorf("'%v' argument missing", args[0])
+		}
+		mf.ManifestFile = args[1]
 	default:
 		return fmt.Errorf(
 			"'%v' is not a valid command (If you are trying to specify a connection string, it must begin with mongodb:// or mongodb+srv://)",
@@ -183,6 +279,12 @@ func (mf *MongoFiles) ValidateCommand(args []string) error {
 	if mf.StorageOptions.GridFSPrefix == "" {
 		return fmt.Errorf("--prefix cannot be blank")
 	}
+	if mf.StorageOptions.FileList != "" && args[0] != Put && args[0] != Get {
+		return fmt.Errorf("--fileList can only be used with put and get")
+	}
+	if mf.StorageOptions.NumParallelFiles < 0 {
+		return fmt.Errorf("--numParallelFiles must not be negative")
+	}
 
 	mf.Command = args[0]
 	return nil
@@ -229,13 +331,9 @@ func (mf *MongoFiles) handleGet() (err error) {
 		return fmt.Errorf("cannot get multiple files with --local specified")
 	}
 
-	for _, file := range files {
-		if err = mf.writeGFSFileToLocal(file); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return mf.transferAll(len(files), func(worker *MongoFiles, i int) error {
+		return worker.writeGFSFileToLocal(files[i])
+	})
 }
 
 // Gets all GridFS files that match the given query.
@@ -341,6 +439,116 @@ func (mf *MongoFiles) handleDeleteID() error {
 	return nil
 }
 
+// handleRename contains the logic for the 'rename' command. The filename is
+// changed with a single findAndModify on the files collection, so concurrent
+// readers see either the old or the new name but never both.
+func (mf *MongoFiles) handleRename() error {
+	if mf.FileName == mf.NewFileName {
+		return fmt.Errorf("cannot rename '%v' to itself", mf.FileName)
+	}
+
+	filesColl := mf.bucket.GetFilesCollection()
+
+	if !mf.StorageOptions.Replace {
+		count, err := filesColl.CountDocuments(
+			context.Background(),
+			bson.M{"filename": mf.NewFileName},
+		)
+		if err != nil {
+			return fmt.Errorf("error checking for existing file '%v': %v", mf.NewFileName, err)
+		}
+		if count > 0 {
+			return fmt.Errorf(
+				"file with name '%v' already exists (use --replace to overwrite it)",
+				mf.NewFileName,
+			)
+		}
+	}
+
+	var renamed gfsFile
+	err := filesColl.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"filename": mf.FileName},
+		bson.M{"$set": bson.M{"filename": mf.NewFileName}},
+		driverOptions.FindOneAndUpdate().SetSort(bson.D{{Key: "uploadDate", Value: -1}}),
+	).Decode(&renamed)
+	if err == mongo.ErrNoDocuments {
+		return fmt.Errorf("no such file with name: %v", mf.FileName)
+	}
+	if err != nil {
+		return fmt.Errorf("error renaming '%v' to '%v': %v", mf.FileName, mf.NewFileName, err)
+	}
+
+	if !mf.StorageOptions.Replace {
+		if err := mf.undoConflictingRename(renamed.ID); err != nil {
+			return err
+		}
+	}
+
+	// with --replace, remove the files previously stored under the new name,
+	// leaving only the one we just renamed
+	if mf.StorageOptions.Replace {
+		gridFiles, err := mf.findGFSFiles(bson.M{
+			"filename": mf.NewFileName,
+			"_id":      bson.M{"$ne": renamed.ID},
+		})
+		if err != nil {
+			return err
+		}
+		for _, gridFile := range gridFiles {
+			if err := gridFile.Delete(); err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Logvf(
+		log.Always,
+		"successfully renamed '%v' to '%v' in GridFS",
+		mf.FileName,
+		mf.NewFileName,
+	)
+
+	return nil
+}
+
+// undoConflictingRename renames the file with id, which was just renamed from
+// FileName to NewFileName, back to FileName if another file is named
+// NewFileName. The check before the rename misses the files that are stored
+// or renamed to NewFileName while it runs, and of two renames to the same
+// name at least one sees the other here.
+func (mf *MongoFiles) undoConflictingRename(id interface{}) error {
+	filesColl := mf.bucket.GetFilesCollection()
+	count, err := filesColl.CountDocuments(
+		context.Background(),
+		bson.M{"filename": mf.NewFileName, "_id": bson.M{"$ne": id}},
+	)
+	if err != nil {
+		return fmt.Errorf("error checking for existing file '%v': %v", mf.NewFileName, err)
+	}
+	if count == 0 {
+		return nil
+	}
+	_, err = filesColl.UpdateOne(
+		context.Background(),
+		bson.M{"_id": id, "filename": mf.NewFileName},
+		bson.M{"$set": bson.M{"filename": mf.FileName}},
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"error renaming '%v' back to '%v' after another file of its name was stored: %v",
+			mf.NewFileName,
+			mf.FileName,
+			err,
+		)
+	}
+	return fmt.Errorf(
+		"file with name '%v' was stored while renaming '%v' to it (use --replace to overwrite it)",
+		mf.NewFileName,
+		mf.FileName,
+	)
+}
+
 // parse and convert input extended JSON _id. Generates a new ObjectID if no _id provided.
 func (mf *MongoFiles) parseOrCreateID() (interface{}, error) {
 	trimmed := strings.Trim(mf.Id, " ")
@@ -388,7 +596,8 @@ func (mf *MongoFiles) writeGFSFileToLocal(gridFile *gfsFile) (err error) {
 	dc := util.DeferredCloser{Closer: stream}
 	defer dc.CloseWithErrorCapture(&err)
 
-	if _, err = io.Copy(localFile, stream); err != nil {
+	_, err = mf.copyWithProgress(localFile, stream, localFileName, gridFile.Length)
+	if err != nil {
 		return fmt.Errorf("error while writing Data into local file '%v': %v", localFileName, err)
 	}
 
@@ -406,6 +615,7 @@ func (mf *MongoFiles) put(id interface{}, name string) (bytesWritten int64, err
 	localFileName := mf.getLocalFileName(gridFile)
 
 	var localFile io.ReadCloser
+	var size int64
 	if localFileName == "-" {
 		localFile = os.Stdin
 	} else {
@@ -413,6 +623,9 @@ func (mf *MongoFiles) put(id interface{}, name string) (bytesWritten int64, err
 		if err != nil {
 			return 0, fmt.Errorf("error while opening local gridFile '%v' : %v", localFileName, err)
 		}
+		if stat, statErr := os.Stat(localFileName); statErr == nil {
+			size = stat.Size()
+		}
 		dc := util.DeferredCloser{Closer: localFile}
 		defer dc.CloseWithErrorCapture(&err)
 		log.Logvf(log.DebugLow, "creating GridFS gridFile '%v' from local gridFile '%v'", mf.FileName, localFileName)
@@ -436,7 +649,7 @@ func (mf *MongoFiles) put(id interface{}, name string) (bytesWritten int64, err
 	dc := util.DeferredCloser{Closer: stream}
 	defer dc.CloseWithErrorCapture(&err)
 
-	n, err := io.Copy(stream, localFile)
+	n, err := mf.copyWithProgress(stream, localFile, localFileName, size)
 	if err != nil {
 		return n, fmt.Errorf("error while storing '%v' into GridFS: %v", localFileName, err)
 	}
@@ -450,24 +663,58 @@ func (mf *MongoFiles) handlePut() error {
 		mf.FileNameList = []string{mf.FileName}
 	}
 
-	for _, filename := range mf.FileNameList {
-		id, err := mf.parseOrCreateID()
+	return mf.transferAll(len(mf.FileNameList), func(worker *MongoFiles, i int) error {
+		filename := mf.FileNameList[i]
+		id, err := worker.parseOrCreateID()
 		if err != nil {
 			return err
 		}
 
 		log.Logvf(log.Always, "adding gridFile: %v\n", filename)
 
-		n, err := mf.put(id, filename)
+		n, err := worker.put(id, filename)
 		if err != nil {
 			log.Logvf(log.Always, "error adding gridFile: %v\n", err)
 			return err
 		}
 		log.Logvf(log.DebugLow, "copied %v bytes to server", n)
 		log.Logvf(log.Always, "added gridFile: %v\n", filename)
+		return nil
+	})
+}
+
+// readFileList reads the names in a --fileList, skipping blank lines.
+func readFileList(path string) ([]string, error) {
+	lines, err := util.GetFieldsFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading --fileList: %v", err)
+	}
+	var names []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			names = append(names, line)
+		}
 	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("--fileList %v has no file names", path)
+	}
+	return names, nil
+}
 
-	return nil
+// newBucket returns the GridFS bucket of the --db and --prefix.
+func (mf *MongoFiles) newBucket() (*gridfs.Bucket, error) {
+	client, err := mf.SessionProvider.GetSession()
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %v", err)
+	}
+	bucket, err := gridfs.NewBucket(
+		client.Database(mf.StorageOptions.DB),
+		&driverOptions.BucketOptions{Name: &mf.StorageOptions.GridFSPrefix},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error getting GridFS bucket: %v", err)
+	}
+	return bucket, nil
 }
 
 // Run the mongofiles utility. If displayHost is true, the connected host/port is
@@ -493,13 +740,9 @@ func (mf *MongoFiles) Run(displayHost bool) (output string, finalErr error) {
 		return "", fmt.Errorf("error connecting to host: %v", err)
 	}
 
-	database := client.Database(mf.StorageOptions.DB)
-	mf.bucket, err = gridfs.NewBucket(
-		database,
-		&driverOptions.BucketOptions{Name: &mf.StorageOptions.GridFSPrefix},
-	)
+	mf.bucket, err = mf.newBucket()
 	if err != nil {
-		return "", fmt.Errorf("error getting GridFS bucket: %v", err)
+		return "", err
 	}
 
 	if displayHost {
@@ -547,6 +790,24 @@ func (mf *MongoFiles) Run(displayHost bool) (output string, finalErr error) {
 
 	case Delete:
 		err = mf.deleteAll(mf.FileName)
+
+	case Rename:
+		err = mf.handleRename()
+
+	case GC:
+		output, err = mf.handleGC()
+
+	case Tail:
+		err = mf.handleTail()
+
+	case Serve:
+		err = mf.handleServe()
+
+	case ExportManifest:
+		err = mf.handleExportManifest()
+
+	case ImportManifest:
+		err = mf.handleImportManifest()
 	}
 
 	return output, err
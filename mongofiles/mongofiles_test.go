@@ -22,6 +22,7 @@ import (
 	"github.com/mongodb/mongo-tools/common/testutil"
 	"github.com/mongodb/mongo-tools/common/util"
 	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"go.mongodb.org/mongo-driver/mongo/writeconcern"
@@ -318,6 +319,92 @@ func TestValidArguments(t *testing.T) {
 			So(err.Error(), ShouldEqual, fmt.Sprintf("'%v' argument(s) missing", "put_id"))
 		})
 
+		Convey("rename should error out when more than 3 positional argument provided", func() {
+			args := []string{"rename", "arg1", "arg2", "arg3"}
+			err := mf.ValidateCommand(args)
+			So(err, ShouldNotBeNil)
+			So(
+				err.Error(),
+				ShouldEqual,
+				"too many non-URI positional arguments (If you are trying to specify a connection string, it must begin with mongodb:// or mongodb+srv://)",
+			)
+		})
+
+		Convey("rename should error out when only 1 positional argument provided", func() {
+			args := []string{"rename", "arg1"}
+			err := mf.ValidateCommand(args)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, fmt.Sprintf("'%v' argument(s) missing", "rename"))
+		})
+
+		Convey("rename should set both the old and new filenames", func() {
+			args := []string{"rename", "old", "new"}
+			So(mf.ValidateCommand(args), ShouldBeNil)
+			So(mf.FileName, ShouldEqual, "old")
+			So(mf.NewFileName, ShouldEqual, "new")
+		})
+
+		Convey("gc should not take any positional arguments", func() {
+			mf.StorageOptions.GCBatchSize = 1000
+			So(mf.ValidateCommand([]string{"gc"}), ShouldBeNil)
+
+			err := mf.ValidateCommand([]string{"gc", "arg1"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "too many non-URI positional arguments")
+
+			mf.StorageOptions.GCBatchSize = 0
+			So(mf.ValidateCommand([]string{"gc"}), ShouldNotBeNil)
+		})
+
+		Convey("tail should take exactly one filename", func() {
+			mf.StorageOptions.TailIntervalMS = 1000
+			So(mf.ValidateCommand([]string{"tail", "log.txt"}), ShouldBeNil)
+			So(mf.FileName, ShouldEqual, "log.txt")
+
+			err := mf.ValidateCommand([]string{"tail"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, fmt.Sprintf("'%v' argument missing", "tail"))
+
+			err = mf.ValidateCommand([]string{"tail", "arg1", "arg2"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "too many non-URI positional arguments")
+
+			mf.StorageOptions.TailIntervalMS = 0
+			So(mf.ValidateCommand([]string{"tail", "log.txt"}), ShouldNotBeNil)
+		})
+
+		Convey("serve should not take any positional arguments", func() {
+			mf.StorageOptions.ServeAddress = "localhost:8080"
+			So(mf.ValidateCommand([]string{"serve"}), ShouldBeNil)
+
+			err := mf.ValidateCommand([]string{"serve", "arg1"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "too many non-URI positional arguments")
+
+			mf.StorageOptions.ServeAddress = ""
+			So(mf.ValidateCommand([]string{"serve"}), ShouldNotBeNil)
+		})
+
+		Convey("export-manifest should take an optional manifest file", func() {
+			So(mf.ValidateCommand([]string{"export-manifest"}), ShouldBeNil)
+			So(mf.ManifestFile, ShouldEqual, "")
+			So(mf.ValidateCommand([]string{"export-manifest", "files.json"}), ShouldBeNil)
+			So(mf.ManifestFile, ShouldEqual, "files.json")
+
+			err := mf.ValidateCommand([]string{"export-manifest", "arg1", "arg2"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "too many non-URI positional arguments")
+		})
+
+		Convey("import-manifest should take exactly one manifest file", func() {
+			So(mf.ValidateCommand([]string{"import-manifest", "files.json"}), ShouldBeNil)
+			So(mf.ManifestFile, ShouldEqual, "files.json")
+
+			err := mf.ValidateCommand([]string{"import-manifest"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, fmt.Sprintf("'%v' argument missing", "import-manifest"))
+		})
+
 		Convey("It should not error out when list command isn't given an argument", func() {
 			args := []string{"list"}
 			So(mf.ValidateCommand(args), ShouldBeNil)
@@ -342,6 +429,29 @@ func TestValidArguments(t *testing.T) {
 			},
 		)
 
+		Convey("put and get should read the file names of a --fileList", func() {
+			list := util.ToUniversalPath(t.TempDir() + "/files.txt")
+			So(os.WriteFile(list, []byte("foo\n\nbar\n"), 0o644), ShouldBeNil)
+			mf.StorageOptions.FileList = list
+			mf.StorageOptions.NumParallelFiles = 4
+			for _, command := range []string{"get", "put"} {
+				So(mf.ValidateCommand([]string{command}), ShouldBeNil)
+				So(mf.FileNameList, ShouldResemble, []string{"foo", "bar"})
+
+				err := mf.ValidateCommand([]string{command, "baz"})
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "cannot use --fileList")
+			}
+
+			err := mf.ValidateCommand([]string{"list"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "--fileList can only be used")
+
+			mf.StorageOptions.FileList = ""
+			mf.StorageOptions.NumParallelFiles = -1
+			So(mf.ValidateCommand([]string{"get", "foo"}), ShouldNotBeNil)
+		})
+
 		Convey(
 			"It should error out when any of (get|put|delete|search|get_id|delete_id) not given supporting argument",
 			func() {
@@ -794,6 +904,142 @@ func TestMongoFilesCommands(t *testing.T) {
 			})
 		})
 
+		Convey("Testing the 'rename' command with a file that is in GridFS should", func() {
+			mf, err := simpleMongoFilesInstanceWithFilename("rename", "testfile3")
+			So(err, ShouldBeNil)
+			So(mf, ShouldNotBeNil)
+			mf.NewFileName = "testfile3renamed"
+
+			var buff bytes.Buffer
+			log.SetWriter(&buff)
+
+			Convey("rename the file in GridFS", func() {
+				str, err := mf.Run(false)
+				So(err, ShouldBeNil)
+				So(str, ShouldEqual, "")
+
+				bytesGotten, err := getFilesAndBytesListFromGridFS()
+				So(err, ShouldBeNil)
+				So(len(bytesGotten), ShouldEqual, len(testFiles))
+				So(bytesGotten, ShouldNotContainKey, "testfile3")
+				So(bytesGotten, ShouldContainKey, "testfile3renamed")
+			})
+
+			Convey("refuse to overwrite an existing file without --replace", func() {
+				mf.NewFileName = "testfile4"
+				_, err := mf.Run(false)
+				So(err, ShouldNotBeNil)
+
+				bytesGotten, err := getFilesAndBytesListFromGridFS()
+				So(err, ShouldBeNil)
+				So(bytesGotten, ShouldContainKey, "testfile3")
+			})
+
+			Convey("undo a rename that conflicts with a file stored while renaming", func() {
+				mf.NewFileName = "testfile4"
+				mf.bucket, err = mf.newBucket()
+				So(err, ShouldBeNil)
+				files := mf.bucket.GetFilesCollection()
+				_, err = files.UpdateOne(
+					context.Background(),
+					bson.M{"_id": testFiles["testfile3"]},
+					bson.M{"$set": bson.M{"filename": "testfile4"}},
+				)
+				So(err, ShouldBeNil)
+
+				err = mf.undoConflictingRename(testFiles["testfile3"])
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "was stored while renaming")
+
+				bytesGotten, err := getFilesAndBytesListFromGridFS()
+				So(err, ShouldBeNil)
+				So(len(bytesGotten), ShouldEqual, len(testFiles))
+				So(bytesGotten, ShouldContainKey, "testfile3")
+			})
+
+			Convey("overwrite an existing file with --replace", func() {
+				mf.NewFileName = "testfile4"
+				mf.StorageOptions.Replace = true
+				_, err := mf.Run(false)
+				So(err, ShouldBeNil)
+
+				bytesGotten, err := getFilesAndBytesListFromGridFS()
+				So(err, ShouldBeNil)
+				So(len(bytesGotten), ShouldEqual, len(testFiles)-1)
+				So(bytesGotten, ShouldNotContainKey, "testfile3")
+				So(bytesGotten, ShouldContainKey, "testfile4")
+			})
+		})
+
+		Convey("Testing the 'gc' command with orphaned chunks and an incomplete file should", func() {
+			session, err := testutil.GetBareSession()
+			So(err, ShouldBeNil)
+			chunks := session.Database(testDB).Collection("fs.chunks")
+
+			orphanID := primitive.NewObjectID()
+			_, err = chunks.InsertMany(context.Background(), []interface{}{
+				bson.D{{"files_id", orphanID}, {"n", 0}, {"data", []byte("a")}},
+				bson.D{{"files_id", orphanID}, {"n", 1}, {"data", []byte("b")}},
+			})
+			So(err, ShouldBeNil)
+			_, err = chunks.DeleteMany(
+				context.Background(),
+				bson.D{{"files_id", testFiles["testfile2"]}},
+			)
+			So(err, ShouldBeNil)
+
+			mf, err := simpleMongoFilesInstanceCommandOnly("gc")
+			So(err, ShouldBeNil)
+			mf.StorageOptions.GCBatchSize = 2
+
+			Convey("report them without deleting anything", func() {
+				output, err := mf.Run(false)
+				So(err, ShouldBeNil)
+				So(output, ShouldContainSubstring, "orphaned chunks\t"+orphanID.Hex())
+				So(output, ShouldContainSubstring, "incomplete file\ttestfile2\t")
+				So(len(cleanAndTokenizeTestOutput(output)), ShouldEqual, 2)
+
+				count, err := chunks.CountDocuments(
+					context.Background(),
+					bson.D{{"files_id", orphanID}},
+				)
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 2)
+			})
+
+			Convey("delete them with --gcDelete", func() {
+				mf.StorageOptions.GCDelete = true
+				_, err := mf.Run(false)
+				So(err, ShouldBeNil)
+
+				count, err := chunks.CountDocuments(
+					context.Background(),
+					bson.D{{"files_id", orphanID}},
+				)
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 0)
+
+				bytesGotten, err := getFilesAndBytesListFromGridFS()
+				So(err, ShouldBeNil)
+				So(len(bytesGotten), ShouldEqual, len(testFiles)-1)
+				So(bytesGotten, ShouldNotContainKey, "testfile2")
+
+				output, err := mf.Run(false)
+				So(err, ShouldBeNil)
+				So(output, ShouldEqual, "")
+			})
+		})
+
+		Convey("Testing the 'rename' command with a file that isn't in GridFS should error", func() {
+			mf, err := simpleMongoFilesInstanceWithFilename("rename", "gibberish")
+			So(err, ShouldBeNil)
+			mf.NewFileName = "gibberish2"
+
+			_, err = mf.Run(false)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "no such file with name: gibberish")
+		})
+
 		Reset(func() {
 			So(tearDownGridFSTestData(), ShouldBeNil)
 			err = os.Remove("lorem_ipsum_copy.txt")
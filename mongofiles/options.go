@@ -15,22 +15,30 @@ import (
 )
 
 // Usage string printed as part of --help.
-var Usage = `<options> <connection-string> <command> <filename or _id>
+var Usage = `<options> <connection-string> <command> <filename or _id> [<newname>]
 
 Manipulate gridfs files using the command line.
 
 Connection strings must begin with mongodb:// or mongodb+srv://.
 
 Possible commands include:
-	list      - list all files; 'filename' is an optional prefix which listed filenames must begin with
-	search    - search all files; 'filename' is a regex which listed filenames must match
-	put       - add files with filenames specified in the supporting arguments
-	put_id    - add a file with filename 'filename' and a given '_id'
-	get       - get files with filenames specified in the supporting arguments
-	get_id    - get a file with the given '_id'
-	get_regex - get files matching the supplied 'regex'
-	delete    - delete all files with filename 'filename'
-	delete_id - delete a file with the given '_id'
+	list            - list all files; 'filename' is an optional prefix which listed filenames must begin with
+	search          - search all files; 'filename' is a regex which listed filenames must match
+	put             - add files with filenames specified in the supporting arguments
+	put_id          - add a file with filename 'filename' and a given '_id'
+	get             - get files with filenames specified in the supporting arguments
+	get_id          - get a file with the given '_id'
+	get_regex       - get files matching the supplied 'regex'
+	delete          - delete all files with filename 'filename'
+	delete_id       - delete a file with the given '_id'
+	rename          - rename the most recent file named 'filename' to 'newname'
+	gc              - report chunks that belong to no file and files missing chunks; delete them with --gcDelete
+	tail            - follow the most recent file named 'filename', writing data appended to it as it arrives
+	serve           - serve the files read-only over HTTP at --serveAddress: a list of them at /, and the most
+	                  recent file of each name at /files/<filename>, with range requests
+	export-manifest - write the files collection documents, without their chunks, to 'filename' or stdout as JSON
+	import-manifest - recreate the files of an export-manifest 'filename' whose content is on the server:
+	                  chunks kept under their _id, or a file put again with the same name and length
 
 See http://docs.mongodb.com/database-tools/mongofiles/ for more information.`
 
@@ -103,8 +111,9 @@ type StorageOptions struct {
 	// 'ContentType' is an option that specifies the Content/MIME type to use for 'put'
 	ContentType string `long:"type" value-nane:"<content-type>" short:"t" description:"content/MIME type for put (optional)"`
 
-	// if set, 'Replace' will remove other files with same name after 'put'
-	Replace bool `long:"replace" short:"r" description:"remove other files with same name after put"`
+	// if set, 'Replace' will remove other files with same name after 'put' or 'rename',
+	// and replace files with the same _id in 'import-manifest'
+	Replace bool `long:"replace" short:"r" description:"remove other files with same name after put|rename; replace files with the same _id in import-manifest"`
 
 	// GridFSPrefix specifies what GridFS prefix to use; defaults to 'fs'
 	GridFSPrefix string `long:"prefix" value-name:"<prefix>" default:"fs" default-mask:"-" description:"GridFS prefix to use"`
@@ -117,6 +126,39 @@ type StorageOptions struct {
 	// RegexOptions specifies the options passed to "$regex" queries that are used for get_regex
 	// The default is to use no options, i.e. standard PCRE syntax
 	RegexOptions string `long:"regexOptions" default:"" value-name:"<regex-options>" description:"regex options used for get_regex"`
+
+	// FileList names the files for put and get in a file, one per line.
+	FileList string `long:"fileList" value-name:"<filename>" description:"file with the names of the files to put or get, one per line, instead of giving them as arguments"`
+
+	// NumParallelFiles is the number of files put and get transfer at once.
+	NumParallelFiles int `long:"numParallelFiles" value-name:"<count>" default:"1" default-mask:"-" description:"number of files put and get transfer at once, each with its own progress bar (default 1)"`
+
+	// GCDelete makes 'gc' delete the orphaned chunks and incomplete files it finds instead of only reporting them.
+	GCDelete bool `long:"gcDelete" description:"delete the orphaned chunks and incomplete files gc finds; make sure no uploads are in progress, since their chunks look orphaned until they finish"`
+
+	// GCBatchSize is the number of files 'gc' checks and deletes at a time.
+	GCBatchSize int `long:"gcBatchSize" value-name:"<count>" default:"1000" default-mask:"-" description:"number of files gc checks and deletes at a time (default 1000)"`
+
+	// GCMaxDeletesPerSecond limits how fast 'gc' deletes documents.
+	GCMaxDeletesPerSecond int `long:"gcMaxDeletesPerSecond" value-name:"<count>" description:"maximum number of documents gc deletes per second (default: no limit)"`
+
+	// TailFromStart makes 'tail' write the data the file already has before following it.
+	TailFromStart bool `long:"tailFromStart" description:"make tail write the existing content of the file before following it, instead of starting at its current end"`
+
+	// TailIntervalMS is how often 'tail' checks the file for new data.
+	TailIntervalMS int `long:"tailIntervalMS" value-name:"<milliseconds>" default:"1000" default-mask:"-" description:"how often tail checks the file for new data (default 1000)"`
+
+	// TailIdleTimeout makes 'tail' stop once the file hasn't grown for this long.
+	TailIdleTimeout int `long:"tailIdleTimeout" value-name:"<seconds>" description:"stop tail once the file hasn't grown for this many seconds (default: follow until interrupted)"`
+
+	// ServeAddress is the address 'serve' listens on.
+	ServeAddress string `long:"serveAddress" value-name:"<host>:<port>" default:"localhost:8080" default-mask:"-" description:"address serve listens on for HTTP requests; an address that is not a loopback address requires --serveToken or --serveTokenFile (default localhost:8080)"`
+
+	// ServeToken is the token that the requests of 'serve' must give.
+	ServeToken string `long:"serveToken" value-name:"<token>" description:"token that the requests of serve must give, as a bearer token or as the password of basic authentication"`
+
+	// ServeTokenFile is a file holding the token of 'serve'.
+	ServeTokenFile string `long:"serveTokenFile" value-name:"<filename>" description:"read the token of --serveToken from a file, without its trailing newline"`
 }
 
 // Name returns a human-readable group name for storage options.
@@ -0,0 +1,115 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongofiles
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/progress"
+)
+
+const (
+	transferBarWaitTime = 3 * time.Second
+	transferBarLength   = 24
+)
+
+// transferAll runs transfer for each of count files, on up to
+// --numParallelFiles files at once, and shows a progress bar for each file
+// being transferred. Every worker gets its own copy of mf with its own GridFS
+// bucket, since a bucket is not safe for concurrent uploads. Once a transfer
+// fails no more are started, and the first error is returned after the ones
+// in progress finish.
+func (mf *MongoFiles) transferAll(count int, transfer func(worker *MongoFiles, i int) error) error {
+	if count <= 1 || mf.StorageOptions.NumParallelFiles <= 1 {
+		for i := 0; i < count; i++ {
+			if err := transfer(mf, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	bars := progress.NewBarWriter(log.Writer(0), transferBarWaitTime, transferBarLength, true)
+	bars.Start()
+	defer bars.Stop()
+
+	numWorkers := max(1, min(mf.StorageOptions.NumParallelFiles, count))
+	indexes := make(chan int)
+	errs := make(chan error, numWorkers)
+	var wg sync.WaitGroup
+	var failed sync.Once
+	stop := make(chan struct{})
+	for w := 0; w < numWorkers; w++ {
+		worker := *mf
+		worker.bars = bars
+		if w > 0 {
+			bucket, err := mf.newBucket()
+			if err != nil {
+				close(indexes)
+				wg.Wait()
+				return err
+			}
+			worker.bucket = bucket
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := transfer(&worker, i); err != nil {
+					errs <- err
+					failed.Do(func() { close(stop) })
+					return
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for i := 0; i < count; i++ {
+		select {
+		case indexes <- i:
+		case <-stop:
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// copyWithProgress copies src to dst, and shows a progress bar named name
+// for the copy if several files are being transferred.
+func (mf *MongoFiles) copyWithProgress(
+	dst io.Writer,
+	src io.Reader,
+	name string,
+	size int64,
+) (int64, error) {
+	if mf.bars == nil {
+		return io.Copy(dst, src)
+	}
+	counter := progress.NewCounter(size)
+	mf.bars.Attach(name, counter)
+	defer mf.bars.Detach(name)
+	return io.Copy(dst, &countingReader{src, counter})
+}
+
+// countingReader counts the bytes read from a reader.
+type countingReader struct {
+	io.Reader
+	counter *progress.CountProgressor
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.counter.Inc(int64(n))
+	return n, err
+}
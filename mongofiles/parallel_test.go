@@ -0,0 +1,54 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongofiles
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTransferAllSequentially(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With one file at a time", t, func() {
+		mf := &MongoFiles{StorageOptions: &StorageOptions{NumParallelFiles: 1}}
+
+		Convey("every file is transferred in order", func() {
+			var transferred []int
+			err := mf.transferAll(3, func(worker *MongoFiles, i int) error {
+				So(worker, ShouldEqual, mf)
+				transferred = append(transferred, i)
+				return nil
+			})
+			So(err, ShouldBeNil)
+			So(transferred, ShouldResemble, []int{0, 1, 2})
+		})
+
+		Convey("no more files are transferred once one fails", func() {
+			var transferred []int
+			err := mf.transferAll(3, func(_ *MongoFiles, i int) error {
+				transferred = append(transferred, i)
+				if i == 1 {
+					return errors.New("failed")
+				}
+				return nil
+			})
+			So(err, ShouldNotBeNil)
+			So(transferred, ShouldResemble, []int{0, 1})
+		})
+
+		Convey("nothing is transferred without files", func() {
+			err := mf.transferAll(0, func(*MongoFiles, int) error {
+				return errors.New("unexpected transfer")
+			})
+			So(err, ShouldBeNil)
+		})
+	})
+}
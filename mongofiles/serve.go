@@ -0,0 +1,396 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongofiles
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/password"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	driverOptions "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// servedFilesPath is the path under which serve serves each file by name.
+const servedFilesPath = "/files/"
+
+// servedFile is a document of the files collection, as serve reads it.
+// Files put by old drivers have a contentType of their own, instead of in
+// their metadata.
+type servedFile struct {
+	ID          interface{}     `bson:"_id"`
+	Name        string          `bson:"filename"`
+	Length      int64           `bson:"length"`
+	UploadDate  time.Time       `bson:"uploadDate"`
+	ChunkSize   int             `bson:"chunkSize"`
+	ContentType string          `bson:"contentType"`
+	Metadata    gfsFileMetadata `bson:"metadata"`
+}
+
+// contentType returns the content type of the file, if it was put with one.
+func (file *servedFile) contentType() string {
+	if file.Metadata.ContentType != "" {
+		return file.Metadata.ContentType
+	}
+	return file.ContentType
+}
+
+// fileStore is the GridFS bucket that serve reads, so that the HTTP handler
+// can be tested without a server.
+type fileStore interface {
+	// list returns the files whose names start with prefix, sorted by name.
+	list(ctx context.Context, prefix string) ([]servedFile, error)
+	// open returns the most recent file named name, and a reader of its
+	// content that must be closed. It returns a nil file if there is none.
+	open(ctx context.Context, name string) (*servedFile, io.ReadSeekCloser, error)
+}
+
+// gridFSServer is the read-only HTTP handler of serve. It lists the files at
+// / and serves the most recent file of each name at /files/<name>, with range
+// requests and the content type of the file.
+type gridFSServer struct {
+	store fileStore
+	// token is the token that requests must give, if set.
+	token string
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html><head><title>{{.Title}}</title></head><body>
+<h1>{{.Title}}</h1>
+<table>
+<tr><th>filename</th><th>length</th><th>uploadDate</th><th>contentType</th></tr>
+{{range .Files}}<tr><td><a href="{{.Link}}">{{.Name}}</a></td><td>{{.Length}}</td>` +
+	`<td>{{.UploadDate}}</td><td>{{.ContentType}}</td></tr>
+{{end}}</table>
+</body></html>
+`))
+
+// ServeHTTP handles a request for the list of files or for a file.
+func (s *gridFSServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "mongofiles serve is read-only", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="mongofiles"`)
+		http.Error(w, "a valid --serveToken is required", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/":
+		s.serveIndex(w, r)
+	case strings.HasPrefix(r.URL.Path, servedFilesPath):
+		s.serveFile(w, r, strings.TrimPrefix(r.URL.Path, servedFilesPath))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorized returns whether r gives the token, as a bearer token or as the
+// password of basic authentication, which browsers prompt for.
+func (s *gridFSServer) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	given := ""
+	if _, pass, ok := r.BasicAuth(); ok {
+		given = pass
+	} else if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		given = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) == 1
+}
+
+func (s *gridFSServer) serveIndex(w http.ResponseWriter, r *http.Request) {
+	files, err := s.store.list(r.Context(), r.URL.Query().Get("prefix"))
+	if err != nil {
+		log.Logvf(log.Always, "error listing GridFS files: %v", err)
+		http.Error(w, "error listing files", http.StatusInternalServerError)
+		return
+	}
+	type row struct {
+		Name, Link, UploadDate, ContentType string
+		Length                              int64
+	}
+	rows := make([]row, len(files))
+	for i, file := range files {
+		rows[i] = row{
+			Name:        file.Name,
+			Link:        servedFilesPath + (&url.URL{Path: file.Name}).EscapedPath(),
+			UploadDate:  file.UploadDate.UTC().Format(time.RFC3339),
+			ContentType: file.contentType(),
+			Length:      file.Length,
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = indexTemplate.Execute(w, struct {
+		Title string
+		Files []row
+	}{"GridFS files", rows})
+	if err != nil {
+		log.Logvf(log.DebugLow, "error writing the list of files: %v", err)
+	}
+}
+
+func (s *gridFSServer) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	file, content, err := s.store.open(r.Context(), name)
+	if err != nil {
+		log.Logvf(log.Always, "error reading GridFS file '%v': %v", name, err)
+		http.Error(w, "error reading file", http.StatusInternalServerError)
+		return
+	}
+	if file == nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer content.Close()
+	if contentType := file.contentType(); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	// the files are served from the origin of the listing, to browsers that
+	// keep its credentials, so a stored HTML or SVG file must not run scripts
+	// there, neither with its own content type nor with a sniffed one
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	log.Logvf(log.DebugLow, "serving '%v' (_id %v) to %v", name, file.ID, r.RemoteAddr)
+	// ServeContent answers range and conditional requests, and guesses the
+	// content type from the name and content of files put without one.
+	http.ServeContent(w, r, file.Name, file.UploadDate, content)
+}
+
+// bucketStore is the fileStore of a GridFS bucket.
+type bucketStore struct {
+	files  *mongo.Collection
+	chunks *mongo.Collection
+}
+
+func (store *bucketStore) list(ctx context.Context, prefix string) ([]servedFile, error) {
+	filter := bson.D{}
+	if prefix != "" {
+		filter = bson.D{{Key: "filename", Value: bson.D{
+			{Key: "$regex", Value: "^" + regexp.QuoteMeta(prefix)},
+		}}}
+	}
+	cursor, err := store.files.Find(
+		ctx,
+		filter,
+		driverOptions.Find().SetSort(bson.D{
+			{Key: "filename", Value: 1},
+			{Key: "uploadDate", Value: -1},
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var files []servedFile
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (store *bucketStore) open(
+	ctx context.Context,
+	name string,
+) (*servedFile, io.ReadSeekCloser, error) {
+	var file servedFile
+	err := store.files.FindOne(
+		ctx,
+		bson.D{{Key: "filename", Value: name}},
+		driverOptions.FindOne().SetSort(bson.D{{Key: "uploadDate", Value: -1}}),
+	).Decode(&file)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if file.ChunkSize <= 0 && file.Length > 0 {
+		return nil, nil, fmt.Errorf("invalid chunkSize of %v", file.ChunkSize)
+	}
+	return &file, &chunkReader{ctx: ctx, chunks: store.chunks, file: &file}, nil
+}
+
+// chunkReader reads the content of a GridFS file from its chunks. A seek only
+// moves its position, so that a range request only reads the chunks of the
+// range.
+type chunkReader struct {
+	ctx    context.Context
+	chunks *mongo.Collection
+	file   *servedFile
+
+	pos    int64
+	cursor *mongo.Cursor
+	// data is the chunk that the cursor was at, which holds the file from
+	// offset on.
+	data   []byte
+	offset int64
+}
+
+// Read reads the content of the file from the position of the reader.
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	if cr.pos >= cr.file.Length {
+		return 0, io.EOF
+	}
+	for cr.pos < cr.offset || cr.pos >= cr.offset+int64(len(cr.data)) {
+		if err := cr.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, cr.data[cr.pos-cr.offset:])
+	cr.pos += int64(n)
+	return n, nil
+}
+
+// nextChunk reads the next chunk of the cursor, after opening one at the
+// chunk of the position if needed.
+func (cr *chunkReader) nextChunk() error {
+	chunkSize := int64(cr.file.ChunkSize)
+	if cr.cursor == nil {
+		var err error
+		cr.cursor, err = cr.chunks.Find(
+			cr.ctx,
+			bson.D{
+				{Key: "files_id", Value: cr.file.ID},
+				{Key: "n", Value: bson.D{{Key: "$gte", Value: cr.pos / chunkSize}}},
+			},
+			driverOptions.Find().SetSort(bson.D{{Key: "n", Value: 1}}),
+		)
+		if err != nil {
+			return fmt.Errorf("error reading chunks: %v", err)
+		}
+	}
+	if !cr.cursor.Next(cr.ctx) {
+		if err := cr.cursor.Err(); err != nil {
+			return fmt.Errorf("error reading chunks: %v", err)
+		}
+		return fmt.Errorf("chunk %v is missing", cr.pos/chunkSize)
+	}
+	var chunk tailChunk
+	if err := cr.cursor.Decode(&chunk); err != nil {
+		return fmt.Errorf("error decoding chunk: %v", err)
+	}
+	if chunk.N*chunkSize > cr.pos {
+		return fmt.Errorf("chunk %v is missing", cr.pos/chunkSize)
+	}
+	cr.data = chunk.Data
+	cr.offset = chunk.N * chunkSize
+	if len(cr.data) == 0 {
+		return fmt.Errorf("chunk %v is empty", chunk.N)
+	}
+	return nil
+}
+
+// Seek moves the position of the reader.
+func (cr *chunkReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = cr.pos + offset
+	case io.SeekEnd:
+		pos = cr.file.Length + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %v", whence)
+	}
+	if pos < 0 {
+		return 0, fmt.Errorf("cannot seek to negative position %v", pos)
+	}
+	if pos < cr.offset || pos >= cr.offset+int64(len(cr.data))+int64(cr.file.ChunkSize) {
+		// the chunks are read again from the chunk of the new position
+		cr.closeCursor()
+	}
+	cr.pos = pos
+	return pos, nil
+}
+
+// Close closes the cursor of the chunks.
+func (cr *chunkReader) Close() error {
+	cr.closeCursor()
+	return nil
+}
+
+func (cr *chunkReader) closeCursor() {
+	if cr.cursor != nil {
+		_ = cr.cursor.Close(cr.ctx)
+		cr.cursor = nil
+	}
+	cr.data = nil
+	cr.offset = 0
+}
+
+// handleServe serves the files of the bucket over HTTP at --serveAddress,
+// until it is interrupted.
+func (mf *MongoFiles) handleServe() error {
+	token := mf.StorageOptions.ServeToken
+	if mf.StorageOptions.ServeTokenFile != "" {
+		if token != "" {
+			return fmt.Errorf("cannot use both --serveToken and --serveTokenFile")
+		}
+		var err error
+		token, err = password.ReadSecretFile(mf.StorageOptions.ServeTokenFile)
+		if err != nil {
+			return fmt.Errorf("error reading --serveTokenFile: %v", err)
+		}
+	}
+	if err := checkServeAddress(mf.StorageOptions.ServeAddress, token); err != nil {
+		return err
+	}
+	server := &http.Server{
+		Addr: mf.StorageOptions.ServeAddress,
+		Handler: &gridFSServer{
+			store: &bucketStore{
+				files:  mf.bucket.GetFilesCollection(),
+				chunks: mf.bucket.GetChunksCollection(),
+			},
+			token: token,
+		},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Logvf(
+		log.Always,
+		"serving the GridFS files of %v.%v at http://%v",
+		mf.StorageOptions.DB,
+		mf.StorageOptions.GridFSPrefix,
+		mf.StorageOptions.ServeAddress,
+	)
+	return server.ListenAndServe()
+}
+
+// checkServeAddress checks that serve listens on a loopback address, which
+// only the users of the host can reach, unless requests must give a token.
+func checkServeAddress(address, token string) error {
+	if token != "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid --serveAddress '%v': %v", address, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf(
+			"--serveAddress '%v' is not a loopback address, which requires --serveToken or --serveTokenFile",
+			address,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongofiles
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// memStore is a fileStore of files in memory.
+type memStore struct {
+	files    []servedFile
+	contents map[string]string
+	// opened is the number of contents opened and not closed yet.
+	opened int
+}
+
+type memContent struct {
+	*strings.Reader
+	store *memStore
+}
+
+func (content memContent) Close() error {
+	content.store.opened--
+	return nil
+}
+
+func (store *memStore) list(_ context.Context, prefix string) ([]servedFile, error) {
+	var files []servedFile
+	for _, file := range store.files {
+		if strings.HasPrefix(file.Name, prefix) {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func (store *memStore) open(
+	_ context.Context,
+	name string,
+) (*servedFile, io.ReadSeekCloser, error) {
+	for i := range store.files {
+		if store.files[i].Name == name {
+			store.opened++
+			return &store.files[i], memContent{strings.NewReader(store.contents[name]), store}, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+func TestServe(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	uploaded := time.Date(2025, 3, 5, 10, 30, 0, 0, time.UTC)
+	store := &memStore{
+		files: []servedFile{
+			{
+				ID:         1,
+				Name:       "images/logo.png",
+				Length:     10,
+				UploadDate: uploaded,
+				Metadata:   gfsFileMetadata{ContentType: "image/png"},
+			},
+			{ID: 2, Name: "notes.txt", Length: 11, UploadDate: uploaded, ContentType: "text/x-notes"},
+			{ID: 3, Name: "report.csv", Length: 3, UploadDate: uploaded},
+		},
+		contents: map[string]string{
+			"images/logo.png": "0123456789",
+			"notes.txt":       "hello world",
+			"report.csv":      "a,b",
+		},
+	}
+
+	get := func(
+		server *gridFSServer,
+		method, path string,
+		header http.Header,
+	) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	Convey("With a server without a token", t, func() {
+		server := &gridFSServer{store: store}
+
+		Convey("files should be served with the content type of their metadata", func() {
+			rec := get(server, http.MethodGet, "/files/images/logo.png", nil)
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(rec.Header().Get("Content-Type"), ShouldEqual, "image/png")
+			So(rec.Header().Get("Accept-Ranges"), ShouldEqual, "bytes")
+			So(rec.Body.String(), ShouldEqual, "0123456789")
+
+			rec = get(server, http.MethodGet, "/files/notes.txt", nil)
+			So(rec.Header().Get("Content-Type"), ShouldEqual, "text/x-notes")
+
+			rec = get(server, http.MethodGet, "/files/report.csv", nil)
+			So(rec.Header().Get("Content-Type"), ShouldStartWith, "text/csv")
+			So(store.opened, ShouldEqual, 0)
+		})
+
+		Convey("files should not be sniffed or run scripts", func() {
+			rec := get(server, http.MethodGet, "/files/notes.txt", nil)
+			So(rec.Header().Get("X-Content-Type-Options"), ShouldEqual, "nosniff")
+			So(rec.Header().Get("Content-Security-Policy"), ShouldEqual, "sandbox")
+		})
+
+		Convey("range requests should be served the range", func() {
+			rec := get(server, http.MethodGet, "/files/images/logo.png",
+				http.Header{"Range": {"bytes=2-5"}})
+			So(rec.Code, ShouldEqual, http.StatusPartialContent)
+			So(rec.Header().Get("Content-Range"), ShouldEqual, "bytes 2-5/10")
+			So(rec.Body.String(), ShouldEqual, "2345")
+			So(store.opened, ShouldEqual, 0)
+		})
+
+		Convey("conditional requests should use the upload date", func() {
+			rec := get(server, http.MethodGet, "/files/notes.txt",
+				http.Header{"If-Modified-Since": {uploaded.Format(http.TimeFormat)}})
+			So(rec.Code, ShouldEqual, http.StatusNotModified)
+		})
+
+		Convey("the index should list the files with links", func() {
+			rec := get(server, http.MethodGet, "/", nil)
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(
+				rec.Body.String(),
+				ShouldContainSubstring,
+				`<a href="/files/images/logo.png">images/logo.png</a>`,
+			)
+			So(rec.Body.String(), ShouldContainSubstring, "2025-03-05T10:30:00Z")
+
+			rec = get(server, http.MethodGet, "/?prefix=images/", nil)
+			So(rec.Body.String(), ShouldNotContainSubstring, "notes.txt")
+		})
+
+		Convey("missing files should not be found", func() {
+			So(get(server, http.MethodGet, "/files/missing", nil).Code, ShouldEqual, http.StatusNotFound)
+			So(get(server, http.MethodGet, "/other", nil).Code, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("only reads should be allowed", func() {
+			rec := get(server, http.MethodDelete, "/files/notes.txt", nil)
+			So(rec.Code, ShouldEqual, http.StatusMethodNotAllowed)
+			So(rec.Header().Get("Allow"), ShouldEqual, "GET, HEAD")
+			So(get(server, http.MethodHead, "/files/notes.txt", nil).Code, ShouldEqual, http.StatusOK)
+		})
+	})
+
+	Convey("With a server with a token", t, func() {
+		server := &gridFSServer{store: store, token: "s3cret"}
+
+		Convey("requests without the token should be rejected", func() {
+			rec := get(server, http.MethodGet, "/files/notes.txt", nil)
+			So(rec.Code, ShouldEqual, http.StatusUnauthorized)
+			So(rec.Header().Get("WWW-Authenticate"), ShouldStartWith, "Basic")
+
+			rec = get(server, http.MethodGet, "/files/notes.txt",
+				http.Header{"Authorization": {"Bearer wrong"}})
+			So(rec.Code, ShouldEqual, http.StatusUnauthorized)
+		})
+
+		Convey("requests with the token should be served", func() {
+			rec := get(server, http.MethodGet, "/files/notes.txt",
+				http.Header{"Authorization": {"Bearer s3cret"}})
+			So(rec.Code, ShouldEqual, http.StatusOK)
+
+			req := httptest.NewRequest(http.MethodGet, "/files/notes.txt", nil)
+			req.SetBasicAuth("anyone", "s3cret")
+			rec = httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(bytes.Equal(rec.Body.Bytes(), []byte("hello world")), ShouldBeTrue)
+		})
+	})
+
+	Convey("Serving on an address that is not a loopback address should require a token", t, func() {
+		So(checkServeAddress("localhost:8080", ""), ShouldBeNil)
+		So(checkServeAddress("127.0.0.1:8080", ""), ShouldBeNil)
+		So(checkServeAddress("[::1]:8080", ""), ShouldBeNil)
+		So(checkServeAddress("0.0.0.0:8080", ""), ShouldNotBeNil)
+		So(checkServeAddress(":8080", ""), ShouldNotBeNil)
+		So(checkServeAddress("files.example.net:8080", ""), ShouldNotBeNil)
+		So(checkServeAddress(":8080", "s3cret"), ShouldBeNil)
+	})
+}
@@ -0,0 +1,186 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongofiles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	driverOptions "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// tailBatchSize is the most chunks tail reads at a time.
+const tailBatchSize = 100
+
+// tailChunk is a document of the chunks collection, as tail reads it.
+type tailChunk struct {
+	N    int64  `bson:"n"`
+	Data []byte `bson:"data"`
+}
+
+// gridFSTailer follows a GridFS file that writers append to, either by adding
+// chunks or by growing its last chunk, in the way of 'tail -f'.
+type gridFSTailer struct {
+	chunks    *mongo.Collection
+	fileID    interface{}
+	chunkSize int
+
+	// next is the chunk to read next, of which the first offset bytes were
+	// already written.
+	next   int64
+	offset int
+}
+
+// seekToEnd makes the tailer skip the bytes the file has now.
+func (tailer *gridFSTailer) seekToEnd(ctx context.Context) error {
+	var last tailChunk
+	err := tailer.chunks.FindOne(
+		ctx,
+		bson.D{{Key: "files_id", Value: tailer.fileID}},
+		driverOptions.FindOne().SetSort(bson.D{{Key: "n", Value: -1}}),
+	).Decode(&last)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error finding the last chunk: %v", err)
+	}
+	tailer.next = last.N
+	tailer.offset = len(last.Data)
+	if tailer.offset >= tailer.chunkSize {
+		tailer.next++
+		tailer.offset = 0
+	}
+	return nil
+}
+
+// poll writes the bytes appended to the file since the last poll to out, and
+// returns how many there were.
+func (tailer *gridFSTailer) poll(ctx context.Context, out io.Writer) (int64, error) {
+	// the chunk being read may have grown, so it is read again
+	cursor, err := tailer.chunks.Find(
+		ctx,
+		bson.D{
+			{Key: "files_id", Value: tailer.fileID},
+			{Key: "n", Value: bson.D{{Key: "$gte", Value: tailer.next}}},
+		},
+		driverOptions.Find().SetSort(bson.D{{Key: "n", Value: 1}}).SetLimit(tailBatchSize),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error reading chunks: %v", err)
+	}
+	var chunks []tailChunk
+	if err := cursor.All(ctx, &chunks); err != nil {
+		return 0, fmt.Errorf("error reading chunks: %v", err)
+	}
+	return tailer.consume(chunks, out)
+}
+
+// consume writes the bytes of chunks, sorted by n, that were not written yet.
+// A short chunk is the last one so far; it is read again until it is full or
+// a later chunk shows up. It stops at a missing chunk, which a writer has yet
+// to insert.
+func (tailer *gridFSTailer) consume(chunks []tailChunk, out io.Writer) (int64, error) {
+	var written int64
+	partial := false
+	for _, chunk := range chunks {
+		if partial && chunk.N == tailer.next+1 {
+			tailer.next++
+			tailer.offset = 0
+		}
+		if chunk.N != tailer.next {
+			break
+		}
+		if len(chunk.Data) > tailer.offset {
+			n, err := out.Write(chunk.Data[tailer.offset:])
+			written += int64(n)
+			if err != nil {
+				return written, fmt.Errorf("error writing file data: %v", err)
+			}
+		}
+		if len(chunk.Data) >= tailer.chunkSize {
+			tailer.next++
+			tailer.offset = 0
+			partial = false
+		} else {
+			tailer.offset = len(chunk.Data)
+			partial = true
+		}
+	}
+	return written, nil
+}
+
+// handleTail follows the most recent file named mf.FileName, writing what is
+// appended to it to --local or stdout, until it is interrupted or the file
+// stops growing for --tailIdleTimeout.
+func (mf *MongoFiles) handleTail() (err error) {
+	ctx := context.Background()
+	var file gfsFile
+	err = mf.bucket.GetFilesCollection().FindOne(
+		ctx,
+		bson.D{{Key: "filename", Value: mf.FileName}},
+		driverOptions.FindOne().SetSort(bson.D{{Key: "uploadDate", Value: -1}}),
+	).Decode(&file)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("no such file with name: %v", mf.FileName)
+	}
+	if err != nil {
+		return fmt.Errorf("error finding file '%v': %v", mf.FileName, err)
+	}
+	if file.ChunkSize <= 0 {
+		return fmt.Errorf("file '%v' has an invalid chunkSize of %v", mf.FileName, file.ChunkSize)
+	}
+
+	out := io.Writer(os.Stdout)
+	localFileName := mf.StorageOptions.LocalFileName
+	if localFileName != "" && localFileName != "-" {
+		localFile, err := os.Create(localFileName)
+		if err != nil {
+			return fmt.Errorf("error while opening local file '%v': %v", localFileName, err)
+		}
+		dc := util.DeferredCloser{Closer: localFile}
+		defer dc.CloseWithErrorCapture(&err)
+		out = localFile
+	}
+
+	tailer := &gridFSTailer{
+		chunks:    mf.bucket.GetChunksCollection(),
+		fileID:    file.ID,
+		chunkSize: file.ChunkSize,
+	}
+	if !mf.StorageOptions.TailFromStart {
+		if err := tailer.seekToEnd(ctx); err != nil {
+			return err
+		}
+	}
+
+	interval := time.Duration(mf.StorageOptions.TailIntervalMS) * time.Millisecond
+	idleTimeout := time.Duration(mf.StorageOptions.TailIdleTimeout) * time.Second
+	log.Logvf(log.Always, "following '%v' (_id %v)", mf.FileName, file.ID)
+	lastGrowth := time.Now()
+	for {
+		n, err := tailer.poll(ctx, out)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			lastGrowth = time.Now()
+		} else if idleTimeout > 0 && time.Since(lastGrowth) >= idleTimeout {
+			log.Logvf(log.Always, "'%v' did not grow for %v; stopping", mf.FileName, idleTimeout)
+			return nil
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongofiles
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTailConsume(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With a tailer over a file with 4-byte chunks", t, func() {
+		tailer := &gridFSTailer{chunkSize: 4}
+		out := &bytes.Buffer{}
+
+		Convey("full chunks are written once and skipped after", func() {
+			n, err := tailer.consume([]tailChunk{{0, []byte("abcd")}, {1, []byte("efgh")}}, out)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 8)
+			So(tailer.next, ShouldEqual, 2)
+			So(tailer.offset, ShouldEqual, 0)
+			So(out.String(), ShouldEqual, "abcdefgh")
+		})
+
+		Convey("a growing last chunk is written as it grows", func() {
+			_, err := tailer.consume([]tailChunk{{0, []byte("ab")}}, out)
+			So(err, ShouldBeNil)
+			So(tailer.next, ShouldEqual, 0)
+			So(tailer.offset, ShouldEqual, 2)
+
+			n, err := tailer.consume([]tailChunk{{0, []byte("ab")}}, out)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 0)
+
+			n, err = tailer.consume([]tailChunk{{0, []byte("abcd")}, {1, []byte("e")}}, out)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 3)
+			So(tailer.next, ShouldEqual, 1)
+			So(tailer.offset, ShouldEqual, 1)
+			So(out.String(), ShouldEqual, "abcde")
+		})
+
+		Convey("a short chunk followed by a new one is left behind", func() {
+			_, err := tailer.consume([]tailChunk{{0, []byte("ab")}}, out)
+			So(err, ShouldBeNil)
+
+			n, err := tailer.consume([]tailChunk{{0, []byte("ab")}, {1, []byte("cd")}}, out)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 2)
+			So(tailer.next, ShouldEqual, 1)
+			So(tailer.offset, ShouldEqual, 2)
+			So(out.String(), ShouldEqual, "abcd")
+		})
+
+		Convey("reading stops at a missing chunk", func() {
+			n, err := tailer.consume([]tailChunk{{0, []byte("abcd")}, {2, []byte("ijkl")}}, out)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 4)
+			So(tailer.next, ShouldEqual, 1)
+			So(out.String(), ShouldEqual, "abcd")
+
+			n, err = tailer.consume([]tailChunk{{1, []byte("efgh")}, {2, []byte("ijkl")}}, out)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 8)
+			So(out.String(), ShouldEqual, "abcdefghijkl")
+		})
+	})
+}
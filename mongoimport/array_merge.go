@@ -0,0 +1,208 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Strategies of --arrayMerge.
+const (
+	arrayReplace = "replace"
+	arrayConcat  = "concat"
+	arrayUnion   = "union"
+)
+
+// arrayMerge is how --mode=merge merges the array at path with the array of
+// the existing document. With arrayUnion, elements are the same if they are
+// equal or, if key is set, if their key fields are equal.
+type arrayMerge struct {
+	path     []string
+	strategy string
+	key      string
+}
+
+// parseArrayMerge parses a value of --arrayMerge, such as tags=union or
+// items=union:sku.
+func parseArrayMerge(spec string) (arrayMerge, error) {
+	field, strategy, ok := strings.Cut(spec, "=")
+	if !ok {
+		return arrayMerge{}, fmt.Errorf("expected <field>=<strategy>, got %q", spec)
+	}
+	if err := validateFields([]string{field}, false); err != nil {
+		return arrayMerge{}, err
+	}
+	if field == "" {
+		return arrayMerge{}, fmt.Errorf("missing field in %q", spec)
+	}
+	merge := arrayMerge{path: strings.Split(field, ".")}
+	merge.strategy, merge.key, _ = strings.Cut(strategy, ":")
+	switch merge.strategy {
+	case arrayReplace, arrayConcat:
+		if merge.key != "" {
+			return arrayMerge{}, fmt.Errorf("only %v takes a key field, in %q", arrayUnion, spec)
+		}
+	case arrayUnion:
+		if strings.HasPrefix(merge.key, "$") {
+			return arrayMerge{}, fmt.Errorf("key field '%v' cannot start with a '$'", merge.key)
+		}
+	default:
+		return arrayMerge{}, fmt.Errorf(
+			"invalid strategy %q, expected %v, %v or %v[:<key field>]",
+			merge.strategy,
+			arrayReplace,
+			arrayConcat,
+			arrayUnion,
+		)
+	}
+	return merge, nil
+}
+
+// validateArrayMerge parses --arrayMerge.
+func (imp *MongoImport) validateArrayMerge() error {
+	specs := imp.IngestOptions.ArrayMerge
+	if len(specs) == 0 {
+		return nil
+	}
+	if imp.IngestOptions.Mode != modeMerge {
+		return fmt.Errorf("cannot use --arrayMerge with --mode=%v", imp.IngestOptions.Mode)
+	}
+	seen := map[string]bool{}
+	for _, spec := range specs {
+		merge, err := parseArrayMerge(spec)
+		if err != nil {
+			return fmt.Errorf("invalid --arrayMerge argument: %v", err)
+		}
+		field := strings.Join(merge.path, ".")
+		for other := range seen {
+			if other == field || strings.HasPrefix(other, field+".") ||
+				strings.HasPrefix(field, other+".") {
+				return fmt.Errorf("invalid --arrayMerge argument: %v overlaps %v", field, other)
+			}
+		}
+		seen[field] = true
+		imp.arrayMerges = append(imp.arrayMerges, merge)
+	}
+	return nil
+}
+
+// mergeUpdate returns the update that --mode=merge applies to the existing
+// document for document: a $set of its fields or, if it has an array that
+// --arrayMerge merges with the existing one, an update pipeline. In the
+// pipeline, the documents that contain such an array are merged field by
+// field, so that the existing array can be read, and every other value is
+// set as it is. Pipeline updates need MongoDB 4.2 or later.
+func mergeUpdate(document bson.D, merges []arrayMerge) (bson.D, mongo.Pipeline) {
+	set := bson.D{}
+	merged := false
+	for _, elem := range document {
+		if mergeFields(&set, []string{elem.Key}, elem.Value, merges) {
+			merged = true
+		}
+	}
+	if !merged {
+		return bson.D{{"$set", document}}, nil
+	}
+	return nil, mongo.Pipeline{{{"$set", set}}}
+}
+
+// mergeFields adds the pipeline expressions that set path to value to set,
+// and reports whether any of them merges an array.
+func mergeFields(set *bson.D, path []string, value interface{}, merges []arrayMerge) bool {
+	field := strings.Join(path, ".")
+	if arr, ok := value.(bson.A); ok {
+		for _, merge := range merges {
+			if strings.Join(merge.path, ".") == field {
+				*set = append(*set, bson.E{field, merge.expression(field, arr)})
+				return merge.strategy != arrayReplace
+			}
+		}
+	}
+	if doc, ok := value.(bson.D); ok && len(doc) > 0 && containsMergedPath(field, merges) {
+		merged := false
+		for _, elem := range doc {
+			subpath := append(append([]string(nil), path...), elem.Key)
+			if mergeFields(set, subpath, elem.Value, merges) {
+				merged = true
+			}
+		}
+		return merged
+	}
+	*set = append(*set, bson.E{field, bson.D{{"$literal", value}}})
+	return false
+}
+
+func containsMergedPath(field string, merges []arrayMerge) bool {
+	for _, merge := range merges {
+		if strings.HasPrefix(strings.Join(merge.path, "."), field+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// expression returns the pipeline expression that merges arr into the
+// array at field of the existing document. A missing or non-array value
+// counts as an empty array.
+func (merge arrayMerge) expression(field string, arr bson.A) interface{} {
+	values := bson.D{{"$literal", arr}}
+	existing := bson.D{{"$cond", bson.A{
+		bson.D{{"$isArray", "$" + field}}, "$" + field, bson.A{},
+	}}}
+	switch {
+	case merge.strategy == arrayConcat:
+		return bson.D{{"$concatArrays", bson.A{existing, values}}}
+	case merge.strategy == arrayUnion && merge.key == "":
+		// the elements that are not in the existing array yet are appended
+		return bson.D{{"$let", bson.D{
+			{"vars", bson.D{{"old", existing}}},
+			{"in", bson.D{{"$concatArrays", bson.A{
+				"$$old",
+				bson.D{{"$filter", bson.D{
+					{"input", values},
+					{"cond", bson.D{{"$not", bson.A{bson.D{{"$in", bson.A{"$$this", "$$old"}}}}}}},
+				}}},
+			}}}},
+		}}}
+	case merge.strategy == arrayUnion:
+		// the existing elements with the key of a new element are replaced by
+		// it in place, and the other new elements are appended
+		key := "$$this." + merge.key
+		return bson.D{{"$let", bson.D{
+			{"vars", bson.D{
+				{"old", existing},
+				{"new", values},
+				{"newKeys", bson.D{{"$map", bson.D{{"input", values}, {"in", key}}}}},
+			}},
+			{"in", bson.D{{"$concatArrays", bson.A{
+				bson.D{{"$map", bson.D{
+					{"input", "$$old"},
+					{"in", bson.D{{"$let", bson.D{
+						{"vars", bson.D{{"i", bson.D{{"$indexOfArray", bson.A{"$$newKeys", key}}}}}},
+						{"in", bson.D{{"$cond", bson.A{
+							bson.D{{"$eq", bson.A{"$$i", -1}}},
+							"$$this",
+							bson.D{{"$arrayElemAt", bson.A{"$$new", "$$i"}}},
+						}}}},
+					}}}},
+				}}},
+				bson.D{{"$filter", bson.D{
+					{"input", "$$new"},
+					{"cond", bson.D{{"$not", bson.A{bson.D{{"$in", bson.A{
+						key,
+						bson.D{{"$map", bson.D{{"input", "$$old"}, {"in", key}}}},
+					}}}}}}},
+				}}},
+			}}}},
+		}}}
+	}
+	return values
+}
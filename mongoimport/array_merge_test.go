@@ -0,0 +1,102 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestArrayMerge(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With --arrayMerge", t, func() {
+		imp := NewMockMongoImport()
+		imp.IngestOptions.Mode = modeMerge
+		imp.IngestOptions.ArrayMerge = []string{"tags=union", "order.items=union:sku", "log=concat"}
+
+		Convey("the strategies are parsed", func() {
+			So(imp.validateSettings(), ShouldBeNil)
+			So(imp.arrayMerges, ShouldResemble, []arrayMerge{
+				{path: []string{"tags"}, strategy: arrayUnion},
+				{path: []string{"order", "items"}, strategy: arrayUnion, key: "sku"},
+				{path: []string{"log"}, strategy: arrayConcat},
+			})
+		})
+
+		Convey("only merge mode is supported", func() {
+			imp.IngestOptions.Mode = modeUpsert
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("invalid arguments are rejected", func() {
+			for _, spec := range []string{
+				"tags",
+				"=union",
+				"tags=merge",
+				"tags=concat:sku",
+				"tags=union:$sku",
+				"$tags=union",
+			} {
+				imp.IngestOptions.ArrayMerge = []string{spec}
+				So(imp.validateSettings(), ShouldNotBeNil)
+			}
+		})
+
+		Convey("overlapping fields are rejected", func() {
+			imp.IngestOptions.ArrayMerge = []string{"order=concat", "order.items=union"}
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+	})
+
+	Convey("The merge update", t, func() {
+		merges := []arrayMerge{
+			{path: []string{"log"}, strategy: arrayConcat},
+			{path: []string{"order", "items"}, strategy: arrayUnion, key: "sku"},
+			{path: []string{"names"}, strategy: arrayReplace},
+		}
+
+		Convey("is a $set without arrays to merge", func() {
+			document := bson.D{{"_id", 1}, {"names", bson.A{"a"}}, {"log", "not an array"}}
+			update, pipeline := mergeUpdate(document, merges)
+			So(pipeline, ShouldBeNil)
+			So(update, ShouldResemble, bson.D{{"$set", document}})
+		})
+
+		Convey("merges the arrays in a pipeline", func() {
+			document := bson.D{
+				{"_id", 1},
+				{"log", bson.A{"x"}},
+				{"order", bson.D{{"id", 7}, {"items", bson.A{bson.D{{"sku", "s1"}}}}}},
+				{"note", "$not a field path"},
+			}
+			update, pipeline := mergeUpdate(document, merges)
+			So(update, ShouldBeNil)
+			So(pipeline, ShouldHaveLength, 1)
+
+			set := pipeline[0][0].Value.(bson.D)
+			var keys []string
+			for _, elem := range set {
+				keys = append(keys, elem.Key)
+			}
+			So(keys, ShouldResemble, []string{"_id", "log", "order.id", "order.items", "note"})
+			So(set[0].Value, ShouldResemble, bson.D{{"$literal", 1}})
+			So(set[4].Value, ShouldResemble, bson.D{{"$literal", "$not a field path"}})
+
+			existing := bson.D{{"$cond", bson.A{
+				bson.D{{"$isArray", "$log"}}, "$log", bson.A{},
+			}}}
+			So(set[1].Value, ShouldResemble, bson.D{{"$concatArrays", bson.A{
+				existing,
+				bson.D{{"$literal", bson.A{"x"}}},
+			}}})
+		})
+	})
+}
@@ -55,6 +55,44 @@ func ParsePG(pg string) (res ParseGrace) {
 	return
 }
 
+// ColumnsMismatchPolicy controls how CSV and TSV rows whose field count differs
+// from the number of columns are handled.
+type ColumnsMismatchPolicy int
+
+const (
+	// cmDefault imports extra fields as "field<N>" and omits missing ones.
+	cmDefault ColumnsMismatchPolicy = iota
+	cmError
+	cmPadNull
+	cmTruncate
+	cmSkipRow
+)
+
+// ValidateCM ensures the user-provided columnsMismatchPolicy is one of the
+// allowed values.
+func ValidateCM(cm string) (ColumnsMismatchPolicy, error) {
+	switch cm {
+	case "":
+		return cmDefault, nil
+	case "error":
+		return cmError, nil
+	case "padNull":
+		return cmPadNull, nil
+	case "truncate":
+		return cmTruncate, nil
+	case "skipRow":
+		return cmSkipRow, nil
+	default:
+		return cmDefault, fmt.Errorf("invalid columns mismatch policy: %s", cm)
+	}
+}
+
+// ParseCM interprets the user-provided columnsMismatchPolicy, assuming it is valid.
+func ParseCM(cm string) (res ColumnsMismatchPolicy) {
+	res, _ = ValidateCM(cm)
+	return
+}
+
 // Converter is an interface that adds the basic Convert method which returns a
 // valid BSON document that has been converted by the underlying implementation.
 // If conversion fails, err will be set.
@@ -73,6 +111,9 @@ type importWorker struct {
 
 	// used to synchronize all worker goroutines
 	tomb *tomb.Tomb
+
+	// counts the records converted, for --progressStages
+	stages *importStages
 }
 
 // an interface for tracking the number of bytes, which is used in mongoimport to feed
@@ -106,6 +147,25 @@ func newSizeTrackingReader(reader io.Reader) *sizeTrackingReader {
 	}
 }
 
+// rejectWriter implements io.Writer by wrapping the writer of the rows that
+// are skipped, which the converters of a reader share while they run
+// concurrently. Each Write is a whole row, and is written out before the next
+// one starts.
+type rejectWriter struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func (rw *rejectWriter) Write(p []byte) (int, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return rw.writer.Write(p)
+}
+
+func newRejectWriter(writer io.Writer) *rejectWriter {
+	return &rejectWriter{writer: writer}
+}
+
 var (
 	UTF8_BOM = []byte{0xEF, 0xBB, 0xBF}
 )
@@ -298,6 +358,9 @@ func setNestedDocumentValue(
 	useArrayIndexFields bool,
 ) (err error) {
 	if len(fieldParts) == 1 {
+		if name, ok := repeatedFieldName(fieldParts[0]); useArrayIndexFields && ok {
+			return appendRepeatedFieldValue(name, value, document)
+		}
 		*document = append(*document, bson.E{Key: fieldParts[0], Value: value})
 		return nil
 	}
@@ -371,7 +434,10 @@ func setNestedArrayValue(fieldParts []string, value interface{}, array *bson.A)
 	}
 
 	if len(fieldParts) == 1 {
-		if idx != len(*array) {
+		// the fields were validated to be sequential, so an index past the end
+		// of the array means that blank or unparseable cells before it were
+		// skipped
+		if idx < len(*array) {
 			return fmt.Errorf(
 				"Trying to add value to array at index %d, but array is %d elements long. "+
 					"Array indices in fields must start from 0 and increase sequentially",
@@ -380,6 +446,7 @@ func setNestedArrayValue(fieldParts []string, value interface{}, array *bson.A)
 			)
 		}
 
+		padArray(array, idx)
 		*array = append(*array, value)
 		return nil
 	}
@@ -405,6 +472,7 @@ func setNestedArrayValue(fieldParts []string, value interface{}, array *bson.A)
 			if err != nil {
 				return err
 			}
+			padArray(array, idx)
 			*array = append(*array, subArray)
 		}
 	} else {
@@ -428,12 +496,103 @@ func setNestedArrayValue(fieldParts []string, value interface{}, array *bson.A)
 			if err != nil {
 				return err
 			}
+			padArray(array, idx)
 			*array = append(*array, subDocument)
 		}
 	}
 	return nil
 }
 
+// padArray fills the elements of array before idx, which skipped cells left
+// unset, with null, so that the element at idx keeps its index.
+func padArray(array *bson.A, idx int) {
+	for len(*array) < idx {
+		*array = append(*array, nil)
+	}
+}
+
+// padSkippedElement sets the array element of fieldParts, a column whose cell
+// was skipped, to null if it is past the end of its array. The cells before
+// other elements were already filled by padArray, so this makes skipped cells
+// null at the end of arrays as well.
+func padSkippedElement(fieldParts []string, document *bson.D) {
+	var container interface{} = document
+	for _, part := range fieldParts {
+		switch c := container.(type) {
+		case *bson.D:
+			value, err := bsonutil.FindValueByKey(part, c)
+			if err != nil {
+				// no other cell set a value on the path
+				return
+			}
+			container = value
+		case *bson.A:
+			idx, ok := isNatNum(part)
+			if !ok {
+				return
+			}
+			if idx >= len(*c) {
+				padArray(c, idx+1)
+				return
+			}
+			container = (*c)[idx]
+		default:
+			return
+		}
+	}
+}
+
+// isIndexedFieldColumn returns true if --useArrayIndexFields is set and the
+// column's name has an array index, like a.0 or a.0.b.
+func isIndexedFieldColumn(colSpec ColumnSpec, useArrayIndexFields bool) bool {
+	if !useArrayIndexFields {
+		return false
+	}
+	for _, part := range colSpec.NameParts[1:] {
+		if _, ok := isNatNum(part); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// arrayFieldSuffix marks a field whose values are appended to an array, so
+// that repeated columns like tags[],tags[],tags[] collapse into one array.
+const arrayFieldSuffix = "[]"
+
+// repeatedFieldName returns the array field name for a field part like
+// "tags[]", and whether the part had the array suffix.
+func repeatedFieldName(fieldPart string) (string, bool) {
+	if !strings.HasSuffix(fieldPart, arrayFieldSuffix) {
+		return fieldPart, false
+	}
+	return strings.TrimSuffix(fieldPart, arrayFieldSuffix), true
+}
+
+// isRepeatedFieldColumn returns true if the column is named like tags[] and
+// --useArrayIndexFields is set, so the column's values are array elements.
+func isRepeatedFieldColumn(colSpec ColumnSpec, useArrayIndexFields bool) bool {
+	_, ok := repeatedFieldName(colSpec.Name)
+	return useArrayIndexFields && ok
+}
+
+// appendRepeatedFieldValue appends value to the array at key name in the
+// document, creating the array if it doesn't exist yet.
+func appendRepeatedFieldValue(name string, value interface{}, document *bson.D) error {
+	elem, err := bsonutil.FindValueByKey(name, document)
+	if err != nil {
+		*document = append(*document, bson.E{Key: name, Value: &bson.A{value}})
+		return nil
+	}
+	array, ok := elem.(*bson.A)
+	if !ok {
+		return fmt.Errorf("Expected document element to be an array, "+
+			"but element has already been set as a document or other value: %#v", elem)
+	}
+	*array = append(*array, value)
+	return nil
+}
+
 // isNatNum returns a number and true if the string can be parsed as a natural number (including 0)
 // The first byte of the string must be a number from 1-9. So "001" would not be parsed.
 // Neither would phone numbers such as "+15558675309".
@@ -452,12 +611,14 @@ func isNatNum(s string) (int, bool) {
 // streamDocuments concurrently processes data gotten from the inputChan
 // channel in parallel and then sends over the processed data to the outputChan
 // channel - either in sequence or concurrently (depending on the value of
-// ordered) - in which the data was received.
+// ordered) - in which the data was received. stages, if not nil, counts the
+// records converted.
 func streamDocuments(
 	ordered bool,
 	numDecoders int,
 	readDocs chan Converter,
 	outputChan chan bson.D,
+	stages *importStages,
 ) (retErr error) {
 	if numDecoders == 0 {
 		numDecoders = 1
@@ -465,6 +626,7 @@ func streamDocuments(
 	var importWorkers []*importWorker
 	wg := new(sync.WaitGroup)
 	importTomb := new(tomb.Tomb)
+	stages.watchDecodeQueue(readDocs)
 	inChan := readDocs
 	outChan := outputChan
 	for i := 0; i < numDecoders; i++ {
@@ -476,6 +638,7 @@ func streamDocuments(
 			unprocessedDataChan:   inChan,
 			processedDocumentChan: outChan,
 			tomb:                  importTomb,
+			stages:                stages,
 		}
 		importWorkers = append(importWorkers, iw)
 		wg.Add(1)
@@ -501,11 +664,21 @@ func streamDocuments(
 	return
 }
 
-// coercionError should only be used as a specific error type to check
-// whether tokensToBSON wants the row to print.
-type coercionError struct{}
+// SkippedRowError is returned by tokensToBSON for a row that should be skipped
+// and written to the rejects output. It is also passed to the
+// DocumentErrorHandler of the MongoImport, if any.
+type SkippedRowError struct {
+	// Row is the index of the row in the input, not counting the header line.
+	Row uint64
+	// Fields holds the raw fields of the row.
+	Fields []string
+	// Reason describes why the row was skipped.
+	Reason string
+}
 
-func (coercionError) Error() string { return "coercionError" }
+func (e SkippedRowError) Error() string {
+	return fmt.Sprintf("skipped row #%d: %v", e.Row, e.Reason)
+}
 
 // tokensToBSON reads in slice of records - along with ordered column names -
 // and returns a BSON document for the record.
@@ -515,12 +688,49 @@ func tokensToBSON(
 	numProcessed uint64,
 	ignoreBlanks bool,
 	useArrayIndexFields bool,
+	columnsMismatchPolicy ColumnsMismatchPolicy,
 ) (bson.D, error) {
 	log.Logvf(log.DebugHigh, "got line: %v", tokens)
+	padNulls := false
+	if len(tokens) != len(colSpecs) {
+		switch columnsMismatchPolicy {
+		case cmError:
+			return nil, fmt.Errorf(
+				"field count mismatch in document #%d: expected %d fields, found %d",
+				numProcessed,
+				len(colSpecs),
+				len(tokens),
+			)
+		case cmSkipRow:
+			log.Logvf(log.Always, "skipping row #%d with %d fields, expected %d: %v",
+				numProcessed, len(tokens), len(colSpecs), tokens)
+			return nil, SkippedRowError{
+				Row:    numProcessed,
+				Fields: tokens,
+				Reason: fmt.Sprintf("expected %d fields, found %d", len(colSpecs), len(tokens)),
+			}
+		case cmTruncate:
+			if len(tokens) > len(colSpecs) {
+				tokens = tokens[:len(colSpecs)]
+			}
+		case cmPadNull:
+			padNulls = len(tokens) < len(colSpecs)
+		}
+	}
 	var parsedValue interface{}
 	document := bson.D{}
+	// skipped are the indexed columns whose cells were skipped
+	var skipped []ColumnSpec
 	for index, token := range tokens {
 		if token == "" && ignoreBlanks {
+			if index < len(colSpecs) && isIndexedFieldColumn(colSpecs[index], useArrayIndexFields) {
+				skipped = append(skipped, colSpecs[index])
+			}
+			continue
+		}
+		if token == "" && index < len(colSpecs) &&
+			isRepeatedFieldColumn(colSpecs[index], useArrayIndexFields) {
+			// empty cells in repeated columns are never array elements
 			continue
 		}
 		if index < len(colSpecs) {
@@ -533,10 +743,22 @@ func tokensToBSON(
 				case pgAutoCast:
 					parsedValue = autoParse(token)
 				case pgSkipField:
+					if isIndexedFieldColumn(colSpecs[index], useArrayIndexFields) {
+						skipped = append(skipped, colSpecs[index])
+					}
 					continue
 				case pgSkipRow:
 					log.Logvf(log.Always, "skipping row #%d: %v", numProcessed, tokens)
-					return nil, coercionError{}
+					return nil, SkippedRowError{
+						Row:    numProcessed,
+						Fields: tokens,
+						Reason: fmt.Sprintf(
+							"could not parse token '%s' to type %s for column '%s'",
+							token,
+							colSpecs[index].TypeName,
+							colSpecs[index].Name,
+						),
+					}
 				case pgStop:
 					return nil, fmt.Errorf(
 						"type coercion failure in document #%d for column '%s', "+
@@ -548,7 +770,8 @@ func tokensToBSON(
 					)
 				}
 			}
-			if len(colSpecs[index].NameParts) > 1 {
+			if len(colSpecs[index].NameParts) > 1 ||
+				isRepeatedFieldColumn(colSpecs[index], useArrayIndexFields) {
 				err = setNestedDocumentValue(
 					colSpecs[index].NameParts,
 					parsedValue,
@@ -575,6 +798,24 @@ func tokensToBSON(
 			document = append(document, bson.E{Key: key, Value: parsedValue})
 		}
 	}
+	for _, colSpec := range skipped {
+		padSkippedElement(colSpec.NameParts, &document)
+	}
+	if padNulls {
+		for _, colSpec := range colSpecs[len(tokens):] {
+			if isRepeatedFieldColumn(colSpec, useArrayIndexFields) {
+				continue
+			}
+			if len(colSpec.NameParts) > 1 {
+				err := setNestedDocumentValue(colSpec.NameParts, nil, &document, useArrayIndexFields)
+				if err != nil {
+					return nil, fmt.Errorf("can't set value for key %s: %s", colSpec.Name, err)
+				}
+			} else {
+				document = append(document, bson.E{Key: colSpec.Name, Value: nil})
+			}
+		}
+	}
 	return document, nil
 }
 
@@ -644,6 +885,9 @@ func addFieldToTree(
 	useArrayIndexFields bool,
 ) (map[string]interface{}, error) {
 	head, tail := fieldParts[0], fieldParts[1:]
+	if name, ok := repeatedFieldName(head); useArrayIndexFields && ok {
+		return addRepeatedFieldToTree(name, tail, fullField, fieldPrefix, tree)
+	}
 	if fieldPrefix == "" {
 		fieldPrefix = head
 	} else {
@@ -708,6 +952,38 @@ func addFieldToTree(
 
 }
 
+// repeatedField is the value in a tree of fields for a field named like
+// tags[], which may appear any number of times.
+type repeatedField struct{}
+
+// addRepeatedFieldToTree adds a field whose last part is named like tags[] to
+// a tree of fields. Repeating the field is allowed, but using the same name
+// for any other kind of value is not.
+func addRepeatedFieldToTree(
+	name string,
+	tail []string,
+	fullField string,
+	fieldPrefix string,
+	tree map[string]interface{},
+) (map[string]interface{}, error) {
+	if len(tail) != 0 {
+		return nil, fmt.Errorf("field '%v' can only use '%v' in its last part", fullField, arrayFieldSuffix)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("field '%v' is missing a name before '%v'", fullField, arrayFieldSuffix)
+	}
+	if fieldPrefix != "" {
+		fieldPrefix += "."
+	}
+	if value, exists := tree[name]; exists {
+		if _, ok := value.(repeatedField); !ok {
+			return nil, incompatibleError(fullField, fieldPrefix+name, value)
+		}
+	}
+	tree[name] = repeatedField{}
+	return tree, nil
+}
+
 // addFieldToArray is used with addFieldToTree() to build a valid tree of fields.
 func addFieldToArray(
 	fieldParts []string,
@@ -811,6 +1087,8 @@ func findFirstField(i interface{}) string {
 	switch v := i.(type) {
 	case bool:
 		return ""
+	case repeatedField:
+		return arrayFieldSuffix
 	case []interface{}:
 		return ".0" + findFirstField(v[0])
 	case map[string]interface{}:
@@ -864,6 +1142,7 @@ func (iw *importWorker) processDocuments(ordered bool) error {
 			if !alive {
 				return nil
 			}
+			iw.stages.took()
 			document, err := converter.Convert()
 			if err != nil {
 				return err
@@ -871,6 +1150,7 @@ func (iw *importWorker) processDocuments(ordered bool) error {
 			if document == nil {
 				continue
 			}
+			iw.stages.produced()
 			iw.processedDocumentChan <- document
 		case <-iw.tomb.Dying():
 			return nil
@@ -131,6 +131,18 @@ func TestValidateFields(t *testing.T) {
 		Convey("if the fields contain the same keys, an error should be thrown", func() {
 			So(validateFields([]string{"a", "ba", "a"}, false), ShouldNotBeNil)
 		})
+		Convey("with array index fields, repeated fields ending in [] are allowed", func() {
+			So(validateFields([]string{"a[]", "a[]", "b.c[]", "b.c[]", "b.d"}, true), ShouldBeNil)
+			So(validateFields([]string{"a[]", "a[]"}, false), ShouldNotBeNil)
+		})
+		Convey("with array index fields, repeated fields ending in [] can't collide", func() {
+			So(validateFields([]string{"a[]", "a"}, true), ShouldNotBeNil)
+			So(validateFields([]string{"a", "a[]"}, true), ShouldNotBeNil)
+			So(validateFields([]string{"a[]", "a.0"}, true), ShouldNotBeNil)
+			So(validateFields([]string{"a[]", "a.b"}, true), ShouldNotBeNil)
+			So(validateFields([]string{"a[].b"}, true), ShouldNotBeNil)
+			So(validateFields([]string{"[]"}, true), ShouldNotBeNil)
+		})
 	})
 }
 
@@ -340,7 +352,7 @@ func TestTokensToBSON(t *testing.T) {
 				{"b", int32(2)},
 				{"c", "hello"},
 			}
-			bsonD, err := tokensToBSON(colSpecs, tokens, uint64(0), false, false)
+			bsonD, err := tokensToBSON(colSpecs, tokens, uint64(0), false, false, cmDefault)
 			So(err, ShouldBeNil)
 			So(bsonD, ShouldResemble, expectedDocument)
 		})
@@ -359,10 +371,49 @@ func TestTokensToBSON(t *testing.T) {
 				{"field3", "mongodb"},
 				{"field4", "user"},
 			}
-			bsonD, err := tokensToBSON(colSpecs, tokens, uint64(0), false, false)
+			bsonD, err := tokensToBSON(colSpecs, tokens, uint64(0), false, false, cmDefault)
 			So(err, ShouldBeNil)
 			So(bsonD, ShouldResemble, expectedDocument)
 		})
+		Convey("rows with a mismatched field count should follow the columns mismatch policy", func() {
+			colSpecs := []ColumnSpec{
+				{"a", new(FieldAutoParser), pgAutoCast, "auto", []string{"a"}},
+				{"b", new(FieldAutoParser), pgAutoCast, "auto", []string{"b"}},
+				{"c", new(FieldAutoParser), pgAutoCast, "auto", []string{"c"}},
+			}
+			shortTokens := []string{"1", "2"}
+			longTokens := []string{"1", "2", "hello", "mongodb"}
+
+			Convey("error should fail on both short and long rows", func() {
+				_, err := tokensToBSON(colSpecs, shortTokens, uint64(0), false, false, cmError)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "expected 3 fields, found 2")
+				_, err = tokensToBSON(colSpecs, longTokens, uint64(0), false, false, cmError)
+				So(err, ShouldNotBeNil)
+			})
+			Convey("padNull should fill in missing fields with null", func() {
+				bsonD, err := tokensToBSON(colSpecs, shortTokens, uint64(0), false, false, cmPadNull)
+				So(err, ShouldBeNil)
+				So(bsonD, ShouldResemble, bson.D{{"a", int32(1)}, {"b", int32(2)}, {"c", nil}})
+			})
+			Convey("truncate should drop extra fields", func() {
+				bsonD, err := tokensToBSON(colSpecs, longTokens, uint64(0), false, false, cmTruncate)
+				So(err, ShouldBeNil)
+				So(bsonD, ShouldResemble, bson.D{{"a", int32(1)}, {"b", int32(2)}, {"c", "hello"}})
+			})
+			Convey("skipRow should reject the row", func() {
+				_, err := tokensToBSON(colSpecs, longTokens, uint64(0), false, false, cmSkipRow)
+				So(err, ShouldHaveSameTypeAs, SkippedRowError{})
+			})
+			Convey("rows with the expected field count should be unaffected", func() {
+				tokens := []string{"1", "2", "hello"}
+				for _, policy := range []ColumnsMismatchPolicy{cmError, cmPadNull, cmTruncate, cmSkipRow} {
+					bsonD, err := tokensToBSON(colSpecs, tokens, uint64(0), false, false, policy)
+					So(err, ShouldBeNil)
+					So(len(bsonD), ShouldEqual, 3)
+				}
+			})
+		})
 		Convey("an error should be thrown if duplicate headers are found", func() {
 			colSpecs := []ColumnSpec{
 				{"a", new(FieldAutoParser), pgAutoCast, "auto", []string{"a"}},
@@ -370,7 +421,7 @@ func TestTokensToBSON(t *testing.T) {
 				{"field3", new(FieldAutoParser), pgAutoCast, "auto", []string{"field3"}},
 			}
 			tokens := []string{"1", "2", "hello", "mongodb", "user"}
-			_, err := tokensToBSON(colSpecs, tokens, uint64(0), false, false)
+			_, err := tokensToBSON(colSpecs, tokens, uint64(0), false, false, cmDefault)
 			So(err, ShouldNotBeNil)
 		})
 		Convey("fields with nested values should be set appropriately", func() {
@@ -388,7 +439,7 @@ func TestTokensToBSON(t *testing.T) {
 				{"b", int32(2)},
 				{"c", c},
 			}
-			bsonD, err := tokensToBSON(colSpecs, tokens, uint64(0), false, false)
+			bsonD, err := tokensToBSON(colSpecs, tokens, uint64(0), false, false, cmDefault)
 			So(err, ShouldBeNil)
 			So(expectedDocument[0].Key, ShouldResemble, bsonD[0].Key)
 			So(expectedDocument[0].Value, ShouldResemble, bsonD[0].Value)
@@ -401,6 +452,99 @@ func TestTokensToBSON(t *testing.T) {
 
 			So(expectedDocument[2].Value, ShouldResemble, *valueD)
 		})
+		Convey("with array index fields, repeated [] columns should be collapsed into an array"+
+			" without empty cells", func() {
+			colSpecs := []ColumnSpec{
+				{"a", new(FieldAutoParser), pgAutoCast, "auto", []string{"a"}},
+				{"tags[]", new(FieldAutoParser), pgAutoCast, "auto", []string{"tags[]"}},
+				{"tags[]", new(FieldAutoParser), pgAutoCast, "auto", []string{"tags[]"}},
+				{"tags[]", new(FieldAutoParser), pgAutoCast, "auto", []string{"tags[]"}},
+				{"b.c[]", new(FieldAutoParser), pgAutoCast, "auto", []string{"b", "c[]"}},
+				{"b.c[]", new(FieldAutoParser), pgAutoCast, "auto", []string{"b", "c[]"}},
+			}
+			tokens := []string{"1", "x", "", "y", "2"}
+			bsonD, err := tokensToBSON(colSpecs, tokens, uint64(0), false, true, cmPadNull)
+			So(err, ShouldBeNil)
+			So(bsonD, ShouldResemble, bson.D{
+				{"a", int32(1)},
+				{"tags", &bson.A{"x", "y"}},
+				{"b", &bson.D{{"c", &bson.A{int32(2)}}}},
+			})
+		})
+		Convey("with array index fields and ignoreBlanks, empty cells in indexed columns"+
+			" should be null so that the other elements keep their indexes", func() {
+			colSpecs := []ColumnSpec{
+				{"a.0", new(FieldAutoParser), pgAutoCast, "auto", []string{"a", "0"}},
+				{"a.1", new(FieldAutoParser), pgAutoCast, "auto", []string{"a", "1"}},
+				{"a.2", new(FieldAutoParser), pgAutoCast, "auto", []string{"a", "2"}},
+			}
+			tokens := []string{"", "x", "y"}
+			bsonD, err := tokensToBSON(colSpecs, tokens, uint64(0), true, true, cmDefault)
+			So(err, ShouldBeNil)
+			So(bsonD, ShouldResemble, bson.D{{"a", &bson.A{nil, "x", "y"}}})
+
+			tokens = []string{"x", "", "y"}
+			bsonD, err = tokensToBSON(colSpecs, tokens, uint64(0), true, true, cmDefault)
+			So(err, ShouldBeNil)
+			So(bsonD, ShouldResemble, bson.D{{"a", &bson.A{"x", nil, "y"}}})
+
+			tokens = []string{"x", "y", ""}
+			bsonD, err = tokensToBSON(colSpecs, tokens, uint64(0), true, true, cmDefault)
+			So(err, ShouldBeNil)
+			So(bsonD, ShouldResemble, bson.D{{"a", &bson.A{"x", "y", nil}}})
+
+			tokens = []string{"x", "", ""}
+			bsonD, err = tokensToBSON(colSpecs, tokens, uint64(0), true, true, cmDefault)
+			So(err, ShouldBeNil)
+			So(bsonD, ShouldResemble, bson.D{{"a", &bson.A{"x", nil, nil}}})
+
+			// no array is made if all of its cells are empty
+			tokens = []string{"", "", ""}
+			bsonD, err = tokensToBSON(colSpecs, tokens, uint64(0), true, true, cmDefault)
+			So(err, ShouldBeNil)
+			So(bsonD, ShouldResemble, bson.D{})
+		})
+		Convey("with array index fields and ignoreBlanks, trailing empty cells in indexed"+
+			" columns of sub-documents should be null", func() {
+			colSpecs := []ColumnSpec{
+				{"a.0.b", new(FieldAutoParser), pgAutoCast, "auto", []string{"a", "0", "b"}},
+				{"a.1.b", new(FieldAutoParser), pgAutoCast, "auto", []string{"a", "1", "b"}},
+				{"a.1.c.0", new(FieldAutoParser), pgAutoCast, "auto", []string{"a", "1", "c", "0"}},
+				{"a.1.c.1", new(FieldAutoParser), pgAutoCast, "auto", []string{"a", "1", "c", "1"}},
+			}
+			tokens := []string{"x", "", "y", ""}
+			bsonD, err := tokensToBSON(colSpecs, tokens, uint64(0), true, true, cmDefault)
+			So(err, ShouldBeNil)
+			So(bsonD, ShouldResemble, bson.D{{"a", &bson.A{
+				&bson.D{{"b", "x"}},
+				&bson.D{{"c", &bson.A{"y", nil}}},
+			}}})
+
+			tokens = []string{"x", "", "", ""}
+			bsonD, err = tokensToBSON(colSpecs, tokens, uint64(0), true, true, cmDefault)
+			So(err, ShouldBeNil)
+			So(bsonD, ShouldResemble, bson.D{{"a", &bson.A{&bson.D{{"b", "x"}}, nil}}})
+		})
+		Convey("with array index fields and ignoreBlanks, empty cells in indexed columns"+
+			" of documents should be null", func() {
+			colSpecs := []ColumnSpec{
+				{"a.0.b", new(FieldAutoParser), pgAutoCast, "auto", []string{"a", "0", "b"}},
+				{"a.1.b", new(FieldAutoParser), pgAutoCast, "auto", []string{"a", "1", "b"}},
+				{"a.2.0", new(FieldAutoParser), pgAutoCast, "auto", []string{"a", "2", "0"}},
+				{"a.3.0", new(FieldAutoParser), pgAutoCast, "auto", []string{"a", "3", "0"}},
+				{"a.4.b", new(FieldAutoParser), pgAutoCast, "auto", []string{"a", "4", "b"}},
+			}
+			tokens := []string{"", "x", "", "y", "z"}
+			bsonD, err := tokensToBSON(colSpecs, tokens, uint64(0), true, true, cmDefault)
+			So(err, ShouldBeNil)
+			So(bsonD, ShouldResemble, bson.D{{"a", &bson.A{
+				nil,
+				&bson.D{{"b", "x"}},
+				nil,
+				&bson.A{"y"},
+				&bson.D{{"b", "z"}},
+			}}})
+		})
 	})
 }
 
@@ -557,7 +701,7 @@ func TestStreamDocuments(t *testing.T) {
 					inputChannel <- csvConverter
 				}
 				close(inputChannel)
-				So(streamDocuments(true, 3, inputChannel, outputChannel), ShouldBeNil)
+				So(streamDocuments(true, 3, inputChannel, outputChannel, nil), ShouldBeNil)
 
 				// ensure documents are streamed out and processed in the correct manner
 				for _, expectedDocument := range expectedDocuments {
@@ -579,7 +723,7 @@ func TestStreamDocuments(t *testing.T) {
 			close(inputChannel)
 
 			// ensure that an error is returned on the error channel
-			So(streamDocuments(true, 3, inputChannel, outputChannel), ShouldNotBeNil)
+			So(streamDocuments(true, 3, inputChannel, outputChannel, nil), ShouldNotBeNil)
 		})
 	})
 }
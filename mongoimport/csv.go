@@ -7,9 +7,11 @@
 package mongoimport
 
 import (
+	"bytes"
 	gocsv "encoding/csv"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/mongodb/mongo-tools/mongoimport/csv"
 	"go.mongodb.org/mongo-driver/bson"
@@ -24,7 +26,7 @@ type CSVInputReader struct {
 	csvReader *csv.Reader
 
 	// csvRejectWriter is where coercion-failed rows are written, if applicable
-	csvRejectWriter *gocsv.Writer
+	csvRejectWriter io.Writer
 
 	// csvRecord stores each line of input we read from the underlying reader
 	csvRecord []string
@@ -43,16 +45,40 @@ type CSVInputReader struct {
 
 	// useArrayIndexFields is whether field names include array indexes
 	useArrayIndexFields bool
+
+	// columnsMismatchPolicy is how rows with an unexpected number of fields are handled
+	columnsMismatchPolicy ColumnsMismatchPolicy
+
+	// documentErrorHandler is called for each skipped row, if set
+	documentErrorHandler DocumentErrorHandler
+
+	// whitespace is how whitespace is cleaned out of every field
+	whitespace WhitespacePolicy
+
+	// headerPolicy is how invalid or duplicate names in the header are handled
+	headerPolicy HeaderPolicy
+
+	// timezone is the location of the dates without a time zone, if set
+	timezone *time.Location
+
+	// resume tracks the position of each record in the --resumeFile, if set
+	resume *resumeTracker
+
+	// stages counts the records of --progressStages, if set
+	stages *importStages
 }
 
 // CSVConverter implements the Converter interface for CSV input.
 type CSVConverter struct {
-	colSpecs            []ColumnSpec
-	data                []string
-	index               uint64
-	ignoreBlanks        bool
-	useArrayIndexFields bool
-	rejectWriter        *gocsv.Writer
+	colSpecs              []ColumnSpec
+	data                  []string
+	index                 uint64
+	ignoreBlanks          bool
+	useArrayIndexFields   bool
+	columnsMismatchPolicy ColumnsMismatchPolicy
+	rejectWriter          io.Writer
+	documentErrorHandler  DocumentErrorHandler
+	whitespace            WhitespacePolicy
 }
 
 // NewCSVInputReader returns a CSVInputReader configured to read data from the
@@ -65,6 +91,7 @@ func NewCSVInputReader(
 	numDecoders int,
 	ignoreBlanks bool,
 	useArrayIndexFields bool,
+	columnsMismatchPolicy ColumnsMismatchPolicy,
 ) *CSVInputReader {
 	szCount := newSizeTrackingReader(newBomDiscardingReader(in))
 	csvReader := csv.NewReader(szCount)
@@ -72,14 +99,15 @@ func NewCSVInputReader(
 	csvReader.FieldsPerRecord = -1
 	csvReader.TrimLeadingSpace = true
 	return &CSVInputReader{
-		colSpecs:            colSpecs,
-		csvReader:           csvReader,
-		csvRejectWriter:     gocsv.NewWriter(rejects),
-		numProcessed:        uint64(0),
-		numDecoders:         numDecoders,
-		sizeTracker:         szCount,
-		ignoreBlanks:        ignoreBlanks,
-		useArrayIndexFields: useArrayIndexFields,
+		colSpecs:              colSpecs,
+		csvReader:             csvReader,
+		csvRejectWriter:       newRejectWriter(rejects),
+		numProcessed:          uint64(0),
+		numDecoders:           numDecoders,
+		sizeTracker:           szCount,
+		ignoreBlanks:          ignoreBlanks,
+		useArrayIndexFields:   useArrayIndexFields,
+		columnsMismatchPolicy: columnsMismatchPolicy,
 	}
 }
 
@@ -91,6 +119,7 @@ func (r *CSVInputReader) ReadAndValidateHeader() (err error) {
 		return err
 	}
 	r.colSpecs = ParseAutoHeaders(fields)
+	applyHeaderPolicy(r.colSpecs, r.headerPolicy, r.useArrayIndexFields)
 	return validateReaderFields(ColumnNames(r.colSpecs), r.useArrayIndexFields)
 }
 
@@ -105,6 +134,9 @@ func (r *CSVInputReader) ReadAndValidateTypedHeader(parseGrace ParseGrace) (err
 	if err != nil {
 		return err
 	}
+	applyWhitespacePolicy(r.colSpecs, r.whitespace)
+	applyTimezone(r.colSpecs, r.timezone)
+	applyHeaderPolicy(r.colSpecs, r.headerPolicy, r.useArrayIndexFields)
 	return validateReaderFields(ColumnNames(r.colSpecs), r.useArrayIndexFields)
 }
 
@@ -130,44 +162,72 @@ func (r *CSVInputReader) StreamDocument(ordered bool, readDocs chan bson.D) (ret
 				}
 				return
 			}
-			csvRecordChan <- CSVConverter{
-				colSpecs:            r.colSpecs,
-				data:                r.csvRecord,
-				index:               r.numProcessed,
-				ignoreBlanks:        r.ignoreBlanks,
-				useArrayIndexFields: r.useArrayIndexFields,
-				rejectWriter:        r.csvRejectWriter,
+			var converter Converter = CSVConverter{
+				colSpecs:              r.colSpecs,
+				data:                  r.csvRecord,
+				index:                 r.numProcessed,
+				ignoreBlanks:          r.ignoreBlanks,
+				useArrayIndexFields:   r.useArrayIndexFields,
+				columnsMismatchPolicy: r.columnsMismatchPolicy,
+				rejectWriter:          r.csvRejectWriter,
+				documentErrorHandler:  r.documentErrorHandler,
+				whitespace:            r.whitespace,
 			}
 			r.numProcessed++
+			if r.resume != nil {
+				converter = r.resume.wrap(converter, r.consumed(), r.numProcessed)
+			}
+			csvRecordChan <- converter
 		}
 	}()
 
 	go func() {
-		csvErrChan <- streamDocuments(ordered, r.numDecoders, csvRecordChan, readDocs)
+		csvErrChan <- streamDocuments(ordered, r.numDecoders, csvRecordChan, readDocs, r.stages)
 	}()
 
 	return channelQuorumError(csvErrChan)
 }
 
+// consumed returns the number of bytes of input the records read so far take
+// up, including the header.
+func (r *CSVInputReader) consumed() int64 {
+	return r.Size() - int64(r.csvReader.Buffered())
+}
+
 // Convert implements the Converter interface for CSV input. It converts a
 // CSVConverter struct to a BSON document.
 func (c CSVConverter) Convert() (b bson.D, err error) {
 	b, err = tokensToBSON(
 		c.colSpecs,
-		c.data,
+		c.whitespace.applyAll(c.data),
 		c.index,
 		c.ignoreBlanks,
 		c.useArrayIndexFields,
+		c.columnsMismatchPolicy,
 	)
-	if _, ok := err.(coercionError); ok {
+	if skipped, ok := err.(SkippedRowError); ok {
 		if err = c.Print(); err != nil {
 			return
 		}
+		if c.documentErrorHandler != nil {
+			c.documentErrorHandler(skipped)
+		}
 		err = nil
 	}
 	return
 }
 
 func (c CSVConverter) Print() error {
-	return c.rejectWriter.Write(c.data)
+	// the row is formatted first, so that it is written with a single Write
+	row := &bytes.Buffer{}
+	rowWriter := gocsv.NewWriter(row)
+	if err := rowWriter.Write(c.data); err != nil {
+		return err
+	}
+	rowWriter.Flush()
+	if err := rowWriter.Error(); err != nil {
+		return err
+	}
+	_, err := c.rejectWriter.Write(row.Bytes())
+	return err
 }
@@ -125,6 +125,12 @@ func NewReader(r io.Reader) *Reader {
 	}
 }
 
+// Buffered returns the number of bytes read from the underlying reader that
+// are not part of the records returned so far.
+func (r *Reader) Buffered() int {
+	return r.r.Buffered()
+}
+
 // error creates a new ParseError based on err.
 func (r *Reader) error(err error) error {
 	return &ParseError{
@@ -8,8 +8,10 @@ package mongoimport
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 
@@ -43,10 +45,40 @@ func TestCSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 1)
 			So(r.StreamDocument(true, docChan), ShouldNotBeNil)
 		})
+		Convey("rows skipped by several decoders should each be rejected whole", func() {
+			var contents, expectedRejects []string
+			for i := 0; i < 40; i++ {
+				// longer than the buffer of a csv.Writer
+				row := fmt.Sprintf("x%v,%v", i, strings.Repeat("y", 5000))
+				contents = append(contents, row)
+				expectedRejects = append(expectedRejects, row)
+			}
+			colSpecs := []ColumnSpec{
+				{"a", new(FieldInt32Parser), pgSkipRow, "int32", []string{"a"}},
+				{"b", new(FieldAutoParser), pgAutoCast, "auto", []string{"b"}},
+			}
+			rejects := &bytes.Buffer{}
+			r := NewCSVInputReader(
+				colSpecs,
+				strings.NewReader(strings.Join(contents, "\n")),
+				rejects,
+				4,
+				false,
+				false,
+				cmDefault,
+			)
+			docChan := make(chan bson.D, 1)
+			So(r.StreamDocument(false, docChan), ShouldBeNil)
+			rejected := strings.Split(strings.TrimSuffix(rejects.String(), "\n"), "\n")
+			slices.Sort(rejected)
+			slices.Sort(expectedRejects)
+			So(rejected, ShouldResemble, expectedRejects)
+		})
 		Convey("escaped quotes are parsed correctly", func() {
 			contents := `1, 2, "foo""bar"`
 			colSpecs := []ColumnSpec{
@@ -61,6 +93,7 @@ func TestCSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 1)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
@@ -84,6 +117,7 @@ func TestCSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 1)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
@@ -108,6 +142,7 @@ func TestCSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 1)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
@@ -133,6 +168,7 @@ func TestCSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 1)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
@@ -159,6 +195,7 @@ func TestCSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 4)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
@@ -188,6 +225,7 @@ func TestCSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 1)
 			So(r.StreamDocument(true, docChan), ShouldNotBeNil)
@@ -206,6 +244,7 @@ func TestCSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 1)
 			So(r.StreamDocument(true, docChan), ShouldNotBeNil)
@@ -235,6 +274,7 @@ func TestCSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 2)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
@@ -261,7 +301,7 @@ func TestCSVStreamDocument(t *testing.T) {
 			}
 			fileHandle, err := os.Open("testdata/test_bom.csv")
 			So(err, ShouldBeNil)
-			r := NewCSVInputReader(colSpecs, fileHandle, os.Stdout, 1, false, false)
+			r := NewCSVInputReader(colSpecs, fileHandle, os.Stdout, 1, false, false, cmDefault)
 			docChan := make(chan bson.D, len(expectedReads))
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
 			for _, expectedRead := range expectedReads {
@@ -288,6 +328,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			So(r.ReadAndValidateHeader(), ShouldBeNil)
 			So(len(r.colSpecs), ShouldEqual, 3)
@@ -303,6 +344,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			So(r.ReadAndValidateHeader(), ShouldBeNil)
 			So(len(r.colSpecs), ShouldEqual, 3)
@@ -315,6 +357,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			So(r.ReadAndValidateHeader(), ShouldBeNil)
 			So(len(r.colSpecs), ShouldEqual, 3)
@@ -328,6 +371,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			So(r.ReadAndValidateHeader(), ShouldBeNil)
 			So(len(r.colSpecs), ShouldEqual, 3)
@@ -341,6 +385,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			So(r.ReadAndValidateHeader(), ShouldBeNil)
 			So(len(r.colSpecs), ShouldEqual, 4)
@@ -356,6 +401,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			So(r.ReadAndValidateHeader(), ShouldNotBeNil)
 
@@ -368,6 +414,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			So(r.ReadAndValidateHeader(), ShouldNotBeNil)
 
@@ -381,6 +428,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			So(r.ReadAndValidateHeader(), ShouldNotBeNil)
 		})
@@ -397,6 +445,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 					1,
 					false,
 					false,
+					cmDefault,
 				).ReadAndValidateHeader(),
 				ShouldNotBeNil,
 			)
@@ -413,6 +462,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 					1,
 					false,
 					false,
+					cmDefault,
 				).ReadAndValidateHeader(),
 				ShouldNotBeNil,
 			)
@@ -429,6 +479,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 					1,
 					false,
 					false,
+					cmDefault,
 				).ReadAndValidateHeader(),
 				ShouldNotBeNil,
 			)
@@ -443,6 +494,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 					1,
 					false,
 					false,
+					cmDefault,
 				).ReadAndValidateHeader(),
 				ShouldNotBeNil,
 			)
@@ -458,6 +510,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			So(r.ReadAndValidateHeader(), ShouldEqual, io.EOF)
 			So(len(r.colSpecs), ShouldEqual, 0)
@@ -477,6 +530,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			So(r.ReadAndValidateHeader(), ShouldBeNil)
 			// if ReadAndValidateHeader() is called with column specs already passed
@@ -503,7 +557,7 @@ func TestCSVReadAndValidateHeader(t *testing.T) {
 			}
 			fileHandle, err := os.Open("testdata/test.csv")
 			So(err, ShouldBeNil)
-			r := NewCSVInputReader(colSpecs, fileHandle, os.Stdout, 1, false, false)
+			r := NewCSVInputReader(colSpecs, fileHandle, os.Stdout, 1, false, false, cmDefault)
 			docChan := make(chan bson.D, 50)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
 			So(<-docChan, ShouldResemble, expectedReadOne)
@@ -537,5 +591,27 @@ func TestCSVConvert(t *testing.T) {
 				So(document, ShouldResemble, expectedDocument)
 			},
 		)
+		Convey("a skipped row should be rejected and passed to the error handler", func() {
+			rejects := &bytes.Buffer{}
+			var handled []error
+			csvConverter := CSVConverter{
+				colSpecs: []ColumnSpec{
+					{"field1", new(FieldInt32Parser), pgSkipRow, "int32", []string{"field1"}},
+				},
+				data:                 []string{"abc"},
+				index:                uint64(7),
+				rejectWriter:         rejects,
+				documentErrorHandler: func(err error) { handled = append(handled, err) },
+			}
+			document, err := csvConverter.Convert()
+			So(err, ShouldBeNil)
+			So(document, ShouldBeNil)
+			So(rejects.String(), ShouldEqual, "abc\n")
+			So(len(handled), ShouldEqual, 1)
+			skipped, ok := handled[0].(SkippedRowError)
+			So(ok, ShouldBeTrue)
+			So(skipped.Row, ShouldEqual, 7)
+			So(skipped.Fields, ShouldResemble, []string{"abc"})
+		})
 	})
 }
@@ -0,0 +1,194 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// maxErrorExamples is how many documents the report shows for each kind
+	// of write error.
+	maxErrorExamples = 3
+	// maxExampleLength is how much of an example document the report shows.
+	maxExampleLength = 200
+)
+
+// dupKeyIndexRegex finds the index in the message of a duplicate key error,
+// e.g. "E11000 duplicate key error collection: test.c index: _id_ dup key:".
+var dupKeyIndexRegex = regexp.MustCompile(`index: (\S+) dup key`)
+
+// writeErrorGroup is the write errors with the same code on the same index
+// or fields.
+type writeErrorGroup struct {
+	code     int
+	target   string
+	message  string
+	count    int
+	examples []string
+}
+
+// writeErrorReport aggregates the write errors mongoimport continues through,
+// so that the log shows the first error of each kind as it happens and a
+// summary of all of them at the end, instead of a line for every document.
+// It is safe for concurrent use.
+type writeErrorReport struct {
+	mu     sync.Mutex
+	groups map[string]*writeErrorGroup
+	order  []string
+}
+
+// reset forgets the errors recorded so far.
+func (r *writeErrorReport) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.groups = nil
+	r.order = nil
+}
+
+// record adds the write errors of a bulk write to the report.
+func (r *writeErrorReport) record(errs []mongo.BulkWriteError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.groups == nil {
+		r.groups = map[string]*writeErrorGroup{}
+	}
+	for _, writeErr := range errs {
+		target := writeErrorTarget(writeErr.WriteError)
+		key := fmt.Sprintf("%v/%v", writeErr.Code, target)
+		group, ok := r.groups[key]
+		if !ok {
+			group = &writeErrorGroup{code: writeErr.Code, target: target, message: writeErr.Message}
+			r.groups[key] = group
+			r.order = append(r.order, key)
+			log.Logvf(log.Always, "continuing through error: %v", writeErr.Message)
+		} else {
+			log.Logvf(log.Info, "continuing through error: %v", writeErr.Message)
+		}
+		group.count++
+		if len(group.examples) < maxErrorExamples {
+			if example := writeModelExample(writeErr.Request); example != "" {
+				group.examples = append(group.examples, example)
+			}
+		}
+	}
+}
+
+// logSummary logs each kind of write error recorded, with how many documents
+// failed with it and a few of them.
+func (r *writeErrorReport) logSummary() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range r.order {
+		group := r.groups[key]
+		on := ""
+		if group.target != "" {
+			on = " on " + group.target
+		}
+		log.Logvf(
+			log.Always,
+			"%v document(s) failed to import with error code %v%v, first error: %v",
+			group.count,
+			group.code,
+			on,
+			group.message,
+		)
+		for _, example := range group.examples {
+			log.Logvf(log.Always, "\tfor example: %v", example)
+		}
+	}
+}
+
+// writeErrorTarget returns the index of a duplicate key error, or the fields
+// that a document failed validation on.
+func writeErrorTarget(writeErr mongo.WriteError) string {
+	switch writeErr.Code {
+	case db.ErrDuplicateKeyCode:
+		if match := dupKeyIndexRegex.FindStringSubmatch(writeErr.Message); match != nil {
+			return "index " + match[1]
+		}
+	case db.ErrFailedDocumentValidation:
+		fields := map[string]bool{}
+		collectValidationFields(writeErr.Details, fields)
+		if len(fields) > 0 {
+			names := make([]string, 0, len(fields))
+			for name := range fields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return "field(s) " + strings.Join(names, ", ")
+		}
+	}
+	return ""
+}
+
+// collectValidationFields adds the names of the properties that the errInfo
+// of a document validation failure reports as not satisfied or missing.
+func collectValidationFields(details bson.Raw, fields map[string]bool) {
+	elems, err := details.Elements()
+	if err != nil {
+		return
+	}
+	for _, elem := range elems {
+		value := elem.Value()
+		switch {
+		case elem.Key() == "propertyName":
+			if name, ok := value.StringValueOK(); ok {
+				fields[name] = true
+			}
+		case elem.Key() == "missingProperties":
+			if names, ok := value.ArrayOK(); ok {
+				values, _ := names.Values()
+				for _, name := range values {
+					if name, ok := name.StringValueOK(); ok {
+						fields[name] = true
+					}
+				}
+			}
+		case value.Type == bson.TypeEmbeddedDocument:
+			collectValidationFields(value.Document(), fields)
+		case value.Type == bson.TypeArray:
+			collectValidationFields(value.Array(), fields)
+		}
+	}
+}
+
+// writeModelExample returns the document of a failed write as extended JSON,
+// or its filter for an update or delete, cut to maxExampleLength.
+func writeModelExample(model mongo.WriteModel) string {
+	var doc interface{}
+	switch m := model.(type) {
+	case *mongo.InsertOneModel:
+		doc = m.Document
+	case *mongo.ReplaceOneModel:
+		doc = m.Replacement
+	case *mongo.UpdateOneModel:
+		doc = m.Filter
+	case *mongo.DeleteOneModel:
+		doc = m.Filter
+	}
+	if doc == nil {
+		return ""
+	}
+	example, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return ""
+	}
+	if runes := []rune(string(example)); len(runes) > maxExampleLength {
+		return string(runes[:maxExampleLength]) + "..."
+	}
+	return string(example)
+}
@@ -0,0 +1,84 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func dupKeyError(id int) mongo.BulkWriteError {
+	return mongo.BulkWriteError{
+		WriteError: mongo.WriteError{
+			Code:    11000,
+			Message: "E11000 duplicate key error collection: test.c index: _id_ dup key: { _id: 1 }",
+		},
+		Request: mongo.NewInsertOneModel().SetDocument(bson.D{{"_id", id}}),
+	}
+}
+
+func TestWriteErrorReport(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With a write error report", t, func() {
+		var report writeErrorReport
+
+		Convey("errors with the same code and index are counted together", func() {
+			report.record([]mongo.BulkWriteError{dupKeyError(1), dupKeyError(2)})
+			report.record([]mongo.BulkWriteError{dupKeyError(3), dupKeyError(4)})
+			So(report.order, ShouldResemble, []string{"11000/index _id_"})
+			group := report.groups["11000/index _id_"]
+			So(group.count, ShouldEqual, 4)
+			So(group.examples, ShouldResemble, []string{`{"_id":1}`, `{"_id":2}`, `{"_id":3}`})
+
+			validationErr := mongo.BulkWriteError{
+				WriteError: mongo.WriteError{Code: 121, Message: "Document failed validation"},
+				Request:    mongo.NewReplaceOneModel().SetReplacement(bson.D{{"a", 1}}),
+			}
+			report.record([]mongo.BulkWriteError{validationErr})
+			So(report.order, ShouldResemble, []string{"11000/index _id_", "121/"})
+			So(report.groups["121/"].examples, ShouldResemble, []string{`{"a":1}`})
+
+			report.reset()
+			So(report.order, ShouldBeEmpty)
+		})
+
+		Convey("validation errors are grouped by the fields they failed on", func() {
+			details, err := bson.Marshal(bson.D{{"details", bson.D{
+				{"operatorName", "$jsonSchema"},
+				{"schemaRulesNotSatisfied", bson.A{
+					bson.D{
+						{"operatorName", "properties"},
+						{"propertiesNotSatisfied", bson.A{bson.D{{"propertyName", "price"}}}},
+					},
+					bson.D{
+						{"operatorName", "required"},
+						{"missingProperties", bson.A{"name"}},
+					},
+				}},
+			}}})
+			So(err, ShouldBeNil)
+			target := writeErrorTarget(mongo.WriteError{Code: 121, Details: details})
+			So(target, ShouldEqual, "field(s) name, price")
+		})
+
+		Convey("examples are cut short", func() {
+			model := mongo.NewUpdateOneModel().
+				SetFilter(bson.D{{"_id", strings.Repeat("é", 300)}}).
+				SetUpdate(bson.D{{"$set", bson.D{{"a", 1}}}})
+			example := writeModelExample(model)
+			So(len([]rune(example)), ShouldEqual, maxExampleLength+3)
+			So(example, ShouldStartWith, `{"_id":"éé`)
+			So(example, ShouldEndWith, "é...")
+		})
+	})
+}
@@ -0,0 +1,130 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// execHook is a server command of --preExec or --postExec, e.g.
+//
+//	{"dropIndexes": "{{collection}}", "index": "*"}
+//
+// The command runs on the database given by its $db field, or on the
+// database of the import. In its string values, {{db}} and {{collection}}
+// are replaced by the namespace of the import.
+type execHook struct {
+	flag    string
+	dbName  string
+	command bson.D
+}
+
+// parseExecHooks parses the commands given to flag.
+func parseExecHooks(flag string, values []string) ([]execHook, error) {
+	var hooks []execHook
+	for _, value := range values {
+		var command bson.D
+		if err := bson.UnmarshalExtJSON([]byte(value), false, &command); err != nil {
+			return nil, fmt.Errorf("error parsing %v command %v: %v", flag, value, err)
+		}
+		hook := execHook{flag: flag}
+		for _, elem := range command {
+			if elem.Key != "$db" {
+				hook.command = append(hook.command, elem)
+				continue
+			}
+			name, ok := elem.Value.(string)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("the $db of %v command %v must be a database name", flag, value)
+			}
+			hook.dbName = name
+		}
+		if len(hook.command) == 0 {
+			return nil, fmt.Errorf("%v command %v is empty", flag, value)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// validateExecHooks parses --preExec and --postExec.
+func (imp *MongoImport) validateExecHooks() error {
+	var err error
+	imp.preExec, err = parseExecHooks("--preExec", imp.IngestOptions.PreExec)
+	if err != nil {
+		return err
+	}
+	imp.postExec, err = parseExecHooks("--postExec", imp.IngestOptions.PostExec)
+	return err
+}
+
+// expandPlaceholders returns value with {{db}} and {{collection}} replaced in
+// its strings, including those of its subdocuments and arrays.
+func (imp *MongoImport) expandPlaceholders(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.NewReplacer(
+			"{{db}}", imp.ToolOptions.DB,
+			"{{collection}}", imp.ToolOptions.Collection,
+		).Replace(v)
+	case bson.D:
+		expanded := make(bson.D, len(v))
+		for i, elem := range v {
+			expanded[i] = bson.E{Key: elem.Key, Value: imp.expandPlaceholders(elem.Value)}
+		}
+		return expanded
+	case bson.A:
+		expanded := make(bson.A, len(v))
+		for i, elem := range v {
+			expanded[i] = imp.expandPlaceholders(elem)
+		}
+		return expanded
+	}
+	return value
+}
+
+// runExecHooks runs the commands of hooks in order. If keepGoing is set, the
+// commands after one that fails still run, and the first error is returned
+// once they have; otherwise the first error stops the commands.
+func (imp *MongoImport) runExecHooks(
+	session *mongo.Client,
+	hooks []execHook,
+	keepGoing bool,
+) error {
+	var firstErr error
+	for _, hook := range hooks {
+		dbName := hook.dbName
+		if dbName == "" {
+			dbName = imp.ToolOptions.DB
+		}
+		command := imp.expandPlaceholders(hook.command).(bson.D)
+		log.Logvf(log.Always, "running %v command %v on %v", hook.flag, command[0].Key, dbName)
+		log.Logvf(log.DebugLow, "%v command: %v", hook.flag, command)
+
+		var result bson.M
+		err := session.Database(dbName).RunCommand(context.TODO(), command).Decode(&result)
+		if err == nil {
+			log.Logvf(log.DebugLow, "%v command %v returned %v", hook.flag, command[0].Key, result)
+			continue
+		}
+		err = fmt.Errorf("error running %v command %v: %v", hook.flag, command[0].Key, err)
+		if !keepGoing {
+			return err
+		}
+		log.Logvf(log.Always, "%v", err)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
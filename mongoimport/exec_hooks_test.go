@@ -0,0 +1,149 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/mongodb/mongo-tools/common/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestExecHooks(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With --preExec and --postExec", t, func() {
+		imp := NewMockMongoImport()
+		imp.ToolOptions.DB = "app"
+		imp.ToolOptions.Collection = "orders"
+
+		Convey("the commands are parsed in order", func() {
+			imp.IngestOptions.PreExec = []string{
+				`{"dropIndexes": "{{collection}}", "index": "*"}`,
+				`{"setParameter": 1, "notablescan": false, "$db": "admin"}`,
+			}
+			imp.IngestOptions.PostExec = []string{
+				`{"createIndexes": "orders", "indexes": [{"key": {"sku": 1}, "name": "sku_1"}]}`,
+			}
+			So(imp.validateSettings(), ShouldBeNil)
+			So(len(imp.preExec), ShouldEqual, 2)
+			So(imp.preExec[0].dbName, ShouldEqual, "")
+			So(imp.preExec[0].command[0].Key, ShouldEqual, "dropIndexes")
+			So(imp.preExec[1].dbName, ShouldEqual, "admin")
+			So(imp.preExec[1].command, ShouldResemble,
+				bson.D{{"setParameter", int32(1)}, {"notablescan", false}})
+			So(len(imp.postExec), ShouldEqual, 1)
+			So(imp.postExec[0].flag, ShouldEqual, "--postExec")
+		})
+
+		Convey("placeholders are replaced in nested strings", func() {
+			imp.IngestOptions.PreExec = []string{
+				`{"collMod": "{{collection}}", "comment": ["{{db}}.{{collection}}", {"x": "{{db}}"}]}`,
+			}
+			So(imp.validateSettings(), ShouldBeNil)
+			command := imp.expandPlaceholders(imp.preExec[0].command)
+			So(command, ShouldResemble, bson.D{
+				{"collMod", "orders"},
+				{"comment", bson.A{"app.orders", bson.D{{"x", "app"}}}},
+			})
+			// the parsed command is left alone for the next import
+			So(imp.preExec[0].command[0].Value, ShouldEqual, "{{collection}}")
+		})
+
+		Convey("invalid commands are rejected", func() {
+			for _, command := range []string{
+				`{"collMod": `,
+				`{}`,
+				`{"$db": "admin"}`,
+				`{"ping": 1, "$db": 1}`,
+			} {
+				imp.IngestOptions.PostExec = []string{command}
+				So(imp.validateSettings(), ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestExecHooksOnFailure(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.IntegrationTestType)
+
+	client, err := testutil.GetBareSession()
+	if err != nil {
+		t.Fatalf("No server available?? (%v)", err)
+	}
+	database := client.Database("mongoimport_exec_hooks")
+	hooks := database.Collection("hooks")
+
+	Convey("--postExec runs when the import fails after --preExec", t, func() {
+		So(database.Drop(context.Background()), ShouldBeNil)
+		imp, err := getImportWithArgs("testdata/test.csv",
+			"--type", "csv",
+			"--fields", "a,b,c",
+			"--db", database.Name(),
+			"--collection", "imported",
+			"--preExec", `{"insert": "hooks", "documents": [{"_id": "pre"}]}`,
+			"--postExec", `{"insert": "hooks", "documents": [{"_id": "post"}]}`,
+		)
+		So(err, ShouldBeNil)
+		// the totals of the collection can't be counted before the import
+		imp.reconcile = &reconciler{fields: []string{"$undefined"}}
+
+		_, _, err = imp.ImportDocuments()
+		So(err, ShouldNotBeNil)
+		cursor, err := hooks.Find(context.Background(), bson.D{})
+		So(err, ShouldBeNil)
+		var ran []bson.M
+		So(cursor.All(context.Background(), &ran), ShouldBeNil)
+		So(ran, ShouldResemble, []bson.M{{"_id": "pre"}, {"_id": "post"}})
+	})
+
+	Convey("--postExec runs when a --preExec command after the first one fails", t, func() {
+		So(database.Drop(context.Background()), ShouldBeNil)
+		imp, err := getImportWithArgs("testdata/test.csv",
+			"--type", "csv",
+			"--fields", "a,b,c",
+			"--db", database.Name(),
+			"--collection", "imported",
+			"--preExec", `{"insert": "hooks", "documents": [{"_id": "pre"}]}`,
+			"--preExec", `{"noSuchCommand": 1}`,
+			"--postExec", `{"insert": "hooks", "documents": [{"_id": "post"}]}`,
+		)
+		So(err, ShouldBeNil)
+
+		_, _, err = imp.ImportDocuments()
+		So(err, ShouldNotBeNil)
+		cursor, err := hooks.Find(context.Background(), bson.D{})
+		So(err, ShouldBeNil)
+		var ran []bson.M
+		So(cursor.All(context.Background(), &ran), ShouldBeNil)
+		So(ran, ShouldResemble, []bson.M{{"_id": "pre"}, {"_id": "post"}})
+	})
+
+	Convey("--postExec doesn't run when the first --preExec command fails", t, func() {
+		So(database.Drop(context.Background()), ShouldBeNil)
+		imp, err := getImportWithArgs("testdata/test.csv",
+			"--type", "csv",
+			"--fields", "a,b,c",
+			"--db", database.Name(),
+			"--collection", "imported",
+			"--preExec", `{"noSuchCommand": 1}`,
+			"--postExec", `{"insert": "hooks", "documents": [{"_id": "post"}]}`,
+		)
+		So(err, ShouldBeNil)
+
+		_, _, err = imp.ImportDocuments()
+		So(err, ShouldNotBeNil)
+		count, err := hooks.CountDocuments(context.Background(), bson.D{})
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+	})
+
+	_ = database.Drop(context.Background())
+}
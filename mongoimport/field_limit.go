@@ -0,0 +1,106 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	distinctFieldsAbort = "abort"
+	distinctFieldsWarn  = "warn"
+)
+
+// fieldExamples is how many of the fields beyond --maxDistinctFields are
+// named in the error or warning.
+const fieldExamples = 5
+
+// fieldLimiter counts the distinct top-level fields of the imported
+// documents, to catch an input that would spread its values over thousands
+// of fields, like a transposed CSV file whose header line holds the values
+// of one column. It is checked before each document is written, so that an
+// abort happens before the document with the first field too many reaches
+// the collection. It is safe for concurrent use.
+type fieldLimiter struct {
+	max  int
+	warn bool
+
+	mu     sync.Mutex
+	fields map[string]bool
+	// err is the error of the abort, returned for every later document
+	err error
+}
+
+// validateFieldLimit checks --maxDistinctFields and --distinctFieldsPolicy,
+// and sets up the fieldLimiter.
+func (imp *MongoImport) validateFieldLimit() error {
+	max := imp.IngestOptions.MaxDistinctFields
+	policy := imp.IngestOptions.DistinctFieldsPolicy
+	switch {
+	case max < 0:
+		return fmt.Errorf("--maxDistinctFields must not be negative")
+	case max == 0 && policy != "":
+		return fmt.Errorf("cannot use --distinctFieldsPolicy without --maxDistinctFields")
+	case max == 0:
+		return nil
+	case policy != "" && policy != distinctFieldsAbort && policy != distinctFieldsWarn:
+		return fmt.Errorf("invalid --distinctFieldsPolicy: %v", policy)
+	}
+	imp.fieldLimiter = &fieldLimiter{
+		max:    max,
+		warn:   policy == distinctFieldsWarn,
+		fields: map[string]bool{},
+	}
+	return nil
+}
+
+// check counts the top-level fields of document, and returns an error if
+// they take the import over the limit, unless it only warns. It is a no-op
+// on a nil fieldLimiter.
+func (l *fieldLimiter) check(document bson.D) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.fields) > l.max {
+		// already aborted or warned
+		return l.err
+	}
+
+	var beyond []string
+	for _, elem := range document {
+		if l.fields[elem.Key] {
+			continue
+		}
+		l.fields[elem.Key] = true
+		if len(l.fields) > l.max && len(beyond) < fieldExamples {
+			beyond = append(beyond, elem.Key)
+		}
+	}
+	if len(beyond) == 0 {
+		return nil
+	}
+
+	message := fmt.Sprintf(
+		"the import has more than %v distinct top-level fields (--maxDistinctFields), "+
+			"including '%v'; check that the input is not transposed and has the right header",
+		l.max,
+		strings.Join(beyond, "', '"),
+	)
+	if !l.warn {
+		l.err = fmt.Errorf("%v", message)
+		return l.err
+	}
+	log.Logvf(log.Always, "warning: %v", message)
+	return nil
+}
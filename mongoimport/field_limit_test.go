@@ -0,0 +1,80 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFieldLimit(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	wideDocument := func(n int) bson.D {
+		doc := bson.D{}
+		for i := 0; i < n; i++ {
+			doc = append(doc, bson.E{fmt.Sprintf("2024-01-%02d", i+1), i})
+		}
+		return doc
+	}
+
+	Convey("With --maxDistinctFields", t, func() {
+		imp := NewMockMongoImport()
+		imp.IngestOptions.MaxDistinctFields = 3
+
+		Convey("documents within the limit are imported", func() {
+			So(imp.validateSettings(), ShouldBeNil)
+			So(imp.fieldLimiter.check(bson.D{{"a", 1}, {"b", 2}}), ShouldBeNil)
+			So(imp.fieldLimiter.check(bson.D{{"b", 3}, {"c", 4}}), ShouldBeNil)
+			So(imp.fieldLimiter.check(bson.D{{"a", 5}}), ShouldBeNil)
+		})
+
+		Convey("the import aborts at the first field too many", func() {
+			So(imp.validateSettings(), ShouldBeNil)
+			So(imp.fieldLimiter.check(bson.D{{"a", 1}, {"b", 2}, {"c", 3}}), ShouldBeNil)
+			err := imp.fieldLimiter.check(bson.D{{"d", 4}})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "'d'")
+			So(imp.fieldLimiter.check(bson.D{{"a", 5}}), ShouldEqual, err)
+		})
+
+		Convey("a transposed file is caught by its first document", func() {
+			So(imp.validateSettings(), ShouldBeNil)
+			err := imp.fieldLimiter.check(wideDocument(1000))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "'2024-01-04', '2024-01-05'")
+		})
+
+		Convey("the warn policy imports everything", func() {
+			imp.IngestOptions.DistinctFieldsPolicy = distinctFieldsWarn
+			So(imp.validateSettings(), ShouldBeNil)
+			So(imp.fieldLimiter.check(wideDocument(10)), ShouldBeNil)
+			So(imp.fieldLimiter.check(wideDocument(20)), ShouldBeNil)
+		})
+
+		Convey("invalid settings are rejected", func() {
+			imp.IngestOptions.DistinctFieldsPolicy = "ignore"
+			So(imp.validateSettings(), ShouldNotBeNil)
+			imp.IngestOptions.DistinctFieldsPolicy = distinctFieldsAbort
+			imp.IngestOptions.MaxDistinctFields = 0
+			So(imp.validateSettings(), ShouldNotBeNil)
+			imp.IngestOptions.MaxDistinctFields = -1
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Without --maxDistinctFields nothing is counted", t, func() {
+		imp := NewMockMongoImport()
+		So(imp.validateSettings(), ShouldBeNil)
+		So(imp.fieldLimiter, ShouldBeNil)
+		So(imp.fieldLimiter.check(wideDocument(100)), ShouldBeNil)
+	})
+}
@@ -0,0 +1,114 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mongodb/mongo-tools/common/log"
+)
+
+// HeaderPolicy controls what is done with CSV and TSV column names that are
+// not valid field names, or that repeat another column's name.
+type HeaderPolicy int
+
+const (
+	// hpStrict fails the import on an invalid or duplicate column name.
+	hpStrict HeaderPolicy = iota
+	// hpSanitize renames such columns to valid, unique field names.
+	hpSanitize
+)
+
+// ValidateHP ensures the user-provided headerPolicy is one of the allowed
+// values.
+func ValidateHP(hp string) (HeaderPolicy, error) {
+	switch hp {
+	case "", "strict":
+		return hpStrict, nil
+	case "sanitize":
+		return hpSanitize, nil
+	default:
+		return hpStrict, fmt.Errorf("invalid header policy: %s", hp)
+	}
+}
+
+// ParseHP interprets the user-provided headerPolicy, assuming it is valid.
+func ParseHP(hp string) (res HeaderPolicy) {
+	res, _ = ValidateHP(hp)
+	return
+}
+
+// applyHeaderPolicy renames the columns according to the policy, and logs
+// every column it renames. With hpSanitize, whitespace in a name becomes an
+// underscore, dots and dollar signs are removed, a name left empty becomes
+// "field<N>" after the column's index, and every repeat of a name gets a
+// suffix of _2, _3 and so on. The columns of a DBRef, and repeated tags[]
+// columns when useArrayIndexFields is set, keep sharing their name.
+func applyHeaderPolicy(colSpecs []ColumnSpec, policy HeaderPolicy, useArrayIndexFields bool) {
+	if policy != hpSanitize {
+		return
+	}
+
+	names := make([]string, len(colSpecs))
+	shared := make([]bool, len(colSpecs))
+	used := map[string]bool{}
+	for i, colSpec := range colSpecs {
+		name := colSpec.Name
+		suffix := ""
+		if dp, ok := colSpec.Parser.(*FieldDBRefParser); ok {
+			suffix = "." + dp.field
+			name = strings.TrimSuffix(name, suffix)
+			shared[i] = true
+		} else if base, ok := repeatedFieldName(name); ok && useArrayIndexFields {
+			suffix = arrayFieldSuffix
+			name = base
+			shared[i] = true
+		}
+		name = sanitizeFieldName(name)
+		if name == "" {
+			name = "field" + strconv.Itoa(i)
+		}
+		names[i] = name + suffix
+		if shared[i] {
+			used[names[i]] = true
+		}
+	}
+
+	for i, name := range names {
+		if !shared[i] {
+			base := name
+			for n := 2; used[name]; n++ {
+				name = base + "_" + strconv.Itoa(n)
+			}
+			used[name] = true
+		}
+		if name == colSpecs[i].Name {
+			continue
+		}
+		log.Logvf(log.Always, "renaming column %d from '%v' to '%v'", i+1, colSpecs[i].Name, name)
+		colSpecs[i].Name = name
+		colSpecs[i].NameParts = strings.Split(name, ".")
+	}
+}
+
+// sanitizeFieldName returns name with its surrounding whitespace removed,
+// the rest of its whitespace replaced by underscores, and its dots and
+// dollar signs removed.
+func sanitizeFieldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '.' || r == '$':
+			return -1
+		case unicode.IsSpace(r):
+			return '_'
+		}
+		return r
+	}, strings.TrimFunc(name, unicode.IsSpace))
+}
@@ -0,0 +1,102 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestHeaderPolicy(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With the sanitize header policy", t, func() {
+		Convey("invalid names should become valid field names", func() {
+			colSpecs := ParseAutoHeaders(
+				[]string{" First Name ", "Unit.Price", "$cost", "", "ok", "a\tb"},
+			)
+			applyHeaderPolicy(colSpecs, hpSanitize, false)
+			So(
+				ColumnNames(colSpecs),
+				ShouldResemble,
+				[]string{"First_Name", "UnitPrice", "cost", "field3", "ok", "a_b"},
+			)
+			So(colSpecs[1].NameParts, ShouldResemble, []string{"UnitPrice"})
+		})
+
+		Convey("repeated names should get unique suffixes", func() {
+			colSpecs := ParseAutoHeaders([]string{"a", "a", "a_2", "a", "a.", "b"})
+			applyHeaderPolicy(colSpecs, hpSanitize, false)
+			So(
+				ColumnNames(colSpecs),
+				ShouldResemble,
+				[]string{"a", "a_2", "a_2_2", "a_3", "a_4", "b"},
+			)
+			So(validateFields(ColumnNames(colSpecs), false), ShouldBeNil)
+		})
+
+		Convey("columns meant to share a name should keep sharing it", func() {
+			colSpecs, err := ParseTypedHeaders([]string{
+				"my owner.dbref(ref)", "my owner.dbref(id)", "tags[].auto()", "tags[].auto()",
+			}, pgStop)
+			So(err, ShouldBeNil)
+			applyHeaderPolicy(colSpecs, hpSanitize, true)
+			So(
+				ColumnNames(colSpecs),
+				ShouldResemble,
+				[]string{"my_owner.$ref", "my_owner.$id", "tags[]", "tags[]"},
+			)
+		})
+
+		Convey("a CSV header line should be sanitized before it is validated", func() {
+			contents := "Full Name,e.mail,e.mail\nalice,a@x,b@x\n"
+			r := NewCSVInputReader(
+				nil,
+				bytes.NewReader([]byte(contents)),
+				&bytes.Buffer{},
+				1,
+				false,
+				false,
+				cmDefault,
+			)
+			r.headerPolicy = hpSanitize
+			So(r.ReadAndValidateHeader(), ShouldBeNil)
+
+			docChan := make(chan bson.D, 1)
+			So(r.StreamDocument(true, docChan), ShouldBeNil)
+			So(<-docChan, ShouldResemble, bson.D{
+				{"Full_Name", "alice"}, {"email", "a@x"}, {"email_2", "b@x"},
+			})
+		})
+	})
+
+	Convey("With the strict header policy", t, func() {
+		colSpecs := ParseAutoHeaders([]string{"a", "a", "$b"})
+		applyHeaderPolicy(colSpecs, hpStrict, false)
+		So(ColumnNames(colSpecs), ShouldResemble, []string{"a", "a", "$b"})
+		So(validateFields(ColumnNames(colSpecs), false), ShouldNotBeNil)
+	})
+
+	Convey("--headerPolicy should be validated", t, func() {
+		imp := NewMockMongoImport()
+		imp.InputOptions.Type = CSV
+		imp.InputOptions.HeaderLine = true
+		imp.InputOptions.HeaderPolicy = "rename"
+		So(imp.validateSettings(), ShouldNotBeNil)
+		imp.InputOptions.HeaderPolicy = "sanitize"
+		So(imp.validateSettings(), ShouldBeNil)
+
+		imp = NewMockMongoImport()
+		imp.InputOptions.Type = JSON
+		imp.InputOptions.HeaderPolicy = "sanitize"
+		So(imp.validateSettings(), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,72 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// validateIdempotency checks --idempotentRetries and --idempotencyFields, and
+// sets the fields to hash into the _id of each document.
+func (imp *MongoImport) validateIdempotency() error {
+	retries := imp.IngestOptions.IdempotentRetries
+	fields := imp.IngestOptions.IdempotencyFields
+	if retries < 0 {
+		return fmt.Errorf("--idempotentRetries must not be negative, got %v", retries)
+	}
+	if retries == 0 {
+		if fields != "" {
+			return fmt.Errorf("cannot use --idempotencyFields without --idempotentRetries")
+		}
+		return nil
+	}
+	if imp.IngestOptions.Mode != modeInsert {
+		return fmt.Errorf("cannot use --idempotentRetries with --mode=%v", imp.IngestOptions.Mode)
+	}
+	if fields == "" {
+		return fmt.Errorf("--idempotentRetries requires --idempotencyFields")
+	}
+
+	imp.idempotencyFields = strings.Split(fields, ",")
+	if err := validateFields(imp.idempotencyFields, imp.InputOptions.UseArrayIndexFields); err != nil {
+		return fmt.Errorf("invalid --idempotencyFields argument: %v", err)
+	}
+	for _, field := range imp.idempotencyFields {
+		if field == "_id" || strings.HasPrefix(field, "_id.") {
+			return fmt.Errorf("--idempotencyFields cannot include _id, which it sets")
+		}
+	}
+	return nil
+}
+
+// withIdempotentID returns document with an _id that is the hex SHA-256 hash
+// of the values of fields, so that the same values always give the same _id.
+func withIdempotentID(fields []string, document bson.D) (bson.D, error) {
+	for _, elem := range document {
+		if elem.Key == "_id" {
+			return nil, fmt.Errorf(
+				"document already has an _id, which --idempotentRetries would replace",
+			)
+		}
+	}
+	key := constructUpsertDocument(fields, document)
+	if key == nil {
+		return nil, fmt.Errorf("document has none of the --idempotencyFields %v", fields)
+	}
+	raw, err := bson.Marshal(key)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing --idempotencyFields: %v", err)
+	}
+	sum := sha256.Sum256(raw)
+	id := bson.E{Key: "_id", Value: hex.EncodeToString(sum[:])}
+	return append(bson.D{id}, document...), nil
+}
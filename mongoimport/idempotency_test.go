@@ -0,0 +1,87 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestIdempotentRetries(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With --idempotentRetries", t, func() {
+		imp := NewMockMongoImport()
+		imp.IngestOptions.IdempotentRetries = 3
+		imp.IngestOptions.IdempotencyFields = "source,event.seq"
+
+		Convey("the fields are parsed", func() {
+			So(imp.validateSettings(), ShouldBeNil)
+			So(imp.idempotencyFields, ShouldResemble, []string{"source", "event.seq"})
+		})
+
+		Convey("--idempotencyFields is required", func() {
+			imp.IngestOptions.IdempotencyFields = ""
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("--idempotencyFields cannot include _id", func() {
+			imp.IngestOptions.IdempotencyFields = "source,_id"
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("only insert mode is supported", func() {
+			imp.IngestOptions.Mode = modeUpsert
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("--idempotencyFields requires it", func() {
+			imp.IngestOptions.IdempotentRetries = 0
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+	})
+
+	Convey("The _id of a document", t, func() {
+		fields := []string{"source", "event.seq"}
+		doc := func(source string, seq int32, other string) bson.D {
+			return bson.D{
+				{"source", source},
+				{"event", bson.D{{"seq", seq}}},
+				{"other", other},
+			}
+		}
+
+		first, err := withIdempotentID(fields, doc("a", 1, "x"))
+		So(err, ShouldBeNil)
+		So(first[0].Key, ShouldEqual, "_id")
+		So(first[0].Value, ShouldHaveLength, 64)
+		So(first[1:], ShouldResemble, doc("a", 1, "x"))
+
+		Convey("depends only on the fields", func() {
+			again, err := withIdempotentID(fields, doc("a", 1, "y"))
+			So(err, ShouldBeNil)
+			So(again[0], ShouldResemble, first[0])
+
+			other, err := withIdempotentID(fields, doc("a", 2, "x"))
+			So(err, ShouldBeNil)
+			So(other[0], ShouldNotResemble, first[0])
+		})
+
+		Convey("cannot replace an existing _id", func() {
+			_, err := withIdempotentID(fields, bson.D{{"_id", 1}, {"source", "a"}})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("needs one of the fields", func() {
+			_, err := withIdempotentID(fields, bson.D{{"other", "x"}})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -52,6 +52,12 @@ type JSONInputReader struct {
 
 	// legacyExtJSON specifies whether or not the legacy extended JSON format should be used.
 	legacyExtJSON bool
+
+	// resume tracks the position of each document in the --resumeFile, if set
+	resume *resumeTracker
+
+	// stages counts the records of --progressStages, if set
+	stages *importStages
 }
 
 // JSONConverter implements the Converter interface for JSON input.
@@ -137,23 +143,33 @@ func (r *JSONInputReader) StreamDocument(ordered bool, readChan chan bson.D) (re
 				}
 				return
 			}
-			rawChan <- JSONConverter{
+			var converter Converter = JSONConverter{
 				data:          rawBytes,
 				index:         r.numProcessed,
 				legacyExtJSON: r.legacyExtJSON,
 			}
 			r.numProcessed++
+			if r.resume != nil {
+				converter = r.resume.wrap(converter, r.consumed(), r.numProcessed)
+			}
+			rawChan <- converter
 		}
 	}()
 
 	// begin processing read bytes
 	go func() {
-		jsonErrChan <- streamDocuments(ordered, r.numDecoders, rawChan, readChan)
+		jsonErrChan <- streamDocuments(ordered, r.numDecoders, rawChan, readChan, r.stages)
 	}()
 
 	return channelQuorumError(jsonErrChan)
 }
 
+// consumed returns the number of bytes of input the documents read so far
+// take up.
+func (r *JSONInputReader) consumed() int64 {
+	return r.Size() - int64(len(r.decoder.Buf))
+}
+
 // Convert implements the Converter interface for JSON input. It converts a
 // JSONConverter struct to a BSON document.
 func (c JSONConverter) Convert() (bson.D, error) {
@@ -12,6 +12,7 @@ import (
 
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/signals"
+	"github.com/mongodb/mongo-tools/common/telemetry"
 	"github.com/mongodb/mongo-tools/common/util"
 	"github.com/mongodb/mongo-tools/mongoimport"
 )
@@ -41,10 +42,16 @@ func main() {
 		return
 	}
 
+	if err := telemetry.Init(opts.AppName, opts.VersionStr, opts.OTelEndpoint); err != nil {
+		log.Logvf(log.Always, "error starting telemetry: %v", err)
+		os.Exit(util.ExitFailure)
+	}
+	defer telemetry.Shutdown()
+
 	m, err := mongoimport.New(opts)
 	if err != nil {
 		log.Logvf(log.Always, err.Error())
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 	defer m.Close()
 
@@ -69,6 +76,6 @@ func main() {
 		}
 	}
 	if err != nil {
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 }
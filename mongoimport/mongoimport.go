@@ -12,12 +12,14 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/fieldcrypt"
+	"github.com/mongodb/mongo-tools/common/fieldmap"
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/options"
 	"github.com/mongodb/mongo-tools/common/progress"
@@ -29,9 +31,10 @@ import (
 
 // Input format types accepted by mongoimport.
 const (
-	CSV  = "csv"
-	TSV  = "tsv"
-	JSON = "json"
+	CSV      = "csv"
+	TSV      = "tsv"
+	JSON     = "json"
+	PROTOBUF = "protobuf"
 )
 
 // Modes accepted by mongoimport.
@@ -71,6 +74,14 @@ type MongoImport struct {
 	// SessionProvider is used for connecting to the database
 	SessionProvider *db.SessionProvider
 
+	// DocumentErrorHandler, if set, is called with the error for each document
+	// that fails to import. See DocumentErrorHandler for details.
+	DocumentErrorHandler DocumentErrorHandler
+
+	// RejectWriter is where CSV and TSV rows skipped because of --parseGrace or
+	// --columnsMismatchPolicy are written. It defaults to os.Stdout.
+	RejectWriter io.Writer
+
 	// the tomb is used to synchronize ingestion goroutines and causes
 	// other sibling goroutines to terminate immediately if one errors out
 	tomb.Tomb
@@ -78,10 +89,61 @@ type MongoImport struct {
 	// fields to use for upsert operations
 	upsertFields []string
 
+	// fields hashed into the _id of each document for --idempotentRetries
+	idempotencyFields []string
+
+	// how --mode=merge merges the arrays of --arrayMerge
+	arrayMerges []arrayMerge
+
+	// counts the top-level fields of the documents for --maxDistinctFields
+	fieldLimiter *fieldLimiter
+
 	// type of node the SessionProvider is connected to
 	nodeType db.NodeType
+
+	// decrypts the values of --decryptFields, if set
+	decrypter *fieldcrypt.Cipher
+
+	// renames the fields of the input documents, if --fieldMap is set
+	fieldMap *fieldmap.Map
+
+	// checks documents before they are sent
+	validator db.DocumentValidator
+
+	// keeps the --resumeFile up to date while ImportDocuments runs, if set
+	resume *resumeTracker
+
+	// the message type of --messageType, for --type=protobuf
+	protobufMessage *protoMessage
+
+	// the write errors continued through, reported when the import finishes
+	writeErrors writeErrorReport
+
+	// compares the collection with the documents imported, for --reconcileFile
+	reconcile *reconciler
+
+	// counts the records that go through each stage, for --progressStages
+	stages *importStages
+
+	// the commands of --preExec and --postExec
+	preExec, postExec []execHook
+
+	// the files of --file and their collections, if there is more than one
+	files []inputFile
+
+	// the collections of files that --drop already dropped
+	droppedCollections map[string]bool
 }
 
+// DocumentErrorHandler is called for each document that mongoimport fails to
+// import. The error is a mongo.BulkWriteError for a document the server
+// rejected, whose Request holds the write model for the document, a
+// db.InvalidDocumentError for a document that was not sent because the server
+// would reject it, or a SkippedRowError for a CSV or TSV row that was skipped.
+// The handler is called from the conversion and insertion goroutines, so it
+// must be safe for concurrent use.
+type DocumentErrorHandler func(err error)
+
 type InputReader interface {
 	// StreamDocument takes a boolean indicating if the documents should be streamed
 	// in read order and a channel on which to stream the documents processed from
@@ -114,6 +176,24 @@ func New(opts Options) (*MongoImport, error) {
 		return nil, fmt.Errorf("error validating settings: %v", err)
 	}
 
+	var err error
+	mi.decrypter, err = fieldcrypt.NewFromFlags(
+		opts.InputOptions.DecryptFields,
+		opts.InputOptions.EncryptionKeyFile,
+		"--decryptFields",
+		"--encryptionKeyFile",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error validating settings: %v", err)
+	}
+
+	if opts.InputOptions.FieldMap != "" {
+		mi.fieldMap, err = fieldmap.Load(opts.InputOptions.FieldMap, true)
+		if err != nil {
+			return nil, fmt.Errorf("error validating settings: %v", err)
+		}
+	}
+
 	sessionProvider, err := db.NewSessionProvider(*opts.ToolOptions)
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to host: %v", err)
@@ -147,10 +227,14 @@ func (imp *MongoImport) validateSettings() error {
 	} else {
 		if !(imp.InputOptions.Type == TSV ||
 			imp.InputOptions.Type == JSON ||
-			imp.InputOptions.Type == CSV) {
+			imp.InputOptions.Type == CSV ||
+			imp.InputOptions.Type == PROTOBUF) {
 			return fmt.Errorf("unknown type %v", imp.InputOptions.Type)
 		}
 	}
+	if err := imp.validateProtobuf(); err != nil {
+		return err
+	}
 
 	// ensure headers are supplied for CSV/TSV
 	if imp.InputOptions.Type == CSV ||
@@ -182,26 +266,66 @@ func (imp *MongoImport) validateSettings() error {
 		if _, err := ValidatePG(imp.InputOptions.ParseGrace); err != nil {
 			return err
 		}
+		if _, err := ValidateCM(imp.InputOptions.ColumnsMismatchPolicy); err != nil {
+			return err
+		}
+		if _, err := ValidateHP(imp.InputOptions.HeaderPolicy); err != nil {
+			return err
+		}
 		if imp.InputOptions.Legacy {
 			return fmt.Errorf("cannot use --legacy if input type is not JSON")
 		}
+		if imp.InputOptions.Timezone != "" {
+			if !imp.InputOptions.ColumnsHaveTypes {
+				return fmt.Errorf("cannot use --timezone without --columnsHaveTypes")
+			}
+			if _, err := time.LoadLocation(imp.InputOptions.Timezone); err != nil {
+				return fmt.Errorf("invalid --timezone: %v", err)
+			}
+		}
 	} else {
-		// input type is JSON
+		// input type is JSON or protobuf
+		inputType := "JSON"
+		if imp.InputOptions.Type == PROTOBUF {
+			inputType = "protobuf"
+		}
 		if imp.InputOptions.HeaderLine {
-			return fmt.Errorf("cannot use --headerline when input type is JSON")
+			return fmt.Errorf("cannot use --headerline when input type is %v", inputType)
 		}
 		if imp.InputOptions.Fields != nil {
-			return fmt.Errorf("cannot use --fields when input type is JSON")
+			return fmt.Errorf("cannot use --fields when input type is %v", inputType)
 		}
 		if imp.InputOptions.FieldFile != nil {
-			return fmt.Errorf("cannot use --fieldFile when input type is JSON")
+			return fmt.Errorf("cannot use --fieldFile when input type is %v", inputType)
 		}
 		if imp.IngestOptions.IgnoreBlanks {
-			return fmt.Errorf("cannot use --ignoreBlanks when input type is JSON")
+			return fmt.Errorf("cannot use --ignoreBlanks when input type is %v", inputType)
 		}
 		if imp.InputOptions.ColumnsHaveTypes {
-			return fmt.Errorf("cannot use --columnsHaveTypes when input type is JSON")
+			return fmt.Errorf("cannot use --columnsHaveTypes when input type is %v", inputType)
+		}
+		if imp.InputOptions.ColumnsMismatchPolicy != "" {
+			return fmt.Errorf("cannot use --columnsMismatchPolicy when input type is %v", inputType)
 		}
+		if imp.InputOptions.HeaderPolicy != "" {
+			return fmt.Errorf("cannot use --headerPolicy when input type is %v", inputType)
+		}
+		if imp.InputOptions.TrimFields {
+			return fmt.Errorf("cannot use --trimFields when input type is %v", inputType)
+		}
+		if imp.InputOptions.TrimNonBreakingSpaces {
+			return fmt.Errorf("cannot use --trimNonBreakingSpaces when input type is %v", inputType)
+		}
+		if imp.InputOptions.StripBOMs {
+			return fmt.Errorf("cannot use --stripBOMs when input type is %v", inputType)
+		}
+		if imp.InputOptions.Timezone != "" {
+			return fmt.Errorf("cannot use --timezone when input type is %v", inputType)
+		}
+	}
+
+	if err := imp.validateOpField(); err != nil {
+		return err
 	}
 
 	// deprecated
@@ -242,6 +366,39 @@ func (imp *MongoImport) validateSettings() error {
 		log.Logvf(log.Info, "using upsert fields: %v", imp.upsertFields)
 	}
 
+	if err := imp.validateIdempotency(); err != nil {
+		return err
+	}
+
+	if err := imp.validateReconcile(); err != nil {
+		return err
+	}
+
+	if err := imp.validateArrayMerge(); err != nil {
+		return err
+	}
+
+	if err := imp.validateFiles(); err != nil {
+		return err
+	}
+
+	if err := imp.validateResume(); err != nil {
+		return err
+	}
+
+	if err := imp.validateFieldLimit(); err != nil {
+		return err
+	}
+
+	if err := imp.validateExecHooks(); err != nil {
+		return err
+	}
+
+	if imp.IngestOptions.MaxDocSizeBytes < 0 {
+		return fmt.Errorf("--maxDocSizeBytes must not be negative")
+	}
+	imp.validator = db.DocumentValidator{MaxSize: imp.IngestOptions.MaxDocSizeBytes}
+
 	if imp.IngestOptions.MaintainInsertionOrder {
 		imp.IngestOptions.StopOnError = true
 		imp.IngestOptions.NumInsertionWorkers = 1
@@ -266,11 +423,7 @@ func (imp *MongoImport) validateSettings() error {
 	// ensure we have a valid string to use for the collection
 	if imp.ToolOptions.Collection == "" {
 		log.Logvf(log.Always, "no collection specified")
-		fileBaseName := filepath.Base(imp.InputOptions.File)
-		lastDotIndex := strings.LastIndex(fileBaseName, ".")
-		if lastDotIndex != -1 {
-			fileBaseName = fileBaseName[0:lastDotIndex]
-		}
+		fileBaseName := collectionFromFileName(imp.InputOptions.File)
 		log.Logvf(log.Always, "using filename '%v' as collection", fileBaseName)
 		imp.ToolOptions.Collection = fileBaseName
 	}
@@ -319,46 +472,115 @@ func (fsp *fileSizeProgressor) Progress() (int64, int64) {
 // number of documents successfully imported to the appropriate namespace,
 // the number of failures, and any error encountered in doing this.
 func (imp *MongoImport) ImportDocuments() (uint64, uint64, error) {
+	if len(imp.files) > 0 {
+		return imp.importFiles()
+	}
+	return imp.importSource()
+}
+
+// importSource imports the documents of --file or stdin.
+func (imp *MongoImport) importSource() (uint64, uint64, error) {
 	source, fileSize, err := imp.getSourceReader()
 	if err != nil {
 		return 0, 0, err
 	}
 	defer source.Close()
 
-	inputReader, err := imp.getInputReader(source)
+	var input io.Reader = source
+	if imp.InputOptions.ResumeFile != "" {
+		defer func() { imp.resume = nil }()
+		input, err = imp.openResume(source, fileSize)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if imp.IngestOptions.ProgressStages {
+		imp.stages = &importStages{}
+		defer func() { imp.stages = nil }()
+	}
+
+	inputReader, err := imp.getHeaderedInputReader(input)
 	if err != nil {
 		return 0, 0, err
 	}
 
-	if imp.InputOptions.HeaderLine {
-		if imp.InputOptions.ColumnsHaveTypes {
-			err = inputReader.ReadAndValidateTypedHeader(ParsePG(imp.InputOptions.ParseGrace))
-		} else {
-			err = inputReader.ReadAndValidateHeader()
-		}
-		if err != nil {
+	var watching sizeTracker = inputReader
+	if imp.resume != nil {
+		if err := imp.resume.start(inputReader, source); err != nil {
 			return 0, 0, err
 		}
+		watching = imp.resume.progress(inputReader)
 	}
 
 	bar := &progress.Bar{
 		Name:      fmt.Sprintf("%v.%v", imp.ToolOptions.DB, imp.ToolOptions.Collection),
-		Watching:  &fileSizeProgressor{fileSize, inputReader},
+		Watching:  &fileSizeProgressor{fileSize, watching},
 		Writer:    log.Writer(0),
 		BarLength: progressBarLength,
 		IsBytes:   true,
 	}
 	bar.Start()
 	defer bar.Stop()
+	defer imp.stages.report(watching)()
+	processedCount, failureCount, err := imp.importDocuments(inputReader)
+	if imp.resume != nil {
+		if saveErr := imp.resume.save(); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return processedCount, failureCount, err
+}
+
+// ImportFrom imports the documents read from source, which holds data in the
+// format given by the InputOptions, in the same way as ImportDocuments does
+// for --file or stdin, but without showing a progress bar. It returns the
+// number of documents successfully imported, the number of failures, and any
+// error encountered. ImportFrom may be called again once it returns, for
+// example to import several sources into the same collection.
+func (imp *MongoImport) ImportFrom(source io.Reader) (uint64, uint64, error) {
+	inputReader, err := imp.getHeaderedInputReader(source)
+	if err != nil {
+		return 0, 0, err
+	}
 	return imp.importDocuments(inputReader)
 }
 
+// getHeaderedInputReader returns the InputReader for source, having read the
+// header line if there is one.
+func (imp *MongoImport) getHeaderedInputReader(source io.Reader) (InputReader, error) {
+	inputReader, err := imp.getInputReader(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if imp.InputOptions.HeaderLine {
+		if imp.InputOptions.ColumnsHaveTypes {
+			err = inputReader.ReadAndValidateTypedHeader(ParsePG(imp.InputOptions.ParseGrace))
+		} else {
+			err = inputReader.ReadAndValidateHeader()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return inputReader, nil
+}
+
 // importDocuments is a helper to ImportDocuments and does all the ingestion
 // work by taking data from the inputReader source and writing it to the
 // appropriate namespace. It returns the number of documents successfully
 // imported to the appropriate namespace, the number of failures, and any error
 // encountered in doing this.
-func (imp *MongoImport) importDocuments(inputReader InputReader) (uint64, uint64, error) {
+func (imp *MongoImport) importDocuments(
+	inputReader InputReader,
+) (numProcessed, numFailed uint64, retErr error) {
+	// start from a clean slate if a previous import already ran
+	atomic.StoreUint64(&imp.processedCount, 0)
+	atomic.StoreUint64(&imp.failureCount, 0)
+	imp.Tomb = tomb.Tomb{}
+	imp.writeErrors.reset()
+
 	session, err := imp.SessionProvider.GetSession()
 	if err != nil {
 		return 0, 0, err
@@ -382,7 +604,7 @@ func (imp *MongoImport) importDocuments(inputReader InputReader) (uint64, uint64
 	log.Logvf(log.Info, "connected to node type: %v", imp.nodeType)
 
 	// drop the database if necessary
-	if imp.IngestOptions.Drop {
+	if imp.IngestOptions.Drop && !imp.droppedCollections[imp.ToolOptions.Collection] {
 		log.Logvf(log.Always, "dropping: %v.%v",
 			imp.ToolOptions.DB,
 			imp.ToolOptions.Collection)
@@ -393,7 +615,31 @@ func (imp *MongoImport) importDocuments(inputReader InputReader) (uint64, uint64
 		}
 	}
 
+	// the --postExec commands clean up after the --preExec commands, so they
+	// run however the import ends once the first --preExec command has run
+	preExec := imp.preExec
+	if len(preExec) > 0 {
+		if err := imp.runExecHooks(session, preExec[:1], false); err != nil {
+			return 0, 0, err
+		}
+		preExec = preExec[1:]
+	}
+	defer func() {
+		if err := imp.runExecHooks(session, imp.postExec, true); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+	if err := imp.runExecHooks(session, preExec, false); err != nil {
+		return 0, 0, err
+	}
+
+	collection := session.Database(imp.ToolOptions.DB).Collection(imp.ToolOptions.Collection)
+	if err := imp.reconcile.start(collection); err != nil {
+		return 0, 0, err
+	}
+
 	readDocs := make(chan bson.D, workerBufferSize)
+	imp.stages.watchInsertQueue(readDocs)
 	processingErrChan := make(chan error)
 	ordered := imp.IngestOptions.MaintainInsertionOrder
 
@@ -408,8 +654,17 @@ func (imp *MongoImport) importDocuments(inputReader InputReader) (uint64, uint64
 	}()
 
 	e1 := channelQuorumError(processingErrChan)
+	imp.writeErrors.logSummary()
 	processedCount := atomic.LoadUint64(&imp.processedCount)
 	failureCount := atomic.LoadUint64(&imp.failureCount)
+	err = imp.reconcile.finish(collection, imp.InputOptions.File, processedCount, failureCount)
+	if err != nil {
+		if e1 == nil {
+			e1 = err
+		} else {
+			log.Logvf(log.Always, "%v", err)
+		}
+	}
 	return processedCount, failureCount, e1
 }
 
@@ -464,7 +719,13 @@ func (imp *MongoImport) runInsertionWorker(readDocs chan bson.D) (err error) {
 	inserter := db.NewUnorderedBufferedBulkInserter(collection, imp.IngestOptions.BulkBufferSize, serverVersion).
 		SetBypassDocumentValidation(imp.IngestOptions.BypassDocumentValidation).
 		SetOrdered(imp.IngestOptions.MaintainInsertionOrder).
-		SetUpsert(true)
+		SetUpsert(true).
+		SetIdempotentRetries(imp.IngestOptions.IdempotentRetries)
+
+	ordered := imp.IngestOptions.MaintainInsertionOrder
+	// the records of the documents written since the last bulk write, which a
+	// --resumeFile records as imported once it is acknowledged
+	var pending []resumeMark
 
 readLoop:
 	for {
@@ -473,8 +734,27 @@ readLoop:
 			if !alive {
 				break readLoop
 			}
-			err := imp.importDocument(inserter, document)
-			if db.FilterError(imp.IngestOptions.StopOnError, err) != nil {
+			var mark resumeMark
+			if imp.resume != nil {
+				document, mark = splitResumeMark(document)
+			}
+			added := inserter.Added()
+			err := imp.filterError(imp.importDocument(inserter, document))
+			if imp.resume != nil {
+				// a document skipped because of an error that the import
+				// goes on through isn't part of any write, and is done
+				switch {
+				case inserter.Added() != added:
+					pending = append(pending, mark)
+				case err == nil:
+					imp.resume.recordsDone([]resumeMark{mark})
+				}
+				if inserter.Buffered() == 0 {
+					imp.resume.batchDone(pending, err, ordered)
+					pending = pending[:0]
+				}
+			}
+			if err != nil {
 				return err
 			}
 		case <-imp.Dying():
@@ -483,58 +763,125 @@ readLoop:
 	}
 	result, err := inserter.Flush()
 	imp.updateCounts(result, err)
-	return db.FilterError(imp.IngestOptions.StopOnError, err)
+	err = imp.filterError(err)
+	if imp.resume != nil {
+		imp.resume.batchDone(pending, err, ordered)
+	}
+	return err
 }
 
 func (imp *MongoImport) updateCounts(result *mongo.BulkWriteResult, err error) {
 	if result != nil {
-		atomic.AddUint64(
-			&imp.processedCount,
-			uint64(
-				result.InsertedCount,
-			)+uint64(
-				result.ModifiedCount,
-			)+uint64(
-				result.UpsertedCount,
-			)+uint64(
-				result.DeletedCount,
-			),
-		)
+		written := result.InsertedCount + result.ModifiedCount +
+			result.UpsertedCount + result.DeletedCount
+		atomic.AddUint64(&imp.processedCount, uint64(written))
+		imp.stages.acknowledged(written)
 	}
 	if bwe, ok := err.(mongo.BulkWriteException); ok {
 		atomic.AddUint64(&imp.failureCount, uint64(len(bwe.WriteErrors)))
+		imp.reconcile.remove(bwe.WriteErrors)
+		if !imp.IngestOptions.StopOnError && db.CanIgnoreError(err) {
+			imp.writeErrors.record(bwe.WriteErrors)
+		}
+		if imp.DocumentErrorHandler != nil {
+			for _, writeErr := range bwe.WriteErrors {
+				imp.DocumentErrorHandler(writeErr)
+			}
+		}
+	}
+}
+
+// filterError returns the error of a write unless the import continues
+// through it. Like db.FilterError, except that the bulk write errors it
+// continues through are logged by the writeErrorReport instead.
+func (imp *MongoImport) filterError(err error) error {
+	if _, ok := err.(mongo.BulkWriteException); ok && !imp.IngestOptions.StopOnError &&
+		db.CanIgnoreError(err) {
+		return nil
 	}
+	return db.FilterError(imp.IngestOptions.StopOnError, err)
 }
 
 func (imp *MongoImport) importDocument(inserter *db.BufferedBulkInserter, document bson.D) error {
 	var result *mongo.BulkWriteResult
 	var err error
 
+	mode := imp.IngestOptions.Mode
+	if imp.IngestOptions.OpField != "" {
+		if document, mode, err = imp.takeOperation(document); err != nil {
+			atomic.AddUint64(&imp.failureCount, 1)
+			if imp.DocumentErrorHandler != nil {
+				imp.DocumentErrorHandler(err)
+			}
+			if imp.IngestOptions.StopOnError {
+				return err
+			}
+			log.Logvf(log.Always, "skipping %v", err)
+			return nil
+		}
+	}
+
+	if imp.fieldMap != nil {
+		if err = imp.fieldMap.Rename(document); err != nil {
+			return err
+		}
+	}
+	if imp.decrypter != nil {
+		if err = imp.decrypter.DecryptDocument(document); err != nil {
+			return err
+		}
+	}
+	if mode != modeDelete {
+		if err = imp.fieldLimiter.check(document); err != nil {
+			return err
+		}
+	}
 	selector := constructUpsertDocument(imp.upsertFields, document)
 
-	if imp.IngestOptions.Mode == modeInsert {
-		result, err = inserter.Insert(document)
-	} else if imp.IngestOptions.Mode == modeUpsert {
+	if mode == modeInsert {
+		if imp.idempotencyFields != nil {
+			document, err = withIdempotentID(imp.idempotencyFields, document)
+			if err != nil {
+				return err
+			}
+		}
+		var rawDocument bson.Raw
+		if rawDocument, err = imp.validateDocument(document); rawDocument == nil {
+			return err
+		}
+		imp.reconcile.add(rawDocument)
+		result, err = inserter.InsertRaw(rawDocument)
+	} else if mode == modeUpsert {
+		if rawDocument, err := imp.validateDocument(document); rawDocument == nil {
+			return err
+		}
 		if selector == nil {
 			result, err = imp.fallbackToInsert(inserter, document)
 		} else {
 			result, err = inserter.Replace(selector, document)
 		}
-	} else if imp.IngestOptions.Mode == modeMerge {
+	} else if mode == modeMerge {
+		if rawDocument, err := imp.validateDocument(document); rawDocument == nil {
+			return err
+		}
 		if selector == nil {
 			result, err = imp.fallbackToInsert(inserter, document)
 		} else {
-			updateDoc := bson.D{{"$set", document}}
-			result, err = inserter.Update(selector, updateDoc)
+			updateDoc, pipeline := mergeUpdate(document, imp.arrayMerges)
+			if pipeline != nil {
+				result, err = inserter.UpdateWithPipeline(selector, pipeline)
+			} else {
+				result, err = inserter.Update(selector, updateDoc)
+			}
 		}
-	} else if imp.IngestOptions.Mode == modeDelete {
+	} else if mode == modeDelete {
 		if selector == nil {
 			log.Logvf(log.Info, "Could not construct selector from %v, skipping document", imp.upsertFields)
 		} else {
 			result, err = inserter.Delete(selector, document)
 		}
 	} else {
-		err = fmt.Errorf("Invalid mode: %v", imp.IngestOptions.Mode)
+		err = fmt.Errorf("Invalid mode: %v", mode)
 	}
 
 	// Update success and failure counts
@@ -543,6 +890,28 @@ func (imp *MongoImport) importDocument(inserter *db.BufferedBulkInserter, docume
 	return err
 }
 
+// validateDocument returns document as BSON if it passes the validator. If
+// it doesn't, it counts it as a failure and returns nil, along with the
+// error if the import stops on errors.
+func (imp *MongoImport) validateDocument(document bson.D) (bson.Raw, error) {
+	rawDocument, err := bson.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("bson encoding error: %v", err)
+	}
+	if err = imp.validator.Validate(rawDocument); err == nil {
+		return rawDocument, nil
+	}
+	atomic.AddUint64(&imp.failureCount, 1)
+	if imp.DocumentErrorHandler != nil {
+		imp.DocumentErrorHandler(err)
+	}
+	if imp.IngestOptions.StopOnError {
+		return nil, err
+	}
+	log.Logvf(log.Always, "skipping %v", err)
+	return nil, nil
+}
+
 func (imp *MongoImport) fallbackToInsert(
 	inserter *db.BufferedBulkInserter,
 	document bson.D,
@@ -576,6 +945,32 @@ func splitInlineHeader(header string) (headers []string) {
 	return
 }
 
+// whitespacePolicy returns the WhitespacePolicy that the options set for every
+// column.
+func (imp *MongoImport) whitespacePolicy() WhitespacePolicy {
+	policy := WhitespacePolicy{
+		NonBreakingSpaces: imp.InputOptions.TrimNonBreakingSpaces,
+		StripBOMs:         imp.InputOptions.StripBOMs,
+	}
+	if imp.InputOptions.TrimFields {
+		policy.Trim = tmTrim
+	}
+	return policy
+}
+
+// timezone returns the location of --timezone, which ValidateSettings has
+// checked, or nil if it is not set.
+func (imp *MongoImport) timezone() *time.Location {
+	if imp.InputOptions.Timezone == "" {
+		return nil
+	}
+	location, err := time.LoadLocation(imp.InputOptions.Timezone)
+	if err != nil {
+		return nil
+	}
+	return location
+}
+
 // getInputReader returns an implementation of InputReader based on the input type.
 func (imp *MongoImport) getInputReader(in io.Reader) (InputReader, error) {
 	var colSpecs []ColumnSpec
@@ -597,33 +992,90 @@ func (imp *MongoImport) getInputReader(in io.Reader) (InputReader, error) {
 	} else {
 		colSpecs = ParseAutoHeaders(headers)
 	}
+	whitespace := imp.whitespacePolicy()
+	applyWhitespacePolicy(colSpecs, whitespace)
+	timezone := imp.timezone()
+	applyTimezone(colSpecs, timezone)
+	headerPolicy := ParseHP(imp.InputOptions.HeaderPolicy)
 
 	// header fields validation can only happen once we have an input reader
 	if !imp.InputOptions.HeaderLine {
+		applyHeaderPolicy(colSpecs, headerPolicy, imp.InputOptions.UseArrayIndexFields)
 		if err = validateReaderFields(ColumnNames(colSpecs), imp.InputOptions.UseArrayIndexFields); err != nil {
 			return nil, err
 		}
 	}
 
-	out := os.Stdout
+	var out io.Writer = os.Stdout
+	if imp.RejectWriter != nil {
+		out = imp.RejectWriter
+	}
 
 	ignoreBlanks := imp.IngestOptions.IgnoreBlanks && imp.InputOptions.Type != JSON
+	columnsMismatchPolicy := ParseCM(imp.InputOptions.ColumnsMismatchPolicy)
 	if imp.InputOptions.Type == CSV {
-		return NewCSVInputReader(
+		r := NewCSVInputReader(
 			colSpecs,
 			in,
 			out,
 			imp.IngestOptions.NumDecodingWorkers,
 			ignoreBlanks,
 			imp.InputOptions.UseArrayIndexFields,
-		), nil
+			columnsMismatchPolicy,
+		)
+		r.documentErrorHandler = imp.DocumentErrorHandler
+		r.whitespace = whitespace
+		r.headerPolicy = headerPolicy
+		r.timezone = timezone
+		if imp.resume != nil {
+			r.resume = imp.resume
+			r.numProcessed = imp.resume.records()
+		}
+		r.stages = imp.stages
+		return r, nil
 	} else if imp.InputOptions.Type == TSV {
-		return NewTSVInputReader(colSpecs, in, out, imp.IngestOptions.NumDecodingWorkers, ignoreBlanks, imp.InputOptions.UseArrayIndexFields), nil
+		r := NewTSVInputReader(
+			colSpecs,
+			in,
+			out,
+			imp.IngestOptions.NumDecodingWorkers,
+			ignoreBlanks,
+			imp.InputOptions.UseArrayIndexFields,
+			columnsMismatchPolicy,
+		)
+		r.documentErrorHandler = imp.DocumentErrorHandler
+		r.whitespace = whitespace
+		r.headerPolicy = headerPolicy
+		r.timezone = timezone
+		if imp.resume != nil {
+			r.resume = imp.resume
+			r.numProcessed = imp.resume.records()
+		}
+		r.stages = imp.stages
+		return r, nil
+	} else if imp.InputOptions.Type == PROTOBUF {
+		r := NewProtobufInputReader(
+			imp.protobufMessage,
+			in,
+			imp.IngestOptions.NumDecodingWorkers,
+		)
+		if imp.resume != nil {
+			r.resume = imp.resume
+			r.numProcessed = imp.resume.records()
+		}
+		r.stages = imp.stages
+		return r, nil
 	}
-	return NewJSONInputReader(
+	r := NewJSONInputReader(
 		imp.InputOptions.JSONArray,
 		imp.InputOptions.Legacy,
 		in,
 		imp.IngestOptions.NumDecodingWorkers,
-	), nil
+	)
+	if imp.resume != nil {
+		r.resume = imp.resume
+		r.numProcessed = imp.resume.records()
+	}
+	r.stages = imp.stages
+	return r, nil
 }
@@ -15,6 +15,7 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/mongodb/mongo-tools/common/db"
@@ -24,6 +25,7 @@ import (
 	"github.com/mongodb/mongo-tools/common/util"
 	. "github.com/smartystreets/goconvey/convey"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	mopt "go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -246,6 +248,23 @@ func TestMongoImportValidateSettings(t *testing.T) {
 			So(imp.validateSettings(), ShouldBeNil)
 		})
 
+		Convey("an error should be thrown if an invalid --columnsMismatchPolicy is given", func() {
+			imp := NewMockMongoImport()
+			fields := "a,b,c"
+			imp.InputOptions.Fields = &fields
+			imp.InputOptions.Type = CSV
+			imp.InputOptions.ColumnsMismatchPolicy = "padNull"
+			So(imp.validateSettings(), ShouldBeNil)
+			imp.InputOptions.ColumnsMismatchPolicy = "invalid"
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("an error should be thrown if --columnsMismatchPolicy is used with JSON input", func() {
+			imp := NewMockMongoImport()
+			imp.InputOptions.ColumnsMismatchPolicy = "error"
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
 		Convey("an error should be thrown if --headerline is used with JSON input", func() {
 			imp := NewMockMongoImport()
 			imp.InputOptions.HeaderLine = true
@@ -427,6 +446,16 @@ func TestMongoImportValidateSettings(t *testing.T) {
 				So(imp.validateSettings(), ShouldNotBeNil)
 			},
 		)
+
+		Convey("--maxDocSizeBytes should set the limit of the validator", func() {
+			imp := NewMockMongoImport()
+			imp.IngestOptions.MaxDocSizeBytes = 1024
+			So(imp.validateSettings(), ShouldBeNil)
+			So(imp.validator.MaxSize, ShouldEqual, 1024)
+
+			imp.IngestOptions.MaxDocSizeBytes = -1
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
 	})
 }
 
@@ -719,6 +748,45 @@ func TestImportDocuments(t *testing.T) {
 				So(checkOnlyHasDocuments(imp.SessionProvider, expectedDocuments), ShouldBeNil)
 			},
 		)
+		Convey("ImportFrom should import from a reader and report failed documents", func() {
+			imp, err := NewMongoImport()
+			So(err, ShouldBeNil)
+			imp.IngestOptions.Mode = modeInsert
+			imp.InputOptions.Type = CSV
+			imp.InputOptions.HeaderLine = true
+			imp.InputOptions.ColumnsHaveTypes = true
+			imp.InputOptions.ParseGrace = "skipRow"
+			imp.IngestOptions.StopOnError = false
+			rejects := &strings.Builder{}
+			imp.RejectWriter = rejects
+			var handled []error
+			var mu sync.Mutex
+			imp.DocumentErrorHandler = func(err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				handled = append(handled, err)
+			}
+
+			input := "_id.int32(),b.auto()\n1,a\n2,b\n1,c\nx,d\n"
+			numProcessed, numFailed, err := imp.ImportFrom(strings.NewReader(input))
+			So(err, ShouldBeNil)
+			So(numProcessed, ShouldEqual, 2)
+			So(numFailed, ShouldEqual, 1)
+			So(rejects.String(), ShouldEqual, "x,d\n")
+
+			So(len(handled), ShouldEqual, 2)
+			var skipped, duplicates int
+			for _, err := range handled {
+				switch err.(type) {
+				case SkippedRowError:
+					skipped++
+				case mongo.BulkWriteError:
+					duplicates++
+				}
+			}
+			So(skipped, ShouldEqual, 1)
+			So(duplicates, ShouldEqual, 1)
+		})
 		Convey("no error should be thrown for CSV import on test data with --drop", func() {
 			imp, err := NewMongoImport()
 			So(err, ShouldBeNil)
@@ -1099,6 +1167,17 @@ func TestImportDocuments(t *testing.T) {
 				nil,
 			),
 		)
+		Convey(
+			"With --useArrayIndexFields: Repeated [] fields should be collapsed into an array, skipping blanks",
+			nestedFieldsTestHelper(
+				"_id,tags[],tags[],tags[]\n1,a,,b\n2,,,",
+				[]bson.M{
+					{"_id": int32(1), "tags": bson.A{"a", "b"}},
+					{"_id": int32(2)},
+				},
+				nil,
+			),
+		)
 		Convey(
 			"With --useArrayIndexFields: If an array with more than 10 fields should be inserted",
 			nestedFieldsTestHelper(
@@ -0,0 +1,178 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+)
+
+// collectionPlaceholder marks the collection name in a --collectionTemplate.
+const collectionPlaceholder = "{collection}"
+
+// inputFile is a file of --file and the collection it is imported into.
+type inputFile struct {
+	path       string
+	collection string
+}
+
+// isGlobPattern returns whether path has any of the special characters of
+// filepath.Match.
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// validateFiles expands the glob patterns of --file and works out the
+// collection of each file. A single file is imported as File; several are
+// imported in turn by ImportDocuments.
+func (imp *MongoImport) validateFiles() error {
+	template := imp.InputOptions.CollectionTemplate
+	var templateRegexp *regexp.Regexp
+	if template != "" {
+		if imp.ToolOptions.Collection != "" {
+			return fmt.Errorf("cannot use --collectionTemplate with --collection")
+		}
+		if imp.InputOptions.File == "" && len(imp.InputOptions.Files) == 0 {
+			return fmt.Errorf("--collectionTemplate requires --file")
+		}
+		var err error
+		if templateRegexp, err = compileCollectionTemplate(template); err != nil {
+			return err
+		}
+	}
+
+	paths := []string{imp.InputOptions.File}
+	if len(imp.InputOptions.Files) > 0 {
+		var err error
+		if paths, err = expandFilePatterns(imp.InputOptions.Files); err != nil {
+			return err
+		}
+	}
+	if len(paths) > 1 && imp.InputOptions.ResumeFile != "" {
+		return fmt.Errorf("cannot use --resumeFile with more than one --file")
+	}
+
+	files := make([]inputFile, len(paths))
+	for i, path := range paths {
+		files[i] = inputFile{path: path, collection: imp.ToolOptions.Collection}
+		if templateRegexp == nil {
+			continue
+		}
+		name := filepath.Base(path)
+		match := templateRegexp.FindStringSubmatch(name)
+		if match == nil {
+			return fmt.Errorf("--file %v does not match --collectionTemplate %v", name, template)
+		}
+		files[i].collection = match[1]
+	}
+	if len(files) > 1 {
+		for i := range files {
+			if files[i].collection == "" {
+				files[i].collection = collectionFromFileName(files[i].path)
+			}
+			if err := util.ValidateCollectionName(files[i].collection); err != nil {
+				return fmt.Errorf("invalid collection name for %v: %v", files[i].path, err)
+			}
+		}
+		imp.files = files
+	}
+	// the first file stands for the import until ImportDocuments starts
+	imp.InputOptions.File = files[0].path
+	imp.ToolOptions.Collection = files[0].collection
+	return nil
+}
+
+// expandFilePatterns returns the files matching patterns, in order and
+// without duplicates. A path without special characters is kept as it is, so
+// that a missing file is reported when it is opened.
+func expandFilePatterns(patterns []string) ([]string, error) {
+	var paths []string
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if isGlobPattern(pattern) {
+			var err error
+			matches, err = filepath.Glob(util.ToUniversalPath(pattern))
+			if err != nil {
+				return nil, fmt.Errorf("invalid --file pattern %v: %v", pattern, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("--file %v matches no files", pattern)
+			}
+		}
+		for _, path := range matches {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// compileCollectionTemplate returns a regular expression matching the file
+// names of template, whose only group is the collection name.
+func compileCollectionTemplate(template string) (*regexp.Regexp, error) {
+	if strings.Count(template, collectionPlaceholder) != 1 {
+		return nil, fmt.Errorf(
+			"--collectionTemplate %v must contain %v exactly once",
+			template,
+			collectionPlaceholder,
+		)
+	}
+	prefix, suffix, _ := strings.Cut(template, collectionPlaceholder)
+	quote := func(part string) string {
+		literals := strings.Split(part, "*")
+		for i := range literals {
+			literals[i] = regexp.QuoteMeta(literals[i])
+		}
+		return strings.Join(literals, ".*")
+	}
+	return regexp.Compile("^" + quote(prefix) + "(.+)" + quote(suffix) + "$")
+}
+
+// collectionFromFileName returns the name of the file at path without its
+// extension, which is the collection of a file by default.
+func collectionFromFileName(path string) string {
+	name := filepath.Base(path)
+	if i := strings.LastIndex(name, "."); i != -1 {
+		name = name[:i]
+	}
+	return name
+}
+
+// importFiles imports the files of --file in order, each into its
+// collection, using the same connections and workers. It stops at the first
+// file that fails, and returns the counts of all of the files.
+func (imp *MongoImport) importFiles() (uint64, uint64, error) {
+	imp.droppedCollections = map[string]bool{}
+	defer func() { imp.droppedCollections = nil }()
+
+	var processed, failed uint64
+	for i, file := range imp.files {
+		imp.InputOptions.File = file.path
+		imp.ToolOptions.Collection = file.collection
+		log.Logvf(log.Always, "importing file %v of %v, %v, into %v.%v",
+			i+1, len(imp.files), file.path, imp.ToolOptions.DB, file.collection)
+
+		fileProcessed, fileFailed, err := imp.importSource()
+		processed += fileProcessed
+		failed += fileFailed
+		imp.droppedCollections[file.collection] = true
+		if err != nil {
+			return processed, failed, fmt.Errorf("error importing %v: %v", file.path, err)
+		}
+		log.Logvf(log.Info, "imported %v document(s) of %v, %v failed",
+			fileProcessed, file.path, fileFailed)
+	}
+	return processed, failed, nil
+}
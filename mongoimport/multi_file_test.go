@@ -0,0 +1,151 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMultipleFileOptions(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With --file given", t, func() {
+		Convey("once, it is the input file", func() {
+			opts, err := ParseOptions([]string{"--file=orders.json"}, "", "")
+			So(err, ShouldBeNil)
+			So(opts.File, ShouldEqual, "orders.json")
+			So(opts.Files, ShouldBeEmpty)
+		})
+
+		Convey("more than once or with a pattern, the files are kept to expand", func() {
+			opts, err := ParseOptions([]string{"--file=a.json", "--file=b.json"}, "", "")
+			So(err, ShouldBeNil)
+			So(opts.File, ShouldEqual, "")
+			So(opts.Files, ShouldResemble, []string{"a.json", "b.json"})
+
+			opts, err = ParseOptions([]string{"--file=*.json"}, "", "")
+			So(err, ShouldBeNil)
+			So(opts.Files, ShouldResemble, []string{"*.json"})
+		})
+
+		Convey("it cannot be used with a positional file", func() {
+			_, err := ParseOptions([]string{"--file=a.json", "--file=b.json", "c.json"}, "", "")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestValidateFiles(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With files to import", t, func() {
+		dir := t.TempDir()
+		for _, name := range []string{"orders-2023.csv", "orders-2024.csv", "users-2024.csv"} {
+			So(os.WriteFile(filepath.Join(dir, name), []byte("a\n1\n"), 0o644), ShouldBeNil)
+		}
+		imp := NewMockMongoImport()
+		imp.ToolOptions.Collection = ""
+
+		Convey("patterns are expanded in order and without duplicates", func() {
+			imp.InputOptions.Files = []string{
+				filepath.Join(dir, "users-*.csv"),
+				filepath.Join(dir, "*.csv"),
+			}
+			So(imp.validateFiles(), ShouldBeNil)
+			So(imp.files, ShouldResemble, []inputFile{
+				{filepath.Join(dir, "users-2024.csv"), "users-2024"},
+				{filepath.Join(dir, "orders-2023.csv"), "orders-2023"},
+				{filepath.Join(dir, "orders-2024.csv"), "orders-2024"},
+			})
+			So(imp.InputOptions.File, ShouldEqual, filepath.Join(dir, "users-2024.csv"))
+			So(imp.ToolOptions.Collection, ShouldEqual, "users-2024")
+		})
+
+		Convey("a template names the collection of each file", func() {
+			imp.InputOptions.Files = []string{filepath.Join(dir, "*.csv")}
+			imp.InputOptions.CollectionTemplate = "{collection}-*.csv"
+			So(imp.validateFiles(), ShouldBeNil)
+			So(imp.files, ShouldResemble, []inputFile{
+				{filepath.Join(dir, "orders-2023.csv"), "orders"},
+				{filepath.Join(dir, "orders-2024.csv"), "orders"},
+				{filepath.Join(dir, "users-2024.csv"), "users"},
+			})
+		})
+
+		Convey("a template names the collection of a single file", func() {
+			imp.InputOptions.File = filepath.Join(dir, "users-2024.csv")
+			imp.InputOptions.CollectionTemplate = "{collection}-*.csv"
+			So(imp.validateFiles(), ShouldBeNil)
+			So(imp.files, ShouldBeEmpty)
+			So(imp.ToolOptions.Collection, ShouldEqual, "users")
+		})
+
+		Convey("--collection imports every file into the same collection", func() {
+			imp.ToolOptions.Collection = "all"
+			imp.InputOptions.Files = []string{filepath.Join(dir, "orders-*.csv")}
+			So(imp.validateFiles(), ShouldBeNil)
+			So(len(imp.files), ShouldEqual, 2)
+			for _, file := range imp.files {
+				So(file.collection, ShouldEqual, "all")
+			}
+		})
+
+		Convey("invalid settings are rejected", func() {
+			Convey("a pattern matching nothing", func() {
+				imp.InputOptions.Files = []string{filepath.Join(dir, "*.json")}
+				So(imp.validateFiles(), ShouldNotBeNil)
+			})
+			Convey("a file not matching the template", func() {
+				imp.InputOptions.Files = []string{filepath.Join(dir, "*.csv")}
+				imp.InputOptions.CollectionTemplate = "{collection}-2024.csv"
+				So(imp.validateFiles(), ShouldNotBeNil)
+			})
+			Convey("a template with --collection", func() {
+				imp.ToolOptions.Collection = "all"
+				imp.InputOptions.File = filepath.Join(dir, "users-2024.csv")
+				imp.InputOptions.CollectionTemplate = "{collection}-*.csv"
+				So(imp.validateFiles(), ShouldNotBeNil)
+			})
+			Convey("a template without a file", func() {
+				imp.InputOptions.CollectionTemplate = "{collection}.csv"
+				So(imp.validateFiles(), ShouldNotBeNil)
+			})
+			Convey("--resumeFile with several files", func() {
+				imp.InputOptions.Files = []string{filepath.Join(dir, "*.csv")}
+				imp.InputOptions.ResumeFile = filepath.Join(dir, "resume.json")
+				So(imp.validateFiles(), ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestCompileCollectionTemplate(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("A collection template", t, func() {
+		Convey("matches the collection and wildcards of file names", func() {
+			re, err := compileCollectionTemplate("export.{collection}.*.json")
+			So(err, ShouldBeNil)
+			So(re.FindStringSubmatch("export.daily.orders.2024-01.json")[1], ShouldEqual,
+				"daily.orders")
+			So(re.MatchString("export.orders.json"), ShouldBeFalse)
+			So(re.MatchString("exportXorders.2024.json"), ShouldBeFalse)
+		})
+
+		Convey("must name the collection exactly once", func() {
+			_, err := compileCollectionTemplate("orders-*.csv")
+			So(err, ShouldNotBeNil)
+			_, err = compileCollectionTemplate("{collection}-{collection}.csv")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
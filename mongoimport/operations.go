@@ -0,0 +1,101 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// operations are the values of the --opField of a document, case-insensitive,
+// and whether they insert, update or delete it. The single letters are those
+// of common change data capture exports.
+var operations = map[string]string{
+	"insert": modeInsert,
+	"i":      modeInsert,
+	"update": modeUpsert,
+	"u":      modeUpsert,
+	"delete": modeDelete,
+	"d":      modeDelete,
+}
+
+// validateOpField checks --opField. The --mode, upsert by default, is how the
+// update operations are applied.
+func (imp *MongoImport) validateOpField() error {
+	field := imp.IngestOptions.OpField
+	if field == "" {
+		return nil
+	}
+	if field == "_id" || strings.Contains(field, ".") || strings.HasPrefix(field, "$") {
+		return fmt.Errorf("--opField must be a top-level field other than _id, not '%v'", field)
+	}
+	if imp.IngestOptions.Upsert {
+		imp.IngestOptions.Mode = modeUpsert
+	}
+	switch imp.IngestOptions.Mode {
+	case "":
+		imp.IngestOptions.Mode = modeUpsert
+	case modeUpsert, modeMerge:
+	default:
+		return fmt.Errorf(
+			"cannot use --opField with --mode=%v; --mode=upsert or --mode=merge sets how "+
+				"the update operations are applied",
+			imp.IngestOptions.Mode,
+		)
+	}
+	for _, upsertField := range strings.Split(imp.IngestOptions.UpsertFields, ",") {
+		if upsertField == field {
+			return fmt.Errorf("--opField %v cannot be one of the --upsertFields", field)
+		}
+	}
+	return nil
+}
+
+// takeOperation removes the --opField from document, and returns the mode
+// that its value applies document with. Update operations are applied with
+// the --mode.
+func (imp *MongoImport) takeOperation(document bson.D) (bson.D, string, error) {
+	field := imp.IngestOptions.OpField
+	for i, elem := range document {
+		if elem.Key != field {
+			continue
+		}
+		document = append(document[:i:i], document[i+1:]...)
+		value, ok := elem.Value.(string)
+		mode := operations[strings.ToLower(value)]
+		if !ok || mode == "" {
+			return nil, "", fmt.Errorf(
+				"invalid operation %v in --opField %v of document %v; "+
+					"must be insert, update or delete",
+				elem.Value,
+				field,
+				selectorString(document),
+			)
+		}
+		if mode == modeUpsert {
+			mode = imp.IngestOptions.Mode
+		}
+		return document, mode, nil
+	}
+	return nil, "", fmt.Errorf(
+		"document %v has no --opField %v",
+		selectorString(document),
+		field,
+	)
+}
+
+// selectorString describes a document by its _id, for errors.
+func selectorString(document bson.D) string {
+	for _, elem := range document {
+		if elem.Key == "_id" {
+			return fmt.Sprintf("with _id %v", elem.Value)
+		}
+	}
+	return "without an _id"
+}
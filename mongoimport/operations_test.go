@@ -0,0 +1,82 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestOpField(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With --opField", t, func() {
+		imp := NewMockMongoImport()
+		imp.IngestOptions.OpField = "_op"
+
+		Convey("updates are upserts by _id, imported in order", func() {
+			So(imp.validateSettings(), ShouldBeNil)
+			So(imp.IngestOptions.Mode, ShouldEqual, modeUpsert)
+			So(imp.upsertFields, ShouldResemble, []string{"_id"})
+			So(imp.IngestOptions.MaintainInsertionOrder, ShouldBeTrue)
+		})
+
+		Convey("updates can be merges", func() {
+			imp.IngestOptions.Mode = modeMerge
+			So(imp.validateSettings(), ShouldBeNil)
+			So(imp.IngestOptions.Mode, ShouldEqual, modeMerge)
+		})
+
+		Convey("--mode cannot be insert or delete", func() {
+			imp.IngestOptions.Mode = modeInsert
+			So(imp.validateSettings(), ShouldNotBeNil)
+			imp.IngestOptions.Mode = modeDelete
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("the field must be top-level and not an upsert field", func() {
+			for _, field := range []string{"_id", "change.op", "$op"} {
+				imp.IngestOptions.OpField = field
+				So(imp.validateOpField(), ShouldNotBeNil)
+			}
+			imp.IngestOptions.OpField = "_op"
+			imp.IngestOptions.UpsertFields = "sku,_op"
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("the operation of each document is taken from it", func() {
+			imp.IngestOptions.Mode = modeMerge
+			So(imp.validateSettings(), ShouldBeNil)
+			for value, expected := range map[string]string{
+				"insert": modeInsert,
+				"I":      modeInsert,
+				"Update": modeMerge,
+				"u":      modeMerge,
+				"DELETE": modeDelete,
+				"d":      modeDelete,
+			} {
+				original := bson.D{{"_id", 1}, {"_op", value}, {"qty", 3}}
+				document, mode, err := imp.takeOperation(original)
+				So(err, ShouldBeNil)
+				So(mode, ShouldEqual, expected)
+				So(document, ShouldResemble, bson.D{{"_id", 1}, {"qty", 3}})
+				So(original[1].Key, ShouldEqual, "_op")
+			}
+
+			_, _, err := imp.takeOperation(bson.D{{"_id", 1}, {"_op", "upsert"}})
+			So(err, ShouldNotBeNil)
+			_, _, err = imp.takeOperation(bson.D{{"_id", 1}, {"_op", 1}})
+			So(err, ShouldNotBeNil)
+			_, _, err = imp.takeOperation(bson.D{{"_id", 1}})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "with _id 1 has no --opField _op")
+		})
+	})
+}
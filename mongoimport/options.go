@@ -16,7 +16,7 @@ import (
 
 var Usage = `<options> <connection-string> <file> 
 
-Import CSV, TSV or JSON data into MongoDB. If no file is provided, mongoimport reads from stdin.
+Import CSV, TSV, JSON or protobuf data into MongoDB. If no file is provided, mongoimport reads from stdin.
 
 Connection strings must begin with mongodb:// or mongodb+srv://.
 
@@ -31,7 +31,13 @@ type InputOptions struct {
 	FieldFile *string `long:"fieldFile" value-name:"<filename>" description:"file with field names - 1 per line"`
 
 	// Specifies the location and name of a file containing the data to import.
-	File string `long:"file" value-name:"<filename>" description:"file to import from; if not specified, stdin is used"`
+	File string `no-flag:"true"`
+
+	// Specifies the files, or glob patterns of files, to import. A single file is set as File.
+	Files []string `long:"file" value-name:"<filename>" description:"file to import from; if not specified, stdin is used. May be a glob pattern, e.g. 'orders-*.csv', and may be specified multiple times to import several files in one run, each in turn and with the same options"`
+
+	// Derives the collection of each file from its name.
+	CollectionTemplate string `long:"collectionTemplate" value-name:"<template>" description:"import each --file into the collection named by the part of its file name matching {collection} in a template in which * matches any text, e.g. '{collection}-*.csv' imports orders-2024.csv into orders. Without it, files are imported into --collection, or else into the collection named after each file without its extension"`
 
 	// Treats the input source's first line as field list (csv and tsv only).
 	HeaderLine bool `long:"headerline" description:"use first line in input source as the field list (CSV and TSV only)"`
@@ -42,16 +48,55 @@ type InputOptions struct {
 	// Indicates how to handle type coercion failures
 	ParseGrace string `long:"parseGrace" value-name:"<grace>" default:"stop" description:"controls behavior when type coercion fails - one of: autoCast, skipField, skipRow, stop"`
 
-	// Specifies the file type to import. The default format is JSON, but it’s possible to import CSV and TSV files.
-	Type string `long:"type" value-name:"<type>" default:"json" default-mask:"-" description:"input format to import: json, csv, or tsv"`
+	// Indicates how to handle CSV and TSV rows whose field count differs from the number of columns
+	ColumnsMismatchPolicy string `long:"columnsMismatchPolicy" value-name:"<policy>" description:"controls behavior when a CSV or TSV row has more or fewer fields than there are columns - one of: error, padNull (fill missing fields with null), truncate (drop extra fields), skipRow. By default extra fields are imported as 'field<N>' and missing fields are omitted"`
+
+	// Indicates how to handle CSV and TSV column names that are invalid or repeated
+	HeaderPolicy string `long:"headerPolicy" value-name:"<policy>" description:"controls what happens to CSV and TSV column names that are not valid field names or that repeat another column's name - one of: strict (fail the import, the default), sanitize (replace whitespace with underscores, remove dots and dollar signs, name empty columns 'field<N>' and add _2, _3, ... to repeated names). Every renamed column is logged"`
+
+	// Specifies the file type to import. The default format is JSON, but it’s possible to import CSV, TSV and protobuf files.
+	Type string `long:"type" value-name:"<type>" default:"json" default-mask:"-" description:"input format to import: json, csv, tsv, or protobuf. Protobuf input is a stream of messages that each start with their size as a varint, like the ones written by writeDelimitedTo, decoded with --descriptorSet and --messageType. Fields are named as in the .proto file, map fields are imported as subdocuments, enums as the names of their values, bytes as binary, and 64 bit unsigned integers that do not fit an int64 as decimals"`
+
+	// Specifies the file with the descriptors of the protobuf messages to import.
+	DescriptorSet string `long:"descriptorSet" value-name:"<filename>" description:"file with the FileDescriptorSet that describes the messages of --type=protobuf, as written by protoc --descriptor_set_out; use --include_imports if the message uses types of other .proto files"`
+
+	// Names the protobuf message type of the input.
+	MessageType string `long:"messageType" value-name:"<name>" description:"fully qualified name of the message type of --type=protobuf input, e.g. events.v1.PageView"`
 
 	// Indicates that field names include type descriptions
-	ColumnsHaveTypes bool `long:"columnsHaveTypes" description:"indicates that the field list (from --fields, --fieldsFile, or --headerline) specifies types; They must be in the form of '<colName>.<type>(<arg>)'. The type can be one of: auto, binary, boolean, date, date_go, date_ms, date_oracle, decimal, double, int32, int64, string. For each of the date types, the argument is a datetime layout string. For the binary type, the argument can be one of: base32, base64, hex. All other types take an empty argument. Only valid for CSV and TSV imports. e.g. zipcode.string(), thumbnail.binary(base64)"`
+	ColumnsHaveTypes bool `long:"columnsHaveTypes" description:"indicates that the field list (from --fields, --fieldsFile, or --headerline) specifies types; They must be in the form of '<colName>.<type>(<arg>)'. The type can be one of: auto, binary, boolean, date, date_go, date_ms, date_oracle, dbref, decimal, double, int32, int64, minmaxkey, string, timestamp. For each of the date types, the argument is a datetime layout string. For the binary type, the argument can be one of: base32, base64, hex. For the string type, the argument can be empty or one of: trim, ltrim, rtrim, to remove whitespace from both ends, the start, or the end of the field. Timestamps are written as <seconds>,<increment>, and minmaxkey fields as minKey or maxKey. A DBRef is built from three columns of the same field with the arguments ref, id and db, in that order; db is optional, and id can be given a type as id:<type> (e.g. id:objectid). All other types take an empty argument. Only valid for CSV and TSV imports. e.g. zipcode.string(), name.string(trim), thumbnail.binary(base64), owner.dbref(ref), owner.dbref(id:objectid)"`
+
+	// Indicates that whitespace should be removed from both ends of every CSV and TSV field
+	TrimFields bool `long:"trimFields" description:"remove whitespace from both ends of every field in CSV and TSV, unless its type specifies another trim modifier"`
+
+	// Indicates that non-breaking spaces count as whitespace when trimming fields
+	TrimNonBreakingSpaces bool `long:"trimNonBreakingSpaces" description:"treat non-breaking spaces (U+00A0, U+2007, U+202F) as whitespace when trimming CSV and TSV fields"`
+
+	// Indicates that byte order marks should be removed from CSV and TSV fields
+	StripBOMs bool `long:"stripBOMs" description:"remove byte order marks (U+FEFF) from every field in CSV and TSV. A byte order mark at the start of the input is always removed"`
+
+	// Specifies the location of the dates without a time zone in typed CSV and TSV columns
+	Timezone string `long:"timezone" value-name:"<location>" description:"IANA time zone, e.g. America/New_York, or Local, in which the date, date_go, date_ms and date_oracle columns of --columnsHaveTypes read dates that have no time zone of their own. By default such dates are UTC"`
+
+	// Lists the fields whose values were encrypted by mongoexport --encryptFields
+	DecryptFields string `long:"decryptFields" value-name:"<field>[,<field>]*" description:"comma separated list of fields whose values were encrypted by mongoexport --encryptFields, to decrypt with the key in --encryptionKeyFile before importing"`
+
+	// Holds the base64 encoded key for DecryptFields
+	EncryptionKeyFile string `long:"encryptionKeyFile" value-name:"<filename>" description:"file with the base64 encoded 32 byte key for --decryptFields"`
+
+	// Specifies a file mapping the field names of the collection to those of the input
+	FieldMap string `long:"fieldMap" value-name:"<filename>" description:"YAML or JSON file mapping field names of the collection to the names read from the input, e.g. 'address.zipCode: address.postal_code', where each part of a dotted path is renamed; the input fields are renamed to the names of the collection, which the other options use. Takes the same file as mongoexport --fieldMap"`
+
+	// Specifies a file to keep the position of the last imported record in, for Resume.
+	ResumeFile string `long:"resumeFile" value-name:"<filename>" description:"file in which to save the byte offset and record number up to which every record of --file was imported, while the import runs and when it stops. Only for --file, and not with --jsonArray"`
+
+	// Indicates that the import should skip the records before the position saved in ResumeFile.
+	Resume bool `long:"resume" description:"skip the records of --file up to the position saved in --resumeFile by a previous import that stopped. Records imported after the file was last saved are imported again, so they may be duplicated unless they have an _id or --mode=upsert is used"`
 
 	// Indicates that the legacy extended JSON format should be used to parse JSON documents. Defaults to false.
 	Legacy bool `long:"legacy" description:"use the legacy extended JSON format"`
 
-	UseArrayIndexFields bool `long:"useArrayIndexFields" description:"indicates that field names may include array indexes that should be used to construct arrays during import (e.g. foo.0,foo.1). Indexes must start from 0 and increase sequentially (foo.1,foo.0 would fail)."`
+	UseArrayIndexFields bool `long:"useArrayIndexFields" description:"indicates that field names may include array indexes that should be used to construct arrays during import (e.g. foo.0,foo.1). Indexes must start from 0 and increase sequentially (foo.1,foo.0 would fail). Repeated fields ending in [] (e.g. foo[],foo[]) are also collapsed into an array, skipping empty cells; with --ignoreBlanks, empty cells in indexed fields, including those at the end of an array, are null so that the other elements keep their indexes."`
 }
 
 // Name returns a description of the InputOptions struct.
@@ -73,6 +118,9 @@ type IngestOptions struct {
 	// Sets the number of insertion routines to use
 	NumInsertionWorkers int `short:"j" value-name:"<number>" long:"numInsertionWorkers" description:"number of insert operations to run concurrently" default:"1" default-mask:"-"`
 
+	// Logs the throughput of each stage of the import next to the progress bar.
+	ProgressStages bool `long:"progressStages" description:"every few seconds, log how fast the input is read and parsed into records, the records are converted into documents, and the server acknowledges their inserts, with the depths of the queues between these stages, to show whether the input, the CPU or the server holds back the import, e.g. to tune --numInsertionWorkers"`
+
 	// Forces mongoimport to halt the import operation at the first insert or upsert error.
 	StopOnError bool `long:"stopOnError" description:"halt after encountering any error during importing. By default, mongoimport will attempt to continue through document validation and DuplicateKey errors, but with this option enabled, the tool will stop instead. A small number of documents may be inserted after encountering an error even with this option enabled; use --maintainInsertionOrder to halt immediately after an error"`
 
@@ -87,11 +135,39 @@ type IngestOptions struct {
 	//nolint:staticcheck
 	Mode string `long:"mode" choice:"insert" choice:"upsert" choice:"merge" choice:"delete" description:"insert: insert only, skips matching documents. upsert: insert new documents or replace existing documents. merge: insert new documents or modify existing documents. delete: deletes matching documents only. If upsert fields match more than one document, only one document is deleted. (default: insert)"`
 
+	// Specifies the field of each document that says whether to insert, update or delete it.
+	OpField string `long:"opField" value-name:"<field>" description:"top-level field of each input document whose value, insert, update or delete (or I, U or D, in any case), says whether the document is inserted, updated or deleted, e.g. to apply a change data capture export of another database in one import. The field is removed from the documents. Updates are applied as --mode, upsert or merge (default: upsert), and updates and deletes match documents by --upsertFields. Documents are imported in order, as with --maintainInsertionOrder"`
+
 	Upsert bool `long:"upsert" hidden:"true" description:"(deprecated; same as --mode=upsert) insert or update objects that already exist"`
 
 	// Specifies a list of fields for the query portion of the upsert; defaults to _id field.
 	UpsertFields string `long:"upsertFields" value-name:"<field>[,<field>]*" description:"comma-separated fields for the query part when --mode is set to upsert or merge"`
 
+	// Specifies how --mode=merge merges arrays with the arrays of existing documents.
+	ArrayMerge []string `long:"arrayMerge" value-name:"<field>=<strategy>" description:"how --mode=merge merges an array field with the array of the existing document, instead of replacing it: concat appends the imported elements, union appends the ones that are not in the array yet, and union:<key field> replaces the elements with the key of an imported element and appends the others, e.g. --arrayMerge items=union:sku (may be specified multiple times for different fields; requires MongoDB 4.2 or later)"`
+
+	// Retries batches that fail with a network error, as upserts of documents
+	// whose _id is derived from IdempotencyFields.
+	IdempotentRetries int `long:"idempotentRetries" value-name:"<count>" description:"number of times to retry a batch that fails with a network error. Each document gets an _id hashed from --idempotencyFields, and retries upsert by that _id, so documents the server received before the error are not inserted twice and importing the same input again adds no duplicates. Only for --mode=insert"`
+
+	// Specifies the fields that identify a document for IdempotentRetries.
+	IdempotencyFields string `long:"idempotencyFields" value-name:"<field>[,<field>]*" description:"comma-separated fields whose values identify a document for --idempotentRetries; documents with the same values get the same _id"`
+
+	// Overrides the largest document size that documents are checked against before they are sent.
+	MaxDocSizeBytes int `long:"maxDocSizeBytes" value-name:"<bytes>" description:"size in bytes of the largest document to send, for clusters whose limit is not the default of 16MB. Larger documents, along with documents that nest too deeply or have an invalid _id or field name, are counted as failures and skipped, unless --stopOnError is set"`
+
+	// Guards against documents that spread over too many distinct top-level fields.
+	MaxDistinctFields    int    `long:"maxDistinctFields" value-name:"<count>" description:"stop the import when its documents have more than this many distinct top-level fields, which usually means the input is transposed or has the wrong header, before the document with the first field too many is written"`
+	DistinctFieldsPolicy string `long:"distinctFieldsPolicy" value-name:"<policy>" description:"what to do when the documents go over --maxDistinctFields - one of: abort (default), warn (log a warning and import everything)"`
+
+	// Runs server commands before and after the documents are imported.
+	PreExec  []string `long:"preExec" value-name:"<command>" description:"server command, as an extended JSON document, to run before the documents are imported and after --drop, e.g. '{\"collMod\": \"{{collection}}\", \"validationLevel\": \"off\"}' or '{\"dropIndexes\": \"{{collection}}\", \"index\": \"*\"}'. The command runs on the database of the import, or on the database given by a $db field; {{db}} and {{collection}} in its strings are replaced by the namespace of the import. An error stops the import (may be specified multiple times; the commands run in order)"`
+	PostExec []string `long:"postExec" value-name:"<command>" description:"server command, as an extended JSON document, to run after the documents are imported, e.g. to rebuild indexes or re-enable validation. Runs like --preExec, and also when the import or a --preExec command fails once the first --preExec command has run; a command that fails does not stop the ones after it (may be specified multiple times; the commands run in order)"`
+
+	// Compares what the server counts in the collection after the import with what was imported.
+	ReconcileFile   string `long:"reconcileFile" value-name:"<filename>" description:"file to write a JSON reconciliation report to after the import: the number of documents the collection gained, counted by the server, next to the number imported, and the same for the totals of --reconcileFields. The import fails if they differ, which they also do when other clients write to the collection during the import. Only for --mode=insert"`
+	ReconcileFields string `long:"reconcileFields" value-name:"<field>[,<field>]*" description:"comma-separated fields for --reconcileFile to total on both sides: the number of documents that have the field, and the sums of its integer values and of its double values"`
+
 	// Sets write concern level for write operations.
 	// By default mongoimport uses a write concern of 'majority'.
 	// Cannot be used simultaneously with write concern options in a URI.
@@ -151,17 +227,19 @@ func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, erro
 
 	// ensure either a positional argument is supplied or an argument is passed
 	// to the --file flag - and not both
-	if inputOpts.File != "" && len(extraArgs) != 0 {
+	if len(inputOpts.Files) != 0 && len(extraArgs) != 0 {
 		return Options{}, fmt.Errorf(
 			"error parsing positional arguments: cannot use both --file and a positional argument to set the input file",
 		)
 	}
 
-	if inputOpts.File == "" {
-		if len(extraArgs) != 0 {
-			// if --file is not supplied, use the positional argument supplied
-			inputOpts.File = extraArgs[0]
-		}
+	if len(inputOpts.Files) == 1 && !isGlobPattern(inputOpts.Files[0]) {
+		// a single file is imported like a positional argument
+		inputOpts.File = inputOpts.Files[0]
+		inputOpts.Files = nil
+	} else if len(extraArgs) != 0 {
+		// if --file is not supplied, use the positional argument supplied
+		inputOpts.File = extraArgs[0]
 	}
 
 	return Options{
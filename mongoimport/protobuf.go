@@ -0,0 +1,772 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxProtobufMessageSize bounds the size a message of the input may have, so
+// that a corrupt length does not make mongoimport allocate all the memory.
+const maxProtobufMessageSize = 64 * 1024 * 1024
+
+// Wire types of the protobuf encoding.
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+	protoWireFixed32 = 5
+)
+
+// Field types of a FieldDescriptorProto.
+const (
+	protoTypeDouble   = 1
+	protoTypeFloat    = 2
+	protoTypeInt64    = 3
+	protoTypeUint64   = 4
+	protoTypeInt32    = 5
+	protoTypeFixed64  = 6
+	protoTypeFixed32  = 7
+	protoTypeBool     = 8
+	protoTypeString   = 9
+	protoTypeGroup    = 10
+	protoTypeMessage  = 11
+	protoTypeBytes    = 12
+	protoTypeUint32   = 13
+	protoTypeEnum     = 14
+	protoTypeSfixed32 = 15
+	protoTypeSfixed64 = 16
+	protoTypeSint32   = 17
+	protoTypeSint64   = 18
+)
+
+const protoLabelRepeated = 3
+
+var errProtobufTruncated = errors.New("truncated protobuf message")
+
+// protoValue is the encoded value of one field of a message. The values of
+// the varint and fixed wire types are held in bits.
+type protoValue struct {
+	wireType int
+	bits     uint64
+	bytes    []byte
+}
+
+// readProtoFields calls fn with the number and the value of each field
+// encoded in b, in order.
+func readProtoFields(b []byte, fn func(number int32, value protoValue) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errProtobufTruncated
+		}
+		b = b[n:]
+		number := tag >> 3
+		if number == 0 || number > math.MaxInt32 {
+			return fmt.Errorf("invalid protobuf field number %v", number)
+		}
+		value := protoValue{wireType: int(tag & 7)}
+		switch value.wireType {
+		case protoWireVarint:
+			value.bits, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errProtobufTruncated
+			}
+			b = b[n:]
+		case protoWireFixed64:
+			if len(b) < 8 {
+				return errProtobufTruncated
+			}
+			value.bits = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case protoWireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errProtobufTruncated
+			}
+			value.bytes = b[n : n+int(size)]
+			b = b[n+int(size):]
+		case protoWireFixed32:
+			if len(b) < 4 {
+				return errProtobufTruncated
+			}
+			value.bits = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %v of field %v", value.wireType, number)
+		}
+		if err := fn(int32(number), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// protoMessage describes a message type of the --descriptorSet.
+type protoMessage struct {
+	name string
+	// fields are in the order of the .proto file, which is the order of the
+	// fields of the documents.
+	fields   []*protoField
+	byNumber map[int32]*protoField
+	// mapEntry is set for the entries of map fields, which have a key field
+	// numbered 1 and a value field numbered 2.
+	mapEntry bool
+}
+
+// protoField describes a field of a message type.
+type protoField struct {
+	name     string
+	number   int32
+	kind     int32
+	repeated bool
+	typeName string
+	message  *protoMessage
+	enum     *protoEnum
+}
+
+// protoEnum describes an enum type of the --descriptorSet.
+type protoEnum struct {
+	name   string
+	values map[int32]string
+}
+
+// protoDescriptors holds the types of a FileDescriptorSet by their fully
+// qualified names.
+type protoDescriptors struct {
+	messages map[string]*protoMessage
+	enums    map[string]*protoEnum
+}
+
+// loadProtobufMessage reads the FileDescriptorSet in path and returns the
+// message type called name in it.
+func loadProtobufMessage(path, name string) (*protoMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading --descriptorSet: %v", err)
+	}
+	descriptors, err := parseFileDescriptorSet(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing --descriptorSet %v: %v", path, err)
+	}
+	message := descriptors.messages[strings.TrimPrefix(name, ".")]
+	if message == nil || message.mapEntry {
+		return nil, fmt.Errorf("--descriptorSet %v has no message type %v", path, name)
+	}
+	return message, nil
+}
+
+func parseFileDescriptorSet(data []byte) (*protoDescriptors, error) {
+	descriptors := &protoDescriptors{
+		messages: map[string]*protoMessage{},
+		enums:    map[string]*protoEnum{},
+	}
+	err := readProtoFields(data, func(number int32, value protoValue) error {
+		if number != 1 {
+			return nil
+		}
+		return descriptors.addFile(value.bytes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range descriptors.messages {
+		if err := descriptors.resolve(message); err != nil {
+			return nil, err
+		}
+	}
+	return descriptors, nil
+}
+
+// addFile adds the types of a FileDescriptorProto.
+func (d *protoDescriptors) addFile(data []byte) error {
+	var pkg string
+	var messages, enums [][]byte
+	err := readProtoFields(data, func(number int32, value protoValue) error {
+		switch number {
+		case 2:
+			pkg = string(value.bytes)
+		case 4:
+			messages = append(messages, value.bytes)
+		case 5:
+			enums = append(enums, value.bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, message := range messages {
+		if err := d.addMessage(pkg, message); err != nil {
+			return err
+		}
+	}
+	for _, enum := range enums {
+		if err := d.addEnum(pkg, enum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addMessage adds the DescriptorProto of a message type declared in scope,
+// and the types nested in it.
+func (d *protoDescriptors) addMessage(scope string, data []byte) error {
+	message := &protoMessage{byNumber: map[int32]*protoField{}}
+	var nested, enums [][]byte
+	err := readProtoFields(data, func(number int32, value protoValue) error {
+		switch number {
+		case 1:
+			message.name = qualifyProtoName(scope, string(value.bytes))
+		case 2:
+			field, err := parseProtoField(value.bytes)
+			if err != nil {
+				return err
+			}
+			message.fields = append(message.fields, field)
+			message.byNumber[field.number] = field
+		case 3:
+			nested = append(nested, value.bytes)
+		case 4:
+			enums = append(enums, value.bytes)
+		case 7:
+			return readProtoFields(value.bytes, func(number int32, value protoValue) error {
+				if number == 7 {
+					message.mapEntry = value.bits != 0
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	d.messages[message.name] = message
+	for _, n := range nested {
+		if err := d.addMessage(message.name, n); err != nil {
+			return err
+		}
+	}
+	for _, enum := range enums {
+		if err := d.addEnum(message.name, enum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addEnum adds the EnumDescriptorProto of an enum type declared in scope.
+func (d *protoDescriptors) addEnum(scope string, data []byte) error {
+	enum := &protoEnum{values: map[int32]string{}}
+	err := readProtoFields(data, func(number int32, value protoValue) error {
+		switch number {
+		case 1:
+			enum.name = qualifyProtoName(scope, string(value.bytes))
+		case 2:
+			var name string
+			var valueNumber int32
+			err := readProtoFields(value.bytes, func(number int32, value protoValue) error {
+				switch number {
+				case 1:
+					name = string(value.bytes)
+				case 2:
+					valueNumber = int32(value.bits)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			// the first name of an aliased number is its canonical one
+			if _, ok := enum.values[valueNumber]; !ok {
+				enum.values[valueNumber] = name
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	d.enums[enum.name] = enum
+	return nil
+}
+
+// parseProtoField parses a FieldDescriptorProto.
+func parseProtoField(data []byte) (*protoField, error) {
+	field := &protoField{}
+	err := readProtoFields(data, func(number int32, value protoValue) error {
+		switch number {
+		case 1:
+			field.name = string(value.bytes)
+		case 3:
+			field.number = int32(value.bits)
+		case 4:
+			field.repeated = value.bits == protoLabelRepeated
+		case 5:
+			field.kind = int32(value.bits)
+		case 6:
+			field.typeName = string(value.bytes)
+		}
+		return nil
+	})
+	return field, err
+}
+
+// resolve links the message and enum fields of message to their types.
+func (d *protoDescriptors) resolve(message *protoMessage) error {
+	for _, field := range message.fields {
+		switch field.kind {
+		case protoTypeGroup:
+			return fmt.Errorf(
+				"field %v of %v is a group, which is not supported",
+				field.name,
+				message.name,
+			)
+		case protoTypeMessage:
+			field.message = d.messages[d.lookup(message.name, field.typeName, true)]
+			if field.message == nil {
+				return fmt.Errorf("unknown type %v of field %v of %v",
+					field.typeName, field.name, message.name)
+			}
+		case protoTypeEnum:
+			field.enum = d.enums[d.lookup(message.name, field.typeName, false)]
+			if field.enum == nil {
+				return fmt.Errorf("unknown type %v of field %v of %v",
+					field.typeName, field.name, message.name)
+			}
+		}
+	}
+	return nil
+}
+
+// lookup returns the fully qualified name of the type that name refers to
+// from scope. The names in descriptor sets written by protoc are already
+// fully qualified and start with a dot.
+func (d *protoDescriptors) lookup(scope, name string, isMessage bool) string {
+	if qualified, ok := strings.CutPrefix(name, "."); ok {
+		return qualified
+	}
+	for {
+		candidate := qualifyProtoName(scope, name)
+		_, isKnownMessage := d.messages[candidate]
+		_, isKnownEnum := d.enums[candidate]
+		if (isMessage && isKnownMessage) || (!isMessage && isKnownEnum) || scope == "" {
+			return candidate
+		}
+		scope = scope[:max(strings.LastIndex(scope, "."), 0)]
+	}
+}
+
+func qualifyProtoName(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// decode converts an encoded message into a document, in which the fields
+// are named as in the .proto file. Fields that are not set are left out, and
+// fields unknown to the descriptor are skipped.
+func (m *protoMessage) decode(data []byte) (bson.D, error) {
+	values, err := m.decodeValues(data)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.D{}
+	for _, field := range m.fields {
+		if value, ok := values[field.number]; ok {
+			doc = append(doc, bson.E{Key: field.name, Value: value})
+		}
+	}
+	return doc, nil
+}
+
+// decodeValues decodes the fields of an encoded message by their numbers.
+func (m *protoMessage) decodeValues(data []byte) (map[int32]interface{}, error) {
+	values := map[int32]interface{}{}
+	err := readProtoFields(data, func(number int32, value protoValue) error {
+		field := m.byNumber[number]
+		if field == nil {
+			return nil
+		}
+		if !field.repeated {
+			decoded, err := field.decode(value)
+			if err != nil {
+				return err
+			}
+			values[number] = decoded
+			return nil
+		}
+		if field.message != nil && field.message.mapEntry {
+			entries, _ := values[number].(bson.D)
+			entries, err := field.addMapEntry(entries, value)
+			if err != nil {
+				return err
+			}
+			values[number] = entries
+			return nil
+		}
+		elements, _ := values[number].(bson.A)
+		if elements == nil {
+			elements = bson.A{}
+		}
+		// repeated scalars may be packed in one length-delimited value
+		if value.wireType == protoWireBytes && field.wireType() != protoWireBytes {
+			unpacked, err := field.unpack(value.bytes)
+			if err != nil {
+				return err
+			}
+			values[number] = append(elements, unpacked...)
+			return nil
+		}
+		decoded, err := field.decode(value)
+		if err != nil {
+			return err
+		}
+		values[number] = append(elements, decoded)
+		return nil
+	})
+	return values, err
+}
+
+// addMapEntry adds an entry of a map field to the subdocument the map is
+// imported as, keyed by the entry's key as a string.
+func (f *protoField) addMapEntry(entries bson.D, value protoValue) (bson.D, error) {
+	if value.wireType != protoWireBytes {
+		return nil, f.wireTypeError(value)
+	}
+	values, err := f.message.decodeValues(value.bytes)
+	if err != nil {
+		return nil, err
+	}
+	var key, entryValue interface{}
+	for _, field := range f.message.fields {
+		decoded, ok := values[field.number]
+		if !ok {
+			// a missing key or value has its default value
+			decoded, err = field.decode(protoValue{wireType: field.wireType()})
+			if err != nil {
+				return nil, err
+			}
+		}
+		switch field.number {
+		case 1:
+			key = decoded
+		case 2:
+			entryValue = decoded
+		}
+	}
+	name := fmt.Sprint(key)
+	for i := range entries {
+		if entries[i].Key == name {
+			entries[i].Value = entryValue
+			return entries, nil
+		}
+	}
+	if entries == nil {
+		entries = bson.D{}
+	}
+	return append(entries, bson.E{Key: name, Value: entryValue}), nil
+}
+
+// wireType returns the wire type the values of the field are encoded with.
+func (f *protoField) wireType() int {
+	switch f.kind {
+	case protoTypeDouble, protoTypeFixed64, protoTypeSfixed64:
+		return protoWireFixed64
+	case protoTypeFloat, protoTypeFixed32, protoTypeSfixed32:
+		return protoWireFixed32
+	case protoTypeString, protoTypeBytes, protoTypeMessage:
+		return protoWireBytes
+	default:
+		return protoWireVarint
+	}
+}
+
+func (f *protoField) wireTypeError(value protoValue) error {
+	return fmt.Errorf(
+		"field %v has wire type %v instead of %v",
+		f.name,
+		value.wireType,
+		f.wireType(),
+	)
+}
+
+// unpack decodes the elements of a packed repeated field.
+func (f *protoField) unpack(data []byte) (bson.A, error) {
+	elements := bson.A{}
+	for len(data) > 0 {
+		value := protoValue{wireType: f.wireType()}
+		switch value.wireType {
+		case protoWireVarint:
+			var n int
+			value.bits, n = binary.Uvarint(data)
+			if n <= 0 {
+				return nil, errProtobufTruncated
+			}
+			data = data[n:]
+		case protoWireFixed64:
+			if len(data) < 8 {
+				return nil, errProtobufTruncated
+			}
+			value.bits = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case protoWireFixed32:
+			if len(data) < 4 {
+				return nil, errProtobufTruncated
+			}
+			value.bits = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		}
+		decoded, err := f.decode(value)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, decoded)
+	}
+	return elements, nil
+}
+
+// decode converts one value of the field. Unsigned 32 bit integers are
+// imported as int64s, and unsigned 64 bit integers as int64s if they fit or
+// else as decimals. Enums are imported as the names of their values, or as
+// numbers if the descriptor does not know them.
+func (f *protoField) decode(value protoValue) (interface{}, error) {
+	if value.wireType != f.wireType() {
+		return nil, f.wireTypeError(value)
+	}
+	bits := value.bits
+	switch f.kind {
+	case protoTypeDouble:
+		return math.Float64frombits(bits), nil
+	case protoTypeFloat:
+		return float64(math.Float32frombits(uint32(bits))), nil
+	case protoTypeInt64, protoTypeSfixed64:
+		return int64(bits), nil
+	case protoTypeInt32, protoTypeSfixed32:
+		return int32(bits), nil
+	case protoTypeUint32, protoTypeFixed32:
+		return int64(uint32(bits)), nil
+	case protoTypeUint64, protoTypeFixed64:
+		if bits <= math.MaxInt64 {
+			return int64(bits), nil
+		}
+		return primitive.ParseDecimal128(strconv.FormatUint(bits, 10))
+	case protoTypeSint32:
+		return int32(uint32(bits)>>1) ^ -int32(bits&1), nil
+	case protoTypeSint64:
+		return int64(bits>>1) ^ -int64(bits&1), nil
+	case protoTypeBool:
+		return bits != 0, nil
+	case protoTypeEnum:
+		if name, ok := f.enum.values[int32(bits)]; ok {
+			return name, nil
+		}
+		return int32(bits), nil
+	case protoTypeString:
+		if !utf8.Valid(value.bytes) {
+			return nil, fmt.Errorf("field %v is not valid UTF-8", f.name)
+		}
+		return string(value.bytes), nil
+	case protoTypeBytes:
+		return primitive.Binary{Data: value.bytes}, nil
+	case protoTypeMessage:
+		return f.message.decode(value.bytes)
+	}
+	return nil, fmt.Errorf("field %v has unknown type %v", f.name, f.kind)
+}
+
+// ProtobufInputReader is an implementation of InputReader that reads
+// protobuf messages, each preceded by its size as a varint.
+type ProtobufInputReader struct {
+	// message is the type of the messages
+	message *protoMessage
+
+	// reader buffers the input source
+	reader *bufio.Reader
+
+	// numProcessed indicates the number of messages processed
+	numProcessed uint64
+
+	// embedded sizeTracker exposes the Size() method to check the number of bytes read so far
+	sizeTracker
+
+	// numDecoders is the number of concurrent goroutines to use for decoding
+	numDecoders int
+
+	// resume tracks the position of each document in the --resumeFile, if set
+	resume *resumeTracker
+
+	// stages counts the records of --progressStages, if set
+	stages *importStages
+}
+
+// ProtobufConverter implements the Converter interface for protobuf input.
+type ProtobufConverter struct {
+	data    []byte
+	index   uint64
+	message *protoMessage
+}
+
+// NewProtobufInputReader creates a new ProtobufInputReader that reads
+// messages of the given type from in.
+func NewProtobufInputReader(
+	message *protoMessage,
+	in io.Reader,
+	numDecoders int,
+) *ProtobufInputReader {
+	szCount := newSizeTrackingReader(in)
+	return &ProtobufInputReader{
+		message:     message,
+		reader:      bufio.NewReader(szCount),
+		sizeTracker: szCount,
+		numDecoders: numDecoders,
+	}
+}
+
+// ReadAndValidateHeader is a no-op for protobuf imports; always returns nil.
+func (r *ProtobufInputReader) ReadAndValidateHeader() error {
+	return nil
+}
+
+// ReadAndValidateTypedHeader is a no-op for protobuf imports; always returns nil.
+func (r *ProtobufInputReader) ReadAndValidateTypedHeader(parseGrace ParseGrace) error {
+	return nil
+}
+
+// StreamDocument takes a boolean indicating if the documents should be streamed
+// in read order and a channel on which to stream the documents processed from
+// the underlying reader. Returns a non-nil error if encountered.
+func (r *ProtobufInputReader) StreamDocument(ordered bool, readChan chan bson.D) error {
+	rawChan := make(chan Converter, r.numDecoders)
+	protobufErrChan := make(chan error)
+
+	// begin reading from source
+	go func() {
+		for {
+			data, err := r.readMessage()
+			if err != nil {
+				close(rawChan)
+				if err == io.EOF {
+					protobufErrChan <- nil
+				} else {
+					r.numProcessed++
+					protobufErrChan <- fmt.Errorf("error reading message #%v: %v", r.numProcessed, err)
+				}
+				return
+			}
+			var converter Converter = ProtobufConverter{
+				data:    data,
+				index:   r.numProcessed,
+				message: r.message,
+			}
+			r.numProcessed++
+			if r.resume != nil {
+				converter = r.resume.wrap(converter, r.consumed(), r.numProcessed)
+			}
+			rawChan <- converter
+		}
+	}()
+
+	// begin processing read bytes
+	go func() {
+		protobufErrChan <- streamDocuments(ordered, r.numDecoders, rawChan, readChan, r.stages)
+	}()
+
+	return channelQuorumError(protobufErrChan)
+}
+
+// readMessage reads the next length-delimited message. It returns io.EOF if
+// the input ends before the message starts.
+func (r *ProtobufInputReader) readMessage() ([]byte, error) {
+	size, err := binary.ReadUvarint(r.reader)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errProtobufTruncated
+		}
+		return nil, err
+	}
+	if size > maxProtobufMessageSize {
+		return nil, fmt.Errorf(
+			"message size %v is larger than the maximum of %v",
+			size,
+			maxProtobufMessageSize,
+		)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.reader, data); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errProtobufTruncated
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// consumed returns the number of bytes of input the messages read so far
+// take up.
+func (r *ProtobufInputReader) consumed() int64 {
+	return r.Size() - int64(r.reader.Buffered())
+}
+
+// Convert implements the Converter interface for protobuf input. It converts
+// a ProtobufConverter struct to a BSON document.
+func (c ProtobufConverter) Convert() (bson.D, error) {
+	doc, err := c.message.decode(c.data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding message #%v: %v", c.index+1, err)
+	}
+	return doc, nil
+}
+
+// validateProtobuf checks the options of a protobuf import and loads the
+// message type of --messageType.
+func (imp *MongoImport) validateProtobuf() error {
+	if imp.InputOptions.Type != PROTOBUF {
+		if imp.InputOptions.DescriptorSet != "" {
+			return fmt.Errorf("--descriptorSet requires --type=protobuf")
+		}
+		if imp.InputOptions.MessageType != "" {
+			return fmt.Errorf("--messageType requires --type=protobuf")
+		}
+		return nil
+	}
+	if imp.InputOptions.DescriptorSet == "" {
+		return fmt.Errorf("--type=protobuf requires --descriptorSet")
+	}
+	if imp.InputOptions.MessageType == "" {
+		return fmt.Errorf("--type=protobuf requires --messageType")
+	}
+	if imp.InputOptions.JSONArray {
+		return fmt.Errorf("cannot use --jsonArray when input type is protobuf")
+	}
+	if imp.InputOptions.Legacy {
+		return fmt.Errorf("cannot use --legacy if input type is not JSON")
+	}
+	message, err := loadProtobufMessage(
+		imp.InputOptions.DescriptorSet,
+		imp.InputOptions.MessageType,
+	)
+	if err != nil {
+		return err
+	}
+	imp.protobufMessage = message
+	return nil
+}
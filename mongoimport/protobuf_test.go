@@ -0,0 +1,263 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func protoTag(number, wireType int) []byte {
+	return binary.AppendUvarint(nil, uint64(number<<3|wireType))
+}
+
+func protoVarint(number int, value uint64) []byte {
+	return binary.AppendUvarint(protoTag(number, protoWireVarint), value)
+}
+
+func protoBytes(number int, value []byte) []byte {
+	b := binary.AppendUvarint(protoTag(number, protoWireBytes), uint64(len(value)))
+	return append(b, value...)
+}
+
+func protoString(number int, value string) []byte {
+	return protoBytes(number, []byte(value))
+}
+
+func protoFixed64(number int, value uint64) []byte {
+	return binary.LittleEndian.AppendUint64(protoTag(number, protoWireFixed64), value)
+}
+
+func protoJoin(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func protoFieldDescriptor(name string, number, label, kind int, typeName string) []byte {
+	field := protoJoin(
+		protoString(1, name),
+		protoVarint(3, uint64(number)),
+		protoVarint(4, uint64(label)),
+		protoVarint(5, uint64(kind)),
+	)
+	if typeName != "" {
+		field = append(field, protoString(6, typeName)...)
+	}
+	return protoBytes(2, field)
+}
+
+// testDescriptorSet describes:
+//
+//	package events.v1;
+//	message PageView {
+//	  enum Kind { UNKNOWN = 0; CLICK = 1; }
+//	  message Page { string url = 1; }
+//	  string user = 1;
+//	  sint64 delta = 2;
+//	  repeated int32 scores = 3;
+//	  Page page = 4;
+//	  Kind kind = 5;
+//	  map<string, int64> counts = 6;
+//	  bytes payload = 7;
+//	  fixed64 total = 8;
+//	}
+func testDescriptorSet() []byte {
+	const optional, repeated = 1, 3
+	kind := protoJoin(
+		protoString(1, "Kind"),
+		protoBytes(2, protoJoin(protoString(1, "UNKNOWN"), protoVarint(2, 0))),
+		protoBytes(2, protoJoin(protoString(1, "CLICK"), protoVarint(2, 1))),
+	)
+	page := protoJoin(
+		protoString(1, "Page"),
+		protoFieldDescriptor("url", 1, optional, protoTypeString, ""),
+	)
+	countsEntry := protoJoin(
+		protoString(1, "CountsEntry"),
+		protoFieldDescriptor("key", 1, optional, protoTypeString, ""),
+		protoFieldDescriptor("value", 2, optional, protoTypeInt64, ""),
+		protoBytes(7, protoVarint(7, 1)),
+	)
+	pageView := protoJoin(
+		protoString(1, "PageView"),
+		protoFieldDescriptor("user", 1, optional, protoTypeString, ""),
+		protoFieldDescriptor("delta", 2, optional, protoTypeSint64, ""),
+		protoFieldDescriptor("scores", 3, repeated, protoTypeInt32, ""),
+		protoFieldDescriptor("page", 4, optional, protoTypeMessage, ".events.v1.PageView.Page"),
+		protoFieldDescriptor("kind", 5, optional, protoTypeEnum, "Kind"),
+		protoFieldDescriptor(
+			"counts", 6, repeated, protoTypeMessage, ".events.v1.PageView.CountsEntry",
+		),
+		protoFieldDescriptor("payload", 7, optional, protoTypeBytes, ""),
+		protoFieldDescriptor("total", 8, optional, protoTypeFixed64, ""),
+		protoBytes(3, page),
+		protoBytes(3, countsEntry),
+		protoBytes(4, kind),
+	)
+	file := protoJoin(
+		protoString(1, "events.proto"),
+		protoString(2, "events.v1"),
+		protoBytes(4, pageView),
+	)
+	return protoBytes(1, file)
+}
+
+func writeTestDescriptorSet(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "events.pb")
+	if err := os.WriteFile(path, testDescriptorSet(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProtobufDescriptorSet(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+	path := writeTestDescriptorSet(t)
+
+	Convey("With a descriptor set", t, func() {
+		Convey("message types are found by their fully qualified names", func() {
+			message, err := loadProtobufMessage(path, "events.v1.PageView")
+			So(err, ShouldBeNil)
+			So(message.fields, ShouldHaveLength, 8)
+			So(message.byNumber[4].message.name, ShouldEqual, "events.v1.PageView.Page")
+			So(message.byNumber[5].enum.name, ShouldEqual, "events.v1.PageView.Kind")
+			So(message.byNumber[6].message.mapEntry, ShouldBeTrue)
+
+			_, err = loadProtobufMessage(path, ".events.v1.PageView.Page")
+			So(err, ShouldBeNil)
+		})
+
+		Convey("unknown message types and map entries are rejected", func() {
+			_, err := loadProtobufMessage(path, "PageView")
+			So(err, ShouldNotBeNil)
+			_, err = loadProtobufMessage(path, "events.v1.PageView.CountsEntry")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestProtobufStreamDocument(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+	message, err := loadProtobufMessage(writeTestDescriptorSet(t), "events.v1.PageView")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delimited := func(messages ...[]byte) []byte {
+		var b []byte
+		for _, m := range messages {
+			b = binary.AppendUvarint(b, uint64(len(m)))
+			b = append(b, m...)
+		}
+		return b
+	}
+
+	Convey("With a protobuf input reader", t, func() {
+		Convey("messages are decoded into documents", func() {
+			packed := protoJoin(
+				binary.AppendUvarint(nil, 7),
+				binary.AppendUvarint(nil, uint64(math.MaxUint64)), // -1
+			)
+			first := protoJoin(
+				protoString(1, "ann"),
+				protoVarint(2, 3), // zigzag encoded -2
+				protoBytes(3, packed),
+				protoVarint(3, 9),
+				protoBytes(4, protoString(1, "/home")),
+				protoVarint(5, 1),
+				protoBytes(6, protoJoin(protoString(1, "a"), protoVarint(2, 1))),
+				protoBytes(6, protoJoin(protoString(1, "b"), protoVarint(2, 2))),
+				protoBytes(6, protoJoin(protoString(1, "a"), protoVarint(2, 3))),
+				protoBytes(7, []byte{1, 2}),
+				protoFixed64(8, math.MaxUint64),
+				protoVarint(99, 1),
+			)
+			second := protoJoin(
+				protoVarint(5, 7),
+				protoFixed64(8, 12),
+				protoString(1, "bob"),
+			)
+
+			r := NewProtobufInputReader(message, bytes.NewReader(delimited(first, second)), 1)
+			docChan := make(chan bson.D, 2)
+			So(r.StreamDocument(true, docChan), ShouldBeNil)
+
+			total, err := primitive.ParseDecimal128("18446744073709551615")
+			So(err, ShouldBeNil)
+			So(<-docChan, ShouldResemble, bson.D{
+				{"user", "ann"},
+				{"delta", int64(-2)},
+				{"scores", bson.A{int32(7), int32(-1), int32(9)}},
+				{"page", bson.D{{"url", "/home"}}},
+				{"kind", "CLICK"},
+				{"counts", bson.D{{"a", int64(3)}, {"b", int64(2)}}},
+				{"payload", primitive.Binary{Data: []byte{1, 2}}},
+				{"total", total},
+			})
+			So(<-docChan, ShouldResemble, bson.D{
+				{"user", "bob"},
+				{"kind", int32(7)},
+				{"total", int64(12)},
+			})
+		})
+
+		Convey("a truncated message is an error", func() {
+			input := delimited(protoString(1, "ann"))
+			r := NewProtobufInputReader(message, bytes.NewReader(input[:len(input)-1]), 1)
+			So(r.StreamDocument(true, make(chan bson.D, 1)), ShouldNotBeNil)
+		})
+
+		Convey("a field with the wrong wire type is an error", func() {
+			input := delimited(protoVarint(1, 1))
+			r := NewProtobufInputReader(message, bytes.NewReader(input), 1)
+			So(r.StreamDocument(true, make(chan bson.D, 1)), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestValidateProtobuf(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+	path := writeTestDescriptorSet(t)
+
+	Convey("With a protobuf import", t, func() {
+		imp := &MongoImport{InputOptions: &InputOptions{
+			Type:          PROTOBUF,
+			DescriptorSet: path,
+			MessageType:   "events.v1.PageView",
+		}}
+
+		Convey("the message type is loaded", func() {
+			So(imp.validateProtobuf(), ShouldBeNil)
+			So(imp.protobufMessage.name, ShouldEqual, "events.v1.PageView")
+		})
+
+		Convey("--descriptorSet and --messageType are required", func() {
+			imp.InputOptions.MessageType = ""
+			So(imp.validateProtobuf(), ShouldNotBeNil)
+			imp.InputOptions.MessageType = "events.v1.PageView"
+			imp.InputOptions.DescriptorSet = ""
+			So(imp.validateProtobuf(), ShouldNotBeNil)
+		})
+
+		Convey("--jsonArray is rejected", func() {
+			imp.InputOptions.JSONArray = true
+			So(imp.validateProtobuf(), ShouldNotBeNil)
+		})
+
+		Convey("--descriptorSet requires --type=protobuf", func() {
+			imp.InputOptions.Type = JSON
+			So(imp.validateProtobuf(), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,445 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// reconcileTolerance is the largest difference between the sums of the
+// doubles of a field on either side, relative to the sum of their absolute
+// values, that is put down to rounding in the order they are added in.
+const reconcileTolerance = 1e-9
+
+// reconciler compares, after each import, what the server counts in the
+// collection with what was imported into it, and writes the results to the
+// --reconcileFile.
+type reconciler struct {
+	path   string
+	fields []string
+	report reconcileReport
+
+	// the tally of the import that runs, and the totals of the collection
+	// before it started
+	tally    *reconcileTally
+	baseline reconcileTotals
+}
+
+// reconcileReport is the content of a --reconcileFile.
+type reconcileReport struct {
+	// Match is whether the counts and sums of every import match.
+	Match       bool                  `json:"match"`
+	Collections []reconcileCollection `json:"collections"`
+}
+
+// reconcileCollection is the reconciliation of an import into a collection.
+type reconcileCollection struct {
+	Namespace  string           `json:"namespace"`
+	File       string           `json:"file,omitempty"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	Imported   uint64           `json:"imported"`
+	Failed     uint64           `json:"failed"`
+	Match      bool             `json:"match"`
+	Count      reconcileValue   `json:"count"`
+	Fields     []reconcileField `json:"fields,omitempty"`
+}
+
+// reconcileField is the reconciliation of a field of --reconcileFields: the
+// number of documents that have it, and the sums of its integer and double
+// values. The sums are strings, since JSON numbers can't hold every 64-bit
+// integer, NaN or infinities.
+type reconcileField struct {
+	Field     string         `json:"field"`
+	Match     bool           `json:"match"`
+	Present   reconcileValue `json:"present"`
+	IntSum    reconcileValue `json:"intSum"`
+	DoubleSum reconcileValue `json:"doubleSum"`
+}
+
+// reconcileValue is a value as tallied by mongoimport and as counted by the
+// server.
+type reconcileValue struct {
+	Client interface{} `json:"client"`
+	Server interface{} `json:"server"`
+	Match  bool        `json:"match"`
+}
+
+// reconcileTotals are the number of documents of a collection, and the
+// totals of each field of --reconcileFields in them.
+type reconcileTotals struct {
+	count  int64
+	fields []fieldTotals
+}
+
+type fieldTotals struct {
+	present int64
+	intSum  big.Int
+	// intApprox is set if the server's sum of the integers overflowed into
+	// a double.
+	intApprox bool
+	doubleSum float64
+	// doubleAbs is the sum of the absolute values of the doubles, which
+	// scales the rounding error of doubleSum.
+	doubleAbs float64
+}
+
+// validateReconcile checks --reconcileFile and --reconcileFields, and sets
+// up the reconciler.
+func (imp *MongoImport) validateReconcile() error {
+	path := imp.IngestOptions.ReconcileFile
+	fields := imp.IngestOptions.ReconcileFields
+	switch {
+	case path == "" && fields != "":
+		return fmt.Errorf("cannot use --reconcileFields without --reconcileFile")
+	case path == "":
+		return nil
+	case imp.IngestOptions.Mode != modeInsert:
+		return fmt.Errorf(
+			"cannot use --reconcileFile with --mode=%v, which changes existing documents",
+			imp.IngestOptions.Mode,
+		)
+	case imp.IngestOptions.IdempotentRetries > 0:
+		return fmt.Errorf(
+			"cannot use --reconcileFile with --idempotentRetries, whose retries can replace " +
+				"existing documents",
+		)
+	}
+
+	imp.reconcile = &reconciler{path: path, report: reconcileReport{Match: true}}
+	if fields == "" {
+		return nil
+	}
+	imp.reconcile.fields = strings.Split(fields, ",")
+	if err := validateFields(imp.reconcile.fields, false); err != nil {
+		return fmt.Errorf("invalid --reconcileFields argument: %v", err)
+	}
+	return nil
+}
+
+// start counts the documents of the collection that the import is about to
+// write to, and starts a new tally. It is a no-op on a nil reconciler.
+func (r *reconciler) start(collection *mongo.Collection) error {
+	if r == nil {
+		return nil
+	}
+	baseline, err := r.serverTotals(collection)
+	if err != nil {
+		return fmt.Errorf("error counting the documents of %v: %v", collection.Name(), err)
+	}
+	r.baseline = baseline
+	r.tally = newReconcileTally(r.fields)
+	return nil
+}
+
+// add tallies a document sent to the server. It is a no-op on a nil
+// reconciler.
+func (r *reconciler) add(document bson.Raw) {
+	if r == nil {
+		return
+	}
+	r.tally.add(document, 1)
+}
+
+// remove takes the documents that the server rejected out of the tally. It
+// is a no-op on a nil reconciler.
+func (r *reconciler) remove(writeErrors []mongo.BulkWriteError) {
+	if r == nil {
+		return
+	}
+	for _, writeErr := range writeErrors {
+		if insert, ok := writeErr.Request.(*mongo.InsertOneModel); ok {
+			if document, ok := insert.Document.([]byte); ok {
+				r.tally.add(document, -1)
+			}
+		}
+	}
+}
+
+// finish counts the documents of the collection again, compares what the
+// import added to it with the tally, and writes the --reconcileFile. It
+// returns an error if they differ. It is a no-op on a nil reconciler.
+func (r *reconciler) finish(
+	collection *mongo.Collection,
+	file string,
+	imported, failed uint64,
+) error {
+	if r == nil {
+		return nil
+	}
+	after, err := r.serverTotals(collection)
+	if err != nil {
+		return fmt.Errorf("error counting the documents of %v: %v", collection.Name(), err)
+	}
+	namespace := collection.Database().Name() + "." + collection.Name()
+	result := r.compare(r.tally.totals(), after.minus(r.baseline))
+	result.Namespace = namespace
+	result.File = file
+	result.FinishedAt = time.Now().UTC()
+	result.Imported = imported
+	result.Failed = failed
+
+	r.report.Collections = append(r.report.Collections, result)
+	r.report.Match = r.report.Match && result.Match
+	if err := r.write(); err != nil {
+		return err
+	}
+	if !result.Match {
+		return fmt.Errorf(
+			"the documents of %v do not reconcile with the ones imported, see %v",
+			namespace,
+			r.path,
+		)
+	}
+	log.Logvf(log.Info, "the documents of %v reconcile with the ones imported", namespace)
+	return nil
+}
+
+// write writes the report to the --reconcileFile.
+func (r *reconciler) write() error {
+	content, err := json.MarshalIndent(r.report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding --reconcileFile: %v", err)
+	}
+	if err := os.WriteFile(r.path, append(content, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing --reconcileFile: %v", err)
+	}
+	return nil
+}
+
+// pipeline returns the aggregation that the server counts the documents of
+// the collection with, and totals each field in like the tally does.
+func (r *reconciler) pipeline() mongo.Pipeline {
+	group := bson.D{{"_id", nil}, {"count", bson.D{{"$sum", 1}}}}
+	for i, field := range r.fields {
+		fieldType := bson.D{{"$type", "$" + field}}
+		sumOf := func(types ...string) bson.D {
+			return bson.D{{"$sum", bson.D{{"$cond", bson.A{
+				bson.D{{"$in", bson.A{fieldType, types}}},
+				"$" + field,
+				0,
+			}}}}}
+		}
+		group = append(group,
+			bson.E{fmt.Sprintf("present%d", i), bson.D{{"$sum", bson.D{{"$cond", bson.A{
+				bson.D{{"$eq", bson.A{fieldType, "missing"}}},
+				0,
+				1,
+			}}}}}},
+			bson.E{fmt.Sprintf("int%d", i), sumOf("int", "long")},
+			bson.E{fmt.Sprintf("double%d", i), sumOf("double")},
+		)
+	}
+	return mongo.Pipeline{{{"$group", group}}}
+}
+
+// serverTotals runs the pipeline on the collection.
+func (r *reconciler) serverTotals(collection *mongo.Collection) (reconcileTotals, error) {
+	totals := reconcileTotals{fields: make([]fieldTotals, len(r.fields))}
+	cursor, err := collection.Aggregate(context.TODO(), r.pipeline())
+	if err != nil {
+		return totals, err
+	}
+	defer cursor.Close(context.TODO())
+	if !cursor.Next(context.TODO()) {
+		// the collection is empty or doesn't exist
+		return totals, cursor.Err()
+	}
+
+	result := cursor.Current
+	totals.count, _ = result.Lookup("count").AsInt64OK()
+	for i := range r.fields {
+		field := &totals.fields[i]
+		field.present, _ = result.Lookup(fmt.Sprintf("present%d", i)).AsInt64OK()
+		intSum := result.Lookup(fmt.Sprintf("int%d", i))
+		if sum, ok := intSum.AsInt64OK(); ok {
+			field.intSum.SetInt64(sum)
+		} else if sum, ok := intSum.DoubleOK(); ok {
+			big.NewFloat(sum).Int(&field.intSum)
+			field.intApprox = true
+		}
+		doubleSum := result.Lookup(fmt.Sprintf("double%d", i))
+		if sum, ok := doubleSum.DoubleOK(); ok {
+			field.doubleSum = sum
+		}
+	}
+	return totals, nil
+}
+
+// minus returns the totals that t adds to the baseline.
+func (t reconcileTotals) minus(baseline reconcileTotals) reconcileTotals {
+	delta := reconcileTotals{
+		count:  t.count - baseline.count,
+		fields: make([]fieldTotals, len(t.fields)),
+	}
+	for i, field := range t.fields {
+		before := baseline.fields[i]
+		delta.fields[i].present = field.present - before.present
+		delta.fields[i].intSum.Sub(&field.intSum, &before.intSum)
+		delta.fields[i].intApprox = field.intApprox || before.intApprox
+		delta.fields[i].doubleSum = field.doubleSum - before.doubleSum
+	}
+	return delta
+}
+
+// compare returns the reconciliation of the client's totals with the
+// server's.
+func (r *reconciler) compare(client, server reconcileTotals) reconcileCollection {
+	result := reconcileCollection{
+		Count: reconcileValue{client.count, server.count, client.count == server.count},
+	}
+	result.Match = result.Count.Match
+	for i, field := range r.fields {
+		c, s := &client.fields[i], &server.fields[i]
+		intMatch := c.intSum.Cmp(&s.intSum) == 0
+		if s.intApprox {
+			clientSum, _ := new(big.Float).SetInt(&c.intSum).Float64()
+			serverSum, _ := new(big.Float).SetInt(&s.intSum).Float64()
+			intMatch = sumsMatch(clientSum, serverSum, math.Abs(clientSum))
+		}
+		fieldResult := reconcileField{
+			Field:   field,
+			Present: reconcileValue{c.present, s.present, c.present == s.present},
+			IntSum:  reconcileValue{c.intSum.String(), s.intSum.String(), intMatch},
+			DoubleSum: reconcileValue{
+				formatSum(c.doubleSum),
+				formatSum(s.doubleSum),
+				sumsMatch(c.doubleSum, s.doubleSum, c.doubleAbs),
+			},
+		}
+		fieldResult.Match = fieldResult.Present.Match &&
+			fieldResult.IntSum.Match &&
+			fieldResult.DoubleSum.Match
+		result.Fields = append(result.Fields, fieldResult)
+		result.Match = result.Match && fieldResult.Match
+	}
+	return result
+}
+
+// sumsMatch returns whether two sums of doubles whose absolute values add up
+// to scale are the same, but for rounding.
+func sumsMatch(a, b, scale float64) bool {
+	if a == b || (math.IsNaN(a) && math.IsNaN(b)) {
+		return true
+	}
+	return math.Abs(a-b) <= reconcileTolerance*scale
+}
+
+func formatSum(sum float64) string {
+	return strconv.FormatFloat(sum, 'g', -1, 64)
+}
+
+// reconcileTally totals the documents that an import sends to the server
+// like the server totals them, for --reconcileFile. It is safe for
+// concurrent use.
+type reconcileTally struct {
+	fields [][]string
+
+	mu   sync.Mutex
+	sums reconcileTotals
+	// the compensations of the Neumaier sums of the doubles
+	compensations []float64
+}
+
+func newReconcileTally(fields []string) *reconcileTally {
+	tally := &reconcileTally{
+		sums:          reconcileTotals{fields: make([]fieldTotals, len(fields))},
+		compensations: make([]float64, len(fields)),
+	}
+	for _, field := range fields {
+		tally.fields = append(tally.fields, strings.Split(field, "."))
+	}
+	return tally
+}
+
+// add adds a document to the totals if sign is 1, or takes it out of them if
+// it is -1.
+func (t *reconcileTally) add(document bson.Raw, sign int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sums.count += sign
+	for i, path := range t.fields {
+		value, present := lookupReconcileField(document, path)
+		if !present {
+			continue
+		}
+		field := &t.sums.fields[i]
+		field.present += sign
+		switch value.Type {
+		case bson.TypeInt32, bson.TypeInt64:
+			n, _ := value.AsInt64OK()
+			field.intSum.Add(&field.intSum, big.NewInt(sign*n))
+		case bson.TypeDouble:
+			d := float64(sign) * value.Double()
+			t.compensations[i] += neumaierError(field.doubleSum, d)
+			field.doubleSum += d
+			field.doubleAbs += math.Abs(d)
+		}
+	}
+}
+
+// totals returns the totals of the documents tallied.
+func (t *reconcileTally) totals() reconcileTotals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	totals := reconcileTotals{count: t.sums.count}
+	for i, field := range t.sums.fields {
+		totals.fields = append(totals.fields, fieldTotals{
+			present:   field.present,
+			doubleSum: field.doubleSum + t.compensations[i],
+			doubleAbs: field.doubleAbs,
+		})
+		totals.fields[i].intSum.Set(&field.intSum)
+	}
+	return totals
+}
+
+// neumaierError returns the rounding error of sum+d.
+func neumaierError(sum, d float64) float64 {
+	total := sum + d
+	if math.Abs(sum) >= math.Abs(d) {
+		return (sum - total) + d
+	}
+	return (d - total) + sum
+}
+
+// lookupReconcileField returns the value of the dotted field path in
+// document, and whether the server's $type would find it there. Like for
+// the server, a field whose path goes through an array is present but has
+// no value that is summed.
+func lookupReconcileField(document bson.Raw, path []string) (bson.RawValue, bool) {
+	value, err := document.LookupErr(path[0])
+	if err != nil {
+		return bson.RawValue{}, false
+	}
+	for _, part := range path[1:] {
+		switch value.Type {
+		case bson.TypeArray:
+			return bson.RawValue{Type: bson.TypeArray}, true
+		case bson.TypeEmbeddedDocument:
+			value, err = value.Document().LookupErr(part)
+			if err != nil {
+				return bson.RawValue{}, false
+			}
+		default:
+			return bson.RawValue{}, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,176 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestReconcile(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	marshal := func(doc bson.D) bson.Raw {
+		raw, err := bson.Marshal(doc)
+		So(err, ShouldBeNil)
+		return raw
+	}
+
+	Convey("With --reconcileFile", t, func() {
+		imp := NewMockMongoImport()
+		imp.IngestOptions.ReconcileFile = filepath.Join(t.TempDir(), "reconcile.json")
+		imp.IngestOptions.ReconcileFields = "amount,order.qty"
+
+		Convey("the fields are parsed", func() {
+			So(imp.validateSettings(), ShouldBeNil)
+			So(imp.reconcile.fields, ShouldResemble, []string{"amount", "order.qty"})
+		})
+
+		Convey("only insert mode is supported", func() {
+			imp.IngestOptions.Mode = modeUpsert
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("--idempotentRetries is not supported", func() {
+			imp.IngestOptions.IdempotentRetries = 3
+			imp.IngestOptions.IdempotencyFields = "order.id"
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("the fields must be valid", func() {
+			imp.IngestOptions.ReconcileFields = "amount,$qty"
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("--reconcileFields requires it", func() {
+			imp.IngestOptions.ReconcileFile = ""
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("the tally totals the documents like the server", func() {
+			So(imp.validateSettings(), ShouldBeNil)
+			r := imp.reconcile
+			r.tally = newReconcileTally(r.fields)
+			r.add(marshal(bson.D{{"amount", 2.5}, {"order", bson.D{{"qty", int32(3)}}}}))
+			r.add(marshal(bson.D{{"amount", int64(7)}, {"order", bson.D{{"qty", nil}}}}))
+			r.add(marshal(bson.D{{"amount", "n/a"}, {"order", bson.A{bson.D{{"qty", 1}}}}}))
+			r.add(marshal(bson.D{{"order", "none"}}))
+
+			totals := r.tally.totals()
+			So(totals.count, ShouldEqual, 4)
+			So(totals.fields[0].present, ShouldEqual, 3)
+			So(totals.fields[0].intSum.Int64(), ShouldEqual, 7)
+			So(totals.fields[0].doubleSum, ShouldEqual, 2.5)
+			So(totals.fields[1].present, ShouldEqual, 3)
+			So(totals.fields[1].intSum.Int64(), ShouldEqual, 3)
+
+			Convey("and takes the documents the server rejected out of it", func() {
+				rejected := marshal(bson.D{{"amount", 2.5}, {"order", bson.D{{"qty", int32(3)}}}})
+				r.remove([]mongo.BulkWriteError{
+					{Request: mongo.NewInsertOneModel().SetDocument([]byte(rejected))},
+				})
+				totals := r.tally.totals()
+				So(totals.count, ShouldEqual, 3)
+				So(totals.fields[0].present, ShouldEqual, 2)
+				So(totals.fields[0].doubleSum, ShouldEqual, 0)
+				So(totals.fields[1].intSum.Int64(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("the totals are compared", func() {
+			So(imp.validateSettings(), ShouldBeNil)
+			r := imp.reconcile
+			totals := func(count int64, intSum int64, doubleSum float64) reconcileTotals {
+				t := reconcileTotals{count: count, fields: make([]fieldTotals, 2)}
+				for i := range t.fields {
+					t.fields[i].present = count
+					t.fields[i].intSum.SetInt64(intSum)
+					t.fields[i].doubleSum = doubleSum
+					t.fields[i].doubleAbs = math.Abs(doubleSum)
+				}
+				return t
+			}
+
+			result := r.compare(totals(10, 1<<40, 0.3), totals(10, 1<<40, 0.1+0.2))
+			So(result.Match, ShouldBeTrue)
+			So(result.Fields[0].IntSum.Client, ShouldEqual, "1099511627776")
+
+			result = r.compare(totals(10, 5, 1), totals(9, 5, 1))
+			So(result.Match, ShouldBeFalse)
+			So(result.Count.Match, ShouldBeFalse)
+			So(result.Fields[0].Match, ShouldBeFalse)
+
+			result = r.compare(totals(10, 5, 1), totals(10, 6, 1))
+			So(result.Count.Match, ShouldBeTrue)
+			So(result.Fields[1].IntSum.Match, ShouldBeFalse)
+			So(result.Fields[1].DoubleSum.Match, ShouldBeTrue)
+
+			result = r.compare(totals(10, 5, math.NaN()), totals(10, 5, math.NaN()))
+			So(result.Match, ShouldBeTrue)
+			So(result.Fields[0].DoubleSum.Server, ShouldEqual, "NaN")
+		})
+
+		Convey("the server's totals since the start are the ones compared", func() {
+			So(imp.validateSettings(), ShouldBeNil)
+			before := reconcileTotals{count: 5, fields: make([]fieldTotals, 2)}
+			before.fields[0].intSum.SetInt64(100)
+			after := reconcileTotals{count: 8, fields: make([]fieldTotals, 2)}
+			after.fields[0].intSum.SetInt64(130)
+			after.fields[0].doubleSum = 1.5
+
+			delta := after.minus(before)
+			So(delta.count, ShouldEqual, 3)
+			So(delta.fields[0].intSum.Int64(), ShouldEqual, 30)
+			So(delta.fields[0].doubleSum, ShouldEqual, 1.5)
+		})
+
+		Convey("the report lists every import", func() {
+			So(imp.validateSettings(), ShouldBeNil)
+			r := imp.reconcile
+			r.report.Collections = append(r.report.Collections, reconcileCollection{
+				Namespace: "test.orders",
+				Match:     true,
+				Fields: []reconcileField{{
+					Field:     "amount",
+					DoubleSum: reconcileValue{formatSum(math.Inf(1)), formatSum(2.5), false},
+				}},
+			})
+			r.report.Match = false
+			So(r.write(), ShouldBeNil)
+
+			content, err := os.ReadFile(imp.IngestOptions.ReconcileFile)
+			So(err, ShouldBeNil)
+			var report map[string]interface{}
+			So(json.Unmarshal(content, &report), ShouldBeNil)
+			So(report["match"], ShouldEqual, false)
+			collections := report["collections"].([]interface{})
+			So(collections, ShouldHaveLength, 1)
+			So(collections[0].(map[string]interface{})["namespace"], ShouldEqual, "test.orders")
+			So(string(content), ShouldContainSubstring, `"client": "+Inf"`)
+		})
+	})
+
+	Convey("The aggregation totals each field", t, func() {
+		r := &reconciler{fields: []string{"amount"}}
+		pipeline := r.pipeline()
+		So(pipeline, ShouldHaveLength, 1)
+		group := pipeline[0][0].Value.(bson.D)
+		var keys []string
+		for _, elem := range group {
+			keys = append(keys, elem.Key)
+		}
+		So(keys, ShouldResemble, []string{"_id", "count", "present0", "int0", "double0"})
+	})
+}
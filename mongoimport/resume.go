@@ -0,0 +1,386 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// resumeSaveInterval is how often the --resumeFile is saved while the import
+// makes progress. It is also saved when the import ends, however it ends.
+const resumeSaveInterval = time.Second
+
+// resumeState is the content of a --resumeFile.
+type resumeState struct {
+	// File is the --file the state belongs to.
+	File string `bson:"file"`
+	// HeaderEnd is the offset in the file where the records start, after the
+	// byte order mark and the header line, if any.
+	HeaderEnd int64 `bson:"headerEnd"`
+	// Offset is the offset in the file after the last record that was
+	// imported along with every record before it.
+	Offset int64 `bson:"offset"`
+	// Records is the number of records before Offset. It is the number of
+	// lines after the header, unless CSV fields span lines.
+	Records int64 `bson:"records"`
+}
+
+// positionedReader is an InputReader that knows how many bytes of its input
+// the records it read so far took up, so that a --resumeFile can record
+// where they end.
+type positionedReader interface {
+	consumed() int64
+}
+
+// resumeMarkKey is the key of the element that holds the resumeMark of a
+// document. No field of a record can have it, since BSON keys can't hold a
+// NUL byte.
+const resumeMarkKey = "\x00resumeMark"
+
+// resumeMark identifies a record and where it ends in the file. It travels
+// with the record's document as the value of its last element, from the
+// conversion to the bulk write.
+type resumeMark struct {
+	seq     uint64
+	offset  int64
+	records int64
+}
+
+// resumeTracker keeps a --resumeFile up to date with the position in the
+// file up to which every record is imported, so that an interrupted import of
+// a huge file can be run again with --resume to skip the records before it.
+//
+// The documents of a file are written in batches that different insertion
+// workers may acknowledge out of order, so the position only moves past a
+// record once the records before it are acknowledged too.
+type resumeTracker struct {
+	path string
+
+	// resuming is whether the import skips the records a previous import
+	// saved it got through.
+	resuming bool
+	// shift turns offsets in the input, which has no byte order mark and,
+	// when resuming, skips from the header to the saved offset, into offsets
+	// in the file.
+	shift int64
+	// nextSeq is the seq of the next record read. Only the goroutine reading
+	// the input uses it.
+	nextSeq uint64
+
+	mu    sync.Mutex
+	state resumeState
+	// next is the seq of the first record that isn't imported yet; done holds
+	// the imported records after it.
+	next     uint64
+	done     map[uint64]resumeMark
+	unsaved  bool
+	lastSave time.Time
+}
+
+// validateResume checks the options of a resumable import.
+func (imp *MongoImport) validateResume() error {
+	if imp.InputOptions.ResumeFile == "" {
+		if imp.InputOptions.Resume {
+			return fmt.Errorf("--resume requires --resumeFile")
+		}
+		return nil
+	}
+	if imp.InputOptions.File == "" {
+		return fmt.Errorf("--resumeFile requires --file")
+	}
+	if imp.InputOptions.JSONArray {
+		return fmt.Errorf("cannot use --resumeFile with --jsonArray")
+	}
+	if imp.InputOptions.Resume && imp.IngestOptions.Drop {
+		return fmt.Errorf("cannot use --drop with --resume")
+	}
+	return nil
+}
+
+// openResume starts tracking the import of file, which holds size bytes, in
+// the --resumeFile. With --resume, it returns a reader that skips from the
+// header of the file to where the saved state says the import got.
+func (imp *MongoImport) openResume(file io.Reader, size int64) (io.Reader, error) {
+	path := imp.InputOptions.ResumeFile
+	tracker := &resumeTracker{
+		path:     path,
+		state:    resumeState{File: imp.InputOptions.File},
+		done:     map[uint64]resumeMark{},
+		lastSave: time.Now(),
+	}
+	imp.resume = tracker
+	if !imp.InputOptions.Resume {
+		return file, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Logvf(log.Always, "--resumeFile %#q does not exist; importing the whole file", path)
+		return file, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading --resumeFile: %v", err)
+	}
+	state := resumeState{}
+	if err := bson.UnmarshalExtJSON(content, true, &state); err != nil {
+		return nil, fmt.Errorf("error parsing --resumeFile %#q: %v", path, err)
+	}
+	if state.HeaderEnd < 0 || state.Offset < state.HeaderEnd || state.Records < 0 {
+		return nil, fmt.Errorf("--resumeFile %#q has an invalid position", path)
+	}
+	if state.Offset > size {
+		return nil, fmt.Errorf(
+			"--resumeFile %#q resumes at byte %v, but %v has only %v bytes",
+			path,
+			state.Offset,
+			imp.InputOptions.File,
+			size,
+		)
+	}
+	if state.File != imp.InputOptions.File {
+		log.Logvf(
+			log.Always,
+			"warning: --resumeFile %#q was saved for %v, not %v",
+			path,
+			state.File,
+			imp.InputOptions.File,
+		)
+	}
+	at, ok := file.(io.ReaderAt)
+	if !ok {
+		return nil, fmt.Errorf("cannot resume an import of %v, which is not seekable", state.File)
+	}
+
+	tracker.resuming = true
+	tracker.state.HeaderEnd = state.HeaderEnd
+	tracker.state.Offset = state.Offset
+	tracker.state.Records = state.Records
+	log.Logvf(
+		log.Always,
+		"resuming the import at byte %v, after %v records",
+		state.Offset,
+		state.Records,
+	)
+	return io.MultiReader(
+		io.NewSectionReader(at, 0, state.HeaderEnd),
+		io.NewSectionReader(at, state.Offset, size-state.Offset),
+	), nil
+}
+
+// start is called once the header of the file is read by inputReader. It
+// checks that the header didn't change since the state was saved, and saves
+// the state of an import that starts from the beginning.
+func (t *resumeTracker) start(inputReader InputReader, file io.Reader) error {
+	reader, ok := inputReader.(positionedReader)
+	if !ok {
+		return fmt.Errorf("cannot track the position of the input in --resumeFile")
+	}
+	var bomLength int64
+	if at, ok := file.(io.ReaderAt); ok {
+		bom := make([]byte, len(UTF8_BOM))
+		if n, _ := at.ReadAt(bom, 0); n == len(bom) && bytes.Equal(bom, UTF8_BOM) {
+			bomLength = int64(len(bom))
+		}
+	}
+	headerEnd := reader.consumed() + bomLength
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.resuming {
+		if headerEnd != t.state.HeaderEnd {
+			return fmt.Errorf(
+				"the header of %v changed since --resumeFile %#q was saved",
+				t.state.File,
+				t.path,
+			)
+		}
+		t.shift = t.state.Offset - t.state.HeaderEnd + bomLength
+		return nil
+	}
+	t.state.HeaderEnd = headerEnd
+	t.state.Offset = headerEnd
+	t.shift = bomLength
+	t.unsaved = true
+	return t.saveLocked()
+}
+
+// records returns the number of records the import skips.
+func (t *resumeTracker) records() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return uint64(t.state.Records)
+}
+
+// progress returns a sizeTracker for the progress bar that counts the bytes
+// skipped by resuming as read.
+func (t *resumeTracker) progress(size sizeTracker) sizeTracker {
+	return &shiftedSizeTracker{size, t.shift}
+}
+
+type shiftedSizeTracker struct {
+	sizeTracker
+	shift int64
+}
+
+func (s *shiftedSizeTracker) Size() int64 {
+	return s.sizeTracker.Size() + s.shift
+}
+
+// wrap tags the document converted from a record that ends at offset in the
+// input, and after which the input has records records, with a resumeMark.
+func (t *resumeTracker) wrap(converter Converter, offset int64, records uint64) Converter {
+	mark := resumeMark{seq: t.nextSeq, offset: offset + t.shift, records: int64(records)}
+	t.nextSeq++
+	return resumeConverter{converter, t, mark}
+}
+
+// resumeConverter converts a record, and appends its resumeMark to the
+// document so that the insertion worker can report when it is imported. A
+// skipped record is done once it is converted.
+type resumeConverter struct {
+	Converter
+	tracker *resumeTracker
+	mark    resumeMark
+}
+
+func (c resumeConverter) Convert() (bson.D, error) {
+	document, err := c.Converter.Convert()
+	if err != nil {
+		return nil, err
+	}
+	if document == nil {
+		c.tracker.recordsDone([]resumeMark{c.mark})
+		return nil, nil
+	}
+	return append(document, bson.E{Key: resumeMarkKey, Value: c.mark}), nil
+}
+
+// splitResumeMark removes the resumeMark that resumeConverter appended to
+// document.
+func splitResumeMark(document bson.D) (bson.D, resumeMark) {
+	last := len(document) - 1
+	if last < 0 || document[last].Key != resumeMarkKey {
+		panic("document has no resume mark")
+	}
+	//nolint:errcheck
+	mark := document[last].Value.(resumeMark)
+	return document[:last], mark
+}
+
+// batchDone records that a bulk write of the documents of marks ended with
+// err, which is nil if the import continues through it. Documents in a
+// failed ordered write are imported up to the first that failed; the whole
+// of a failed unordered write is imported again by --resume.
+func (t *resumeTracker) batchDone(marks []resumeMark, err error, ordered bool) {
+	if err != nil {
+		var bwe mongo.BulkWriteException
+		if !ordered || !errors.As(err, &bwe) || len(bwe.WriteErrors) == 0 {
+			return
+		}
+		first := bwe.WriteErrors[0].Index
+		for _, writeErr := range bwe.WriteErrors {
+			if writeErr.Index < first {
+				first = writeErr.Index
+			}
+		}
+		if first < len(marks) {
+			marks = marks[:first]
+		}
+	}
+	t.recordsDone(marks)
+}
+
+// recordsDone records that the records of marks are imported, and saves the
+// state if it moved and wasn't saved for a while.
+func (t *resumeTracker) recordsDone(marks []resumeMark) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, mark := range marks {
+		t.done[mark.seq] = mark
+	}
+	for {
+		mark, ok := t.done[t.next]
+		if !ok {
+			break
+		}
+		delete(t.done, t.next)
+		t.next++
+		t.state.Offset = mark.offset
+		t.state.Records = mark.records
+		t.unsaved = true
+	}
+	if t.unsaved && time.Since(t.lastSave) >= resumeSaveInterval {
+		if err := t.saveLocked(); err != nil {
+			log.Logvf(log.Always, "warning: %v", err)
+		}
+	}
+}
+
+// save saves the state if it changed since it was last saved, and logs how
+// far the import got.
+func (t *resumeTracker) save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.saveLocked(); err != nil {
+		return err
+	}
+	log.Logvf(
+		log.Always,
+		"saved the import position in --resumeFile %#q: byte %v, after %v records",
+		t.path,
+		t.state.Offset,
+		t.state.Records,
+	)
+	return nil
+}
+
+// saveLocked atomically replaces the --resumeFile, by writing a temporary
+// file in the same directory and renaming it over the state.
+func (t *resumeTracker) saveLocked() error {
+	if !t.unsaved {
+		return nil
+	}
+	content, err := bson.MarshalExtJSON(t.state, true, false)
+	if err != nil {
+		return fmt.Errorf("error encoding --resumeFile: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), filepath.Base(t.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("error writing --resumeFile: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(content, '\n'))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), t.path)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing --resumeFile: %v", err)
+	}
+	t.unsaved = false
+	t.lastSave = time.Now()
+	log.Logvf(log.DebugHigh, "saved the import position at byte %v", t.state.Offset)
+	return nil
+}
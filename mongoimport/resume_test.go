@@ -0,0 +1,204 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// readResumable reads the records of the --file of imp the way
+// ImportDocuments does, and returns their documents and marks.
+func readResumable(imp *MongoImport) ([]bson.D, []resumeMark) {
+	file, err := os.Open(imp.InputOptions.File)
+	So(err, ShouldBeNil)
+	defer file.Close()
+	info, err := file.Stat()
+	So(err, ShouldBeNil)
+
+	input, err := imp.openResume(file, info.Size())
+	So(err, ShouldBeNil)
+	inputReader, err := imp.getHeaderedInputReader(input)
+	So(err, ShouldBeNil)
+	So(imp.resume.start(inputReader, file), ShouldBeNil)
+
+	docChan := make(chan bson.D, 10)
+	So(inputReader.StreamDocument(true, docChan), ShouldBeNil)
+	var docs []bson.D
+	var marks []resumeMark
+	for doc := range docChan {
+		doc, mark := splitResumeMark(doc)
+		docs = append(docs, doc)
+		marks = append(marks, mark)
+	}
+	return docs, marks
+}
+
+// fixedConverter converts to its document.
+type fixedConverter struct {
+	document bson.D
+}
+
+func (c fixedConverter) Convert() (bson.D, error) {
+	return append(bson.D{}, c.document...), nil
+}
+
+func TestResumeFile(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With --resumeFile", t, func() {
+		imp := NewMockMongoImport()
+		imp.InputOptions.File = "data.csv"
+		imp.InputOptions.Type = CSV
+		imp.InputOptions.HeaderLine = true
+		imp.InputOptions.ResumeFile = "data.resume"
+
+		Convey("the options are valid", func() {
+			So(imp.validateSettings(), ShouldBeNil)
+			imp.InputOptions.Resume = true
+			So(imp.validateSettings(), ShouldBeNil)
+		})
+
+		Convey("--file is required", func() {
+			imp.InputOptions.File = ""
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("--jsonArray is not supported", func() {
+			imp.InputOptions.Type = JSON
+			imp.InputOptions.HeaderLine = false
+			imp.InputOptions.JSONArray = true
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("--resume cannot drop the collection", func() {
+			imp.InputOptions.Resume = true
+			imp.IngestOptions.Drop = true
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+
+		Convey("--resume requires it", func() {
+			imp.InputOptions.ResumeFile = ""
+			imp.InputOptions.Resume = true
+			So(imp.validateSettings(), ShouldNotBeNil)
+		})
+	})
+
+	Convey("The position should only move past records imported in order", t, func() {
+		tracker := &resumeTracker{done: map[uint64]resumeMark{}}
+		marks := make([]resumeMark, 6)
+		for i := range marks {
+			marks[i] = resumeMark{seq: uint64(i), offset: int64(10 * (i + 1)), records: int64(i + 1)}
+		}
+
+		tracker.batchDone(marks[2:4], nil, false)
+		So(tracker.state.Offset, ShouldEqual, 0)
+		tracker.batchDone(marks[0:2], nil, false)
+		So(tracker.state.Offset, ShouldEqual, 40)
+		So(tracker.state.Records, ShouldEqual, 4)
+
+		Convey("an unordered write that fails is not imported", func() {
+			err := mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{{}}}
+			tracker.batchDone(marks[4:6], err, false)
+			So(tracker.state.Records, ShouldEqual, 4)
+		})
+
+		Convey("an ordered write that fails is imported up to the failed document", func() {
+			err := mongo.BulkWriteException{
+				WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Index: 1}}},
+			}
+			tracker.batchDone(marks[4:6], err, true)
+			So(tracker.state.Records, ShouldEqual, 5)
+			So(tracker.state.Offset, ShouldEqual, 50)
+		})
+	})
+
+	Convey("The mark should not be confused with a field without a name", t, func() {
+		tracker := &resumeTracker{done: map[uint64]resumeMark{}}
+		converter := tracker.wrap(fixedConverter{bson.D{{"a", 1}, {"", 2}}}, 10, 1)
+		doc, err := converter.Convert()
+		So(err, ShouldBeNil)
+		So(doc, ShouldHaveLength, 3)
+		So(doc[2].Key, ShouldNotBeEmpty)
+
+		doc, mark := splitResumeMark(doc)
+		So(doc, ShouldResemble, bson.D{{"a", 1}, {"", 2}})
+		So(mark.offset, ShouldEqual, 10)
+		So(func() { splitResumeMark(doc) }, ShouldPanic)
+	})
+
+	Convey("An import should resume after the saved record", t, func() {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "data.csv")
+		content := string(UTF8_BOM) + "a,b\n1,x\n2,\"y\ny\"\n3,z\n"
+		So(os.WriteFile(path, []byte(content), 0o644), ShouldBeNil)
+
+		imp := NewMockMongoImport()
+		imp.InputOptions.File = path
+		imp.InputOptions.Type = CSV
+		imp.InputOptions.HeaderLine = true
+		imp.InputOptions.ResumeFile = filepath.Join(dir, "data.resume")
+
+		docs, marks := readResumable(imp)
+		So(docs, ShouldHaveLength, 3)
+		So(docs[2], ShouldResemble, bson.D{{"a", int32(3)}, {"b", "z"}})
+		So(imp.resume.state.HeaderEnd, ShouldEqual, 7)
+		So([]int64{marks[0].offset, marks[1].offset, marks[2].offset}, ShouldResemble,
+			[]int64{11, 19, 23})
+		So(marks[2].records, ShouldEqual, 3)
+
+		imp.resume.batchDone(marks[:2], nil, false)
+		So(imp.resume.save(), ShouldBeNil)
+
+		imp = NewMockMongoImport()
+		imp.InputOptions.File = path
+		imp.InputOptions.Type = CSV
+		imp.InputOptions.HeaderLine = true
+		imp.InputOptions.ResumeFile = filepath.Join(dir, "data.resume")
+		imp.InputOptions.Resume = true
+
+		docs, marks = readResumable(imp)
+		So(docs, ShouldResemble, []bson.D{{{"a", int32(3)}, {"b", "z"}}})
+		So(marks[0].offset, ShouldEqual, 23)
+		So(marks[0].records, ShouldEqual, 3)
+
+		Convey("unless the header changed", func() {
+			content := "a,b,c\n1,x\n2,\"y\ny\"\n3,z\n"
+			So(os.WriteFile(path, []byte(content), 0o644), ShouldBeNil)
+			file, err := os.Open(path)
+			So(err, ShouldBeNil)
+			defer file.Close()
+			input, err := imp.openResume(file, int64(len(content)))
+			So(err, ShouldBeNil)
+			inputReader, err := imp.getHeaderedInputReader(input)
+			So(err, ShouldBeNil)
+			So(imp.resume.start(inputReader, file), ShouldNotBeNil)
+		})
+	})
+
+	Convey("JSON documents should be tracked by the offset after each one", t, func() {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "data.json")
+		So(os.WriteFile(path, []byte("{\"a\":1}\n{\"a\":22}\n"), 0o644), ShouldBeNil)
+
+		imp := NewMockMongoImport()
+		imp.InputOptions.File = path
+		imp.InputOptions.Type = JSON
+		imp.InputOptions.ResumeFile = filepath.Join(dir, "data.resume")
+
+		docs, marks := readResumable(imp)
+		So(docs, ShouldHaveLength, 2)
+		So(marks[0].offset, ShouldEqual, 7)
+		So(marks[1].offset, ShouldEqual, 16)
+	})
+}
@@ -0,0 +1,192 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/progress"
+	"github.com/mongodb/mongo-tools/common/text"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// fullQueue is the share of its capacity from which a queue between two
+// stages counts as full, which means that the stage reading it is slower
+// than the one writing it.
+const fullQueue = 0.9
+
+// importStages counts the records that go through each stage of an import
+// for --progressStages: the input is read and split into records, which the
+// decoding workers convert into documents, which the insertion workers send
+// to the server. Its methods do nothing on a nil importStages.
+type importStages struct {
+	// records the decoding workers took from the decode queue
+	decoded atomic.Int64
+	// documents the decoding workers produced
+	converted atomic.Int64
+	// documents the server acknowledged
+	inserted atomic.Int64
+
+	mu sync.Mutex
+	// records waiting for a decoding worker
+	decodeQueue chan Converter
+	// documents waiting for an insertion worker
+	insertQueue chan bson.D
+}
+
+// stageSample is what importStages counted at a point in time.
+type stageSample struct {
+	at        time.Time
+	read      int64
+	parsed    int64
+	converted int64
+	inserted  int64
+
+	decodeQueue, decodeCap int
+	insertQueue, insertCap int
+}
+
+// watchDecodeQueue sets the channel on which records wait to be decoded.
+func (s *importStages) watchDecodeQueue(queue chan Converter) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.decodeQueue = queue
+}
+
+// watchInsertQueue sets the channel on which documents wait to be inserted.
+func (s *importStages) watchInsertQueue(queue chan bson.D) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.insertQueue = queue
+}
+
+// took counts a record taken by a decoding worker.
+func (s *importStages) took() {
+	if s != nil {
+		s.decoded.Add(1)
+	}
+}
+
+// produced counts a document produced by a decoding worker.
+func (s *importStages) produced() {
+	if s != nil {
+		s.converted.Add(1)
+	}
+}
+
+// acknowledged counts documents the server acknowledged.
+func (s *importStages) acknowledged(count int64) {
+	if s != nil {
+		s.inserted.Add(count)
+	}
+}
+
+// sample returns the counts so far, with read the number of bytes read from
+// the input. The records parsed are the ones taken by the decoding workers
+// and the ones waiting for them.
+func (s *importStages) sample(read int64) stageSample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sample := stageSample{
+		at:        time.Now(),
+		read:      read,
+		converted: s.converted.Load(),
+		inserted:  s.inserted.Load(),
+	}
+	if s.decodeQueue != nil {
+		sample.decodeQueue, sample.decodeCap = len(s.decodeQueue), cap(s.decodeQueue)
+	}
+	if s.insertQueue != nil {
+		sample.insertQueue, sample.insertCap = len(s.insertQueue), cap(s.insertQueue)
+	}
+	sample.parsed = s.decoded.Load() + int64(sample.decodeQueue)
+	return sample
+}
+
+// bottleneck returns the stage that holds back the import at the time of
+// sample: the first stage, going from the server back to the input, whose
+// queue is full.
+func (sample stageSample) bottleneck() string {
+	switch {
+	case isFull(sample.insertQueue, sample.insertCap):
+		return "insert (server acknowledgment; try more --numInsertionWorkers)"
+	case isFull(sample.decodeQueue, sample.decodeCap):
+		return "convert (CPU)"
+	default:
+		return "read (input)"
+	}
+}
+
+func isFull(depth, capacity int) bool {
+	return capacity > 0 && float64(depth) >= fullQueue*float64(capacity)
+}
+
+// formatStages describes the throughput of each stage between prev and cur,
+// and the queues between them at cur.
+func formatStages(prev, cur stageSample) string {
+	seconds := cur.at.Sub(prev.at).Seconds()
+	if seconds <= 0 {
+		seconds = 1
+	}
+	rate := func(from, to int64) int64 {
+		return int64(float64(to-from) / seconds)
+	}
+	return fmt.Sprintf(
+		"stages: read %v/s, parse %v/s, decode queue %v/%v, convert %v/s, "+
+			"insert queue %v/%v, insert %v/s; bottleneck: %v",
+		text.FormatByteAmount(rate(prev.read, cur.read)),
+		rate(prev.parsed, cur.parsed),
+		cur.decodeQueue,
+		cur.decodeCap,
+		rate(prev.converted, cur.converted),
+		cur.insertQueue,
+		cur.insertCap,
+		rate(prev.inserted, cur.inserted),
+		cur.bottleneck(),
+	)
+}
+
+// report logs the throughput of the stages of the import every
+// progress.DefaultWaitTime, next to the progress bar, until the returned
+// function is called. input counts the bytes read from the input.
+func (s *importStages) report(input sizeTracker) (stop func()) {
+	if s == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(progress.DefaultWaitTime)
+		defer ticker.Stop()
+		prev := s.sample(input.Size())
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				cur := s.sample(input.Size())
+				log.Logv(log.Always, formatStages(prev, cur))
+				prev = cur
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
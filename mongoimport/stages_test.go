@@ -0,0 +1,70 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestImportStages(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("streamDocuments should count the records it converts", t, func() {
+		stages := &importStages{}
+		inputChannel := make(chan Converter, len(csvConverters))
+		outputChannel := make(chan bson.D, len(csvConverters))
+		for _, csvConverter := range csvConverters {
+			inputChannel <- csvConverter
+		}
+		close(inputChannel)
+
+		So(streamDocuments(false, 2, inputChannel, outputChannel, stages), ShouldBeNil)
+		sample := stages.sample(0)
+		So(sample.parsed, ShouldEqual, len(csvConverters))
+		So(sample.converted, ShouldEqual, len(csvConverters))
+		So(sample.decodeCap, ShouldEqual, len(csvConverters))
+	})
+
+	Convey("A nil importStages should count nothing", t, func() {
+		var stages *importStages
+		stages.took()
+		stages.produced()
+		stages.acknowledged(3)
+		stages.watchInsertQueue(make(chan bson.D))
+		stages.report(nil)()
+	})
+
+	Convey("The stages line should show the rates and the bottleneck", t, func() {
+		start := time.Now()
+		prev := stageSample{at: start}
+		cur := stageSample{
+			at:          start.Add(2 * time.Second),
+			read:        4096,
+			parsed:      200,
+			converted:   180,
+			inserted:    100,
+			decodeQueue: 1,
+			decodeCap:   4,
+			insertQueue: 950,
+			insertCap:   1000,
+		}
+		So(formatStages(prev, cur), ShouldEqual,
+			"stages: read 2.00KB/s, parse 100/s, decode queue 1/4, convert 90/s, "+
+				"insert queue 950/1000, insert 50/s; "+
+				"bottleneck: insert (server acknowledgment; try more --numInsertionWorkers)")
+
+		cur.insertQueue = 10
+		So(cur.bottleneck(), ShouldEqual, "read (input)")
+		cur.decodeQueue = 4
+		So(cur.bottleneck(), ShouldEqual, "convert (CPU)")
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 )
@@ -50,16 +51,40 @@ type TSVInputReader struct {
 
 	// useArrayIndexFields is whether field names include array indexes
 	useArrayIndexFields bool
+
+	// columnsMismatchPolicy is how rows with an unexpected number of fields are handled
+	columnsMismatchPolicy ColumnsMismatchPolicy
+
+	// documentErrorHandler is called for each skipped row, if set
+	documentErrorHandler DocumentErrorHandler
+
+	// whitespace is how whitespace is cleaned out of every field
+	whitespace WhitespacePolicy
+
+	// headerPolicy is how invalid or duplicate names in the header are handled
+	headerPolicy HeaderPolicy
+
+	// timezone is the location of the dates without a time zone, if set
+	timezone *time.Location
+
+	// resume tracks the position of each record in the --resumeFile, if set
+	resume *resumeTracker
+
+	// stages counts the records of --progressStages, if set
+	stages *importStages
 }
 
 // TSVConverter implements the Converter interface for TSV input.
 type TSVConverter struct {
-	colSpecs            []ColumnSpec
-	data                string
-	index               uint64
-	ignoreBlanks        bool
-	useArrayIndexFields bool
-	rejectWriter        io.Writer
+	colSpecs              []ColumnSpec
+	data                  string
+	index                 uint64
+	ignoreBlanks          bool
+	useArrayIndexFields   bool
+	columnsMismatchPolicy ColumnsMismatchPolicy
+	rejectWriter          io.Writer
+	documentErrorHandler  DocumentErrorHandler
+	whitespace            WhitespacePolicy
 }
 
 // NewTSVInputReader returns a TSVInputReader configured to read input from the
@@ -71,17 +96,19 @@ func NewTSVInputReader(
 	numDecoders int,
 	ignoreBlanks bool,
 	useArrayIndexFields bool,
+	columnsMismatchPolicy ColumnsMismatchPolicy,
 ) *TSVInputReader {
 	szCount := newSizeTrackingReader(newBomDiscardingReader(in))
 	return &TSVInputReader{
-		colSpecs:            colSpecs,
-		tsvReader:           bufio.NewReader(szCount),
-		tsvRejectWriter:     rejects,
-		numProcessed:        uint64(0),
-		numDecoders:         numDecoders,
-		sizeTracker:         szCount,
-		ignoreBlanks:        ignoreBlanks,
-		useArrayIndexFields: useArrayIndexFields,
+		colSpecs:              colSpecs,
+		tsvReader:             bufio.NewReader(szCount),
+		tsvRejectWriter:       newRejectWriter(rejects),
+		numProcessed:          uint64(0),
+		numDecoders:           numDecoders,
+		sizeTracker:           szCount,
+		ignoreBlanks:          ignoreBlanks,
+		useArrayIndexFields:   useArrayIndexFields,
+		columnsMismatchPolicy: columnsMismatchPolicy,
 	}
 }
 
@@ -97,6 +124,7 @@ func (r *TSVInputReader) ReadAndValidateHeader() (err error) {
 		headerFields = append(headerFields, strings.TrimRight(field, "\r\n"))
 	}
 	r.colSpecs = ParseAutoHeaders(headerFields)
+	applyHeaderPolicy(r.colSpecs, r.headerPolicy, r.useArrayIndexFields)
 	return validateReaderFields(ColumnNames(r.colSpecs), r.useArrayIndexFields)
 }
 
@@ -115,6 +143,9 @@ func (r *TSVInputReader) ReadAndValidateTypedHeader(parseGrace ParseGrace) (err
 	if err != nil {
 		return err
 	}
+	applyWhitespacePolicy(r.colSpecs, r.whitespace)
+	applyTimezone(r.colSpecs, r.timezone)
+	applyHeaderPolicy(r.colSpecs, r.headerPolicy, r.useArrayIndexFields)
 	return validateReaderFields(ColumnNames(r.colSpecs), r.useArrayIndexFields)
 }
 
@@ -140,38 +171,55 @@ func (r *TSVInputReader) StreamDocument(ordered bool, readDocs chan bson.D) (ret
 				}
 				return
 			}
-			tsvRecordChan <- TSVConverter{
-				colSpecs:            r.colSpecs,
-				data:                r.tsvRecord,
-				index:               r.numProcessed,
-				ignoreBlanks:        r.ignoreBlanks,
-				useArrayIndexFields: r.useArrayIndexFields,
-				rejectWriter:        r.tsvRejectWriter,
+			var converter Converter = TSVConverter{
+				colSpecs:              r.colSpecs,
+				data:                  r.tsvRecord,
+				index:                 r.numProcessed,
+				ignoreBlanks:          r.ignoreBlanks,
+				useArrayIndexFields:   r.useArrayIndexFields,
+				columnsMismatchPolicy: r.columnsMismatchPolicy,
+				rejectWriter:          r.tsvRejectWriter,
+				documentErrorHandler:  r.documentErrorHandler,
+				whitespace:            r.whitespace,
 			}
 			r.numProcessed++
+			if r.resume != nil {
+				converter = r.resume.wrap(converter, r.consumed(), r.numProcessed)
+			}
+			tsvRecordChan <- converter
 		}
 	}()
 
 	// begin processing read bytes
 	go func() {
-		tsvErrChan <- streamDocuments(ordered, r.numDecoders, tsvRecordChan, readDocs)
+		tsvErrChan <- streamDocuments(ordered, r.numDecoders, tsvRecordChan, readDocs, r.stages)
 	}()
 
 	return channelQuorumError(tsvErrChan)
 }
 
+// consumed returns the number of bytes of input the records read so far take
+// up, including the header.
+func (r *TSVInputReader) consumed() int64 {
+	return r.Size() - int64(r.tsvReader.Buffered())
+}
+
 // Convert implements the Converter interface for TSV input. It converts a
 // TSVConverter struct to a BSON document.
 func (c TSVConverter) Convert() (b bson.D, err error) {
 	b, err = tokensToBSON(
 		c.colSpecs,
-		strings.Split(strings.TrimRight(c.data, "\r\n"), tokenSeparator),
+		c.whitespace.applyAll(strings.Split(strings.TrimRight(c.data, "\r\n"), tokenSeparator)),
 		c.index,
 		c.ignoreBlanks,
 		c.useArrayIndexFields,
+		c.columnsMismatchPolicy,
 	)
-	if _, ok := err.(coercionError); ok {
+	if skipped, ok := err.(SkippedRowError); ok {
 		err = c.Print()
+		if err == nil && c.documentErrorHandler != nil {
+			c.documentErrorHandler(skipped)
+		}
 	}
 	return
 }
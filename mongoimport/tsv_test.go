@@ -8,7 +8,10 @@ package mongoimport
 
 import (
 	"bytes"
+	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/mongodb/mongo-tools/common/testtype"
@@ -38,12 +41,43 @@ func TestTSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 1)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
 			So(<-docChan, ShouldResemble, expectedRead)
 		})
 
+		Convey("rows skipped by several decoders should each be rejected whole", func() {
+			var contents, expectedRejects []string
+			for i := 0; i < 40; i++ {
+				row := fmt.Sprintf("x%v\t%v", i, strings.Repeat("y", 5000))
+				contents = append(contents, row)
+				expectedRejects = append(expectedRejects, row)
+			}
+			colSpecs := []ColumnSpec{
+				{"a", new(FieldInt32Parser), pgSkipRow, "int32", []string{"a"}},
+				{"b", new(FieldAutoParser), pgAutoCast, "auto", []string{"b"}},
+			}
+			rejects := &bytes.Buffer{}
+			r := NewTSVInputReader(
+				colSpecs,
+				strings.NewReader(strings.Join(contents, "\n")+"\n"),
+				rejects,
+				4,
+				false,
+				false,
+				cmDefault,
+			)
+			docChan := make(chan bson.D, 1)
+			So(r.StreamDocument(false, docChan), ShouldBeNil)
+			// each row is rejected with its own newline, and another one
+			rejected := strings.Split(strings.TrimSuffix(rejects.String(), "\n\n"), "\n\n")
+			slices.Sort(rejected)
+			slices.Sort(expectedRejects)
+			So(rejected, ShouldResemble, expectedRejects)
+		})
+
 		Convey("valid TSV input file that starts with the UTF-8 BOM should "+
 			"not raise an error", func() {
 			colSpecs := []ColumnSpec{
@@ -58,7 +92,7 @@ func TestTSVStreamDocument(t *testing.T) {
 			}
 			fileHandle, err := os.Open("testdata/test_bom.tsv")
 			So(err, ShouldBeNil)
-			r := NewTSVInputReader(colSpecs, fileHandle, os.Stdout, 1, false, false)
+			r := NewTSVInputReader(colSpecs, fileHandle, os.Stdout, 1, false, false, cmDefault)
 			docChan := make(chan bson.D, 2)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
 			So(<-docChan, ShouldResemble, expectedRead)
@@ -84,6 +118,7 @@ func TestTSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 1)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
@@ -110,6 +145,7 @@ func TestTSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 1)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
@@ -137,6 +173,7 @@ func TestTSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 1)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
@@ -169,6 +206,7 @@ func TestTSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, len(expectedReads))
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
@@ -205,6 +243,7 @@ func TestTSVStreamDocument(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			docChan := make(chan bson.D, 2)
 			So(r.StreamDocument(true, docChan), ShouldBeNil)
@@ -232,7 +271,7 @@ func TestTSVStreamDocument(t *testing.T) {
 				}
 				fileHandle, err := os.Open("testdata/test.tsv")
 				So(err, ShouldBeNil)
-				r := NewTSVInputReader(colSpecs, fileHandle, os.Stdout, 1, false, false)
+				r := NewTSVInputReader(colSpecs, fileHandle, os.Stdout, 1, false, false, cmDefault)
 				docChan := make(chan bson.D, 50)
 				So(r.StreamDocument(true, docChan), ShouldBeNil)
 				So(<-docChan, ShouldResemble, expectedReadOne)
@@ -254,6 +293,7 @@ func TestTSVReadAndValidateHeader(t *testing.T) {
 				1,
 				false,
 				false,
+				cmDefault,
 			)
 			So(r.ReadAndValidateHeader(), ShouldBeNil)
 			So(len(r.colSpecs), ShouldEqual, 3)
@@ -37,6 +37,11 @@ const (
 	ctInt64
 	ctDecimal
 	ctString
+	ctTimestamp
+	ctMinMaxKey
+	ctDBRef
+	ctEpochSeconds
+	ctEpochMillis
 )
 
 var (
@@ -54,6 +59,12 @@ var (
 		"int32":       ctInt32,
 		"int64":       ctInt64,
 		"string":      ctString,
+		"timestamp":   ctTimestamp,
+		"minmaxkey":   ctMinMaxKey,
+		"dbref":       ctDBRef,
+
+		"epoch_seconds": ctEpochSeconds,
+		"epoch_millis":  ctEpochMillis,
 	}
 )
 
@@ -105,8 +116,17 @@ func ParseTypedHeader(header string, parseGrace ParseGrace) (f ColumnSpec, err e
 	if err != nil {
 		return
 	}
-	nameParts := strings.Split(match[1], ".")
-	return ColumnSpec{match[1], p, parseGrace, match[2], nameParts}, nil
+	name := match[1]
+	if dp, ok := p.(*FieldDBRefParser); ok {
+		// a dbref column is a field of the DBRef document named by the column
+		if name == "" {
+			err = fmt.Errorf("dbref column %s must name the field of the DBRef", header)
+			return
+		}
+		name += "." + dp.field
+	}
+	nameParts := strings.Split(name, ".")
+	return ColumnSpec{name, p, parseGrace, match[2], nameParts}, nil
 }
 
 // ParseTypedHeaders performs ParseTypedHeader on each item, returning an
@@ -119,9 +139,45 @@ func ParseTypedHeaders(headers []string, parseGrace ParseGrace) (fs []ColumnSpec
 			return
 		}
 	}
+	err = validateDBRefColumns(fs)
 	return
 }
 
+// validateDBRefColumns checks that the dbref columns of each DBRef field are
+// ref, id and optionally db, in that order, and come before any other column
+// of the field, so that the documents they build are DBRefs.
+func validateDBRefColumns(fs []ColumnSpec) error {
+	parts := map[string][]string{}
+	last := map[string]int{}
+	var fields []string
+	for i, f := range fs {
+		if _, ok := f.Parser.(*FieldDBRefParser); ok {
+			field := strings.Join(f.NameParts[:len(f.NameParts)-1], ".")
+			if _, ok := parts[field]; !ok {
+				fields = append(fields, field)
+			}
+			parts[field] = append(parts[field], f.NameParts[len(f.NameParts)-1])
+			last[field] = i
+		}
+	}
+	for _, field := range fields {
+		got := strings.Join(parts[field], ",")
+		if got != "$ref,$id" && got != "$ref,$id,$db" {
+			return fmt.Errorf(
+				"the dbref columns of %s must be ref, id and optionally db, in that order; got %s",
+				field,
+				got,
+			)
+		}
+		for _, f := range fs[:last[field]] {
+			if _, ok := f.Parser.(*FieldDBRefParser); !ok && strings.HasPrefix(f.Name, field+".") {
+				return fmt.Errorf("column %s comes before the dbref columns of %s", f.Name, field)
+			}
+		}
+	}
+	return nil
+}
+
 // ParseAutoHeaders converts a list of header items to ColumnSpec objects, with
 // automatic parsers.
 func ParseAutoHeaders(headers []string) (fs []ColumnSpec) {
@@ -161,6 +217,8 @@ func NewFieldParser(t columnType, arg string) (parser FieldParser, err error) {
 	case ctDateGo:
 	case ctDateMS:
 	case ctDateOracle:
+	case ctString:
+	case ctDBRef:
 	default:
 		if arg != "" {
 			err = fmt.Errorf("type %v does not support arguments", t)
@@ -176,11 +234,11 @@ func NewFieldParser(t columnType, arg string) (parser FieldParser, err error) {
 	case ctDate:
 		fallthrough
 	case ctDateGo:
-		parser = &FieldDateParser{arg}
+		parser = &FieldDateParser{layout: arg}
 	case ctDateMS:
-		parser = &FieldDateParser{dateconv.FromMS(arg)}
+		parser = &FieldDateParser{layout: dateconv.FromMS(arg)}
 	case ctDateOracle:
-		parser = &FieldDateParser{dateconv.FromOracle(arg)}
+		parser = &FieldDateParser{layout: dateconv.FromOracle(arg)}
 	case ctDouble:
 		parser = new(FieldDoubleParser)
 	case ctInt32:
@@ -190,7 +248,19 @@ func NewFieldParser(t columnType, arg string) (parser FieldParser, err error) {
 	case ctDecimal:
 		parser = new(FieldDecimalParser)
 	case ctString:
-		parser = new(FieldStringParser)
+		var trim TrimMode
+		trim, err = ParseTrimMode(arg)
+		parser = &FieldStringParser{WhitespacePolicy{Trim: trim}}
+	case ctTimestamp:
+		parser = new(FieldTimestampParser)
+	case ctMinMaxKey:
+		parser = new(FieldMinMaxKeyParser)
+	case ctDBRef:
+		parser, err = NewFieldDBRefParser(arg)
+	case ctEpochSeconds:
+		parser = &FieldEpochParser{unit: time.Second}
+	case ctEpochMillis:
+		parser = &FieldEpochParser{unit: time.Millisecond}
 	default: // ctAuto
 		parser = new(FieldAutoParser)
 	}
@@ -253,14 +323,61 @@ func (bp *FieldBooleanParser) Parse(in string) (interface{}, error) {
 	return nil, fmt.Errorf("failed to parse boolean: %s", in)
 }
 
+// FieldDateParser parses dates in a layout. Dates without a time zone are in
+// its location, which is UTC unless --timezone sets it.
 type FieldDateParser struct {
-	layout string
+	layout   string
+	location *time.Location
 }
 
 func (dp *FieldDateParser) Parse(in string) (interface{}, error) {
+	if dp.location != nil {
+		return time.ParseInLocation(dp.layout, in, dp.location)
+	}
 	return time.Parse(dp.layout, in)
 }
 
+// FieldEpochParser parses dates written as an integer number of units, e.g.
+// seconds or milliseconds, since the Unix epoch.
+type FieldEpochParser struct {
+	unit time.Duration
+}
+
+func (ep *FieldEpochParser) Parse(in string) (interface{}, error) {
+	n, err := strconv.ParseInt(in, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse epoch %v: %s", ep.unitName(), in)
+	}
+	if ep.unit == time.Millisecond {
+		return time.UnixMilli(n).UTC(), nil
+	}
+	return time.Unix(n, 0).UTC(), nil
+}
+
+func (ep *FieldEpochParser) unitName() string {
+	if ep.unit == time.Millisecond {
+		return "milliseconds"
+	}
+	return "seconds"
+}
+
+// applyTimezone makes the date columns, including DBRef ids that are dates,
+// parse the dates without a time zone in location. A nil location keeps UTC.
+func applyTimezone(colSpecs []ColumnSpec, location *time.Location) {
+	if location == nil {
+		return
+	}
+	for _, colSpec := range colSpecs {
+		parser := colSpec.Parser
+		if dp, ok := parser.(*FieldDBRefParser); ok {
+			parser = dp.parser
+		}
+		if dp, ok := parser.(*FieldDateParser); ok {
+			dp.location = location
+		}
+	}
+}
+
 type FieldDoubleParser struct{}
 
 func (dp *FieldDoubleParser) Parse(in string) (interface{}, error) {
@@ -286,8 +403,89 @@ func (ip *FieldDecimalParser) Parse(in string) (interface{}, error) {
 	return primitive.ParseDecimal128(in)
 }
 
-type FieldStringParser struct{}
+// FieldStringParser parses string fields, trimming them as its argument says.
+type FieldStringParser struct {
+	whitespace WhitespacePolicy
+}
 
 func (sp *FieldStringParser) Parse(in string) (interface{}, error) {
-	return in, nil
+	return sp.whitespace.Apply(in), nil
+}
+
+// FieldTimestampParser parses BSON timestamps written as
+// "<seconds>,<increment>".
+type FieldTimestampParser struct{}
+
+func (tp *FieldTimestampParser) Parse(in string) (interface{}, error) {
+	seconds, increment, ok := strings.Cut(in, ",")
+	if !ok {
+		return nil, fmt.Errorf("failed to parse timestamp, expected <seconds>,<increment>: %s", in)
+	}
+	t, err := strconv.ParseUint(strings.TrimSpace(seconds), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp seconds: %v", err)
+	}
+	i, err := strconv.ParseUint(strings.TrimSpace(increment), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp increment: %v", err)
+	}
+	return primitive.Timestamp{T: uint32(t), I: uint32(i)}, nil
+}
+
+// FieldMinMaxKeyParser parses the MinKey and MaxKey sentinels, written as
+// "minKey" and "maxKey" in any case.
+type FieldMinMaxKeyParser struct{}
+
+func (mp *FieldMinMaxKeyParser) Parse(in string) (interface{}, error) {
+	switch strings.ToLower(in) {
+	case "minkey":
+		return primitive.MinKey{}, nil
+	case "maxkey":
+		return primitive.MaxKey{}, nil
+	}
+	return nil, fmt.Errorf("failed to parse minKey or maxKey: %s", in)
+}
+
+// FieldDBRefParser parses one of the fields of a DBRef. Its argument names
+// the field: ref and db take the collection and database names as they are,
+// and id takes the referenced _id, which is parsed automatically unless its
+// type is given as id:<type>, e.g. id:objectid or id:int64.
+type FieldDBRefParser struct {
+	field  string
+	parser FieldParser
+}
+
+func (dp *FieldDBRefParser) Parse(in string) (interface{}, error) {
+	return dp.parser.Parse(in)
+}
+
+func NewFieldDBRefParser(arg string) (*FieldDBRefParser, error) {
+	part, idType, hasType := strings.Cut(arg, ":")
+	switch {
+	case part == "ref" && !hasType:
+		return &FieldDBRefParser{"$ref", new(FieldStringParser)}, nil
+	case part == "db" && !hasType:
+		return &FieldDBRefParser{"$db", new(FieldStringParser)}, nil
+	case part == "id" && !hasType:
+		return &FieldDBRefParser{"$id", new(FieldAutoParser)}, nil
+	case part == "id" && idType == "objectid":
+		return &FieldDBRefParser{"$id", new(fieldObjectIDParser)}, nil
+	case part == "id":
+		t, ok := columnTypeNameMap[idType]
+		if !ok || t == ctDBRef {
+			return nil, fmt.Errorf("invalid dbref id type: %s", idType)
+		}
+		parser, err := NewFieldParser(t, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid dbref id type %s: %v", idType, err)
+		}
+		return &FieldDBRefParser{"$id", parser}, nil
+	}
+	return nil, fmt.Errorf("invalid dbref field, expected ref, id or db: %s", arg)
+}
+
+type fieldObjectIDParser struct{}
+
+func (op *fieldObjectIDParser) Parse(in string) (interface{}, error) {
+	return primitive.ObjectIDFromHex(in)
 }
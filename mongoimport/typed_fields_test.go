@@ -14,6 +14,7 @@ import (
 	"github.com/mongodb/mongo-tools/common/options"
 	"github.com/mongodb/mongo-tools/common/testtype"
 	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -42,7 +43,7 @@ func TestTypedHeaderParser(t *testing.T) {
 					{"foo", new(FieldAutoParser), pgAutoCast, "auto", []string{"foo"}},
 					{
 						"bar",
-						&FieldDateParser{"January 2, (2006)"},
+						&FieldDateParser{layout: "January 2, (2006)"},
 						pgAutoCast,
 						"date",
 						[]string{"bar"},
@@ -58,7 +59,7 @@ func TestTypedHeaderParser(t *testing.T) {
 					{"foo", new(FieldAutoParser), pgSkipRow, "auto", []string{"foo"}},
 					{
 						"bar",
-						&FieldDateParser{"January 2, (2006)"},
+						&FieldDateParser{layout: "January 2, (2006)"},
 						pgSkipRow,
 						"date",
 						[]string{"bar"},
@@ -316,6 +317,56 @@ func TestFieldParsers(t *testing.T) {
 				So(err, ShouldNotBeNil)
 			})
 		})
+		Convey("with a timezone", func() {
+			est := time.FixedZone("EST", -5*60*60)
+			naive, _ := NewFieldParser(ctDate, "2006-01-02 15:04:05")
+			zoned, _ := NewFieldParser(ctDate, "2006-01-02 15:04:05 -0700")
+			applyTimezone([]ColumnSpec{{Parser: naive}, {Parser: zoned}}, est)
+			Convey("parses dates without a time zone in it", func() {
+				value, err = naive.Parse("2000-01-04 17:38:10")
+				So(err, ShouldBeNil)
+				So(
+					cast[time.Time](value).Equal(time.Date(2000, 1, 4, 22, 38, 10, 0, time.UTC)),
+					ShouldBeTrue,
+				)
+			})
+			Convey("keeps the time zone of dates that have one", func() {
+				value, err = zoned.Parse("2000-01-04 17:38:10 +0000")
+				So(err, ShouldBeNil)
+				So(
+					cast[time.Time](value).Equal(time.Date(2000, 1, 4, 17, 38, 10, 0, time.UTC)),
+					ShouldBeTrue,
+				)
+			})
+		})
+	})
+
+	Convey("Using FieldEpochParser", t, func() {
+		Convey("parses epoch seconds", func() {
+			p, err := NewFieldParser(ctEpochSeconds, "")
+			So(err, ShouldBeNil)
+			value, err := p.Parse("946707490")
+			So(err, ShouldBeNil)
+			So(cast[time.Time](value), ShouldResemble, time.Date(2000, 1, 1, 6, 18, 10, 0, time.UTC))
+		})
+		Convey("parses epoch milliseconds", func() {
+			p, err := NewFieldParser(ctEpochMillis, "")
+			So(err, ShouldBeNil)
+			value, err := p.Parse("946707490123")
+			So(err, ShouldBeNil)
+			So(
+				cast[time.Time](value),
+				ShouldResemble,
+				time.Date(2000, 1, 1, 6, 18, 10, 123e6, time.UTC),
+			)
+		})
+		Convey("rejects values that are not integers", func() {
+			p, _ := NewFieldParser(ctEpochSeconds, "")
+			_, err := p.Parse("946707490.5")
+			So(err, ShouldNotBeNil)
+			_, err = NewFieldParser(ctEpochMillis, "arg")
+			So(err, ShouldNotBeNil)
+		})
 	})
 
 	Convey("Using FieldDoubleParser", t, func() {
@@ -447,4 +498,126 @@ func TestFieldParsers(t *testing.T) {
 		})
 	})
 
+	Convey("Using FieldTimestampParser", t, func() {
+		var p, _ = NewFieldParser(ctTimestamp, "")
+
+		Convey("parses seconds and increments", func() {
+			value, err := p.Parse("1700000000,3")
+			So(err, ShouldBeNil)
+			So(value, ShouldResemble, primitive.Timestamp{T: 1700000000, I: 3})
+			value, err = p.Parse(" 4294967295 , 0 ")
+			So(err, ShouldBeNil)
+			So(value, ShouldResemble, primitive.Timestamp{T: 4294967295})
+		})
+		Convey("does not parse invalid timestamps", func() {
+			for _, ts := range []string{"", "1700000000", "1,2,3", "-1,2", "4294967296,0", "a,b"} {
+				_, err := p.Parse(ts)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+
+	Convey("Using FieldMinMaxKeyParser", t, func() {
+		var p, _ = NewFieldParser(ctMinMaxKey, "")
+
+		Convey("parses minKey and maxKey in any case", func() {
+			value, err := p.Parse("minKey")
+			So(err, ShouldBeNil)
+			So(value, ShouldResemble, primitive.MinKey{})
+			value, err = p.Parse("MAXKEY")
+			So(err, ShouldBeNil)
+			So(value, ShouldResemble, primitive.MaxKey{})
+		})
+		Convey("does not parse other values", func() {
+			for _, ts := range []string{"", "min", "$minKey", "1"} {
+				_, err := p.Parse(ts)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+
+	Convey("Using FieldDBRefParser", t, func() {
+		Convey("parses ids of the given type", func() {
+			p, err := NewFieldParser(ctDBRef, "id:objectid")
+			So(err, ShouldBeNil)
+			value, err := p.Parse("5f0c9c1e8b3e4a2b1c0d9e8f")
+			So(err, ShouldBeNil)
+			So(cast[primitive.ObjectID](value).Hex(), ShouldEqual, "5f0c9c1e8b3e4a2b1c0d9e8f")
+			_, err = p.Parse("42")
+			So(err, ShouldNotBeNil)
+
+			p, err = NewFieldParser(ctDBRef, "id:int64")
+			So(err, ShouldBeNil)
+			value, err = p.Parse("42")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, int64(42))
+
+			p, err = NewFieldParser(ctDBRef, "id")
+			So(err, ShouldBeNil)
+			value, err = p.Parse("42")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, int32(42))
+		})
+		Convey("rejects invalid arguments", func() {
+			for _, arg := range []string{"", "collection", "ref:string", "id:dbref", "id:bogus"} {
+				_, err := NewFieldParser(ctDBRef, arg)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestDBRefColumns(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With dbref columns", t, func() {
+		Convey("the columns build a DBRef document", func() {
+			colSpecs, err := ParseTypedHeaders([]string{
+				"name.string()",
+				"owner.dbref(ref)",
+				"owner.dbref(id:objectid)",
+				"owner.dbref(db)",
+				"owner.note.string()",
+			}, pgStop)
+			So(err, ShouldBeNil)
+			So(ColumnNames(colSpecs), ShouldResemble, []string{
+				"name", "owner.$ref", "owner.$id", "owner.$db", "owner.note",
+			})
+			So(validateFields(ColumnNames(colSpecs), false), ShouldBeNil)
+
+			document, err := tokensToBSON(colSpecs, []string{
+				"a", "users", "5f0c9c1e8b3e4a2b1c0d9e8f", "accounts", "x",
+			}, 1, false, false, cmError)
+			So(err, ShouldBeNil)
+			id, err := primitive.ObjectIDFromHex("5f0c9c1e8b3e4a2b1c0d9e8f")
+			So(err, ShouldBeNil)
+			So(document, ShouldResemble, bson.D{
+				{"name", "a"},
+				{"owner", &bson.D{
+					{"$ref", "users"},
+					{"$id", id},
+					{"$db", "accounts"},
+					{"note", "x"},
+				}},
+			})
+		})
+		Convey("the $db column is optional", func() {
+			_, err := ParseTypedHeaders([]string{"o.dbref(ref)", "o.dbref(id)"}, pgStop)
+			So(err, ShouldBeNil)
+		})
+		Convey("the columns must be complete and in order", func() {
+			for _, headers := range [][]string{
+				{"o.dbref(ref)"},
+				{"o.dbref(id)", "o.dbref(ref)"},
+				{"o.dbref(ref)", "o.dbref(db)", "o.dbref(id)"},
+				{"o.dbref(ref)", "o.dbref(id)", "o.dbref(ref)"},
+				{"o.note.string()", "o.dbref(ref)", "o.dbref(id)"},
+				{"o.dbref(ref)", "o.note.string()", "o.dbref(id)"},
+				{".dbref(ref)", ".dbref(id)"},
+			} {
+				_, err := ParseTypedHeaders(headers, pgStop)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
 }
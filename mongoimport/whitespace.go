@@ -0,0 +1,112 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// TrimMode is which ends of a CSV or TSV field have their whitespace removed
+// before the field is parsed.
+type TrimMode int
+
+const (
+	tmNone TrimMode = iota
+	tmTrim
+	tmLeft
+	tmRight
+)
+
+var trimModeNameMap = map[string]TrimMode{
+	"":      tmNone,
+	"trim":  tmTrim,
+	"ltrim": tmLeft,
+	"rtrim": tmRight,
+}
+
+// ParseTrimMode returns the TrimMode for the argument of a string column,
+// e.g. the "trim" of name.string(trim).
+func ParseTrimMode(arg string) (TrimMode, error) {
+	mode, ok := trimModeNameMap[arg]
+	if !ok {
+		return tmNone, fmt.Errorf(
+			"invalid string modifier: %s (must be one of trim, ltrim, rtrim)",
+			arg,
+		)
+	}
+	return mode, nil
+}
+
+const byteOrderMark = '\uFEFF'
+
+// isNonBreakingSpace returns whether r is one of the non-breaking spaces that
+// spreadsheet exports commonly leave in fields.
+func isNonBreakingSpace(r rune) bool {
+	return r == '\u00A0' || r == '\u2007' || r == '\u202F'
+}
+
+// WhitespacePolicy is how whitespace and byte order marks are cleaned out of a
+// column's fields before they are parsed.
+type WhitespacePolicy struct {
+	Trim TrimMode
+
+	// NonBreakingSpaces is whether non-breaking spaces count as whitespace
+	// when trimming.
+	NonBreakingSpaces bool
+
+	// StripBOMs is whether byte order marks are removed from anywhere in the
+	// field. A byte order mark at the start of the input is always removed.
+	StripBOMs bool
+}
+
+func (wp WhitespacePolicy) isSpace(r rune) bool {
+	if isNonBreakingSpace(r) {
+		return wp.NonBreakingSpaces
+	}
+	return unicode.IsSpace(r)
+}
+
+// Apply returns the field cleaned according to the policy.
+func (wp WhitespacePolicy) Apply(field string) string {
+	if wp.StripBOMs && strings.ContainsRune(field, byteOrderMark) {
+		field = strings.ReplaceAll(field, string(byteOrderMark), "")
+	}
+	switch wp.Trim {
+	case tmTrim:
+		field = strings.TrimFunc(field, wp.isSpace)
+	case tmLeft:
+		field = strings.TrimLeftFunc(field, wp.isSpace)
+	case tmRight:
+		field = strings.TrimRightFunc(field, wp.isSpace)
+	}
+	return field
+}
+
+// applyAll returns the fields cleaned according to the policy. It returns
+// fields itself if the policy leaves fields unchanged.
+func (wp WhitespacePolicy) applyAll(fields []string) []string {
+	if wp == (WhitespacePolicy{}) {
+		return fields
+	}
+	cleaned := make([]string, len(fields))
+	for i, field := range fields {
+		cleaned[i] = wp.Apply(field)
+	}
+	return cleaned
+}
+
+// applyWhitespacePolicy makes the trim modifiers of the string columns in
+// colSpecs treat non-breaking spaces like the global policy does.
+func applyWhitespacePolicy(colSpecs []ColumnSpec, global WhitespacePolicy) {
+	for _, colSpec := range colSpecs {
+		if sp, ok := colSpec.Parser.(*FieldStringParser); ok {
+			sp.whitespace.NonBreakingSpaces = global.NonBreakingSpaces
+		}
+	}
+}
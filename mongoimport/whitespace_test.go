@@ -0,0 +1,114 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongoimport
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestWhitespacePolicy(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With a whitespace policy", t, func() {
+		Convey("trim modes should remove whitespace from the right ends", func() {
+			So(WhitespacePolicy{Trim: tmTrim}.Apply(" \ta b\r\n"), ShouldEqual, "a b")
+			So(WhitespacePolicy{Trim: tmLeft}.Apply(" a b "), ShouldEqual, "a b ")
+			So(WhitespacePolicy{Trim: tmRight}.Apply(" a b "), ShouldEqual, " a b")
+			So(WhitespacePolicy{}.Apply(" a b "), ShouldEqual, " a b ")
+		})
+
+		Convey("non-breaking spaces should only be trimmed if enabled", func() {
+			field := "\u00a0 a\u00a0b \u202f"
+			So(WhitespacePolicy{Trim: tmTrim}.Apply(field), ShouldEqual, "\u00a0 a\u00a0b \u202f")
+			So(
+				WhitespacePolicy{Trim: tmTrim, NonBreakingSpaces: true}.Apply(field),
+				ShouldEqual,
+				"a\u00a0b",
+			)
+		})
+
+		Convey("byte order marks should be stripped from anywhere if enabled", func() {
+			field := "\ufeffa\ufeffb"
+			So(WhitespacePolicy{}.Apply(field), ShouldEqual, field)
+			So(WhitespacePolicy{StripBOMs: true}.Apply(field), ShouldEqual, "ab")
+			So(
+				WhitespacePolicy{Trim: tmTrim, StripBOMs: true}.Apply(" \ufeff a "),
+				ShouldEqual,
+				"a",
+			)
+		})
+	})
+
+	Convey("With string columns with trim modifiers", t, func() {
+		colSpecs, err := ParseTypedHeaders(
+			[]string{"a.string(trim)", "b.string(ltrim)", "c.string(rtrim)", "d.string()"},
+			pgStop,
+		)
+		So(err, ShouldBeNil)
+		_, err = ParseTypedHeader("a.string(strip)", pgStop)
+		So(err, ShouldNotBeNil)
+
+		Convey("fields should be trimmed before they are parsed", func() {
+			doc, err := tokensToBSON(
+				colSpecs,
+				[]string{" a ", " b ", " c ", " d "},
+				0,
+				false,
+				false,
+				cmDefault,
+			)
+			So(err, ShouldBeNil)
+			So(doc, ShouldResemble, bson.D{{"a", "a"}, {"b", "b "}, {"c", " c"}, {"d", " d "}})
+		})
+
+		Convey("the global policy should decide about non-breaking spaces", func() {
+			applyWhitespacePolicy(colSpecs, WhitespacePolicy{NonBreakingSpaces: true})
+			doc, err := tokensToBSON(colSpecs, []string{"\u00a0a "}, 0, false, false, cmDefault)
+			So(err, ShouldBeNil)
+			So(doc, ShouldResemble, bson.D{{"a", "a"}})
+		})
+	})
+
+	Convey("With --trimFields and --stripBOMs set on a CSV import", t, func() {
+		contents := "\ufeffname,age\n  alice , 30 \n\ufeffbob,\t\n"
+		r := NewCSVInputReader(
+			nil,
+			bytes.NewReader([]byte(contents)),
+			&bytes.Buffer{},
+			1,
+			true,
+			false,
+			cmDefault,
+		)
+		r.whitespace = WhitespacePolicy{Trim: tmTrim, StripBOMs: true}
+		So(r.ReadAndValidateHeader(), ShouldBeNil)
+
+		docChan := make(chan bson.D, 2)
+		So(r.StreamDocument(true, docChan), ShouldBeNil)
+		So(<-docChan, ShouldResemble, bson.D{{"name", "alice"}, {"age", int32(30)}})
+		So(<-docChan, ShouldResemble, bson.D{{"name", "bob"}})
+	})
+
+	Convey("Whitespace options should only be valid for CSV and TSV", t, func() {
+		for _, opt := range []string{"--trimFields", "--trimNonBreakingSpaces", "--stripBOMs"} {
+			imp := NewMockMongoImport()
+			imp.InputOptions.Type = JSON
+			imp.InputOptions.TrimFields = opt == "--trimFields"
+			imp.InputOptions.TrimNonBreakingSpaces = opt == "--trimNonBreakingSpaces"
+			imp.InputOptions.StripBOMs = opt == "--stripBOMs"
+			err := imp.validateSettings()
+			So(err, ShouldNotBeNil)
+			So(strings.Contains(err.Error(), opt), ShouldBeTrue)
+		}
+	})
+}
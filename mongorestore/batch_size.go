@@ -0,0 +1,92 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"sync/atomic"
+
+	"github.com/mongodb/mongo-tools/common/db"
+)
+
+const (
+	// targetBatchBytes is the amount of document data that automatic batch
+	// sizing aims to send in each insert batch.
+	targetBatchBytes = db.MaxBSONSize
+
+	// initialBatchDocs is the batch size used until enough documents have
+	// been seen to estimate their average size.
+	initialBatchDocs = 1000
+
+	// batchSizeWarmupDocs is the number of documents a namespace's average
+	// document size is estimated from before batches are resized.
+	batchSizeWarmupDocs = 100
+)
+
+// batchSizer picks the number of documents to insert per batch for a
+// namespace from the average size of the documents restored to it so far,
+// so that collections of tiny documents are inserted in fewer, larger
+// batches and collections of large documents in smaller ones. It is shared by
+// all insertion workers of a namespace.
+type batchSizer struct {
+	// fixed is the batch size given with --batchSize, if any.
+	fixed int
+
+	docs  atomic.Int64
+	bytes atomic.Int64
+}
+
+func newBatchSizer(fixed int) *batchSizer {
+	return &batchSizer{fixed: fixed}
+}
+
+// observe records a document of the given size and returns the number of
+// documents the next batches should hold.
+func (s *batchSizer) observe(size int) int {
+	if s.fixed == 0 {
+		s.docs.Add(1)
+		s.bytes.Add(int64(size))
+	}
+	return s.docLimit()
+}
+
+// docLimit returns the current number of documents per batch.
+func (s *batchSizer) docLimit() int {
+	if s.fixed > 0 {
+		return s.fixed
+	}
+	docs := s.docs.Load()
+	if docs < batchSizeWarmupDocs {
+		return initialBatchDocs
+	}
+	return batchDocsForAverageSize(s.bytes.Load() / docs)
+}
+
+// averageDocSize returns the average size of the documents observed so far.
+func (s *batchSizer) averageDocSize() int64 {
+	docs := s.docs.Load()
+	if docs == 0 {
+		return 0
+	}
+	return s.bytes.Load() / docs
+}
+
+// batchDocsForAverageSize returns how many documents of the given average
+// size fit in targetBatchBytes, within the server's limit on the number of
+// operations per write batch.
+func batchDocsForAverageSize(avgSize int64) int {
+	if avgSize <= 0 {
+		return db.MaxWriteBatchSize
+	}
+	docs := targetBatchBytes / avgSize
+	if docs < 1 {
+		return 1
+	}
+	if docs > db.MaxWriteBatchSize {
+		return db.MaxWriteBatchSize
+	}
+	return int(docs)
+}
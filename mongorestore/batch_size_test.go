@@ -0,0 +1,50 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchSizer(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	t.Run("--batchSize fixes the batch size", func(t *testing.T) {
+		sizer := newBatchSizer(500)
+		for i := 0; i < 1000; i++ {
+			assert.Equal(t, 500, sizer.observe(10))
+		}
+	})
+
+	t.Run("the initial batch size is used until sizes are known", func(t *testing.T) {
+		sizer := newBatchSizer(0)
+		assert.Equal(t, initialBatchDocs, sizer.docLimit())
+		for i := 1; i < batchSizeWarmupDocs; i++ {
+			assert.Equal(t, initialBatchDocs, sizer.observe(1024))
+		}
+		assert.Equal(t, targetBatchBytes/1024, sizer.observe(1024))
+	})
+
+	t.Run("tiny documents are capped at the server's batch limit", func(t *testing.T) {
+		sizer := newBatchSizer(0)
+		for i := 0; i < batchSizeWarmupDocs; i++ {
+			sizer.observe(20)
+		}
+		assert.Equal(t, db.MaxWriteBatchSize, sizer.docLimit())
+		assert.EqualValues(t, 20, sizer.averageDocSize())
+	})
+
+	t.Run("large documents are inserted a few at a time", func(t *testing.T) {
+		assert.Equal(t, 4, batchDocsForAverageSize(4*1024*1024))
+		assert.Equal(t, 1, batchDocsForAverageSize(db.MaxBSONSize))
+		assert.Equal(t, 1, batchDocsForAverageSize(db.MaxBSONSize+1))
+	})
+}
@@ -0,0 +1,70 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mongodb/mongo-tools/common/archive"
+	"github.com/mongodb/mongo-tools/common/log"
+)
+
+// CheckArchive verifies the archive of --checkArchive without connecting to a
+// server, and logs what it found. It returns an error if the archive can't be
+// read or is damaged.
+func CheckArchive(opts Options) error {
+	if opts.InputOptions.Archive == "" {
+		return fmt.Errorf("%v requires %v", CheckArchiveOption, ArchiveOption)
+	}
+	restore := &MongoRestore{
+		ToolOptions:  opts.ToolOptions,
+		InputOptions: opts.InputOptions,
+		InputReader:  os.Stdin,
+	}
+	in, err := restore.getArchiveReader()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	report, err := archive.Check(in)
+	if err != nil {
+		return fmt.Errorf("error reading archive: %v", err)
+	}
+	log.Logvf(
+		log.Always,
+		"read %v namespace(s), %v block(s) and %v document(s) in %v bytes",
+		report.Namespaces,
+		report.Blocks,
+		report.Documents,
+		report.Bytes,
+	)
+	if !report.Checksums {
+		log.Logv(
+			log.Always,
+			"the archive has no block checksums, so only the checksum of each namespace was verified",
+		)
+	}
+	if oplog := report.Header.Oplog; oplog != nil {
+		if oplog.End.IsZero() {
+			log.Logvf(log.Always, "the archive captured the oplog from %v:%v",
+				oplog.Start.T, oplog.Start.I)
+		} else {
+			log.Logvf(log.Always, "the archive captured the oplog from %v:%v to %v:%v",
+				oplog.Start.T, oplog.Start.I, oplog.End.T, oplog.End.I)
+		}
+	}
+	for _, problem := range report.Problems {
+		log.Logvf(log.Always, "archive problem: %v", problem)
+	}
+	if len(report.Problems) > 0 {
+		return fmt.Errorf("the archive is damaged: found %v problem(s)", len(report.Problems))
+	}
+	log.Logv(log.Always, "the archive is intact")
+	return nil
+}
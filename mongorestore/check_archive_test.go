@@ -0,0 +1,47 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/archive"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCheckArchive(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	content, err := archive.SimpleArchive{
+		CollectionMetadata: []archive.CollectionMetadata{{Database: "db", Collection: "coll"}},
+		Namespaces: []archive.SimpleNamespace{{
+			Database:   "db",
+			Collection: "coll",
+			Documents:  []bson.D{{{"_id", 1}, {"name", "checked"}}},
+		}},
+	}.Marshal()
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "archive")
+	require.NoError(t, os.WriteFile(path, content, 0o644))
+
+	// not parsed, because parsing options sets the log verbosity
+	opts := Options{InputOptions: &InputOptions{Archive: path, CheckArchive: true}}
+	assert.NoError(t, CheckArchive(opts))
+
+	damaged := bytes.Replace(content, []byte("checked"), []byte("CHECKED"), 1)
+	require.NoError(t, os.WriteFile(path, damaged, 0o644))
+	assert.ErrorContains(t, CheckArchive(opts), "damaged")
+
+	opts.InputOptions.Archive = ""
+	assert.ErrorContains(t, CheckArchive(opts), ArchiveOption)
+}
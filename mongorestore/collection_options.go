@@ -0,0 +1,120 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mongodb/mongo-tools/common/bsonutil"
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// changeStreamImagesOption is the collection option that makes change
+	// streams able to return the document before and after each change.
+	changeStreamImagesOption = "changeStreamPreAndPostImages"
+	clusteredIndexOption     = "clusteredIndex"
+)
+
+// reapplyCollectionOptions brings the options of a collection that already
+// exists on the destination, and so was not created with the options of the
+// dump, in line with the dump where it can. Change stream pre- and post-images
+// are turned on or off with collMod. Whether a collection is clustered can
+// only be chosen when it is created, so a difference is only logged.
+func (restore *MongoRestore) reapplyCollectionOptions(
+	intent *intents.Intent,
+	options bson.D,
+) error {
+	images, imagesErr := bsonutil.FindValueByKey(changeStreamImagesOption, &options)
+	_, clusteredErr := bsonutil.FindValueByKey(clusteredIndexOption, &options)
+	if imagesErr != nil && clusteredErr != nil {
+		return nil
+	}
+
+	session, err := restore.SessionProvider.GetSession()
+	if err != nil {
+		return fmt.Errorf("error establishing connection: %v", err)
+	}
+	database := session.Database(intent.DB)
+	info, err := db.GetCollectionInfo(database.Collection(intent.C))
+	if err != nil {
+		return fmt.Errorf("error reading the options of %v: %v", intent.Namespace(), err)
+	}
+	if info == nil {
+		return nil
+	}
+
+	_, existingClusteredErr := bsonutil.FindValueByKey(clusteredIndexOption, &info.Options)
+	if dumpClustered := clusteredErr == nil; dumpClustered != (existingClusteredErr == nil) {
+		log.Logvf(
+			log.Always,
+			"warning: %v is %vclustered in the dump but %vclustered on the destination, "+
+				"which cannot be changed for an existing collection; use --drop to recreate it",
+			intent.Namespace(),
+			notUnless(dumpClustered),
+			notUnless(!dumpClustered),
+		)
+	}
+
+	if imagesErr != nil {
+		return nil
+	}
+	existing, _ := bsonutil.FindValueByKey(changeStreamImagesOption, &info.Options)
+	if imagesEnabled(images) == imagesEnabled(existing) {
+		return nil
+	}
+	log.Logvf(
+		log.Always,
+		"turning %v change stream pre- and post-images of existing collection %v, as in the dump",
+		onOrOff(imagesEnabled(images)),
+		intent.Namespace(),
+	)
+	err = database.RunCommand(context.Background(), bson.D{
+		{"collMod", intent.C},
+		{changeStreamImagesOption, images},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf(
+			"error setting %v of %v: %v",
+			changeStreamImagesOption,
+			intent.Namespace(),
+			err,
+		)
+	}
+	return nil
+}
+
+// imagesEnabled returns whether a changeStreamPreAndPostImages option, like
+// {enabled: true}, turns them on.
+func imagesEnabled(option interface{}) bool {
+	switch v := option.(type) {
+	case bson.D:
+		enabled, _ := bsonutil.FindValueByKey("enabled", &v)
+		return enabled == true
+	case bson.M:
+		return v["enabled"] == true
+	}
+	return false
+}
+
+func notUnless(b bool) string {
+	if b {
+		return ""
+	}
+	return "not "
+}
+
+func onOrOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,24 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestImagesEnabled(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	assert.True(t, imagesEnabled(bson.D{{"enabled", true}}))
+	assert.True(t, imagesEnabled(bson.M{"enabled": true}))
+	assert.False(t, imagesEnabled(bson.D{{"enabled", false}}))
+	assert.False(t, imagesEnabled(nil))
+}
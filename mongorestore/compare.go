@@ -0,0 +1,394 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// compareFetchBatchSize is how many sampled documents are looked up on the
+// target with each query.
+const compareFetchBatchSize = 500
+
+// ComparisonReport is how the target differs from the dump in a namespace,
+// as found by --compare.
+type ComparisonReport struct {
+	Namespace string `json:"namespace"`
+	// Missing is set if the collection does not exist on the target.
+	Missing            bool     `json:"missing,omitempty"`
+	DumpDocuments      int64    `json:"dumpDocuments"`
+	TargetDocuments    int64    `json:"targetDocuments"`
+	MissingIndexes     []string `json:"missingIndexes,omitempty"`
+	ExtraIndexes       []string `json:"extraIndexes,omitempty"`
+	ChangedIndexes     []string `json:"changedIndexes,omitempty"`
+	SampledDocuments   int      `json:"sampledDocuments"`
+	MissingDocuments   int      `json:"missingDocuments"`
+	DifferentDocuments int      `json:"differentDocuments"`
+	Drifted            bool     `json:"drifted"`
+}
+
+// drift returns the ways the target differs from the dump.
+func (c *ComparisonReport) drift() []string {
+	if c.Missing {
+		return []string{"collection missing on the target"}
+	}
+	var drift []string
+	if c.DumpDocuments != c.TargetDocuments {
+		drift = append(drift, fmt.Sprintf(
+			"%v documents in the dump, %v on the target", c.DumpDocuments, c.TargetDocuments))
+	}
+	for _, list := range []struct {
+		what    string
+		indexes []string
+	}{
+		{"missing on the target", c.MissingIndexes},
+		{"only on the target", c.ExtraIndexes},
+		{"with different keys", c.ChangedIndexes},
+	} {
+		if len(list.indexes) > 0 {
+			drift = append(drift, fmt.Sprintf(
+				"%v %v: %v",
+				util.Pluralize(len(list.indexes), "index", "indexes"),
+				list.what,
+				strings.Join(list.indexes, ", ")))
+		}
+	}
+	if c.MissingDocuments > 0 || c.DifferentDocuments > 0 {
+		drift = append(drift, fmt.Sprintf(
+			"of %v sampled documents, %v missing and %v different on the target",
+			c.SampledDocuments, c.MissingDocuments, c.DifferentDocuments))
+	}
+	return drift
+}
+
+// sampledDocument is the hash of a document of the dump that --compare looks
+// up on the target.
+type sampledDocument struct {
+	id   bson.RawValue
+	hash [sha256.Size]byte
+}
+
+// documentSampler keeps a uniform sample of up to size documents of a dump
+// collection, whose number is not known until it is read.
+type documentSampler struct {
+	size    int
+	seen    int64
+	sample  []sampledDocument
+	randGen *rand.Rand
+}
+
+func newDocumentSampler(size int) *documentSampler {
+	return &documentSampler{size: size, randGen: rand.New(rand.NewSource(1))}
+}
+
+// add considers doc for the sample. Documents without an _id cannot be looked
+// up, so they are only counted.
+func (s *documentSampler) add(doc bson.Raw) {
+	s.seen++
+	if s.size <= 0 {
+		return
+	}
+	id, err := doc.LookupErr("_id")
+	if err != nil {
+		return
+	}
+	sampled := sampledDocument{id: id, hash: sha256.Sum256(doc)}
+	if len(s.sample) < s.size {
+		s.sample = append(s.sample, sampled)
+		return
+	}
+	if i := s.randGen.Int63n(s.seen); i < int64(s.size) {
+		s.sample[i] = sampled
+	}
+}
+
+// compareIntents compares each collection of the dump with the one it would
+// be restored into, up to --numParallelCollections at a time, without
+// writing anything. It logs a drift report, and fails if any namespace
+// differs.
+func (restore *MongoRestore) compareIntents() Result {
+	restore.prioritizeIntents()
+
+	workers := max(1, restore.OutputOptions.NumParallelCollections)
+	errs := make(chan error, workers)
+	var mu sync.Mutex
+	var reports []*ComparisonReport
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var ioBuf []byte
+			for {
+				intent := restore.manager.Pop()
+				if intent == nil {
+					return
+				}
+				if fileNeedsIOBuffer, ok := intent.BSONFile.(intents.FileNeedsIOBuffer); ok {
+					if ioBuf == nil {
+						ioBuf = make([]byte, db.MaxBSONSize)
+					}
+					fileNeedsIOBuffer.TakeIOBuffer(ioBuf)
+				}
+				report, err := restore.compareIntent(intent)
+				if err != nil {
+					errs <- fmt.Errorf("%v: %v", intent.Namespace(), err)
+					return
+				}
+				restore.manager.Finish(intent)
+				if fileNeedsIOBuffer, ok := intent.BSONFile.(intents.FileNeedsIOBuffer); ok {
+					fileNeedsIOBuffer.ReleaseIOBuffer()
+				}
+				if report != nil {
+					mu.Lock()
+					reports = append(reports, report)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return Result{Err: err}
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Namespace < reports[j].Namespace
+	})
+	drifted := 0
+	for _, report := range reports {
+		restore.stats.recordComparison(*report)
+		drift := report.drift()
+		if len(drift) == 0 {
+			log.Logvf(log.Info, "%v matches the dump", report.Namespace)
+			continue
+		}
+		drifted++
+		log.Logvf(log.Always, "%v differs from the dump:", report.Namespace)
+		for _, line := range drift {
+			log.Logvf(log.Always, "\t%v", line)
+		}
+	}
+	log.Logvf(
+		log.Always,
+		"compared %v %v with the target: %v %v from the dump",
+		len(reports),
+		util.Pluralize(len(reports), "namespace", "namespaces"),
+		drifted,
+		util.Pluralize(drifted, "differs", "differ"),
+	)
+	if drifted > 0 {
+		return Result{Err: fmt.Errorf(
+			"the target differs from the dump in %v %v",
+			drifted,
+			util.Pluralize(drifted, "namespace", "namespaces"),
+		)}
+	}
+	return Result{}
+}
+
+// compareIntent compares the collection of intent with the one on the
+// target: their number of documents, their indexes, and a sample of
+// --compareSampleSize documents, which must have the same _id and the same
+// bytes on the target. The documents are compared after --valueMapFile
+// remaps them, as they would be restored. It returns nil for the intents
+// that have no collection to compare, like the users and the oplog.
+func (restore *MongoRestore) compareIntent(intent *intents.Intent) (*ComparisonReport, error) {
+	sampler := newDocumentSampler(restore.OutputOptions.CompareSampleSize)
+	if intent.BSONFile != nil {
+		// the file is read even for the intents that are not compared, so
+		// that the demultiplexer of an archive can go on to the next one
+		if err := restore.readDumpSample(intent, sampler); err != nil {
+			return nil, err
+		}
+	}
+	if intent.IsOplog() || intent.IsSpecialCollection() {
+		return nil, nil
+	}
+
+	report := &ComparisonReport{Namespace: intent.Namespace(), DumpDocuments: sampler.seen}
+	exists, err := restore.CollectionExists(intent.DB, intent.C)
+	if err != nil {
+		return nil, fmt.Errorf("error reading database: %v", err)
+	}
+	if !exists {
+		report.Missing = true
+		report.Drifted = true
+		return report, nil
+	}
+	if intent.IsView() {
+		return report, nil
+	}
+
+	session, err := restore.SessionProvider.GetSession()
+	if err != nil {
+		return nil, fmt.Errorf("error establishing connection: %v", err)
+	}
+	database := session.Database(intent.DB)
+	data := database.Collection(intent.DataCollection())
+
+	report.TargetDocuments, err = data.CountDocuments(context.Background(), bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("error counting documents: %v", err)
+	}
+	if !restore.OutputOptions.NoIndexRestore {
+		if err = restore.compareIndexes(database.Collection(intent.C), intent, report); err != nil {
+			return nil, err
+		}
+	}
+	if err = compareSample(data, sampler.sample, report); err != nil {
+		return nil, err
+	}
+	report.Drifted = len(report.drift()) > 0
+	return report, nil
+}
+
+// readDumpSample reads the documents of intent from the dump into sampler.
+func (restore *MongoRestore) readDumpSample(
+	intent *intents.Intent,
+	sampler *documentSampler,
+) error {
+	if err := intent.BSONFile.Open(); err != nil {
+		return err
+	}
+	defer intent.BSONFile.Close()
+	log.Logvf(log.Info, "comparing %v with %v", intent.DataNamespace(), intent.Location)
+
+	bsonSource := db.NewDecodedBSONSource(db.NewBSONSource(intent.BSONFile))
+	defer bsonSource.Close()
+	remaps := restore.valueMapper.forNamespace(intent.DB + "." + intent.DataCollection())
+	for {
+		if restore.terminate.Load() {
+			return util.ErrTerminated
+		}
+		doc := bsonSource.LoadNext()
+		if doc == nil {
+			break
+		}
+		raw := bson.Raw(append([]byte(nil), doc...))
+		if len(remaps) > 0 {
+			if remapped, err := remapRaw(raw, remaps); err == nil {
+				raw = remapped
+			}
+		}
+		sampler.add(raw)
+	}
+	if err := bsonSource.Err(); err != nil {
+		return fmt.Errorf("error reading %v: %v", intent.Location, err)
+	}
+	return nil
+}
+
+// compareIndexes compares, by name, the indexes of the dump for intent with
+// the ones of coll on the target.
+func (restore *MongoRestore) compareIndexes(
+	coll *mongo.Collection,
+	intent *intents.Intent,
+	report *ComparisonReport,
+) error {
+	dumpKeys := map[string][]byte{}
+	if restore.indexCatalog != nil {
+		for _, index := range restore.indexCatalog.GetIndexes(intent.DB, intent.C) {
+			name, _ := index.Options["name"].(string)
+			key, err := bson.Marshal(index.Key)
+			if err != nil {
+				return fmt.Errorf("error encoding the key of index %v: %v", name, err)
+			}
+			dumpKeys[name] = key
+		}
+	}
+
+	specs, err := coll.Indexes().ListSpecifications(context.Background())
+	if err != nil {
+		return fmt.Errorf("error listing indexes: %v", err)
+	}
+	targetKeys := map[string][]byte{}
+	for _, spec := range specs {
+		targetKeys[spec.Name] = spec.KeysDocument
+	}
+
+	for name, key := range dumpKeys {
+		targetKey, ok := targetKeys[name]
+		switch {
+		case !ok:
+			report.MissingIndexes = append(report.MissingIndexes, name)
+		case !bytes.Equal(key, targetKey):
+			report.ChangedIndexes = append(report.ChangedIndexes, name)
+		}
+	}
+	for name := range targetKeys {
+		if _, ok := dumpKeys[name]; !ok {
+			report.ExtraIndexes = append(report.ExtraIndexes, name)
+		}
+	}
+	sort.Strings(report.MissingIndexes)
+	sort.Strings(report.ChangedIndexes)
+	sort.Strings(report.ExtraIndexes)
+	return nil
+}
+
+// compareSample looks up the sampled documents on the target by _id, and
+// counts the ones that are missing or have different hashes.
+func compareSample(
+	coll *mongo.Collection,
+	sample []sampledDocument,
+	report *ComparisonReport,
+) error {
+	report.SampledDocuments = len(sample)
+	for start := 0; start < len(sample); start += compareFetchBatchSize {
+		batch := sample[start:min(start+compareFetchBatchSize, len(sample))]
+		ids := make(bson.A, len(batch))
+		hashes := make(map[string][sha256.Size]byte, len(batch))
+		for i, sampled := range batch {
+			ids[i] = sampled.id
+			hashes[rawValueKey(sampled.id)] = sampled.hash
+		}
+
+		cursor, err := coll.Find(
+			context.Background(),
+			bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}},
+		)
+		if err != nil {
+			return fmt.Errorf("error reading sampled documents: %v", err)
+		}
+		found := 0
+		for cursor.Next(context.Background()) {
+			key := rawValueKey(cursor.Current.Lookup("_id"))
+			hash, ok := hashes[key]
+			if !ok {
+				continue
+			}
+			delete(hashes, key)
+			found++
+			if sha256.Sum256(cursor.Current) != hash {
+				report.DifferentDocuments++
+			}
+		}
+		err = cursor.Err()
+		_ = cursor.Close(context.Background())
+		if err != nil {
+			return fmt.Errorf("error reading sampled documents: %v", err)
+		}
+		report.MissingDocuments += len(batch) - found
+	}
+	return nil
+}
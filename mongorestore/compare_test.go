@@ -0,0 +1,70 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDocumentSampler(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	sampler := newDocumentSampler(10)
+	for i := 0; i < 100; i++ {
+		doc, err := bson.Marshal(bson.D{{Key: "_id", Value: int32(i)}})
+		require.NoError(t, err)
+		sampler.add(doc)
+	}
+	noID, err := bson.Marshal(bson.D{{Key: "a", Value: 1}})
+	require.NoError(t, err)
+	sampler.add(noID)
+
+	assert.EqualValues(t, 101, sampler.seen)
+	require.Len(t, sampler.sample, 10)
+	seen := map[int32]bool{}
+	for _, sampled := range sampler.sample {
+		id := sampled.id.Int32()
+		assert.False(t, seen[id], "%v is sampled twice", id)
+		seen[id] = true
+	}
+
+	disabled := newDocumentSampler(0)
+	disabled.add(noID)
+	assert.EqualValues(t, 1, disabled.seen)
+	assert.Empty(t, disabled.sample)
+}
+
+func TestComparisonReportDrift(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	same := &ComparisonReport{DumpDocuments: 5, TargetDocuments: 5, SampledDocuments: 5}
+	assert.Empty(t, same.drift())
+
+	missing := &ComparisonReport{Missing: true, DumpDocuments: 5}
+	assert.Equal(t, []string{"collection missing on the target"}, missing.drift())
+
+	drifted := &ComparisonReport{
+		DumpDocuments:      5,
+		TargetDocuments:    4,
+		MissingIndexes:     []string{"a_1"},
+		ExtraIndexes:       []string{"b_1", "c_1"},
+		SampledDocuments:   5,
+		MissingDocuments:   1,
+		DifferentDocuments: 2,
+	}
+	assert.Equal(t, []string{
+		"5 documents in the dump, 4 on the target",
+		"index missing on the target: a_1",
+		"indexes only on the target: b_1, c_1",
+		"of 5 sampled documents, 1 missing and 2 different on the target",
+	}, drifted.drift())
+}
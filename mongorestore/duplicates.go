@@ -0,0 +1,284 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// Policies of --onDuplicateKey.
+const (
+	duplicateKeySkip      = "skip"
+	duplicateKeyOverwrite = "overwrite"
+	duplicateKeyFail      = "fail"
+	duplicateKeySuffix    = "suffix"
+)
+
+// maxDuplicateSuffixes is how many suffixes --onDuplicateKey=suffix tries for
+// the _id of a document before giving up.
+const maxDuplicateSuffixes = 100
+
+// DuplicateKeyReport describes the documents of a namespace whose keys
+// already existed, and that --onDuplicateKey resolved.
+type DuplicateKeyReport struct {
+	Namespace  string            `json:"namespace"`
+	Policy     string            `json:"policy"`
+	Duplicates int64             `json:"duplicates"`
+	IDs        []json.RawMessage `json:"ids"`
+	// SuffixedIDs are the _ids that suffix gave the documents of IDs.
+	SuffixedIDs  []json.RawMessage `json:"suffixedIds,omitempty"`
+	IDsTruncated bool              `json:"idsTruncated,omitempty"`
+}
+
+// validateDuplicateKeyPolicy checks that --onDuplicateKey can be applied.
+func (restore *MongoRestore) validateDuplicateKeyPolicy() error {
+	policy := restore.OutputOptions.OnDuplicateKey
+	if (policy == duplicateKeySkip || policy == duplicateKeySuffix) &&
+		restore.OutputOptions.MaintainInsertionOrder {
+		// an ordered bulk write stops at its first error, so that the
+		// documents after a duplicate would not be restored
+		return fmt.Errorf(
+			"cannot use %v=%v with %v",
+			OnDuplicateKeyOption,
+			policy,
+			MaintainInsertionOrderOption,
+		)
+	}
+	return nil
+}
+
+// duplicate is a document whose insert failed with a duplicate key error.
+type duplicate struct {
+	doc bson.Raw
+	// onID is whether the key is the _id, rather than the key of another
+	// unique index.
+	onID bool
+}
+
+// duplicatesID returns whether the duplicate key error writeErr is about the
+// _id index.
+func duplicatesID(writeErr mongo.BulkWriteError) bool {
+	if pattern, ok := writeErr.Raw.Lookup("keyPattern").DocumentOK(); ok {
+		elems, err := pattern.Elements()
+		return err == nil && len(elems) == 1 && elems[0].Key() == "_id"
+	}
+	// servers before 4.2 only name the index in the message
+	return strings.Contains(writeErr.Message, "index: _id_ ")
+}
+
+// resolveDuplicateKeys applies --onDuplicateKey to the documents of result
+// whose inserts failed because their key already exists, so that the
+// duplicate key errors no longer reach filterInsertError.
+func (restore *MongoRestore) resolveDuplicateKeys(
+	collection *mongo.Collection,
+	namespace string,
+	result Result,
+) Result {
+	policy := restore.OutputOptions.OnDuplicateKey
+	var bwe mongo.BulkWriteException
+	if policy == "" || !errors.As(result.Err, &bwe) {
+		return result
+	}
+
+	var remaining []mongo.BulkWriteError
+	var duplicates []duplicate
+	for _, writeErr := range bwe.WriteErrors {
+		doc, isRaw := requestDocument(writeErr.Request)
+		if writeErr.Code != db.ErrDuplicateKeyCode || !isRaw {
+			remaining = append(remaining, writeErr)
+			continue
+		}
+		duplicates = append(duplicates, duplicate{doc: doc, onID: duplicatesID(writeErr)})
+	}
+	if len(duplicates) == 0 {
+		return result
+	}
+
+	ids := make([]bson.RawValue, len(duplicates))
+	for i, dup := range duplicates {
+		ids[i] = dup.doc.Lookup("_id")
+		if policy == duplicateKeyFail || (policy != duplicateKeySkip && !dup.onID) {
+			reason := "already exists"
+			if !dup.onID {
+				reason = "has the key of another document in a unique index other than _id"
+			}
+			return result.withErr(fmt.Errorf(
+				"document %s of %v %v, which %v=%v does not resolve",
+				idJSON(ids[i]),
+				namespace,
+				reason,
+				OnDuplicateKeyOption,
+				policy,
+			))
+		}
+	}
+
+	var suffixed []bson.RawValue
+	if policy == duplicateKeySuffix {
+		for _, dup := range duplicates {
+			id, err := insertWithSuffix(collection, dup.doc)
+			if err != nil {
+				return result.withErr(fmt.Errorf(
+					"error inserting document %s of %v with a suffixed _id: %v",
+					idJSON(dup.doc.Lookup("_id")),
+					namespace,
+					err,
+				))
+			}
+			suffixed = append(suffixed, id)
+		}
+		result.Successes += int64(len(duplicates))
+	}
+	if policy == duplicateKeyOverwrite {
+		// the upsert of the document raced with the insert of another
+		// document with its _id, which it can now replace
+		for _, dup := range duplicates {
+			if err := replaceByID(collection, dup.doc); err != nil {
+				return result.withErr(fmt.Errorf(
+					"error replacing document %s of %v: %v",
+					idJSON(dup.doc.Lookup("_id")),
+					namespace,
+					err,
+				))
+			}
+		}
+		result.Successes += int64(len(duplicates))
+	}
+	result.Failures -= int64(len(duplicates))
+	restore.stats.recordDuplicates(namespace, policy, ids, suffixed)
+	log.Logvf(
+		log.Info,
+		"resolved %v %v of %v whose key already exists with %v=%v",
+		len(duplicates),
+		util.Pluralize(len(duplicates), "document", "documents"),
+		namespace,
+		OnDuplicateKeyOption,
+		policy,
+	)
+
+	if len(remaining) == 0 && bwe.WriteConcernError == nil {
+		result.Err = nil
+	} else {
+		bwe.WriteErrors = remaining
+		result.Err = bwe
+	}
+	return result
+}
+
+// recordOverwrites records that --onDuplicateKey=overwrite replaced the
+// existing documents of namespace with the _ids ids.
+func (restore *MongoRestore) recordOverwrites(namespace string, ids []bson.RawValue) {
+	restore.stats.recordDuplicates(namespace, duplicateKeyOverwrite, ids, nil)
+	log.Logvf(
+		log.Info,
+		"replaced %v %v of %v whose _id already exists with %v=%v",
+		len(ids),
+		util.Pluralize(len(ids), "document", "documents"),
+		namespace,
+		OnDuplicateKeyOption,
+		duplicateKeyOverwrite,
+	)
+}
+
+// requestDocument returns the raw document of the insert or replacement of a
+// bulk write, and whether it has one.
+func requestDocument(request mongo.WriteModel) (bson.Raw, bool) {
+	var raw []byte
+	var ok bool
+	switch model := request.(type) {
+	case *mongo.InsertOneModel:
+		raw, ok = model.Document.([]byte)
+	case *mongo.ReplaceOneModel:
+		raw, ok = model.Replacement.([]byte)
+	}
+	return raw, ok
+}
+
+// replaceByID upserts doc by its _id.
+func replaceByID(collection *mongo.Collection, doc bson.Raw) error {
+	_, err := collection.ReplaceOne(
+		context.Background(),
+		bson.D{{"_id", doc.Lookup("_id")}},
+		doc,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// suffixedID returns the _id that --onDuplicateKey=suffix gives a document
+// whose _id is id, for the nth attempt: id as a string, followed by -n.
+func suffixedID(id bson.RawValue, n int) string {
+	var base string
+	switch id.Type {
+	case bson.TypeString:
+		base = id.StringValue()
+	case bson.TypeObjectID:
+		base = id.ObjectID().Hex()
+	case bson.TypeInt32, bson.TypeInt64:
+		i, _ := id.AsInt64OK()
+		base = strconv.FormatInt(i, 10)
+	default:
+		base = id.String()
+	}
+	return base + "-" + strconv.Itoa(n)
+}
+
+// withID returns doc with its _id replaced by id.
+func withID(doc bson.Raw, id string) (bson.Raw, error) {
+	elems, err := doc.Elements()
+	if err != nil {
+		return nil, err
+	}
+	built := make([][]byte, len(elems))
+	for i, elem := range elems {
+		if elem.Key() == "_id" {
+			built[i] = bsoncore.AppendStringElement(nil, "_id", id)
+		} else {
+			built[i] = elem
+		}
+	}
+	return bsoncore.BuildDocument(nil, built...), nil
+}
+
+// insertWithSuffix inserts doc with the first suffixed _id that does not
+// exist yet, and returns it.
+func insertWithSuffix(collection *mongo.Collection, doc bson.Raw) (bson.RawValue, error) {
+	original := doc.Lookup("_id")
+	for n := 1; n <= maxDuplicateSuffixes; n++ {
+		renamed, err := withID(doc, suffixedID(original, n))
+		if err != nil {
+			return bson.RawValue{}, err
+		}
+		_, err = collection.InsertOne(context.Background(), renamed)
+		if err == nil {
+			return renamed.Lookup("_id"), nil
+		}
+		var we mongo.WriteException
+		if !errors.As(err, &we) || len(we.WriteErrors) != 1 ||
+			we.WriteErrors[0].Code != db.ErrDuplicateKeyCode ||
+			!duplicatesID(mongo.BulkWriteError{WriteError: we.WriteErrors[0]}) {
+			return bson.RawValue{}, err
+		}
+	}
+	return bson.RawValue{}, fmt.Errorf(
+		"the _ids with suffixes up to -%v all exist",
+		maxDuplicateSuffixes,
+	)
+}
@@ -0,0 +1,211 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func duplicateKeyError(t *testing.T, id interface{}, keyPattern bson.D) mongo.BulkWriteError {
+	doc, err := bson.Marshal(bson.D{{"_id", id}, {"sku", "a-1"}})
+	require.NoError(t, err)
+	raw, err := bson.Marshal(bson.D{{"keyPattern", keyPattern}})
+	require.NoError(t, err)
+	return mongo.BulkWriteError{
+		WriteError: mongo.WriteError{Code: db.ErrDuplicateKeyCode, Raw: raw},
+		Request:    mongo.NewInsertOneModel().SetDocument(doc),
+	}
+}
+
+// asUpsert returns writeErr with its insert replaced by the upsert that
+// --onDuplicateKey=overwrite writes instead.
+func asUpsert(writeErr mongo.BulkWriteError) mongo.BulkWriteError {
+	doc := bson.Raw(writeErr.Request.(*mongo.InsertOneModel).Document.([]byte))
+	writeErr.Request = mongo.NewReplaceOneModel().
+		SetFilter(bson.D{{"_id", doc.Lookup("_id")}}).
+		SetReplacement([]byte(doc)).
+		SetUpsert(true)
+	return writeErr
+}
+
+func TestValidateDuplicateKeyPolicy(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	for _, policy := range []string{"", duplicateKeyOverwrite, duplicateKeyFail} {
+		restore := newValidationRestore(OutputOptions{
+			OnDuplicateKey:         policy,
+			MaintainInsertionOrder: true,
+		})
+		assert.NoError(t, restore.validateDuplicateKeyPolicy(), policy)
+	}
+	for _, policy := range []string{duplicateKeySkip, duplicateKeySuffix} {
+		restore := newValidationRestore(OutputOptions{
+			OnDuplicateKey:         policy,
+			MaintainInsertionOrder: true,
+		})
+		assert.Error(t, restore.validateDuplicateKeyPolicy(), policy)
+	}
+}
+
+func TestResolveDuplicateKeys(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	invalid, err := bson.Marshal(bson.D{{"_id", int32(9)}})
+	require.NoError(t, err)
+	bulkErr := mongo.BulkWriteException{
+		WriteErrors: []mongo.BulkWriteError{
+			duplicateKeyError(t, int32(7), bson.D{{"_id", 1}}),
+			duplicateKeyError(t, int32(8), bson.D{{"sku", 1}}),
+			{
+				WriteError: mongo.WriteError{Code: db.ErrFailedDocumentValidation},
+				Request:    mongo.NewInsertOneModel().SetDocument(invalid),
+			},
+		},
+	}
+	result := Result{Successes: 7, Failures: 3, Err: bulkErr}
+
+	t.Run("without a policy", func(t *testing.T) {
+		restore := newValidationRestore(OutputOptions{})
+		assert.Equal(t, result, restore.resolveDuplicateKeys(nil, "app.items", result))
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		restore := newValidationRestore(OutputOptions{OnDuplicateKey: duplicateKeySkip})
+		resolved := restore.resolveDuplicateKeys(nil, "app.items", result)
+		assert.Equal(t, int64(7), resolved.Successes)
+		assert.Equal(t, int64(1), resolved.Failures)
+		var remaining mongo.BulkWriteException
+		require.ErrorAs(t, resolved.Err, &remaining)
+		require.Len(t, remaining.WriteErrors, 1)
+		assert.Equal(t, db.ErrFailedDocumentValidation, remaining.WriteErrors[0].Code)
+
+		report := restore.stats.report(Result{})
+		require.Len(t, report.DuplicateKeys, 1)
+		assert.Equal(t, DuplicateKeyReport{
+			Namespace:  "app.items",
+			Policy:     duplicateKeySkip,
+			Duplicates: 2,
+			IDs: []json.RawMessage{
+				json.RawMessage(`{"$numberInt":"7"}`),
+				json.RawMessage(`{"$numberInt":"8"}`),
+			},
+		}, report.DuplicateKeys[0])
+	})
+
+	t.Run("skip clears the error", func(t *testing.T) {
+		restore := newValidationRestore(OutputOptions{OnDuplicateKey: duplicateKeySkip})
+		onlyDuplicates := mongo.BulkWriteException{WriteErrors: bulkErr.WriteErrors[:2]}
+		resolved := restore.resolveDuplicateKeys(
+			nil,
+			"app.items",
+			Result{Successes: 7, Failures: 2, Err: onlyDuplicates},
+		)
+		assert.NoError(t, resolved.Err)
+		assert.Zero(t, resolved.Failures)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		restore := newValidationRestore(OutputOptions{OnDuplicateKey: duplicateKeyFail})
+		resolved := restore.resolveDuplicateKeys(nil, "app.items", result)
+		require.Error(t, resolved.Err)
+		assert.Contains(t, resolved.Err.Error(), "already exists")
+		assert.Empty(t, restore.stats.report(Result{}).DuplicateKeys)
+	})
+
+	t.Run("keys of other unique indexes", func(t *testing.T) {
+		for _, policy := range []string{duplicateKeyOverwrite, duplicateKeySuffix} {
+			restore := newValidationRestore(OutputOptions{OnDuplicateKey: policy})
+			onlySKU := mongo.BulkWriteException{WriteErrors: bulkErr.WriteErrors[1:2]}
+			if policy == duplicateKeyOverwrite {
+				onlySKU.WriteErrors = []mongo.BulkWriteError{asUpsert(bulkErr.WriteErrors[1])}
+			}
+			resolved := restore.resolveDuplicateKeys(
+				nil,
+				"app.items",
+				Result{Failures: 1, Err: onlySKU},
+			)
+			require.Error(t, resolved.Err, policy)
+			assert.Contains(t, resolved.Err.Error(), "unique index other than _id", policy)
+		}
+	})
+
+	t.Run("overwrite reports the replaced documents", func(t *testing.T) {
+		restore := newValidationRestore(OutputOptions{OnDuplicateKey: duplicateKeyOverwrite})
+		var ids []bson.RawValue
+		for _, writeErr := range bulkErr.WriteErrors[:2] {
+			upsert := asUpsert(writeErr).Request.(*mongo.ReplaceOneModel)
+			ids = append(ids, upsert.Filter.(bson.D)[0].Value.(bson.RawValue))
+		}
+		restore.recordOverwrites("app.items", ids)
+
+		report := restore.stats.report(Result{})
+		require.Len(t, report.DuplicateKeys, 1)
+		assert.Equal(t, DuplicateKeyReport{
+			Namespace:  "app.items",
+			Policy:     duplicateKeyOverwrite,
+			Duplicates: 2,
+			IDs: []json.RawMessage{
+				json.RawMessage(`{"$numberInt":"7"}`),
+				json.RawMessage(`{"$numberInt":"8"}`),
+			},
+		}, report.DuplicateKeys[0])
+	})
+}
+
+func TestDuplicatesID(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	assert.True(t, duplicatesID(duplicateKeyError(t, 1, bson.D{{"_id", 1}})))
+	assert.False(t, duplicatesID(duplicateKeyError(t, 1, bson.D{{"sku", 1}})))
+	assert.False(t, duplicatesID(duplicateKeyError(t, 1, bson.D{{"_id", 1}, {"sku", 1}})))
+
+	legacy := mongo.BulkWriteError{WriteError: mongo.WriteError{
+		Code:    db.ErrDuplicateKeyCode,
+		Message: "E11000 duplicate key error collection: app.items index: _id_ dup key: { : 1 }",
+	}}
+	assert.True(t, duplicatesID(legacy))
+	legacy.Message = "E11000 duplicate key error collection: app.items index: sku_1 dup key: { : 1 }"
+	assert.False(t, duplicatesID(legacy))
+}
+
+func TestSuffixedID(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	oid, err := primitive.ObjectIDFromHex("5f1b2c3d4e5f60718293a4b5")
+	require.NoError(t, err)
+	for _, test := range []struct {
+		id       interface{}
+		n        int
+		expected string
+	}{
+		{"order", 1, "order-1"},
+		{oid, 2, "5f1b2c3d4e5f60718293a4b5-2"},
+		{int32(42), 1, "42-1"},
+		{int64(-7), 3, "-7-3"},
+	} {
+		doc, err := bson.Marshal(bson.D{{"_id", test.id}})
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, suffixedID(bson.Raw(doc).Lookup("_id"), test.n))
+	}
+
+	doc, err := bson.Marshal(bson.D{{"sku", "a-1"}, {"_id", int32(42)}, {"qty", int32(3)}})
+	require.NoError(t, err)
+	renamed, err := withID(doc, "42-1")
+	require.NoError(t, err)
+	var fields bson.D
+	require.NoError(t, bson.Unmarshal(renamed, &fields))
+	assert.Equal(t, bson.D{{"sku", "a-1"}, {"_id", "42-1"}, {"qty", int32(3)}}, fields)
+}
@@ -437,7 +437,7 @@ func (restore *MongoRestore) CreateIntentsForDB(db string, dir archive.DirLike)
 			sourceNS := db + "." + collection
 			switch fileType {
 			case BSONFileType:
-				var skip bool
+				var skip string
 				// Dumps of a single database (i.e. with the -d flag) may contain special
 				// db-specific files that start with a "$" (for example, $admin.system.users
 				// holds the users for a database that was dumped with --dumpDbUsersAndRoles enabled).
@@ -445,14 +445,14 @@ func (restore *MongoRestore) CreateIntentsForDB(db string, dir archive.DirLike)
 				// (multi-db) restore, we should ignore them.
 				if restore.ToolOptions.Namespace != nil && restore.ToolOptions.Namespace.DB == "" && strings.HasPrefix(collection, "$") {
 					log.Logvf(log.DebugLow, "not restoring special collection %v.%v", db, collection)
-					skip = true
+					skip = skipSpecialCollection
 				}
 				// TOOLS-717: disallow restoring to the system.profile collection.
 				// Server versions >= 3.0.3 disallow user inserts to system.profile so
 				// it would likely fail anyway.
 				if collection == "system.profile" {
 					log.Logvf(log.DebugLow, "skipping restore of system.profile collection in %v", db)
-					skip = true
+					skip = skipSystemProfile
 				}
 				// skip restoring the indexes collection if we are using metadata
 				// files to store index information, to eliminate redundancy
@@ -460,18 +460,24 @@ func (restore *MongoRestore) CreateIntentsForDB(db string, dir archive.DirLike)
 					log.Logvf(log.DebugLow,
 						"not restoring system.indexes collection because database %v "+
 							"has .metadata.json files", db)
-					skip = true
+					skip = skipSystemIndexes
+				}
+				if restore.skipsSystemCollection(db, collection) {
+					log.Logvf(log.DebugLow,
+						"skipping restore of system collection %v.%v, it is not named by "+
+							"--systemCollection", db, collection)
+					skip = skipSystemCollection
 				}
 
 				checkSourceNS := db + "." + strings.TrimPrefix(collection, "system.buckets.")
 
 				if !restore.includer.Has(checkSourceNS) {
 					log.Logvf(log.DebugLow, "skipping restoring %v.%v, it is not included", db, collection)
-					skip = true
+					skip = skipNotIncluded
 				}
 				if restore.excluder.Has(checkSourceNS) {
 					log.Logvf(log.DebugLow, "skipping restoring %v.%v, it is excluded", db, collection)
-					skip = true
+					skip = skipExcluded
 				}
 				destNS := restore.renamer.Get(sourceNS)
 				destDB, destC := util.SplitNamespace(destNS)
@@ -487,10 +493,28 @@ func (restore *MongoRestore) CreateIntentsForDB(db string, dir archive.DirLike)
 					} else {
 						intent.Location = fmt.Sprintf("archive '%v'", restore.InputOptions.Archive)
 					}
-					if skip {
+					if skip != "" {
 						// adding the DemuxOut to the demux, but not adding the intent to the manager
 						mutedOut := &archive.MutedCollection{Intent: intent, Demux: restore.archive.Demux}
 						restore.archive.Demux.Open(sourceNS, mutedOut)
+						restore.stats.recordSkipped(sourceNS, skip, entry.Size(), mutedOut)
+						continue
+					}
+					journaled := ""
+					switch {
+					case restore.restoreJournal.completed(destNS):
+						journaled = skipJournaled
+					case restore.oplogJournal.resuming() && !intent.IsSpecialCollection():
+						journaled = skipOplogJournaled
+					}
+					if journaled != "" {
+						// the demux seeks past the muted data and never announces the
+						// namespace, so the intent, which has no BSONFile, is only used
+						// to restore the indexes of the collection
+						mutedOut := &archive.MutedCollection{Intent: intent, Demux: restore.archive.Demux}
+						restore.archive.Demux.Open(sourceNS, mutedOut)
+						restore.stats.recordSkipped(sourceNS, journaled, entry.Size(), mutedOut)
+						restore.manager.PutWithNamespace(checkSourceNS, intent)
 						continue
 					}
 					if intent.IsSpecialCollection() {
@@ -505,7 +529,8 @@ func (restore *MongoRestore) CreateIntentsForDB(db string, dir archive.DirLike)
 						}
 					}
 				} else {
-					if skip {
+					if skip != "" {
+						restore.stats.recordSkipped(sourceNS, skip, entry.Size(), nil)
 						continue
 					}
 					intent.Location = entry.Path()
@@ -518,6 +543,10 @@ func (restore *MongoRestore) CreateIntentsForDB(db string, dir archive.DirLike)
 					log.Logvf(log.DebugLow, "skipping restore of system.profile metadata")
 					continue
 				}
+				if restore.skipsSystemCollection(db, collection) {
+					log.Logvf(log.DebugLow, "skipping restore of %v.%v metadata", db, collection)
+					continue
+				}
 
 				checkSourceNS := sourceNS
 				if strings.HasPrefix(collection, "system.buckets.") {
@@ -0,0 +1,250 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/bsonutil"
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/idx"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Values of --unsupportedIndexOptionPolicy, --duplicateKeyIndexPolicy and
+// --indexMemoryLimitPolicy.
+const (
+	indexErrorFail  = "fail"
+	indexErrorSkip  = "skip"
+	indexErrorRetry = "retry"
+)
+
+// Classes of index build errors that have their own policy.
+const (
+	indexErrorUnsupportedOption = "unsupported option"
+	indexErrorDuplicateKey      = "duplicate key"
+	indexErrorMemoryLimit       = "exceeded memory limit"
+)
+
+// Server error codes of the index error classes.
+const (
+	errInvalidIndexSpecificationOption = 197
+	errExceededMemoryLimit             = 146
+)
+
+// unsupportedIndexFieldRegex matches the message of the server naming the
+// option of an index specification it rejects.
+var unsupportedIndexFieldRegex = regexp.MustCompile(
+	`field '([^']+)' is not valid for an index specification`,
+)
+
+// IndexFailureReport describes an index that could not be created as it was
+// dumped, and what the policy for its class of error did about it.
+type IndexFailureReport struct {
+	Namespace string `json:"namespace"`
+	Index     string `json:"index"`
+	Class     string `json:"class"`
+	Error     string `json:"error"`
+	Action    string `json:"action"`
+}
+
+// classifyIndexError returns the class of an error creating indexes, or ""
+// if it has none.
+func classifyIndexError(err error) string {
+	var mongoErr mongo.ServerError
+	if !errors.As(err, &mongoErr) {
+		return ""
+	}
+	switch {
+	case mongoErr.HasErrorCode(errInvalidIndexSpecificationOption),
+		unsupportedIndexFieldRegex.MatchString(mongoErr.Error()):
+		return indexErrorUnsupportedOption
+	case mongoErr.HasErrorCode(db.ErrDuplicateKeyCode):
+		return indexErrorDuplicateKey
+	case mongoErr.HasErrorCode(errExceededMemoryLimit):
+		return indexErrorMemoryLimit
+	}
+	return ""
+}
+
+// indexErrorPolicy returns the policy for a class of index errors.
+func (restore *MongoRestore) indexErrorPolicy(class string) string {
+	var policy string
+	switch class {
+	case indexErrorUnsupportedOption:
+		policy = restore.OutputOptions.IndexOptionPolicy
+	case indexErrorDuplicateKey:
+		policy = restore.OutputOptions.IndexDuplicatePolicy
+	case indexErrorMemoryLimit:
+		policy = restore.OutputOptions.IndexMemoryPolicy
+	}
+	if policy == "" {
+		return indexErrorFail
+	}
+	return policy
+}
+
+// createIndexesWithPolicies creates the indexes of ns with create, and
+// handles the errors as the index error policies say. The indexes of a
+// collection are normally created with one command; if it fails with an
+// error whose policy is not fail, they are created again one at a time, so
+// that only the indexes that fail are skipped or modified, and so that they
+// don't share the memory limit of the destination. Every index that fails is
+// logged and recorded for --reportFile.
+func (restore *MongoRestore) createIndexesWithPolicies(
+	ns string,
+	indexes []*idx.IndexDocument,
+	create func([]*idx.IndexDocument) error,
+) error {
+	err := create(indexes)
+	if err == nil {
+		return nil
+	}
+	class := classifyIndexError(err)
+	if class == "" || restore.indexErrorPolicy(class) == indexErrorFail {
+		names := make([]string, len(indexes))
+		for i, index := range indexes {
+			names[i] = indexName(index)
+		}
+		restore.recordIndexFailure(ns, strings.Join(names, ", "), class, err, "failed")
+		return err
+	}
+
+	log.Logvf(
+		log.Always,
+		"error creating the indexes of %v (%v); creating them one at a time: %v",
+		ns,
+		class,
+		err,
+	)
+	for _, index := range indexes {
+		if err := restore.createIndexWithPolicies(ns, index, create); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createIndexWithPolicies creates one index, skipping it or retrying it with
+// a modification if it fails with an error whose policy says so. Every
+// modification is made at most once.
+func (restore *MongoRestore) createIndexWithPolicies(
+	ns string,
+	index *idx.IndexDocument,
+	create func([]*idx.IndexDocument) error,
+) error {
+	name := indexName(index)
+	modified := map[string]bool{}
+	for {
+		err := create([]*idx.IndexDocument{index})
+		if err == nil {
+			return nil
+		}
+		class := classifyIndexError(err)
+		switch policy := restore.indexErrorPolicy(class); {
+		case class == "" || policy == indexErrorFail:
+			restore.recordIndexFailure(ns, name, class, err, "failed")
+			return err
+		case policy == indexErrorSkip:
+			restore.recordIndexFailure(ns, name, class, err, "skipped")
+			return nil
+		}
+
+		change := ""
+		if !modified[class] {
+			modified[class] = true
+			change = modifyIndexForRetry(index, class, err)
+		}
+		if change == "" {
+			restore.recordIndexFailure(ns, name, class, err, "failed; no modification to retry with")
+			return err
+		}
+		restore.recordIndexFailure(ns, name, class, err, "retried "+change)
+	}
+}
+
+// modifyIndexForRetry modifies index so that creating it again may not fail
+// with an error of class, and describes the modification. It returns "" if
+// there is no modification to make.
+func modifyIndexForRetry(index *idx.IndexDocument, class string, err error) string {
+	switch class {
+	case indexErrorUnsupportedOption:
+		if match := unsupportedIndexFieldRegex.FindStringSubmatch(err.Error()); match != nil {
+			if _, ok := index.Options[match[1]]; ok {
+				delete(index.Options, match[1])
+				return fmt.Sprintf("without the %#q option", match[1])
+			}
+		}
+		before := len(index.Options)
+		bsonutil.ConvertLegacyIndexOptions(index.Options)
+		if len(index.Options) < before {
+			return "without the options that are not index options"
+		}
+	case indexErrorDuplicateKey:
+		if unique, _ := index.Options["unique"].(bool); unique {
+			delete(index.Options, "unique")
+			return "as a non-unique index"
+		}
+	}
+	// an index built alone that exceeds the memory limit can only fail
+	return ""
+}
+
+func indexName(index *idx.IndexDocument) string {
+	return fmt.Sprint(index.Options["name"])
+}
+
+// recordIndexFailure logs and records for --reportFile that an index of ns
+// failed to be created with err, and what was done about it.
+func (restore *MongoRestore) recordIndexFailure(
+	ns, index, class string,
+	err error,
+	action string,
+) {
+	if class == "" {
+		class = "other"
+	}
+	log.Logvf(log.Always, "index %v on %v: %v: %v", index, ns, action, err)
+	restore.stats.recordIndexFailure(IndexFailureReport{
+		Namespace: ns,
+		Index:     index,
+		Class:     class,
+		Error:     err.Error(),
+		Action:    action,
+	})
+}
+
+// LogIndexFailures logs every index that could not be created as it was
+// dumped, and what was done about it. It should be called once the restore
+// is done.
+func (restore *MongoRestore) LogIndexFailures() {
+	failures := restore.stats.report(Result{}).IndexFailures
+	if len(failures) == 0 {
+		return
+	}
+	log.Logvf(
+		log.Always,
+		"%v index %v failed:",
+		len(failures),
+		util.Pluralize(len(failures), "creation", "creations"),
+	)
+	for _, failure := range failures {
+		log.Logvf(
+			log.Always,
+			"\t%v index %v (%v): %v",
+			failure.Namespace,
+			failure.Index,
+			failure.Class,
+			failure.Action,
+		)
+	}
+}
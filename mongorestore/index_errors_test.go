@@ -0,0 +1,157 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/idx"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func policyIndexes() []*idx.IndexDocument {
+	return []*idx.IndexDocument{
+		{Options: bson.M{"name": "a_1", "unique": true}, Key: bson.D{{"a", 1}}},
+		{Options: bson.M{"name": "b_1", "bogus": true}, Key: bson.D{{"b", 1}}},
+		{Options: bson.M{"name": "c_1"}, Key: bson.D{{"c", 1}}},
+	}
+}
+
+// fakeIndexServer creates indexes like a server that rejects the bogus
+// option, can't build unique indexes because of duplicate keys, and runs out
+// of memory when building more than one index at a time.
+type fakeIndexServer struct {
+	created []string
+	calls   int
+}
+
+func (s *fakeIndexServer) create(indexes []*idx.IndexDocument) error {
+	s.calls++
+	if len(indexes) > 1 {
+		return mongo.CommandError{Code: errExceededMemoryLimit, Message: "exceeded memory limit"}
+	}
+	index := indexes[0]
+	if _, ok := index.Options["bogus"]; ok {
+		return mongo.CommandError{
+			Code:    errInvalidIndexSpecificationOption,
+			Message: "The field 'bogus' is not valid for an index specification",
+		}
+	}
+	if unique, _ := index.Options["unique"].(bool); unique {
+		return mongo.CommandError{Code: 11000, Message: "E11000 duplicate key error"}
+	}
+	s.created = append(s.created, indexName(index))
+	return nil
+}
+
+func TestClassifyIndexError(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	for code, class := range map[int32]string{
+		errInvalidIndexSpecificationOption: indexErrorUnsupportedOption,
+		11000:                              indexErrorDuplicateKey,
+		errExceededMemoryLimit:             indexErrorMemoryLimit,
+		2:                                  "",
+	} {
+		err := fmt.Errorf("createIndex error: %w", mongo.CommandError{Code: code})
+		assert.Equal(t, class, classifyIndexError(err), code)
+	}
+	assert.Equal(t, "", classifyIndexError(fmt.Errorf("connection refused")))
+}
+
+func TestIndexErrorPolicies(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	newRestore := func(option, duplicate, memory string) *MongoRestore {
+		mr := newMongoRestore()
+		mr.OutputOptions = &OutputOptions{
+			IndexOptionPolicy:    option,
+			IndexDuplicatePolicy: duplicate,
+			IndexMemoryPolicy:    memory,
+		}
+		return mr
+	}
+
+	t.Run("fail by default", func(t *testing.T) {
+		mr := newRestore("", "", "")
+		server := &fakeIndexServer{}
+		err := mr.createIndexesWithPolicies("db.c", policyIndexes(), server.create)
+		require.Error(t, err)
+		assert.Equal(t, 1, server.calls)
+
+		failures := mr.stats.report(Result{}).IndexFailures
+		require.Len(t, failures, 1)
+		assert.Equal(t, "a_1, b_1, c_1", failures[0].Index)
+		assert.Equal(t, indexErrorMemoryLimit, failures[0].Class)
+		assert.Equal(t, "failed", failures[0].Action)
+	})
+
+	t.Run("retry everything", func(t *testing.T) {
+		mr := newRestore(indexErrorRetry, indexErrorRetry, indexErrorRetry)
+		server := &fakeIndexServer{}
+		indexes := policyIndexes()
+		require.NoError(t, mr.createIndexesWithPolicies("db.c", indexes, server.create))
+		assert.Equal(t, []string{"a_1", "b_1", "c_1"}, server.created)
+		assert.NotContains(t, indexes[0].Options, "unique")
+		assert.NotContains(t, indexes[1].Options, "bogus")
+
+		failures := mr.stats.report(Result{}).IndexFailures
+		require.Len(t, failures, 2)
+		assert.Equal(t, "retried as a non-unique index", failures[0].Action)
+		assert.Equal(t, indexErrorDuplicateKey, failures[0].Class)
+		assert.Equal(t, "retried without the `bogus` option", failures[1].Action)
+		assert.Equal(t, indexErrorUnsupportedOption, failures[1].Class)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		mr := newRestore(indexErrorSkip, indexErrorSkip, indexErrorRetry)
+		server := &fakeIndexServer{}
+		require.NoError(t, mr.createIndexesWithPolicies("db.c", policyIndexes(), server.create))
+		assert.Equal(t, []string{"c_1"}, server.created)
+
+		failures := mr.stats.report(Result{}).IndexFailures
+		require.Len(t, failures, 2)
+		assert.Equal(t, "skipped", failures[0].Action)
+		assert.Equal(t, "skipped", failures[1].Action)
+	})
+
+	t.Run("a class that fails stops the restore", func(t *testing.T) {
+		mr := newRestore(indexErrorFail, indexErrorRetry, indexErrorRetry)
+		server := &fakeIndexServer{}
+		err := mr.createIndexesWithPolicies("db.c", policyIndexes(), server.create)
+		require.Error(t, err)
+		assert.Equal(t, []string{"a_1"}, server.created)
+
+		failures := mr.stats.report(Result{}).IndexFailures
+		require.Len(t, failures, 2)
+		assert.Equal(t, "b_1", failures[1].Index)
+		assert.Equal(t, "failed", failures[1].Action)
+	})
+
+	t.Run("no modification to retry with", func(t *testing.T) {
+		mr := newRestore("", "", indexErrorRetry)
+		calls := 0
+		err := mr.createIndexesWithPolicies(
+			"db.c",
+			policyIndexes()[2:],
+			func([]*idx.IndexDocument) error {
+				calls++
+				return mongo.CommandError{Code: errExceededMemoryLimit}
+			},
+		)
+		require.Error(t, err)
+		assert.Equal(t, 2, calls)
+		failures := mr.stats.report(Result{}).IndexFailures
+		require.Len(t, failures, 1)
+		assert.Equal(t, "failed; no modification to retry with", failures[0].Action)
+	})
+}
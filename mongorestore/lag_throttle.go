@@ -0,0 +1,237 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// lagSampleInterval is how often --maxReplicationLagSeconds samples the
+// replication lag of the target.
+const lagSampleInterval = time.Second
+
+// Replica set member states, as reported by replSetGetStatus.
+const (
+	memberStatePrimary   = 1
+	memberStateSecondary = 2
+)
+
+// lagSample is a measurement of how far the secondaries of the target are
+// behind its primary.
+type lagSample struct {
+	// lag is how far behind the primary the furthest behind secondary is.
+	lag time.Duration
+	// flowControlLagged is whether the primary's flow control is throttling
+	// writes because the majority commit point is lagging.
+	flowControlLagged bool
+}
+
+// lagThrottle limits how many insertion workers write to the target at once,
+// to keep its replication lag under --maxReplicationLagSeconds. It halves the
+// number of writers whenever the lag goes over the bound or flow control
+// kicks in, and adds back a writer at a time while the lag is under half the
+// bound.
+type lagThrottle struct {
+	maxLag     time.Duration
+	maxWriters int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int
+	writing int
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func newLagThrottle(maxLag time.Duration, maxWriters int) *lagThrottle {
+	if maxWriters < 1 {
+		maxWriters = 1
+	}
+	t := &lagThrottle{
+		maxLag:     maxLag,
+		maxWriters: maxWriters,
+		limit:      maxWriters,
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+// acquire waits until another writer is allowed. It does nothing on a nil
+// lagThrottle.
+func (t *lagThrottle) acquire() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.writing >= t.limit {
+		t.cond.Wait()
+	}
+	t.writing++
+}
+
+// release lets another writer write.
+func (t *lagThrottle) release() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.writing--
+	t.cond.Signal()
+}
+
+// adjust changes the number of writers allowed for a new sample.
+func (t *lagThrottle) adjust(sample lagSample) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	limit := t.limit
+	switch {
+	case sample.lag > t.maxLag || sample.flowControlLagged:
+		limit = max(1, limit/2)
+	case sample.lag <= t.maxLag/2:
+		limit = min(t.maxWriters, limit+1)
+	}
+	if limit == t.limit {
+		return
+	}
+	log.Logvf(
+		log.Info,
+		"replication lag is %v (flow control lagged: %v); allowing %v of %v insertion workers to write",
+		sample.lag,
+		sample.flowControlLagged,
+		limit,
+		t.maxWriters,
+	)
+	t.limit = limit
+	t.cond.Broadcast()
+}
+
+// run adjusts the number of writers with a sample taken every interval until
+// the throttle is stopped. Failing to sample is logged, and doesn't change
+// the number of writers.
+func (t *lagThrottle) run(sample func() (lagSample, error), interval time.Duration) {
+	defer close(t.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		}
+		s, err := sample()
+		if err != nil {
+			log.Logvf(log.Always, "warning: error sampling the replication lag: %v", err)
+			continue
+		}
+		t.adjust(s)
+	}
+}
+
+// finish stops sampling, and lets every writer write.
+func (t *lagThrottle) finish() {
+	close(t.stop)
+	<-t.stopped
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limit = t.maxWriters
+	t.cond.Broadcast()
+}
+
+// startLagThrottle starts throttling the insertion workers to keep the
+// replication lag of the target under --maxReplicationLagSeconds.
+func (restore *MongoRestore) startLagThrottle() error {
+	nodeType, err := restore.SessionProvider.GetNodeType()
+	if err != nil {
+		return fmt.Errorf("error checking the connected node type: %v", err)
+	}
+	if nodeType != db.ReplSet {
+		return fmt.Errorf(
+			"%v requires connecting to a replica set, not a %v",
+			MaxReplicationLagSecondsOption,
+			nodeType,
+		)
+	}
+	restore.lagThrottle = newLagThrottle(
+		time.Duration(restore.OutputOptions.MaxReplicationLagSeconds)*time.Second,
+		restore.OutputOptions.NumParallelCollections*restore.OutputOptions.NumInsertionWorkers,
+	)
+	go restore.lagThrottle.run(restore.sampleReplicationLag, lagSampleInterval)
+	return nil
+}
+
+// stopLagThrottle stops throttling the insertion workers.
+func (restore *MongoRestore) stopLagThrottle() {
+	if restore.lagThrottle == nil {
+		return
+	}
+	restore.lagThrottle.finish()
+	restore.lagThrottle = nil
+}
+
+type replSetMember struct {
+	State      int       `bson:"state"`
+	Health     float64   `bson:"health"`
+	OptimeDate time.Time `bson:"optimeDate"`
+}
+
+// sampleReplicationLag measures the replication lag of the target with
+// replSetGetStatus, and whether its flow control is throttling writes.
+func (restore *MongoRestore) sampleReplicationLag() (lagSample, error) {
+	var status struct {
+		Members []replSetMember `bson:"members"`
+	}
+	err := restore.SessionProvider.Run(bson.D{{"replSetGetStatus", 1}}, &status, "admin")
+	if err != nil {
+		return lagSample{}, err
+	}
+	sample := lagSample{lag: replicationLag(status.Members)}
+
+	// flow control is only reported by 4.2+, so it is best effort
+	var serverStatus struct {
+		FlowControl struct {
+			IsLagged bool `bson:"isLagged"`
+		} `bson:"flowControl"`
+	}
+	err = restore.SessionProvider.Run(bson.D{{"serverStatus", 1}}, &serverStatus, "admin")
+	if err == nil {
+		sample.flowControlLagged = serverStatus.FlowControl.IsLagged
+	}
+	return sample, nil
+}
+
+// replicationLag returns how far behind the primary the furthest behind
+// healthy secondary is, or zero if there is no primary or secondary.
+func replicationLag(members []replSetMember) time.Duration {
+	var primary *replSetMember
+	for i := range members {
+		if members[i].State == memberStatePrimary {
+			primary = &members[i]
+		}
+	}
+	if primary == nil {
+		return 0
+	}
+	var lag time.Duration
+	for _, member := range members {
+		if member.State != memberStateSecondary || member.Health != 1 {
+			continue
+		}
+		lag = max(lag, primary.OptimeDate.Sub(member.OptimeDate))
+	}
+	return lag
+}
@@ -0,0 +1,86 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLagThrottle(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	t.Run("the number of writers follows the lag", func(t *testing.T) {
+		throttle := newLagThrottle(10*time.Second, 8)
+		assert.Equal(t, 8, throttle.limit)
+
+		throttle.adjust(lagSample{lag: 11 * time.Second})
+		assert.Equal(t, 4, throttle.limit)
+		throttle.adjust(lagSample{lag: time.Second, flowControlLagged: true})
+		assert.Equal(t, 2, throttle.limit)
+		throttle.adjust(lagSample{lag: time.Minute})
+		throttle.adjust(lagSample{lag: time.Minute})
+		assert.Equal(t, 1, throttle.limit)
+
+		// between half the bound and the bound, the writers stay the same
+		throttle.adjust(lagSample{lag: 7 * time.Second})
+		assert.Equal(t, 1, throttle.limit)
+
+		for i := 0; i < 10; i++ {
+			throttle.adjust(lagSample{lag: 5 * time.Second})
+		}
+		assert.Equal(t, 8, throttle.limit)
+	})
+
+	t.Run("writers wait while the limit is reached", func(t *testing.T) {
+		throttle := newLagThrottle(time.Second, 4)
+		throttle.adjust(lagSample{lag: time.Minute})
+		throttle.adjust(lagSample{lag: time.Minute})
+		assert.Equal(t, 1, throttle.limit)
+
+		throttle.acquire()
+		var acquired atomic.Bool
+		done := make(chan struct{})
+		go func() {
+			throttle.acquire()
+			acquired.Store(true)
+			throttle.release()
+			close(done)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		assert.False(t, acquired.Load())
+
+		throttle.release()
+		<-done
+		assert.True(t, acquired.Load())
+	})
+
+	t.Run("a nil throttle lets every writer write", func(t *testing.T) {
+		var throttle *lagThrottle
+		throttle.acquire()
+		throttle.release()
+	})
+
+	t.Run("the lag is that of the furthest behind healthy secondary", func(t *testing.T) {
+		now := time.Now()
+		members := []replSetMember{
+			{State: memberStateSecondary, Health: 1, OptimeDate: now.Add(-3 * time.Second)},
+			{State: memberStatePrimary, Health: 1, OptimeDate: now},
+			{State: memberStateSecondary, Health: 1, OptimeDate: now.Add(-5 * time.Second)},
+			// down, or an arbiter
+			{State: memberStateSecondary, Health: 0, OptimeDate: now.Add(-time.Hour)},
+			{State: 7, Health: 1},
+		}
+		assert.Equal(t, 5*time.Second, replicationLag(members))
+		assert.Equal(t, time.Duration(0), replicationLag(members[1:2]))
+		assert.Equal(t, time.Duration(0), replicationLag(members[2:3]))
+	})
+}
@@ -0,0 +1,209 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/bsonutil"
+	"github.com/mongodb/mongo-tools/common/idx"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// indexTranslationFile is the format of an --indexTranslationFile, e.g.
+//
+//	{"keys": [{"from": "1", "to": 1}, {"from": true, "to": 1}],
+//	 "removeOptions": ["dropDups", "safe"],
+//	 "versions": [{"from": 0, "to": 1}]}
+type indexTranslationFile struct {
+	Keys []struct {
+		From bson.RawValue `bson:"from"`
+		To   bson.RawValue `bson:"to"`
+	} `bson:"keys"`
+	RemoveOptions []string `bson:"removeOptions"`
+	Versions      []struct {
+		From int32 `bson:"from"`
+		To   int32 `bson:"to"`
+	} `bson:"versions"`
+}
+
+// indexTranslation rewrites the index definitions of a dump with the explicit
+// rules of an --indexTranslationFile, before any other conversion. Like the
+// values of a --valueMapFile, index key values only match if both their BSON
+// type and value are the same.
+type indexTranslation struct {
+	keys          map[string]interface{}
+	removeOptions []string
+	versions      map[int32]int32
+}
+
+// loadIndexTranslation reads an --indexTranslationFile.
+func loadIndexTranslation(path string, keepIndexVersion bool) (*indexTranslation, error) {
+	content, err := os.ReadFile(util.ToUniversalPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %v: %v", IndexTranslationFileOption, err)
+	}
+	var file indexTranslationFile
+	if err := bson.UnmarshalExtJSON(content, false, &file); err != nil {
+		return nil, fmt.Errorf("error parsing %v %v: %v", IndexTranslationFileOption, path, err)
+	}
+
+	t := &indexTranslation{
+		keys:          map[string]interface{}{},
+		removeOptions: file.RemoveOptions,
+		versions:      map[int32]int32{},
+	}
+	for _, key := range file.Keys {
+		if key.From.Type == 0 || key.To.Type == 0 {
+			return nil, fmt.Errorf("every key of %v needs a from and a to", path)
+		}
+		var to interface{}
+		if err := key.To.Unmarshal(&to); err != nil {
+			return nil, fmt.Errorf("invalid key value %v in %v: %v", key.To, path, err)
+		}
+		from := rawValueKey(key.From)
+		if _, ok := t.keys[from]; ok {
+			return nil, fmt.Errorf("key value %v is translated twice in %v", key.From, path)
+		}
+		t.keys[from] = to
+	}
+	for _, option := range file.RemoveOptions {
+		if option == "" || option == "key" || option == "name" {
+			return nil, fmt.Errorf("cannot remove the index option '%v' in %v", option, path)
+		}
+	}
+	for _, version := range file.Versions {
+		if version.To < 1 || version.To > 2 {
+			return nil, fmt.Errorf("cannot translate indexes to version %v in %v", version.To, path)
+		}
+		t.versions[version.From] = version.To
+	}
+	if len(t.versions) > 0 && !keepIndexVersion {
+		return nil, fmt.Errorf(
+			"the versions of %v require %v, as other indexes are created with the current version",
+			IndexTranslationFileOption,
+			KeepIndexVersionOption,
+		)
+	}
+	return t, nil
+}
+
+// translate rewrites index, and describes what it changed.
+func (t *indexTranslation) translate(index *idx.IndexDocument) []string {
+	var changes []string
+	for i, key := range index.Key {
+		valueType, value, err := bson.MarshalValue(key.Value)
+		if err != nil {
+			continue
+		}
+		to, ok := t.keys[string(rune(valueType))+string(value)]
+		if !ok {
+			continue
+		}
+		changes = append(changes, fmt.Sprintf(
+			"translated the key %v to %v",
+			bsonutil.CreateExtJSONString(bson.D{key}),
+			bsonutil.CreateExtJSONString(bson.D{{Key: key.Key, Value: to}}),
+		))
+		index.Key[i].Value = to
+	}
+	for _, option := range t.removeOptions {
+		if _, ok := index.Options[option]; ok {
+			delete(index.Options, option)
+			changes = append(changes, "removed the option "+option)
+		}
+	}
+	if v, err := util.ToFloat64(index.Options["v"]); err == nil {
+		if to, ok := t.versions[int32(v)]; ok && float64(int32(v)) == v && int32(v) != to {
+			index.Options["v"] = to
+			changes = append(changes, fmt.Sprintf("translated version %v to version %v", v, to))
+		}
+	}
+	return changes
+}
+
+// translateIndexes applies the --indexTranslationFile, if any, to the indexes
+// of ns. Every change is logged and recorded for --reportFile.
+func (restore *MongoRestore) translateIndexes(indexes []*idx.IndexDocument, ns string) {
+	if restore.indexTranslation == nil {
+		return
+	}
+	for _, index := range indexes {
+		for _, change := range restore.indexTranslation.translate(index) {
+			change += " by " + IndexTranslationFileOption
+			log.Logvf(log.Always, "index %v on %v: %v", index.Options["name"], ns, change)
+			restore.stats.recordIndexChange(ns, fmt.Sprint(index.Options["name"]), change)
+		}
+	}
+}
+
+// legacyIndexFindings describes how index, as it was dumped, differs from the
+// definitions that current servers create.
+func legacyIndexFindings(index *idx.IndexDocument) []string {
+	var findings []string
+	for _, key := range index.Key {
+		if _, converted := bsonutil.ConvertLegacyIndexKeyValue(key.Value); converted {
+			findings = append(findings, fmt.Sprintf(
+				"the key %v has a legacy value",
+				bsonutil.CreateExtJSONString(bson.D{key}),
+			))
+		}
+	}
+	if v, err := util.ToFloat64(index.Options["v"]); err == nil {
+		switch v {
+		case 0:
+			findings = append(findings, "version 0 index, which servers cannot create since 3.2")
+		case 1:
+			findings = append(findings, "version 1 index")
+		}
+	}
+	var unknown []string
+	for option := range index.Options {
+		if !bsonutil.IsValidIndexOption(option) {
+			unknown = append(unknown, option)
+		}
+	}
+	sort.Strings(unknown)
+	if len(unknown) > 0 {
+		findings = append(findings, "unknown options "+strings.Join(unknown, ", "))
+	}
+	for _, feature := range removedIndexFeatures {
+		if feature.usedBy(index) {
+			findings = append(findings, "uses "+feature.name)
+		}
+	}
+	return findings
+}
+
+// reportLegacyIndexes records, for --reportFile, the indexes of ns whose
+// dumped definitions use legacy formats, whatever the options convert.
+func (restore *MongoRestore) reportLegacyIndexes(indexes []*idx.IndexDocument, ns string) {
+	for _, index := range indexes {
+		findings := legacyIndexFindings(index)
+		if len(findings) == 0 {
+			continue
+		}
+		name := fmt.Sprint(index.Options["name"])
+		log.Logvf(
+			log.Info,
+			"index %v on %v was dumped in a legacy format: %v",
+			name,
+			ns,
+			strings.Join(findings, "; "),
+		)
+		restore.stats.recordLegacyIndex(LegacyIndexReport{
+			Namespace: ns,
+			Index:     name,
+			Findings:  findings,
+		})
+	}
+}
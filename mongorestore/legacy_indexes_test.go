@@ -0,0 +1,104 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/idx"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func writeIndexTranslationFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "translation.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestIndexTranslation(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	path := writeIndexTranslationFile(t, `{
+		"keys": [{"from": "1", "to": 1}, {"from": true, "to": -1}],
+		"removeOptions": ["dropDups", "safe"],
+		"versions": [{"from": 0, "to": 1}]
+	}`)
+	_, err := loadIndexTranslation(path, false)
+	assert.Error(t, err, "versions require --keepIndexVersion")
+
+	translation, err := loadIndexTranslation(path, true)
+	require.NoError(t, err)
+	mr := newMongoRestore()
+	mr.indexTranslation = translation
+
+	indexes := []*idx.IndexDocument{
+		{
+			Options: bson.M{"name": "a_1_b_1", "v": int32(0), "dropDups": true, "safe": 1},
+			Key:     bson.D{{"a", "1"}, {"b", true}, {"c", 1.0}},
+		},
+		{
+			Options: bson.M{"name": "d_1", "v": int32(2)},
+			Key:     bson.D{{"d", int32(1)}},
+		},
+	}
+	mr.translateIndexes(indexes, "db.c")
+
+	assert.Equal(t, bson.D{{"a", int32(1)}, {"b", int32(-1)}, {"c", 1.0}}, indexes[0].Key)
+	assert.Equal(t, bson.M{"name": "a_1_b_1", "v": int32(1)}, indexes[0].Options)
+	assert.Equal(t, bson.M{"name": "d_1", "v": int32(2)}, indexes[1].Options)
+
+	changes := mr.stats.report(Result{}).IndexChanges
+	require.Len(t, changes, 5)
+	assert.Equal(t, IndexChangeReport{
+		Namespace: "db.c",
+		Index:     "a_1_b_1",
+		Change:    `translated the key {"a":"1"} to {"a":1} by --indexTranslationFile`,
+	}, changes[0])
+	assert.Equal(t, "translated version 0 to version 1 by --indexTranslationFile", changes[4].Change)
+
+	for _, content := range []string{
+		`{"keys": [{"from": "1"}]}`,
+		`{"keys": [{"from": "1", "to": 1}, {"from": "1", "to": -1}]}`,
+		`{"removeOptions": ["key"]}`,
+		`{"versions": [{"from": 1, "to": 3}]}`,
+	} {
+		_, err := loadIndexTranslation(writeIndexTranslationFile(t, content), true)
+		assert.Error(t, err, content)
+	}
+}
+
+func TestReportLegacyIndexes(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	mr := newMongoRestore()
+	mr.reportLegacyIndexes([]*idx.IndexDocument{
+		{
+			Options: bson.M{"name": "a_1", "v": int32(0), "dropDups": true, "safe": true},
+			Key:     bson.D{{"a", ""}},
+		},
+		{
+			Options: bson.M{"name": "b_1", "v": int32(2)},
+			Key:     bson.D{{"b", int32(1)}},
+		},
+	}, "db.c")
+
+	assert.Equal(t, []LegacyIndexReport{{
+		Namespace: "db.c",
+		Index:     "a_1",
+		Findings: []string{
+			`the key {"a":""} has a legacy value`,
+			"version 0 index, which servers cannot create since 3.2",
+			"unknown options dropDups, safe",
+			"uses the dropDups option",
+		},
+	}}, mr.stats.report(Result{}).LegacyIndexes)
+}
@@ -8,10 +8,13 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/signals"
+	"github.com/mongodb/mongo-tools/common/telemetry"
 	"github.com/mongodb/mongo-tools/common/util"
 	"github.com/mongodb/mongo-tools/mongorestore"
 )
@@ -39,10 +42,58 @@ func main() {
 		return
 	}
 
+	if err := telemetry.Init(opts.AppName, opts.VersionStr, opts.OTelEndpoint); err != nil {
+		log.Logvf(log.Always, "error starting telemetry: %v", err)
+		os.Exit(util.ExitFailure)
+	}
+	defer telemetry.Shutdown()
+
+	if opts.Serve != "" {
+		server, err := mongorestore.NewJobServer(opts)
+		if err != nil {
+			log.Logvf(log.Always, "Failed: %v", err)
+			telemetry.Exit(util.ExitFailure)
+		}
+		finishedChan := signals.HandleWithInterrupt(server.Shutdown)
+		err = server.ListenAndServe(opts.Serve)
+		close(finishedChan)
+		if err != nil {
+			log.Logvf(log.Always, "Failed: %v", err)
+			telemetry.Exit(util.ExitFailure)
+		}
+		telemetry.Exit(util.ExitSuccess)
+	}
+
+	if opts.Cutover != "" {
+		token, err := mongorestore.ReadCutoverToken(opts.CutoverTokenFile)
+		if err != nil {
+			log.Logvf(log.Always, "Failed: %v", err)
+			telemetry.Exit(util.ExitFailure)
+		}
+		status, err := mongorestore.RequestCutover(opts.Cutover, token)
+		if status.State != "" {
+			out, _ := json.MarshalIndent(status, "", "  ")
+			fmt.Println(string(out))
+		}
+		if err != nil {
+			log.Logvf(log.Always, "Failed: %v", err)
+			telemetry.Exit(util.ExitFailure)
+		}
+		telemetry.Exit(util.ExitSuccess)
+	}
+
+	if opts.CheckArchive {
+		if err := mongorestore.CheckArchive(opts); err != nil {
+			log.Logvf(log.Always, "Failed: %v", err)
+			telemetry.Exit(util.ExitFailure)
+		}
+		telemetry.Exit(util.ExitSuccess)
+	}
+
 	restore, err := mongorestore.New(opts)
 	if err != nil {
 		log.Logvf(log.Always, err.Error())
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 	defer restore.Close()
 
@@ -54,7 +105,9 @@ func main() {
 		log.Logvf(log.Always, "Failed: %v", result.Err)
 	}
 
-	if restore.ToolOptions.WriteConcern.Acknowledged() {
+	if restore.OutputOptions.Compare {
+		log.Logvf(log.Always, "done")
+	} else if restore.ToolOptions.WriteConcern.Acknowledged() {
 		log.Logvf(
 			log.Always,
 			"%v document(s) restored successfully. %v document(s) failed to restore.",
@@ -64,9 +117,18 @@ func main() {
 	} else {
 		log.Logvf(log.Always, "done")
 	}
+	restore.LogSkippedNamespaces()
+	restore.LogIndexFailures()
 
+	if err := restore.WriteReport(result); err != nil {
+		log.Logvf(log.Always, "Failed: %v", err)
+		telemetry.Exit(util.ExitFailure)
+	}
 	if result.Err != nil {
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
+	}
+	if result.IsIncomplete() {
+		telemetry.Exit(mongorestore.ExitIncomplete)
 	}
-	os.Exit(util.ExitSuccess)
+	telemetry.Exit(util.ExitSuccess)
 }
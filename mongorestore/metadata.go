@@ -218,7 +218,7 @@ func (restore *MongoRestore) CreateIndexes(
 		return nil
 	}
 	if err.Error() != "no such cmd: createIndexes" {
-		return fmt.Errorf("createIndex error: %v", err)
+		return fmt.Errorf("createIndex error: %w", err)
 	}
 
 	// if we're here, the connected server does not support the command, so we fall back
@@ -15,6 +15,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -24,14 +25,17 @@ import (
 	"github.com/mongodb/mongo-tools/common/archive"
 	"github.com/mongodb/mongo-tools/common/auth"
 	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/dumprestore"
 	"github.com/mongodb/mongo-tools/common/idx"
 	"github.com/mongodb/mongo-tools/common/intents"
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/options"
 	"github.com/mongodb/mongo-tools/common/progress"
+	"github.com/mongodb/mongo-tools/common/tempfile"
 	"github.com/mongodb/mongo-tools/common/util"
 	"github.com/mongodb/mongo-tools/mongorestore/ns"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/exp/maps"
 )
 
 const (
@@ -68,6 +72,9 @@ type MongoRestore struct {
 	manager *intents.Manager
 
 	objCheck     bool
+	docValidator db.DocumentValidator
+	lagThrottle  *lagThrottle
+	valueMapper  *valueMapper
 	oplogLimit   primitive.Timestamp
 	isMongos     bool
 	isAtlasProxy bool
@@ -86,6 +93,13 @@ type MongoRestore struct {
 
 	indexCatalog *idx.IndexCatalog
 
+	// the indexes of the catalog built before --oplogFollow follows its
+	// source, which are not built again after the cutover
+	builtIndexes *builtIndexes
+
+	// rewrites the dumped index definitions, if --indexTranslationFile is set
+	indexTranslation *indexTranslation
+
 	archive *archive.Reader
 
 	// boolean set if termination signal received; false by default
@@ -98,6 +112,37 @@ type MongoRestore struct {
 	// Server versions for version-specific behavior
 	dumpServerVersion db.Version
 	serverVersion     db.Version
+
+	// sharding metadata to re-apply with --restoreShardingConfig, keyed by
+	// destination namespace
+	shardedNamespaces map[string]*namespaceShardingConfig
+	shardZones        []shardZones
+
+	// namespaces to warm the cache for with --warmCache, and the warmer that
+	// loads them while the restore goes on
+	warmCacheMatcher *ns.Matcher
+	cacheWarmer      *cacheWarmer
+
+	// namespaces to restore as timeseries collections with --convertToTimeseries
+	timeseriesMatcher *ns.Matcher
+
+	// the --documentValidation policies, in the order they were given
+	validationRules []validationRule
+
+	// the shadow collections --oplogDryApply replays the oplog into
+	oplogShadow *oplogShadow
+
+	// how many documents of each collection are restored, for --restoreJournal
+	restoreJournal *restoreJournal
+
+	// how far the oplog replay got, for --oplogJournal
+	oplogJournal *oplogJournal
+
+	// per-namespace counts of restored and skipped documents
+	stats restoreStats
+
+	// the temporary files of the restore, like the --archivePrefetchBytes buffer
+	tempFiles *tempfile.Manager
 }
 
 type collectionIndexes map[string][]*idx.IndexDocument
@@ -153,6 +198,11 @@ func New(opts Options) (*MongoRestore, error) {
 // Close ends any connections and cleans up other internal state.
 func (restore *MongoRestore) Close() {
 	restore.SessionProvider.Close()
+	if restore.tempFiles != nil {
+		if err := restore.tempFiles.Cleanup(); err != nil {
+			log.Logvf(log.Always, "%v", err)
+		}
+	}
 	barWriter, ok := restore.ProgressManager.(*progress.BarWriter)
 	if ok { // should always be ok
 		barWriter.Stop()
@@ -221,6 +271,44 @@ func (restore *MongoRestore) ParseAndValidateOptions() error {
 			return fmt.Errorf("cannot use --oplogFile with --archive specified")
 		}
 	}
+	if restore.InputOptions.OplogJournal != "" && !restore.InputOptions.OplogReplay {
+		return fmt.Errorf("cannot use %v without %v enabled", OplogJournalOption, OplogReplayOption)
+	}
+	if err := restore.validateOplogDryApply(); err != nil {
+		return err
+	}
+	if err := restore.validateOplogFollow(); err != nil {
+		return err
+	}
+	if err := restore.validateRestoreJournal(); err != nil {
+		return err
+	}
+
+	if err := dumprestore.ValidateSystemCollections(restore.NSOptions.SystemCollections); err != nil {
+		return err
+	}
+
+	if restore.InputOptions.Tolerant && restore.InputOptions.Archive == "" {
+		return fmt.Errorf("cannot use %v without %v", TolerantOption, ArchiveOption)
+	}
+	if restore.InputOptions.ArchivePrefetchBytes < 0 {
+		return fmt.Errorf("%v must not be negative", ArchivePrefetchBytesOption)
+	}
+	if restore.InputOptions.ArchivePrefetchBytes > 0 && restore.InputOptions.Archive == "" {
+		return fmt.Errorf("cannot use %v without %v", ArchivePrefetchBytesOption, ArchiveOption)
+	}
+	if restore.InputOptions.ArchivePrefetchDir != "" &&
+		restore.InputOptions.ArchivePrefetchBytes == 0 {
+		return fmt.Errorf(
+			"cannot use %v without %v",
+			ArchivePrefetchDirOption,
+			ArchivePrefetchBytesOption,
+		)
+	}
+	if restore.InputOptions.ArchivePrefetchDir != "" &&
+		restore.ToolOptions.TempFiles != nil && restore.ToolOptions.TempDir != "" {
+		return fmt.Errorf("cannot use %v with --tempDir", ArchivePrefetchDirOption)
+	}
 
 	// check if we are using a replica set and fall back to w=1 if we aren't (for <= 2.4)
 	nodeType, err := restore.SessionProvider.GetNodeType()
@@ -286,6 +374,10 @@ func (restore *MongoRestore) ParseAndValidateOptions() error {
 	for _, colPrefix := range restore.NSOptions.ExcludedCollectionPrefixes {
 		excludes = append(excludes, "*."+ns.Escape(colPrefix)+"*")
 	}
+	if restore.OutputOptions.RestoreShardingConfig {
+		// the config database is read for its sharding metadata, not restored
+		excludes = append(excludes, "config.*")
+	}
 	restore.excluder, err = ns.NewMatcher(excludes)
 	if err != nil {
 		return fmt.Errorf("invalid excludes: %v", err)
@@ -296,7 +388,11 @@ func (restore *MongoRestore) ParseAndValidateOptions() error {
 			"--nsFrom and --nsTo arguments must be specified an equal number of times",
 		)
 	}
-	restore.renamer, err = ns.NewRenamer(restore.NSOptions.NSFrom, restore.NSOptions.NSTo)
+	nsTo, err := restore.expandNSTo(time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid renames: %v", err)
+	}
+	restore.renamer, err = ns.NewRenamer(restore.NSOptions.NSFrom, nsTo)
 	if err != nil {
 		return fmt.Errorf("invalid renames: %v", err)
 	}
@@ -306,6 +402,40 @@ func (restore *MongoRestore) ParseAndValidateOptions() error {
 			"cannot specify a negative number of insertion workers per collection")
 	}
 
+	if restore.OutputOptions.BulkBufferSize < 0 {
+		return fmt.Errorf("cannot specify a negative %v", BulkBufferSizeOption)
+	}
+
+	if restore.OutputOptions.MaxDocSizeBytes < 0 {
+		return fmt.Errorf("cannot specify a negative %v", MaxDocSizeBytesOption)
+	}
+	restore.docValidator = db.DocumentValidator{MaxSize: restore.OutputOptions.MaxDocSizeBytes}
+
+	if restore.OutputOptions.MaxReplicationLagSeconds < 0 {
+		return fmt.Errorf("cannot specify a negative %v", MaxReplicationLagSecondsOption)
+	}
+
+	if restore.OutputOptions.ValueMapFile != "" {
+		restore.valueMapper, err = loadValueMapper(restore.OutputOptions.ValueMapFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if restore.OutputOptions.IndexTranslationFile != "" {
+		restore.indexTranslation, err = loadIndexTranslation(
+			restore.OutputOptions.IndexTranslationFile,
+			restore.OutputOptions.KeepIndexVersion,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := restore.validateDuplicateKeyPolicy(); err != nil {
+		return err
+	}
+
 	if restore.OutputOptions.MaintainInsertionOrder {
 		restore.OutputOptions.StopOnError = true
 		restore.OutputOptions.NumInsertionWorkers = 1
@@ -315,6 +445,69 @@ func (restore *MongoRestore) ParseAndValidateOptions() error {
 		return fmt.Errorf("cannot specify --preserveUUID without --drop")
 	}
 
+	if restore.OutputOptions.ShardingConfigFile != "" && !restore.OutputOptions.RestoreShardingConfig {
+		return fmt.Errorf("cannot use %v without %v", ShardingConfigFileOption, RestoreShardingConfigOption)
+	}
+	if restore.OutputOptions.RestoreShardingConfig {
+		if !restore.isMongos {
+			return fmt.Errorf("cannot use %v unless connected to a mongos", RestoreShardingConfigOption)
+		}
+		if restore.InputOptions.Archive != "" && restore.OutputOptions.ShardingConfigFile == "" {
+			return fmt.Errorf(
+				"cannot use %v with %v unless %v is also specified",
+				RestoreShardingConfigOption,
+				ArchiveOption,
+				ShardingConfigFileOption,
+			)
+		}
+	}
+
+	if restore.OutputOptions.Compare {
+		for _, conflict := range []struct {
+			option string
+			set    bool
+		}{
+			{DropOption, restore.OutputOptions.Drop},
+			{DryRunOption, restore.OutputOptions.DryRun},
+			{OplogReplayOption, restore.InputOptions.OplogReplay},
+			{RestoreDBUsersAndRolesOption, restore.InputOptions.RestoreDBUsersAndRoles},
+			{RestoreShardingConfigOption, restore.OutputOptions.RestoreShardingConfig},
+			{WarmCacheOption, restore.OutputOptions.WarmCache},
+		} {
+			if conflict.set {
+				return fmt.Errorf("cannot use %v with %v", conflict.option, CompareOption)
+			}
+		}
+		if restore.OutputOptions.CompareSampleSize < 0 {
+			return fmt.Errorf("%v must not be negative", CompareSampleSizeOption)
+		}
+	}
+
+	if len(restore.OutputOptions.WarmCacheNS) > 0 && !restore.OutputOptions.WarmCache {
+		return fmt.Errorf("cannot use %v without %v", WarmCacheNSOption, WarmCacheOption)
+	}
+	if restore.OutputOptions.WarmCache {
+		if restore.OutputOptions.NumWarmCacheWorkers <= 0 {
+			return fmt.Errorf("%v must be positive", NumWarmCacheWorkersOption)
+		}
+		warmCacheNS := restore.OutputOptions.WarmCacheNS
+		if len(warmCacheNS) == 0 {
+			warmCacheNS = []string{"*"}
+		}
+		restore.warmCacheMatcher, err = ns.NewMatcher(warmCacheNS)
+		if err != nil {
+			return fmt.Errorf("invalid %v: %v", WarmCacheNSOption, err)
+		}
+	}
+
+	if err = restore.validateTimeseriesOptions(); err != nil {
+		return err
+	}
+
+	if err = restore.validateValidationPolicies(); err != nil {
+		return err
+	}
+
 	// a single dash signals reading from stdin
 	if restore.TargetDirectory == "-" {
 		if restore.InputOptions.Archive != "" {
@@ -341,6 +534,12 @@ func (restore *MongoRestore) Restore() Result {
 		return Result{Err: err}
 	}
 
+	// a replay that resumes from its journal must not restore the
+	// collections again, which would undo the entries it already applied
+	if err := restore.loadOplogJournal(); err != nil {
+		return Result{Err: err}
+	}
+
 	// Build up all intents to be restored
 	restore.manager = intents.NewIntentManager()
 	if restore.InputOptions.Archive == "" && restore.InputOptions.OplogReplay {
@@ -379,6 +578,18 @@ func (restore *MongoRestore) Restore() Result {
 			`archive tool version "%v"`,
 			restore.archive.Prelude.Header.ToolVersion,
 		)
+		if restore.archive.Prelude.Header.Layout != "" {
+			log.Logvf(
+				log.DebugLow,
+				`archive layout "%v"`,
+				restore.archive.Prelude.Header.Layout,
+			)
+		}
+		if restore.InputOptions.RestoreJournal != "" {
+			if err := restore.openRestoreJournal(); err != nil {
+				return Result{Err: err}
+			}
+		}
 		target, err = restore.archive.Prelude.NewPreludeExplorer()
 		if err != nil {
 			return Result{Err: err}
@@ -450,6 +661,7 @@ func (restore *MongoRestore) Restore() Result {
 			restore.archive.In,
 			restore.isAtlasProxy,
 		)
+		restore.archive.Demux.Tolerant = restore.InputOptions.Tolerant
 	}
 
 	switch {
@@ -611,37 +823,66 @@ func (restore *MongoRestore) Restore() Result {
 		return Result{Err: fmt.Errorf("restore error: %v", err)}
 	}
 
+	if restore.OutputOptions.Compare {
+		result := restore.compareIntents()
+		if restore.InputOptions.Archive != "" {
+			<-demuxFinished
+			if result.Err == nil {
+				result.Err = demuxErr
+			}
+		}
+		return result
+	}
+
 	err = restore.preFlightChecks()
 	if err != nil {
 		return Result{Err: fmt.Errorf("restore error: %v", err)}
 	}
 
-	// Restore the regular collections
-	if restore.InputOptions.Archive != "" {
-		restore.manager.UsePrioritizer(restore.archive.Demux.NewPrioritizer(restore.manager))
-	} else if restore.OutputOptions.NumParallelCollections > 1 {
-		// 3.0+ has collection-level locking for writes, so it is most efficient to
-		// prioritize by collection size. Pre-3.0 we try to avoid inserting into collections
-		// in the same database simultaneously due to the database-level locking.
-		// Up to 4.2, foreground index builds take a database-level lock for the entire build,
-		// but this prioritizer is not used for index builds so we don't need to worry about that here.
-		if restore.serverVersion.GTE(db.Version{3, 0, 0}) {
-			restore.manager.Finalize(intents.LongestTaskFirst)
-		} else {
-			restore.manager.Finalize(intents.MultiDatabaseLTF)
+	if restore.OutputOptions.RestoreShardingConfig {
+		if err = restore.loadShardingConfig(); err != nil {
+			return Result{Err: fmt.Errorf("error reading sharding config: %v", err)}
+		}
+		if err = restore.restoreShardZones(); err != nil {
+			return Result{Err: fmt.Errorf("restore error: %v", err)}
 		}
-	} else {
-		// use legacy restoration order if we are single-threaded
-		restore.manager.Finalize(intents.Legacy)
 	}
 
-	result := restore.RestoreIntents()
+	restore.prioritizeIntents()
+
+	if restore.OutputOptions.MaxReplicationLagSeconds > 0 {
+		if err := restore.startLagThrottle(); err != nil {
+			return Result{Err: err}
+		}
+	}
+	if restore.OutputOptions.WarmCache {
+		restore.startCacheWarmer()
+		defer restore.cacheWarmer.cancel()
+	}
+	var result Result
+	if restore.oplogJournal.resuming() {
+		log.Logvf(
+			log.Always,
+			"not restoring the collections, users and roles, which were restored "+
+				"before the oplog replay that %v resumes",
+			OplogJournalOption,
+		)
+		for _, intent := range restore.manager.Intents() {
+			restore.cacheWarmer.restored(intent.Namespace())
+		}
+	} else {
+		result = restore.RestoreIntents()
+	}
+	restore.stopLagThrottle()
+	if err := restore.restoreJournal.save(); err != nil {
+		log.Logvf(log.Always, "warning: %v", err)
+	}
 	if result.Err != nil {
 		return result
 	}
 
 	// Restore users/roles
-	if restore.ShouldRestoreUsersAndRoles() {
+	if restore.ShouldRestoreUsersAndRoles() && !restore.oplogJournal.resuming() {
 		err = restore.RestoreUsersOrRoles(restore.manager.Users(), restore.manager.Roles())
 		if err != nil {
 			return result.withErr(fmt.Errorf("restore error: %v", err))
@@ -663,14 +904,74 @@ func (restore *MongoRestore) Restore() Result {
 		}
 	}
 
+	restore.cacheWarmer.finish()
+
+	restore.valueMapper.logSummary()
+
 	if restore.InputOptions.Archive != "" {
 		<-demuxFinished
+		restore.reportIncompleteNamespaces(&result)
 		return result.withErr(demuxErr)
 	}
 
 	return result
 }
 
+// prioritizeIntents sets the order in which the regular collections are
+// restored.
+func (restore *MongoRestore) prioritizeIntents() {
+	if restore.InputOptions.Archive != "" {
+		restore.manager.UsePrioritizer(restore.archive.Demux.NewPrioritizer(restore.manager))
+	} else if restore.OutputOptions.NumParallelCollections > 1 {
+		// 3.0+ has collection-level locking for writes, so it is most efficient to
+		// prioritize by collection size. Pre-3.0 we try to avoid inserting into collections
+		// in the same database simultaneously due to the database-level locking.
+		// Up to 4.2, foreground index builds take a database-level lock for the entire build,
+		// but this prioritizer is not used for index builds so we don't need to worry about that here.
+		if restore.serverVersion.GTE(db.Version{3, 0, 0}) {
+			restore.manager.Finalize(intents.LongestTaskFirst)
+		} else {
+			restore.manager.Finalize(intents.MultiDatabaseLTF)
+		}
+	} else {
+		// use legacy restoration order if we are single-threaded
+		restore.manager.Finalize(intents.Legacy)
+	}
+}
+
+// reportIncompleteNamespaces records in result, and logs, the namespaces that
+// --tolerant could only partially restore from a damaged archive.
+func (restore *MongoRestore) reportIncompleteNamespaces(result *Result) {
+	demux := restore.archive.Demux
+	if !demux.Tolerant {
+		return
+	}
+	result.SkippedBytes = demux.SkippedBytes
+	if len(demux.Incomplete) > 0 {
+		result.Incomplete = maps.Clone(demux.Incomplete)
+	}
+	if demux.SkippedBytes > 0 {
+		log.Logvf(log.Always, "skipped %v bytes of corrupted archive data", demux.SkippedBytes)
+	}
+	if len(demux.Incomplete) == 0 {
+		return
+	}
+	namespaces := make([]string, 0, len(demux.Incomplete))
+	for ns := range demux.Incomplete {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	log.Logvf(
+		log.Always,
+		"%v %v could not be fully restored from the archive:",
+		len(namespaces),
+		util.Pluralize(len(namespaces), "namespace", "namespaces"),
+	)
+	for _, ns := range namespaces {
+		log.Logvf(log.Always, "\t%v: %v", ns, demux.Incomplete[ns])
+	}
+}
+
 // ReadPreludeMetadata finds and parses the prelude.json file if it's present.
 // It currently only sets the server.dumpServerVersion, but in the future we can read and set other metadata from the dump as required.
 // Returns true if the metadata file exists.
@@ -749,70 +1050,14 @@ func (restore *MongoRestore) ReadPreludeMetadata(target archive.DirLike) (bool,
 	}
 }
 
-func (restore *MongoRestore) preFlightChecks() error {
-
-	for _, intent := range restore.manager.Intents() {
-		if intent.Type == "timeseries" {
-
-			if !restore.OutputOptions.Drop {
-				timeseriesExists, err := restore.CollectionExists(intent.DB, intent.C)
-				if err != nil {
-					return err
-				}
-
-				if timeseriesExists {
-					return fmt.Errorf(
-						"timeseries collection `%s` already exists on the destination. "+
-							"You must remove this collection from the destination or use --drop",
-						intent.Namespace(),
-					)
-				}
-
-				bucketExists, err := restore.CollectionExists(intent.DB, intent.DataCollection())
-				if err != nil {
-					return err
-				}
-
-				if bucketExists {
-					return fmt.Errorf(
-						"system.buckets collection `%v` already exists on the destination. "+
-							"You must remove this collection from the destination in order to restore %s",
-						intent.DataNamespace(),
-						intent.Namespace(),
-					)
-				}
-			}
-
-			if restore.OutputOptions.NoOptionsRestore {
-				return fmt.Errorf(
-					"cannot specify --noOptionsRestore when restoring timeseries collections",
-				)
-			}
-		}
-	}
-
-	if restore.serverVersion.GTE(db.Version{4, 9, 0}) && !restore.OutputOptions.NoIndexRestore {
-		namespaces := restore.indexCatalog.Namespaces()
-		for _, ns := range namespaces {
-			indexes := restore.indexCatalog.GetIndexes(ns.DB, ns.Collection)
-			for _, index := range indexes {
-				for _, keyElement := range index.Key {
-					if keyElement.Value == "geoHaystack" {
-						return fmt.Errorf("found a geoHaystack index: %v on %s. "+
-							"geoHaystack indexes are not supported by the destination cluster. "+
-							"Remove the index from the source or use --noIndexRestore to skip all indexes.", index.Key, ns.String())
-					}
-				}
-			}
-		}
-	}
-
-	return nil
-}
-
 func (restore *MongoRestore) getArchiveReader() (rc io.ReadCloser, err error) {
 	if restore.InputOptions.Archive == "-" {
 		rc = io.NopCloser(restore.InputReader)
+	} else if archive.IsStreamEndpoint(restore.InputOptions.Archive) {
+		rc, err = archive.OpenStreamReader(restore.InputOptions.Archive)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		targetStat, err := os.Stat(restore.InputOptions.Archive)
 		if err != nil {
@@ -834,6 +1079,18 @@ func (restore *MongoRestore) getArchiveReader() (rc io.ReadCloser, err error) {
 			}
 		}
 	}
+	if restore.InputOptions.ArchivePrefetchBytes > 0 {
+		prefetch, err := newPrefetchReader(
+			rc,
+			restore.InputOptions.ArchivePrefetchBytes,
+			restore.tempFileManager(),
+		)
+		if err != nil {
+			_ = rc.Close()
+			return nil, err
+		}
+		rc = prefetch
+	}
 	if restore.InputOptions.Gzip {
 		gzrc, err := gzip.NewReader(rc)
 		if err != nil {
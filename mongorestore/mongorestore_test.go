@@ -724,7 +724,8 @@ func TestMongorestoreMIOSOE(t *testing.T) {
 			DBOption, database.Name(),
 			DropOption,
 			StopOnErrorOption,
-			NumParallelCollectionsOption, "1")
+			NumParallelCollectionsOption, "1",
+			BulkBufferSizeOption, "1000")
 		So(err, ShouldBeNil)
 		defer restore.Close()
 		So(restore.OutputOptions.StopOnError, ShouldBeTrue)
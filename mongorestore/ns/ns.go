@@ -19,6 +19,8 @@ type Renamer struct {
 	matchers []*regexp.Regexp
 	// List of regexp-style replacement strings to use with the matcher
 	replacers []string
+	// Whether each replacement has {{source_db}} or {{source_collection}}
+	sourceVariables []bool
 }
 
 // Matcher identifies namespaces given user-defined patterns.
@@ -201,6 +203,7 @@ func NewRenamer(fromSlice, toSlice []string) (r *Renamer, err error) {
 		}
 		r.matchers = append(r.matchers, matcher)
 		r.replacers = append(r.replacers, replacer)
+		r.sourceVariables = append(r.sourceVariables, hasSourceVariables(to))
 	}
 	return
 }
@@ -209,7 +212,11 @@ func NewRenamer(fromSlice, toSlice []string) (r *Renamer, err error) {
 func (r *Renamer) Get(name string) string {
 	for i, matcher := range r.matchers {
 		if matcher.MatchString(name) {
-			return matcher.ReplaceAllString(name, r.replacers[i])
+			renamed := matcher.ReplaceAllString(name, r.replacers[i])
+			if r.sourceVariables[i] {
+				renamed = expandSourceVariables(renamed, name)
+			}
+			return renamed
 		}
 	}
 	return name
@@ -146,3 +146,33 @@ func TestMatcher(t *testing.T) {
 		})
 	})
 }
+
+func TestTemplate(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	vars := map[string]string{"date": "20240101", "dumpId": "nightly"}
+	Convey("with template variables", t, func() {
+		Convey("known variables are expanded", func() {
+			to, err := ExpandTemplate("staging_{{date}}.*", vars)
+			So(err, ShouldBeNil)
+			So(to, ShouldEqual, "staging_20240101.*")
+			to, err = ExpandTemplate("{{dumpId}}_{{source_db}}.{{source_collection}}", vars)
+			So(err, ShouldBeNil)
+			So(to, ShouldEqual, "nightly_{{source_db}}.{{source_collection}}")
+		})
+		Convey("unknown variables are rejected", func() {
+			_, err := ExpandTemplate("staging_{{build}}.*", vars)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("the variables of the source namespace are replaced when renaming", func() {
+			r, err := NewRenamer(
+				[]string{"prod.*", "$db$.logs"},
+				[]string{"{{source_db}}_20240101.*", "archive.$db$_{{source_collection}}"},
+			)
+			So(err, ShouldBeNil)
+			So(r.Get("prod.users"), ShouldEqual, "prod_20240101.users")
+			So(r.Get("app.logs"), ShouldEqual, "archive.app_logs")
+			So(r.Get("other.collection"), ShouldEqual, "other.collection")
+		})
+	})
+}
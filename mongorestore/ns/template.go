@@ -0,0 +1,59 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package ns
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The template variables that are replaced with a part of the namespace being
+// renamed, rather than with a value known before the restore starts.
+const (
+	SourceDBVariable         = "source_db"
+	SourceCollectionVariable = "source_collection"
+)
+
+// Finds {{variables}}.
+var templateVariableRE = regexp.MustCompile(`\{\{(\w+)\}\}`)
+
+// ExpandTemplate replaces the {{variables}} of a rename target with their
+// values in vars. The variables of the namespace being renamed are left for
+// the Renamer to replace. An unknown variable is an error.
+func ExpandTemplate(to string, vars map[string]string) (string, error) {
+	var err error
+	expanded := templateVariableRE.ReplaceAllStringFunc(to, func(match string) string {
+		name := templateVariableRE.FindStringSubmatch(match)[1]
+		if name == SourceDBVariable || name == SourceCollectionVariable {
+			return "{{" + name + "}}"
+		}
+		value, ok := vars[name]
+		if !ok && err == nil {
+			err = fmt.Errorf("Unknown template variable '{{%s}}' in '%s'", name, to)
+		}
+		return value
+	})
+	return expanded, err
+}
+
+// hasSourceVariables returns whether a rename target has variables of the
+// namespace being renamed.
+func hasSourceVariables(to string) bool {
+	return strings.Contains(to, "{{"+SourceDBVariable+"}}") ||
+		strings.Contains(to, "{{"+SourceCollectionVariable+"}}")
+}
+
+// expandSourceVariables replaces the variables of the source namespace in a
+// renamed namespace.
+func expandSourceVariables(renamed, source string) string {
+	db, collection, _ := strings.Cut(source, ".")
+	return strings.NewReplacer(
+		"{{"+SourceDBVariable+"}}", db,
+		"{{"+SourceCollectionVariable+"}}", collection,
+	).Replace(renamed)
+}
@@ -108,11 +108,43 @@ func (restore *MongoRestore) RestoreOplog() error {
 	}
 	defer oplogCtx.txnBuffer.Stop()
 
+	if restore.InputOptions.OplogDryApply != "" {
+		restore.oplogShadow = newOplogShadow(
+			session,
+			restore.InputOptions.OplogShadowPrefix,
+			restore.InputOptions.OplogDryApply,
+		)
+		log.Logvf(
+			log.Always,
+			"replaying oplog into shadow collections prefixed with %q",
+			restore.InputOptions.OplogShadowPrefix,
+		)
+	}
+
 	if restore.ProgressManager != nil {
 		restore.ProgressManager.Attach("oplog", oplogCtx.progressor)
 		defer restore.ProgressManager.Detach("oplog")
 	}
 
+	if err := restore.loadOplogJournal(); err != nil {
+		return err
+	}
+	journal := restore.oplogJournal
+	if journal != nil {
+		if journal.resuming() {
+			journal.logResume()
+		}
+		// an interrupted replay resumes after the last entry it applied
+		defer func() {
+			if err := journal.save(); err != nil {
+				log.Logvf(log.Always, "%v", err)
+			}
+		}()
+	}
+	skipped := 0
+	// last is the timestamp of the last entry of the oplog of the dump
+	var last primitive.Timestamp
+
 	for {
 		rawOplogEntry := decodedBsonSource.LoadNext()
 		if rawOplogEntry == nil {
@@ -126,28 +158,61 @@ func (restore *MongoRestore) RestoreOplog() error {
 		if err != nil {
 			return fmt.Errorf("error reading oplog: %v", err)
 		}
+		last = entryAsOplog.Timestamp
+		if journal != nil && journal.alreadyApplied(entryAsOplog.Timestamp) {
+			skipped++
+			continue
+		}
 
-		err := restore.HandleOp(oplogCtx, entryAsOplog)
+		err := restore.applyOplogEntry(oplogCtx, journal, entryAsOplog)
 		if err == errorTimestampBeforeLimit {
 			break
 		}
 		if err != nil {
 			return err
 		}
-
 	}
 	if fileNeedsIOBuffer, ok := intent.BSONFile.(intents.FileNeedsIOBuffer); ok {
 		fileNeedsIOBuffer.ReleaseIOBuffer()
 	}
 
+	if journal != nil {
+		journal.logMissingResumePoint()
+		if skipped > 0 {
+			log.Logvf(log.Always, "skipped %v oplog entries applied by a previous replay", skipped)
+		}
+	}
 	log.Logvf(log.Always, "applied %v oplog entries", oplogCtx.totalOps)
 	if err := decodedBsonSource.Err(); err != nil {
 		return fmt.Errorf("error reading oplog bson input: %v", err)
 	}
+	if restore.InputOptions.OplogFollow != "" {
+		return restore.followOplog(oplogCtx, journal, last)
+	}
+	if restore.oplogShadow != nil {
+		return restore.finishOplogDryApply()
+	}
 	return nil
 
 }
 
+// applyOplogEntry applies entry, and records it in the journal, if any.
+func (restore *MongoRestore) applyOplogEntry(
+	oplogCtx *oplogContext,
+	journal *oplogJournal,
+	entry db.Oplog,
+) error {
+	if err := restore.HandleOp(oplogCtx, entry); err != nil {
+		return err
+	}
+	// entries of a transaction that isn't committed yet are not applied,
+	// so a replay cannot resume after them
+	if journal != nil && db.OpTimeIsEmpty(oplogCtx.txnBuffer.OldestOpTime()) {
+		return journal.record(entry.Timestamp, entry.Operation == "c")
+	}
+	return nil
+}
+
 func (restore *MongoRestore) HandleOp(oplogCtx *oplogContext, op db.Oplog) error {
 	if shouldIgnoreNamespace(op.Namespace) {
 		return nil
@@ -212,6 +277,19 @@ func (restore *MongoRestore) HandleNonTxnOp(oplogCtx *oplogContext, op db.Oplog)
 		}
 	}
 
+	restore.valueMapper.remapOplog(&op)
+
+	// --oplogDryApply applies the entries to the shadow collections, and
+	// leaves the index catalog of the real ones alone
+	if restore.oplogShadow != nil &&
+		(op.Operation != "c" || len(op.Object) == 0 || op.Object[0].Key != "applyOps") {
+		shadowOp, apply, err := restore.oplogShadow.rewrite(op)
+		if err != nil || !apply {
+			return err
+		}
+		return restore.ApplyOps(oplogCtx.session, []interface{}{shadowOp})
+	}
+
 	if op.Operation == "c" {
 		if len(op.Object) == 0 {
 			return fmt.Errorf("Empty object value for op: %v", op)
@@ -285,6 +363,8 @@ func (restore *MongoRestore) HandleNonTxnOp(oplogCtx *oplogContext, op db.Oplog)
 			restore.indexCatalog.DropCollection(dbName, collName)
 
 		case "applyOps":
+			// the nested ops are handled like the entries of the oplog, so
+			// that their values are remapped once, by HandleNonTxnOp
 			rawOps, ok := op.Object[0].Value.(bson.A)
 			if !ok {
 				return fmt.Errorf("unknown format for applyOps: %#v", op.Object)
@@ -0,0 +1,496 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/bsonutil"
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// What --oplogDryApply compares the shadow collections with.
+const (
+	// the documents that the inserts and replacements of the oplog wrote,
+	// and the absence of the ones it deleted
+	dryApplyPostImages = "postImages"
+	// the documents of the real collections on the target
+	dryApplyCurrent = "current"
+)
+
+// defaultOplogShadowPrefix is the prefix of the shadow collections if
+// --oplogShadowPrefix is not set.
+const defaultOplogShadowPrefix = "oplogShadow."
+
+// OplogShadowReport is how the shadow collection of a namespace diverged
+// from what was expected once --oplogDryApply replayed the oplog into it.
+type OplogShadowReport struct {
+	Namespace string `json:"namespace"`
+	Shadow    string `json:"shadow"`
+	Compared  string `json:"compared"`
+	// Documents is the number of documents the oplog wrote or deleted.
+	Documents int `json:"documents"`
+	// Unverified is the number of documents whose post-image is not in the
+	// oplog, because the last entry that wrote them was an update with
+	// operators.
+	Unverified          int  `json:"unverified"`
+	MissingDocuments    int  `json:"missingDocuments"`
+	UnexpectedDocuments int  `json:"unexpectedDocuments"`
+	DifferentDocuments  int  `json:"differentDocuments"`
+	Diverged            bool `json:"diverged"`
+}
+
+// divergence returns the ways the shadow collection differs from what was
+// expected.
+func (r *OplogShadowReport) divergence() []string {
+	expected := "the oplog"
+	if r.Compared == dryApplyCurrent {
+		expected = "the target"
+	}
+	var divergence []string
+	for _, count := range []struct {
+		n    int
+		what string
+	}{
+		{r.MissingDocuments, "missing from the shadow collection"},
+		{r.UnexpectedDocuments, "in the shadow collection but not in " + expected},
+		{r.DifferentDocuments, "different from " + expected},
+	} {
+		if count.n > 0 {
+			divergence = append(divergence, fmt.Sprintf(
+				"%v %v %v",
+				count.n,
+				util.Pluralize(count.n, "document", "documents"),
+				count.what,
+			))
+		}
+	}
+	return divergence
+}
+
+// shadowDocument is what the oplog did last to a document.
+type shadowDocument struct {
+	id bson.RawValue
+	// known is unset if the last entry was an update with operators, whose
+	// result cannot be told without applying it.
+	known   bool
+	deleted bool
+	hash    [sha256.Size]byte
+}
+
+// oplogShadow replays the oplog for --oplogDryApply into shadow collections,
+// named after the real ones with a prefix, in the same databases. The shadow
+// collection of a namespace starts as a copy of the real one the first time
+// the oplog touches it.
+type oplogShadow struct {
+	session *mongo.Client
+	prefix  string
+	compare string
+
+	// seed creates the shadow collection of a namespace.
+	seed func(dbName, collName string) error
+	// seeded holds the namespaces whose shadow collection was created.
+	seeded map[string]bool
+	// written holds, by namespace and by _id, the documents the oplog wrote.
+	written map[string]map[string]*shadowDocument
+	// skipped counts the commands that were not applied, by name.
+	skipped map[string]int
+}
+
+func newOplogShadow(session *mongo.Client, prefix, compare string) *oplogShadow {
+	shadow := &oplogShadow{
+		session: session,
+		prefix:  prefix,
+		compare: compare,
+		seeded:  map[string]bool{},
+		written: map[string]map[string]*shadowDocument{},
+		skipped: map[string]int{},
+	}
+	shadow.seed = shadow.copyCollection
+	return shadow
+}
+
+// validateOplogDryApply checks --oplogDryApply and --oplogShadowPrefix.
+func (restore *MongoRestore) validateOplogDryApply() error {
+	input := restore.InputOptions
+	if input.OplogDryApply == "" {
+		if input.OplogShadowPrefix != "" {
+			return fmt.Errorf(
+				"cannot use %v without %v", OplogShadowPrefixOption, OplogDryApplyOption)
+		}
+		return nil
+	}
+	if input.OplogDryApply != dryApplyPostImages && input.OplogDryApply != dryApplyCurrent {
+		return fmt.Errorf(
+			"%v must be %v or %v, not %q",
+			OplogDryApplyOption, dryApplyPostImages, dryApplyCurrent, input.OplogDryApply)
+	}
+	if !input.OplogReplay {
+		return fmt.Errorf("cannot use %v without %v enabled", OplogDryApplyOption, OplogReplayOption)
+	}
+	if input.OplogJournal != "" {
+		return fmt.Errorf("cannot use %v with %v", OplogDryApplyOption, OplogJournalOption)
+	}
+	if input.OplogShadowPrefix == "" {
+		input.OplogShadowPrefix = defaultOplogShadowPrefix
+	}
+	if strings.ContainsAny(input.OplogShadowPrefix, "$\x00") ||
+		strings.HasPrefix(input.OplogShadowPrefix, "system.") {
+		return fmt.Errorf("invalid %v %q", OplogShadowPrefixOption, input.OplogShadowPrefix)
+	}
+	return nil
+}
+
+// shadowNamespace returns the namespace of the shadow collection of ns.
+func (s *oplogShadow) shadowNamespace(ns string) string {
+	dbName, collName, _ := strings.Cut(ns, ".")
+	return dbName + "." + s.prefix + collName
+}
+
+// ensureSeeded creates the shadow collection of ns if the oplog has not
+// touched ns yet.
+func (s *oplogShadow) ensureSeeded(ns string) error {
+	if s.seeded[ns] {
+		return nil
+	}
+	dbName, collName, _ := strings.Cut(ns, ".")
+	if err := s.seed(dbName, collName); err != nil {
+		return fmt.Errorf("error creating the shadow collection of %v: %v", ns, err)
+	}
+	s.seeded[ns] = true
+	return nil
+}
+
+// copyCollection replaces the shadow collection of dbName.collName with a
+// copy of the documents of the real collection, if there is one.
+func (s *oplogShadow) copyCollection(dbName, collName string) error {
+	database := s.session.Database(dbName)
+	if err := database.Collection(s.prefix + collName).Drop(context.Background()); err != nil {
+		return err
+	}
+	names, err := database.ListCollectionNames(
+		context.Background(),
+		bson.D{{"name", collName}, {"type", "collection"}},
+	)
+	if err != nil || len(names) == 0 {
+		return err
+	}
+	cursor, err := database.Collection(collName).Aggregate(
+		context.Background(),
+		mongo.Pipeline{{{"$out", s.prefix + collName}}},
+	)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(context.Background())
+}
+
+// rewrite returns op with its namespaces replaced by the ones of the shadow
+// collections, creating them first. It returns false for the entries that
+// must not be applied: the ones on indexes, which do not change documents and
+// would change the index catalog of the real collections, and dropDatabase,
+// which would drop the real collections.
+func (s *oplogShadow) rewrite(op db.Oplog) (db.Oplog, bool, error) {
+	// a UUID would make the server apply the entry to the real collection
+	op.UI = nil
+
+	if op.Operation != "c" {
+		if err := s.ensureSeeded(op.Namespace); err != nil {
+			return op, false, err
+		}
+		s.record(op)
+		op.Namespace = s.shadowNamespace(op.Namespace)
+		return op, true, nil
+	}
+
+	if len(op.Object) == 0 {
+		return op, false, fmt.Errorf("Empty object value for op: %v", op)
+	}
+	cmdName := op.Object[0].Key
+	if !knownCommands[cmdName] {
+		return op, false, fmt.Errorf("unknown oplog command name %v: %v", cmdName, op)
+	}
+	dbName, _, _ := strings.Cut(op.Namespace, ".")
+	cmd := append(bson.D{}, op.Object...)
+
+	switch cmdName {
+	case "create", "drop", "collMod", "convertToCapped", "emptycapped":
+		collName, ok := cmd[0].Value.(string)
+		if !ok {
+			return op, false, fmt.Errorf("could not parse collection name from op: %v", op)
+		}
+		ns := dbName + "." + collName
+		if err := s.ensureSeeded(ns); err != nil {
+			return op, false, err
+		}
+		cmd[0].Value = s.prefix + collName
+		if cmdName == "drop" {
+			delete(s.written, ns)
+		}
+		if cmdName == "collMod" {
+			_, _ = bsonutil.RemoveKey("index", &cmd)
+			_, _ = bsonutil.RemoveKey("noPadding", &cmd)
+			_, _ = bsonutil.RemoveKey("usePowerOf2Sizes", &cmd)
+			if len(cmd) == 1 {
+				s.skipped[cmdName]++
+				return op, false, nil
+			}
+		}
+
+	case "renameCollection":
+		from, ok := cmd[0].Value.(string)
+		if !ok {
+			return op, false, fmt.Errorf("could not parse source namespace from op: %v", op)
+		}
+		to, err := bsonutil.FindValueByKey("to", &cmd)
+		if err != nil {
+			return op, false, fmt.Errorf("could not parse target namespace from op: %v", op)
+		}
+		toNS, ok := to.(string)
+		if !ok {
+			return op, false, fmt.Errorf("could not parse target namespace from op: %v", op)
+		}
+		if err := s.ensureSeeded(from); err != nil {
+			return op, false, err
+		}
+		cmd[0].Value = s.shadowNamespace(from)
+		for i := range cmd {
+			if cmd[i].Key == "to" {
+				cmd[i].Value = s.shadowNamespace(toNS)
+			}
+		}
+		s.written[toNS] = s.written[from]
+		delete(s.written, from)
+		s.seeded[toNS] = true
+
+	default:
+		s.skipped[cmdName]++
+		return op, false, nil
+	}
+
+	op.Object = cmd
+	return op, true, nil
+}
+
+// record remembers what the CRUD entry op did to its document.
+func (s *oplogShadow) record(op db.Oplog) {
+	var id interface{}
+	doc := shadowDocument{}
+	switch op.Operation {
+	case "i":
+		id, _ = bsonutil.FindValueByKey("_id", &op.Object)
+		doc.known = true
+		doc.hash = hashDocument(op.Object)
+	case "u":
+		id, _ = bsonutil.FindValueByKey("_id", &op.Query)
+		// a replacement is stored with its _id first
+		if len(op.Object) > 0 && !strings.HasPrefix(op.Object[0].Key, "$") {
+			replacement := bson.D{{"_id", id}}
+			for _, elem := range op.Object {
+				if elem.Key != "_id" {
+					replacement = append(replacement, elem)
+				}
+			}
+			doc.known = true
+			doc.hash = hashDocument(replacement)
+		}
+	case "d":
+		id, _ = bsonutil.FindValueByKey("_id", &op.Object)
+		doc.known = true
+		doc.deleted = true
+	default:
+		return
+	}
+	if id == nil {
+		return
+	}
+	valueType, value, err := bson.MarshalValue(id)
+	if err != nil {
+		return
+	}
+	doc.id = bson.RawValue{Type: valueType, Value: value}
+	if s.written[op.Namespace] == nil {
+		s.written[op.Namespace] = map[string]*shadowDocument{}
+	}
+	s.written[op.Namespace][rawValueKey(doc.id)] = &doc
+}
+
+func hashDocument(doc bson.D) [sha256.Size]byte {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return [sha256.Size]byte{}
+	}
+	return sha256.Sum256(raw)
+}
+
+// tally counts, in report, the documents of written that diverge. shadow and
+// current are the hashes of the documents found, by _id, in the shadow
+// collection and, if the shadow collection is compared with the target, in
+// the real collection.
+func (s *oplogShadow) tally(
+	report *OplogShadowReport,
+	written map[string]*shadowDocument,
+	shadow, current map[string][sha256.Size]byte,
+) {
+	report.Documents = len(written)
+	for key, doc := range written {
+		shadowHash, inShadow := shadow[key]
+		expectedHash, expected := doc.hash, !doc.deleted
+		if s.compare == dryApplyCurrent {
+			expectedHash, expected = current[key]
+		} else if !doc.known {
+			report.Unverified++
+			continue
+		}
+		switch {
+		case expected && !inShadow:
+			report.MissingDocuments++
+		case !expected && inShadow:
+			report.UnexpectedDocuments++
+		case expected && shadowHash != expectedHash:
+			report.DifferentDocuments++
+		}
+	}
+	report.Diverged = len(report.divergence()) > 0
+}
+
+// compareNamespace compares the documents the oplog wrote in the shadow
+// collection of ns with what was expected.
+func (s *oplogShadow) compareNamespace(ns string) (*OplogShadowReport, error) {
+	report := &OplogShadowReport{
+		Namespace: ns,
+		Shadow:    s.shadowNamespace(ns),
+		Compared:  s.compare,
+	}
+	written := s.written[ns]
+	ids := make([]bson.RawValue, 0, len(written))
+	for _, doc := range written {
+		ids = append(ids, doc.id)
+	}
+	dbName, collName, _ := strings.Cut(ns, ".")
+	database := s.session.Database(dbName)
+
+	shadow, err := fetchHashes(database.Collection(s.prefix+collName), ids)
+	if err != nil {
+		return nil, err
+	}
+	var current map[string][sha256.Size]byte
+	if s.compare == dryApplyCurrent {
+		current, err = fetchHashes(database.Collection(collName), ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.tally(report, written, shadow, current)
+	return report, nil
+}
+
+// fetchHashes returns the hashes of the documents of coll with the given
+// _ids, by _id.
+func fetchHashes(
+	coll *mongo.Collection,
+	ids []bson.RawValue,
+) (map[string][sha256.Size]byte, error) {
+	hashes := map[string][sha256.Size]byte{}
+	for start := 0; start < len(ids); start += compareFetchBatchSize {
+		batch := ids[start:min(start+compareFetchBatchSize, len(ids))]
+		in := make(bson.A, len(batch))
+		for i, id := range batch {
+			in[i] = id
+		}
+		cursor, err := coll.Find(
+			context.Background(),
+			bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: in}}}},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %v: %v", coll.Name(), err)
+		}
+		for cursor.Next(context.Background()) {
+			hashes[rawValueKey(cursor.Current.Lookup("_id"))] = sha256.Sum256(cursor.Current)
+		}
+		err = cursor.Err()
+		_ = cursor.Close(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("error reading %v: %v", coll.Name(), err)
+		}
+	}
+	return hashes, nil
+}
+
+// finishOplogDryApply compares the shadow collections once the oplog was
+// replayed into them and reports where they diverge. The shadow collections
+// are dropped if none diverged, and kept to be inspected otherwise.
+func (restore *MongoRestore) finishOplogDryApply() error {
+	shadow := restore.oplogShadow
+	for name, count := range shadow.skipped {
+		log.Logvf(
+			log.Always,
+			"--oplogDryApply did not apply %v %v %v",
+			count,
+			name,
+			util.Pluralize(count, "entry", "entries"),
+		)
+	}
+
+	namespaces := make([]string, 0, len(shadow.written))
+	for ns := range shadow.written {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	diverged := 0
+	for _, ns := range namespaces {
+		report, err := shadow.compareNamespace(ns)
+		if err != nil {
+			return fmt.Errorf("error comparing the shadow collection of %v: %v", ns, err)
+		}
+		restore.stats.recordOplogShadow(*report)
+		if !report.Diverged {
+			log.Logvf(log.Info, "%v matches the oplog replay", report.Shadow)
+			continue
+		}
+		diverged++
+		log.Logvf(log.Always, "%v diverges from %v:", report.Shadow, report.Namespace)
+		for _, line := range report.divergence() {
+			log.Logvf(log.Always, "\t%v", line)
+		}
+	}
+	log.Logvf(
+		log.Always,
+		"replayed the oplog into %v shadow %v: %v %v",
+		len(namespaces),
+		util.Pluralize(len(namespaces), "collection", "collections"),
+		diverged,
+		util.Pluralize(diverged, "diverges", "diverge"),
+	)
+
+	if diverged > 0 {
+		log.Logvf(log.Always, "kept the shadow collections with the prefix %q", shadow.prefix)
+		return fmt.Errorf(
+			"the oplog replay diverged in %v %v",
+			diverged,
+			util.Pluralize(diverged, "namespace", "namespaces"),
+		)
+	}
+	for ns := range shadow.seeded {
+		dbName, collName, _ := strings.Cut(ns, ".")
+		err := shadow.session.Database(dbName).
+			Collection(shadow.prefix + collName).
+			Drop(context.Background())
+		if err != nil {
+			return fmt.Errorf("error dropping the shadow collection of %v: %v", ns, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidateOplogDryApply(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	restore := &MongoRestore{InputOptions: &InputOptions{
+		OplogReplay:   true,
+		OplogDryApply: dryApplyPostImages,
+	}}
+	require.NoError(t, restore.validateOplogDryApply())
+	assert.Equal(t, defaultOplogShadowPrefix, restore.InputOptions.OplogShadowPrefix)
+
+	for _, input := range []InputOptions{
+		{OplogDryApply: dryApplyCurrent},
+		{OplogReplay: true, OplogDryApply: "everything"},
+		{OplogReplay: true, OplogDryApply: dryApplyCurrent, OplogJournal: "journal"},
+		{OplogReplay: true, OplogDryApply: dryApplyCurrent, OplogShadowPrefix: "system."},
+		{OplogReplay: true, OplogShadowPrefix: "shadow_"},
+	} {
+		restore := &MongoRestore{InputOptions: &input}
+		assert.Error(t, restore.validateOplogDryApply(), "%+v", input)
+	}
+}
+
+func newTestOplogShadow(compare string) (*oplogShadow, *[]string) {
+	shadow := newOplogShadow(nil, "shadow_", compare)
+	var seeded []string
+	shadow.seed = func(dbName, collName string) error {
+		seeded = append(seeded, dbName+"."+collName)
+		return nil
+	}
+	return shadow, &seeded
+}
+
+func TestOplogShadowRewrite(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	shadow, seeded := newTestOplogShadow(dryApplyPostImages)
+	uuid := &primitive.Binary{Subtype: 4, Data: make([]byte, 16)}
+
+	op, apply, err := shadow.rewrite(db.Oplog{
+		Operation: "i",
+		Namespace: "shop.orders",
+		Object:    bson.D{{"_id", int32(1)}, {"total", 10}},
+		UI:        uuid,
+	})
+	require.NoError(t, err)
+	assert.True(t, apply)
+	assert.Equal(t, "shop.shadow_orders", op.Namespace)
+	assert.Nil(t, op.UI)
+
+	_, _, err = shadow.rewrite(db.Oplog{
+		Operation: "d",
+		Namespace: "shop.orders",
+		Object:    bson.D{{"_id", int32(2)}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shop.orders"}, *seeded)
+
+	original := bson.D{{"renameCollection", "shop.orders"}, {"to", "shop.archive"}}
+	op, apply, err = shadow.rewrite(db.Oplog{
+		Operation: "c",
+		Namespace: "shop.$cmd",
+		Object:    original,
+	})
+	require.NoError(t, err)
+	assert.True(t, apply)
+	assert.Equal(
+		t,
+		bson.D{{"renameCollection", "shop.shadow_orders"}, {"to", "shop.shadow_archive"}},
+		op.Object,
+	)
+	assert.Equal(t, "shop.orders", original[0].Value, "the entry should not be modified")
+	assert.Len(t, shadow.written["shop.archive"], 2)
+	assert.NotContains(t, shadow.written, "shop.orders")
+
+	for _, cmd := range []bson.D{
+		{{"createIndexes", "archive"}, {"v", 2}, {"key", bson.D{{"a", 1}}}, {"name", "a_1"}},
+		{{"dropDatabase", 1}},
+		{{"collMod", "archive"}, {"index", bson.D{{"name", "a_1"}, {"hidden", true}}}},
+	} {
+		_, apply, err = shadow.rewrite(db.Oplog{Operation: "c", Namespace: "shop.$cmd", Object: cmd})
+		require.NoError(t, err)
+		assert.False(t, apply, "%v", cmd)
+	}
+	assert.Equal(
+		t,
+		map[string]int{"createIndexes": 1, "dropDatabase": 1, "collMod": 1},
+		shadow.skipped,
+	)
+
+	op, apply, err = shadow.rewrite(db.Oplog{
+		Operation: "c",
+		Namespace: "logs.$cmd",
+		Object:    bson.D{{"drop", "events"}},
+	})
+	require.NoError(t, err)
+	assert.True(t, apply)
+	assert.Equal(t, bson.D{{"drop", "shadow_events"}}, op.Object)
+	assert.Equal(t, []string{"shop.orders", "logs.events"}, *seeded)
+
+	_, _, err = shadow.rewrite(db.Oplog{
+		Operation: "c",
+		Namespace: "shop.$cmd",
+		Object:    bson.D{{"frobnicate", "orders"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestOplogShadowTally(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	ops := []db.Oplog{
+		{Operation: "i", Object: bson.D{{"_id", int32(1)}, {"n", 1}}},
+		{Operation: "i", Object: bson.D{{"_id", int32(2)}, {"n", 1}}},
+		{
+			Operation: "u",
+			Query:     bson.D{{"_id", int32(2)}},
+			Object:    bson.D{{"n", 2}},
+		},
+		{Operation: "i", Object: bson.D{{"_id", int32(3)}, {"n", 1}}},
+		{
+			Operation: "u",
+			Query:     bson.D{{"_id", int32(3)}},
+			Object:    bson.D{{"$v", 2}, {"diff", bson.D{{"u", bson.D{{"n", 2}}}}}},
+		},
+		{Operation: "d", Object: bson.D{{"_id", int32(4)}}},
+		{Operation: "i", Object: bson.D{{"_id", int32(5)}, {"n", 1}}},
+	}
+	for _, compare := range []string{dryApplyPostImages, dryApplyCurrent} {
+		shadow, _ := newTestOplogShadow(compare)
+		for _, op := range ops {
+			op.Namespace = "shop.orders"
+			_, _, err := shadow.rewrite(op)
+			require.NoError(t, err)
+		}
+		written := shadow.written["shop.orders"]
+		require.Len(t, written, 5)
+
+		hashes := func(docs ...bson.D) map[string][sha256.Size]byte {
+			found := map[string][sha256.Size]byte{}
+			for _, doc := range docs {
+				raw, err := bson.Marshal(doc)
+				require.NoError(t, err)
+				found[rawValueKey(bson.Raw(raw).Lookup("_id"))] = sha256.Sum256(raw)
+			}
+			return found
+		}
+		inShadow := hashes(
+			bson.D{{"_id", int32(1)}, {"n", 1}},
+			bson.D{{"_id", int32(2)}, {"n", 2}},
+			bson.D{{"_id", int32(3)}, {"n", 2}},
+			bson.D{{"_id", int32(4)}, {"n", 1}},
+		)
+		current := hashes(
+			bson.D{{"_id", int32(1)}, {"n", 1}},
+			bson.D{{"_id", int32(2)}, {"n", 2}},
+			bson.D{{"_id", int32(3)}, {"n", 3}},
+			bson.D{{"_id", int32(5)}, {"n", 1}},
+		)
+
+		report := &OplogShadowReport{Compared: compare}
+		shadow.tally(report, written, inShadow, current)
+		assert.Equal(t, 5, report.Documents, compare)
+		assert.True(t, report.Diverged, compare)
+		assert.Equal(t, 1, report.MissingDocuments, compare)
+		assert.Equal(t, 1, report.UnexpectedDocuments, compare)
+		if compare == dryApplyPostImages {
+			assert.Equal(t, 1, report.Unverified)
+			assert.Equal(t, 0, report.DifferentDocuments)
+		} else {
+			assert.Equal(t, 0, report.Unverified)
+			assert.Equal(t, 1, report.DifferentDocuments)
+		}
+	}
+}
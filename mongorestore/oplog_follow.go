@@ -0,0 +1,701 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/idx"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/password"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// followPollInterval is how long --oplogFollow waits for new entries at the
+// end of its source before checking whether to cut over.
+const followPollInterval = time.Second
+
+// maxOplogEntrySize is the largest oplog entry, a document of the maximum
+// size plus the fields of the oplog.
+const maxOplogEntrySize = db.MaxBSONSize + 16*1024
+
+// FollowState is the state of --oplogFollow.
+type FollowState string
+
+// States of --oplogFollow. It follows its source until a cutover is
+// requested, then cuts over until it has applied the entries the source had
+// at that time, and ends either done or failed.
+const (
+	FollowFollowing   FollowState = "following"
+	FollowCuttingOver FollowState = "cuttingOver"
+	FollowDone        FollowState = "done"
+	FollowFailed      FollowState = "failed"
+)
+
+// FollowStatus is what the API of --cutoverAddress returns about
+// --oplogFollow.
+type FollowStatus struct {
+	State FollowState `json:"state"`
+	// Applied is the number of entries of the source read so far.
+	Applied int64 `json:"applied"`
+	// LastApplied is the timestamp of the last entry applied, in the
+	// <seconds>:<ordinal> format of --oplogLimit, and LastAppliedTime is
+	// when it was written to the source.
+	LastApplied     string     `json:"lastApplied,omitempty"`
+	LastAppliedTime *time.Time `json:"lastAppliedTime,omitempty"`
+	// LagSeconds is how far the last entry applied is behind the latest
+	// entry of a source replica set.
+	LagSeconds *int64 `json:"lagSeconds,omitempty"`
+	// PendingBytes is how many bytes of a source file are not applied yet.
+	PendingBytes *int64 `json:"pendingBytes,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// oplogSource is where --oplogFollow reads the entries to apply.
+type oplogSource interface {
+	// next returns the next entry of the source, or nil if there is none
+	// yet. It waits for one up to followPollInterval, or until wake is
+	// closed.
+	next(wake <-chan struct{}) (bson.Raw, error)
+	// mark records the end of the source at the time of a cutover.
+	mark() error
+	// drained returns whether the entries up to the mark are applied, after
+	// the entry at last.
+	drained(last primitive.Timestamp) bool
+	// lag sets how far the entry at last is behind the end of the source in
+	// status. It may be called concurrently with the other methods.
+	lag(last primitive.Timestamp, status *FollowStatus)
+	close()
+}
+
+// validateOplogFollow checks the options of --oplogFollow.
+func (restore *MongoRestore) validateOplogFollow() error {
+	input := restore.InputOptions
+	if input.OplogFollow == "" {
+		if input.CutoverAddress != "" {
+			return fmt.Errorf("cannot use %v without %v", CutoverAddressOption, OplogFollowOption)
+		}
+		if input.CutoverTokenFile != "" {
+			return fmt.Errorf(
+				"cannot use %v without %v or %v",
+				CutoverTokenFileOption,
+				OplogFollowOption,
+				CutoverOption,
+			)
+		}
+		return nil
+	}
+	switch {
+	case !input.OplogReplay:
+		return fmt.Errorf("cannot use %v without %v enabled", OplogFollowOption, OplogReplayOption)
+	case input.CutoverAddress == "":
+		return fmt.Errorf("%v requires %v", OplogFollowOption, CutoverAddressOption)
+	case input.OplogLimit != "":
+		return fmt.Errorf("cannot use %v with %v", OplogFollowOption, OplogLimitOption)
+	case input.OplogDryApply != "":
+		return fmt.Errorf("cannot use %v with %v", OplogFollowOption, OplogDryApplyOption)
+	}
+	return nil
+}
+
+// isConnectionString returns whether the source of --oplogFollow is a
+// replica set rather than a file.
+func isConnectionString(source string) bool {
+	return strings.HasPrefix(source, "mongodb://") || strings.HasPrefix(source, "mongodb+srv://")
+}
+
+// followOplog applies the entries of --oplogFollow after last, the timestamp
+// of the last entry of the oplog of the dump, until a cutover is requested on
+// --cutoverAddress and the entries that the source had by then are applied.
+// The indexes of the dump are built first, so that they are in place at the
+// cutover; those that the entries followed create are built after it, and
+// the ones already built are not built again.
+func (restore *MongoRestore) followOplog(
+	oplogCtx *oplogContext,
+	journal *oplogJournal,
+	last primitive.Timestamp,
+) error {
+	if journal != nil && journal.applied.After(last) {
+		last = journal.applied
+	}
+	token, err := ReadCutoverToken(restore.InputOptions.CutoverTokenFile)
+	if err != nil {
+		return err
+	}
+	if !restore.OutputOptions.NoIndexRestore {
+		restore.builtIndexes = &builtIndexes{indexes: map[*idx.IndexDocument]bool{}}
+		if err := restore.RestoreIndexes(); err != nil {
+			return err
+		}
+	}
+	source, err := restore.openOplogSource(last)
+	if err != nil {
+		return err
+	}
+	defer source.close()
+
+	follower := newOplogFollower(source, last)
+	follower.token = token
+	listener, err := listenHTTP(restore.InputOptions.CutoverAddress, token != "")
+	if err != nil {
+		return fmt.Errorf(
+			"error with %v: %v; %v lets it listen on any address",
+			CutoverAddressOption,
+			err,
+			CutoverTokenFileOption,
+		)
+	}
+	server := &http.Server{Handler: follower.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	// waits for the response of the cutover request
+	defer func() {
+		_ = server.Shutdown(context.Background())
+	}()
+	log.Logvf(
+		log.Always,
+		"following the oplog of %v; POST http://%v/cutover to cut over",
+		followSourceName(restore.InputOptions.OplogFollow),
+		listener.Addr(),
+	)
+
+	err = follower.run(restore.terminate.Load, func(entry db.Oplog) error {
+		return restore.applyOplogEntry(oplogCtx, journal, entry)
+	})
+	status := follower.status()
+	if err != nil {
+		return fmt.Errorf("error following the oplog: %v", err)
+	}
+	log.Logvf(
+		log.Always,
+		"cut over after applying %v %v from the source, up to %v",
+		status.Applied,
+		util.Pluralize(int(status.Applied), "entry", "entries"),
+		status.LastApplied,
+	)
+	return nil
+}
+
+// ReadCutoverToken returns the token of the --cutoverTokenFile at path, or an
+// empty token if path is empty.
+func ReadCutoverToken(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	token, err := password.ReadSecretFile(util.ToUniversalPath(path))
+	if err != nil {
+		return "", fmt.Errorf("error reading %v: %v", CutoverTokenFileOption, err)
+	}
+	if token == "" {
+		return "", fmt.Errorf("%v %v is empty", CutoverTokenFileOption, path)
+	}
+	return token, nil
+}
+
+// builtIndexes are the indexes of the catalog that were built before
+// --oplogFollow follows its source. The entries it applies add the indexes
+// they create to the catalog, and replace the ones of the collections they
+// drop and create again.
+type builtIndexes struct {
+	mu      sync.Mutex
+	indexes map[*idx.IndexDocument]bool
+}
+
+// unbuilt returns the indexes that are not built yet. It is safe to call on
+// a nil *builtIndexes, which returns all of them.
+func (b *builtIndexes) unbuilt(indexes []*idx.IndexDocument) []*idx.IndexDocument {
+	if b == nil {
+		return indexes
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var unbuilt []*idx.IndexDocument
+	for _, index := range indexes {
+		if !b.indexes[index] {
+			unbuilt = append(unbuilt, index)
+		}
+	}
+	return unbuilt
+}
+
+// add records that indexes are built. It does nothing on a nil
+// *builtIndexes.
+func (b *builtIndexes) add(indexes []*idx.IndexDocument) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, index := range indexes {
+		b.indexes[index] = true
+	}
+}
+
+// followSourceName describes the source of --oplogFollow without the
+// credentials of a connection string.
+func followSourceName(source string) string {
+	if !isConnectionString(source) {
+		return source
+	}
+	uri, err := options.NewURI(source)
+	if err != nil {
+		return "the source"
+	}
+	return strings.Join(uri.GetConnectionAddrs(), ",")
+}
+
+// openOplogSource opens the source of --oplogFollow, whose entries after last
+// are applied.
+func (restore *MongoRestore) openOplogSource(last primitive.Timestamp) (oplogSource, error) {
+	source := restore.InputOptions.OplogFollow
+	if !isConnectionString(source) {
+		file, err := os.Open(util.ToUniversalPath(source))
+		if err != nil {
+			return nil, fmt.Errorf("error opening %v: %v", OplogFollowOption, err)
+		}
+		return &fileOplogSource{file: file}, nil
+	}
+
+	if last.IsZero() {
+		return nil, fmt.Errorf(
+			"the oplog of the dump has no entries to follow the oplog of %v from",
+			followSourceName(source),
+		)
+	}
+	opts := options.New(
+		restore.ToolOptions.AppName,
+		restore.ToolOptions.VersionStr,
+		restore.ToolOptions.GitCommit,
+		"",
+		true,
+		options.EnabledOptions{Auth: true, Connection: true, URI: true},
+	)
+	if _, err := opts.ParseArgs([]string{"--uri=" + source}); err != nil {
+		return nil, fmt.Errorf("error parsing the connection string of %v: %v", OplogFollowOption, err)
+	}
+	provider, err := db.NewSessionProvider(*opts)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %v: %v", followSourceName(source), err)
+	}
+	client, err := provider.GetSession()
+	if err != nil {
+		provider.Close()
+		return nil, fmt.Errorf("error connecting to %v: %v", followSourceName(source), err)
+	}
+	s := &directOplogSource{
+		provider:    provider,
+		client:      client,
+		oplog:       client.Database("local").Collection("oplog.rs"),
+		resumeAfter: last,
+	}
+	if err := s.checkStart(); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// oplogFollower applies the entries of an oplogSource until a cutover, and
+// serves its status on the API of --cutoverAddress:
+//
+//	GET  /status   returns the FollowStatus
+//	POST /cutover  cuts over, and returns the FollowStatus once it is done
+//
+// If the follower has a token, every request must give it as a bearer token.
+// POST /cutover requires the application/json content type, which a web page
+// can't make a browser send to another site without asking it first.
+type oplogFollower struct {
+	source oplogSource
+	token  string
+
+	cutover     chan struct{}
+	cutoverOnce sync.Once
+	done        chan struct{}
+
+	mu      sync.Mutex
+	state   FollowState
+	applied int64
+	last    primitive.Timestamp
+	err     error
+}
+
+func newOplogFollower(source oplogSource, last primitive.Timestamp) *oplogFollower {
+	return &oplogFollower{
+		source:  source,
+		cutover: make(chan struct{}),
+		done:    make(chan struct{}),
+		state:   FollowFollowing,
+		last:    last,
+	}
+}
+
+// run applies the entries of the source with apply until the cutover is
+// done, or terminated returns true.
+func (f *oplogFollower) run(terminated func() bool, apply func(db.Oplog) error) error {
+	err := f.follow(terminated, apply)
+	f.mu.Lock()
+	f.err = err
+	if err != nil {
+		f.state = FollowFailed
+	} else {
+		f.state = FollowDone
+	}
+	f.mu.Unlock()
+	close(f.done)
+	return err
+}
+
+func (f *oplogFollower) follow(terminated func() bool, apply func(db.Oplog) error) error {
+	marked := false
+	for {
+		if terminated() {
+			return util.ErrTerminated
+		}
+		if !marked && f.cuttingOver() {
+			if err := f.source.mark(); err != nil {
+				return fmt.Errorf("error finding the end of the source to cut over at: %v", err)
+			}
+			marked = true
+			log.Logv(log.Always, "cutting over: applying the remaining oplog entries of the source")
+		}
+		last := f.lastApplied()
+		if marked && f.source.drained(last) {
+			return nil
+		}
+
+		raw, err := f.source.next(f.cutover)
+		if err != nil {
+			return err
+		}
+		if raw == nil {
+			continue
+		}
+		var entry db.Oplog
+		if err := bson.Unmarshal(raw, &entry); err != nil {
+			return fmt.Errorf("error reading oplog: %v", err)
+		}
+		// the source may start with entries the dump already has
+		if !entry.Timestamp.After(last) {
+			continue
+		}
+		if err := apply(entry); err != nil {
+			return err
+		}
+		f.mu.Lock()
+		f.applied++
+		f.last = entry.Timestamp
+		f.mu.Unlock()
+	}
+}
+
+func (f *oplogFollower) cuttingOver() bool {
+	select {
+	case <-f.cutover:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *oplogFollower) lastApplied() primitive.Timestamp {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.last
+}
+
+// requestCutover makes the follower cut over, if it hasn't already.
+func (f *oplogFollower) requestCutover() {
+	f.cutoverOnce.Do(func() {
+		f.mu.Lock()
+		if f.state == FollowFollowing {
+			f.state = FollowCuttingOver
+		}
+		f.mu.Unlock()
+		close(f.cutover)
+	})
+}
+
+func (f *oplogFollower) status() FollowStatus {
+	f.mu.Lock()
+	status := FollowStatus{State: f.state, Applied: f.applied}
+	last, err := f.last, f.err
+	f.mu.Unlock()
+
+	if !last.IsZero() {
+		status.LastApplied = fmt.Sprintf("%v:%v", last.T, last.I)
+		written := time.Unix(int64(last.T), 0).UTC()
+		status.LastAppliedTime = &written
+	}
+	if err != nil {
+		status.Error = err.Error()
+	} else if status.State != FollowDone {
+		f.source.lag(last, &status)
+	}
+	return status
+}
+
+// Handler returns the HTTP handler of the API of --cutoverAddress.
+func (f *oplogFollower) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, f.status())
+	})
+	mux.HandleFunc("POST /cutover", func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/json" {
+			writeJSONError(
+				w,
+				http.StatusUnsupportedMediaType,
+				fmt.Errorf("the content type of a cutover request must be application/json"),
+			)
+			return
+		}
+		f.requestCutover()
+		select {
+		case <-f.done:
+		case <-r.Context().Done():
+			return
+		}
+		status := f.status()
+		code := http.StatusOK
+		if status.State == FollowFailed {
+			code = http.StatusInternalServerError
+		}
+		writeJSON(w, code, status)
+	})
+	return bearerAuth(f.token, mux)
+}
+
+// RequestCutover makes the mongorestore following an oplog with its API on
+// address cut over, and returns its status once it is done. The token of its
+// --cutoverTokenFile is given if it is set.
+func RequestCutover(address, token string) (FollowStatus, error) {
+	req, err := http.NewRequest(http.MethodPost, "http://"+address+"/cutover", nil)
+	if err != nil {
+		return FollowStatus{}, fmt.Errorf("error requesting the cutover: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return FollowStatus{}, fmt.Errorf("error requesting the cutover: %v", err)
+	}
+	defer resp.Body.Close()
+	var status FollowStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return FollowStatus{}, fmt.Errorf(
+			"error reading the response to the cutover (%v): %v",
+			resp.Status,
+			err,
+		)
+	}
+	if status.State != FollowDone {
+		return status, fmt.Errorf("the cutover failed: %v", status.Error)
+	}
+	return status, nil
+}
+
+// fileOplogSource reads the entries of a BSON file that another process keeps
+// appending oplog entries to.
+type fileOplogSource struct {
+	file *os.File
+	// offset is the offset of the next entry of the file
+	offset atomic.Int64
+	// end is the size of the file at the cutover
+	end int64
+}
+
+func (s *fileOplogSource) next(wake <-chan struct{}) (bson.Raw, error) {
+	offset := s.offset.Load()
+	var header [4]byte
+	if n, err := s.file.ReadAt(header[:], offset); n < len(header) {
+		return nil, s.wait(err, wake)
+	}
+	size := int64(binary.LittleEndian.Uint32(header[:]))
+	if size < 5 || size > maxOplogEntrySize {
+		return nil, fmt.Errorf(
+			"invalid oplog entry of %v bytes at offset %v of %v",
+			size,
+			offset,
+			s.file.Name(),
+		)
+	}
+	entry := make(bson.Raw, size)
+	// the writer of the file may not have written the whole entry yet
+	if n, err := s.file.ReadAt(entry, offset); int64(n) < size {
+		return nil, s.wait(err, wake)
+	}
+	s.offset.Store(offset + size)
+	return entry, nil
+}
+
+// wait waits for more of the file to be written, after a read that stopped
+// at its end with err.
+func (s *fileOplogSource) wait(err error, wake <-chan struct{}) error {
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("error reading %v: %v", s.file.Name(), err)
+	}
+	select {
+	case <-time.After(followPollInterval):
+	case <-wake:
+	}
+	return nil
+}
+
+func (s *fileOplogSource) mark() error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	s.end = info.Size()
+	return nil
+}
+
+func (s *fileOplogSource) drained(primitive.Timestamp) bool {
+	return s.offset.Load() >= s.end
+}
+
+func (s *fileOplogSource) lag(_ primitive.Timestamp, status *FollowStatus) {
+	info, err := s.file.Stat()
+	if err != nil {
+		return
+	}
+	pending := info.Size() - s.offset.Load()
+	status.PendingBytes = &pending
+}
+
+func (s *fileOplogSource) close() {
+	_ = s.file.Close()
+}
+
+// directOplogSource tails the oplog of a source replica set.
+type directOplogSource struct {
+	provider *db.SessionProvider
+	client   *mongo.Client
+	oplog    *mongo.Collection
+	cursor   *mongo.Cursor
+	// resumeAfter is the timestamp of the last entry read
+	resumeAfter primitive.Timestamp
+	// end is the timestamp of the latest entry of the source at the cutover
+	end primitive.Timestamp
+}
+
+// checkStart checks that the oplog of the source still has the entries after
+// the ones to resume after.
+func (s *directOplogSource) checkStart() error {
+	first, err := s.oplog.FindOne(
+		context.Background(),
+		bson.D{},
+		mopt.FindOne().SetSort(bson.D{{"$natural", 1}}).SetProjection(bson.D{{"ts", 1}}),
+	).Raw()
+	if err != nil {
+		return fmt.Errorf("error reading the oplog of the source: %v", err)
+	}
+	t, i, ok := first.Lookup("ts").TimestampOK()
+	if !ok {
+		return fmt.Errorf("the first entry of the oplog of the source has no timestamp")
+	}
+	if start := (primitive.Timestamp{T: t, I: i}); start.After(s.resumeAfter) {
+		return fmt.Errorf(
+			"the oplog of the source starts at %v:%v, after the last entry of the dump at %v:%v, "+
+				"so that the entries in between are lost; take a new dump",
+			start.T,
+			start.I,
+			s.resumeAfter.T,
+			s.resumeAfter.I,
+		)
+	}
+	return nil
+}
+
+func (s *directOplogSource) next(wake <-chan struct{}) (bson.Raw, error) {
+	ctx := context.Background()
+	if s.cursor == nil {
+		cursor, err := s.oplog.Find(
+			ctx,
+			bson.D{{"ts", bson.D{{"$gt", s.resumeAfter}}}},
+			mopt.Find().SetCursorType(mopt.TailableAwait).SetMaxAwaitTime(followPollInterval),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error tailing the oplog of the source: %v", err)
+		}
+		s.cursor = cursor
+	}
+	if s.cursor.TryNext(ctx) {
+		entry := make(bson.Raw, len(s.cursor.Current))
+		copy(entry, s.cursor.Current)
+		if t, i, ok := entry.Lookup("ts").TimestampOK(); ok {
+			s.resumeAfter = primitive.Timestamp{T: t, I: i}
+		}
+		return entry, nil
+	}
+	if err := s.cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error tailing the oplog of the source: %v", err)
+	}
+	if s.cursor.ID() == 0 {
+		// a tailable cursor dies when it finds nothing to start from
+		_ = s.cursor.Close(ctx)
+		s.cursor = nil
+		select {
+		case <-time.After(followPollInterval):
+		case <-wake:
+		}
+	}
+	return nil, nil
+}
+
+func (s *directOplogSource) mark() error {
+	latest, err := db.GetLatestVisibleOplogOpTime(s.client)
+	if err != nil {
+		return err
+	}
+	s.end = latest.Timestamp
+	return nil
+}
+
+func (s *directOplogSource) drained(last primitive.Timestamp) bool {
+	return !s.end.After(last)
+}
+
+func (s *directOplogSource) lag(last primitive.Timestamp, status *FollowStatus) {
+	latest, err := db.GetLatestOplogOpTime(s.client, bson.D{})
+	if err != nil {
+		return
+	}
+	lag := int64(latest.Timestamp.T) - int64(last.T)
+	if lag < 0 {
+		lag = 0
+	}
+	status.LagSeconds = &lag
+}
+
+func (s *directOplogSource) close() {
+	if s.cursor != nil {
+		_ = s.cursor.Close(context.Background())
+	}
+	s.provider.Close()
+}
@@ -0,0 +1,186 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The journal is saved after this many entries or this much time since it
+// was last saved, and after every command.
+const (
+	oplogJournalSaveEntries  = 1000
+	oplogJournalSaveInterval = time.Second
+)
+
+// oplogJournalState is the content of an --oplogJournal file.
+type oplogJournalState struct {
+	// Applied is the timestamp of the last top-level oplog entry that was
+	// applied with no transaction left partially applied.
+	Applied primitive.Timestamp `bson:"applied"`
+}
+
+// oplogJournal records how far an oplog replay got, so that a replay that is
+// interrupted can resume after the last applied entry instead of applying
+// the whole oplog again.
+type oplogJournal struct {
+	path string
+
+	// resumeAfter is the timestamp the journal held when the replay started;
+	// entries up to it were applied by a previous replay.
+	resumeAfter primitive.Timestamp
+	// sawResumePoint is whether the oplog has the entry at resumeAfter.
+	sawResumePoint bool
+
+	applied  primitive.Timestamp
+	unsaved  int
+	lastSave time.Time
+}
+
+// openOplogJournal reads the journal at path. A missing file is a journal of
+// a replay that hasn't started.
+func openOplogJournal(path string) (*oplogJournal, error) {
+	journal := &oplogJournal{path: path, lastSave: time.Now()}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return journal, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %v: %v", OplogJournalOption, err)
+	}
+	state := oplogJournalState{}
+	err = bson.UnmarshalExtJSON(content, true, &state)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %v %#q: %v", OplogJournalOption, path, err)
+	}
+	journal.resumeAfter = state.Applied
+	journal.applied = state.Applied
+	return journal, nil
+}
+
+// loadOplogJournal opens the --oplogJournal, if any and not opened yet.
+func (restore *MongoRestore) loadOplogJournal() error {
+	if restore.InputOptions.OplogJournal == "" || restore.oplogJournal != nil {
+		return nil
+	}
+	journal, err := openOplogJournal(restore.InputOptions.OplogJournal)
+	if err != nil {
+		return err
+	}
+	restore.oplogJournal = journal
+	return nil
+}
+
+// resuming returns whether a previous replay applied part of the oplog, in
+// which case the collections were restored before it and are not restored
+// again. A nil journal never resumes.
+func (journal *oplogJournal) resuming() bool {
+	return journal != nil && !journal.resumeAfter.IsZero()
+}
+
+// alreadyApplied returns whether a previous replay applied the entry at ts.
+func (journal *oplogJournal) alreadyApplied(ts primitive.Timestamp) bool {
+	if !journal.resuming() {
+		return false
+	}
+	if ts.Equal(journal.resumeAfter) {
+		journal.sawResumePoint = true
+	}
+	return !ts.After(journal.resumeAfter)
+}
+
+// record notes that the entry at ts was applied, and saves the journal if
+// the entry is a command, which may not be idempotent, or if enough entries
+// or time went by since it was last saved.
+func (journal *oplogJournal) record(ts primitive.Timestamp, isCommand bool) error {
+	journal.applied = ts
+	journal.unsaved++
+	if isCommand ||
+		journal.unsaved >= oplogJournalSaveEntries ||
+		time.Since(journal.lastSave) >= oplogJournalSaveInterval {
+		return journal.save()
+	}
+	return nil
+}
+
+// save atomically replaces the journal file, by writing a temporary file in
+// the same directory and renaming it over the journal.
+func (journal *oplogJournal) save() error {
+	if journal.unsaved == 0 {
+		return nil
+	}
+	content, err := bson.MarshalExtJSON(oplogJournalState{Applied: journal.applied}, true, false)
+	if err != nil {
+		return fmt.Errorf("error encoding %v: %v", OplogJournalOption, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(journal.path), filepath.Base(journal.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("error writing %v: %v", OplogJournalOption, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(content, '\n'))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), journal.path)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing %v: %v", OplogJournalOption, err)
+	}
+	journal.unsaved = 0
+	journal.lastSave = time.Now()
+	log.Logvf(log.DebugHigh, "saved oplog journal at %v", journal.applied)
+	return nil
+}
+
+// logResume warns about replaying an oplog that a previous replay applied
+// part of.
+func (journal *oplogJournal) logResume() {
+	log.Logvf(
+		log.Always,
+		"resuming oplog replay after %v from %v %#q; skipping the entries up to it",
+		journal.resumeAfter,
+		OplogJournalOption,
+		journal.path,
+	)
+	log.Logvf(
+		log.Always,
+		"up to %v CRUD entries applied after the journal was last saved may be applied again; "+
+			"they are idempotent unless the oplog was edited or documents were changed since",
+		oplogJournalSaveEntries,
+	)
+}
+
+// logMissingResumePoint warns if the oplog doesn't have the entry the journal
+// resumes after, which means the journal belongs to another oplog.
+func (journal *oplogJournal) logMissingResumePoint() {
+	if !journal.resuming() || journal.sawResumePoint {
+		return
+	}
+	log.Logvf(
+		log.Always,
+		"warning: the oplog has no entry at %v, where %v %#q resumes; "+
+			"it may have been written for a different oplog",
+		journal.resumeAfter,
+		OplogJournalOption,
+		journal.path,
+	)
+}
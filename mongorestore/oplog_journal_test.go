@@ -0,0 +1,54 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOplogJournal(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	path := filepath.Join(t.TempDir(), "oplog.journal")
+	assert.False(t, (*oplogJournal)(nil).resuming(), "a restore without a journal never resumes")
+
+	journal, err := openOplogJournal(path)
+	require.NoError(t, err)
+	assert.False(t, journal.resuming(), "a missing journal starts from the beginning")
+	assert.False(t, journal.alreadyApplied(primitive.Timestamp{T: 1, I: 1}))
+
+	require.NoError(t, journal.record(primitive.Timestamp{T: 10, I: 1}, false))
+	require.NoError(t, journal.record(primitive.Timestamp{T: 10, I: 2}, false))
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist, "CRUD entries are saved in batches")
+
+	require.NoError(t, journal.record(primitive.Timestamp{T: 11, I: 1}, true))
+	require.NoError(t, journal.record(primitive.Timestamp{T: 12, I: 1}, false))
+	resumed, err := openOplogJournal(path)
+	require.NoError(t, err)
+	assert.Equal(t, primitive.Timestamp{T: 11, I: 1}, resumed.resumeAfter, "commands are saved")
+
+	require.NoError(t, journal.save())
+	resumed, err = openOplogJournal(path)
+	require.NoError(t, err)
+	assert.True(t, resumed.resuming())
+	assert.True(t, resumed.alreadyApplied(primitive.Timestamp{T: 11, I: 5}))
+	assert.True(t, resumed.alreadyApplied(primitive.Timestamp{T: 12, I: 1}))
+	assert.False(t, resumed.alreadyApplied(primitive.Timestamp{T: 12, I: 2}))
+	assert.True(t, resumed.sawResumePoint)
+
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o644))
+	_, err = openOplogJournal(path)
+	assert.ErrorContains(t, err, "error parsing --oplogJournal")
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/mongodb/mongo-tools/common/testtype"
 	"github.com/mongodb/mongo-tools/common/testutil"
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -769,3 +771,87 @@ func testOplogRestoreVectoredInsert(t *testing.T, linked bool) {
 	}
 	require.Equal(t, len(expectedDocs), i)
 }
+
+func TestOplogRestoreResumedFromJournal(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.IntegrationTestType)
+
+	ctx := context.Background()
+	session, err := testutil.GetBareSession()
+	require.NoError(t, err)
+	//nolint:errcheck
+	defer session.Disconnect(ctx)
+
+	dbName := uniqueDBName()
+	coll := session.Database(dbName).Collection("c1")
+	//nolint:errcheck
+	defer session.Database(dbName).Drop(ctx)
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, dbName), 0o755))
+	var data []byte
+	for _, id := range []int{1, 2, 3} {
+		doc, err := bson.Marshal(bson.D{{"_id", id}})
+		require.NoError(t, err)
+		data = append(data, doc...)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, dbName, "c1.bson"), data, 0o644))
+
+	var oplog []byte
+	for _, entry := range []db.Oplog{
+		{
+			Timestamp: primitive.Timestamp{T: 100, I: 1},
+			Version:   2,
+			Operation: "d",
+			Namespace: dbName + ".c1",
+			Object:    bson.D{{"_id", 2}},
+		},
+		{
+			Timestamp: primitive.Timestamp{T: 101, I: 1},
+			Version:   2,
+			Operation: "i",
+			Namespace: dbName + ".c1",
+			Object:    bson.D{{"_id", 4}},
+		},
+	} {
+		raw, err := bson.Marshal(entry)
+		require.NoError(t, err)
+		oplog = append(oplog, raw...)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "oplog.bson"), oplog, 0o644))
+
+	// a previous replay restored the collection and applied the delete
+	// before it was interrupted
+	_, err = coll.InsertMany(ctx, []interface{}{bson.D{{"_id", 1}}, bson.D{{"_id", 3}}})
+	require.NoError(t, err)
+	journalPath := filepath.Join(dir, "oplog.journal")
+	journal, err := bson.MarshalExtJSON(
+		oplogJournalState{Applied: primitive.Timestamp{T: 100, I: 1}},
+		true,
+		false,
+	)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(journalPath, journal, 0o644))
+
+	restore, err := getRestoreWithArgs(
+		DirectoryOption, dir,
+		OplogReplayOption,
+		OplogJournalOption, journalPath,
+		DropOption,
+	)
+	require.NoError(t, err)
+	defer restore.Close()
+
+	result := restore.Restore()
+	require.NoError(t, result.Err)
+	assert.EqualValues(t, 0, result.Successes, "the collection is not restored again")
+
+	cursor, err := coll.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{"_id", 1}}))
+	require.NoError(t, err)
+	var docs []bson.M
+	require.NoError(t, cursor.All(ctx, &docs))
+	var ids []int32
+	for _, doc := range docs {
+		ids = append(ids, doc["_id"].(int32))
+	}
+	assert.Equal(t, []int32{1, 3, 4}, ids, "the deleted document is not restored")
+}
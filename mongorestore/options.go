@@ -16,6 +16,11 @@ import (
 )
 
 // Usage describes basic usage of mongorestore.
+// ExitIncomplete is the exit code of a --tolerant restore that skipped over
+// damaged parts of the archive, so that some namespaces are only partially
+// restored.
+const ExitIncomplete = 2
+
 var Usage = `<options> <connection-string> <directory or file to restore>
 
 Restore backups generated with mongodump to a running server.
@@ -38,26 +43,58 @@ type Options struct {
 
 // InputOptions command line argument long names.
 const (
-	ObjcheckOption               = "--objcheck"
-	OplogReplayOption            = "--oplogReplay"
-	OplogLimitOption             = "--oplogLimit"
-	OplogFileOption              = "--oplogFile"
-	ArchiveOption                = "--archive" // Value is optional, so must use '=' if specifying one
-	RestoreDBUsersAndRolesOption = "--restoreDbUsersAndRoles"
-	DirectoryOption              = "--dir"
-	GzipOption                   = "--gzip"
+	ObjcheckOption                = "--objcheck"
+	OplogReplayOption             = "--oplogReplay"
+	OplogLimitOption              = "--oplogLimit"
+	OplogFileOption               = "--oplogFile"
+	OplogJournalOption            = "--oplogJournal"
+	OplogDryApplyOption           = "--oplogDryApply" // Value is optional, so must use '=' if specifying one
+	OplogShadowPrefixOption       = "--oplogShadowPrefix"
+	OplogFollowOption             = "--oplogFollow"
+	CutoverAddressOption          = "--cutoverAddress"
+	CutoverOption                 = "--cutover"
+	CutoverTokenFileOption        = "--cutoverTokenFile"
+	ArchiveOption                 = "--archive" // Value is optional, so must use '=' if specifying one
+	RestoreDBUsersAndRolesOption  = "--restoreDbUsersAndRoles"
+	DirectoryOption               = "--dir"
+	GzipOption                    = "--gzip"
+	TolerantOption                = "--tolerant"
+	ArchivePrefetchBytesOption    = "--archivePrefetchBytes"
+	ArchivePrefetchDirOption      = "--archivePrefetchDir"
+	CheckArchiveOption            = "--checkArchive"
+	RestoreJournalOption          = "--restoreJournal"
+	PartialCollectionPolicyOption = "--partialCollectionPolicy"
+	ServeOption                   = "--serve"
+	ServeTokenFileOption          = "--serveTokenFile"
+	ServeAllowRemoteOption        = "--serveAllowRemote"
 )
 
 // InputOptions defines the set of options to use in configuring the restore process.
 type InputOptions struct {
-	Objcheck               bool   `long:"objcheck" description:"validate all objects before inserting"`
-	OplogReplay            bool   `long:"oplogReplay" description:"for recovering a point-in-time snapshot on a replica set that is not part of a sharded cluster."`
-	OplogLimit             string `long:"oplogLimit" value-name:"<seconds>[:ordinal]" description:"only include oplog entries before the provided Timestamp"`
-	OplogFile              string `long:"oplogFile" value-name:"<filename>" description:"oplog file to use for replay of oplog"`
-	Archive                string `long:"archive" value-name:"<filename>" optional:"true" optional-value:"-" description:"restore dump from the specified archive file.  If flag is specified without a value, archive is read from stdin"`
-	RestoreDBUsersAndRoles bool   `long:"restoreDbUsersAndRoles" description:"restore user and role definitions for the given database"`
-	Directory              string `long:"dir" value-name:"<directory-name>" description:"input directory, use '-' for stdin"`
-	Gzip                   bool   `long:"gzip" description:"decompress gzipped input"`
+	Objcheck                bool   `long:"objcheck" description:"validate all objects before inserting"`
+	OplogReplay             bool   `long:"oplogReplay" description:"for recovering a point-in-time snapshot on a replica set that is not part of a sharded cluster."`
+	OplogLimit              string `long:"oplogLimit" value-name:"<seconds>[:ordinal]" description:"only include oplog entries before the provided Timestamp"`
+	OplogFile               string `long:"oplogFile" value-name:"<filename>" description:"oplog file to use for replay of oplog"`
+	OplogJournal            string `long:"oplogJournal" value-name:"<filename>" description:"file recording the timestamp of the last oplog entry applied by --oplogReplay; if a replay is interrupted, running it again with the same file skips the restore of the collections and the entries that were already applied"`
+	OplogDryApply           string `long:"oplogDryApply" value-name:"<postImages|current>" optional:"true" optional-value:"postImages" description:"with --oplogReplay, replay the oplog into shadow collections instead of the real ones and report where the result diverges: from the documents the inserts and replacements of the oplog wrote (postImages, the default), or from the real collections on the target (current). Each shadow collection starts as a copy of its real collection. They are dropped if nothing diverges"`
+	OplogShadowPrefix       string `long:"oplogShadowPrefix" value-name:"<prefix>" description:"prefix of the names of the shadow collections of --oplogDryApply, which are created in the databases of their real collections (default: oplogShadow.)"`
+	OplogFollow             string `long:"oplogFollow" value-name:"<filename|uri>" description:"with --oplogReplay, keep applying the oplog entries after those of the dump once they are replayed, from a BSON file that another process keeps appending oplog entries to, or from the oplog of the replica set of a mongodb:// connection string, until a cutover is requested on --cutoverAddress"`
+	CutoverAddress          string `long:"cutoverAddress" value-name:"<host:port>" description:"address of the HTTP API of --oplogFollow: GET /status returns how far the restore lags behind its source, and POST /cutover applies the entries the source has by then, stops, and returns the final status; it listens only on a loopback address unless --cutoverTokenFile is set"`
+	CutoverTokenFile        string `long:"cutoverTokenFile" value-name:"<filename>" description:"file holding the token that the requests to --cutoverAddress must give as a bearer token in their Authorization header, which lets it listen on any address; --cutover sends the token of the file"`
+	Cutover                 string `long:"cutover" value-name:"<host:port>" description:"request the cutover of the mongorestore following an oplog with its --cutoverAddress on the given address, wait until it has stopped, and print its final status, without connecting to a server"`
+	Archive                 string `long:"archive" value-name:"<filename>" optional:"true" optional-value:"-" description:"restore dump from the specified archive file, which may be a named pipe or a UNIX domain socket opened by another process.  If flag is specified without a value, archive is read from stdin"`
+	RestoreDBUsersAndRoles  bool   `long:"restoreDbUsersAndRoles" description:"restore user and role definitions for the given database"`
+	Directory               string `long:"dir" value-name:"<directory-name>" description:"input directory, use '-' for stdin"`
+	Gzip                    bool   `long:"gzip" description:"decompress gzipped input"`
+	ArchivePrefetchBytes    int64  `long:"archivePrefetchBytes" value-name:"<bytes>" description:"when restoring from an archive, read up to this many bytes ahead of the restore into a file on disk, so that a slow source like a download piped to stdin does not starve the insertion workers (default 0, which reads the archive directly)"`
+	ArchivePrefetchDir      string `long:"archivePrefetchDir" value-name:"<directory-path>" description:"directory of the file that --archivePrefetchBytes reads ahead into, and of the other temporary files of the restore; like --tempDir, which it predates (default: --tempDir)"`
+	Tolerant                bool   `long:"tolerant" description:"when restoring from an archive, skip over corrupted or truncated parts of the archive instead of failing, restore everything that can be read, and report the namespaces that could not be fully restored; mongorestore then exits with code 2"`
+	CheckArchive            bool   `long:"checkArchive" description:"read the whole archive given by --archive and verify its prelude, every block and their checksums, without connecting to a server or restoring anything. Exits with an error if the archive is damaged"`
+	RestoreJournal          string `long:"restoreJournal" value-name:"<filename>" description:"file recording how many documents of each collection of the --archive were restored, which must have the contiguous layout of mongodump --archiveLayout=contiguous; if a restore is interrupted, running it again with the same file seeks past the collections that were completely restored, and skips the documents of the others that were. Each collection is restored with a single insertion worker"`
+	PartialCollectionPolicy string `long:"partialCollectionPolicy" value-name:"<policy>" choice:"upsert" choice:"drop" default:"upsert" description:"how a restore resumed from --restoreJournal restores the collections that were partially restored, some of whose documents may have been restored after the journal was last saved: upsert skips the documents the journal records and replaces the others by _id, and drop drops the collection and restores it from the start"`
+	Serve                   string `long:"serve" value-name:"<host:port>|unix:<path>" description:"run as a server that restores the jobs submitted to an HTTP API on the given loopback address or unix socket, one at a time: POST /jobs with {\"args\": [...]} submits the command line arguments of a restore, GET /jobs/<id> returns its state, progress and report, and DELETE /jobs/<id> cancels it. Jobs cannot use options that read the environment or other files than the dump, write other files, run commands or send data to other addresses, or prompt for a password"`
+	ServeTokenFile          string `long:"serveTokenFile" value-name:"<filename>" description:"file holding the token that the requests of --serve must give as a bearer token in their Authorization header; required with --serve"`
+	ServeAllowRemote        bool   `long:"serveAllowRemote" description:"let --serve listen on an address that is not a loopback address"`
 }
 
 // Name returns a human-readable group name for input options.
@@ -74,6 +111,7 @@ const (
 	ConvertLegacyIndexesOption     = "--convertLegacyIndexes"
 	NoOptionsRestoreOption         = "--noOptionsRestore"
 	KeepIndexVersionOption         = "--keepIndexVersion"
+	IndexTranslationFileOption     = "--indexTranslationFile"
 	MaintainInsertionOrderOption   = "--maintainInsertionOrder"
 	NumParallelCollectionsOption   = "--numParallelCollections"
 	NumInsertionWorkersOption      = "--numInsertionWorkersPerCollection"
@@ -83,7 +121,28 @@ const (
 	TempUsersCollOption            = "--tempUsersColl"
 	TempRolesCollOption            = "--tempRolesColl"
 	BulkBufferSizeOption           = "--batchSize"
+	MaxDocSizeBytesOption          = "--maxDocSizeBytes"
+	MaxReplicationLagSecondsOption = "--maxReplicationLagSeconds"
+	ValueMapFileOption             = "--valueMapFile"
 	FixDottedHashedIndexesOption   = "--fixDottedHashIndex"
+	RestoreShardingConfigOption    = "--restoreShardingConfig"
+	ShardingConfigFileOption       = "--shardingConfigFile"
+	WarmCacheOption                = "--warmCache"
+	WarmCacheNSOption              = "--warmCacheNS"
+	NumWarmCacheWorkersOption      = "--numWarmCacheWorkers"
+	ReportFileOption               = "--reportFile"
+	RemovedIndexPolicyOption       = "--removedIndexPolicy"
+	IndexOptionPolicyOption        = "--unsupportedIndexOptionPolicy"
+	IndexDuplicatePolicyOption     = "--duplicateKeyIndexPolicy"
+	IndexMemoryPolicyOption        = "--indexMemoryLimitPolicy"
+	CompareOption                  = "--compare"
+	CompareSampleSizeOption        = "--compareSampleSize"
+	ConvertToTimeseriesOption      = "--convertToTimeseries"
+	TimeFieldOption                = "--timeField"
+	MetaFieldOption                = "--metaField"
+	GranularityOption              = "--granularity"
+	DocumentValidationOption       = "--documentValidation"
+	OnDuplicateKeyOption           = "--onDuplicateKey"
 )
 
 // OutputOptions defines the set of options for restoring dump data.
@@ -97,16 +156,45 @@ type OutputOptions struct {
 	ConvertLegacyIndexes     bool   `long:"convertLegacyIndexes" description:"Removes invalid index options and rewrites legacy option values (e.g. true becomes 1)."`
 	NoOptionsRestore         bool   `long:"noOptionsRestore" description:"don't restore collection options"`
 	KeepIndexVersion         bool   `long:"keepIndexVersion" description:"don't update index version"`
+	IndexTranslationFile     string `long:"indexTranslationFile" value-name:"<filename>" description:"JSON file with explicit rules that rewrite the dumped index definitions before any other conversion: 'keys' replace index key values, matched by BSON type and value, e.g. {\"from\": \"1\", \"to\": 1}; 'removeOptions' lists index options to remove, e.g. dropDups; 'versions' rewrite index versions, e.g. {\"from\": 0, \"to\": 1}, and require --keepIndexVersion. Every change is logged and included in --reportFile, along with the indexes dumped in legacy formats"`
 	MaintainInsertionOrder   bool   `long:"maintainInsertionOrder" description:"restore the documents in the order of their appearance in the input source. By default the insertions will be performed in an arbitrary order. Setting this flag also enables the behavior of --stopOnError and restricts NumInsertionWorkersPerCollection to 1."`
 	NumParallelCollections   int    `long:"numParallelCollections" short:"j" description:"number of collections to restore in parallel" default:"4" default-mask:"-"`
 	NumInsertionWorkers      int    `long:"numInsertionWorkersPerCollection" description:"number of insert operations to run concurrently per collection" default:"1" default-mask:"-"`
 	StopOnError              bool   `long:"stopOnError" description:"halt after encountering any error during insertion. By default, mongorestore will attempt to continue through document validation and DuplicateKey errors, but with this option enabled, the tool will stop instead. A small number of documents may be inserted after encountering an error even with this option enabled; use --maintainInsertionOrder to halt immediately after an error"`
-	BypassDocumentValidation bool   `long:"bypassDocumentValidation" description:"bypass document validation"`
+	BypassDocumentValidation bool   `long:"bypassDocumentValidation" description:"bypass document validation in the namespaces that match no --documentValidation pattern"`
+
+	// validation policies per namespace, which take precedence over --bypassDocumentValidation
+	DocumentValidation []string `long:"documentValidation" value-name:"<namespace-pattern>=<policy>" description:"how to validate the documents restored into the namespaces matching the pattern; the first matching pattern applies (may be specified multiple times). bypass: insert them without validation. enforce: validate them and, without --stopOnError, skip the ones that fail. strict: validate them and fail the restore at the first one that fails. report: insert them without validation, then find the ones that fail the validator of the collection. Documents that fail are counted and their _ids listed in --reportFile"`
+
+	OnDuplicateKey           string `long:"onDuplicateKey" value-name:"<policy>" choice:"skip" choice:"overwrite" choice:"fail" choice:"suffix" description:"what to do with the documents whose _id, or key of another unique index, already exists in the target collection, e.g. when restoring into a partially populated collection. skip: skip them. overwrite: replace the documents with the same _id. fail: fail the restore. suffix: insert them with their _id as a string followed by -1, or the first such number that is free. Without it, such documents are skipped with their errors logged, unless --stopOnError is set. Documents that overwrite and suffix cannot resolve, because another unique index has their key, fail the restore. Resolved documents are included in --reportFile"`
 	PreserveUUID             bool   `long:"preserveUUID" description:"preserve original collection UUIDs (off by default, requires drop)"`
 	TempUsersColl            string `long:"tempUsersColl" default:"tempusers" hidden:"true"`
 	TempRolesColl            string `long:"tempRolesColl" default:"temproles" hidden:"true"`
-	BulkBufferSize           int    `long:"batchSize" default:"1000" hidden:"true"`
+	BulkBufferSize           int    `long:"batchSize" hidden:"true"`
+	MaxDocSizeBytes          int    `long:"maxDocSizeBytes" value-name:"<bytes>" description:"size in bytes of the largest document to restore, for clusters whose limit is not the default of 16MB. Larger documents, along with documents that nest too deeply or have an invalid _id or field name, are counted as failures and skipped, unless --stopOnError is set"`
+	MaxReplicationLagSeconds int    `long:"maxReplicationLagSeconds" value-name:"<seconds>" description:"keep the replication lag of the target replica set under this many seconds, by sampling it every second and reducing the number of insertion workers that write at once while it, or flow control, shows the secondaries falling behind"`
+	ValueMapFile             string `long:"valueMapFile" value-name:"<filename>" description:"JSON file with 'remaps' that each replace values of a field, such as a tenant ID, in the namespaces matching a pattern, in the restored documents and in the replayed oplog entries"`
 	FixDottedHashedIndexes   bool   `long:"fixDottedHashIndex" description:"when enabled, all the hashed indexes on dotted fields will be created as single field ascending indexes on the destination"`
+	RestoreShardingConfig    bool   `long:"restoreShardingConfig" description:"shard restored collections and re-create their zones using the shard keys, zones and zone ranges from the dumped config database (or --shardingConfigFile), instead of restoring the config database itself. Requires connecting to a mongos"`
+	ShardingConfigFile       string `long:"shardingConfigFile" value-name:"<filename>" description:"JSON file with 'collections', 'tags' and 'shards' arrays in the format of the config database collections of the same names, for use with --restoreShardingConfig"`
+	RemovedIndexPolicy       string `long:"removedIndexPolicy" value-name:"<policy>" choice:"fail" choice:"convert" choice:"drop" default:"fail" description:"what to do with indexes that use types or options that the destination no longer supports, such as geoHaystack indexes, dropDups, and version 1 text and 2dsphere indexes. fail: create them as they are, which fails on servers that reject them. convert: rewrite them to the closest supported definition, e.g. geoHaystack indexes become 2d indexes. drop: skip them. Every change is logged and included in --reportFile"`
+	IndexOptionPolicy        string `long:"unsupportedIndexOptionPolicy" value-name:"<policy>" choice:"fail" choice:"skip" choice:"retry" default:"fail" description:"what to do when the destination rejects an option of an index. fail: fail the restore. skip: restore the collection without the index. retry: create the index again without the rejected option. Every index that fails is logged and included in --reportFile"`
+	IndexDuplicatePolicy     string `long:"duplicateKeyIndexPolicy" value-name:"<policy>" choice:"fail" choice:"skip" choice:"retry" default:"fail" description:"what to do when a unique index cannot be built because the restored documents have duplicate keys. fail: fail the restore. skip: restore the collection without the index. retry: create the index again as a non-unique index. Every index that fails is logged and included in --reportFile"`
+	IndexMemoryPolicy        string `long:"indexMemoryLimitPolicy" value-name:"<policy>" choice:"fail" choice:"skip" choice:"retry" default:"fail" description:"what to do when building the indexes of a collection exceeds the memory limit of the destination. fail: fail the restore. skip: restore the collection without the indexes that exceed it. retry: build the indexes of the collection one at a time, so that they do not share the limit. Every index that fails is logged and included in --reportFile"`
+	ReportFile               string `long:"reportFile" value-name:"<filename>" description:"write a JSON report of the number of documents restored into each namespace, and of the namespaces of the dump skipped by the include, exclude and special collection rules, to this file"`
+	Compare                  bool   `long:"compare" description:"write nothing, and instead compare each collection of the dump with the one it would be restored into: the number of documents, the indexes, and a sample of documents by _id. Differences are logged, included in --reportFile, and make mongorestore fail"`
+	CompareSampleSize        int    `long:"compareSampleSize" value-name:"<count>" description:"number of documents of each collection that --compare looks up on the target and compares byte for byte" default:"1000" default-mask:"-"`
+
+	// loading the restored collections into the target's cache
+	WarmCache           bool     `long:"warmCache" description:"scan each collection once its data is restored, and traverse its indexes once they are built, to load them into the target's cache before it takes traffic"`
+	WarmCacheNS         []string `long:"warmCacheNS" value-name:"<namespace-pattern>" description:"only warm the cache for restored namespaces matching this pattern (may be specified multiple times), for use with --warmCache"`
+	NumWarmCacheWorkers int      `long:"numWarmCacheWorkers" description:"number of collections to warm the cache for in parallel, for use with --warmCache" default:"1" default-mask:"-"`
+
+	// restoring regular collections as timeseries collections
+	ConvertToTimeseries []string `long:"convertToTimeseries" value-name:"<namespace-pattern>" description:"create the collections of the dump matching this pattern as timeseries collections, with the options of --timeField, --metaField and --granularity, and insert their documents as measurements; documents without a date in the time field are counted as failures (may be specified multiple times). Requires server version 5.0 or later"`
+	TimeField           string   `long:"timeField" value-name:"<field>" description:"field holding the date of each measurement in the collections of --convertToTimeseries"`
+	MetaField           string   `long:"metaField" value-name:"<field>" description:"field holding the metadata of each measurement in the collections of --convertToTimeseries, if any"`
+	Granularity         string   `long:"granularity" value-name:"<granularity>" choice:"seconds" choice:"minutes" choice:"hours" description:"granularity of the collections of --convertToTimeseries, either seconds, minutes or hours (default: the server's, seconds)"`
 }
 
 // Name returns a human-readable group name for output options.
@@ -133,7 +221,8 @@ type NSOptions struct {
 	NSExclude                  []string `long:"nsExclude" value-name:"<namespace-pattern>" description:"exclude matching namespaces"`
 	NSInclude                  []string `long:"nsInclude" value-name:"<namespace-pattern>" description:"include matching namespaces"`
 	NSFrom                     []string `long:"nsFrom" value-name:"<namespace-pattern>" description:"rename matching namespaces, must have matching nsTo"`
-	NSTo                       []string `long:"nsTo" value-name:"<namespace-pattern>" description:"rename matched namespaces, must have matching nsFrom"`
+	SystemCollections          []string `long:"systemCollection" value-name:"<collection-name>" description:"also restore the system collection of this name, e.g. system.myapp, in every database other than admin and config, which are otherwise skipped except for system.js. With --drop, these system collections and system.js are emptied before restoring, since they can't be dropped (may be specified multiple times)"`
+	NSTo                       []string `long:"nsTo" value-name:"<namespace-pattern>" description:"rename matched namespaces, must have matching nsFrom; may use the variables {{date}} (YYYYMMDD) and {{time}} (HHMMSS) of when the restore started, {{dumpId}} (the name of the dump directory, or of the archive file without .archive and .gz), and {{source_db}} and {{source_collection}} of the renamed namespace, e.g. staging_{{date}}.*"`
 }
 
 // Name returns a human-readable group name for output options.
@@ -143,16 +232,7 @@ func (*NSOptions) Name() string {
 
 // ParseOptions reads the command line arguments and converts them into options used to configure a MongoRestore instance.
 func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, error) {
-	opts := options.New("mongorestore", versionStr, gitCommit, Usage, true,
-		options.EnabledOptions{Auth: true, Connection: true, Namespace: true, URI: true})
-	nsOpts := &NSOptions{}
-	opts.AddOptions(nsOpts)
-
-	inputOpts := &InputOptions{}
-	opts.AddOptions(inputOpts)
-
-	outputOpts := &OutputOptions{}
-	opts.AddOptions(outputOpts)
+	opts, inputOpts, nsOpts, outputOpts := newOptions(versionStr, gitCommit)
 
 	extraArgs, err := opts.ParseArgs(rawArgs)
 	if err != nil {
@@ -186,6 +266,29 @@ func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, erro
 	return Options{opts, inputOpts, nsOpts, outputOpts, targetDir}, nil
 }
 
+// newOptions returns the options of mongorestore, before they are parsed.
+func newOptions(
+	versionStr, gitCommit string,
+) (*options.ToolOptions, *InputOptions, *NSOptions, *OutputOptions) {
+	opts := options.New("mongorestore", versionStr, gitCommit, Usage, true,
+		options.EnabledOptions{
+			Auth:       true,
+			Connection: true,
+			Namespace:  true,
+			URI:        true,
+			TempFiles:  true,
+		})
+	nsOpts := &NSOptions{}
+	opts.AddOptions(nsOpts)
+
+	inputOpts := &InputOptions{}
+	opts.AddOptions(inputOpts)
+
+	outputOpts := &OutputOptions{}
+	opts.AddOptions(outputOpts)
+	return opts, inputOpts, nsOpts, outputOpts
+}
+
 // getTargetDirFromArgs handles the logic and error cases of figuring out
 // the target restore directory.
 func getTargetDirFromArgs(extraArgs []string, dirFlag string) (string, error) {
@@ -0,0 +1,184 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/tempfile"
+)
+
+// prefetchChunkSize is the most that a prefetchReader reads from its source
+// at once.
+const prefetchChunkSize = 1024 * 1024
+
+// prefetchReader reads ahead of its reader, from a slow source like an
+// archive piped from a download, into a spill file used as a ring buffer of
+// --archivePrefetchBytes. The insertion workers then keep being fed from the
+// file while the network stalls, instead of waiting on every read of it.
+type prefetchReader struct {
+	source io.ReadCloser
+	file   *tempfile.File
+	size   int64
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// written and read are how many bytes went into the file and came out of
+	// it; the bytes in between are buffered, at offsets modulo size.
+	written, read int64
+	// err is the error that stopped the reads from the source, like io.EOF,
+	// returned once the buffered bytes are read.
+	err    error
+	closed bool
+	// emptyWaits counts how often a read found the buffer empty and had to
+	// wait for the source.
+	emptyWaits int
+}
+
+// newPrefetchReader starts to read ahead of source into a spill file created
+// by files.
+func newPrefetchReader(
+	source io.ReadCloser,
+	size int64,
+	files *tempfile.Manager,
+) (*prefetchReader, error) {
+	file, err := files.Create("mongorestore-prefetch-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating archive prefetch file: %v", err)
+	}
+	log.Logvf(log.DebugLow, "prefetching up to %v bytes of the archive into %v", size, file.Name())
+	r := &prefetchReader{source: source, file: file, size: size}
+	r.cond = sync.NewCond(&r.mu)
+	go r.fill()
+	return r, nil
+}
+
+// tempFileManager returns the manager of the temporary files of the restore,
+// which creates them in --archivePrefetchDir if it is set, and in --tempDir
+// otherwise.
+func (restore *MongoRestore) tempFileManager() *tempfile.Manager {
+	if restore.tempFiles != nil {
+		return restore.tempFiles
+	}
+	var opts *options.TempFiles
+	if restore.ToolOptions != nil {
+		opts = restore.ToolOptions.TempFiles
+	}
+	restore.tempFiles = opts.Manager()
+	if dir := restore.InputOptions.ArchivePrefetchDir; dir != "" {
+		var maxBytes int64
+		if opts != nil {
+			maxBytes = opts.MaxTempBytes
+		}
+		restore.tempFiles = tempfile.NewManager(dir, maxBytes)
+	}
+	return restore.tempFiles
+}
+
+// fill reads from the source into the free space of the buffer until the
+// source ends or fails, or the reader is closed.
+func (r *prefetchReader) fill() {
+	chunk := make([]byte, min(r.size, prefetchChunkSize))
+	for {
+		r.mu.Lock()
+		for r.written-r.read == r.size && !r.closed {
+			r.cond.Wait()
+		}
+		if r.closed {
+			r.mu.Unlock()
+			return
+		}
+		offset := r.written % r.size
+		n := min(r.size-(r.written-r.read), r.size-offset, int64(len(chunk)))
+		r.mu.Unlock()
+
+		read, err := r.source.Read(chunk[:n])
+		if read > 0 {
+			if _, writeErr := r.file.WriteAt(chunk[:read], offset); writeErr != nil {
+				read, err = 0, fmt.Errorf("error writing archive prefetch file: %v", writeErr)
+			}
+		}
+
+		r.mu.Lock()
+		r.written += int64(read)
+		if err != nil {
+			r.err = err
+		}
+		r.cond.Broadcast()
+		r.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Read reads the bytes buffered so far, waiting for the source if there are
+// none.
+func (r *prefetchReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	r.mu.Lock()
+	if r.written == r.read && r.err == nil && !r.closed {
+		r.emptyWaits++
+	}
+	for r.written == r.read && r.err == nil && !r.closed {
+		r.cond.Wait()
+	}
+	if r.closed {
+		r.mu.Unlock()
+		return 0, os.ErrClosed
+	}
+	if r.written == r.read {
+		err := r.err
+		r.mu.Unlock()
+		return 0, err
+	}
+	offset := r.read % r.size
+	n := min(int64(len(p)), r.written-r.read, r.size-offset)
+	r.mu.Unlock()
+
+	read, err := r.file.ReadAt(p[:n], offset)
+	if err != nil && int64(read) < n {
+		return read, fmt.Errorf("error reading archive prefetch file: %v", err)
+	}
+
+	r.mu.Lock()
+	r.read += int64(read)
+	r.cond.Broadcast()
+	r.mu.Unlock()
+	return read, nil
+}
+
+// Close closes the source and removes the spill file.
+func (r *prefetchReader) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	emptyWaits := r.emptyWaits
+	r.cond.Broadcast()
+	r.mu.Unlock()
+
+	log.Logvf(
+		log.Info,
+		"the restore waited for the archive source %v times with the prefetch buffer empty",
+		emptyWaits,
+	)
+	err := r.source.Close()
+	if removeErr := r.file.Remove(); removeErr != nil && err == nil {
+		err = fmt.Errorf("error removing archive prefetch file: %v", removeErr)
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/tempfile"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefetchReader(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	data := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	t.Run("reads the source through a smaller buffer", func(t *testing.T) {
+		dir := t.TempDir()
+		files := tempfile.NewManager(dir, 0)
+		source := io.NopCloser(iotest.HalfReader(bytes.NewReader(data)))
+		r, err := newPrefetchReader(source, 4099, files)
+		require.NoError(t, err)
+
+		read, err := io.ReadAll(iotest.OneByteReader(io.LimitReader(r, 10)))
+		require.NoError(t, err)
+		rest, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, data, append(read, rest...))
+
+		require.NoError(t, r.Close())
+		assert.Zero(t, files.Used())
+		require.NoError(t, files.Cleanup())
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries, "the spill file is removed")
+	})
+
+	t.Run("reads ahead no more than the buffer size", func(t *testing.T) {
+		files := tempfile.NewManager(t.TempDir(), 0)
+		r, err := newPrefetchReader(io.NopCloser(bytes.NewReader(data)), 1000, files)
+		require.NoError(t, err)
+		defer r.Close()
+
+		require.Eventually(t, func() bool {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			return r.written == 1000
+		}, time.Second, time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+		r.mu.Lock()
+		assert.EqualValues(t, 1000, r.written)
+		r.mu.Unlock()
+
+		buf := make([]byte, 300)
+		n, err := io.ReadFull(r, buf)
+		require.NoError(t, err)
+		assert.Equal(t, data[:n], buf)
+		require.Eventually(t, func() bool {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			return r.written == 1300
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("returns the error of the source after the data before it", func(t *testing.T) {
+		failure := errors.New("connection reset")
+		source := io.NopCloser(io.MultiReader(
+			bytes.NewReader(data[:5000]),
+			iotest.ErrReader(failure),
+		))
+		r, err := newPrefetchReader(source, 4096, tempfile.NewManager(t.TempDir(), 0))
+		require.NoError(t, err)
+		defer r.Close()
+
+		read, err := io.ReadAll(r)
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, data[:5000], read)
+	})
+
+	t.Run("closing while a read waits for the source", func(t *testing.T) {
+		pr, pw := io.Pipe()
+		r, err := newPrefetchReader(pr, 4096, tempfile.NewManager(t.TempDir(), 0))
+		require.NoError(t, err)
+
+		done := make(chan error)
+		go func() {
+			_, err := r.Read(make([]byte, 10))
+			done <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		require.NoError(t, r.Close())
+		assert.ErrorIs(t, <-done, os.ErrClosed)
+		_, err = pw.Write([]byte("late"))
+		assert.Error(t, err, "the source is closed")
+	})
+}
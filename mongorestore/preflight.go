@@ -0,0 +1,490 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/bsonutil"
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/idx"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// supportedCollationVersion is the only collation version that servers accept
+// when creating collections and indexes.
+const supportedCollationVersion = "57.1"
+
+// maxListedUses is the number of namespaces or indexes listed in a preflight
+// problem before the rest are summarized as a count.
+const maxListedUses = 5
+
+// capability is a feature that the dump may require of the destination
+// cluster.
+type capability struct {
+	// name is the plural name of the feature, used in the report.
+	name string
+	// singular names a single use of the feature, e.g. "a wildcard index".
+	singular string
+	// minVersion is the first version that supports the feature.
+	minVersion db.Version
+	// removedIn, if set, is the first version that no longer supports it.
+	removedIn  db.Version
+	workaround string
+}
+
+var (
+	timeseriesCapability = &capability{
+		name:       "timeseries collections",
+		singular:   "a timeseries collection",
+		minVersion: db.Version{5, 0, 0},
+		workaround: "Exclude them with --nsExclude.",
+	}
+	clusteredCapability = &capability{
+		name:       "clustered collections",
+		singular:   "a clustered collection",
+		minVersion: db.Version{5, 3, 0},
+		workaround: "Use --noOptionsRestore to restore them as regular collections.",
+	}
+	cappedClusteredCapability = &capability{
+		name:       "capped clustered collections",
+		singular:   "a capped clustered collection",
+		minVersion: db.Version{5, 3, 0},
+		workaround: "Use --noOptionsRestore to restore them as regular collections.",
+	}
+	changeStreamImagesCapability = &capability{
+		name:       "change stream pre- and post-images",
+		singular:   "a collection with change stream pre- and post-images",
+		minVersion: db.Version{6, 0, 0},
+		workaround: "Use --noOptionsRestore to restore the collections without them.",
+	}
+	collationCapability = &capability{
+		name:       "collations",
+		singular:   "a collation",
+		minVersion: db.Version{3, 4, 0},
+		workaround: "Use --noOptionsRestore and --noIndexRestore to restore without collations.",
+	}
+	wildcardIndexCapability = &capability{
+		name:       "wildcard indexes",
+		singular:   "a wildcard index",
+		minVersion: db.Version{4, 2, 0},
+		workaround: "Use --noIndexRestore to skip all indexes.",
+	}
+	compoundWildcardIndexCapability = &capability{
+		name:       "compound wildcard indexes",
+		singular:   "a compound wildcard index",
+		minVersion: db.Version{7, 0, 0},
+		workaround: "Use --noIndexRestore to skip all indexes.",
+	}
+	columnstoreIndexCapability = &capability{
+		name:       "columnstore indexes",
+		singular:   "a columnstore index",
+		minVersion: db.Version{6, 3, 0},
+		workaround: "Use --noIndexRestore to skip all indexes.",
+	}
+	geoHaystackIndexCapability = &capability{
+		name:       "geoHaystack indexes",
+		singular:   "a geoHaystack index",
+		removedIn:  db.Version{4, 9, 0},
+		workaround: "Use --removedIndexPolicy=convert to restore them as 2d indexes, or --removedIndexPolicy=drop to skip them.",
+	}
+)
+
+// capabilities lists every capability in the order of the preflight report.
+var capabilities = []*capability{
+	timeseriesCapability,
+	clusteredCapability,
+	cappedClusteredCapability,
+	changeStreamImagesCapability,
+	collationCapability,
+	wildcardIndexCapability,
+	compoundWildcardIndexCapability,
+	columnstoreIndexCapability,
+	geoHaystackIndexCapability,
+}
+
+// unsupportedBy returns which version of a cluster doesn't support the
+// capability for the preflight report, e.g. "server version is 5.0.0", or ""
+// if the cluster supports it. Capabilities are added with the featureCompatibilityVersion,
+// but removed with the server binary.
+func (c *capability) unsupportedBy(featureVersion, serverVersion db.Version) string {
+	if featureVersion.LT(c.minVersion) {
+		return "feature version is " + formatVersion(featureVersion)
+	}
+	if c.removedIn != (db.Version{}) && !serverVersion.LT(c.removedIn) {
+		return "server version is " + formatVersion(serverVersion)
+	}
+	return ""
+}
+
+// requirement describes the versions that support the capability.
+func (c *capability) requirement() string {
+	if c.removedIn != (db.Version{}) {
+		return "< " + formatVersion(c.removedIn)
+	}
+	return ">= " + formatVersion(c.minVersion)
+}
+
+// found describes the uses of the capability that the destination does not
+// support.
+func (c *capability) found(uses []string) string {
+	return foundUses(c.singular, c.name, uses)
+}
+
+// foundUses describes uses of something named singular, or plural for more
+// than one use.
+func foundUses(singular, plural string, uses []string) string {
+	if len(uses) == 1 {
+		return fmt.Sprintf("found %v: %v", singular, uses[0])
+	}
+	return fmt.Sprintf("found %v %v: %v", len(uses), plural, listUses(uses))
+}
+
+// listUses lists the first maxListedUses uses, and counts the rest.
+func listUses(uses []string) string {
+	listed := uses
+	more := ""
+	if len(listed) > maxListedUses {
+		listed = listed[:maxListedUses]
+		more = fmt.Sprintf(" and %v more", len(uses)-maxListedUses)
+	}
+	return strings.Join(listed, ", ") + more
+}
+
+// preflightReport collects what the dump requires of the destination cluster
+// and every problem that would make the restore fail, so that they can all be
+// reported at once before anything is written.
+type preflightReport struct {
+	uses map[*capability][]string
+	// collationVersions holds the uses of each unsupported collation version
+	collationVersions map[string][]string
+	problems          []string
+}
+
+func newPreflightReport() *preflightReport {
+	return &preflightReport{
+		uses:              map[*capability][]string{},
+		collationVersions: map[string][]string{},
+	}
+}
+
+func (report *preflightReport) require(c *capability, use string) {
+	report.uses[c] = append(report.uses[c], use)
+}
+
+func (report *preflightReport) problem(format string, args ...interface{}) {
+	report.problems = append(report.problems, fmt.Sprintf(format, args...))
+}
+
+// addIntents records the capabilities required by the options of the
+// collections to restore.
+func (report *preflightReport) addIntents(allIntents []*intents.Intent, noOptionsRestore bool) {
+	var timeseries []string
+	for _, intent := range allIntents {
+		if intent.Type == "timeseries" {
+			report.require(timeseriesCapability, intent.Namespace())
+			timeseries = append(timeseries, intent.Namespace())
+		}
+		if noOptionsRestore || intent.Options == nil {
+			continue
+		}
+		if _, err := bsonutil.FindValueByKey(clusteredIndexOption, &intent.Options); err == nil {
+			capped, _ := bsonutil.FindValueByKey("capped", &intent.Options)
+			if capped == true {
+				report.require(cappedClusteredCapability, intent.Namespace())
+			} else {
+				report.require(clusteredCapability, intent.Namespace())
+			}
+		}
+		_, err := bsonutil.FindValueByKey(changeStreamImagesOption, &intent.Options)
+		if err == nil {
+			report.require(changeStreamImagesCapability, intent.Namespace())
+		}
+		if collation, err := bsonutil.FindValueByKey("collation", &intent.Options); err == nil {
+			report.addCollation(collation, intent.Namespace())
+		}
+	}
+	if noOptionsRestore && len(timeseries) > 0 {
+		sort.Strings(timeseries)
+		report.problem(
+			"cannot specify --noOptionsRestore when restoring timeseries collections: %v",
+			listUses(timeseries),
+		)
+	}
+}
+
+// addIndexes records the capabilities required by the indexes to restore.
+func (report *preflightReport) addIndexes(catalog *idx.IndexCatalog) {
+	for _, ns := range catalog.Namespaces() {
+		for _, index := range catalog.GetIndexes(ns.DB, ns.Collection) {
+			use := fmt.Sprintf("%v on %v", index.Key, ns.String())
+			wildcardFields := 0
+			for _, keyElement := range index.Key {
+				switch keyElement.Value {
+				case "columnstore":
+					report.require(columnstoreIndexCapability, use)
+					continue
+				case "geoHaystack":
+					report.require(geoHaystackIndexCapability, use)
+				}
+				if keyElement.Key == "$**" || strings.HasSuffix(keyElement.Key, ".$**") {
+					wildcardFields++
+				}
+			}
+			if wildcardFields > 0 {
+				if len(index.Key) > 1 {
+					report.require(compoundWildcardIndexCapability, use)
+				} else {
+					report.require(wildcardIndexCapability, use)
+				}
+			}
+			if collation, ok := index.Options["collation"]; ok {
+				report.addCollation(collation, use)
+			}
+		}
+	}
+}
+
+// addCollation records a collation used by a collection or index. The simple
+// collation does not require any capability, but other collations must have
+// a version that the destination supports, which check reports once for each
+// version.
+func (report *preflightReport) addCollation(collation interface{}, use string) {
+	var locale, version interface{}
+	switch c := collation.(type) {
+	case bson.D:
+		locale, _ = bsonutil.FindValueByKey("locale", &c)
+		version, _ = bsonutil.FindValueByKey("version", &c)
+	case bson.M:
+		locale, version = c["locale"], c["version"]
+	default:
+		return
+	}
+	if locale == "simple" {
+		return
+	}
+	report.require(collationCapability, use)
+	if version != nil && version != supportedCollationVersion {
+		key := fmt.Sprint(version)
+		report.collationVersions[key] = append(report.collationVersions[key], use)
+	}
+}
+
+// check adds a problem for every collation version that the destination does
+// not support, and for every capability that the dump requires but a cluster
+// with the given feature and server versions does not support, listing their
+// uses.
+func (report *preflightReport) check(featureVersion, serverVersion db.Version) {
+	versions := make([]string, 0, len(report.collationVersions))
+	for v := range report.collationVersions {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	for _, v := range versions {
+		uses := report.collationVersions[v]
+		sort.Strings(uses)
+		report.problem(
+			"%v. The destination cluster only supports collation version %v. Remove the "+
+				"version from the collations in the dump, or use --noOptionsRestore and "+
+				"--noIndexRestore to restore without collations.",
+			foundUses("a collation with version "+v, "collations with version "+v, uses),
+			supportedCollationVersion,
+		)
+	}
+
+	for _, c := range capabilities {
+		uses := report.uses[c]
+		if len(uses) == 0 {
+			continue
+		}
+		unsupportedBy := c.unsupportedBy(featureVersion, serverVersion)
+		if unsupportedBy == "" {
+			continue
+		}
+		sort.Strings(uses)
+		report.problem(
+			"%v. %v are not supported by the destination cluster, whose %v. %v",
+			c.found(uses),
+			c.name,
+			unsupportedBy,
+			c.workaround,
+		)
+	}
+}
+
+// log logs the capability matrix and the problems found.
+func (report *preflightReport) log(target string, featureVersion, serverVersion db.Version) {
+	level := log.Info
+	if len(report.problems) > 0 {
+		level = log.Always
+	}
+	log.Logvf(level, "preflight checks against %v:", target)
+	for _, c := range capabilities {
+		uses := len(report.uses[c])
+		if uses == 0 {
+			continue
+		}
+		status := "ok"
+		if c.unsupportedBy(featureVersion, serverVersion) != "" {
+			status = "unsupported"
+		}
+		log.Logvf(
+			level,
+			"\t%v (requires %v): used by %v, %v",
+			c.name,
+			c.requirement(),
+			uses,
+			status,
+		)
+	}
+	for _, problem := range report.problems {
+		log.Logvf(level, "\tproblem: %v", problem)
+	}
+}
+
+// err returns a single error listing every problem, or nil if there are none.
+func (report *preflightReport) err() error {
+	if len(report.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf(
+		"%v preflight %v found, nothing was restored:\n\t- %v",
+		len(report.problems),
+		util.Pluralize(len(report.problems), "problem", "problems"),
+		strings.Join(report.problems, "\n\t- "),
+	)
+}
+
+// preFlightChecks compares what the dump requires of the destination cluster
+// with what the destination supports, and checks for existing collections that
+// would make the restore fail. It reports every problem at once, before
+// anything is written.
+func (restore *MongoRestore) preFlightChecks() error {
+	report := newPreflightReport()
+	allIntents := restore.manager.Intents()
+	report.addIntents(allIntents, restore.OutputOptions.NoOptionsRestore)
+	if !restore.OutputOptions.NoIndexRestore && restore.indexCatalog != nil {
+		report.addIndexes(restore.indexCatalog)
+		if policy := restore.OutputOptions.RemovedIndexPolicy; policy != "" &&
+			policy != removedIndexFail {
+			// the indexes are converted or dropped when they are restored
+			delete(report.uses, geoHaystackIndexCapability)
+		}
+	}
+
+	if !restore.OutputOptions.Drop {
+		for _, intent := range allIntents {
+			if intent.Type != "timeseries" {
+				continue
+			}
+			if err := restore.checkTimeseriesDestination(report, intent); err != nil {
+				return err
+			}
+		}
+	}
+
+	featureVersion, target := restore.destinationFeatureVersion()
+	report.check(featureVersion, restore.serverVersion)
+	report.log(target, featureVersion, restore.serverVersion)
+	return report.err()
+}
+
+// checkTimeseriesDestination adds a problem if the timeseries collection of
+// intent or its buckets collection already exists on the destination.
+func (restore *MongoRestore) checkTimeseriesDestination(
+	report *preflightReport,
+	intent *intents.Intent,
+) error {
+	timeseriesExists, err := restore.CollectionExists(intent.DB, intent.C)
+	if err != nil {
+		return err
+	}
+	if timeseriesExists {
+		report.problem(
+			"timeseries collection `%s` already exists on the destination. "+
+				"You must remove this collection from the destination or use --drop",
+			intent.Namespace(),
+		)
+	}
+
+	bucketExists, err := restore.CollectionExists(intent.DB, intent.DataCollection())
+	if err != nil {
+		return err
+	}
+	if bucketExists {
+		report.problem(
+			"system.buckets collection `%v` already exists on the destination. "+
+				"You must remove this collection from the destination in order to restore %s",
+			intent.DataNamespace(),
+			intent.Namespace(),
+		)
+	}
+	return nil
+}
+
+// destinationFeatureVersion returns the version whose features the destination
+// cluster supports, and a description of it for the preflight report. This is
+// its featureCompatibilityVersion when that is lower than its server version.
+func (restore *MongoRestore) destinationFeatureVersion() (db.Version, string) {
+	target := "server version " + formatVersion(restore.serverVersion)
+	fcv, err := restore.getFeatureCompatibilityVersion()
+	if err != nil {
+		log.Logvf(
+			log.DebugLow,
+			"could not get the featureCompatibilityVersion of the destination: %v",
+			err,
+		)
+		return restore.serverVersion, target
+	}
+	target += ", featureCompatibilityVersion " + formatVersion(fcv)
+	if fcv.LT(restore.serverVersion) {
+		return fcv, target
+	}
+	return restore.serverVersion, target
+}
+
+// getFeatureCompatibilityVersion returns the featureCompatibilityVersion of
+// the destination cluster.
+func (restore *MongoRestore) getFeatureCompatibilityVersion() (db.Version, error) {
+	var result struct {
+		FCV bson.RawValue `bson:"featureCompatibilityVersion"`
+	}
+	err := restore.SessionProvider.Run(bson.D{
+		{Key: "getParameter", Value: 1},
+		{Key: "featureCompatibilityVersion", Value: 1},
+	}, &result, "admin")
+	if err != nil {
+		return db.Version{}, err
+	}
+
+	var version string
+	switch result.FCV.Type {
+	case bson.TypeString:
+		version = result.FCV.StringValue()
+	case bson.TypeEmbeddedDocument:
+		value, err := result.FCV.Document().LookupErr("version")
+		if err != nil {
+			return db.Version{}, fmt.Errorf("featureCompatibilityVersion has no version")
+		}
+		version, _ = value.StringValueOK()
+	default:
+		return db.Version{}, fmt.Errorf("featureCompatibilityVersion not found")
+	}
+	if strings.Count(version, ".") == 1 {
+		version += ".0"
+	}
+	return db.StrToVersion(version)
+}
+
+func formatVersion(version db.Version) string {
+	return fmt.Sprintf("%d.%d.%d", version[0], version[1], version[2])
+}
@@ -0,0 +1,206 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/idx"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func preflightTestIndexes() *idx.IndexCatalog {
+	catalog := idx.NewIndexCatalog()
+	catalog.AddIndexes("test", "coll", []*idx.IndexDocument{
+		{Key: bson.D{{"_id", 1}}, Options: bson.M{"name": "_id_"}},
+		{Key: bson.D{{"$**", 1}}, Options: bson.M{"name": "$**_1"}},
+		{Key: bson.D{{"a", 1}, {"b.$**", 1}}, Options: bson.M{"name": "a_1_b.$**_1"}},
+		{Key: bson.D{{"$**", "columnstore"}}, Options: bson.M{"name": "$**_columnstore"}},
+		{Key: bson.D{{"pos", "geoHaystack"}, {"type", 1}}, Options: bson.M{"name": "geo"}},
+		{
+			Key: bson.D{{"c", 1}},
+			Options: bson.M{
+				"name":      "c_1",
+				"collation": bson.D{{"locale", "fr"}, {"version", "57.1"}},
+			},
+		},
+	})
+	catalog.SetCollation("test", "coll", true)
+	return catalog
+}
+
+func TestPreflightReport(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	allIntents := []*intents.Intent{
+		{DB: "test", C: "ts", Type: "timeseries"},
+		{DB: "test", C: "clustered", Options: bson.D{{"clusteredIndex", true}}},
+		{
+			DB:      "test",
+			C:       "cappedClustered",
+			Options: bson.D{{"capped", true}, {"clusteredIndex", true}},
+		},
+		{
+			DB:      "test",
+			C:       "oldCollation",
+			Options: bson.D{{"collation", bson.D{{"locale", "en"}, {"version", "57.0"}}}},
+		},
+		{DB: "test", C: "simple", Options: bson.D{{"collation", bson.D{{"locale", "simple"}}}}},
+	}
+
+	t.Run("capabilities are detected from options and indexes", func(t *testing.T) {
+		report := newPreflightReport()
+		report.addIntents(allIntents, false)
+		report.addIndexes(preflightTestIndexes())
+
+		assert.Equal(t, []string{"test.ts"}, report.uses[timeseriesCapability])
+		assert.Equal(t, []string{"test.clustered"}, report.uses[clusteredCapability])
+		assert.Equal(t, []string{"test.cappedClustered"}, report.uses[cappedClusteredCapability])
+		assert.Len(t, report.uses[collationCapability], 2)
+		assert.Len(t, report.uses[wildcardIndexCapability], 1)
+		assert.Len(t, report.uses[compoundWildcardIndexCapability], 1)
+		assert.Len(t, report.uses[columnstoreIndexCapability], 1)
+		assert.Len(t, report.uses[geoHaystackIndexCapability], 1)
+		assert.Equal(
+			t,
+			map[string][]string{"57.0": {"test.oldCollation"}},
+			report.collationVersions,
+		)
+
+		report.check(db.Version{8, 0, 0}, db.Version{8, 0, 0})
+		require.Len(t, report.problems, 2)
+		assert.Contains(t, report.problems[0], "found a collation with version 57.0: test.oldCollation")
+		assert.Contains(t, report.problems[1], "found a geoHaystack index")
+	})
+
+	t.Run("options are ignored with --noOptionsRestore", func(t *testing.T) {
+		report := newPreflightReport()
+		report.addIntents(append(allIntents, &intents.Intent{
+			DB:   "test",
+			C:    "another",
+			Type: "timeseries",
+		}), true)
+
+		assert.Len(t, report.uses, 1)
+		require.Len(t, report.problems, 1)
+		assert.Equal(
+			t,
+			"cannot specify --noOptionsRestore when restoring timeseries collections: "+
+				"test.another, test.ts",
+			report.problems[0],
+		)
+	})
+
+	t.Run("each unsupported collation version is reported once", func(t *testing.T) {
+		report := newPreflightReport()
+		report.addIntents(allIntents, false)
+		report.addIntents([]*intents.Intent{{
+			DB:      "other",
+			C:       "coll",
+			Options: bson.D{{"collation", bson.D{{"locale", "de"}, {"version", "57.0"}}}},
+		}}, false)
+		report.addCollation(bson.M{"locale": "en", "version": "55.0"}, "{a: 1} on test.coll")
+		report.check(db.Version{8, 0, 0}, db.Version{8, 0, 0})
+
+		require.Len(t, report.problems, 2)
+		assert.Contains(
+			t,
+			report.problems[0],
+			"found a collation with version 55.0: {a: 1} on test.coll",
+		)
+		assert.Contains(
+			t,
+			report.problems[1],
+			"found 2 collations with version 57.0: other.coll, test.oldCollation",
+		)
+	})
+
+	t.Run("every unsupported capability is reported at once", func(t *testing.T) {
+		report := newPreflightReport()
+		report.addIntents(allIntents[:3], false)
+		report.addIndexes(preflightTestIndexes())
+		report.check(db.Version{4, 4, 0}, db.Version{4, 4, 0})
+
+		assert.Len(t, report.problems, 5)
+		err := report.err()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "5 preflight problems found")
+		assert.Contains(t, err.Error(), "found a timeseries collection: test.ts")
+		assert.Contains(t, err.Error(), "found a compound wildcard index")
+		assert.Contains(t, err.Error(), "found a columnstore index")
+		assert.NotContains(t, err.Error(), "found a wildcard index")
+		assert.NotContains(t, err.Error(), "geoHaystack")
+	})
+
+	t.Run("geoHaystack indexes are reported on newer servers", func(t *testing.T) {
+		report := newPreflightReport()
+		report.addIndexes(preflightTestIndexes())
+		report.check(db.Version{7, 0, 0}, db.Version{7, 0, 0})
+
+		require.Len(t, report.problems, 1)
+		assert.Contains(t, report.problems[0], "found a geoHaystack index")
+	})
+
+	t.Run("geoHaystack indexes are reported on newer binaries at an older FCV", func(t *testing.T) {
+		report := newPreflightReport()
+		report.addIndexes(preflightTestIndexes())
+		report.check(db.Version{4, 4, 0}, db.Version{5, 0, 0})
+
+		require.Len(t, report.problems, 3)
+		assert.Contains(t, report.problems[2], "found a geoHaystack index")
+		assert.Contains(t, report.problems[2], "whose server version is 5.0.0")
+
+		report = newPreflightReport()
+		report.addIndexes(preflightTestIndexes())
+		report.check(db.Version{4, 4, 0}, db.Version{4, 4, 0})
+		for _, problem := range report.problems {
+			assert.NotContains(t, problem, "geoHaystack")
+		}
+	})
+
+	t.Run("supported capabilities are not problems", func(t *testing.T) {
+		report := newPreflightReport()
+		report.addIntents(allIntents[:3], false)
+		report.check(db.Version{8, 0, 0}, db.Version{8, 0, 0})
+
+		assert.NoError(t, report.err())
+	})
+
+	t.Run("change stream pre- and post-images need 6.0", func(t *testing.T) {
+		report := newPreflightReport()
+		report.addIntents([]*intents.Intent{{
+			DB:      "test",
+			C:       "audited",
+			Options: bson.D{{"changeStreamPreAndPostImages", bson.D{{"enabled", true}}}},
+		}}, false)
+		assert.Equal(t, []string{"test.audited"}, report.uses[changeStreamImagesCapability])
+
+		report.check(db.Version{6, 0, 0}, db.Version{6, 0, 0})
+		assert.NoError(t, report.err())
+		report.check(db.Version{5, 0, 0}, db.Version{5, 0, 0})
+		require.Len(t, report.problems, 1)
+		assert.Contains(
+			t,
+			report.problems[0],
+			"found a collection with change stream pre- and post-images: test.audited",
+		)
+	})
+
+	t.Run("long lists of uses are summarized", func(t *testing.T) {
+		uses := []string{"a", "b", "c", "d", "e", "f", "g"}
+		assert.Equal(
+			t,
+			"found 7 timeseries collections: a, b, c, d, e and 2 more",
+			timeseriesCapability.found(uses),
+		)
+	})
+}
@@ -0,0 +1,143 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"fmt"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/idx"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+)
+
+// Values of --removedIndexPolicy.
+const (
+	removedIndexFail    = "fail"
+	removedIndexConvert = "convert"
+	removedIndexDrop    = "drop"
+)
+
+// removedIndexFeature is an index type or option of old dumps that servers
+// deprecated or no longer accept.
+type removedIndexFeature struct {
+	name string
+	// removedIn, if set, is the first version that rejects the feature. The
+	// features without it are handled for every destination.
+	removedIn db.Version
+	usedBy    func(index *idx.IndexDocument) bool
+	// convert rewrites the index to the closest definition that servers
+	// accept, and describes what it changed.
+	convert func(index *idx.IndexDocument) string
+}
+
+var removedIndexFeatures = []removedIndexFeature{
+	{
+		name:      "the geoHaystack index type",
+		removedIn: db.Version{4, 9, 0},
+		usedBy: func(index *idx.IndexDocument) bool {
+			for _, key := range index.Key {
+				if key.Value == "geoHaystack" {
+					return true
+				}
+			}
+			return false
+		},
+		convert: func(index *idx.IndexDocument) string {
+			for i, key := range index.Key {
+				if key.Value == "geoHaystack" {
+					index.Key[i].Value = "2d"
+				}
+			}
+			delete(index.Options, "bucketSize")
+			return "converted the geoHaystack index to a 2d index"
+		},
+	},
+	{
+		name: "the dropDups option",
+		usedBy: func(index *idx.IndexDocument) bool {
+			_, ok := index.Options["dropDups"]
+			return ok
+		},
+		convert: func(index *idx.IndexDocument) string {
+			delete(index.Options, "dropDups")
+			return "removed the dropDups option, which servers ignore since 3.0"
+		},
+	},
+	{
+		name:    "version 1 text indexes",
+		usedBy:  optionEquals("textIndexVersion", 1),
+		convert: removeOption("textIndexVersion", "text"),
+	},
+	{
+		name:    "version 1 2dsphere indexes",
+		usedBy:  optionEquals("2dsphereIndexVersion", 1),
+		convert: removeOption("2dsphereIndexVersion", "2dsphere"),
+	},
+}
+
+func optionEquals(option string, value float64) func(index *idx.IndexDocument) bool {
+	return func(index *idx.IndexDocument) bool {
+		v, err := util.ToFloat64(index.Options[option])
+		return err == nil && v == value
+	}
+}
+
+func removeOption(option, indexType string) func(index *idx.IndexDocument) string {
+	return func(index *idx.IndexDocument) string {
+		delete(index.Options, option)
+		return fmt.Sprintf(
+			"removed %v so that the server builds the current %v index version",
+			option,
+			indexType,
+		)
+	}
+}
+
+// handleRemovedIndexFeatures converts or drops, as --removedIndexPolicy says,
+// the indexes of ns that use features the destination no longer supports.
+// Every change is logged and recorded for --reportFile. With the default
+// policy the indexes are left as they are, and creating them fails.
+func (restore *MongoRestore) handleRemovedIndexFeatures(
+	indexes []*idx.IndexDocument,
+	ns string,
+) []*idx.IndexDocument {
+	policy := restore.OutputOptions.RemovedIndexPolicy
+	if policy == "" || policy == removedIndexFail {
+		return indexes
+	}
+
+	var kept []*idx.IndexDocument
+	for _, index := range indexes {
+		var changes []string
+		dropped := false
+		for _, feature := range removedIndexFeatures {
+			if !restore.serverLacks(feature) || !feature.usedBy(index) {
+				continue
+			}
+			if policy == removedIndexDrop {
+				changes = append(changes, "dropped the index, which uses "+feature.name)
+				dropped = true
+				break
+			}
+			changes = append(changes, feature.convert(index))
+		}
+		for _, change := range changes {
+			log.Logvf(log.Always, "index %v on %v: %v", index.Options["name"], ns, change)
+			restore.stats.recordIndexChange(ns, fmt.Sprint(index.Options["name"]), change)
+		}
+		if !dropped {
+			kept = append(kept, index)
+		}
+	}
+	return kept
+}
+
+// serverLacks returns whether the destination does not support feature.
+func (restore *MongoRestore) serverLacks(feature removedIndexFeature) bool {
+	return feature.removedIn == (db.Version{}) || restore.serverVersion.GTE(feature.removedIn)
+}
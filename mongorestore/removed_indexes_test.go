@@ -0,0 +1,103 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/idx"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func removedFeatureIndexes() []*idx.IndexDocument {
+	return []*idx.IndexDocument{
+		{
+			Options: bson.M{"name": "pos_haystack", "v": 1, "bucketSize": 1.0},
+			Key:     bson.D{{"pos", "geoHaystack"}, {"type", 1}},
+		},
+		{
+			Options: bson.M{"name": "a_1", "v": 1, "unique": true, "dropDups": true},
+			Key:     bson.D{{"a", 1}},
+		},
+		{
+			Options: bson.M{"name": "body_text", "v": 1, "textIndexVersion": int32(1)},
+			Key:     bson.D{{"_fts", "text"}, {"_ftsx", 1}},
+		},
+		{
+			Options: bson.M{"name": "b_1", "v": 2},
+			Key:     bson.D{{"b", 1}},
+		},
+	}
+}
+
+func TestRemovedIndexPolicy(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	newRestore := func(policy string, version db.Version) *MongoRestore {
+		mr := newMongoRestore()
+		mr.OutputOptions = &OutputOptions{RemovedIndexPolicy: policy}
+		mr.serverVersion = version
+		return mr
+	}
+
+	t.Run("fail leaves the indexes as they are", func(t *testing.T) {
+		mr := newRestore(removedIndexFail, db.Version{7, 0, 0})
+		indexes := mr.handleRemovedIndexFeatures(removedFeatureIndexes(), "db.c")
+		assert.Equal(t, removedFeatureIndexes(), indexes)
+		assert.Empty(t, mr.stats.report(Result{}).IndexChanges)
+	})
+
+	t.Run("convert", func(t *testing.T) {
+		mr := newRestore(removedIndexConvert, db.Version{7, 0, 0})
+		indexes := mr.handleRemovedIndexFeatures(removedFeatureIndexes(), "db.c")
+		require.Len(t, indexes, 4)
+
+		assert.Equal(t, bson.D{{"pos", "2d"}, {"type", 1}}, indexes[0].Key)
+		assert.NotContains(t, indexes[0].Options, "bucketSize")
+		assert.NotContains(t, indexes[1].Options, "dropDups")
+		assert.Equal(t, true, indexes[1].Options["unique"])
+		assert.NotContains(t, indexes[2].Options, "textIndexVersion")
+		assert.Equal(t, removedFeatureIndexes()[3], indexes[3])
+
+		changes := mr.stats.report(Result{}).IndexChanges
+		require.Len(t, changes, 3)
+		assert.Equal(t, IndexChangeReport{
+			Namespace: "db.c",
+			Index:     "pos_haystack",
+			Change:    "converted the geoHaystack index to a 2d index",
+		}, changes[0])
+		assert.Equal(t, "a_1", changes[1].Index)
+		assert.Equal(t, "body_text", changes[2].Index)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		mr := newRestore(removedIndexDrop, db.Version{7, 0, 0})
+		indexes := mr.handleRemovedIndexFeatures(removedFeatureIndexes(), "db.c")
+		require.Len(t, indexes, 1)
+		assert.Equal(t, "b_1", indexes[0].Options["name"])
+
+		changes := mr.stats.report(Result{}).IndexChanges
+		require.Len(t, changes, 3)
+		assert.Equal(
+			t,
+			"dropped the index, which uses the geoHaystack index type",
+			changes[0].Change,
+		)
+	})
+
+	t.Run("geoHaystack indexes are kept for servers that support them", func(t *testing.T) {
+		mr := newRestore(removedIndexConvert, db.Version{4, 4, 0})
+		indexes := mr.handleRemovedIndexFeatures(removedFeatureIndexes(), "db.c")
+		require.Len(t, indexes, 4)
+		assert.Equal(t, bson.D{{"pos", "geoHaystack"}, {"type", 1}}, indexes[0].Key)
+		assert.Len(t, mr.stats.report(Result{}).IndexChanges, 2)
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mongodb/mongo-tools/mongorestore/ns"
+)
+
+// Finds the characters of a dump's name that are replaced in {{dumpId}}.
+var dumpIDReplacedRE = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// expandNSTo replaces the template variables of --nsTo, e.g. staging_{{date}}
+// becomes staging_20240101. {{date}} and {{time}} are the local date and time
+// the restore started as YYYYMMDD and HHMMSS, and {{dumpId}} is the name of
+// the dump directory, or of the archive file without .archive and .gz, with
+// the characters other than letters, digits, - and _ replaced by _.
+// {{source_db}} and {{source_collection}} are replaced for each namespace by
+// the renamer.
+func (restore *MongoRestore) expandNSTo(now time.Time) ([]string, error) {
+	vars := map[string]string{
+		"date": now.Format("20060102"),
+		"time": now.Format("150405"),
+	}
+	if dumpID := restore.dumpID(); dumpID != "" {
+		vars["dumpId"] = dumpID
+	}
+
+	expanded := make([]string, len(restore.NSOptions.NSTo))
+	for i, to := range restore.NSOptions.NSTo {
+		if _, ok := vars["dumpId"]; !ok && strings.Contains(to, "{{dumpId}}") {
+			return nil, fmt.Errorf("cannot use {{dumpId}} in %v when reading from stdin", NSToOption)
+		}
+		var err error
+		if expanded[i], err = ns.ExpandTemplate(to, vars); err != nil {
+			return nil, err
+		}
+	}
+	return expanded, nil
+}
+
+// dumpID returns the name of the dump being restored for {{dumpId}}, or ""
+// if it is read from stdin.
+func (restore *MongoRestore) dumpID() string {
+	path := restore.TargetDirectory
+	if restore.InputOptions != nil && restore.InputOptions.Archive != "" {
+		path = restore.InputOptions.Archive
+	}
+	switch path {
+	case "-":
+		return ""
+	case "":
+		path = "dump"
+	}
+	name := filepath.Base(filepath.Clean(path))
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".archive")
+	return dumpIDReplacedRE.ReplaceAllString(name, "_")
+}
@@ -0,0 +1,49 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandNSTo(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	now := time.Date(2024, 1, 1, 2, 30, 0, 0, time.Local)
+	restore := &MongoRestore{
+		TargetDirectory: "/backups/nightly-2024.01.01/",
+		InputOptions:    &InputOptions{},
+		NSOptions: &NSOptions{NSTo: []string{
+			"staging_{{date}}.*",
+			"{{dumpId}}_{{time}}.{{source_collection}}",
+		}},
+	}
+	nsTo, err := restore.expandNSTo(now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"staging_20240101.*",
+		"nightly-2024_01_01_023000.{{source_collection}}",
+	}, nsTo)
+
+	restore.InputOptions.Archive = "weekly.archive.gz"
+	nsTo, err = restore.expandNSTo(now)
+	require.NoError(t, err)
+	assert.Equal(t, "weekly_023000.{{source_collection}}", nsTo[1])
+
+	restore.InputOptions.Archive = "-"
+	_, err = restore.expandNSTo(now)
+	assert.Error(t, err, "{{dumpId}} needs a named dump")
+
+	restore.NSOptions.NSTo = []string{"staging_{{build}}.*"}
+	_, err = restore.expandNSTo(now)
+	assert.Error(t, err)
+}
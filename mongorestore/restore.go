@@ -17,11 +17,13 @@ import (
 
 	"github.com/mongodb/mongo-tools/common/bsonutil"
 	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/dumprestore"
 	"github.com/mongodb/mongo-tools/common/idx"
 	"github.com/mongodb/mongo-tools/common/intents"
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/options"
 	"github.com/mongodb/mongo-tools/common/progress"
+	"github.com/mongodb/mongo-tools/common/telemetry"
 	"github.com/mongodb/mongo-tools/common/util"
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
@@ -37,6 +39,19 @@ type Result struct {
 	Successes int64
 	Failures  int64
 	Err       error
+
+	// Incomplete maps each namespace that --tolerant could only partially
+	// restore from a damaged archive to the reason why.
+	Incomplete map[string]string
+	// SkippedBytes is the amount of corrupted archive data that --tolerant
+	// skipped.
+	SkippedBytes int64
+}
+
+// IsIncomplete returns true if --tolerant skipped over data of the archive,
+// so that the restore is only partial.
+func (result Result) IsIncomplete() bool {
+	return len(result.Incomplete) > 0 || result.SkippedBytes > 0
 }
 
 // log pretty-prints the result, associated with restoring the given namespace.
@@ -73,7 +88,7 @@ func NewResultFromBulkResult(result *mongo.BulkWriteResult, err error) Result {
 		nFailure = int64(len(bwe.WriteErrors))
 	}
 
-	return Result{nSuccess, nFailure, err}
+	return Result{Successes: nSuccess, Failures: nFailure, Err: err}
 }
 
 func (restore *MongoRestore) RestoreIndexes() error {
@@ -153,8 +168,17 @@ func (restore *MongoRestore) RestoreIndexesForNamespace(namespace *options.Names
 			err,
 		)
 	}
+	if len(indexes) > 0 {
+		if indexes = restore.builtIndexes.unbuilt(indexes); len(indexes) == 0 {
+			return nil
+		}
+	}
+	// the catalog's documents, before they are rewritten for the build
+	pending := slices.Clone(indexes)
 
 	if len(indexes) > 0 && !restore.OutputOptions.NoIndexRestore {
+		restore.reportLegacyIndexes(indexes, namespaceString)
+		restore.translateIndexes(indexes, namespaceString)
 		for _, index := range indexes {
 			if addedOpts := index.EnsureIndexVersions(); len(addedOpts) != 0 {
 				optNames := maps.Keys(addedOpts)
@@ -180,10 +204,20 @@ func (restore *MongoRestore) RestoreIndexesForNamespace(namespace *options.Names
 		if restore.OutputOptions.FixDottedHashedIndexes {
 			fixDottedHashedIndexes(indexes)
 		}
+		indexes = restore.handleRemovedIndexFeatures(indexes, namespaceString)
+		if len(indexes) == 0 {
+			return nil
+		}
 		for _, index := range indexes {
 			log.Logvf(log.Always, "index: %#v", index)
 		}
-		err = restore.CreateIndexes(namespace.DB, namespace.Collection, indexes)
+		err = restore.createIndexesWithPolicies(
+			namespaceString,
+			indexes,
+			func(indexes []*idx.IndexDocument) error {
+				return restore.CreateIndexes(namespace.DB, namespace.Collection, indexes)
+			},
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"%s: error creating indexes for %s: %v",
@@ -192,6 +226,8 @@ func (restore *MongoRestore) RestoreIndexesForNamespace(namespace *options.Names
 				err,
 			)
 		}
+		restore.builtIndexes.add(pending)
+		restore.cacheWarmer.indexed(namespaceString, indexes)
 	} else {
 		log.Logvf(log.Always, "no indexes to restore for collection %v", namespaceString)
 	}
@@ -312,12 +348,14 @@ func (restore *MongoRestore) RestoreIntents() Result {
 					}
 					result := restore.RestoreIntent(intent)
 					result.log(intent.Namespace())
+					restore.stats.recordRestored(intent.Namespace(), result)
 					workerResult.combineWith(result)
 					if result.Err != nil {
 						resultChan <- workerResult.withErr(fmt.Errorf("%v: %v", intent.Namespace(), result.Err))
 						return
 					}
 					restore.manager.Finish(intent)
+					restore.cacheWarmer.restored(intent.Namespace())
 					if fileNeedsIOBuffer, ok := intent.BSONFile.(intents.FileNeedsIOBuffer); ok {
 						fileNeedsIOBuffer.ReleaseIOBuffer()
 					}
@@ -347,23 +385,45 @@ func (restore *MongoRestore) RestoreIntents() Result {
 		}
 		result := restore.RestoreIntent(intent)
 		result.log(intent.Namespace())
+		restore.stats.recordRestored(intent.Namespace(), result)
 		totalResult.combineWith(result)
 		if result.Err != nil {
 			return totalResult.withErr(fmt.Errorf("%v: %v", intent.Namespace(), result.Err))
 		}
 		restore.manager.Finish(intent)
+		restore.cacheWarmer.restored(intent.Namespace())
 	}
 	return totalResult
 }
 
 // RestoreIntent attempts to restore a given intent into MongoDB.
 func (restore *MongoRestore) RestoreIntent(intent *intents.Intent) Result {
+	namespace := telemetry.String("db.namespace", intent.Namespace())
+	span := telemetry.Start("mongorestore.restoreCollection", namespace)
+	result := restore.restoreIntent(intent)
+	span.SetAttributes(
+		telemetry.Int("documents", result.Successes),
+		telemetry.Int("failures", result.Failures),
+	)
+	span.End(result.Err)
+	telemetry.Add("mongorestore.documents", result.Successes, namespace)
+	telemetry.Add("mongorestore.failures", result.Failures, namespace)
+	return result
+}
+
+func (restore *MongoRestore) restoreIntent(intent *intents.Intent) Result {
 	collectionExists, err := restore.CollectionExists(intent.DB, intent.C)
 	if err != nil {
 		return Result{Err: fmt.Errorf("error reading database: %v", err)}
 	}
 
-	if !restore.OutputOptions.Drop && collectionExists {
+	// a journaled restore that resumes doesn't drop the collections it
+	// started restoring, unless it restarts them
+	journal := restore.restoreJournal
+	drop := restore.OutputOptions.Drop && !journal.started(intent.DataNamespace()) ||
+		journal.restarts(intent.DataNamespace())
+
+	if !drop && collectionExists {
 		log.Logvf(
 			log.Always,
 			"restoring to existing collection %v without dropping",
@@ -371,9 +431,13 @@ func (restore *MongoRestore) RestoreIntent(intent *intents.Intent) Result {
 		)
 	}
 
-	if restore.OutputOptions.Drop {
+	if drop {
 		if collectionExists {
-			if strings.HasPrefix(intent.C, "system.") {
+			if restore.restoresSystemCollection(intent) {
+				if err = restore.emptySystemCollection(intent); err != nil {
+					return Result{Err: err}
+				}
+			} else if strings.HasPrefix(intent.C, "system.") {
 				log.Logvf(
 					log.Always,
 					"cannot drop system collection %v, skipping",
@@ -438,6 +502,33 @@ func (restore *MongoRestore) RestoreIntent(intent *intents.Intent) Result {
 		options = nil
 	}
 
+	toTimeseries := !intent.IsView() && restore.convertsToTimeseries(intent.DB, intent.C, intent.Type)
+	if toTimeseries {
+		options = restore.timeseriesCollectionOptions(intent, options)
+		logMessageSuffix = "as a timeseries collection"
+	}
+
+	if toTimeseries && collectionExists {
+		session, err := restore.SessionProvider.GetSession()
+		if err != nil {
+			return Result{Err: fmt.Errorf("error establishing connection: %v", err)}
+		}
+		info, err := db.GetCollectionInfo(session.Database(intent.DB).Collection(intent.C))
+		if err != nil {
+			return Result{
+				Err: fmt.Errorf("error reading the options of %v: %v", intent.Namespace(), err),
+			}
+		}
+		if info != nil && !info.IsTimeseries() {
+			return Result{Err: fmt.Errorf(
+				"cannot convert existing collection %v to a timeseries collection; "+
+					"use %v to recreate it",
+				intent.Namespace(),
+				DropOption,
+			)}
+		}
+	}
+
 	if !collectionExists {
 		log.Logvf(log.Info, "creating collection %v %s", intent.Namespace(), logMessageSuffix)
 		log.Logvf(log.DebugHigh, "using collection options: %#v", options)
@@ -450,6 +541,15 @@ func (restore *MongoRestore) RestoreIntent(intent *intents.Intent) Result {
 		restore.addToKnownCollections(intent)
 	} else {
 		log.Logvf(log.Info, "collection %v already exists - skipping collection create", intent.Namespace())
+		if err = restore.reapplyCollectionOptions(intent, options); err != nil {
+			return Result{Err: err}
+		}
+	}
+
+	if restore.OutputOptions.RestoreShardingConfig {
+		if err = restore.restoreShardingForIntent(intent); err != nil {
+			return Result{Err: err}
+		}
 	}
 
 	var result Result
@@ -477,6 +577,12 @@ func (restore *MongoRestore) RestoreIntent(intent *intents.Intent) Result {
 			result.Err = fmt.Errorf("error restoring from %v: %v", intent.Location, result.Err)
 			return result
 		}
+		if err = journal.complete(intent.DataNamespace()); err != nil {
+			return result.withErr(err)
+		}
+		if intent.C == dumprestore.SystemJS {
+			restore.logStoredFunctions(intent)
+		}
 	}
 
 	return result
@@ -568,9 +674,27 @@ func (restore *MongoRestore) RestoreCollectionToDB(
 	}
 
 	maxInsertWorkers := restore.OutputOptions.NumInsertionWorkers
+	namespace := dbName + "." + colName
+
+	// the journal counts the documents restored along with every one before
+	// them, which only one insertion worker knows
+	progress := restore.restoreJournal.progress(namespace)
+	if progress != nil && maxInsertWorkers > 1 {
+		log.Logvf(
+			log.DebugLow,
+			"restoring %v with one insertion worker for %v",
+			namespace,
+			RestoreJournalOption,
+		)
+		maxInsertWorkers = 1
+	}
+	skip := progress.skips()
 
 	docChan := make(chan bson.Raw, insertBufferFactor)
 	resultChan := make(chan Result, maxInsertWorkers)
+	remaps := restore.valueMapper.forNamespace(namespace)
+	toTimeseries := restore.convertsToTimeseries(dbName, colName, collectionType)
+	validation := restore.validationPolicy(namespace, collectionType)
 
 	// stream documents for this collection on docChan
 	go func() {
@@ -587,6 +711,12 @@ func (restore *MongoRestore) RestoreCollectionToDB(
 				return
 			}
 
+			// skip the documents a previous restore restored
+			if documentCount < skip {
+				documentCount++
+				continue
+			}
+
 			rawBytes := make([]byte, len(doc))
 			copy(rawBytes, doc)
 			docChan <- bson.Raw(rawBytes)
@@ -599,20 +729,35 @@ func (restore *MongoRestore) RestoreCollectionToDB(
 
 	var warnedAboutEmptyTimestamp atomic.Bool
 
+	// without --batchSize, batches are sized from the average document size
+	sizer := newBatchSizer(restore.OutputOptions.BulkBufferSize)
+
 	for i := 0; i < maxInsertWorkers; i++ {
 		go func() {
 			var result Result
 
 			bulk := db.NewUnorderedBufferedBulkInserter(
 				collection,
-				restore.OutputOptions.BulkBufferSize,
+				sizer.docLimit(),
 				restore.serverVersion,
 			).
 				SetOrdered(restore.OutputOptions.MaintainInsertionOrder)
 			if collectionType != "timeseries" {
-				bulk.SetBypassDocumentValidation(restore.OutputOptions.BypassDocumentValidation)
+				bulk.SetBypassDocumentValidation(
+					validation == validationBypass || validation == validationReport,
+				)
+			}
+			bulk.SetReplaceByID(
+				progress.replaces() ||
+					restore.OutputOptions.OnDuplicateKey == duplicateKeyOverwrite,
+			)
+			if restore.OutputOptions.OnDuplicateKey == duplicateKeyOverwrite {
+				bulk.SetOnReplace(func(ids []bson.RawValue) {
+					restore.recordOverwrites(namespace, ids)
+				})
 			}
 			for rawDoc := range docChan {
+				progress.take()
 				if restore.objCheck {
 					result.Err = bson.Unmarshal(rawDoc, &bson.D{})
 					if result.Err != nil {
@@ -621,6 +766,32 @@ func (restore *MongoRestore) RestoreCollectionToDB(
 					}
 				}
 
+				// a document whose values can't be remapped is not restored
+				// with the values it had
+				var err error
+				if len(remaps) > 0 {
+					rawDoc, err = remapRaw(rawDoc, remaps)
+					if err != nil {
+						err = fmt.Errorf("document whose values could not be remapped: %v", err)
+					}
+				}
+				if err == nil {
+					err = restore.docValidator.Validate(rawDoc)
+				}
+				if err == nil && toTimeseries {
+					err = restore.checkTimeField(rawDoc)
+				}
+				if err != nil {
+					result.Failures++
+					if restore.OutputOptions.StopOnError {
+						resultChan <- result.withErr(err)
+						return
+					}
+					log.Logvf(log.Always, "skipping %v", err)
+					progress.handled(bulk.Buffered(), false)
+					continue
+				}
+
 				needsSpecialZeroTimestampHandling := false
 				if !bulk.CanDoZeroTimestamp() {
 					emptyTsFields, err := FindZeroTimestamps(rawDoc)
@@ -648,33 +819,41 @@ func (restore *MongoRestore) RestoreCollectionToDB(
 							)
 						}
 
+						restore.lagThrottle.acquire()
 						err := insertDocWithEmptyTimestamps(
 							context.Background(),
 							collection,
 							rawDoc,
 						)
+						restore.lagThrottle.release()
 
 						if err != nil {
-							newResult = Result{0, 1, err}
+							newResult = Result{Failures: 1, Err: err}
 						} else {
-							newResult = Result{1, 0, nil}
+							newResult = Result{Successes: 1}
 						}
 					} else {
+						bulk.SetDocLimit(sizer.observe(len(rawDoc)))
+						restore.lagThrottle.acquire()
 						newResult = NewResultFromBulkResult(bulk.InsertRaw(rawDoc))
+						restore.lagThrottle.release()
 					}
 
-					result.combineWith(newResult)
-					result.Err = db.FilterError(restore.OutputOptions.StopOnError, result.Err)
+					result.combineWith(restore.resolveDuplicateKeys(collection, namespace, newResult))
+					result.Err = restore.filterInsertError(namespace, validation, result.Err)
 				}
 
 				if result.Err != nil {
 					resultChan <- result
 					return
 				}
+				progress.handled(bulk.Buffered(), !needsSpecialZeroTimestampHandling)
 				watchProgressor.Set(file.Pos())
 			}
 			// flush the remaining docs
+			restore.lagThrottle.acquire()
 			bwResult, bwErr := bulk.TryFlush()
+			restore.lagThrottle.release()
 			defer bulk.ResetBulk()
 
 			if db.TimeseriesBucketNeedsMixedSchema(bwErr) {
@@ -689,10 +868,20 @@ func (restore *MongoRestore) RestoreCollectionToDB(
 					resultChan <- result.withErr(errors.Wrap(collModErr, "failed to enable mixed schema in a timeseries bucket"))
 					return
 				}
+				restore.lagThrottle.acquire()
 				bwResult, bwErr = bulk.TryFlush()
+				restore.lagThrottle.release()
+			}
+			result.combineWith(restore.resolveDuplicateKeys(
+				collection,
+				namespace,
+				NewResultFromBulkResult(bwResult, bwErr),
+			))
+			result.Err = restore.filterInsertError(namespace, validation, result.Err)
+			if result.Err == nil {
+				progress.handled(0, false)
 			}
-			result.combineWith(NewResultFromBulkResult(bwResult, bwErr))
-			resultChan <- result.withErr(db.FilterError(restore.OutputOptions.StopOnError, result.Err))
+			resultChan <- result
 			return
 		}()
 
@@ -712,12 +901,23 @@ func (restore *MongoRestore) RestoreCollectionToDB(
 		}
 	}
 
+	log.Logvf(
+		log.DebugLow,
+		"restored %v.%v with an average document size of %v bytes in batches of up to %v documents",
+		dbName,
+		colName,
+		sizer.averageDocSize(),
+		sizer.docLimit(),
+	)
+
 	if finalErr != nil {
 		totalResult.Err = finalErr
 	} else if err = bsonSource.Err(); err != nil {
 		totalResult.Err = fmt.Errorf("reading bson input: %v", err)
 	} else if termErr != nil {
 		totalResult.Err = termErr
+	} else if validation == validationReport {
+		totalResult.Err = restore.reportInvalidDocuments(collection)
 	}
 	return totalResult
 }
@@ -0,0 +1,400 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/archive"
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// restoreJournalSaveInterval is how often the journal is saved while the
+// documents of collections are restored. It is also saved when a collection
+// is completely restored, and when the restore of the collections ends.
+const restoreJournalSaveInterval = time.Second
+
+// Policies of --partialCollectionPolicy.
+const (
+	partialCollectionUpsert = "upsert"
+	partialCollectionDrop   = "drop"
+)
+
+// restoreJournalState is the content of a --restoreJournal file.
+type restoreJournalState struct {
+	// Archive is the --archive the journal belongs to.
+	Archive     string                `bson:"archive"`
+	Collections []journaledCollection `bson:"collections"`
+}
+
+// journaledCollection is how far the restore of the documents of a namespace
+// of the archive got.
+type journaledCollection struct {
+	Namespace string `bson:"ns"`
+	// Restored is the number of documents of the namespace, in the order of
+	// the archive, that are restored along with every document before them.
+	Restored int64 `bson:"restored"`
+	// Complete is set once every document of the namespace is restored.
+	Complete bool `bson:"complete"`
+}
+
+// restoreJournal records how many documents of each namespace of an archive
+// with the contiguous layout were restored, so that a restore that is
+// interrupted can be run again to skip the namespaces that were completely
+// restored, by seeking past them, and the documents of the others up to
+// where their restore got.
+//
+// The documents restored after the journal was last saved are restored
+// again, which --partialCollectionPolicy makes harmless either by replacing
+// the documents of partially restored collections by _id, or by dropping
+// them and restoring them from the start.
+type restoreJournal struct {
+	path    string
+	archive string
+	policy  string
+
+	// previous holds what the journal held when the restore started.
+	previous map[string]journaledCollection
+
+	mu          sync.Mutex
+	collections map[string]*journaledCollection
+	unsaved     bool
+	lastSave    time.Time
+}
+
+// openRestoreJournal reads the journal at path of the restore of the archive
+// called archiveName. A missing file is a journal of a restore that hasn't
+// started.
+func openRestoreJournal(path, archiveName, policy string) (*restoreJournal, error) {
+	journal := &restoreJournal{
+		path:        path,
+		archive:     archiveName,
+		policy:      policy,
+		previous:    map[string]journaledCollection{},
+		collections: map[string]*journaledCollection{},
+		lastSave:    time.Now(),
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return journal, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %v: %v", RestoreJournalOption, err)
+	}
+	state := restoreJournalState{}
+	if err := bson.UnmarshalExtJSON(content, true, &state); err != nil {
+		return nil, fmt.Errorf("error parsing %v %#q: %v", RestoreJournalOption, path, err)
+	}
+	if state.Archive != archiveName {
+		log.Logvf(
+			log.Always,
+			"warning: %v %#q was saved for the archive %v, not %v",
+			RestoreJournalOption,
+			path,
+			state.Archive,
+			archiveName,
+		)
+	}
+	for _, collection := range state.Collections {
+		if collection.Restored < 0 {
+			return nil, fmt.Errorf(
+				"%v %#q has an invalid count for %v",
+				RestoreJournalOption,
+				path,
+				collection.Namespace,
+			)
+		}
+		journal.previous[collection.Namespace] = collection
+		saved := collection
+		journal.collections[collection.Namespace] = &saved
+	}
+	return journal, nil
+}
+
+// openRestoreJournal opens the --restoreJournal, once the prelude of the
+// archive is read.
+func (restore *MongoRestore) openRestoreJournal() error {
+	if layout := restore.archive.Prelude.Header.Layout; layout != archive.ContiguousLayout {
+		return fmt.Errorf(
+			"cannot use %v with an archive that does not have the %v layout; "+
+				"write it with mongodump --archiveLayout=%v",
+			RestoreJournalOption,
+			archive.ContiguousLayout,
+			archive.ContiguousLayout,
+		)
+	}
+	journal, err := openRestoreJournal(
+		restore.InputOptions.RestoreJournal,
+		restore.InputOptions.Archive,
+		restore.InputOptions.PartialCollectionPolicy,
+	)
+	if err != nil {
+		return err
+	}
+	journal.logResume()
+	restore.restoreJournal = journal
+	return nil
+}
+
+// validateRestoreJournal checks the options of a journaled restore.
+func (restore *MongoRestore) validateRestoreJournal() error {
+	input := restore.InputOptions
+	if input.RestoreJournal == "" {
+		return nil
+	}
+	if input.Archive == "" || input.Archive == "-" || archive.IsStreamEndpoint(input.Archive) {
+		return fmt.Errorf("%v requires %v with a file", RestoreJournalOption, ArchiveOption)
+	}
+	if input.Tolerant {
+		return fmt.Errorf("cannot use %v with %v", RestoreJournalOption, TolerantOption)
+	}
+	if restore.OutputOptions.Compare {
+		return fmt.Errorf("cannot use %v with %v", RestoreJournalOption, CompareOption)
+	}
+	return nil
+}
+
+// logResume logs what a restore skips because a previous restore with the
+// same journal restored it.
+func (journal *restoreJournal) logResume() {
+	var complete, partial int
+	for _, collection := range journal.previous {
+		if collection.Complete {
+			complete++
+		} else {
+			partial++
+		}
+	}
+	if complete == 0 && partial == 0 {
+		return
+	}
+	log.Logvf(
+		log.Always,
+		"resuming the restore from %v %#q: skipping %v completely restored collections, "+
+			"and the documents restored from %v partially restored collections",
+		RestoreJournalOption,
+		journal.path,
+		complete,
+		partial,
+	)
+}
+
+// completed returns whether a previous restore completely restored the
+// documents of ns.
+func (journal *restoreJournal) completed(ns string) bool {
+	if journal == nil {
+		return false
+	}
+	return journal.previous[ns].Complete
+}
+
+// started returns whether a previous restore started restoring ns, which a
+// journaled restore then doesn't drop for --drop.
+func (journal *restoreJournal) started(ns string) bool {
+	if journal == nil {
+		return false
+	}
+	_, ok := journal.previous[ns]
+	return ok
+}
+
+// restarts returns whether ns was partially restored by a previous restore,
+// and is dropped to be restored from the start.
+func (journal *restoreJournal) restarts(ns string) bool {
+	if journal == nil || journal.policy != partialCollectionDrop {
+		return false
+	}
+	previous, ok := journal.previous[ns]
+	return ok && !previous.Complete
+}
+
+// progress returns the collectionProgress of the documents of ns, or nil
+// without a journal.
+func (journal *restoreJournal) progress(ns string) *collectionProgress {
+	if journal == nil {
+		return nil
+	}
+	progress := &collectionProgress{journal: journal, ns: ns}
+	previous, ok := journal.previous[ns]
+	switch {
+	case !ok:
+	case journal.policy == partialCollectionDrop:
+		journal.restored(ns, 0)
+	case journal.policy == partialCollectionUpsert:
+		progress.skipped = previous.Restored
+		progress.received = previous.Restored
+		progress.pending = previous.Restored
+		progress.replace = true
+		log.Logvf(
+			log.Always,
+			"skipping the first %v documents of %v, restored by a previous restore, "+
+				"and replacing the others by _id",
+			previous.Restored,
+			ns,
+		)
+	}
+	return progress
+}
+
+// restored records that the first n documents of ns are restored, and saves
+// the journal if it wasn't saved for a while.
+func (journal *restoreJournal) restored(ns string, n int64) {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	collection, ok := journal.collections[ns]
+	if !ok {
+		collection = &journaledCollection{Namespace: ns}
+		journal.collections[ns] = collection
+	}
+	if ok && collection.Restored == n {
+		return
+	}
+	collection.Restored = n
+	journal.unsaved = true
+	if time.Since(journal.lastSave) >= restoreJournalSaveInterval {
+		if err := journal.saveLocked(); err != nil {
+			log.Logvf(log.Always, "warning: %v", err)
+		}
+	}
+}
+
+// complete records that every document of ns is restored, and saves the
+// journal.
+func (journal *restoreJournal) complete(ns string) error {
+	if journal == nil {
+		return nil
+	}
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	collection, ok := journal.collections[ns]
+	if !ok {
+		collection = &journaledCollection{Namespace: ns}
+		journal.collections[ns] = collection
+	}
+	collection.Complete = true
+	journal.unsaved = true
+	return journal.saveLocked()
+}
+
+// save saves the journal if it changed since it was last saved.
+func (journal *restoreJournal) save() error {
+	if journal == nil {
+		return nil
+	}
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	return journal.saveLocked()
+}
+
+// saveLocked atomically replaces the journal file, by writing a temporary
+// file in the same directory and renaming it over the journal.
+func (journal *restoreJournal) saveLocked() error {
+	if !journal.unsaved {
+		return nil
+	}
+	state := restoreJournalState{Archive: journal.archive}
+	for _, collection := range journal.collections {
+		state.Collections = append(state.Collections, *collection)
+	}
+	sort.Slice(state.Collections, func(i, j int) bool {
+		return state.Collections[i].Namespace < state.Collections[j].Namespace
+	})
+	content, err := bson.MarshalExtJSON(state, true, false)
+	if err != nil {
+		return fmt.Errorf("error encoding %v: %v", RestoreJournalOption, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(journal.path), filepath.Base(journal.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("error writing %v: %v", RestoreJournalOption, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(content, '\n'))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), journal.path)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing %v: %v", RestoreJournalOption, err)
+	}
+	journal.unsaved = false
+	journal.lastSave = time.Now()
+	log.Logvf(log.DebugHigh, "saved %v %#q", RestoreJournalOption, journal.path)
+	return nil
+}
+
+// collectionProgress follows the single insertion worker of a journaled
+// collection, to record how many of its documents are restored along with
+// every document before them. Its methods do nothing on a nil
+// collectionProgress.
+type collectionProgress struct {
+	journal *restoreJournal
+	ns      string
+
+	// skipped is the number of documents at the start of the collection that
+	// a previous restore restored.
+	skipped int64
+	// replace is set for collections that were partially restored, whose
+	// documents are replaced by _id.
+	replace bool
+
+	// received is the number of documents of the collection that the worker
+	// received, including the skipped ones.
+	received int64
+	// pending is the number of documents before the first one that waits
+	// for the next bulk write.
+	pending int64
+}
+
+// skips returns the number of documents to skip.
+func (progress *collectionProgress) skips() int64 {
+	if progress == nil {
+		return 0
+	}
+	return progress.skipped
+}
+
+// replaces returns whether the documents are replaced by _id.
+func (progress *collectionProgress) replaces() bool {
+	return progress != nil && progress.replace
+}
+
+// take records that the worker received a document.
+func (progress *collectionProgress) take() {
+	if progress != nil {
+		progress.received++
+	}
+}
+
+// handled records that the worker handled the last document it received,
+// after which buffered documents wait for the next bulk write. added is set
+// if that document was added to them.
+func (progress *collectionProgress) handled(buffered int, added bool) {
+	if progress == nil {
+		return
+	}
+	switch {
+	case buffered == 0:
+		progress.pending = progress.received
+	case added && buffered == 1:
+		progress.pending = progress.received - 1
+	}
+	progress.journal.restored(progress.ns, progress.pending)
+}
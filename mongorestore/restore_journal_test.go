@@ -0,0 +1,129 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreJournal(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	path := filepath.Join(t.TempDir(), "journal.json")
+	journal, err := openRestoreJournal(path, "dump.archive", partialCollectionUpsert)
+	require.NoError(t, err)
+	assert.False(t, journal.started("shop.orders"))
+	assert.Zero(t, journal.progress("shop.orders").skips())
+	assert.False(t, journal.progress("shop.orders").replaces())
+
+	journal.restored("shop.orders", 1500)
+	journal.restored("shop.customers", 20)
+	require.NoError(t, journal.complete("shop.customers"))
+	require.NoError(t, journal.save())
+
+	for _, policy := range []string{partialCollectionUpsert, partialCollectionDrop} {
+		resumed, err := openRestoreJournal(path, "dump.archive", policy)
+		require.NoError(t, err)
+		assert.True(t, resumed.started("shop.orders"), policy)
+		assert.False(t, resumed.completed("shop.orders"), policy)
+		assert.True(t, resumed.completed("shop.customers"), policy)
+		assert.False(t, resumed.restarts("shop.customers"), policy)
+		assert.False(t, resumed.started("shop.items"), policy)
+
+		progress := resumed.progress("shop.orders")
+		if policy == partialCollectionUpsert {
+			assert.False(t, resumed.restarts("shop.orders"))
+			assert.EqualValues(t, 1500, progress.skips())
+			assert.True(t, progress.replaces())
+		} else {
+			assert.True(t, resumed.restarts("shop.orders"))
+			assert.Zero(t, progress.skips())
+			assert.False(t, progress.replaces())
+			assert.Zero(t, resumed.collections["shop.orders"].Restored)
+		}
+	}
+
+	var missing *restoreJournal
+	assert.False(t, missing.completed("shop.orders"))
+	assert.Nil(t, missing.progress("shop.orders"))
+	assert.NoError(t, missing.complete("shop.orders"))
+}
+
+func TestCollectionProgress(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	journal, err := openRestoreJournal(
+		filepath.Join(t.TempDir(), "journal.json"),
+		"dump.archive",
+		partialCollectionUpsert,
+	)
+	require.NoError(t, err)
+	progress := journal.progress("shop.orders")
+	restored := func() int64 {
+		return journal.collections["shop.orders"].Restored
+	}
+
+	// three documents are buffered, and flushed with the third
+	for buffered := 1; buffered <= 2; buffered++ {
+		progress.take()
+		progress.handled(buffered, true)
+		assert.Zero(t, restored())
+	}
+	progress.take()
+	progress.handled(0, true)
+	assert.EqualValues(t, 3, restored())
+
+	// the fifth document is inserted on its own while the fourth is buffered
+	progress.take()
+	progress.handled(1, true)
+	progress.take()
+	progress.handled(1, false)
+	assert.EqualValues(t, 3, restored())
+
+	// the sixth fails validation, and the final flush writes the fourth
+	progress.take()
+	progress.handled(1, false)
+	assert.EqualValues(t, 3, restored())
+	progress.handled(0, false)
+	assert.EqualValues(t, 6, restored())
+
+	var missing *collectionProgress
+	missing.take()
+	missing.handled(0, false)
+	assert.Zero(t, missing.skips())
+}
+
+func TestValidateRestoreJournal(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	archivePath := filepath.Join(t.TempDir(), "dump.archive")
+	require.NoError(t, os.WriteFile(archivePath, nil, 0o600))
+
+	restore := &MongoRestore{
+		InputOptions:  &InputOptions{Archive: archivePath, RestoreJournal: "journal.json"},
+		OutputOptions: &OutputOptions{},
+	}
+	assert.NoError(t, restore.validateRestoreJournal())
+
+	for _, input := range []InputOptions{
+		{RestoreJournal: "journal.json"},
+		{RestoreJournal: "journal.json", Archive: "-"},
+		{RestoreJournal: "journal.json", Archive: archivePath, Tolerant: true},
+	} {
+		restore := &MongoRestore{InputOptions: &input, OutputOptions: &OutputOptions{}}
+		assert.Error(t, restore.validateRestoreJournal(), "%+v", input)
+	}
+
+	restore.OutputOptions.Compare = true
+	assert.Error(t, restore.validateRestoreJournal())
+}
@@ -0,0 +1,652 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/password"
+	"github.com/mongodb/mongo-tools/common/progress"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+// JobState is the state of a restore job of --serve.
+type JobState string
+
+// States of a restore job. A job is queued until the jobs before it are done,
+// and ends either succeeded, incomplete, failed or canceled. An incomplete job
+// is a --tolerant restore that skipped over damaged parts of its archive.
+const (
+	JobQueued     JobState = "queued"
+	JobRunning    JobState = "running"
+	JobSucceeded  JobState = "succeeded"
+	JobIncomplete JobState = "incomplete"
+	JobFailed     JobState = "failed"
+	JobCanceled   JobState = "canceled"
+)
+
+// JobRequest is the body of a POST /jobs request. Args are the command line
+// arguments of the restore, as they would be given to mongorestore, e.g.
+// ["--uri=mongodb://localhost:27017", "--drop", "/backups/dump"].
+type JobRequest struct {
+	Args []string `json:"args"`
+}
+
+// JobStatus is what the API returns about a job. The arguments of the job are
+// not returned, since they may hold credentials.
+type JobStatus struct {
+	ID        string     `json:"id"`
+	State     JobState   `json:"state"`
+	Submitted time.Time  `json:"submitted"`
+	Started   *time.Time `json:"started,omitempty"`
+	Finished  *time.Time `json:"finished,omitempty"`
+	// Progress holds the collections being restored by a running job.
+	Progress []NamespaceProgress `json:"progress,omitempty"`
+	// Report is the report of a finished job, as written by --reportFile.
+	Report *Report `json:"report,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// NamespaceProgress is how far a running job is in restoring a collection,
+// or in replaying the oplog.
+type NamespaceProgress struct {
+	Name  string `json:"name"`
+	Done  int64  `json:"done"`
+	Total int64  `json:"total"`
+}
+
+// restoreJob is a job submitted to a JobServer. Its fields are guarded by
+// the mutex of the server.
+type restoreJob struct {
+	status    JobStatus
+	args      []string
+	progress  *jobProgress
+	interrupt func()
+	canceled  bool
+}
+
+// JobServer runs the restore jobs submitted to its HTTP API one at a time, in
+// the order they are submitted. Jobs don't run concurrently since a restore
+// configures state of the process, like the verbosity of the log.
+//
+// The API is:
+//
+//	POST   /jobs       submits a job, with a JobRequest body
+//	GET    /jobs       lists the jobs, in the order they were submitted
+//	GET    /jobs/{id}  returns the JobStatus of a job
+//	DELETE /jobs/{id}  cancels a job; a running job is interrupted like a
+//	                   mongorestore receiving SIGINT
+//
+// Every request must give the token of the server as a bearer token. Jobs
+// cannot use jobForbiddenOptions.
+type JobServer struct {
+	versionStr  string
+	gitCommit   string
+	token       string
+	allowRemote bool
+	// run runs a job and returns its report.
+	run func(job *restoreJob) (*Report, error)
+
+	mu      sync.Mutex
+	jobs    map[string]*restoreJob
+	order   []string
+	pending []*restoreJob
+	current *restoreJob
+	nextID  int
+	closed  bool
+	wake    chan struct{}
+}
+
+// jobForbiddenOptions are the options that jobs cannot use, since they make
+// the server read its environment, read or write other files than the dump,
+// run commands, or listen on or send data to an address.
+var jobForbiddenOptions = []string{
+	"config",
+	"otelEndpoint",
+	"passwordFile",
+	"passwordCommand",
+	"passwordEnv",
+	"sslCAFile",
+	"sslPEMKeyFile",
+	"sslPEMKeyPasswordFile",
+	"sslPEMKeyPasswordCommand",
+	"sslPEMKeyPasswordEnv",
+	"sslCRLFile",
+	"sslHostsFile",
+	"tempDir",
+	"archivePrefetchDir",
+	"reportFile",
+	"oplogFile",
+	"oplogJournal",
+	"restoreJournal",
+	"indexTranslationFile",
+	"valueMapFile",
+	"shardingConfigFile",
+	"oplogFollow",
+	"cutoverAddress",
+	"cutover",
+	"cutoverTokenFile",
+	"serve",
+	"serveTokenFile",
+	"serveAllowRemote",
+}
+
+// NewJobServer returns the JobServer of the --serve options of opts, whose
+// jobs report the version of opts as the version of mongorestore.
+func NewJobServer(opts Options) (*JobServer, error) {
+	if opts.ServeTokenFile == "" {
+		return nil, fmt.Errorf("%v requires %v", ServeOption, ServeTokenFileOption)
+	}
+	token, err := password.ReadSecretFile(util.ToUniversalPath(opts.ServeTokenFile))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %v: %v", ServeTokenFileOption, err)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("%v %v is empty", ServeTokenFileOption, opts.ServeTokenFile)
+	}
+	s := newJobServer(opts.VersionStr, opts.GitCommit, token)
+	s.allowRemote = opts.ServeAllowRemote
+	return s, nil
+}
+
+func newJobServer(versionStr, gitCommit, token string) *JobServer {
+	s := &JobServer{
+		versionStr: versionStr,
+		gitCommit:  gitCommit,
+		token:      token,
+		jobs:       map[string]*restoreJob{},
+		wake:       make(chan struct{}, 1),
+	}
+	s.run = s.restore
+	return s
+}
+
+// ListenAndServe serves the API on addr and runs the submitted jobs, until
+// Shutdown is called and the running job is done.
+func (s *JobServer) ListenAndServe(addr string) error {
+	listener, err := listenHTTP(addr, s.allowRemote)
+	if err != nil {
+		return fmt.Errorf("cannot serve on %v: %v", addr, err)
+	}
+	server := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	worked := make(chan struct{})
+	go func() {
+		s.Work()
+		close(worked)
+	}()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	log.Logvf(log.Always, "serving restore jobs on http://%v", listener.Addr())
+
+	select {
+	case err := <-served:
+		s.Shutdown()
+		<-worked
+		return err
+	case <-worked:
+	}
+	return server.Shutdown(context.Background())
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *JobServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.handleSubmit)
+	mux.HandleFunc("GET /jobs", s.handleList)
+	mux.HandleFunc("GET /jobs/{id}", s.handleGet)
+	mux.HandleFunc("DELETE /jobs/{id}", s.handleCancel)
+	return bearerAuth(s.token, mux)
+}
+
+// Work runs the queued jobs until Shutdown is called and the running job is
+// done.
+func (s *JobServer) Work() {
+	for {
+		job, ok := s.next()
+		if !ok {
+			return
+		}
+		if job == nil {
+			<-s.wake
+			continue
+		}
+		report, err := s.run(job)
+		s.finish(job, report, err)
+	}
+}
+
+// Shutdown stops taking jobs, cancels the queued ones and interrupts the
+// running one.
+func (s *JobServer) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for _, job := range s.pending {
+		s.cancelQueued(job)
+	}
+	s.pending = nil
+	if s.current != nil {
+		s.interruptRunning(s.current)
+	}
+	s.notify()
+}
+
+// Submit queues a job with the command line arguments args.
+func (s *JobServer) Submit(args []string) (JobStatus, error) {
+	if err := s.checkJobArgs(args); err != nil {
+		return JobStatus{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return JobStatus{}, fmt.Errorf("the server is shutting down")
+	}
+	s.nextID++
+	job := &restoreJob{
+		status: JobStatus{
+			ID:        strconv.Itoa(s.nextID),
+			State:     JobQueued,
+			Submitted: time.Now().UTC(),
+		},
+		args:     append([]string(nil), args...),
+		progress: &jobProgress{progressors: map[string]progress.Progressor{}},
+	}
+	s.jobs[job.status.ID] = job
+	s.order = append(s.order, job.status.ID)
+	s.pending = append(s.pending, job)
+	s.notify()
+	log.Logvf(log.Info, "queued restore job %v", job.status.ID)
+	return job.status, nil
+}
+
+// Status returns the status of the job with the given id.
+func (s *JobServer) Status(id string) (JobStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return JobStatus{}, false
+	}
+	return job.snapshot(), true
+}
+
+// Cancel cancels the job with the given id. A queued job is removed from the
+// queue, and a running one is interrupted; it is canceled once its restore
+// returns. It is an error to cancel a job that is done.
+func (s *JobServer) Cancel(id string) (JobStatus, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return JobStatus{}, false, nil
+	}
+	switch job.status.State {
+	case JobQueued:
+		for i, pending := range s.pending {
+			if pending == job {
+				s.pending = append(s.pending[:i], s.pending[i+1:]...)
+				break
+			}
+		}
+		s.cancelQueued(job)
+	case JobRunning:
+		s.interruptRunning(job)
+	default:
+		return job.snapshot(), true, fmt.Errorf("job %v is already %v", id, job.status.State)
+	}
+	return job.snapshot(), true, nil
+}
+
+// next starts the next queued job and returns it, or returns nil if there is
+// none. It returns false once the server is shut down.
+func (s *JobServer) next() (*restoreJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, false
+	}
+	if len(s.pending) == 0 {
+		return nil, true
+	}
+	job := s.pending[0]
+	s.pending = s.pending[1:]
+	started := time.Now().UTC()
+	job.status.State = JobRunning
+	job.status.Started = &started
+	s.current = job
+	log.Logvf(log.Info, "starting restore job %v", job.status.ID)
+	return job, true
+}
+
+func (s *JobServer) finish(job *restoreJob, report *Report, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := time.Now().UTC()
+	job.status.Finished = &finished
+	job.status.Report = report
+	job.interrupt = nil
+	switch {
+	case job.canceled:
+		job.status.State = JobCanceled
+	case err != nil:
+		job.status.State = JobFailed
+	case report != nil && report.isIncomplete():
+		job.status.State = JobIncomplete
+	default:
+		job.status.State = JobSucceeded
+	}
+	if err != nil {
+		job.status.Error = err.Error()
+	}
+	s.current = nil
+	log.Logvf(log.Info, "restore job %v %v", job.status.ID, job.status.State)
+}
+
+// setInterrupt records how to interrupt the running job, or calls interrupt
+// right away if the job was canceled while it was starting.
+func (s *JobServer) setInterrupt(job *restoreJob, interrupt func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.interrupt = interrupt
+	if job.canceled {
+		interrupt()
+	}
+}
+
+func (s *JobServer) cancelQueued(job *restoreJob) {
+	finished := time.Now().UTC()
+	job.canceled = true
+	job.status.State = JobCanceled
+	job.status.Finished = &finished
+	log.Logvf(log.Info, "canceled queued restore job %v", job.status.ID)
+}
+
+func (s *JobServer) interruptRunning(job *restoreJob) {
+	if job.canceled {
+		return
+	}
+	job.canceled = true
+	if job.interrupt != nil {
+		job.interrupt()
+	}
+	log.Logvf(log.Info, "interrupting restore job %v", job.status.ID)
+}
+
+func (s *JobServer) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// errInvalidJob is the error of the jobs that cannot be submitted.
+var errInvalidJob = errors.New("invalid job")
+
+// checkJobArgs returns an error if args use one of jobForbiddenOptions, a
+// connection string with TLS files, or would prompt for a password on the
+// stdin of the server. The args are checked before they are parsed
+// completely, so that nothing they refer to is read or run.
+func (s *JobServer) checkJobArgs(args []string) error {
+	opts, _, _, _ := newOptions(s.versionStr, s.gitCommit)
+	extraArgs, err := opts.CallArgParser(args)
+	if err != nil {
+		return fmt.Errorf("%w: error parsing command line options: %v", errInvalidJob, err)
+	}
+	for _, name := range jobForbiddenOptions {
+		if option := opts.FindOptionByLongName(name); option != nil && option.IsSet() {
+			return fmt.Errorf("%w: jobs cannot use --%v", errInvalidJob, name)
+		}
+	}
+	for _, arg := range append(extraArgs, opts.URI.ConnectionString) {
+		cs, err := connstring.Parse(arg)
+		if err != nil {
+			continue
+		}
+		if cs.SSLCaFile != "" || cs.SSLClientCertificateKeyFile != "" ||
+			cs.SSLCertificateFile != "" || cs.SSLPrivateKeyFile != "" {
+			return fmt.Errorf("%w: jobs cannot use connection strings with TLS files", errInvalidJob)
+		}
+	}
+
+	opts, _, _, _ = newOptions(s.versionStr, s.gitCommit)
+	opts.NoPasswordPrompt = true
+	if _, err := opts.ParseArgs(args); err != nil {
+		if errors.Is(err, options.ErrPasswordPrompt) {
+			return fmt.Errorf("%w: jobs cannot prompt for passwords: %v", errInvalidJob, err)
+		}
+		return fmt.Errorf("%w: error parsing command line options: %v", errInvalidJob, err)
+	}
+	return nil
+}
+
+// restore runs the restore of a job, as mongorestore would with its
+// arguments.
+func (s *JobServer) restore(job *restoreJob) (*Report, error) {
+	if err := s.checkJobArgs(job.args); err != nil {
+		return nil, err
+	}
+	opts, err := ParseOptions(job.args, s.versionStr, s.gitCommit)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing command line options: %v", err)
+	}
+	switch {
+	case opts.InputOptions.Archive == "-" || opts.TargetDirectory == "-":
+		return nil, fmt.Errorf("a job cannot read its dump from stdin")
+	case opts.CheckArchive:
+		return nil, CheckArchive(opts)
+	}
+
+	restore, err := New(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer restore.Close()
+	// the progress of the job is recorded as well as logged, and Close must
+	// stop the progress bars
+	bars := restore.ProgressManager
+	job.progress.bars = bars
+	restore.ProgressManager = job.progress
+	defer func() { restore.ProgressManager = bars }()
+	s.setInterrupt(job, restore.HandleInterrupt)
+
+	result := restore.Restore()
+	restore.LogSkippedNamespaces()
+	restore.LogIndexFailures()
+	report := restore.stats.report(result)
+	if err := restore.WriteReport(result); err != nil && result.Err == nil {
+		return report, err
+	}
+	return report, result.Err
+}
+
+func (s *JobServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var request JobRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid job: %v", err))
+		return
+	}
+	if len(request.Args) == 0 {
+		writeJSONError(w, http.StatusBadRequest, errors.New("invalid job: args are required"))
+		return
+	}
+	status, err := s.Submit(request.Args)
+	if errors.Is(err, errInvalidJob) {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+status.ID)
+	writeJSON(w, http.StatusCreated, status)
+}
+
+func (s *JobServer) handleList(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	statuses := make([]JobStatus, 0, len(s.order))
+	for _, id := range s.order {
+		statuses = append(statuses, s.jobs[id].snapshot())
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *JobServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.Status(r.PathValue("id"))
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("no job %v", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *JobServer) handleCancel(w http.ResponseWriter, r *http.Request) {
+	status, ok, err := s.Cancel(r.PathValue("id"))
+	switch {
+	case !ok:
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("no job %v", r.PathValue("id")))
+	case err != nil:
+		writeJSONError(w, http.StatusConflict, err)
+	case status.State == JobRunning:
+		writeJSON(w, http.StatusAccepted, status)
+	default:
+		writeJSON(w, http.StatusOK, status)
+	}
+}
+
+// listenHTTP listens on addr, which is either unix:<path> for a unix socket,
+// or a host and port that must be a loopback address unless allowRemote is
+// set.
+func listenHTTP(addr string, allowRemote bool) (net.Listener, error) {
+	network := "tcp"
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		network, addr = "unix", path
+	} else if !allowRemote && !isLoopback(addr) {
+		return nil, fmt.Errorf("%v is not a loopback address", addr)
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on %v: %v", addr, err)
+	}
+	return listener, nil
+}
+
+// isLoopback returns whether the host of addr is a loopback address.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// bearerAuth rejects the requests to next that don't give token as a bearer
+// token, if it is set.
+func bearerAuth(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSONError(w, http.StatusUnauthorized, errors.New("a valid bearer token is required"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Logvf(log.DebugLow, "error writing response: %v", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// snapshot returns a copy of the status of the job, with the progress of a
+// running job.
+func (job *restoreJob) snapshot() JobStatus {
+	status := job.status
+	if status.State == JobRunning {
+		status.Progress = job.progress.snapshot()
+	}
+	return status
+}
+
+// jobProgress is the progress.Manager of a running job. It records the
+// progressors attached to it and passes them on to the progress bars.
+type jobProgress struct {
+	bars progress.Manager
+
+	mu          sync.Mutex
+	progressors map[string]progress.Progressor
+}
+
+// Attach implements progress.Manager.
+func (p *jobProgress) Attach(name string, progressor progress.Progressor) {
+	p.mu.Lock()
+	p.progressors[name] = progressor
+	p.mu.Unlock()
+	if p.bars != nil {
+		p.bars.Attach(name, progressor)
+	}
+}
+
+// Detach implements progress.Manager.
+func (p *jobProgress) Detach(name string) {
+	p.mu.Lock()
+	delete(p.progressors, name)
+	p.mu.Unlock()
+	if p.bars != nil {
+		p.bars.Detach(name)
+	}
+}
+
+func (p *jobProgress) snapshot() []NamespaceProgress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var snapshot []NamespaceProgress
+	for name, progressor := range p.progressors {
+		done, total := progressor.Progress()
+		snapshot = append(snapshot, NamespaceProgress{Name: name, Done: done, Total: total})
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].Name < snapshot[j].Name
+	})
+	return snapshot
+}
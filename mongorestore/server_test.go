@@ -0,0 +1,284 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/progress"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJobs replaces the restores of a JobServer. Each job runs until it is
+// released or interrupted.
+type fakeJobs struct {
+	started  chan string
+	releases map[string]chan error
+}
+
+func newFakeJobs(s *JobServer, ids ...string) *fakeJobs {
+	fake := &fakeJobs{started: make(chan string, len(ids)), releases: map[string]chan error{}}
+	for _, id := range ids {
+		fake.releases[id] = make(chan error, 1)
+	}
+	s.run = func(job *restoreJob) (*Report, error) {
+		release := fake.releases[job.status.ID]
+		s.setInterrupt(job, func() { release <- errors.New("interrupted") })
+		job.progress.Attach("db.coll", progress.NewCounter(10))
+		fake.started <- job.status.ID
+		err := <-release
+		job.progress.Detach("db.coll")
+		return &Report{Documents: 10}, err
+	}
+	return fake
+}
+
+func (fake *fakeJobs) waitStarted(t *testing.T, id string) {
+	select {
+	case started := <-fake.started:
+		require.Equal(t, id, started)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "job did not start", id)
+	}
+}
+
+func waitState(t *testing.T, s *JobServer, id string, state JobState) JobStatus {
+	var status JobStatus
+	require.Eventually(t, func() bool {
+		status, _ = s.Status(id)
+		return status.State == state
+	}, 5*time.Second, 10*time.Millisecond)
+	return status
+}
+
+const testToken = "secret"
+
+func request(t *testing.T, server *httptest.Server, method, path, body string) (int, []byte) {
+	return requestWithToken(t, server, testToken, method, path, body)
+}
+
+func requestWithToken(
+	t *testing.T,
+	server *httptest.Server,
+	token, method, path, body string,
+) (int, []byte) {
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var content json.RawMessage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&content))
+	return resp.StatusCode, content
+}
+
+func TestJobServer(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	s := newJobServer("", "", testToken)
+	fake := newFakeJobs(s, "1", "2", "3")
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+	worked := make(chan struct{})
+	go func() {
+		s.Work()
+		close(worked)
+	}()
+
+	for range 3 {
+		code, _ := request(t, server, "POST", "/jobs", `{"args": ["--uri=mongodb://u:p@h", "dump"]}`)
+		require.Equal(t, http.StatusCreated, code)
+	}
+	fake.waitStarted(t, "1")
+
+	t.Run("running jobs report their progress", func(t *testing.T) {
+		code, body := request(t, server, "GET", "/jobs/1", "")
+		require.Equal(t, http.StatusOK, code)
+		var status JobStatus
+		require.NoError(t, json.Unmarshal(body, &status))
+		assert.Equal(t, JobRunning, status.State)
+		assert.Equal(t, []NamespaceProgress{{Name: "db.coll", Total: 10}}, status.Progress)
+		assert.NotContains(t, string(body), "mongodb://")
+	})
+
+	t.Run("queued jobs are canceled without running", func(t *testing.T) {
+		code, body := request(t, server, "DELETE", "/jobs/2", "")
+		require.Equal(t, http.StatusOK, code)
+		var status JobStatus
+		require.NoError(t, json.Unmarshal(body, &status))
+		assert.Equal(t, JobCanceled, status.State)
+	})
+
+	t.Run("jobs run in order, one at a time", func(t *testing.T) {
+		fake.releases["1"] <- nil
+		status := waitState(t, s, "1", JobSucceeded)
+		assert.EqualValues(t, 10, status.Report.Documents)
+		assert.Empty(t, status.Progress)
+
+		fake.waitStarted(t, "3")
+		code, _ := request(t, server, "DELETE", "/jobs/3", "")
+		assert.Equal(t, http.StatusAccepted, code)
+		status = waitState(t, s, "3", JobCanceled)
+		assert.Equal(t, "interrupted", status.Error)
+	})
+
+	t.Run("listing returns the jobs in order", func(t *testing.T) {
+		code, body := request(t, server, "GET", "/jobs", "")
+		require.Equal(t, http.StatusOK, code)
+		var statuses []JobStatus
+		require.NoError(t, json.Unmarshal(body, &statuses))
+		require.Len(t, statuses, 3)
+		for i, state := range []JobState{JobSucceeded, JobCanceled, JobCanceled} {
+			assert.Equal(t, state, statuses[i].State)
+		}
+	})
+
+	t.Run("finished jobs cannot be canceled", func(t *testing.T) {
+		code, _ := request(t, server, "DELETE", "/jobs/1", "")
+		assert.Equal(t, http.StatusConflict, code)
+	})
+
+	t.Run("unknown jobs are not found", func(t *testing.T) {
+		code, _ := request(t, server, "GET", "/jobs/42", "")
+		assert.Equal(t, http.StatusNotFound, code)
+		code, _ = request(t, server, "DELETE", "/jobs/42", "")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("invalid jobs are rejected", func(t *testing.T) {
+		for _, body := range []string{`{"args": []}`, `{"argv": ["dump"]}`, `not json`} {
+			code, _ := request(t, server, "POST", "/jobs", body)
+			assert.Equal(t, http.StatusBadRequest, code, body)
+		}
+	})
+
+	t.Run("requests without the token are unauthorized", func(t *testing.T) {
+		for _, token := range []string{"", "wrong"} {
+			code, _ := requestWithToken(t, server, token, "GET", "/jobs", "")
+			assert.Equal(t, http.StatusUnauthorized, code, token)
+		}
+	})
+
+	t.Run("jobs that read files or run commands are rejected", func(t *testing.T) {
+		for _, args := range []string{
+			`["--config=/etc/mongorestore.yaml", "dump"]`,
+			`["--passwordCommand=cat /etc/shadow", "dump"]`,
+			`["--username=user", "--passwordEnv=AWS_SECRET_ACCESS_KEY", "dump"]`,
+			`["--reportFile", "/tmp/report.json", "dump"]`,
+			`["--oplogReplay", "--oplogFollow=/var/oplog.bson", "dump"]`,
+			`["--serve=localhost:0"]`,
+			`["--uri=mongodb://h/?tls=true&tlsCAFile=/etc/ca.pem", "dump"]`,
+			`["mongodb://h/?tlsCertificateKeyFile=/etc/key.pem", "dump"]`,
+			`["--otelEndpoint=http://collector:4318", "dump"]`,
+			`["--oplogReplay", "--oplogFile=/var/oplog.bson", "dump"]`,
+		} {
+			code, _ := request(t, server, "POST", "/jobs", `{"args": `+args+`}`)
+			assert.Equal(t, http.StatusBadRequest, code, args)
+		}
+	})
+
+	t.Run("jobs that would prompt for a password are rejected", func(t *testing.T) {
+		for _, args := range []string{
+			`["--username=user", "dump"]`,
+			`["mongodb://user@h/", "dump"]`,
+		} {
+			code, body := request(t, server, "POST", "/jobs", `{"args": `+args+`}`)
+			assert.Equal(t, http.StatusBadRequest, code, args)
+			assert.Contains(t, string(body), "cannot prompt for passwords", args)
+		}
+	})
+
+	s.Shutdown()
+	<-worked
+	code, _ := request(t, server, "POST", "/jobs", `{"args": ["dump"]}`)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestJobServerIncompleteJobs(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	s := newJobServer("", "", testToken)
+	s.run = func(job *restoreJob) (*Report, error) {
+		return &Report{
+			Documents:           10,
+			Incomplete:          []IncompleteNamespaceReport{{"db.coll", "truncated archive"}},
+			SkippedArchiveBytes: 42,
+		}, nil
+	}
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+	worked := make(chan struct{})
+	go func() {
+		s.Work()
+		close(worked)
+	}()
+
+	code, _ := request(t, server, "POST", "/jobs", `{"args": ["--tolerant", "--archive=a"]}`)
+	require.Equal(t, http.StatusCreated, code)
+	status := waitState(t, s, "1", JobIncomplete)
+	require.NotNil(t, status.Report)
+	assert.Equal(t, "db.coll", status.Report.Incomplete[0].Namespace)
+	assert.EqualValues(t, 42, status.Report.SkippedArchiveBytes)
+	assert.Empty(t, status.Error)
+
+	s.Shutdown()
+	<-worked
+}
+
+func TestNewJobServer(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	opts := Options{ToolOptions: &options.ToolOptions{}, InputOptions: &InputOptions{}}
+	_, err := NewJobServer(opts)
+	assert.Error(t, err)
+
+	opts.ServeTokenFile = filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(opts.ServeTokenFile, []byte("\n"), 0600))
+	_, err = NewJobServer(opts)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(opts.ServeTokenFile, []byte(testToken+"\n"), 0600))
+	s, err := NewJobServer(opts)
+	require.NoError(t, err)
+	assert.Equal(t, testToken, s.token)
+}
+
+func TestListenHTTP(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	for _, addr := range []string{"127.0.0.1:0", "localhost:0", "[::1]:0"} {
+		assert.True(t, isLoopback(addr), addr)
+	}
+	for _, addr := range []string{":8080", "0.0.0.0:8080", "10.0.0.1:8080", "example.com:80"} {
+		assert.False(t, isLoopback(addr), addr)
+		_, err := listenHTTP(addr, false)
+		assert.Error(t, err, addr)
+	}
+
+	listener, err := listenHTTP("127.0.0.1:0", false)
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	listener, err = listenHTTP("unix:"+filepath.Join(t.TempDir(), "mongorestore.sock"), false)
+	require.NoError(t, err)
+	assert.Equal(t, "unix", listener.Addr().Network())
+	require.NoError(t, listener.Close())
+}
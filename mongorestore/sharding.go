@@ -0,0 +1,300 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// errAlreadyInitialized is returned by enableSharding and shardCollection on
+// older servers when the database or collection is already sharded.
+const errAlreadyInitialized = 23
+
+// shardingConfig holds the sharding metadata that --restoreShardingConfig
+// re-applies to the target cluster. The field layout matches the documents
+// in the config database's collections, tags and shards collections, so the
+// same struct can be filled from a dumped config database or from a JSON
+// manifest file.
+type shardingConfig struct {
+	Collections []shardedCollection `bson:"collections"`
+	Tags        []zoneRange         `bson:"tags"`
+	Shards      []shardZones        `bson:"shards"`
+}
+
+// shardedCollection is a config.collections document.
+type shardedCollection struct {
+	NS      string `bson:"_id"`
+	Key     bson.D `bson:"key"`
+	Unique  bool   `bson:"unique"`
+	Dropped bool   `bson:"dropped"`
+}
+
+// zoneRange is a config.tags document.
+type zoneRange struct {
+	NS  string `bson:"ns"`
+	Min bson.D `bson:"min"`
+	Max bson.D `bson:"max"`
+	Tag string `bson:"tag"`
+}
+
+// shardZones is the subset of a config.shards document that we care about.
+type shardZones struct {
+	ID   string   `bson:"_id"`
+	Tags []string `bson:"tags"`
+}
+
+// namespaceShardingConfig is the sharding metadata to apply to a single
+// restored namespace.
+type namespaceShardingConfig struct {
+	collection shardedCollection
+	zones      []zoneRange
+}
+
+// loadShardingConfigManifest reads a JSON manifest with "collections", "tags"
+// and "shards" arrays in the format of the corresponding config collections.
+func loadShardingConfigManifest(path string) (*shardingConfig, error) {
+	content, err := os.ReadFile(util.ToUniversalPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("error reading sharding config file: %v", err)
+	}
+	var cfg shardingConfig
+	if err := bson.UnmarshalExtJSON(content, false, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing sharding config file %v: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+// loadShardingConfigFromDump reads the collections, tags and shards
+// collections of a config database dumped into dumpDir.
+func loadShardingConfigFromDump(dumpDir string, gzipped bool) (*shardingConfig, error) {
+	var cfg shardingConfig
+	configDir := filepath.Join(dumpDir, "config")
+	if err := readConfigCollection(configDir, "collections", gzipped, &cfg.Collections); err != nil {
+		return nil, err
+	}
+	if err := readConfigCollection(configDir, "tags", gzipped, &cfg.Tags); err != nil {
+		return nil, err
+	}
+	if err := readConfigCollection(configDir, "shards", gzipped, &cfg.Shards); err != nil {
+		return nil, err
+	}
+	if len(cfg.Collections) == 0 {
+		return nil, fmt.Errorf(
+			"no sharded collections found in %v; dump the config database or use --shardingConfigFile",
+			configDir,
+		)
+	}
+	return &cfg, nil
+}
+
+// readConfigCollection decodes every document of <configDir>/<name>.bson into
+// the slice pointed to by out. A missing file is not an error.
+func readConfigCollection[T any](configDir, name string, gzipped bool, out *[]T) error {
+	path := filepath.Join(configDir, name+".bson")
+	if gzipped {
+		path += ".gz"
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Logvf(log.DebugLow, "no %v found, skipping", path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error opening %v: %v", path, err)
+	}
+	defer file.Close()
+
+	var in io.ReadCloser = file
+	if gzipped {
+		gzFile, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("error decompressing %v: %v", path, err)
+		}
+		defer gzFile.Close()
+		in = gzFile
+	}
+
+	source := db.NewDecodedBSONSource(db.NewBufferlessBSONSource(in))
+	for {
+		var doc T
+		if !source.Next(&doc) {
+			break
+		}
+		*out = append(*out, doc)
+	}
+	if err := source.Err(); err != nil {
+		return fmt.Errorf("error reading %v: %v", path, err)
+	}
+	return nil
+}
+
+// loadShardingConfig reads the sharding metadata for --restoreShardingConfig
+// and indexes it by destination namespace, honoring namespace filters and
+// renames.
+func (restore *MongoRestore) loadShardingConfig() error {
+	var cfg *shardingConfig
+	var err error
+	if restore.OutputOptions.ShardingConfigFile != "" {
+		cfg, err = loadShardingConfigManifest(restore.OutputOptions.ShardingConfigFile)
+	} else {
+		cfg, err = loadShardingConfigFromDump(restore.TargetDirectory, restore.InputOptions.Gzip)
+	}
+	if err != nil {
+		return err
+	}
+
+	restore.shardedNamespaces = map[string]*namespaceShardingConfig{}
+	for _, coll := range cfg.Collections {
+		if coll.Dropped || len(coll.Key) == 0 || !restore.shardingNamespaceIncluded(coll.NS) {
+			continue
+		}
+		restore.shardedNamespaces[restore.renamer.Get(coll.NS)] = &namespaceShardingConfig{
+			collection: coll,
+		}
+	}
+	for _, zone := range cfg.Tags {
+		nsConfig, ok := restore.shardedNamespaces[restore.renamer.Get(zone.NS)]
+		if !ok {
+			continue
+		}
+		nsConfig.zones = append(nsConfig.zones, zone)
+	}
+	restore.shardZones = cfg.Shards
+
+	log.Logvf(
+		log.DebugLow,
+		"loaded sharding config for %v %v",
+		len(restore.shardedNamespaces),
+		util.Pluralize(len(restore.shardedNamespaces), "namespace", "namespaces"),
+	)
+	return nil
+}
+
+func (restore *MongoRestore) shardingNamespaceIncluded(namespace string) bool {
+	dbName, _ := util.SplitNamespace(namespace)
+	if dbName == "config" || dbName == "admin" || dbName == "local" {
+		return false
+	}
+	return restore.includer.Has(namespace) && !restore.excluder.Has(namespace)
+}
+
+// restoreShardZones assigns zones to the shards of the target cluster that
+// have the same names as the shards in the dumped cluster. Zones that cannot
+// be assigned are reported, since their key ranges will fail to apply.
+func (restore *MongoRestore) restoreShardZones() error {
+	var shards struct {
+		Shards []shardZones `bson:"shards"`
+	}
+	err := restore.SessionProvider.Run(bson.D{{Key: "listShards", Value: 1}}, &shards, "admin")
+	if err != nil {
+		return fmt.Errorf("error listing shards: %v", err)
+	}
+	targetShards := map[string]bool{}
+	for _, shard := range shards.Shards {
+		targetShards[shard.ID] = true
+	}
+
+	for _, shard := range restore.shardZones {
+		if len(shard.Tags) == 0 {
+			continue
+		}
+		if !targetShards[shard.ID] {
+			log.Logvf(
+				log.Always,
+				"shard %v does not exist in the target cluster, not assigning zones %v; "+
+					"assign them with addShardToZone before the zone ranges can be applied",
+				shard.ID,
+				shard.Tags,
+			)
+			continue
+		}
+		for _, zone := range shard.Tags {
+			log.Logvf(log.Info, "adding shard %v to zone %v", shard.ID, zone)
+			err := restore.runAdminCommand(bson.D{
+				{Key: "addShardToZone", Value: shard.ID},
+				{Key: "zone", Value: zone},
+			})
+			if err != nil {
+				return fmt.Errorf("error adding shard %v to zone %v: %v", shard.ID, zone, err)
+			}
+		}
+	}
+	return nil
+}
+
+// restoreShardingForIntent applies the zone ranges and shard key recorded for
+// the intent's namespace. It must run after the collection is created and
+// before any documents are inserted.
+func (restore *MongoRestore) restoreShardingForIntent(intent *intents.Intent) error {
+	nsConfig, ok := restore.shardedNamespaces[intent.Namespace()]
+	if !ok {
+		return nil
+	}
+
+	// zone ranges go first so that the initial chunks of the newly sharded
+	// collection are already placed on the right shards
+	for _, zone := range nsConfig.zones {
+		log.Logvf(log.Info, "adding zone range %v for %v", zone.Tag, intent.Namespace())
+		err := restore.runAdminCommand(bson.D{
+			{Key: "updateZoneKeyRange", Value: intent.Namespace()},
+			{Key: "min", Value: zone.Min},
+			{Key: "max", Value: zone.Max},
+			{Key: "zone", Value: zone.Tag},
+		})
+		if err != nil {
+			return fmt.Errorf(
+				"error adding zone range %v for %v: %v",
+				zone.Tag,
+				intent.Namespace(),
+				err,
+			)
+		}
+	}
+
+	err := restore.runAdminCommand(bson.D{{Key: "enableSharding", Value: intent.DB}})
+	if err != nil && !isAlreadyInitialized(err) {
+		return fmt.Errorf("error enabling sharding for database %v: %v", intent.DB, err)
+	}
+
+	log.Logvf(
+		log.Always,
+		"sharding collection %v with key %v",
+		intent.Namespace(),
+		nsConfig.collection.Key,
+	)
+	err = restore.runAdminCommand(bson.D{
+		{Key: "shardCollection", Value: intent.Namespace()},
+		{Key: "key", Value: nsConfig.collection.Key},
+		{Key: "unique", Value: nsConfig.collection.Unique},
+	})
+	if err != nil && !isAlreadyInitialized(err) {
+		return fmt.Errorf("error sharding collection %v: %v", intent.Namespace(), err)
+	}
+	return nil
+}
+
+func (restore *MongoRestore) runAdminCommand(command bson.D) error {
+	var result bson.M
+	return restore.SessionProvider.Run(command, &result, "admin")
+}
+
+func isAlreadyInitialized(err error) bool {
+	var mongoErr mongo.ServerError
+	return errors.As(err, &mongoErr) && mongoErr.HasErrorCode(errAlreadyInitialized)
+}
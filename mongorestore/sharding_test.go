@@ -0,0 +1,112 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/mongodb/mongo-tools/mongorestore/ns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func writeBSONFile(t *testing.T, path string, docs ...interface{}) {
+	var out []byte
+	for _, doc := range docs {
+		raw, err := bson.Marshal(doc)
+		require.NoError(t, err)
+		out = append(out, raw...)
+	}
+	require.NoError(t, os.WriteFile(path, out, 0o644))
+}
+
+func TestLoadShardingConfigFromDump(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	dumpDir := t.TempDir()
+	configDir := filepath.Join(dumpDir, "config")
+	require.NoError(t, os.Mkdir(configDir, 0o755))
+
+	writeBSONFile(t, filepath.Join(configDir, "collections.bson"),
+		bson.D{{"_id", "db1.sharded"}, {"key", bson.D{{"a", 1}}}, {"unique", true}},
+		bson.D{{"_id", "db1.gone"}, {"key", bson.D{{"a", 1}}}, {"dropped", true}},
+		bson.D{{"_id", "config.system.sessions"}, {"key", bson.D{{"_id", 1}}}},
+		bson.D{{"_id", "db2.hashed"}, {"key", bson.D{{"b", "hashed"}}}},
+	)
+	writeBSONFile(t, filepath.Join(configDir, "tags.bson"),
+		bson.D{
+			{"ns", "db1.sharded"},
+			{"min", bson.D{{"a", 0}}},
+			{"max", bson.D{{"a", 10}}},
+			{"tag", "east"},
+		},
+		bson.D{
+			{"ns", "db3.unsharded"},
+			{"min", bson.D{{"a", 0}}},
+			{"max", bson.D{{"a", 10}}},
+			{"tag", "west"},
+		},
+	)
+	writeBSONFile(t, filepath.Join(configDir, "shards.bson"),
+		bson.D{{"_id", "shard0"}, {"host", "rs0/localhost:27018"}, {"tags", bson.A{"east"}}},
+	)
+
+	includer, err := ns.NewMatcher([]string{"*"})
+	require.NoError(t, err)
+	excluder, err := ns.NewMatcher([]string{"db2.*"})
+	require.NoError(t, err)
+	renamer, err := ns.NewRenamer([]string{"db1.*"}, []string{"renamed.*"})
+	require.NoError(t, err)
+
+	restore := &MongoRestore{
+		InputOptions:    &InputOptions{},
+		OutputOptions:   &OutputOptions{RestoreShardingConfig: true},
+		TargetDirectory: dumpDir,
+		includer:        includer,
+		excluder:        excluder,
+		renamer:         renamer,
+	}
+	require.NoError(t, restore.loadShardingConfig())
+
+	require.Len(t, restore.shardedNamespaces, 1, "dropped, excluded and config namespaces are skipped")
+	nsConfig := restore.shardedNamespaces["renamed.sharded"]
+	require.NotNil(t, nsConfig, "namespaces are renamed")
+	assert.Equal(t, bson.D{{"a", int32(1)}}, nsConfig.collection.Key)
+	assert.True(t, nsConfig.collection.Unique)
+	require.Len(t, nsConfig.zones, 1)
+	assert.Equal(t, "east", nsConfig.zones[0].Tag)
+
+	require.Len(t, restore.shardZones, 1)
+	assert.Equal(t, shardZones{ID: "shard0", Tags: []string{"east"}}, restore.shardZones[0])
+}
+
+func TestLoadShardingConfigManifest(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	manifest := filepath.Join(t.TempDir(), "sharding.json")
+	require.NoError(t, os.WriteFile(manifest, []byte(`{
+		"collections": [{"_id": "db.coll", "key": {"x": 1, "y": 1}}],
+		"tags": [{"ns": "db.coll", "min": {"x": {"$minKey": 1}}, "max": {"x": 5}, "tag": "z1"}],
+		"shards": [{"_id": "s1", "tags": ["z1"]}]
+	}`), 0o644))
+
+	cfg, err := loadShardingConfigManifest(manifest)
+	require.NoError(t, err)
+	require.Len(t, cfg.Collections, 1)
+	assert.Equal(t, "db.coll", cfg.Collections[0].NS)
+	assert.Len(t, cfg.Collections[0].Key, 2)
+	require.Len(t, cfg.Tags, 1)
+	assert.Equal(t, "z1", cfg.Tags[0].Tag)
+	require.Len(t, cfg.Shards, 1)
+
+	_, err = loadShardingConfigFromDump(t.TempDir(), false)
+	assert.Error(t, err, "a dump without a config database has nothing to restore")
+}
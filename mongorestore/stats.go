@@ -0,0 +1,352 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/mongodb/mongo-tools/common/archive"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/text"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Reasons for which the documents of a namespace are skipped.
+const (
+	skipNotIncluded       = "not included"
+	skipExcluded          = "excluded"
+	skipSystemProfile     = "system.profile is never restored"
+	skipSpecialCollection = "special collection of a single database dump"
+	skipSystemIndexes     = "system.indexes is replaced by metadata files"
+	skipSystemCollection  = "system collection not named by --systemCollection"
+	skipJournaled         = "restored by a previous restore with --restoreJournal"
+	skipOplogJournaled    = "restored before the oplog replay that --oplogJournal resumes"
+)
+
+// NamespaceReport is the number of documents restored into a namespace.
+type NamespaceReport struct {
+	Namespace string `json:"namespace"`
+	Documents int64  `json:"documents"`
+	Failures  int64  `json:"failures"`
+}
+
+// SkippedNamespaceReport describes a namespace of the dump that was not
+// restored. Documents is only known for namespaces whose documents were read
+// from an archive; the files of skipped namespaces in a dump directory are
+// not read, so only their size is known.
+type SkippedNamespaceReport struct {
+	Namespace string `json:"namespace"`
+	Reason    string `json:"reason"`
+	Documents *int64 `json:"documents,omitempty"`
+	Bytes     int64  `json:"bytes"`
+
+	muted *archive.MutedCollection
+}
+
+// IndexChangeReport describes an index that --removedIndexPolicy converted or
+// dropped, or that --indexTranslationFile rewrote.
+type IndexChangeReport struct {
+	Namespace string `json:"namespace"`
+	Index     string `json:"index"`
+	Change    string `json:"change"`
+}
+
+// LegacyIndexReport describes how an index of the dump differs from the
+// definitions that current servers create.
+type LegacyIndexReport struct {
+	Namespace string   `json:"namespace"`
+	Index     string   `json:"index"`
+	Findings  []string `json:"findings"`
+}
+
+// Report is the summary of a restore written to --reportFile.
+type Report struct {
+	Restored         []NamespaceReport        `json:"restored"`
+	Skipped          []SkippedNamespaceReport `json:"skipped"`
+	IndexChanges     []IndexChangeReport      `json:"indexChanges"`
+	LegacyIndexes    []LegacyIndexReport      `json:"legacyIndexes,omitempty"`
+	IndexFailures    []IndexFailureReport     `json:"indexFailures"`
+	Compared         []ComparisonReport       `json:"compared,omitempty"`
+	Validation       []ValidationReport       `json:"validation,omitempty"`
+	DuplicateKeys    []DuplicateKeyReport     `json:"duplicateKeys,omitempty"`
+	OplogDryApply    []OplogShadowReport      `json:"oplogDryApply,omitempty"`
+	Documents        int64                    `json:"documents"`
+	Failures         int64                    `json:"failures"`
+	SkippedDocuments int64                    `json:"skippedDocuments"`
+	// Incomplete holds the namespaces that --tolerant could only partially
+	// restore from a damaged archive, and SkippedArchiveBytes is the amount
+	// of corrupted data it skipped.
+	Incomplete          []IncompleteNamespaceReport `json:"incomplete,omitempty"`
+	SkippedArchiveBytes int64                       `json:"skippedArchiveBytes,omitempty"`
+	Error               string                      `json:"error,omitempty"`
+}
+
+// isIncomplete returns true if --tolerant skipped over data of the archive.
+func (report *Report) isIncomplete() bool {
+	return len(report.Incomplete) > 0 || report.SkippedArchiveBytes > 0
+}
+
+// IncompleteNamespaceReport describes a namespace that --tolerant could only
+// partially restore from a damaged archive.
+type IncompleteNamespaceReport struct {
+	Namespace string `json:"namespace"`
+	Reason    string `json:"reason"`
+}
+
+// restoreStats collects the per-namespace counts of a restore.
+type restoreStats struct {
+	mu       sync.Mutex
+	restored map[string]*NamespaceReport
+	skipped  map[string]*SkippedNamespaceReport
+	indexes  []IndexChangeReport
+	legacy   []LegacyIndexReport
+	compared []ComparisonReport
+	invalid  map[string]*ValidationReport
+	dups     map[string]*DuplicateKeyReport
+	shadows  []OplogShadowReport
+
+	indexFailures []IndexFailureReport
+}
+
+// recordSkipped records that the documents of ns are skipped for reason. If
+// they are read from an archive, muted is the demux output that discards
+// them, which counts them.
+func (stats *restoreStats) recordSkipped(
+	ns, reason string,
+	size int64,
+	muted *archive.MutedCollection,
+) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.skipped == nil {
+		stats.skipped = map[string]*SkippedNamespaceReport{}
+	}
+	stats.skipped[ns] = &SkippedNamespaceReport{
+		Namespace: ns,
+		Reason:    reason,
+		Bytes:     size,
+		muted:     muted,
+	}
+}
+
+// recordRestored adds the result of restoring an intent to the counts of ns.
+func (stats *restoreStats) recordRestored(ns string, result Result) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.restored == nil {
+		stats.restored = map[string]*NamespaceReport{}
+	}
+	report, ok := stats.restored[ns]
+	if !ok {
+		report = &NamespaceReport{Namespace: ns}
+		stats.restored[ns] = report
+	}
+	report.Documents += result.Successes
+	report.Failures += result.Failures
+}
+
+// recordIndexChange records that --removedIndexPolicy or
+// --indexTranslationFile changed an index of ns.
+func (stats *restoreStats) recordIndexChange(ns, index, change string) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.indexes = append(stats.indexes, IndexChangeReport{
+		Namespace: ns,
+		Index:     index,
+		Change:    change,
+	})
+}
+
+// recordLegacyIndex records an index of the dump in a legacy format.
+func (stats *restoreStats) recordLegacyIndex(report LegacyIndexReport) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.legacy = append(stats.legacy, report)
+}
+
+// recordIndexFailure records that an index could not be created as it was
+// dumped.
+func (stats *restoreStats) recordIndexFailure(failure IndexFailureReport) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.indexFailures = append(stats.indexFailures, failure)
+}
+
+// recordComparison records how --compare found the target to differ from
+// the dump in a namespace.
+func (stats *restoreStats) recordComparison(report ComparisonReport) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.compared = append(stats.compared, report)
+}
+
+// recordOplogShadow records how --oplogDryApply found the shadow collection
+// of a namespace to diverge.
+func (stats *restoreStats) recordOplogShadow(report OplogShadowReport) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.shadows = append(stats.shadows, report)
+}
+
+// recordInvalid records that count documents of ns failed validation under
+// policy, of which ids are the _ids of those that fit in the report.
+func (stats *restoreStats) recordInvalid(ns, policy string, count int64, ids []bson.RawValue) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.invalid == nil {
+		stats.invalid = map[string]*ValidationReport{}
+	}
+	report, ok := stats.invalid[ns]
+	if !ok {
+		report = &ValidationReport{Namespace: ns, Policy: policy, IDs: []json.RawMessage{}}
+		stats.invalid[ns] = report
+	}
+	report.InvalidDocuments += count
+	for _, id := range ids {
+		if len(report.IDs) == maxReportedInvalidIDs {
+			break
+		}
+		report.IDs = append(report.IDs, idJSON(id))
+	}
+	report.IDsTruncated = report.InvalidDocuments > int64(len(report.IDs))
+}
+
+// recordDuplicates records that --onDuplicateKey resolved the documents of ns
+// with the _ids ids under policy, and gave them the _ids suffixed with suffix.
+func (stats *restoreStats) recordDuplicates(ns, policy string, ids, suffixed []bson.RawValue) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.dups == nil {
+		stats.dups = map[string]*DuplicateKeyReport{}
+	}
+	report, ok := stats.dups[ns]
+	if !ok {
+		report = &DuplicateKeyReport{Namespace: ns, Policy: policy, IDs: []json.RawMessage{}}
+		stats.dups[ns] = report
+	}
+	report.Duplicates += int64(len(ids))
+	for i, id := range ids {
+		if len(report.IDs) == maxReportedInvalidIDs {
+			break
+		}
+		report.IDs = append(report.IDs, idJSON(id))
+		if suffixed != nil {
+			report.SuffixedIDs = append(report.SuffixedIDs, idJSON(suffixed[i]))
+		}
+	}
+	report.IDsTruncated = report.Duplicates > int64(len(report.IDs))
+}
+
+// report returns the counts collected so far, sorted by namespace.
+func (stats *restoreStats) report(result Result) *Report {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	report := &Report{
+		Restored:      []NamespaceReport{},
+		Skipped:       []SkippedNamespaceReport{},
+		IndexChanges:  append([]IndexChangeReport{}, stats.indexes...),
+		LegacyIndexes: append([]LegacyIndexReport(nil), stats.legacy...),
+		Compared:      append([]ComparisonReport(nil), stats.compared...),
+		OplogDryApply: append([]OplogShadowReport(nil), stats.shadows...),
+		Documents:     result.Successes,
+		Failures:      result.Failures,
+	}
+	report.IndexFailures = append([]IndexFailureReport{}, stats.indexFailures...)
+	report.SkippedArchiveBytes = result.SkippedBytes
+	for ns, reason := range result.Incomplete {
+		report.Incomplete = append(report.Incomplete, IncompleteNamespaceReport{ns, reason})
+	}
+	sort.Slice(report.Incomplete, func(i, j int) bool {
+		return report.Incomplete[i].Namespace < report.Incomplete[j].Namespace
+	})
+	if result.Err != nil {
+		report.Error = result.Err.Error()
+	}
+	for _, restored := range stats.restored {
+		report.Restored = append(report.Restored, *restored)
+	}
+	sort.Slice(report.Restored, func(i, j int) bool {
+		return report.Restored[i].Namespace < report.Restored[j].Namespace
+	})
+	for _, skipped := range stats.skipped {
+		entry := *skipped
+		if skipped.muted != nil {
+			documents := skipped.muted.Documents()
+			entry.Documents = &documents
+			report.SkippedDocuments += documents
+		}
+		report.Skipped = append(report.Skipped, entry)
+	}
+	sort.Slice(report.Skipped, func(i, j int) bool {
+		return report.Skipped[i].Namespace < report.Skipped[j].Namespace
+	})
+	for _, invalid := range stats.invalid {
+		report.Validation = append(report.Validation, *invalid)
+	}
+	sort.Slice(report.Validation, func(i, j int) bool {
+		return report.Validation[i].Namespace < report.Validation[j].Namespace
+	})
+	for _, dups := range stats.dups {
+		report.DuplicateKeys = append(report.DuplicateKeys, *dups)
+	}
+	sort.Slice(report.DuplicateKeys, func(i, j int) bool {
+		return report.DuplicateKeys[i].Namespace < report.DuplicateKeys[j].Namespace
+	})
+	return report
+}
+
+// LogSkippedNamespaces logs the namespaces of the dump that were skipped by
+// the include, exclude and special collection rules, with the number of
+// documents they had. It should be called once the restore is done.
+func (restore *MongoRestore) LogSkippedNamespaces() {
+	report := restore.stats.report(Result{})
+	if len(report.Skipped) == 0 {
+		return
+	}
+	log.Logvf(
+		log.Always,
+		"skipped %v %v of the dump:",
+		len(report.Skipped),
+		util.Pluralize(len(report.Skipped), "namespace", "namespaces"),
+	)
+	for _, skipped := range report.Skipped {
+		var amount string
+		if skipped.Documents != nil {
+			amount = fmt.Sprintf(
+				"%v %v",
+				*skipped.Documents,
+				util.Pluralize(int(*skipped.Documents), "document", "documents"),
+			)
+		} else {
+			amount = text.FormatByteAmount(skipped.Bytes)
+		}
+		log.Logvf(log.Always, "\t%v: %v (%v)", skipped.Namespace, amount, skipped.Reason)
+	}
+}
+
+// WriteReport writes the per-namespace counts of restored and skipped
+// documents, and the overall result, as JSON to --reportFile, if it is set.
+func (restore *MongoRestore) WriteReport(result Result) error {
+	if restore.OutputOptions.ReportFile == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(restore.stats.report(result), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding report: %v", err)
+	}
+	err = os.WriteFile(restore.OutputOptions.ReportFile, append(content, '\n'), 0o644)
+	if err != nil {
+		return fmt.Errorf("error writing --reportFile: %v", err)
+	}
+	return nil
+}
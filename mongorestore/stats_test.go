@@ -0,0 +1,112 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/archive"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/mongodb/mongo-tools/mongorestore/ns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkippedNamespacesAreRecorded(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	mr := newMongoRestore()
+	var err error
+	mr.includer, err = ns.NewMatcher([]string{"db1.*"})
+	require.NoError(t, err)
+	mr.excluder, err = ns.NewMatcher([]string{"db1.c2"})
+	require.NoError(t, err)
+
+	ddl, err := newActualPath("testdata/testdirs/")
+	require.NoError(t, err)
+	require.NoError(t, mr.CreateAllIntents(ddl))
+
+	report := mr.stats.report(Result{})
+	require.Len(t, report.Skipped, 2)
+	assert.Equal(t, "db1.c2", report.Skipped[0].Namespace)
+	assert.Equal(t, skipExcluded, report.Skipped[0].Reason)
+	assert.Nil(t, report.Skipped[0].Documents, "documents of skipped files are not read")
+	assert.Equal(t, "db2.c1", report.Skipped[1].Namespace)
+	assert.Equal(t, skipNotIncluded, report.Skipped[1].Reason)
+}
+
+func TestWriteReport(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	mr := newMongoRestore()
+	mr.OutputOptions = &OutputOptions{}
+	require.NoError(t, mr.WriteReport(Result{}), "no report is written without --reportFile")
+
+	mr.OutputOptions.ReportFile = filepath.Join(t.TempDir(), "report.json")
+	mr.stats.recordRestored("db.b", Result{Successes: 3, Failures: 1})
+	mr.stats.recordRestored("db.a", Result{Successes: 2})
+	mr.stats.recordRestored("db.b", Result{Successes: 4})
+
+	muted := &archive.MutedCollection{}
+	for i := 0; i < 5; i++ {
+		_, err := muted.Write([]byte("doc"))
+		require.NoError(t, err)
+	}
+	mr.stats.recordSkipped("db.c", skipNotIncluded, 100, muted)
+	mr.stats.recordSkipped("db.d", skipSystemProfile, 200, nil)
+
+	require.NoError(t, mr.WriteReport(Result{Successes: 9, Failures: 1}))
+	content, err := os.ReadFile(mr.OutputOptions.ReportFile)
+	require.NoError(t, err)
+
+	var report Report
+	require.NoError(t, json.Unmarshal(content, &report))
+	assert.Equal(t, []NamespaceReport{
+		{Namespace: "db.a", Documents: 2},
+		{Namespace: "db.b", Documents: 7, Failures: 1},
+	}, report.Restored)
+	require.Len(t, report.Skipped, 2)
+	assert.Equal(t, "db.c", report.Skipped[0].Namespace)
+	require.NotNil(t, report.Skipped[0].Documents)
+	assert.EqualValues(t, 5, *report.Skipped[0].Documents)
+	assert.Equal(t, "db.d", report.Skipped[1].Namespace)
+	assert.Nil(t, report.Skipped[1].Documents)
+	assert.EqualValues(t, 200, report.Skipped[1].Bytes)
+	assert.EqualValues(t, 9, report.Documents)
+	assert.EqualValues(t, 1, report.Failures)
+	assert.EqualValues(t, 5, report.SkippedDocuments)
+	assert.Empty(t, report.Incomplete)
+	assert.Zero(t, report.SkippedArchiveBytes)
+	assert.Empty(t, report.Error)
+}
+
+func TestReportIncompleteNamespaces(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	mr := newMongoRestore()
+	result := Result{
+		Successes: 3,
+		Incomplete: map[string]string{
+			"db.b": "truncated archive",
+			"db.a": "corrupted block",
+		},
+		SkippedBytes: 42,
+	}
+	assert.True(t, result.IsIncomplete())
+	assert.False(t, Result{Successes: 3}.IsIncomplete())
+
+	report := mr.stats.report(result)
+	assert.Equal(t, []IncompleteNamespaceReport{
+		{Namespace: "db.a", Reason: "corrupted block"},
+		{Namespace: "db.b", Reason: "truncated archive"},
+	}, report.Incomplete)
+	assert.EqualValues(t, 42, report.SkippedArchiveBytes)
+	assert.True(t, report.isIncomplete())
+}
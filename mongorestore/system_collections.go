@@ -0,0 +1,93 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/dumprestore"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// skipsSystemCollection returns true if the collection of dbName is a system
+// collection that is not restored: one outside of the admin and config
+// databases that is neither system.js nor named by --systemCollection. The
+// data of timeseries collections, and system.profile and system.indexes,
+// which have reasons of their own to be skipped, are not counted.
+func (restore *MongoRestore) skipsSystemCollection(dbName, collection string) bool {
+	switch {
+	case dbName == "admin" || dbName == "config":
+		return false
+	case !strings.HasPrefix(collection, "system."),
+		strings.HasPrefix(collection, "system.buckets."),
+		collection == "system.profile",
+		collection == "system.indexes":
+		return false
+	}
+	return !dumprestore.IncludesSystemCollection(restore.NSOptions.SystemCollections, collection)
+}
+
+// restoresSystemCollection returns true if the intent is for a system
+// collection that is restored like any other collection, which --drop
+// empties instead of dropping, since system collections can't be dropped.
+func (restore *MongoRestore) restoresSystemCollection(intent *intents.Intent) bool {
+	return intent.DB != "admin" && intent.DB != "config" &&
+		dumprestore.IncludesSystemCollection(restore.NSOptions.SystemCollections, intent.C)
+}
+
+// emptySystemCollection removes all of the documents of a system collection
+// before it is restored with --drop.
+func (restore *MongoRestore) emptySystemCollection(intent *intents.Intent) error {
+	session, err := restore.SessionProvider.GetSession()
+	if err != nil {
+		return fmt.Errorf("error establishing connection: %v", err)
+	}
+	result, err := session.Database(intent.DB).
+		Collection(intent.C).
+		DeleteMany(context.TODO(), bson.D{})
+	if err != nil {
+		return fmt.Errorf("error removing the documents of %v: %v", intent.Namespace(), err)
+	}
+	log.Logvf(log.Always, "removed %v document(s) from system collection %v before restoring",
+		result.DeletedCount, intent.Namespace())
+	return nil
+}
+
+// logStoredFunctions logs the names of the stored JavaScript functions of the
+// database of a restored system.js collection.
+func (restore *MongoRestore) logStoredFunctions(intent *intents.Intent) {
+	session, err := restore.SessionProvider.GetSession()
+	if err != nil {
+		log.Logvf(log.Info, "error listing the restored functions of %v: %v", intent.DB, err)
+		return
+	}
+	cursor, err := session.Database(intent.DB).
+		Collection(dumprestore.SystemJS).
+		Find(context.TODO(), bson.D{}, options.Find().SetProjection(bson.D{{"_id", 1}}))
+	if err != nil {
+		log.Logvf(log.Info, "error listing the restored functions of %v: %v", intent.DB, err)
+		return
+	}
+	var functions []struct {
+		ID interface{} `bson:"_id"`
+	}
+	if err := cursor.All(context.TODO(), &functions); err != nil {
+		log.Logvf(log.Info, "error listing the restored functions of %v: %v", intent.DB, err)
+		return
+	}
+	names := make([]string, len(functions))
+	for i, function := range functions {
+		names[i] = fmt.Sprint(function.ID)
+	}
+	log.Logvf(log.Always, "database %v has %v stored JavaScript function(s): %v",
+		intent.DB, len(names), strings.Join(names, ", "))
+}
@@ -0,0 +1,81 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/dumprestore"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSystemCollections(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	assert.NoError(t, dumprestore.ValidateSystemCollections(nil))
+	assert.NoError(t, dumprestore.ValidateSystemCollections(
+		[]string{"system.js", "system.custom"},
+	))
+	for _, name := range []string{
+		"custom",
+		"system.",
+		"system.users",
+		"system.views",
+		"system.buckets.metrics",
+	} {
+		assert.Error(t, dumprestore.ValidateSystemCollections([]string{name}), name)
+	}
+}
+
+func TestSkipsSystemCollection(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	mr := newMongoRestore()
+	mr.NSOptions.SystemCollections = []string{"system.custom"}
+
+	assert.False(t, mr.skipsSystemCollection("db", "coll"))
+	assert.False(t, mr.skipsSystemCollection("db", "system.js"))
+	assert.False(t, mr.skipsSystemCollection("db", "system.custom"))
+	assert.False(t, mr.skipsSystemCollection("db", "system.buckets.metrics"))
+	assert.False(t, mr.skipsSystemCollection("admin", "system.other"))
+	assert.True(t, mr.skipsSystemCollection("db", "system.other"))
+
+	assert.True(t, mr.restoresSystemCollection(&intents.Intent{DB: "db", C: "system.js"}))
+	assert.True(t, mr.restoresSystemCollection(&intents.Intent{DB: "db", C: "system.custom"}))
+	assert.False(t, mr.restoresSystemCollection(&intents.Intent{DB: "db", C: "coll"}))
+	assert.False(t, mr.restoresSystemCollection(&intents.Intent{DB: "admin", C: "system.js"}))
+}
+
+func TestCreateIntentsForSystemCollections(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	dir := t.TempDir()
+	for _, name := range []string{"coll", "system.custom", "system.js", "system.other"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".bson"), nil, 0o644))
+	}
+
+	mr := newMongoRestore()
+	mr.NSOptions.SystemCollections = []string{"system.custom"}
+	ddl, err := newActualPath(dir)
+	require.NoError(t, err)
+	require.NoError(t, mr.CreateIntentsForDB("myDB", ddl))
+	mr.manager.Finalize(intents.Legacy)
+
+	var restored []string
+	for intent := mr.manager.Pop(); intent != nil; intent = mr.manager.Pop() {
+		restored = append(restored, intent.C)
+	}
+	assert.Equal(t, []string{"coll", "system.custom", "system.js"}, restored)
+
+	require.Contains(t, mr.stats.skipped, "myDB.system.other")
+	assert.Equal(t, skipSystemCollection, mr.stats.skipped["myDB.system.other"].Reason)
+}
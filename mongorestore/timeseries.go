@@ -0,0 +1,156 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/mongorestore/ns"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// regularCollectionOptions are the collection options of the dump that a
+// timeseries collection cannot be created with, and so are left out of the
+// collections that --convertToTimeseries converts.
+var regularCollectionOptions = []string{
+	"autoIndexId",
+	"capped",
+	"size",
+	"max",
+	"idIndex",
+	clusteredIndexOption,
+	changeStreamImagesOption,
+	"recordPreImages",
+	"validator",
+	"validationLevel",
+	"validationAction",
+	"timeseries",
+}
+
+// validateTimeseriesOptions checks the options of --convertToTimeseries and
+// sets up the matcher of the namespaces it converts.
+func (restore *MongoRestore) validateTimeseriesOptions() error {
+	opts := restore.OutputOptions
+	if len(opts.ConvertToTimeseries) == 0 {
+		for _, option := range []struct {
+			name  string
+			value string
+		}{
+			{TimeFieldOption, opts.TimeField},
+			{MetaFieldOption, opts.MetaField},
+			{GranularityOption, opts.Granularity},
+		} {
+			if option.value != "" {
+				return fmt.Errorf("cannot use %v without %v", option.name, ConvertToTimeseriesOption)
+			}
+		}
+		return nil
+	}
+
+	switch {
+	case opts.TimeField == "":
+		return fmt.Errorf("%v requires %v", ConvertToTimeseriesOption, TimeFieldOption)
+	case strings.ContainsAny(opts.TimeField, ".$") || opts.TimeField == "_id":
+		return fmt.Errorf("invalid %v '%v'", TimeFieldOption, opts.TimeField)
+	case strings.Contains(opts.MetaField, "$") || opts.MetaField == "_id":
+		return fmt.Errorf("invalid %v '%v'", MetaFieldOption, opts.MetaField)
+	case opts.MetaField == opts.TimeField:
+		return fmt.Errorf("%v and %v must be different fields", TimeFieldOption, MetaFieldOption)
+	case opts.PreserveUUID:
+		return fmt.Errorf("cannot use %v with %v", ConvertToTimeseriesOption, PreserveUUIDOption)
+	case restore.InputOptions.OplogReplay:
+		return fmt.Errorf("cannot use %v with %v", ConvertToTimeseriesOption, OplogReplayOption)
+	case restore.serverVersion.LT(db.Version{5, 0, 0}):
+		return fmt.Errorf(
+			"cannot use %v with server version %v: timeseries collections require 5.0 or later",
+			ConvertToTimeseriesOption,
+			restore.serverVersion,
+		)
+	}
+
+	var err error
+	restore.timeseriesMatcher, err = ns.NewMatcher(opts.ConvertToTimeseries)
+	if err != nil {
+		return fmt.Errorf("invalid %v: %v", ConvertToTimeseriesOption, err)
+	}
+	return nil
+}
+
+// convertsToTimeseries returns whether --convertToTimeseries restores the
+// collection dbName.collName of the given type as a timeseries collection.
+// Collections that are already timeseries collections in the dump, and
+// system collections, are restored as they are.
+func (restore *MongoRestore) convertsToTimeseries(dbName, collName, collectionType string) bool {
+	return restore.timeseriesMatcher != nil &&
+		collectionType != "timeseries" &&
+		!strings.HasPrefix(collName, "system.") &&
+		restore.timeseriesMatcher.Has(dbName+"."+collName)
+}
+
+// timeseriesCollectionOptions returns the options to create the collection
+// of intent with as a timeseries collection: those of the dump that a
+// timeseries collection can have, and the timeseries options of
+// --timeField, --metaField and --granularity.
+func (restore *MongoRestore) timeseriesCollectionOptions(
+	intent *intents.Intent,
+	options bson.D,
+) bson.D {
+	var converted bson.D
+	var dropped []string
+	for _, option := range options {
+		if slices.Contains(regularCollectionOptions, option.Key) {
+			if option.Key != "idIndex" && option.Key != "autoIndexId" {
+				dropped = append(dropped, option.Key)
+			}
+			continue
+		}
+		converted = append(converted, option)
+	}
+	if len(dropped) > 0 {
+		log.Logvf(
+			log.Always,
+			"not restoring the options %v of %v, which timeseries collections cannot have",
+			strings.Join(dropped, ", "),
+			intent.Namespace(),
+		)
+	}
+
+	timeseries := bson.D{{"timeField", restore.OutputOptions.TimeField}}
+	if restore.OutputOptions.MetaField != "" {
+		timeseries = append(timeseries, bson.E{"metaField", restore.OutputOptions.MetaField})
+	}
+	if restore.OutputOptions.Granularity != "" {
+		timeseries = append(timeseries, bson.E{"granularity", restore.OutputOptions.Granularity})
+	}
+	return append(converted, bson.E{"timeseries", timeseries})
+}
+
+// checkTimeField returns an InvalidDocumentError if doc, to be inserted into
+// a collection converted by --convertToTimeseries, does not have a date in
+// its --timeField, which the server would reject.
+func (restore *MongoRestore) checkTimeField(doc bson.Raw) error {
+	value, err := doc.LookupErr(restore.OutputOptions.TimeField)
+	if err == nil && value.Type == bson.TypeDateTime {
+		return nil
+	}
+	invalid := db.InvalidDocumentError{
+		Reason: fmt.Sprintf("its %v is not a date", restore.OutputOptions.TimeField),
+	}
+	if err != nil {
+		invalid.Reason = fmt.Sprintf("it has no %v", restore.OutputOptions.TimeField)
+	}
+	if id, err := doc.LookupErr("_id"); err == nil {
+		//nolint:errcheck
+		id.Unmarshal(&invalid.ID)
+	}
+	return invalid
+}
@@ -0,0 +1,115 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func newTimeseriesRestore(output OutputOptions) *MongoRestore {
+	return &MongoRestore{
+		OutputOptions: &output,
+		InputOptions:  &InputOptions{},
+		serverVersion: db.Version{7, 0, 0},
+	}
+}
+
+func TestValidateTimeseriesOptions(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	valid := OutputOptions{
+		ConvertToTimeseries: []string{"events.*"},
+		TimeField:           "ts",
+		MetaField:           "sensor",
+		Granularity:         "minutes",
+	}
+	restore := newTimeseriesRestore(valid)
+	require.NoError(t, restore.validateTimeseriesOptions())
+	assert.True(t, restore.convertsToTimeseries("events", "clicks", ""))
+	assert.False(t, restore.convertsToTimeseries("events", "metrics", "timeseries"))
+	assert.False(t, restore.convertsToTimeseries("events", "system.js", ""))
+	assert.False(t, restore.convertsToTimeseries("other", "clicks", ""))
+
+	for name, change := range map[string]func(*MongoRestore){
+		"no time field":           func(r *MongoRestore) { r.OutputOptions.TimeField = "" },
+		"a dotted time field":     func(r *MongoRestore) { r.OutputOptions.TimeField = "a.ts" },
+		"the time field as _id":   func(r *MongoRestore) { r.OutputOptions.TimeField = "_id" },
+		"the same time and meta":  func(r *MongoRestore) { r.OutputOptions.MetaField = "ts" },
+		"--preserveUUID":          func(r *MongoRestore) { r.OutputOptions.PreserveUUID = true },
+		"--oplogReplay":           func(r *MongoRestore) { r.InputOptions.OplogReplay = true },
+		"a server older than 5.0": func(r *MongoRestore) { r.serverVersion = db.Version{4, 4, 0} },
+		"an invalid namespace": func(r *MongoRestore) {
+			r.OutputOptions.ConvertToTimeseries = []string{"$x"}
+		},
+		"a time field without it": func(r *MongoRestore) { r.OutputOptions.ConvertToTimeseries = nil },
+		"a granularity without it": func(r *MongoRestore) {
+			*r.OutputOptions = OutputOptions{Granularity: "hours"}
+		},
+		"a meta field without it": func(r *MongoRestore) {
+			*r.OutputOptions = OutputOptions{MetaField: "m"}
+		},
+	} {
+		restore := newTimeseriesRestore(valid)
+		change(restore)
+		assert.Error(t, restore.validateTimeseriesOptions(), name)
+	}
+
+	restore = newTimeseriesRestore(OutputOptions{})
+	require.NoError(t, restore.validateTimeseriesOptions())
+	assert.False(t, restore.convertsToTimeseries("events", "clicks", ""))
+}
+
+func TestTimeseriesCollectionOptions(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	restore := newTimeseriesRestore(OutputOptions{TimeField: "ts", Granularity: "hours"})
+	options := restore.timeseriesCollectionOptions(
+		&intents.Intent{DB: "events", C: "clicks"},
+		bson.D{
+			{"capped", true},
+			{"size", 4096},
+			{"collation", bson.D{{"locale", "fr"}}},
+			{"idIndex", bson.D{{"name", "_id_"}}},
+			{"validator", bson.D{{"ts", bson.D{{"$exists", true}}}}},
+		},
+	)
+	assert.Equal(t, bson.D{
+		{"collation", bson.D{{"locale", "fr"}}},
+		{"timeseries", bson.D{{"timeField", "ts"}, {"granularity", "hours"}}},
+	}, options)
+}
+
+func TestCheckTimeField(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	restore := newTimeseriesRestore(OutputOptions{TimeField: "ts"})
+	marshal := func(doc bson.D) bson.Raw {
+		raw, err := bson.Marshal(doc)
+		require.NoError(t, err)
+		return raw
+	}
+
+	assert.NoError(t, restore.checkTimeField(marshal(bson.D{{"_id", 1}, {"ts", time.Now()}})))
+
+	err := restore.checkTimeField(marshal(bson.D{{"_id", 2}, {"ts", "2024-01-01"}}))
+	var invalid db.InvalidDocumentError
+	require.ErrorAs(t, err, &invalid)
+	assert.EqualValues(t, 2, invalid.ID)
+	assert.Contains(t, invalid.Reason, "not a date")
+
+	err = restore.checkTimeField(marshal(bson.D{{"_id", 3}}))
+	require.ErrorAs(t, err, &invalid)
+	assert.Contains(t, invalid.Reason, "has no ts")
+}
@@ -0,0 +1,218 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"github.com/mongodb/mongo-tools/mongorestore/ns"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Policies of --documentValidation.
+const (
+	// validationBypass inserts the documents without validating them.
+	validationBypass = "bypass"
+	// validationEnforce validates the documents and, unless --stopOnError is
+	// set, continues through the ones that fail.
+	validationEnforce = "enforce"
+	// validationStrict validates the documents and fails the restore at the
+	// first one that fails.
+	validationStrict = "strict"
+	// validationReport inserts the documents without validating them, then
+	// reports the ones that fail the validator of the collection.
+	validationReport = "report"
+)
+
+// maxReportedInvalidIDs is the number of _ids of the documents that failed
+// validation that are listed for each namespace in --reportFile.
+const maxReportedInvalidIDs = 1000
+
+// validationRule is a --documentValidation argument.
+type validationRule struct {
+	matcher *ns.Matcher
+	policy  string
+}
+
+// ValidationReport describes the documents of a namespace that failed, or
+// with --documentValidation report would fail, its validator.
+type ValidationReport struct {
+	Namespace        string            `json:"namespace"`
+	Policy           string            `json:"policy"`
+	InvalidDocuments int64             `json:"invalidDocuments"`
+	IDs              []json.RawMessage `json:"ids"`
+	IDsTruncated     bool              `json:"idsTruncated,omitempty"`
+}
+
+// validateValidationPolicies parses the <namespace-pattern>=<policy>
+// arguments of --documentValidation.
+func (restore *MongoRestore) validateValidationPolicies() error {
+	restore.validationRules = nil
+	for _, arg := range restore.OutputOptions.DocumentValidation {
+		i := strings.LastIndex(arg, "=")
+		if i <= 0 {
+			return fmt.Errorf(
+				"invalid %v '%v': must be <namespace-pattern>=<policy>",
+				DocumentValidationOption,
+				arg,
+			)
+		}
+		pattern, policy := arg[:i], arg[i+1:]
+		switch policy {
+		case validationBypass, validationEnforce, validationStrict, validationReport:
+		default:
+			return fmt.Errorf(
+				"invalid %v policy '%v': must be bypass, enforce, strict or report",
+				DocumentValidationOption,
+				policy,
+			)
+		}
+		matcher, err := ns.NewMatcher([]string{pattern})
+		if err != nil {
+			return fmt.Errorf("invalid %v pattern '%v': %v", DocumentValidationOption, pattern, err)
+		}
+		restore.validationRules = append(restore.validationRules, validationRule{matcher, policy})
+	}
+	return nil
+}
+
+// validationPolicy returns the --documentValidation policy of the first
+// pattern that matches the namespace documents are restored into. Other
+// namespaces are bypassed with --bypassDocumentValidation and enforced
+// without it. Timeseries collections are always enforced, since their
+// inserts can't bypass validation.
+func (restore *MongoRestore) validationPolicy(namespace, collectionType string) string {
+	if collectionType == "timeseries" {
+		return validationEnforce
+	}
+	for _, rule := range restore.validationRules {
+		if rule.matcher.Has(namespace) {
+			return rule.policy
+		}
+	}
+	if restore.OutputOptions.BypassDocumentValidation {
+		return validationBypass
+	}
+	return validationEnforce
+}
+
+// filterInsertError records the documents of an insert error that failed
+// validation, and filters the error like db.FilterError, except that with the
+// strict policy a document that failed validation fails the restore.
+func (restore *MongoRestore) filterInsertError(namespace, policy string, err error) error {
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) {
+		var ids []bson.RawValue
+		for _, writeErr := range bwe.WriteErrors {
+			if writeErr.Code != db.ErrFailedDocumentValidation {
+				continue
+			}
+			if insert, ok := writeErr.Request.(*mongo.InsertOneModel); ok {
+				if doc, ok := insert.Document.([]byte); ok {
+					ids = append(ids, bson.Raw(doc).Lookup("_id"))
+				}
+			}
+		}
+		if len(ids) > 0 {
+			restore.stats.recordInvalid(namespace, policy, int64(len(ids)), ids)
+			if policy == validationStrict {
+				return fmt.Errorf(
+					"document of %v failed validation with %v strict: %v",
+					namespace,
+					DocumentValidationOption,
+					err,
+				)
+			}
+		}
+	}
+	return db.FilterError(restore.OutputOptions.StopOnError, err)
+}
+
+// reportInvalidDocuments finds the documents of collection that fail its
+// validator, for the report policy of --documentValidation.
+func (restore *MongoRestore) reportInvalidDocuments(collection *mongo.Collection) error {
+	ctx := context.Background()
+	namespace := collection.Database().Name() + "." + collection.Name()
+
+	specs, err := collection.Database().ListCollectionSpecifications(
+		ctx,
+		bson.D{{"name", collection.Name()}},
+	)
+	if err != nil {
+		return fmt.Errorf("error reading the validator of %v: %v", namespace, err)
+	}
+	var validator bson.Raw
+	if len(specs) == 1 && specs[0].Options != nil {
+		value, err := specs[0].Options.LookupErr("validator")
+		if err == nil && value.Type == bson.TypeEmbeddedDocument {
+			validator = value.Document()
+		}
+	}
+	if elements, _ := validator.Elements(); len(elements) == 0 {
+		log.Logvf(log.Info, "%v has no validator to report invalid documents against", namespace)
+		return nil
+	}
+
+	cursor, err := collection.Find(
+		ctx,
+		bson.D{{"$nor", bson.A{validator}}},
+		options.Find().SetProjection(bson.D{{"_id", 1}}),
+	)
+	if err != nil {
+		return fmt.Errorf("error finding the invalid documents of %v: %v", namespace, err)
+	}
+	defer cursor.Close(ctx)
+
+	var count int64
+	var ids []bson.RawValue
+	for cursor.Next(ctx) {
+		count++
+		if len(ids) < maxReportedInvalidIDs {
+			id := cursor.Current.Lookup("_id")
+			id.Value = append([]byte(nil), id.Value...)
+			ids = append(ids, id)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("error finding the invalid documents of %v: %v", namespace, err)
+	}
+	if count == 0 {
+		return nil
+	}
+	restore.stats.recordInvalid(namespace, validationReport, count, ids)
+	log.Logvf(
+		log.Always,
+		"%v %v restored into %v %v its validator",
+		count,
+		util.Pluralize(int(count), "document", "documents"),
+		namespace,
+		util.Pluralize(int(count), "fails", "fail"),
+	)
+	return nil
+}
+
+// idJSON returns id as canonical extended JSON.
+func idJSON(id bson.RawValue) json.RawMessage {
+	content, err := bson.MarshalExtJSON(bson.D{{"_id", id}}, true, false)
+	if err != nil {
+		return json.RawMessage(`null`)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return json.RawMessage(`null`)
+	}
+	return doc["_id"]
+}
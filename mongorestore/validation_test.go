@@ -0,0 +1,114 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newValidationRestore(output OutputOptions) *MongoRestore {
+	return &MongoRestore{
+		OutputOptions: &output,
+		InputOptions:  &InputOptions{},
+	}
+}
+
+func TestValidationPolicy(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	restore := newValidationRestore(OutputOptions{
+		BypassDocumentValidation: true,
+		DocumentValidation: []string{
+			"billing.invoices=strict",
+			"billing.*=report",
+			"logs.*=enforce",
+		},
+	})
+	require.NoError(t, restore.validateValidationPolicies())
+
+	assert.Equal(t, validationStrict, restore.validationPolicy("billing.invoices", ""))
+	assert.Equal(t, validationReport, restore.validationPolicy("billing.payments", ""))
+	assert.Equal(t, validationEnforce, restore.validationPolicy("logs.app", ""))
+	assert.Equal(t, validationBypass, restore.validationPolicy("users.people", ""))
+	assert.Equal(t, validationEnforce, restore.validationPolicy("billing.metrics", "timeseries"))
+
+	restore.OutputOptions.BypassDocumentValidation = false
+	assert.Equal(t, validationEnforce, restore.validationPolicy("users.people", ""))
+
+	for _, arg := range []string{"billing.*", "=strict", "billing.*=skip"} {
+		restore := newValidationRestore(OutputOptions{DocumentValidation: []string{arg}})
+		assert.Error(t, restore.validateValidationPolicies(), arg)
+	}
+}
+
+func TestFilterInsertError(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	invalid, err := bson.Marshal(bson.D{{"_id", int32(7)}, {"amount", "x"}})
+	require.NoError(t, err)
+	duplicate, err := bson.Marshal(bson.D{{"_id", int32(8)}})
+	require.NoError(t, err)
+	bulkErr := mongo.BulkWriteException{
+		WriteErrors: []mongo.BulkWriteError{
+			{
+				WriteError: mongo.WriteError{Code: db.ErrFailedDocumentValidation},
+				Request:    mongo.NewInsertOneModel().SetDocument(invalid),
+			},
+			{
+				WriteError: mongo.WriteError{Code: db.ErrDuplicateKeyCode},
+				Request:    mongo.NewInsertOneModel().SetDocument(duplicate),
+			},
+		},
+	}
+
+	restore := newValidationRestore(OutputOptions{})
+	assert.NoError(t, restore.filterInsertError("billing.payments", validationEnforce, bulkErr))
+	assert.Error(t, restore.filterInsertError("billing.invoices", validationStrict, bulkErr))
+
+	report := restore.stats.report(Result{})
+	require.Len(t, report.Validation, 2)
+	assert.Equal(t, ValidationReport{
+		Namespace:        "billing.invoices",
+		Policy:           validationStrict,
+		InvalidDocuments: 1,
+		IDs:              []json.RawMessage{json.RawMessage(`{"$numberInt":"7"}`)},
+	}, report.Validation[0])
+	assert.Equal(t, "billing.payments", report.Validation[1].Namespace)
+}
+
+func TestRecordInvalidTruncatesIDs(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	var stats restoreStats
+	ids := make([]bson.RawValue, maxReportedInvalidIDs)
+	for i := range ids {
+		ids[i] = bson.RawValue{Type: bson.TypeString, Value: bsonString(t, "doc")}
+	}
+	stats.recordInvalid("app.orders", validationReport, int64(len(ids))+5, ids)
+	stats.recordInvalid("app.orders", validationReport, 1, ids[:1])
+
+	report := stats.report(Result{})
+	require.Len(t, report.Validation, 1)
+	assert.Equal(t, int64(maxReportedInvalidIDs+6), report.Validation[0].InvalidDocuments)
+	assert.Len(t, report.Validation[0].IDs, maxReportedInvalidIDs)
+	assert.True(t, report.Validation[0].IDsTruncated)
+	assert.Equal(t, json.RawMessage(`"doc"`), report.Validation[0].IDs[0])
+}
+
+func bsonString(t *testing.T, s string) []byte {
+	doc, err := bson.Marshal(bson.D{{"s", s}})
+	require.NoError(t, err)
+	return bson.Raw(doc).Lookup("s").Value
+}
@@ -0,0 +1,310 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/util"
+	"github.com/mongodb/mongo-tools/mongorestore/ns"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// valueMapFile is the format of a --valueMapFile, e.g.
+//
+//	{"remaps": [{"namespace": "app.*", "field": "tenantId",
+//	             "values": [{"from": "acme", "to": "acme-eu"}]}]}
+type valueMapFile struct {
+	Remaps []struct {
+		Namespace string `bson:"namespace"`
+		Field     string `bson:"field"`
+		Values    []struct {
+			From bson.RawValue `bson:"from"`
+			To   bson.RawValue `bson:"to"`
+		} `bson:"values"`
+	} `bson:"remaps"`
+}
+
+// fieldRemap replaces the values of one field in the namespaces matching a
+// pattern. Values only match if both their BSON type and value are the same,
+// so 1 and NumberLong(1) are different values.
+type fieldRemap struct {
+	matcher  *ns.Matcher
+	path     []string
+	values   map[string]bson.RawValue
+	remapped atomic.Int64
+}
+
+// valueMapper applies the remaps of a --valueMapFile to the restored
+// documents and to the replayed oplog entries, so that the operations
+// replayed on a remapped document still find it.
+type valueMapper struct {
+	remaps []*fieldRemap
+
+	mu          sync.Mutex
+	byNamespace map[string][]*fieldRemap
+}
+
+// loadValueMapper reads a --valueMapFile.
+func loadValueMapper(path string) (*valueMapper, error) {
+	content, err := os.ReadFile(util.ToUniversalPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("error reading value map file: %v", err)
+	}
+	var file valueMapFile
+	if err := bson.UnmarshalExtJSON(content, false, &file); err != nil {
+		return nil, fmt.Errorf("error parsing value map file %v: %v", path, err)
+	}
+	if len(file.Remaps) == 0 {
+		return nil, fmt.Errorf("value map file %v has no remaps", path)
+	}
+
+	m := &valueMapper{byNamespace: map[string][]*fieldRemap{}}
+	for i, remap := range file.Remaps {
+		if remap.Namespace == "" || remap.Field == "" {
+			return nil, fmt.Errorf("remap %v of %v needs a namespace and a field", i, path)
+		}
+		matcher, err := ns.NewMatcher([]string{remap.Namespace})
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace in remap %v of %v: %v", i, path, err)
+		}
+		fr := &fieldRemap{
+			matcher: matcher,
+			path:    strings.Split(remap.Field, "."),
+			values:  map[string]bson.RawValue{},
+		}
+		for _, part := range fr.path {
+			if part == "" || strings.HasPrefix(part, "$") {
+				return nil, fmt.Errorf("invalid field %q in remap %v of %v", remap.Field, i, path)
+			}
+		}
+		if len(remap.Values) == 0 {
+			return nil, fmt.Errorf("remap %v of %v has no values", i, path)
+		}
+		for _, value := range remap.Values {
+			if value.From.Type == 0 || value.To.Type == 0 {
+				return nil, fmt.Errorf("every value in remap %v of %v needs a from and a to", i, path)
+			}
+			key := rawValueKey(value.From)
+			if _, ok := fr.values[key]; ok {
+				return nil, fmt.Errorf("value %v is remapped twice in remap %v of %v",
+					value.From, i, path)
+			}
+			fr.values[key] = value.To
+		}
+		m.remaps = append(m.remaps, fr)
+	}
+	return m, nil
+}
+
+// rawValueKey identifies a value by its BSON type and bytes.
+func rawValueKey(value bson.RawValue) string {
+	return string(rune(value.Type)) + string(value.Value)
+}
+
+// forNamespace returns the remaps that apply to a namespace. It is safe to
+// call on a nil valueMapper.
+func (m *valueMapper) forNamespace(namespace string) []*fieldRemap {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	remaps, ok := m.byNamespace[namespace]
+	if !ok {
+		for _, remap := range m.remaps {
+			if remap.matcher.Has(namespace) {
+				remaps = append(remaps, remap)
+			}
+		}
+		m.byNamespace[namespace] = remaps
+	}
+	return remaps
+}
+
+// logSummary logs how many values each remap replaced.
+func (m *valueMapper) logSummary() {
+	if m == nil {
+		return
+	}
+	for _, remap := range m.remaps {
+		log.Logvf(log.Always, "remapped %v values of %v", remap.remapped.Load(),
+			strings.Join(remap.path, "."))
+	}
+}
+
+// remapRaw returns doc with the values of remaps replaced. doc is returned
+// unchanged if nothing is remapped.
+func remapRaw(doc bson.Raw, remaps []*fieldRemap) (bson.Raw, error) {
+	var d bson.D
+	if err := bson.Unmarshal(doc, &d); err != nil {
+		return nil, err
+	}
+	changed := false
+	for _, remap := range remaps {
+		if remap.remapPath(d, remap.path) {
+			changed = true
+		}
+	}
+	if !changed {
+		return doc, nil
+	}
+	return bson.Marshal(d)
+}
+
+// remapPath replaces the value at path in doc, or in every element of the
+// arrays along it. It reports whether anything was replaced.
+func (remap *fieldRemap) remapPath(doc bson.D, path []string) bool {
+	for i := range doc {
+		if doc[i].Key != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return remap.remapValue(&doc[i].Value)
+		}
+		return remap.remapNested(doc[i].Value, path[1:])
+	}
+	return false
+}
+
+// remapNested follows the rest of a path into a subdocument or array.
+func (remap *fieldRemap) remapNested(value interface{}, path []string) bool {
+	switch v := value.(type) {
+	case bson.D:
+		return remap.remapPath(v, path)
+	case bson.A:
+		changed := false
+		for _, elem := range v {
+			if remap.remapNested(elem, path) {
+				changed = true
+			}
+		}
+		return changed
+	}
+	return false
+}
+
+// remapValue replaces a value if it is remapped, or every remapped element
+// of an array value, which is how queries match arrays.
+func (remap *fieldRemap) remapValue(value *interface{}) bool {
+	if arr, ok := (*value).(bson.A); ok {
+		changed := false
+		for i := range arr {
+			if remap.remapValue(&arr[i]) {
+				changed = true
+			}
+		}
+		return changed
+	}
+	t, data, err := bson.MarshalValue(*value)
+	if err != nil {
+		return false
+	}
+	to, ok := remap.values[rawValueKey(bson.RawValue{Type: t, Value: data})]
+	if !ok {
+		return false
+	}
+	*value = to
+	remap.remapped.Add(1)
+	return true
+}
+
+// remapOplog replaces the remapped values in an insert, update or delete
+// oplog entry the same way as in the restored documents: in the document of
+// an insert, in the _id and shard key of an update or delete, and in the
+// values an update sets. It is safe to call on a nil valueMapper.
+func (m *valueMapper) remapOplog(op *db.Oplog) {
+	remaps := m.forNamespace(op.Namespace)
+	if len(remaps) == 0 {
+		return
+	}
+	for _, remap := range remaps {
+		switch op.Operation {
+		case "i", "d":
+			remap.remapPath(op.Object, remap.path)
+		case "u":
+			remap.remapPath(op.Query, remap.path)
+			remap.remapUpdate(op.Object)
+		}
+	}
+}
+
+// remapUpdate replaces the remapped values set by the o field of an update
+// oplog entry, which is a replacement document, a $v:2 diff, or a legacy
+// update with $set.
+func (remap *fieldRemap) remapUpdate(update bson.D) {
+	isModifier := false
+	for i := range update {
+		switch key := update[i].Key; {
+		case key == "diff":
+			if diff, ok := update[i].Value.(bson.D); ok {
+				remap.remapDiff(diff, remap.path)
+			}
+			isModifier = true
+		case key == "$set":
+			if set, ok := update[i].Value.(bson.D); ok {
+				remap.remapSet(set)
+			}
+			isModifier = true
+		case strings.HasPrefix(key, "$"):
+			isModifier = true
+		}
+	}
+	if !isModifier {
+		remap.remapPath(update, remap.path)
+	}
+}
+
+// remapSet replaces the remapped values of a $set, whose fields may be
+// dotted paths to, or into, the remapped field.
+func (remap *fieldRemap) remapSet(set bson.D) {
+	for i := range set {
+		path := strings.Split(set[i].Key, ".")
+		if len(path) > len(remap.path) || !samePrefix(path, remap.path) {
+			continue
+		}
+		if len(path) == len(remap.path) {
+			remap.remapValue(&set[i].Value)
+		} else {
+			remap.remapNested(set[i].Value, remap.path[len(path):])
+		}
+	}
+}
+
+// remapDiff replaces the remapped values of a $v:2 update diff, where "u"
+// and "i" hold the updated and inserted fields, and "s<field>" the diff of
+// a subdocument.
+func (remap *fieldRemap) remapDiff(diff bson.D, path []string) {
+	for i := range diff {
+		switch key := diff[i].Key; key {
+		case "u", "i":
+			if fields, ok := diff[i].Value.(bson.D); ok {
+				remap.remapPath(fields, path)
+			}
+		case "s" + path[0]:
+			if sub, ok := diff[i].Value.(bson.D); ok && len(path) > 1 {
+				remap.remapDiff(sub, path[1:])
+			}
+		}
+	}
+}
+
+// samePrefix reports whether path is a prefix of field.
+func samePrefix(path, field []string) bool {
+	for i := range path {
+		if path[i] != field[i] {
+			return false
+		}
+	}
+	return true
+}
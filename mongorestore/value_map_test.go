@@ -0,0 +1,202 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func writeValueMap(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "values.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// remarshal returns a document as it reads after a round trip through BSON.
+func remarshal(t *testing.T, doc bson.D) bson.D {
+	raw, err := bson.Marshal(doc)
+	require.NoError(t, err)
+	var out bson.D
+	require.NoError(t, bson.Unmarshal(raw, &out))
+	return out
+}
+
+func TestLoadValueMapper(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	for _, tc := range []struct{ content, expected string }{
+		{`{"remaps": []}`, "has no remaps"},
+		{
+			`{"remaps": [{"field": "t", "values": [{"from": 1, "to": 2}]}]}`,
+			"needs a namespace and a field",
+		},
+		{
+			`{"remaps": [{"namespace": "a.b", "field": "t.$x", "values": [{"from": 1, "to": 2}]}]}`,
+			"invalid field",
+		},
+		{`{"remaps": [{"namespace": "a.b", "field": "t", "values": []}]}`, "has no values"},
+		{
+			`{"remaps": [{"namespace": "a.b", "field": "t", "values": [{"from": 1}]}]}`,
+			"needs a from and a to",
+		},
+		{
+			`{"remaps": [{"namespace": "a.b", "field": "t",
+			  "values": [{"from": 1, "to": 2}, {"from": 1, "to": 3}]}]}`,
+			"remapped twice",
+		},
+		{`not json`, "error parsing value map file"},
+	} {
+		_, err := loadValueMapper(writeValueMap(t, tc.content))
+		assert.ErrorContains(t, err, tc.expected, tc.content)
+	}
+}
+
+func TestValueMapper(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	m, err := loadValueMapper(writeValueMap(t, `{"remaps": [
+		{"namespace": "app.*", "field": "tenantId",
+		 "values": [{"from": "acme", "to": "acme-eu"}, {"from": 1, "to": 10}]},
+		{"namespace": "app.orders", "field": "owner.org",
+		 "values": [{"from": {"$oid": "5f0000000000000000000001"}, "to": "org1"}]}
+	]}`))
+	require.NoError(t, err)
+
+	assert.Len(t, m.forNamespace("app.orders"), 2)
+	assert.Len(t, m.forNamespace("app.users"), 1)
+	assert.Empty(t, m.forNamespace("other.orders"))
+	assert.Empty(t, (*valueMapper)(nil).forNamespace("app.orders"))
+
+	orders := m.forNamespace("app.orders")
+	oid, err := primitive.ObjectIDFromHex("5f0000000000000000000001")
+	require.NoError(t, err)
+
+	t.Run("restored documents", func(t *testing.T) {
+		raw, err := bson.Marshal(bson.D{
+			{"_id", 1},
+			{"tenantId", "acme"},
+			{"owner", bson.A{bson.D{{"org", oid}}, bson.D{{"org", "x"}}}},
+		})
+		require.NoError(t, err)
+		remapped, err := remapRaw(raw, orders)
+		require.NoError(t, err)
+		var doc bson.D
+		require.NoError(t, bson.Unmarshal(remapped, &doc))
+		assert.Equal(t, remarshal(t, bson.D{
+			{"_id", int32(1)},
+			{"tenantId", "acme-eu"},
+			{"owner", bson.A{bson.D{{"org", "org1"}}, bson.D{{"org", "x"}}}},
+		}), doc)
+	})
+
+	t.Run("values of another type are not remapped", func(t *testing.T) {
+		raw, err := bson.Marshal(bson.D{{"tenantId", int64(1)}, {"other", "acme"}})
+		require.NoError(t, err)
+		remapped, err := remapRaw(raw, orders)
+		require.NoError(t, err)
+		assert.Equal(t, bson.Raw(raw), remapped)
+
+		raw, err = bson.Marshal(bson.D{{"tenantId", int32(1)}})
+		require.NoError(t, err)
+		remapped, err = remapRaw(raw, orders)
+		require.NoError(t, err)
+		assert.NotEqual(t, bson.Raw(raw), remapped)
+	})
+
+	t.Run("corrupt documents fail to be remapped", func(t *testing.T) {
+		raw, err := bson.Marshal(bson.D{{"tenantId", "acme"}})
+		require.NoError(t, err)
+		_, err = remapRaw(raw[:len(raw)-3], orders)
+		assert.Error(t, err)
+	})
+
+	t.Run("oplog entries", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			op       db.Oplog
+			expected db.Oplog
+		}{
+			"insert": {
+				op: db.Oplog{Operation: "i", Namespace: "app.orders",
+					Object: bson.D{{"_id", 1}, {"tenantId", "acme"}}},
+				expected: db.Oplog{Operation: "i", Namespace: "app.orders",
+					Object: bson.D{{"_id", 1}, {"tenantId", "acme-eu"}}},
+			},
+			"delete": {
+				op: db.Oplog{Operation: "d", Namespace: "app.users",
+					Object: bson.D{{"_id", 1}, {"tenantId", "acme"}}},
+				expected: db.Oplog{Operation: "d", Namespace: "app.users",
+					Object: bson.D{{"_id", 1}, {"tenantId", "acme-eu"}}},
+			},
+			"other namespace": {
+				op: db.Oplog{Operation: "i", Namespace: "other.orders",
+					Object: bson.D{{"tenantId", "acme"}}},
+				expected: db.Oplog{Operation: "i", Namespace: "other.orders",
+					Object: bson.D{{"tenantId", "acme"}}},
+			},
+			"update diff": {
+				op: db.Oplog{Operation: "u", Namespace: "app.orders",
+					Query: bson.D{{"_id", 1}, {"tenantId", "acme"}},
+					Object: bson.D{{"$v", 2}, {"diff", bson.D{
+						{"u", bson.D{{"tenantId", "acme"}}},
+						{"sowner", bson.D{{"i", bson.D{{"org", oid}}}}},
+					}}}},
+				expected: db.Oplog{Operation: "u", Namespace: "app.orders",
+					Query: bson.D{{"_id", 1}, {"tenantId", "acme-eu"}},
+					Object: bson.D{{"$v", 2}, {"diff", bson.D{
+						{"u", bson.D{{"tenantId", "acme-eu"}}},
+						{"sowner", bson.D{{"i", bson.D{{"org", "org1"}}}}},
+					}}}},
+			},
+			"legacy update": {
+				op: db.Oplog{Operation: "u", Namespace: "app.orders",
+					Query: bson.D{{"_id", 1}},
+					Object: bson.D{{"$set", bson.D{
+						{"tenantId", "acme"},
+						{"owner.org", oid},
+						{"note", "acme"},
+					}}}},
+				expected: db.Oplog{Operation: "u", Namespace: "app.orders",
+					Query: bson.D{{"_id", 1}},
+					Object: bson.D{{"$set", bson.D{
+						{"tenantId", "acme-eu"},
+						{"owner.org", "org1"},
+						{"note", "acme"},
+					}}}},
+			},
+			"replacement": {
+				op: db.Oplog{Operation: "u", Namespace: "app.orders",
+					Query:  bson.D{{"_id", 1}},
+					Object: bson.D{{"_id", 1}, {"tenantId", "acme"}}},
+				expected: db.Oplog{Operation: "u", Namespace: "app.orders",
+					Query:  bson.D{{"_id", 1}},
+					Object: bson.D{{"_id", 1}, {"tenantId", "acme-eu"}}},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				op := tc.op
+				op.Object = remarshal(t, op.Object)
+				if op.Query != nil {
+					op.Query = remarshal(t, op.Query)
+				}
+				m.remapOplog(&op)
+				assert.Equal(t, remarshal(t, tc.expected.Object), remarshal(t, op.Object))
+				if tc.expected.Query != nil {
+					assert.Equal(t, remarshal(t, tc.expected.Query), remarshal(t, op.Query))
+				}
+			})
+		}
+	})
+}
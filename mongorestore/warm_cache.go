@@ -0,0 +1,264 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/idx"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// warmTask is a restored collection for the cache warmer to load, or some of
+// its indexes.
+type warmTask struct {
+	namespace options.Namespace
+	// indexes names the indexes to traverse; without them, the collection is
+	// scanned and all of its indexes are traversed
+	indexes []string
+}
+
+// cacheWarmer loads the restored collections into the cache of the target
+// while the restore goes on: each collection once its data is restored, and
+// the indexes built after the data once they are built. Warming is best
+// effort, so failures are logged rather than failing the restore.
+type cacheWarmer struct {
+	// namespaces maps the namespaces of the collections to warm to the ones
+	// that hold their data
+	namespaces map[string]options.Namespace
+	warm       func(warmTask)
+	wg         sync.WaitGroup
+
+	mu     sync.Mutex
+	ready  *sync.Cond
+	queue  []warmTask
+	closed bool
+}
+
+// warmCacheNamespaces returns the restored collections that --warmCache
+// should load into the cache, keyed by namespace. Views have nothing to load,
+// and timeseries collections are warmed through their buckets collection.
+func (restore *MongoRestore) warmCacheNamespaces() map[string]options.Namespace {
+	namespaces := map[string]options.Namespace{}
+	for _, intent := range restore.manager.Intents() {
+		if intent.IsView() || intent.IsSpecialCollection() || intent.IsOplog() {
+			continue
+		}
+		if !restore.warmCacheMatcher.Has(intent.Namespace()) {
+			continue
+		}
+		namespaces[intent.Namespace()] = options.Namespace{
+			DB:         intent.DB,
+			Collection: intent.DataCollection(),
+		}
+	}
+	return namespaces
+}
+
+// startCacheWarmer starts warming the cache for --warmCache, with up to
+// --numWarmCacheWorkers collections at a time.
+func (restore *MongoRestore) startCacheWarmer() {
+	namespaces := restore.warmCacheNamespaces()
+	if len(namespaces) == 0 {
+		return
+	}
+
+	session, err := restore.SessionProvider.GetSession()
+	if err != nil {
+		log.Logvf(log.Always, "not warming the cache: error getting a session: %v", err)
+		return
+	}
+
+	log.Logvf(
+		log.Always,
+		"warming the cache for %v %v as they are restored",
+		len(namespaces),
+		util.Pluralize(len(namespaces), "collection", "collections"),
+	)
+	restore.cacheWarmer = newCacheWarmer(
+		namespaces,
+		restore.OutputOptions.NumWarmCacheWorkers,
+		func(task warmTask) {
+			if restore.terminate.Load() {
+				return
+			}
+			coll := session.Database(task.namespace.DB).Collection(task.namespace.Collection)
+			if err := warmCollection(coll, task.indexes); err != nil {
+				log.Logvf(log.Always, "error warming the cache for %v: %v", &task.namespace, err)
+			}
+		},
+	)
+}
+
+func newCacheWarmer(
+	namespaces map[string]options.Namespace,
+	workers int,
+	warm func(warmTask),
+) *cacheWarmer {
+	w := &cacheWarmer{namespaces: namespaces, warm: warm}
+	w.ready = sync.NewCond(&w.mu)
+	for i := 0; i < workers; i++ {
+		w.wg.Add(1)
+		go w.work()
+	}
+	return w
+}
+
+func (w *cacheWarmer) work() {
+	defer w.wg.Done()
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && !w.closed {
+			w.ready.Wait()
+		}
+		if len(w.queue) == 0 {
+			w.mu.Unlock()
+			return
+		}
+		task := w.queue[0]
+		w.queue = w.queue[1:]
+		w.mu.Unlock()
+		w.warm(task)
+	}
+}
+
+// add queues task without waiting for the workers, so that the restore isn't
+// slowed down by warming.
+func (w *cacheWarmer) add(task warmTask) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	w.queue = append(w.queue, task)
+	w.ready.Signal()
+}
+
+// restored warms the collection of namespace, whose data is restored.
+func (w *cacheWarmer) restored(namespace string) {
+	if w == nil {
+		return
+	}
+	if data, ok := w.namespaces[namespace]; ok {
+		w.add(warmTask{namespace: data})
+	}
+}
+
+// indexed traverses the indexes of namespace that were just built.
+func (w *cacheWarmer) indexed(namespace string, indexes []*idx.IndexDocument) {
+	if w == nil || len(indexes) == 0 {
+		return
+	}
+	data, ok := w.namespaces[namespace]
+	if !ok {
+		return
+	}
+	task := warmTask{namespace: data}
+	for _, index := range indexes {
+		task.indexes = append(task.indexes, indexName(index))
+	}
+	w.add(task)
+}
+
+// finish waits for the queued collections to be warmed.
+func (w *cacheWarmer) finish() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	w.closed = true
+	w.ready.Broadcast()
+	w.mu.Unlock()
+	w.wg.Wait()
+}
+
+// cancel drops the queued collections and waits for the ones being warmed,
+// e.g. when the restore fails.
+func (w *cacheWarmer) cancel() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	w.queue = nil
+	w.mu.Unlock()
+	w.finish()
+}
+
+// warmCollection scans coll in natural order and then traverses each of its
+// indexes, so that the server reads their pages into its cache. If indexes
+// are given, only they are traversed.
+func warmCollection(coll *mongo.Collection, indexes []string) error {
+	namespace := coll.Database().Name() + "." + coll.Name()
+	start := time.Now()
+
+	scanned := len(indexes) == 0
+	if scanned {
+		if err := scanWithHint(coll, bson.D{{Key: "$natural", Value: 1}}); err != nil {
+			return fmt.Errorf("error scanning collection: %v", err)
+		}
+
+		specs, err := coll.Indexes().ListSpecifications(context.Background())
+		if err != nil {
+			return fmt.Errorf("error listing indexes: %v", err)
+		}
+		for _, spec := range specs {
+			indexes = append(indexes, spec.Name)
+		}
+	}
+	for _, index := range indexes {
+		// some indexes, e.g. text or hidden ones, cannot be hinted; the rest
+		// are still worth warming
+		if err := scanWithHint(coll, index); err != nil {
+			log.Logvf(log.Info, "could not traverse index %v of %v: %v", index, namespace, err)
+		}
+	}
+
+	warmed := fmt.Sprintf(
+		"%v %v of %v",
+		len(indexes),
+		util.Pluralize(len(indexes), "index", "indexes"),
+		namespace,
+	)
+	if scanned {
+		warmed = namespace + " and " + warmed
+	}
+	log.Logvf(
+		log.Always,
+		"warmed the cache for %v in %v",
+		warmed,
+		time.Since(start).Round(time.Millisecond),
+	)
+	return nil
+}
+
+// scanWithHint counts the documents of coll using the given plan hint, which
+// makes the server read the whole collection or index without returning any
+// documents.
+func scanWithHint(coll *mongo.Collection, hint interface{}) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "n", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+	cursor, err := coll.Aggregate(context.Background(), pipeline, mopt.Aggregate().SetHint(hint))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+	for cursor.Next(context.Background()) {
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,101 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongorestore
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/idx"
+	"github.com/mongodb/mongo-tools/common/intents"
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/mongodb/mongo-tools/mongorestore/ns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestWarmCacheNamespaces(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	manager := intents.NewIntentManager()
+	for _, intent := range []*intents.Intent{
+		{DB: "db1", C: "b"},
+		{DB: "db1", C: "a"},
+		{DB: "db1", C: "view", Type: "view"},
+		{DB: "db1", C: "metrics", Type: "timeseries"},
+		{DB: "db2", C: "c"},
+		{DB: "admin", C: "system.users"},
+	} {
+		manager.Put(intent)
+	}
+
+	matcher, err := ns.NewMatcher([]string{"db1.*"})
+	require.NoError(t, err)
+	restore := &MongoRestore{manager: manager, warmCacheMatcher: matcher}
+
+	assert.Equal(
+		t,
+		map[string]options.Namespace{
+			"db1.a":       {DB: "db1", Collection: "a"},
+			"db1.b":       {DB: "db1", Collection: "b"},
+			"db1.metrics": {DB: "db1", Collection: "system.buckets.metrics"},
+		},
+		restore.warmCacheNamespaces(),
+		"views and unmatched namespaces are skipped, timeseries are warmed through their buckets",
+	)
+}
+
+func TestCacheWarmer(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	var mu sync.Mutex
+	var warmed []warmTask
+	release := make(chan struct{})
+	warmer := newCacheWarmer(
+		map[string]options.Namespace{
+			"db.a":       {DB: "db", Collection: "a"},
+			"db.metrics": {DB: "db", Collection: "system.buckets.metrics"},
+		},
+		1,
+		func(task warmTask) {
+			<-release
+			mu.Lock()
+			defer mu.Unlock()
+			warmed = append(warmed, task)
+		},
+	)
+
+	// queueing doesn't wait for the collections to be warmed
+	warmer.restored("db.a")
+	warmer.restored("db.unmatched")
+	warmer.restored("db.metrics")
+	warmer.indexed("db.a", nil)
+	warmer.indexed("db.metrics", []*idx.IndexDocument{
+		{Key: bson.D{{"meta", 1}}, Options: bson.M{"name": "meta_1"}},
+	})
+	close(release)
+	warmer.finish()
+
+	assert.Equal(t, []warmTask{
+		{namespace: options.Namespace{DB: "db", Collection: "a"}},
+		{namespace: options.Namespace{DB: "db", Collection: "system.buckets.metrics"}},
+		{
+			namespace: options.Namespace{DB: "db", Collection: "system.buckets.metrics"},
+			indexes:   []string{"meta_1"},
+		},
+	}, warmed)
+
+	// nothing is queued once the restore is done
+	warmer.restored("db.a")
+	assert.Len(t, warmed, 3)
+
+	var unset *cacheWarmer
+	unset.restored("db.a")
+	unset.cancel()
+}
@@ -0,0 +1,161 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongostat
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mongodb/mongo-tools/mongostat/status"
+)
+
+const (
+	// adaptiveMinInterval is the shortest interval --adaptive will poll at.
+	adaptiveMinInterval = 250 * time.Millisecond
+
+	// adaptiveMaxFactor bounds how far --adaptive lengthens the interval,
+	// as a multiple of the interval given on the command line.
+	adaptiveMaxFactor = 8
+
+	// adaptiveQueueSpike is the minimum increase in queued operations
+	// between two samples that counts as a spike.
+	adaptiveQueueSpike = 10
+
+	// adaptiveOpsBurst is the minimum increase in operations per second
+	// between two samples that counts as a burst.
+	adaptiveOpsBurst = 100
+
+	// adaptiveSteadyChange is the largest relative change in operations per
+	// second for which a node with nothing queued is considered idle.
+	adaptiveSteadyChange = 0.25
+)
+
+// adaptiveInterval picks the time to wait before the next poll of a node
+// from how much the node's metrics changed between consecutive samples. It
+// drops to the minimum interval when it sees an anomaly, stays short while
+// the node is busy, and backs off towards the maximum while it is idle.
+type adaptiveInterval struct {
+	base, min, max time.Duration
+	current        time.Duration
+
+	lastStat *status.ServerStatus
+	lastOps  *float64
+}
+
+func newAdaptiveInterval(base time.Duration) *adaptiveInterval {
+	minInterval := base / 4
+	if minInterval < adaptiveMinInterval {
+		minInterval = adaptiveMinInterval
+	}
+	if minInterval > base {
+		minInterval = base
+	}
+	return &adaptiveInterval{
+		base:    base,
+		min:     minInterval,
+		max:     base * adaptiveMaxFactor,
+		current: base,
+	}
+}
+
+// next records stat as the latest sample and returns how long to sleep
+// before taking the following one. If the sample is anomalous compared to the
+// previous one, stat.Anomaly is set to a short description of why. A nil stat
+// (a failed poll) resets the interval to the base interval.
+func (a *adaptiveInterval) next(stat *status.ServerStatus) time.Duration {
+	if stat == nil {
+		a.lastStat = nil
+		a.lastOps = nil
+		a.current = a.base
+		return a.current
+	}
+
+	prev := a.lastStat
+	a.lastStat = stat
+	if prev == nil {
+		return a.current
+	}
+
+	// the op rate needs two samples, so there is nothing to compare it to
+	// until the third one
+	ops := opsPerSecond(prev, stat)
+	prevOps := ops
+	if a.lastOps != nil {
+		prevOps = *a.lastOps
+	}
+	a.lastOps = &ops
+
+	stat.Anomaly = detectAnomaly(prev, stat, prevOps, ops)
+	switch {
+	case stat.Anomaly != "":
+		a.current = a.min
+	case totalQueued(stat) > 0 || relativeChange(prevOps, ops) > adaptiveSteadyChange:
+		// the node is busy; keep sampling quickly if we already are, but
+		// come back from a long idle interval right away
+		if a.current > a.base {
+			a.current = a.base
+		}
+	default:
+		a.current *= 2
+		if a.current > a.max {
+			a.current = a.max
+		}
+	}
+	return a.current
+}
+
+// detectAnomaly returns a description of what changed sharply between the
+// previous and latest samples, or the empty string if nothing did.
+func detectAnomaly(prev, stat *status.ServerStatus, prevOps, ops float64) string {
+	oldState := status.ReadRepl(nil, prev, nil)
+	newState := status.ReadRepl(nil, stat, nil)
+	if oldState != newState {
+		return fmt.Sprintf("repl state %v -> %v", oldState, newState)
+	}
+	oldQueued := totalQueued(prev)
+	newQueued := totalQueued(stat)
+	if newQueued >= oldQueued+adaptiveQueueSpike && newQueued >= 2*oldQueued {
+		return fmt.Sprintf("queued %v -> %v", oldQueued, newQueued)
+	}
+	if ops >= prevOps+adaptiveOpsBurst && ops >= 2*prevOps {
+		return fmt.Sprintf("ops/s %.0f -> %.0f", prevOps, ops)
+	}
+	return ""
+}
+
+func totalQueued(stat *status.ServerStatus) int64 {
+	qr, qw := status.QueuedOps(stat)
+	return qr + qw
+}
+
+// opsPerSecond returns the combined rate of all opcounters between two
+// samples.
+func opsPerSecond(prev, stat *status.ServerStatus) float64 {
+	secs := stat.SampleTime.Sub(prev.SampleTime).Seconds()
+	if secs <= 0 || prev.Opcounters == nil || stat.Opcounters == nil {
+		return 0
+	}
+	return float64(totalOps(stat.Opcounters)-totalOps(prev.Opcounters)) / secs
+}
+
+func totalOps(ops *status.OpcountStats) int64 {
+	return ops.Insert + ops.Query + ops.Update + ops.Delete + ops.GetMore + ops.Command
+}
+
+func relativeChange(oldVal, newVal float64) float64 {
+	if oldVal == 0 {
+		if newVal == 0 {
+			return 0
+		}
+		return 1
+	}
+	change := (newVal - oldVal) / oldVal
+	if change < 0 {
+		return -change
+	}
+	return change
+}
@@ -0,0 +1,96 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongostat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/mongodb/mongo-tools/mongostat/status"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// sampler produces ServerStatus samples one second apart.
+type sampler struct {
+	now time.Time
+	ops int64
+}
+
+func (s *sampler) sample(opsPerSec, queued int64, primary bool) *status.ServerStatus {
+	s.now = s.now.Add(time.Second)
+	s.ops += opsPerSec
+	return &status.ServerStatus{
+		SampleTime: s.now,
+		Opcounters: &status.OpcountStats{Query: s.ops},
+		GlobalLock: &status.GlobalLockStats{
+			CurrentQueue: &status.QueueStats{Readers: queued},
+		},
+		Repl: &status.ReplStatus{SetName: "rs", IsMaster: primary, Secondary: !primary},
+	}
+}
+
+func TestAdaptiveInterval(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With an adaptive interval based on 4 seconds", t, func() {
+		a := newAdaptiveInterval(4 * time.Second)
+		s := &sampler{}
+		So(a.next(s.sample(50, 0, true)), ShouldEqual, 4*time.Second)
+		So(a.next(s.sample(50, 0, true)), ShouldEqual, 8*time.Second)
+
+		Convey("the interval backs off while idle, up to the maximum", func() {
+			for i := 0; i < 10; i++ {
+				a.next(s.sample(50, 0, true))
+			}
+			So(a.current, ShouldEqual, 4*time.Second*adaptiveMaxFactor)
+
+			Convey("and returns to the base interval once the node is busy", func() {
+				stat := s.sample(50, 3, true)
+				So(a.next(stat), ShouldEqual, 4*time.Second)
+				So(stat.Anomaly, ShouldEqual, "")
+			})
+		})
+
+		Convey("a queue spike drops to the minimum and is reported", func() {
+			stat := s.sample(50, 40, true)
+			So(a.next(stat), ShouldEqual, time.Second)
+			So(stat.Anomaly, ShouldEqual, "queued 0 -> 40")
+
+			Convey("and sampling stays fast while the queue is draining", func() {
+				stat := s.sample(50, 30, true)
+				So(a.next(stat), ShouldEqual, time.Second)
+				So(stat.Anomaly, ShouldEqual, "")
+			})
+		})
+
+		Convey("a burst of operations is reported", func() {
+			stat := s.sample(1000, 0, true)
+			So(a.next(stat), ShouldEqual, time.Second)
+			So(stat.Anomaly, ShouldEqual, "ops/s 50 -> 1000")
+		})
+
+		Convey("a replica set state change is reported", func() {
+			stat := s.sample(50, 0, false)
+			So(a.next(stat), ShouldEqual, time.Second)
+			So(stat.Anomaly, ShouldEqual, "repl state PRI -> SEC")
+		})
+
+		Convey("a failed poll resets to the base interval", func() {
+			So(a.next(nil), ShouldEqual, 4*time.Second)
+			stat := s.sample(1000, 0, true)
+			So(a.next(stat), ShouldEqual, 4*time.Second)
+			So(stat.Anomaly, ShouldEqual, "")
+		})
+	})
+
+	Convey("The minimum interval is never longer than the base interval", t, func() {
+		a := newAdaptiveInterval(100 * time.Millisecond)
+		So(a.min, ShouldEqual, 100*time.Millisecond)
+		So(newAdaptiveInterval(time.Second).min, ShouldEqual, adaptiveMinInterval)
+	})
+}
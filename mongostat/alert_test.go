@@ -0,0 +1,140 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongostat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/mongodb/mongo-tools/mongostat/stat_consumer/line"
+	"github.com/mongodb/mongo-tools/mongostat/status"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func alertSample(host string, secs int64, queued, conns, inserts int64) *status.ServerStatus {
+	return &status.ServerStatus{
+		Host:       host,
+		SampleTime: time.Unix(secs, 0),
+		GlobalLock: &status.GlobalLockStats{
+			CurrentQueue:  &status.QueueStats{Readers: queued, Writers: 0},
+			ActiveClients: &status.ClientStats{},
+		},
+		Connections: &status.ConnectionStats{Current: conns},
+		Opcounters:  &status.OpcountStats{Insert: inserts},
+		Flattened:   map[string]interface{}{"metrics.cursor.open.total": conns},
+	}
+}
+
+func TestAlerts(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("Alerts should parse", t, func() {
+		for _, source := range []string{
+			"qrw>100",
+			"qrw > 100 for 3 samples",
+			"conn>=5000 for 1 sample",
+			"insert<0.5",
+			`"metrics.cursor.open.total" != 0`,
+			"metrics.document.inserted.rate() / 2 <= 10",
+		} {
+			alert, err := status.ParseAlert(source, nil)
+			So(err, ShouldBeNil)
+			So(alert.String(), ShouldEqual, source)
+		}
+	})
+
+	Convey("Malformed alerts should not parse", t, func() {
+		for _, source := range []string{
+			"",
+			"qrw",
+			">100",
+			"qrw>",
+			"qrw>lots",
+			"qrw>100 for 0 samples",
+			"1 +>5",
+		} {
+			_, err := status.ParseAlert(source, nil)
+			So(err, ShouldNotBeNil)
+		}
+
+		_, err := ParseOptions([]string{"--alert", "qrw>>1"}, "", "")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Alerts should be parsed from the options", t, func() {
+		opts, err := ParseOptions([]string{"--alert", "qrw>100", "--alert", "conn>10"}, "", "")
+		So(err, ShouldBeNil)
+		So(opts.AlertThresholds, ShouldHaveLength, 2)
+	})
+
+	Convey("An alert should fire after enough consecutive samples", t, func() {
+		alert, err := status.ParseAlert("qrw>100 for 3 samples", nil)
+		So(err, ShouldBeNil)
+		tracker := status.NewAlertTracker([]*status.Alert{alert})
+
+		queues := []int64{150, 150, 50, 150, 150, 150, 150}
+		fired := []bool{false, false, false, false, false, true, true}
+		prev := alertSample("a", 0, 0, 0, 0)
+		for i, queued := range queues {
+			stat := alertSample("a", int64(i+1), queued, 0, 0)
+			So(len(tracker.Check(stat, prev)) > 0, ShouldEqual, fired[i])
+			prev = stat
+		}
+		So(tracker.Fired(), ShouldBeTrue)
+	})
+
+	Convey("Alerts should be tracked separately for each host", t, func() {
+		alert, err := status.ParseAlert("conn>=10 for 2 samples", nil)
+		So(err, ShouldBeNil)
+		tracker := status.NewAlertTracker([]*status.Alert{alert})
+
+		So(tracker.Check(alertSample("a", 1, 0, 10, 0), alertSample("a", 0, 0, 0, 0)), ShouldBeEmpty)
+		So(tracker.Check(alertSample("b", 1, 0, 10, 0), alertSample("b", 0, 0, 0, 0)), ShouldBeEmpty)
+		So(tracker.Fired(), ShouldBeFalse)
+		So(
+			tracker.Check(alertSample("a", 2, 0, 12, 0), alertSample("a", 1, 0, 10, 0)),
+			ShouldResemble,
+			[]string{"conn>=10 for 2 samples (conn=12)"},
+		)
+		So(tracker.Fired(), ShouldBeTrue)
+	})
+
+	Convey("Alerts should use rates, derived columns and serverStatus fields", t, func() {
+		expr, err := status.ParseExpression("metrics.cursor.open.total * 2")
+		So(err, ShouldBeNil)
+		expressions := map[string]*status.Expression{"cursors": expr}
+		var alerts []*status.Alert
+		for _, source := range []string{
+			"insert>=50",
+			"cursors==20",
+			"metrics.cursor.open.total<5",
+			"metrics.missing>0",
+		} {
+			alert, err := status.ParseAlert(source, expressions)
+			So(err, ShouldBeNil)
+			alerts = append(alerts, alert)
+		}
+		tracker := status.NewAlertTracker(alerts)
+
+		firing := tracker.Check(alertSample("a", 2, 0, 10, 100), alertSample("a", 0, 0, 10, 0))
+		So(firing, ShouldResemble, []string{"insert>=50 (insert=50)", "cursors==20 (cursors=20)"})
+	})
+
+	Convey("Firing alerts should be added to stat lines", t, func() {
+		alert, err := status.ParseAlert("qr>0", nil)
+		So(err, ShouldBeNil)
+		config := &status.ReaderConfig{Alerts: status.NewAlertTracker([]*status.Alert{alert})}
+		statLine := line.NewStatLine(
+			alertSample("a", 0, 0, 0, 0),
+			alertSample("a", 1, 3, 0, 0),
+			[]string{"qrw"},
+			config,
+		)
+		So(statLine.Alerts, ShouldResemble, []string{"qr>0 (qr=3)"})
+	})
+}
@@ -0,0 +1,396 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongostat
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/util"
+	"github.com/mongodb/mongo-tools/mongostat/stat_consumer"
+	"github.com/mongodb/mongo-tools/mongostat/stat_consumer/line"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// deltaChunkSamples is the most samples of a host that a chunk of a
+// --deltaFile holds.
+const deltaChunkSamples = 300
+
+// deltaChunk is a chunk of a --deltaFile, which is a sequence of them as BSON
+// documents, so that bsondump can show what a file holds.
+//
+// Like the FTDC files of the server, a chunk holds the first serverStatus
+// document of a run of samples of a host whose documents only differ by their
+// numeric values. The numbers, booleans, dates and timestamps of those
+// documents are the metrics of the chunk; Data holds, zlib compressed, the
+// first document followed by the differences between the consecutive values
+// of each metric, as zigzag varints with runs of zeros written as a zero and
+// the number of zeros that follow it.
+type deltaChunk struct {
+	Host    string    `bson:"host"`
+	Start   time.Time `bson:"start"`
+	Samples int32     `bson:"samples"`
+	Data    []byte    `bson:"data"`
+}
+
+// deltaSamples are the samples of a host that the next chunk of a
+// --deltaFile holds.
+type deltaSamples struct {
+	reference bson.Raw
+	// schema is reference with every metric set to zero. The samples of a
+	// chunk all have the same schema.
+	schema []byte
+	// metrics holds the sample time, in milliseconds since the epoch,
+	// followed by the metrics of each sample.
+	metrics [][]int64
+}
+
+// deltaFileWriter writes the serverStatus documents that mongostat samples to
+// a --deltaFile.
+type deltaFileWriter struct {
+	mu      sync.Mutex
+	file    *os.File
+	samples map[string]*deltaSamples
+}
+
+// validateDeltaFile checks --deltaFile and --decode.
+func validateDeltaFile(opts *StatOptions) error {
+	if opts.Decode == "" {
+		return nil
+	}
+	switch {
+	case opts.DeltaFile != "":
+		return fmt.Errorf("cannot use --decode with --deltaFile")
+	case opts.RawDump != "":
+		return fmt.Errorf("cannot use --decode with --rawDump")
+	case opts.Discover:
+		return fmt.Errorf("cannot use --decode with --discover")
+	case opts.Adaptive:
+		return fmt.Errorf("cannot use --decode with --adaptive")
+	}
+	return nil
+}
+
+// newDeltaFileWriter creates the --deltaFile, replacing it if it exists.
+func newDeltaFileWriter(path string) (*deltaFileWriter, error) {
+	file, err := os.Create(util.ToUniversalPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("error creating --deltaFile: %v", err)
+	}
+	return &deltaFileWriter{file: file, samples: map[string]*deltaSamples{}}, nil
+}
+
+// write adds the serverStatus document of host sampled at sampleTime to the
+// next chunk of host, after writing the chunk if the document doesn't fit in
+// it.
+func (w *deltaFileWriter) write(host string, sampleTime time.Time, serverStatus bson.Raw) error {
+	metrics := []int64{sampleTime.UnixMilli()}
+	schema, err := replaceMetrics(bsoncore.Document(serverStatus), func(value int64) int64 {
+		metrics = append(metrics, value)
+		return 0
+	})
+	if err != nil {
+		return fmt.Errorf("error reading serverStatus of %v: %v", host, err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	samples := w.samples[host]
+	if samples != nil && !bytes.Equal(samples.schema, schema) {
+		if err := w.writeChunk(host); err != nil {
+			return err
+		}
+		samples = nil
+	}
+	if samples == nil {
+		samples = &deltaSamples{reference: serverStatus, schema: schema}
+		w.samples[host] = samples
+	}
+	samples.metrics = append(samples.metrics, metrics)
+	if len(samples.metrics) == deltaChunkSamples {
+		return w.writeChunk(host)
+	}
+	return nil
+}
+
+// writeChunk writes the chunk of the samples of host.
+func (w *deltaFileWriter) writeChunk(host string) error {
+	samples := w.samples[host]
+	delete(w.samples, host)
+
+	var data bytes.Buffer
+	zw := zlib.NewWriter(&data)
+	_, err := zw.Write(encodeDeltas(samples.reference, samples.metrics))
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error compressing --deltaFile chunk: %v", err)
+	}
+	chunk, err := bson.Marshal(deltaChunk{
+		Host:    host,
+		Start:   time.UnixMilli(samples.metrics[0][0]),
+		Samples: int32(len(samples.metrics)),
+		Data:    data.Bytes(),
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding --deltaFile chunk: %v", err)
+	}
+	if _, err := w.file.Write(chunk); err != nil {
+		return fmt.Errorf("error writing --deltaFile: %v", err)
+	}
+	return nil
+}
+
+// Close writes the chunks of the samples that weren't written yet, and
+// closes the file. Samples written after Close are dropped.
+func (w *deltaFileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	hosts := make([]string, 0, len(w.samples))
+	for host := range w.samples {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	var err error
+	for _, host := range hosts {
+		if chunkErr := w.writeChunk(host); err == nil {
+			err = chunkErr
+		}
+	}
+	if closeErr := w.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("error writing --deltaFile: %v", closeErr)
+	}
+	w.file = nil
+	return err
+}
+
+// replaceMetrics returns a copy of doc where the value of every metric is
+// replaced by what replace returns for it, in the order of doc.
+func replaceMetrics(doc bsoncore.Document, replace func(int64) int64) ([]byte, error) {
+	elements, err := doc.Elements()
+	if err != nil {
+		return nil, err
+	}
+	idx, dst := bsoncore.AppendDocumentStart(nil)
+	for _, element := range elements {
+		key, value := element.Key(), element.Value()
+		switch value.Type {
+		case bsontype.Int32:
+			dst = bsoncore.AppendInt32Element(dst, key, int32(replace(int64(value.Int32()))))
+		case bsontype.Int64:
+			dst = bsoncore.AppendInt64Element(dst, key, replace(value.Int64()))
+		case bsontype.Double:
+			bits := replace(int64(math.Float64bits(value.Double())))
+			dst = bsoncore.AppendDoubleElement(dst, key, math.Float64frombits(uint64(bits)))
+		case bsontype.Boolean:
+			var b int64
+			if value.Boolean() {
+				b = 1
+			}
+			dst = bsoncore.AppendBooleanElement(dst, key, replace(b) != 0)
+		case bsontype.DateTime:
+			dst = bsoncore.AppendDateTimeElement(dst, key, replace(value.DateTime()))
+		case bsontype.Timestamp:
+			t, i := value.Timestamp()
+			ts := replace(int64(t)<<32 | int64(i))
+			dst = bsoncore.AppendTimestampElement(dst, key, uint32(ts>>32), uint32(ts))
+		case bsontype.EmbeddedDocument, bsontype.Array:
+			sub, err := replaceMetrics(value.Data, replace)
+			if err != nil {
+				return nil, err
+			}
+			if value.Type == bsontype.Array {
+				dst = bsoncore.AppendArrayElement(dst, key, sub)
+			} else {
+				dst = bsoncore.AppendDocumentElement(dst, key, sub)
+			}
+		default:
+			dst = bsoncore.AppendValueElement(dst, key, value)
+		}
+	}
+	return bsoncore.AppendDocumentEnd(dst, idx)
+}
+
+// encodeDeltas encodes the uncompressed data of a chunk.
+func encodeDeltas(reference bson.Raw, metrics [][]int64) []byte {
+	b := binary.AppendUvarint(nil, uint64(len(reference)))
+	b = append(b, reference...)
+	b = binary.AppendUvarint(b, uint64(len(metrics[0])))
+	b = binary.AppendUvarint(b, uint64(len(metrics)-1))
+
+	var zeros uint64
+	for m := range metrics[0] {
+		for s := 1; s < len(metrics); s++ {
+			delta := metrics[s][m] - metrics[s-1][m]
+			if delta == 0 {
+				zeros++
+				continue
+			}
+			if zeros > 0 {
+				b = binary.AppendVarint(b, 0)
+				b = binary.AppendUvarint(b, zeros-1)
+				zeros = 0
+			}
+			b = binary.AppendVarint(b, delta)
+		}
+	}
+	if zeros > 0 {
+		b = binary.AppendVarint(b, 0)
+		b = binary.AppendUvarint(b, zeros-1)
+	}
+	return b
+}
+
+// decodeDeltas decodes the uncompressed data of a chunk whose first sample was
+// sampled at start into its samples and their sample times.
+func decodeDeltas(data []byte, start time.Time) ([]bson.Raw, []time.Time, error) {
+	r := bytes.NewReader(data)
+	size, err := binary.ReadUvarint(r)
+	if err != nil || size > uint64(r.Len()) {
+		return nil, nil, fmt.Errorf("invalid reference document")
+	}
+	reference := make([]byte, size)
+	_, _ = r.Read(reference)
+
+	first := []int64{start.UnixMilli()}
+	_, err = replaceMetrics(reference, func(value int64) int64 {
+		first = append(first, value)
+		return value
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid reference document: %v", err)
+	}
+	metricCount, err := binary.ReadUvarint(r)
+	if err != nil || metricCount != uint64(len(first)) {
+		return nil, nil, fmt.Errorf("number of metrics does not match the reference document")
+	}
+	deltaCount, err := binary.ReadUvarint(r)
+	if err != nil || deltaCount >= deltaChunkSamples {
+		return nil, nil, fmt.Errorf("invalid number of samples")
+	}
+
+	metrics := make([][]int64, deltaCount+1)
+	for s := range metrics {
+		metrics[s] = make([]int64, metricCount)
+	}
+	var zeros uint64
+	for m := range first {
+		for s := 1; s < len(metrics); s++ {
+			var delta int64
+			if zeros > 0 {
+				zeros--
+			} else {
+				delta, err = binary.ReadVarint(r)
+				if err == nil && delta == 0 {
+					zeros, err = binary.ReadUvarint(r)
+				}
+				if err != nil {
+					return nil, nil, fmt.Errorf("truncated deltas")
+				}
+			}
+			metrics[s][m] = metrics[s-1][m] + delta
+		}
+	}
+
+	docs := make([]bson.Raw, len(metrics))
+	times := make([]time.Time, len(metrics))
+	for s := range metrics {
+		values := metrics[s]
+		if s == 0 {
+			docs[0] = reference
+		} else {
+			next := 1
+			docs[s], err = replaceMetrics(reference, func(value int64) int64 {
+				value = first[next] + values[next]
+				next++
+				return value
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		times[s] = time.UnixMilli(first[0] + values[0])
+	}
+	return docs, times, nil
+}
+
+// samples returns the serverStatus documents of chunk and their sample times.
+func (chunk deltaChunk) samples() ([]bson.Raw, []time.Time, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(chunk.Data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decompressing chunk of %v: %v", chunk.Host, err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decompressing chunk of %v: %v", chunk.Host, err)
+	}
+	docs, times, err := decodeDeltas(data, chunk.Start)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decoding chunk of %v: %v", chunk.Host, err)
+	}
+	return docs, times, nil
+}
+
+// DecodeDeltaFile replays the samples of the --deltaFile at path, for
+// --decode, as the rows that consumer would have printed while they were
+// sampled.
+func DecodeDeltaFile(path string, consumer *stat_consumer.StatConsumer) error {
+	file, err := os.Open(util.ToUniversalPath(path))
+	if err != nil {
+		return fmt.Errorf("error opening --decode file: %v", err)
+	}
+	defer file.Close()
+
+	for {
+		raw, err := bson.NewFromIOReader(file)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading --decode file: %v", err)
+		}
+		var chunk deltaChunk
+		if err := bson.Unmarshal(raw, &chunk); err != nil {
+			return fmt.Errorf("error reading --decode file: %v", err)
+		}
+		docs, times, err := chunk.samples()
+		if err != nil {
+			return err
+		}
+
+		for i, doc := range docs {
+			stat, err := parseServerStatus(doc)
+			if err != nil {
+				return err
+			}
+			stat.SampleTime = times[i]
+			stat.Host = chunk.Host
+			statLine, ok := consumer.Update(stat)
+			if ok && consumer.FormatLines([]*line.StatLine{statLine}) {
+				return nil
+			}
+		}
+	}
+}
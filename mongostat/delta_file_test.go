@@ -0,0 +1,146 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongostat
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/mongodb/mongo-tools/mongostat/stat_consumer"
+	"github.com/mongodb/mongo-tools/mongostat/status"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func deltaSample(host string, inserts int64, version string) bson.Raw {
+	raw, err := bson.Marshal(bson.D{
+		{"host", host},
+		{"version", version},
+		{"load", 10.5 + float64(inserts)},
+		{"localTime", primitive.DateTime(1700000000000 + inserts*1000)},
+		{"opcounters", bson.D{
+			{"insert", inserts * 10},
+			{"query", int32(7)},
+			{"update", int64(0)},
+			{"delete", int64(0)},
+			{"getmore", int64(0)},
+			{"command", int64(inserts)},
+		}},
+		{"repl", bson.D{{"ismaster", inserts%2 == 0}, {"hosts", bson.A{"a:1"}}}},
+		{"extra", bson.A{"b", int32(3)}},
+		{"ts", primitive.Timestamp{T: 1700000000, I: uint32(inserts)}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
+
+// readDeltaFile returns the chunks of the --deltaFile at path.
+func readDeltaFile(path string) ([]deltaChunk, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var chunks []deltaChunk
+	for {
+		raw, err := bson.NewFromIOReader(file)
+		if err != nil {
+			return chunks, nil
+		}
+		var chunk deltaChunk
+		if err := bson.Unmarshal(raw, &chunk); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestDeltaFile(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("--decode should not be used with the options of a live run", t, func() {
+		_, err := ParseOptions([]string{"--decode", "stats.delta", "--deltaFile", "x"}, "", "")
+		So(err, ShouldNotBeNil)
+		_, err = ParseOptions([]string{"--decode", "stats.delta", "--discover"}, "", "")
+		So(err, ShouldNotBeNil)
+
+		opts, err := ParseOptions([]string{"--decode", "stats.delta", "--json"}, "", "")
+		So(err, ShouldBeNil)
+		So(opts.Decode, ShouldEqual, "stats.delta")
+	})
+
+	Convey("Samples should be written as deltas and decoded back", t, func() {
+		path := filepath.Join(t.TempDir(), "stats.delta")
+		w, err := newDeltaFileWriter(path)
+		So(err, ShouldBeNil)
+		start := time.UnixMilli(1700000000123)
+
+		var written []bson.Raw
+		for i := int64(0); i < 5; i++ {
+			version := "7.0.1"
+			if i >= 3 {
+				// an upgrade changes the schema, and starts a new chunk
+				version = "7.0.2"
+			}
+			sample := deltaSample("a:1", i, version)
+			written = append(written, sample)
+			So(w.write("a:1", start.Add(time.Duration(i)*time.Second), sample), ShouldBeNil)
+		}
+		So(w.write("b:1", start, deltaSample("b:1", 4, "7.0.1")), ShouldBeNil)
+		So(w.Close(), ShouldBeNil)
+		So(w.write("a:1", start, deltaSample("a:1", 9, "7.0.1")), ShouldBeNil)
+
+		chunks, err := readDeltaFile(path)
+		So(err, ShouldBeNil)
+		So(chunks, ShouldHaveLength, 3)
+		So(chunks[0].Host, ShouldEqual, "a:1")
+		So(chunks[0].Samples, ShouldEqual, 3)
+		So(chunks[1].Host, ShouldEqual, "a:1")
+		So(chunks[1].Samples, ShouldEqual, 2)
+		So(chunks[1].Start.Equal(start.Add(3*time.Second)), ShouldBeTrue)
+		So(chunks[2].Host, ShouldEqual, "b:1")
+
+		var decoded []bson.Raw
+		for _, chunk := range chunks[:2] {
+			docs, times, err := chunk.samples()
+			So(err, ShouldBeNil)
+			for i := range times {
+				So(times[i].Equal(chunk.Start.Add(time.Duration(i)*time.Second)), ShouldBeTrue)
+			}
+			decoded = append(decoded, docs...)
+		}
+		So(decoded, ShouldHaveLength, len(written))
+		for i := range written {
+			So(bytes.Equal(decoded[i], written[i]), ShouldBeTrue)
+		}
+
+		Convey("and replayed as the rows of the samples", func() {
+			var out bytes.Buffer
+			headers := []string{"host", "insert", "time"}
+			consumer := stat_consumer.NewStatConsumer(0, headers,
+				map[string]string{"host": "host", "insert": "insert", "time": "time"},
+				&status.ReaderConfig{TimeFormat: "15:04:05"},
+				stat_consumer.NewJSONLineFormatter(0, false), &out)
+			So(DecodeDeltaFile(path, consumer), ShouldBeNil)
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			So(lines, ShouldHaveLength, 4)
+			var row map[string]map[string]string
+			So(json.Unmarshal([]byte(lines[0]), &row), ShouldBeNil)
+			So(row["a:1"]["insert"], ShouldEqual, "10")
+		})
+	})
+}
@@ -0,0 +1,125 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongostat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/mongodb/mongo-tools/mongostat/stat_consumer"
+	"github.com/mongodb/mongo-tools/mongostat/stat_consumer/line"
+	"github.com/mongodb/mongo-tools/mongostat/status"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFilter(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("Filters should parse", t, func() {
+		for _, source := range []string{
+			"qrw>50",
+			"dirty>5 || qrw>50",
+			"conn>=10 && !(insert<1 || query<1)",
+			"(qr + qw) / 2 > 5",
+			"((qr+qw)>5) && conn!=0",
+			`"metrics.cursor.open.total" >= 1 || !conn==0`,
+		} {
+			filter, err := status.ParseFilter(source, nil)
+			So(err, ShouldBeNil)
+			So(filter.String(), ShouldEqual, source)
+		}
+	})
+
+	Convey("Malformed filters should not parse", t, func() {
+		for _, source := range []string{
+			"",
+			"qrw",
+			"qrw>50 ||",
+			"&& qrw>50",
+			"(qrw>50",
+			"qrw>50)",
+			"qrw>50 for 3 samples",
+			"!",
+		} {
+			_, err := status.ParseFilter(source, nil)
+			So(err, ShouldNotBeNil)
+		}
+
+		_, err := ParseOptions([]string{"--filter", "qrw>>1"}, "", "")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("The filter should be parsed from the options", t, func() {
+		opts, err := ParseOptions([]string{"--filter", "qrw>50 || conn>10"}, "", "")
+		So(err, ShouldBeNil)
+		So(opts.RowFilter, ShouldNotBeNil)
+		So(opts.RowFilter.String(), ShouldEqual, "qrw>50 || conn>10")
+	})
+
+	Convey("Filters should match samples", t, func() {
+		expr, err := status.ParseExpression("metrics.cursor.open.total * 2")
+		So(err, ShouldBeNil)
+		expressions := map[string]*status.Expression{"cursors": expr}
+		prev := alertSample("a", 0, 0, 10, 0)
+		// 5 queued, 10 connections, 20 cursors and 50 inserts per second
+		stat := alertSample("a", 2, 5, 10, 100)
+
+		for source, matched := range map[string]bool{
+			"qrw>50":                                     false,
+			"qrw>50 || conn>=10":                         true,
+			"qrw>1 && conn>10":                           false,
+			"!(qrw>1 && conn>10)":                        true,
+			"qrw>1 && conn>1 || insert<0":                true,
+			"insert<0 || qrw>1 && conn>10":               false,
+			"(metrics.cursor.open.total + 20) / 3 == 10": true,
+			"cursors==20 && !dirty>0":                    true,
+			"metrics.missing>0 || conn<1":                false,
+		} {
+			filter, err := status.ParseFilter(source, expressions)
+			So(err, ShouldBeNil)
+			So(filter.Matches(stat, prev), ShouldEqual, matched)
+		}
+	})
+
+	Convey("Only the rows of the samples that match should be printed", t, func() {
+		filter, err := status.ParseFilter("qr>0", nil)
+		So(err, ShouldBeNil)
+		config := &status.ReaderConfig{Filter: filter}
+		var out bytes.Buffer
+		consumer := stat_consumer.NewStatConsumer(0, []string{"host", "qrw"},
+			map[string]string{"host": "host", "qrw": "qrw"}, config,
+			stat_consumer.NewJSONLineFormatter(0, false), &out)
+
+		for _, host := range []string{"a", "b"} {
+			_, seen := consumer.Update(alertSample(host, 0, 0, 0, 0))
+			So(seen, ShouldBeFalse)
+		}
+		busy, _ := consumer.Update(alertSample("a", 1, 3, 0, 0))
+		idle, _ := consumer.Update(alertSample("b", 1, 0, 0, 0))
+		So(busy.FilteredOut, ShouldBeFalse)
+		So(idle.FilteredOut, ShouldBeTrue)
+
+		So(consumer.FormatLines([]*line.StatLine{busy, idle}), ShouldBeFalse)
+		So(out.String(), ShouldContainSubstring, `"a"`)
+		So(out.String(), ShouldNotContainSubstring, `"b"`)
+
+		Convey("and nothing when none does", func() {
+			out.Reset()
+			quiet, _ := consumer.Update(alertSample("a", 2, 0, 0, 0))
+			So(consumer.FormatLines([]*line.StatLine{quiet}), ShouldBeFalse)
+			So(out.String(), ShouldBeEmpty)
+		})
+
+		Convey("except for hosts that sent no new sample", func() {
+			out.Reset()
+			consumer.FormatLines([]*line.StatLine{busy, idle})
+			So(strings.Count(out.String(), "no data received"), ShouldEqual, 2)
+		})
+	})
+}
@@ -10,11 +10,13 @@ package main
 import (
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/password"
 	"github.com/mongodb/mongo-tools/common/signals"
+	"github.com/mongodb/mongo-tools/common/telemetry"
 	"github.com/mongodb/mongo-tools/common/util"
 	"github.com/mongodb/mongo-tools/mongostat"
 	"github.com/mongodb/mongo-tools/mongostat/stat_consumer"
@@ -64,7 +66,11 @@ func main() {
 	}
 
 	log.SetVerbosity(opts.Verbosity)
-	signals.Handle()
+	if !opts.Summary && opts.DeltaFile == "" {
+		// with --summary or --deltaFile, signals are handled once the summary
+		// can be printed and the samples written
+		signals.Handle()
+	}
 
 	// print help, if specified
 	if opts.PrintHelp(false) {
@@ -76,6 +82,12 @@ func main() {
 		return
 	}
 
+	if err := telemetry.Init(opts.AppName, opts.VersionStr, opts.OTelEndpoint); err != nil {
+		log.Logvf(log.Always, "error starting telemetry: %v", err)
+		os.Exit(util.ExitFailure)
+	}
+	defer telemetry.Shutdown()
+
 	// verify uri options and log them
 	opts.URI.LogUnsupportedOptions()
 
@@ -87,19 +99,24 @@ func main() {
 				log.Always,
 				"authSource is required when authenticating against a non $external database",
 			)
-			os.Exit(util.ExitFailure)
+			telemetry.Exit(util.ExitFailure)
 		}
 
 		log.Logvf(
 			log.Always,
 			"--authenticationDatabase is required when authenticating against a non $external database",
 		)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 
 	if opts.Interactive && opts.Json {
 		log.Logvf(log.Always, "cannot use output formats --json and --interactive together")
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
+	}
+
+	if opts.Watch && (opts.Json || opts.Interactive) {
+		log.Logvf(log.Always, "cannot use --watch with --json or --interactive")
+		telemetry.Exit(util.ExitFailure)
 	}
 
 	if opts.Deprecated && !opts.Json {
@@ -107,17 +124,17 @@ func main() {
 			log.Always,
 			"--useDeprecatedJsonKeys can only be used when --json is also specified",
 		)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 
 	if opts.Columns != "" && opts.AppendColumns != "" {
 		log.Logvf(log.Always, "-O cannot be used if -o is also specified")
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 
 	if opts.HumanReadable != "true" && opts.HumanReadable != "false" {
 		log.Logvf(log.Always, "--humanReadable must be set to either 'true' or 'false'")
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 
 	// we have to check this here, otherwise the user will be prompted
@@ -126,7 +143,7 @@ func main() {
 		pass, err := password.Prompt("mongo user")
 		if err != nil {
 			log.Logvf(log.Always, "Failed: %v", err)
-			os.Exit(util.ExitFailure)
+			telemetry.Exit(util.ExitFailure)
 		}
 		opts.Auth.Password = pass
 	}
@@ -136,6 +153,8 @@ func main() {
 		factory = stat_consumer.FormatterConstructors["json"]
 	} else if opts.Interactive {
 		factory = stat_consumer.FormatterConstructors["interactive"]
+	} else if opts.Watch {
+		factory = stat_consumer.FormatterConstructors["watch"]
 	} else {
 		factory = stat_consumer.FormatterConstructors[""]
 	}
@@ -180,13 +199,67 @@ func main() {
 
 	readerConfig := &status.ReaderConfig{
 		HumanReadable: opts.HumanReadable == "true",
+		Expressions:   opts.Expressions,
+		Filter:        opts.RowFilter,
+	}
+	if len(opts.AlertThresholds) > 0 {
+		readerConfig.Alerts = status.NewAlertTracker(opts.AlertThresholds)
 	}
 	if opts.Json {
 		readerConfig.TimeFormat = "15:04:05"
 	}
 
+	// finish stops the output, writes the rest of the --deltaFile and prints
+	// the --summary, only once, so that an interrupt while mongostat exits
+	// doesn't print it twice
+	var stat *mongostat.MongoStat
+	var finishOnce sync.Once
+	finish := func() {
+		finishOnce.Do(func() {
+			formatter.Finish()
+			if stat != nil {
+				if err := stat.Close(); err != nil {
+					log.Logvf(log.Always, "Failed: %v", err)
+					telemetry.Exit(util.ExitFailure)
+				}
+			}
+			if readerConfig.Summary == nil {
+				return
+			}
+			err := mongostat.WriteSummary(readerConfig.Summary, os.Stdout, opts.Json, opts.SummaryFile)
+			if err != nil {
+				log.Logvf(log.Always, "Failed: %v", err)
+				telemetry.Exit(util.ExitFailure)
+			}
+		})
+	}
+	if opts.Summary {
+		readerConfig.Summary = status.NewSummary(opts.Expressions)
+	}
+	if opts.Summary || opts.DeltaFile != "" {
+		signals.HandleWithInterrupt(func() {
+			finish()
+			if readerConfig.Alerts != nil && readerConfig.Alerts.Fired() {
+				telemetry.Exit(mongostat.ExitAlertFired)
+			}
+			telemetry.Exit(util.ExitSuccess)
+		})
+	}
+
 	consumer := stat_consumer.NewStatConsumer(cliFlags, customHeaders,
 		keyNames, readerConfig, formatter, os.Stdout)
+	if opts.Decode != "" {
+		err := mongostat.DecodeDeltaFile(opts.Decode, consumer)
+		finish()
+		if err != nil {
+			log.Logvf(log.Always, "Failed: %v", err)
+			telemetry.Exit(util.ExitFailure)
+		}
+		if readerConfig.Alerts != nil && readerConfig.Alerts.Fired() {
+			telemetry.Exit(mongostat.ExitAlertFired)
+		}
+		return
+	}
 	seedHosts := util.CreateConnectionAddrs(opts.Host, opts.Port)
 	var cluster mongostat.ClusterMonitor
 	if opts.Discover || len(seedHosts) > 1 {
@@ -195,6 +268,7 @@ func main() {
 			ErrorChan:     make(chan *status.NodeError),
 			LastStatLines: map[string]*line.StatLine{},
 			Consumer:      consumer,
+			Adaptive:      opts.Adaptive,
 		}
 	} else {
 		cluster = &mongostat.SyncClusterMonitor{
@@ -210,7 +284,7 @@ func main() {
 	}
 
 	opts.Direct = true
-	stat := &mongostat.MongoStat{
+	stat = &mongostat.MongoStat{
 		Options:       opts.ToolOptions,
 		StatOptions:   opts.StatOptions,
 		Nodes:         map[string]*mongostat.NodeMonitor{},
@@ -222,7 +296,7 @@ func main() {
 	for _, v := range seedHosts {
 		if err := stat.AddNewNode(v); err != nil {
 			log.Logv(log.Always, err.Error())
-			os.Exit(util.ExitFailure)
+			telemetry.Exit(util.ExitFailure)
 		}
 	}
 
@@ -231,9 +305,12 @@ func main() {
 	for _, monitor := range stat.Nodes {
 		monitor.Disconnect()
 	}
-	formatter.Finish()
+	finish()
 	if err != nil {
 		log.Logvf(log.Always, "Failed: %v", err)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
+	}
+	if readerConfig.Alerts != nil && readerConfig.Alerts.Fired() {
+		telemetry.Exit(mongostat.ExitAlertFired)
 	}
 }
@@ -49,6 +49,12 @@ type MongoStat struct {
 
 	// Mutex to handle safe concurrent adding to or looping over discovered nodes.
 	nodesLock sync.RWMutex
+
+	// Writes the sampled serverStatus documents of every node, if --rawDump is set.
+	rawDump *rawDumper
+
+	// Writes the sampled serverStatus documents of every node, if --deltaFile is set.
+	deltaFile *deltaFileWriter
 }
 
 // ConfigShard holds a mapping for the format of shard hosts as they
@@ -69,6 +75,15 @@ type NodeMonitor struct {
 
 	// The most recent error encountered when collecting stats for this node.
 	Err error
+
+	// If set, adapts the polling interval to the node's activity.
+	adaptive *adaptiveInterval
+
+	// If set, writes each serverStatus document sampled.
+	rawDump *rawDumper
+
+	// If set, writes each serverStatus document sampled to the --deltaFile.
+	deltaFile *deltaFileWriter
 }
 
 // SyncClusterMonitor is an implementation of ClusterMonitor that writes output
@@ -104,6 +119,10 @@ type ClusterMonitor interface {
 type AsyncClusterMonitor struct {
 	Discover bool
 
+	// Nodes poll on their own adaptive intervals, so a snapshot is only
+	// printed once some node has reported new data.
+	Adaptive bool
+
 	// Channel to listen for incoming stat data
 	ReportChan chan *status.ServerStatus
 
@@ -176,10 +195,21 @@ func (cluster *AsyncClusterMonitor) printSnapshot() bool {
 	cluster.mapLock.RLock()
 	defer cluster.mapLock.RUnlock()
 	lines := make([]*line.StatLine, 0, len(cluster.LastStatLines))
+	fresh := false
 	for _, stat := range cluster.LastStatLines {
+		if cluster.Adaptive && stat.Printed && stat.Error == nil {
+			// the node is just polled less often than the snapshot interval,
+			// so repeat its last line instead of reporting it as missing
+			repeated := *stat
+			repeated.Printed = false
+			repeated.Anomaly = ""
+			stat = &repeated
+		} else {
+			fresh = true
+		}
 		lines = append(lines, stat)
 	}
-	if len(lines) == 0 {
+	if len(lines) == 0 || !fresh {
 		return false
 	}
 	return cluster.Consumer.FormatLines(lines)
@@ -205,6 +235,9 @@ func (cluster *AsyncClusterMonitor) Monitor(sleep time.Duration) error {
 		return err
 	}
 
+	// anomalies detected by --adaptive are printed right away rather than
+	// waiting for the next snapshot
+	anomalies := make(chan struct{}, 1)
 	go func() {
 		for {
 			select {
@@ -212,6 +245,12 @@ func (cluster *AsyncClusterMonitor) Monitor(sleep time.Duration) error {
 				statLine, ok := cluster.Consumer.Update(stat)
 				if ok {
 					cluster.updateHostInfo(statLine)
+					if statLine.Anomaly != "" {
+						select {
+						case anomalies <- struct{}{}:
+						default:
+						}
+					}
 				}
 			case err := <-cluster.ErrorChan:
 				cluster.updateHostInfo(&line.StatLine{
@@ -223,12 +262,16 @@ func (cluster *AsyncClusterMonitor) Monitor(sleep time.Duration) error {
 	}()
 
 	ticker := time.NewTicker(sleep)
-	for range ticker.C {
+	for {
+		select {
+		case <-ticker.C:
+		case <-anomalies:
+			ticker.Reset(sleep)
+		}
 		if cluster.printSnapshot() {
-			break
+			return nil
 		}
 	}
-	return nil
 }
 
 // NewNodeMonitor copies the same connection settings from an instance of
@@ -279,7 +322,6 @@ func (node *NodeMonitor) Poll(
 	discover chan string,
 	checkShards bool,
 ) (*status.ServerStatus, error) {
-	stat := &status.ServerStatus{}
 	log.Logvf(log.DebugHigh, "getting session on server: %v", node.host)
 	session, err := node.sessionProvider.GetSession()
 	if err != nil {
@@ -300,21 +342,23 @@ func (node *NodeMonitor) Poll(
 		log.Logvf(log.Always, "Encountered error decoding serverStatus: %v\n", err)
 		return nil, fmt.Errorf("Error decoding serverStatus: %v\n", err)
 	}
-	err = bson.Unmarshal(tempBson, &stat)
+	stat, err := parseServerStatus(tempBson)
 	if err != nil {
-		log.Logvf(log.Always, "Encountered error reading serverStatus: %v\n", err)
-		return nil, fmt.Errorf("Error reading serverStatus: %v\n", err)
-	}
-	// The flattened version is required by some lookup functions
-	statMap := make(map[string]interface{})
-	err = result.Decode(&statMap)
-	if err != nil {
-		return nil, fmt.Errorf("Error flattening serverStatus: %v\n", err)
+		return nil, err
 	}
-	stat.Flattened = status.Flatten(statMap)
 
 	node.Err = nil
 	stat.SampleTime = time.Now()
+	if node.rawDump != nil {
+		if err := node.rawDump.write(node.host, stat.SampleTime, tempBson); err != nil {
+			log.Logvf(log.Always, "%v", err)
+		}
+	}
+	if node.deltaFile != nil {
+		if err := node.deltaFile.write(node.host, stat.SampleTime, tempBson); err != nil {
+			log.Logvf(log.Always, "%v", err)
+		}
+	}
 
 	if stat.Repl != nil && discover != nil {
 		for _, host := range stat.Repl.Hosts {
@@ -354,17 +398,28 @@ func (node *NodeMonitor) Poll(
 
 // Watch continuously collects and processes stats for a single node on a
 // regular interval. At each interval, it triggers the node's Poll function
-// with the 'discover' channel.
+// with the 'discover' channel. With --adaptive, the interval is recomputed
+// after each poll instead.
 func (node *NodeMonitor) Watch(sleep time.Duration, discover chan string, cluster ClusterMonitor) {
 	var cycle uint64
-	ticker := time.NewTicker(sleep)
-	for range ticker.C {
+	timer := time.NewTimer(sleep)
+	for range timer.C {
+		start := time.Now()
 		log.Logvf(log.DebugHigh, "polling server: %v", node.host)
 		stat, err := node.Poll(discover, cycle%10 == 0)
 
 		if stat != nil {
 			log.Logvf(log.DebugHigh, "successfully got statline from host: %v", node.host)
 		}
+		next := sleep
+		if node.adaptive != nil {
+			next = node.adaptive.next(stat)
+			log.Logvf(log.DebugHigh, "next poll of server %v in %v", node.host, next)
+		}
+		// reset the timer before handing off the stat, since Update can block
+		// until the stat has been printed
+		timer.Reset(next - time.Since(start))
+
 		var nodeError *status.NodeError
 		if err != nil {
 			nodeError = status.NewNodeError(node.host, err)
@@ -374,6 +429,25 @@ func (node *NodeMonitor) Watch(sleep time.Duration, discover chan string, cluste
 	}
 }
 
+// parseServerStatus reads a serverStatus document, along with its flattened
+// version.
+func parseServerStatus(raw bson.Raw) (*status.ServerStatus, error) {
+	stat := &status.ServerStatus{}
+	err := bson.Unmarshal(raw, &stat)
+	if err != nil {
+		log.Logvf(log.Always, "Encountered error reading serverStatus: %v\n", err)
+		return nil, fmt.Errorf("Error reading serverStatus: %v\n", err)
+	}
+	// The flattened version is required by some lookup functions
+	statMap := make(map[string]interface{})
+	err = bson.Unmarshal(raw, &statMap)
+	if err != nil {
+		return nil, fmt.Errorf("Error flattening serverStatus: %v\n", err)
+	}
+	stat.Flattened = status.Flatten(statMap)
+	return stat, nil
+}
+
 func parseHostPort(fullHostName string) (string, string) {
 	if colon := strings.LastIndex(fullHostName, ":"); colon >= 0 {
 		return fullHostName[0:colon], fullHostName[colon+1:]
@@ -405,6 +479,32 @@ func (mstat *MongoStat) AddNewNode(fullhost string) error {
 	if err != nil {
 		return err
 	}
+	if mstat.StatOptions.Adaptive {
+		node.adaptive = newAdaptiveInterval(mstat.SleepInterval)
+	}
+	if mstat.StatOptions.RawDump != "" {
+		if mstat.rawDump == nil {
+			mstat.rawDump, err = newRawDumper(
+				mstat.StatOptions.RawDump,
+				mstat.StatOptions.RawDumpFormat,
+			)
+			if err != nil {
+				node.Disconnect()
+				return err
+			}
+		}
+		node.rawDump = mstat.rawDump
+	}
+	if mstat.StatOptions.DeltaFile != "" {
+		if mstat.deltaFile == nil {
+			mstat.deltaFile, err = newDeltaFileWriter(mstat.StatOptions.DeltaFile)
+			if err != nil {
+				node.Disconnect()
+				return err
+			}
+		}
+		node.deltaFile = mstat.deltaFile
+	}
 	mstat.Nodes[fullhost] = node
 	go node.Watch(mstat.SleepInterval, mstat.Discovered, mstat.Cluster)
 	return nil
@@ -426,3 +526,14 @@ func (mstat *MongoStat) Run() error {
 	}
 	return mstat.Cluster.Monitor(mstat.SleepInterval)
 }
+
+// Close writes what wasn't written yet of the --deltaFile. Samples taken
+// after Close are not written to it.
+func (mstat *MongoStat) Close() error {
+	mstat.nodesLock.Lock()
+	defer mstat.nodesLock.Unlock()
+	if mstat.deltaFile == nil {
+		return nil
+	}
+	return mstat.deltaFile.Close()
+}
@@ -12,14 +12,21 @@ import (
 
 	"github.com/mongodb/mongo-tools/common/options"
 	"github.com/mongodb/mongo-tools/mongostat/stat_consumer"
+	"github.com/mongodb/mongo-tools/mongostat/status"
 )
 
+// ExitAlertFired is the exit code of a run in which an --alert fired.
+const ExitAlertFired = 2
+
 var Usage = `<options> <connection-string> <polling interval in seconds>
 
 Monitor basic MongoDB server statistics.
 
 Connection strings must begin with mongodb:// or mongodb+srv://.
 
+With --alert, mongostat exits with code 2 if any alert fired, so that it can be used in health checks,
+e.g. mongostat -n 10 --alert 'qrw>100 for 3 samples' --alert 'conn>=5000'
+
 See http://docs.mongodb.com/database-tools/mongostat/ for more information.`
 
 // StatOptions defines the set of options to use for configuring mongostat.
@@ -35,6 +42,23 @@ type StatOptions struct {
 	Json          bool   `long:"json" description:"output as JSON rather than a formatted table"`
 	Deprecated    bool   `long:"useDeprecatedJsonKeys" description:"use old key names; only valid with the json output option."`
 	Interactive   bool   `short:"i" long:"interactive" description:"display stats in a non-scrolling interface"`
+	Watch         bool   `long:"watch" description:"display the latest stats of each host in the interactive terminal UI shared with mongotop --watch, where rows can be sorted by any column, paused, filtered by host, and drilled down into to show the previous stats of a host"`
+	ProfileFile   string `long:"profileFile" value-name:"<filename>" description:"path to a YAML file defining derived columns computed from serverStatus fields, and named column layouts"`
+	Profile       string `long:"profile" value-name:"<name>" description:"show the columns of a layout defined in --profileFile instead of -o or -O"`
+	Adaptive      bool   `long:"adaptive" description:"poll more often while metrics change rapidly (queue spikes, bursts of operations, replica set state changes) and less often while idle, and mark the rows where such changes were detected"`
+
+	Alerts []string `long:"alert" value-name:"<metric><op><threshold>[ for <n> samples]" description:"highlight the rows where a metric crosses a threshold in n consecutive samples (default 1), and make mongostat exit with code 2 if it does. Metrics are qr, qw, qrw, ar, aw, arw, conn, dirty, used, res, vsize, insert, query, update, delete, getmore, command, net_in, net_out, derived columns of the --profileFile, or serverStatus expressions; operators are >, >=, <, <=, == and !=. May be repeated"`
+
+	Filter string `long:"filter" value-name:"<expression>" description:"only print the rows of the samples that match an expression of comparisons like those of --alert combined with && (and), || (or), ! (not) and parentheses, e.g. 'dirty>5 || qrw>50', to only watch the outliers of many hosts with --discover. The rows of hosts that fail to respond are always printed"`
+
+	Summary     bool   `long:"summary" description:"on exit, including by Ctrl-C, print the samples, min, avg, max and 95th percentile of each numeric column of each host over the whole run, in the units of --alert metrics"`
+	SummaryFile string `long:"summaryFile" value-name:"<filename>" description:"with --summary, also write the summary to a file as JSON"`
+
+	RawDump       string `long:"rawDump" value-name:"<directory>" description:"also write each serverStatus document sampled to a file of its own in this directory, named <host>-<UTC sample time>.<format>, for analysis after the run"`
+	RawDumpFormat string `long:"rawDumpFormat" value-name:"<format>" description:"format of the --rawDump files: json (canonical extended JSON, the default) or bson"`
+
+	DeltaFile string `long:"deltaFile" value-name:"<filename>" description:"also write each serverStatus document sampled to this file, compressed by only keeping the changes of its numeric values between samples, for long captures to be replayed with --decode"`
+	Decode    string `long:"decode" value-name:"<filename>" description:"instead of connecting, print the rows of the samples of a --deltaFile, with the same output options"`
 }
 
 // Name returns a human-readable group name for mongostat options.
@@ -46,6 +70,15 @@ type Options struct {
 	*options.ToolOptions
 	*StatOptions
 	SleepInterval int
+
+	// Expressions are the derived columns defined in the --profileFile.
+	Expressions map[string]*status.Expression
+
+	// AlertThresholds are the parsed --alert options.
+	AlertThresholds []*status.Alert
+
+	// RowFilter is the parsed --filter, if any.
+	RowFilter *status.Filter
 }
 
 func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, error) {
@@ -65,6 +98,12 @@ func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, erro
 		interactiveOption.ShortName = 0
 	}
 
+	watchOption := opts.FindOptionByLongName("watch")
+	if _, available := stat_consumer.FormatterConstructors["watch"]; !available {
+		// make --watch inaccessible
+		watchOption.LongName = ""
+	}
+
 	args, err := opts.ParseArgs(rawArgs)
 	if err != nil {
 		return Options{}, err
@@ -88,5 +127,39 @@ func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, erro
 		}
 	}
 
-	return Options{opts, statOpts, sleepInterval}, nil
+	expressions, err := loadProfiles(statOpts)
+	if err != nil {
+		return Options{}, err
+	}
+
+	if statOpts.SummaryFile != "" && !statOpts.Summary {
+		return Options{}, fmt.Errorf("--summaryFile can only be used with --summary")
+	}
+
+	if err := validateRawDump(statOpts); err != nil {
+		return Options{}, err
+	}
+
+	if err := validateDeltaFile(statOpts); err != nil {
+		return Options{}, err
+	}
+
+	var alerts []*status.Alert
+	for _, source := range statOpts.Alerts {
+		alert, err := status.ParseAlert(source, expressions)
+		if err != nil {
+			return Options{}, fmt.Errorf("error parsing --alert: %v", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	var rowFilter *status.Filter
+	if statOpts.Filter != "" {
+		rowFilter, err = status.ParseFilter(statOpts.Filter, expressions)
+		if err != nil {
+			return Options{}, fmt.Errorf("error parsing --filter: %v", err)
+		}
+	}
+
+	return Options{opts, statOpts, sleepInterval, expressions, alerts, rowFilter}, nil
 }
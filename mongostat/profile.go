@@ -0,0 +1,137 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongostat
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/mongodb/mongo-tools/mongostat/stat_consumer/line"
+	"github.com/mongodb/mongo-tools/mongostat/status"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ProfileFile is the content of a --profileFile. It defines derived columns,
+// computed from serverStatus fields, and named column layouts that can be
+// selected with --profile. For example:
+//
+//	expressions:
+//	  dirty_ratio: '"wiredTiger.cache.tracked dirty bytes in the cache" / "wiredTiger.cache.bytes currently in the cache" * 100'
+//	profiles:
+//	  cache:
+//	    columns: [host, dirty, used, dirty_ratio=dirty%, time]
+//	  docs:
+//	    append: true
+//	    columns: [metrics.document.inserted.rate()=docs/s]
+//
+// Expressions can be used as columns in any profile, and with -o and -O.
+type ProfileFile struct {
+	Expressions map[string]string        `yaml:"expressions"`
+	Profiles    map[string]ColumnProfile `yaml:"profiles"`
+}
+
+// ColumnProfile is a named column layout. Columns use the syntax of the
+// fields given to -o. If Append is true, the columns are shown after the
+// default ones, like with -O.
+type ColumnProfile struct {
+	Columns []string `yaml:"columns"`
+	Append  bool     `yaml:"append"`
+}
+
+var expressionNameRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// LoadProfileFile reads and parses the --profileFile at path.
+func LoadProfileFile(path string) (*ProfileFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening file with --profileFile")
+	}
+	profiles := &ProfileFile{}
+	err = yaml.UnmarshalStrict(content, profiles)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing profile file %s", path)
+	}
+	return profiles, nil
+}
+
+// ParseExpressions parses the expressions of the profile file.
+func (pf *ProfileFile) ParseExpressions() (map[string]*status.Expression, error) {
+	expressions := make(map[string]*status.Expression, len(pf.Expressions))
+	for name, source := range pf.Expressions {
+		if !expressionNameRE.MatchString(name) {
+			return nil, fmt.Errorf(
+				"expression name '%v' must contain only letters, digits and underscores",
+				name,
+			)
+		}
+		if _, ok := line.StatHeaders[name]; ok {
+			return nil, fmt.Errorf("expression name '%v' is the name of a default column", name)
+		}
+		expr, err := status.ParseExpression(source)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing expression '%v': %v", name, err)
+		}
+		expressions[name] = expr
+	}
+	return expressions, nil
+}
+
+// applyProfile sets the columns of opts to those of the named profile.
+func (pf *ProfileFile) applyProfile(name string, opts *StatOptions) error {
+	profile, ok := pf.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile '%v' is not defined in %v", name, opts.ProfileFile)
+	}
+	if len(profile.Columns) == 0 {
+		return fmt.Errorf("profile '%v' has no columns", name)
+	}
+	for _, column := range profile.Columns {
+		if column == "" || strings.Contains(column, ",") {
+			return fmt.Errorf("profile '%v' has an invalid column '%v'", name, column)
+		}
+	}
+
+	columns := strings.Join(profile.Columns, ",")
+	if profile.Append {
+		opts.AppendColumns = columns
+	} else {
+		opts.Columns = columns
+	}
+	return nil
+}
+
+// loadProfiles reads the --profileFile, if any, applies the --profile it
+// selects to opts, and returns the expressions it defines.
+func loadProfiles(opts *StatOptions) (map[string]*status.Expression, error) {
+	if opts.ProfileFile == "" {
+		if opts.Profile != "" {
+			return nil, fmt.Errorf("cannot use --profile without --profileFile")
+		}
+		return nil, nil
+	}
+
+	profiles, err := LoadProfileFile(opts.ProfileFile)
+	if err != nil {
+		return nil, err
+	}
+	expressions, err := profiles.ParseExpressions()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Profile != "" {
+		if opts.Columns != "" || opts.AppendColumns != "" {
+			return nil, fmt.Errorf("cannot use --profile with -o or -O")
+		}
+		if err := profiles.applyProfile(opts.Profile, opts); err != nil {
+			return nil, err
+		}
+	}
+	return expressions, nil
+}
@@ -0,0 +1,168 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongostat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/mongodb/mongo-tools/mongostat/stat_consumer/line"
+	"github.com/mongodb/mongo-tools/mongostat/status"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testProfileFile = `
+expressions:
+  dirty_ratio: '"wiredTiger.cache.tracked dirty bytes in the cache" / "wiredTiger.cache.bytes currently in the cache" * 100'
+  moves: metrics.record.moves.diff()
+profiles:
+  cache:
+    columns: [host, dirty_ratio=dirty%, time]
+  moves:
+    append: true
+    columns: [moves]
+`
+
+func writeProfileFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "profiles.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExpressions(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	oldStat := &status.ServerStatus{
+		SampleTime: time.Unix(100, 0),
+		Flattened: map[string]interface{}{
+			"metrics.record.moves": int64(10),
+			"opcounters.insert":    int32(10),
+		},
+	}
+	newStat := &status.ServerStatus{
+		SampleTime: time.Unix(104, 0),
+		Flattened: map[string]interface{}{
+			"metrics.record.moves":                              int64(30),
+			"opcounters.insert":                                 int32(30),
+			"wiredTiger.cache.tracked dirty bytes in the cache": 25.0,
+			"wiredTiger.cache.bytes currently in the cache":     int64(200),
+			"empty": int32(0),
+			"name":  "mongod",
+		},
+	}
+	eval := func(source string) string {
+		expr, err := status.ParseExpression(source)
+		So(err, ShouldBeNil)
+		return expr.Eval(newStat, oldStat)
+	}
+
+	Convey("Expressions should evaluate over serverStatus fields", t, func() {
+		So(eval(`1 + 2 * 3`), ShouldEqual, "7")
+		So(eval(`(1 + 2) * 3`), ShouldEqual, "9")
+		So(eval(`10 - 2 - 3`), ShouldEqual, "5")
+		So(eval(`-2 * -3`), ShouldEqual, "6")
+		So(eval(`1 / 3`), ShouldEqual, "0.33")
+		So(eval(
+			`"wiredTiger.cache.tracked dirty bytes in the cache" / `+
+				`"wiredTiger.cache.bytes currently in the cache" * 100`,
+		), ShouldEqual, "12.50")
+		So(eval(`metrics.record.moves.diff()`), ShouldEqual, "20")
+		So(eval(`metrics.record.moves.rate()`), ShouldEqual, "5")
+		So(eval(`"opcounters.insert".rate() / 2`), ShouldEqual, "2.50")
+	})
+
+	Convey("Expressions should be INVALID when they cannot be computed", t, func() {
+		So(eval(`missing.field + 1`), ShouldEqual, "INVALID")
+		So(eval(`name * 2`), ShouldEqual, "INVALID")
+		So(eval(`opcounters.insert / empty`), ShouldEqual, "INVALID")
+		So(eval(`empty.diff()`), ShouldEqual, "INVALID")
+	})
+
+	Convey("Malformed expressions should not parse", t, func() {
+		for _, source := range []string{``, `1 +`, `(1 + 2`, `1 2`, `"unterminated`, `* 2`, `1..2`} {
+			_, err := status.ParseExpression(source)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Expression columns should be computed in stat lines", t, func() {
+		expr, err := status.ParseExpression(`metrics.record.moves.diff() * 2`)
+		So(err, ShouldBeNil)
+		config := &status.ReaderConfig{
+			Expressions: map[string]*status.Expression{"moves": expr},
+		}
+		statLine := line.NewStatLine(oldStat, newStat, []string{"moves"}, config)
+		So(statLine.Fields["moves"], ShouldEqual, "40")
+	})
+}
+
+func TestProfiles(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	path := writeProfileFile(t, testProfileFile)
+
+	Convey("Profiles should set the columns to show", t, func() {
+		opts, err := ParseOptions([]string{"--profileFile", path, "--profile", "cache"}, "", "")
+		So(err, ShouldBeNil)
+		So(opts.Columns, ShouldEqual, "host,dirty_ratio=dirty%,time")
+		So(opts.AppendColumns, ShouldEqual, "")
+		So(opts.Expressions, ShouldContainKey, "dirty_ratio")
+		So(opts.Expressions, ShouldContainKey, "moves")
+
+		opts, err = ParseOptions([]string{"--profileFile", path, "--profile", "moves"}, "", "")
+		So(err, ShouldBeNil)
+		So(opts.Columns, ShouldEqual, "")
+		So(opts.AppendColumns, ShouldEqual, "moves")
+	})
+
+	Convey("Expressions should be usable with -o without a profile", t, func() {
+		opts, err := ParseOptions([]string{"--profileFile", path, "-o", "host,moves"}, "", "")
+		So(err, ShouldBeNil)
+		So(opts.Columns, ShouldEqual, "host,moves")
+		So(opts.Expressions, ShouldContainKey, "moves")
+	})
+
+	Convey("Invalid profile options should error", t, func() {
+		_, err := ParseOptions([]string{"--profile", "cache"}, "", "")
+		So(err, ShouldNotBeNil)
+
+		_, err = ParseOptions([]string{"--profileFile", path, "--profile", "nope"}, "", "")
+		So(err, ShouldNotBeNil)
+
+		_, err = ParseOptions(
+			[]string{"--profileFile", path, "--profile", "cache", "-o", "host"},
+			"",
+			"",
+		)
+		So(err, ShouldNotBeNil)
+
+		_, err = ParseOptions([]string{"--profileFile", filepath.Join(t.TempDir(), "no")}, "", "")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Invalid profile files should error", t, func() {
+		for _, content := range []string{
+			"expressions: {dirty: '1 + 1'}",
+			"expressions: {bad.name: '1 + 1'}",
+			"expressions: {broken: '1 +'}",
+			"profiles: {empty: {columns: []}}",
+			"unknown: true",
+		} {
+			_, err := ParseOptions(
+				[]string{"--profileFile", writeProfileFile(t, content), "--profile", "empty"},
+				"",
+				"",
+			)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongostat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	rawDumpJSON = "json"
+	rawDumpBSON = "bson"
+)
+
+// rawDumpTimeFormat is the format of the sample times in the names of the
+// --rawDump files. It sorts in time order.
+const rawDumpTimeFormat = "20060102T150405.000Z"
+
+// rawDumper writes each serverStatus document that mongostat samples to a
+// file of its own in the --rawDump directory, named after the host and the
+// time of the sample, so that they can be analyzed after the run.
+type rawDumper struct {
+	dir    string
+	format string
+}
+
+// validateRawDump checks --rawDump and --rawDumpFormat.
+func validateRawDump(opts *StatOptions) error {
+	switch {
+	case opts.RawDump == "" && opts.RawDumpFormat != "":
+		return fmt.Errorf("--rawDumpFormat can only be used with --rawDump")
+	case opts.RawDumpFormat != "" &&
+		opts.RawDumpFormat != rawDumpJSON &&
+		opts.RawDumpFormat != rawDumpBSON:
+		return fmt.Errorf("--rawDumpFormat must be json or bson, got %v", opts.RawDumpFormat)
+	}
+	return nil
+}
+
+// newRawDumper creates the directory of --rawDump if it doesn't exist yet.
+func newRawDumper(dir, format string) (*rawDumper, error) {
+	dir = util.ToUniversalPath(dir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating --rawDump directory: %v", err)
+	}
+	if format == "" {
+		format = rawDumpJSON
+	}
+	return &rawDumper{dir: dir, format: format}, nil
+}
+
+// path returns the file that the sample of host at sampleTime is written to.
+func (d *rawDumper) path(host string, sampleTime time.Time) string {
+	name := strings.NewReplacer(":", "_", "/", "_", `\`, "_").Replace(host) +
+		"-" + sampleTime.UTC().Format(rawDumpTimeFormat) + "." + d.format
+	return filepath.Join(d.dir, name)
+}
+
+// write writes the serverStatus document of host sampled at sampleTime,
+// as canonical extended JSON or as BSON.
+func (d *rawDumper) write(host string, sampleTime time.Time, serverStatus bson.Raw) error {
+	content := []byte(serverStatus)
+	if d.format == rawDumpJSON {
+		var err error
+		content, err = bson.MarshalExtJSON(serverStatus, true, false)
+		if err != nil {
+			return fmt.Errorf("error encoding serverStatus of %v: %v", host, err)
+		}
+		content = append(content, '\n')
+	}
+	path := d.path(host, sampleTime)
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return fmt.Errorf("error writing --rawDump file: %v", err)
+	}
+	return nil
+}
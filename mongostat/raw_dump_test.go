@@ -0,0 +1,63 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongostat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRawDump(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("--rawDumpFormat should require --rawDump and a known format", t, func() {
+		_, err := ParseOptions([]string{"--rawDumpFormat", "bson"}, "", "")
+		So(err, ShouldNotBeNil)
+
+		_, err = ParseOptions([]string{"--rawDump", "stats", "--rawDumpFormat", "csv"}, "", "")
+		So(err, ShouldNotBeNil)
+
+		opts, err := ParseOptions([]string{"--rawDump", "stats", "--rawDumpFormat", "bson"}, "", "")
+		So(err, ShouldBeNil)
+		So(opts.RawDump, ShouldEqual, "stats")
+	})
+
+	Convey("Each sample should be written to a file named after its host and time", t, func() {
+		dir := filepath.Join(t.TempDir(), "stats")
+		serverStatus, err := bson.Marshal(bson.D{{"host", "db1"}, {"uptime", 12.5}})
+		So(err, ShouldBeNil)
+		sampleTime := time.Date(2025, 3, 4, 5, 6, 7, 890e6, time.FixedZone("EST", -5*3600))
+
+		Convey("as extended JSON by default", func() {
+			dumper, err := newRawDumper(dir, "")
+			So(err, ShouldBeNil)
+			So(dumper.write("db1.example.com:27017", sampleTime, serverStatus), ShouldBeNil)
+
+			path := filepath.Join(dir, "db1.example.com_27017-20250304T100607.890Z.json")
+			content, err := os.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(content), ShouldEqual,
+				`{"host":"db1","uptime":{"$numberDouble":"12.5"}}`+"\n")
+		})
+
+		Convey("or as BSON", func() {
+			dumper, err := newRawDumper(dir, rawDumpBSON)
+			So(err, ShouldBeNil)
+			So(dumper.write("db1:27017", sampleTime, serverStatus), ShouldBeNil)
+
+			content, err := os.ReadFile(filepath.Join(dir, "db1_27017-20250304T100607.890Z.bson"))
+			So(err, ShouldBeNil)
+			So(bson.Raw(content), ShouldResemble, bson.Raw(serverStatus))
+		})
+	})
+}
@@ -83,6 +83,12 @@ func (glf *GridLineFormatter) FormatLines(
 		for _, key := range headerKeys {
 			glf.WriteCell(l.Fields[key])
 		}
+		if l.Anomaly != "" {
+			glf.WriteCell("<< " + l.Anomaly)
+		}
+		if len(l.Alerts) > 0 {
+			glf.WriteCell("!! ALERT: " + strings.Join(l.Alerts, "; "))
+		}
 		glf.EndRow()
 	}
 	glf.Flush(buf)
@@ -64,6 +64,8 @@ type cell struct {
 	feed     bool
 	selected bool
 	header   bool
+	anomaly  bool
+	alert    bool
 }
 
 func (ilf *InteractiveLineFormatter) Finish() {
@@ -114,6 +116,8 @@ func (ilf *InteractiveLineFormatter) FormatLines(
 			cell.text = newText
 			cell.feed = false
 			cell.header = j == 0 && ilf.includeHeader
+			cell.anomaly = l.Anomaly != ""
+			cell.alert = len(l.Alerts) > 0
 			if w := len(cell.text); w > column.width {
 				column.width = w
 			}
@@ -232,7 +236,14 @@ func (ilf *InteractiveLineFormatter) update() {
 				fgAttr = termbox.ColorBlack
 				bgAttr = termbox.ColorWhite
 			}
-			if cell.changed || cell.feed {
+			if cell.anomaly && !cell.selected {
+				fgAttr = termbox.ColorRed
+			}
+			if cell.alert && !cell.selected {
+				fgAttr = termbox.ColorWhite
+				bgAttr = termbox.ColorRed
+			}
+			if cell.changed || cell.feed || cell.anomaly || cell.alert {
 				fgAttr |= termbox.AttrBold
 			}
 			if cell.header {
@@ -58,6 +58,12 @@ func (jlf *JSONLineFormatter) FormatLines(
 		for _, key := range headerKeys {
 			lineJson[keyNames[key]] = l.Fields[key]
 		}
+		if l.Anomaly != "" {
+			lineJson["anomaly"] = l.Anomaly
+		}
+		if len(l.Alerts) > 0 {
+			lineJson["alerts"] = l.Alerts
+		}
 		jsonFormat[l.Fields["host"]] = lineJson
 	}
 
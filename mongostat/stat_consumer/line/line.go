@@ -15,6 +15,17 @@ type StatLine struct {
 	Fields  map[string]string
 	Error   error
 	Printed bool
+
+	// Anomaly is set when --adaptive detected a sharp change in the metrics
+	// since the previous sample, and describes the change.
+	Anomaly string
+
+	// Alerts describes the --alert thresholds that fired for this sample.
+	Alerts []string
+
+	// FilteredOut is set when the sample doesn't match the --filter, so that
+	// its row isn't printed.
+	FilteredOut bool
 }
 
 type StatLines []*StatLine
@@ -38,12 +49,16 @@ func NewStatLine(
 	c *status.ReaderConfig,
 ) *StatLine {
 	line := &StatLine{
-		Fields: make(map[string]string),
+		Fields:  make(map[string]string),
+		Anomaly: newStat.Anomaly,
 	}
 	for _, key := range headerKeys {
 		_, ok := StatHeaders[key]
+		expr, isExpr := c.Expressions[key]
 		if ok {
 			line.Fields[key] = StatHeaders[key].ReadField(c, newStat, oldStat)
+		} else if isExpr {
+			line.Fields[key] = expr.Eval(newStat, oldStat)
 		} else {
 			line.Fields[key] = status.InterpretField(key, newStat, oldStat)
 		}
@@ -51,5 +66,14 @@ func NewStatLine(
 	// We always need host and storage_engine, even if they aren't being displayed
 	line.Fields["host"] = StatHeaders["host"].ReadField(c, newStat, oldStat)
 	line.Fields["storage_engine"] = StatHeaders["storage_engine"].ReadField(c, newStat, oldStat)
+	if c.Alerts != nil {
+		line.Alerts = c.Alerts.Check(newStat, oldStat)
+	}
+	if c.Filter != nil {
+		line.FilteredOut = !c.Filter.Matches(newStat, oldStat)
+	}
+	if c.Summary != nil {
+		c.Summary.Add(headerKeys, newStat, oldStat)
+	}
 	return line
 }
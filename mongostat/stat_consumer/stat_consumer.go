@@ -90,6 +90,10 @@ func (sc *StatConsumer) Update(newStat *status.ServerStatus) (l *line.StatLine,
 // FormatLines consumes StatLines, formats them, and sends them to its writer
 // It returns true if the formatter should no longer receive data.
 func (sc *StatConsumer) FormatLines(lines []*line.StatLine) bool {
+	lines = filterLines(lines)
+	if len(lines) == 0 {
+		return sc.formatter.IsFinished()
+	}
 	str := sc.formatter.FormatLines(lines, sc.headers, sc.keyNames)
 	_, err := fmt.Fprintf(sc.writer, "%s", str)
 	if err != nil {
@@ -98,3 +102,18 @@ func (sc *StatConsumer) FormatLines(lines []*line.StatLine) bool {
 	}
 	return sc.formatter.IsFinished()
 }
+
+// filterLines drops the lines that the --filter filtered out. They are marked
+// as printed, so that a host that sends no new sample is still reported as
+// such.
+func filterLines(lines []*line.StatLine) []*line.StatLine {
+	var kept []*line.StatLine
+	for _, l := range lines {
+		if l.FilteredOut && l.Error == nil && !l.Printed {
+			l.Printed = true
+			continue
+		}
+		kept = append(kept, l)
+	}
+	return kept
+}
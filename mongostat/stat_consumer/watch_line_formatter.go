@@ -0,0 +1,93 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package stat_consumer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mongodb/mongo-tools/common/tui"
+	"github.com/mongodb/mongo-tools/mongostat/stat_consumer/line"
+)
+
+// WatchLineFormatter shows the StatLines in the terminal UI shared with
+// mongotop, where the latest line of each host can be sorted and filtered,
+// and the previous lines of a host shown by drilling down into it.
+type WatchLineFormatter struct {
+	*limitableFormatter
+	screen *tui.Screen
+}
+
+func NewWatchLineFormatter(maxRows int64, _ bool) LineFormatter {
+	screen, err := tui.NewScreen(tui.NewView("", false))
+	if err != nil {
+		fmt.Printf("Error setting up terminal UI: %v", err)
+		panic("could not set up interactive terminal interface")
+	}
+	return &WatchLineFormatter{
+		limitableFormatter: &limitableFormatter{maxRows: maxRows},
+		screen:             screen,
+	}
+}
+
+func init() {
+	if tui.Supported {
+		FormatterConstructors["watch"] = NewWatchLineFormatter
+	}
+}
+
+// IsFinished returns true once the user quits or --rowcount samples have been
+// shown.
+func (wlf *WatchLineFormatter) IsFinished() bool {
+	select {
+	case <-wlf.screen.Quit():
+		return true
+	default:
+	}
+	return wlf.limitableFormatter.IsFinished()
+}
+
+func (wlf *WatchLineFormatter) Finish() {
+	wlf.screen.Close()
+}
+
+// FormatLines shows the StatLines in the terminal UI.
+func (wlf *WatchLineFormatter) FormatLines(
+	lines []*line.StatLine,
+	headerKeys []string,
+	keyNames map[string]string,
+) string {
+	sort.Sort(line.StatLines(lines))
+	wlf.screen.Show(watchTable(lines, headerKeys, keyNames))
+	wlf.increment()
+	return ""
+}
+
+// watchTable returns the StatLines as a table of the terminal UI, with the
+// lines of the hosts that could not be polled as errors.
+func watchTable(lines []*line.StatLine, headerKeys []string, keyNames map[string]string) tui.Table {
+	table := tui.Table{History: true}
+	for i, key := range headerKeys {
+		table.Columns = append(table.Columns, keyNames[key])
+		if key == "host" {
+			table.FilterColumn = i
+		}
+	}
+	for _, l := range lines {
+		host := l.Fields["host"]
+		if l.Error != nil {
+			table.Errors = append(table.Errors, fmt.Sprintf("%s: %s", host, l.Error))
+			continue
+		}
+		row := tui.Row{Host: host, Alert: l.Anomaly != "" || len(l.Alerts) > 0}
+		for _, key := range headerKeys {
+			row.Cells = append(row.Cells, l.Fields[key])
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
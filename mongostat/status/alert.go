@@ -0,0 +1,298 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package status
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Alert is a threshold on a metric, such as 'qrw>100 for 3 samples', that
+// fires when the metric crosses the threshold in that many consecutive
+// samples of a host.
+//
+// The metric is one of the names in alertMetrics, a derived column defined in
+// a --profileFile, or an expression over serverStatus fields.
+type Alert struct {
+	source string
+	*comparison
+	samples int
+}
+
+// comparison compares a metric to a threshold, as alerts and filters do.
+type comparison struct {
+	metric    string
+	value     alertMetric
+	op        string
+	threshold float64
+}
+
+// alertOperators are the comparisons an alert can use, longest first so that
+// '>=' isn't read as '>'.
+var alertOperators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+var alertSamplesRE = regexp.MustCompile(`\s+for\s+(\d+)\s+samples?\s*$`)
+
+// alertMetric computes a metric from the latest two samples of a host, and
+// returns false if it can't.
+type alertMetric func(newStat, oldStat *ServerStatus) (float64, bool)
+
+// alertMetrics are the metrics alerts can refer to by the name of their
+// column. Queues, active operations and connections are the values at the
+// time of the sample; operation counts and network traffic are per second;
+// dirty and used are percentages of the cache and res and vsize are in MB.
+var alertMetrics = map[string]alertMetric{
+	"qr":  queuedMetric(func(qr, _ int64) int64 { return qr }),
+	"qw":  queuedMetric(func(_, qw int64) int64 { return qw }),
+	"qrw": queuedMetric(func(qr, qw int64) int64 { return qr + qw }),
+	"ar":  activeMetric(func(ar, _ int64) int64 { return ar }),
+	"aw":  activeMetric(func(_, aw int64) int64 { return aw }),
+	"arw": activeMetric(func(ar, aw int64) int64 { return ar + aw }),
+	"conn": func(newStat, _ *ServerStatus) (float64, bool) {
+		if newStat.Connections == nil {
+			return 0, false
+		}
+		return float64(newStat.Connections.Current), true
+	},
+	"dirty":   cacheMetric(func(c *CacheStats) int64 { return c.TrackedDirtyBytes }),
+	"used":    cacheMetric(func(c *CacheStats) int64 { return c.CurrentCachedBytes }),
+	"insert":  opcountMetric(func(o *OpcountStats) int64 { return o.Insert }),
+	"query":   opcountMetric(func(o *OpcountStats) int64 { return o.Query }),
+	"update":  opcountMetric(func(o *OpcountStats) int64 { return o.Update }),
+	"delete":  opcountMetric(func(o *OpcountStats) int64 { return o.Delete }),
+	"getmore": opcountMetric(func(o *OpcountStats) int64 { return o.GetMore }),
+	"command": opcountMetric(func(o *OpcountStats) int64 { return o.Command }),
+	"net_in":  networkMetric(func(n *NetworkStats) int64 { return n.BytesIn }),
+	"net_out": networkMetric(func(n *NetworkStats) int64 { return n.BytesOut }),
+	"res":     memoryMetric(func(m *MemStats) int64 { return m.Resident }),
+	"vsize":   memoryMetric(func(m *MemStats) int64 { return m.Virtual }),
+}
+
+func queuedMetric(f func(qr, qw int64) int64) alertMetric {
+	return func(newStat, _ *ServerStatus) (float64, bool) {
+		if newStat.GlobalLock == nil {
+			return 0, false
+		}
+		return float64(f(QueuedOps(newStat))), true
+	}
+}
+
+func activeMetric(f func(ar, aw int64) int64) alertMetric {
+	return func(newStat, _ *ServerStatus) (float64, bool) {
+		if newStat.GlobalLock == nil {
+			return 0, false
+		}
+		return float64(f(ActiveOps(newStat))), true
+	}
+}
+
+func cacheMetric(f func(*CacheStats) int64) alertMetric {
+	return func(newStat, _ *ServerStatus) (float64, bool) {
+		if newStat.WiredTiger == nil || newStat.WiredTiger.Cache.MaxBytesConfigured == 0 {
+			return 0, false
+		}
+		cache := &newStat.WiredTiger.Cache
+		return 100 * float64(f(cache)) / float64(cache.MaxBytesConfigured), true
+	}
+}
+
+func opcountMetric(f func(*OpcountStats) int64) alertMetric {
+	return func(newStat, oldStat *ServerStatus) (float64, bool) {
+		if newStat.Opcounters == nil || oldStat.Opcounters == nil {
+			return 0, false
+		}
+		return perSecond(f(newStat.Opcounters), f(oldStat.Opcounters), newStat, oldStat)
+	}
+}
+
+func networkMetric(f func(*NetworkStats) int64) alertMetric {
+	return func(newStat, oldStat *ServerStatus) (float64, bool) {
+		if newStat.Network == nil || oldStat.Network == nil {
+			return 0, false
+		}
+		return perSecond(f(newStat.Network), f(oldStat.Network), newStat, oldStat)
+	}
+}
+
+func memoryMetric(f func(*MemStats) int64) alertMetric {
+	return func(newStat, _ *ServerStatus) (float64, bool) {
+		if newStat.Mem == nil {
+			return 0, false
+		}
+		return float64(f(newStat.Mem)), true
+	}
+}
+
+func perSecond(newVal, oldVal int64, newStat, oldStat *ServerStatus) (float64, bool) {
+	sampleSecs := newStat.SampleTime.Sub(oldStat.SampleTime).Seconds()
+	if sampleSecs <= 0 {
+		return 0, false
+	}
+	return float64(newVal-oldVal) / sampleSecs, true
+}
+
+// ParseAlert parses an alert of the form '<metric><op><threshold>', optionally
+// followed by 'for <n> samples'. The operator is one of >, >=, <, <=, == and
+// !=. Derived columns are looked up in expressions.
+func ParseAlert(source string, expressions map[string]*Expression) (*Alert, error) {
+	alert := &Alert{source: strings.TrimSpace(source), samples: 1}
+	rest := alert.source
+	if m := alertSamplesRE.FindStringSubmatchIndex(rest); m != nil {
+		samples, err := strconv.Atoi(rest[m[2]:m[3]])
+		if err != nil || samples < 1 {
+			return nil, fmt.Errorf("invalid alert '%v': the number of samples must be positive", source)
+		}
+		alert.samples = samples
+		rest = rest[:m[0]]
+	}
+
+	c, err := parseComparison(rest, expressions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid alert '%v': %v", source, err)
+	}
+	alert.comparison = c
+	return alert, nil
+}
+
+// parseComparison parses a comparison of the form '<metric><op><threshold>'.
+func parseComparison(source string, expressions map[string]*Expression) (*comparison, error) {
+	pos, op := findAlertOperator(source)
+	if op == "" {
+		return nil, fmt.Errorf(
+			"expected a comparison with one of %v",
+			strings.Join(alertOperators, " "),
+		)
+	}
+	c := &comparison{op: op, metric: strings.TrimSpace(source[:pos])}
+	thresholdSource := strings.TrimSpace(source[pos+len(op):])
+	threshold, err := strconv.ParseFloat(thresholdSource, 64)
+	if err != nil {
+		return nil, fmt.Errorf("threshold '%v' is not a number", thresholdSource)
+	}
+	c.threshold = threshold
+
+	if c.metric == "" {
+		return nil, fmt.Errorf("missing metric")
+	}
+	if metric, ok := alertMetrics[c.metric]; ok {
+		c.value = metric
+	} else if expr, ok := expressions[c.metric]; ok {
+		c.value = expr.root.eval
+	} else {
+		expr, err := ParseExpression(c.metric)
+		if err != nil {
+			return nil, err
+		}
+		c.value = expr.root.eval
+	}
+	return c, nil
+}
+
+// findAlertOperator returns the position of the first comparison operator in
+// source that is outside of a quoted field name.
+func findAlertOperator(source string) (int, string) {
+	quoted := false
+	for i := 0; i < len(source); i++ {
+		if source[i] == '"' {
+			quoted = !quoted
+			continue
+		}
+		if quoted {
+			continue
+		}
+		for _, op := range alertOperators {
+			if strings.HasPrefix(source[i:], op) {
+				return i, op
+			}
+		}
+	}
+	return -1, ""
+}
+
+// String returns the source of the alert.
+func (a *Alert) String() string {
+	return a.source
+}
+
+// matches returns the value of the metric, and whether it crosses the
+// threshold. A metric that can't be computed never does.
+func (c *comparison) matches(newStat, oldStat *ServerStatus) (float64, bool) {
+	val, ok := c.value(newStat, oldStat)
+	if !ok || math.IsNaN(val) || math.IsInf(val, 0) {
+		return val, false
+	}
+	switch c.op {
+	case ">":
+		return val, val > c.threshold
+	case ">=":
+		return val, val >= c.threshold
+	case "<":
+		return val, val < c.threshold
+	case "<=":
+		return val, val <= c.threshold
+	case "==":
+		return val, val == c.threshold
+	}
+	return val, val != c.threshold
+}
+
+// AlertTracker checks alerts against the samples of each host, counting for
+// how many consecutive samples each alert matched, and remembers whether any
+// fired.
+type AlertTracker struct {
+	alerts []*Alert
+
+	mu     sync.Mutex
+	streak map[string][]int
+	fired  bool
+}
+
+// NewAlertTracker returns an AlertTracker for alerts.
+func NewAlertTracker(alerts []*Alert) *AlertTracker {
+	return &AlertTracker{alerts: alerts, streak: map[string][]int{}}
+}
+
+// Check updates the counts with the latest two samples of a host, and
+// returns a description of each alert that is firing.
+func (t *AlertTracker) Check(newStat, oldStat *ServerStatus) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	streak, ok := t.streak[newStat.Host]
+	if !ok {
+		streak = make([]int, len(t.alerts))
+		t.streak[newStat.Host] = streak
+	}
+	var firing []string
+	for i, alert := range t.alerts {
+		val, matched := alert.matches(newStat, oldStat)
+		if !matched {
+			streak[i] = 0
+			continue
+		}
+		streak[i]++
+		if streak[i] >= alert.samples {
+			t.fired = true
+			firing = append(
+				firing,
+				fmt.Sprintf("%v (%v=%v)", alert, alert.metric, formatNumber(val)),
+			)
+		}
+	}
+	return firing
+}
+
+// Fired returns whether any alert fired so far.
+func (t *AlertTracker) Fired() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.fired
+}
@@ -0,0 +1,286 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package status
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is an arithmetic expression over serverStatus fields, used for
+// derived columns such as the ratio of dirty bytes in the cache.
+//
+// Expressions combine numbers and fields with +, -, *, / and parentheses.
+// Fields use the same dot-syntax as -o, including the optional .diff() and
+// .rate() methods. Fields whose names contain spaces or operators are written
+// in double quotes, e.g.
+//
+//	"wiredTiger.cache.tracked dirty bytes in the cache" / "wiredTiger.cache.maximum bytes configured" * 100
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+type exprNode interface {
+	eval(newStat, oldStat *ServerStatus) (float64, bool)
+}
+
+type numberNode float64
+
+type fieldNode struct {
+	field  string
+	method string
+}
+
+type negateNode struct {
+	operand exprNode
+}
+
+type binaryNode struct {
+	op          byte
+	left, right exprNode
+}
+
+// ParseExpression parses an Expression from its source.
+func ParseExpression(source string) (*Expression, error) {
+	p := &exprParser{source: source}
+	root, err := p.parseSum()
+	if err == nil {
+		p.skipSpace()
+		if p.pos < len(p.source) {
+			err = fmt.Errorf("unexpected '%c'", p.source[p.pos])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%v' at offset %v: %v", source, p.pos, err)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression against the latest two ServerStatuses. It
+// returns "INVALID" if a field is missing or not a number, or if the
+// expression divides by zero, like the values of other custom fields.
+func (e *Expression) Eval(newStat, oldStat *ServerStatus) string {
+	val, ok := e.root.eval(newStat, oldStat)
+	if !ok || math.IsNaN(val) || math.IsInf(val, 0) {
+		return "INVALID"
+	}
+	return formatNumber(val)
+}
+
+// formatNumber formats whole numbers as integers and others with two
+// decimals.
+func formatNumber(val float64) string {
+	if val == math.Trunc(val) && math.Abs(val) < 1e15 {
+		return strconv.FormatInt(int64(val), 10)
+	}
+	return strconv.FormatFloat(val, 'f', 2, 64)
+}
+
+func (n numberNode) eval(_, _ *ServerStatus) (float64, bool) {
+	return float64(n), true
+}
+
+func (n *fieldNode) eval(newStat, oldStat *ServerStatus) (float64, bool) {
+	newVal, ok := numberToFloat64(newStat.Flattened[n.field])
+	if !ok || n.method == "" {
+		return newVal, ok
+	}
+	oldVal, ok := numberToFloat64(oldStat.Flattened[n.field])
+	if !ok {
+		return 0, false
+	}
+	if n.method == "diff" {
+		return newVal - oldVal, true
+	}
+	sampleSecs := newStat.SampleTime.Sub(oldStat.SampleTime).Seconds()
+	if sampleSecs <= 0 {
+		return 0, false
+	}
+	return (newVal - oldVal) / sampleSecs, true
+}
+
+func (n *negateNode) eval(newStat, oldStat *ServerStatus) (float64, bool) {
+	val, ok := n.operand.eval(newStat, oldStat)
+	return -val, ok
+}
+
+func (n *binaryNode) eval(newStat, oldStat *ServerStatus) (float64, bool) {
+	left, ok := n.left.eval(newStat, oldStat)
+	if !ok {
+		return 0, false
+	}
+	right, ok := n.right.eval(newStat, oldStat)
+	if !ok {
+		return 0, false
+	}
+	switch n.op {
+	case '+':
+		return left + right, true
+	case '-':
+		return left - right, true
+	case '*':
+		return left * right, true
+	}
+	if right == 0 {
+		return 0, false
+	}
+	return left / right, true
+}
+
+func numberToFloat64(num interface{}) (float64, bool) {
+	if n, ok := numberToInt64(num); ok {
+		return float64(n), true
+	}
+	if n, ok := num.(float64); ok {
+		return n, true
+	}
+	return 0, false
+}
+
+// exprParser is a recursive descent parser for expressions.
+type exprParser struct {
+	source string
+	pos    int
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.source) && unicode.IsSpace(rune(p.source[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space character, or 0 at the end of the source.
+func (p *exprParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.source) {
+		return p.source[p.pos]
+	}
+	return 0
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '+' || op == '-'; op = p.peek() {
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '*' || op == '/'; op = p.peek() {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek() == '-' {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateNode{operand}, nil
+	}
+	return p.parseOperand()
+}
+
+func (p *exprParser) parseOperand() (exprNode, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		node, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.pos++
+		return node, nil
+	case c == '"':
+		p.pos++
+		end := strings.IndexByte(p.source[p.pos:], '"')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated field name")
+		}
+		field := p.source[p.pos : p.pos+end]
+		p.pos += end + 1
+		return p.fieldWithMethod(field), nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.source) && strings.IndexByte("0123456789.eE", p.source[p.pos]) >= 0 {
+			p.pos++
+		}
+		literal := p.source[start:p.pos]
+		num, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			p.pos = start
+			return nil, fmt.Errorf("invalid number '%v'", literal)
+		}
+		return numberNode(num), nil
+	case strings.IndexByte("+-*/)", c) >= 0:
+		return nil, fmt.Errorf("unexpected '%c'", c)
+	}
+
+	start := p.pos
+	for p.pos < len(p.source) && !isExprDelimiter(p.source[p.pos]) {
+		p.pos++
+	}
+	return p.fieldWithMethod(p.source[start:p.pos]), nil
+}
+
+// fieldWithMethod returns a field node for field, consuming a .diff() or
+// .rate() that follows it. The "." and method name of an unquoted field have
+// already been read as part of its name.
+func (p *exprParser) fieldWithMethod(field string) exprNode {
+	rest := p.source[p.pos:]
+	for _, method := range []string{"diff", "rate"} {
+		if strings.HasSuffix(field, "."+method) && strings.HasPrefix(rest, "()") {
+			p.pos += len("()")
+			return &fieldNode{field: strings.TrimSuffix(field, "."+method), method: method}
+		}
+		if strings.HasPrefix(rest, "."+method+"()") {
+			p.pos += len("." + method + "()")
+			return &fieldNode{field: field, method: method}
+		}
+	}
+	return &fieldNode{field: field}
+}
+
+func isExprDelimiter(c byte) bool {
+	return unicode.IsSpace(rune(c)) || strings.IndexByte("+-*/()\"", c) >= 0
+}
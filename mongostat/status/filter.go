@@ -0,0 +1,231 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package status
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter selects the samples whose rows are printed, such as
+// 'dirty>5 || qrw>50'.
+//
+// Filters combine comparisons of the same form as alerts, without their
+// number of samples, with && (and), || (or), ! (not) and parentheses. && binds
+// tighter than ||. A comparison whose metric can't be computed doesn't match.
+type Filter struct {
+	source string
+	root   filterNode
+}
+
+type filterNode interface {
+	matches(newStat, oldStat *ServerStatus) bool
+}
+
+type orNode []filterNode
+
+type andNode []filterNode
+
+type notNode struct {
+	operand filterNode
+}
+
+type comparisonNode struct {
+	*comparison
+}
+
+// ParseFilter parses a Filter from its source. Derived columns are looked up
+// in expressions.
+func ParseFilter(source string, expressions map[string]*Expression) (*Filter, error) {
+	p := &filterParser{source: source, expressions: expressions}
+	root, err := p.parseOr()
+	if err == nil {
+		p.skipSpace()
+		if p.pos < len(p.source) {
+			err = fmt.Errorf("unexpected '%c'", p.source[p.pos])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter '%v' at offset %v: %v", source, p.pos, err)
+	}
+	return &Filter{source: source, root: root}, nil
+}
+
+// String returns the source of the filter.
+func (f *Filter) String() string {
+	return f.source
+}
+
+// Matches returns whether the latest two samples of a host match the filter.
+func (f *Filter) Matches(newStat, oldStat *ServerStatus) bool {
+	return f.root.matches(newStat, oldStat)
+}
+
+func (n orNode) matches(newStat, oldStat *ServerStatus) bool {
+	for _, operand := range n {
+		if operand.matches(newStat, oldStat) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n andNode) matches(newStat, oldStat *ServerStatus) bool {
+	for _, operand := range n {
+		if !operand.matches(newStat, oldStat) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *notNode) matches(newStat, oldStat *ServerStatus) bool {
+	return !n.operand.matches(newStat, oldStat)
+}
+
+func (n comparisonNode) matches(newStat, oldStat *ServerStatus) bool {
+	_, matched := n.comparison.matches(newStat, oldStat)
+	return matched
+}
+
+type filterParser struct {
+	source      string
+	pos         int
+	expressions map[string]*Expression
+}
+
+func (p *filterParser) skipSpace() {
+	for p.pos < len(p.source) && p.source[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// consume skips token if it is next in the source.
+func (p *filterParser) consume(token string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.source[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	var operands orNode
+	for {
+		operand, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+		if !p.consume("||") {
+			break
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return operands, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	var operands andNode
+	for {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+		if !p.consume("&&") {
+			break
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return operands, nil
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	p.skipSpace()
+	if strings.HasPrefix(p.source[p.pos:], "!") && !strings.HasPrefix(p.source[p.pos:], "!=") {
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses a filter in parentheses or a comparison. Parentheses
+// that are followed by the rest of a comparison, as in '(qr+qw)/2>5', belong
+// to the expression of the comparison instead.
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	p.skipSpace()
+	if p.pos < len(p.source) && p.source[p.pos] == '(' {
+		end := p.scan(p.pos+1, true)
+		if end == len(p.source) {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		if p.endsOperand(end + 1) {
+			p.pos++
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume(")") {
+				return nil, fmt.Errorf("expected ')'")
+			}
+			return node, nil
+		}
+	}
+
+	end := p.scan(p.pos, false)
+	c, err := parseComparison(p.source[p.pos:end], p.expressions)
+	if err != nil {
+		return nil, fmt.Errorf("'%v': %v", strings.TrimSpace(p.source[p.pos:end]), err)
+	}
+	p.pos = end
+	return comparisonNode{c}, nil
+}
+
+// endsOperand returns whether the operand of a filter can end at pos.
+func (p *filterParser) endsOperand(pos int) bool {
+	rest := strings.TrimLeft(p.source[pos:], " ")
+	return rest == "" ||
+		strings.HasPrefix(rest, ")") ||
+		strings.HasPrefix(rest, "&&") ||
+		strings.HasPrefix(rest, "||")
+}
+
+// scan returns the position of the end of the text that starts at pos, which
+// is the ')' that closes it if inParens is set, and otherwise the next && or
+// || or unbalanced ')'. Quoted field names are skipped.
+func (p *filterParser) scan(pos int, inParens bool) int {
+	depth := 0
+	quoted := false
+	for ; pos < len(p.source); pos++ {
+		c := p.source[pos]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case c == ')':
+			return pos
+		case !inParens && depth == 0 &&
+			(strings.HasPrefix(p.source[pos:], "&&") || strings.HasPrefix(p.source[pos:], "||")):
+			return pos
+		}
+	}
+	return pos
+}
@@ -19,6 +19,19 @@ import (
 type ReaderConfig struct {
 	HumanReadable bool
 	TimeFormat    string
+
+	// Expressions are the derived columns defined in a --profileFile, by
+	// column name.
+	Expressions map[string]*Expression
+
+	// Alerts, if set, checks the --alert thresholds against each sample.
+	Alerts *AlertTracker
+
+	// Filter, if set, selects the samples whose rows are printed.
+	Filter *Filter
+
+	// Summary, if set, accumulates the values of each sample for --summary.
+	Summary *Summary
 }
 
 type LockUsage struct {
@@ -390,14 +403,19 @@ func ReadLockedDB(_ *ReaderConfig, newStat, oldStat *ServerStatus) (val string)
 }
 
 func ReadQRW(_ *ReaderConfig, newStat, _ *ServerStatus) string {
-	var qr int64
-	var qw int64
-	gl := newStat.GlobalLock
+	qr, qw := QueuedOps(newStat)
+	return fmt.Sprintf("%v|%v", qr, qw)
+}
+
+// QueuedOps returns the number of read and write operations queued on the
+// server when the stat was sampled.
+func QueuedOps(stat *ServerStatus) (qr, qw int64) {
+	gl := stat.GlobalLock
 	if gl != nil && gl.CurrentQueue != nil {
 		// If we have wiredtiger stats, use those instead
-		if newStat.WiredTiger != nil {
-			qr = gl.CurrentQueue.Readers + gl.ActiveClients.Readers - newStat.WiredTiger.Concurrent.Read.Out
-			qw = gl.CurrentQueue.Writers + gl.ActiveClients.Writers - newStat.WiredTiger.Concurrent.Write.Out
+		if stat.WiredTiger != nil {
+			qr = gl.CurrentQueue.Readers + gl.ActiveClients.Readers - stat.WiredTiger.Concurrent.Read.Out
+			qw = gl.CurrentQueue.Writers + gl.ActiveClients.Writers - stat.WiredTiger.Concurrent.Write.Out
 			if qr < 0 {
 				qr = 0
 			}
@@ -409,22 +427,27 @@ func ReadQRW(_ *ReaderConfig, newStat, _ *ServerStatus) string {
 			qw = gl.CurrentQueue.Writers
 		}
 	}
-	return fmt.Sprintf("%v|%v", qr, qw)
+	return qr, qw
 }
 
 func ReadARW(_ *ReaderConfig, newStat, _ *ServerStatus) string {
-	var ar int64
-	var aw int64
-	if gl := newStat.GlobalLock; gl != nil {
-		if newStat.WiredTiger != nil {
-			ar = newStat.WiredTiger.Concurrent.Read.Out
-			aw = newStat.WiredTiger.Concurrent.Write.Out
-		} else if newStat.GlobalLock.ActiveClients != nil {
+	ar, aw := ActiveOps(newStat)
+	return fmt.Sprintf("%v|%v", ar, aw)
+}
+
+// ActiveOps returns the number of read and write operations active on the
+// server when the stat was sampled.
+func ActiveOps(stat *ServerStatus) (ar, aw int64) {
+	if gl := stat.GlobalLock; gl != nil {
+		if stat.WiredTiger != nil {
+			ar = stat.WiredTiger.Concurrent.Read.Out
+			aw = stat.WiredTiger.Concurrent.Write.Out
+		} else if gl.ActiveClients != nil {
 			ar = gl.ActiveClients.Readers
 			aw = gl.ActiveClients.Writers
 		}
 	}
-	return fmt.Sprintf("%v|%v", ar, aw)
+	return ar, aw
 }
 
 func ReadNetIn(c *ReaderConfig, newStat, oldStat *ServerStatus) string {
@@ -11,6 +11,7 @@ import "time"
 type ServerStatus struct {
 	SampleTime         time.Time              `bson:""`
 	Flattened          map[string]interface{} `bson:""`
+	Anomaly            string                 `bson:""` // set by --adaptive burst detection
 	Host               string                 `bson:"host"`
 	Version            string                 `bson:"version"`
 	Process            string                 `bson:"process"`
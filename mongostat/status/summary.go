@@ -0,0 +1,209 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/mongodb/mongo-tools/common/text"
+)
+
+// summaryParts are the columns that show two metrics, which are summarized
+// separately.
+var summaryParts = map[string][]string{
+	"qrw": {"qr", "qw"},
+	"arw": {"ar", "aw"},
+}
+
+// unsummarizedColumns are the columns that are not numbers, or whose values
+// are not kept as numbers.
+var unsummarizedColumns = map[string]bool{
+	"host":           true,
+	"storage_engine": true,
+	"time":           true,
+	"set":            true,
+	"repl":           true,
+	"locked_db":      true,
+	"lrw":            true,
+	"lrwt":           true,
+	"flushes":        true,
+	"faults":         true,
+	"mapped":         true,
+	"nonmapped":      true,
+}
+
+// summaryMetric is a metric shown by a column.
+type summaryMetric struct {
+	name  string
+	value alertMetric
+}
+
+// Summary accumulates the values of the sampled columns of each host over a
+// whole run, for --summary. The metrics have the units of alerts: operation
+// counts and network traffic are per second, dirty and used are percentages
+// of the cache, and res and vsize are in MB. It is safe for concurrent use.
+type Summary struct {
+	expressions map[string]*Expression
+
+	mu      sync.Mutex
+	metrics map[string][]summaryMetric
+	// values holds the values of each metric of each host, in the order the
+	// metrics were first sampled
+	values map[string]map[string][]float64
+	order  map[string][]string
+}
+
+// NewSummary returns an empty Summary. Derived columns are looked up in
+// expressions.
+func NewSummary(expressions map[string]*Expression) *Summary {
+	return &Summary{
+		expressions: expressions,
+		metrics:     map[string][]summaryMetric{},
+		values:      map[string]map[string][]float64{},
+		order:       map[string][]string{},
+	}
+}
+
+// metricsOf returns the metrics shown by a column, which are none for a
+// column that is not a number.
+func (s *Summary) metricsOf(column string) []summaryMetric {
+	if metrics, ok := s.metrics[column]; ok {
+		return metrics
+	}
+	var metrics []summaryMetric
+	switch {
+	case unsummarizedColumns[column]:
+	case summaryParts[column] != nil:
+		for _, part := range summaryParts[column] {
+			metrics = append(metrics, summaryMetric{part, alertMetrics[part]})
+		}
+	case alertMetrics[column] != nil:
+		metrics = []summaryMetric{{column, alertMetrics[column]}}
+	case s.expressions[column] != nil:
+		metrics = []summaryMetric{{column, s.expressions[column].root.eval}}
+	default:
+		// a custom serverStatus field
+		if expr, err := ParseExpression(column); err == nil {
+			metrics = []summaryMetric{{column, expr.root.eval}}
+		}
+	}
+	s.metrics[column] = metrics
+	return metrics
+}
+
+// Add records the values of the columns in the latest two samples of a host.
+// Values that can't be computed are left out.
+func (s *Summary) Add(columns []string, newStat, oldStat *ServerStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	host := newStat.Host
+	values, ok := s.values[host]
+	if !ok {
+		values = map[string][]float64{}
+		s.values[host] = values
+	}
+	for _, column := range columns {
+		for _, metric := range s.metricsOf(column) {
+			val, ok := metric.value(newStat, oldStat)
+			if !ok || math.IsNaN(val) || math.IsInf(val, 0) {
+				continue
+			}
+			if _, seen := values[metric.name]; !seen {
+				s.order[host] = append(s.order[host], metric.name)
+			}
+			values[metric.name] = append(values[metric.name], val)
+		}
+	}
+}
+
+// MetricSummary summarizes the values of one metric.
+type MetricSummary struct {
+	Metric  string  `json:"metric"`
+	Samples int     `json:"samples"`
+	Min     float64 `json:"min"`
+	Avg     float64 `json:"avg"`
+	Max     float64 `json:"max"`
+	P95     float64 `json:"p95"`
+}
+
+// HostSummary summarizes the metrics of one host.
+type HostSummary struct {
+	Host    string          `json:"host"`
+	Metrics []MetricSummary `json:"metrics"`
+}
+
+// Hosts returns the summary of each host, in the order of their names, with
+// the metrics in the order of the columns.
+func (s *Summary) Hosts() []HostSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hosts := make([]HostSummary, 0, len(s.values))
+	for host, values := range s.values {
+		summary := HostSummary{Host: host, Metrics: []MetricSummary{}}
+		for _, metric := range s.order[host] {
+			summary.Metrics = append(summary.Metrics, summarize(metric, values[metric]))
+		}
+		hosts = append(hosts, summary)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+	return hosts
+}
+
+// summarize returns the summary of values, whose 95th percentile is found by
+// the nearest-rank method.
+func summarize(metric string, values []float64) MetricSummary {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	summary := MetricSummary{
+		Metric:  metric,
+		Samples: len(sorted),
+		Min:     sorted[0],
+		Max:     sorted[len(sorted)-1],
+		P95:     sorted[int(math.Ceil(0.95*float64(len(sorted))))-1],
+	}
+	for _, val := range sorted {
+		summary.Avg += val
+	}
+	summary.Avg /= float64(len(sorted))
+	return summary
+}
+
+// WriteText writes the summary as a table for each host.
+func (s *Summary) WriteText(w io.Writer) {
+	for _, host := range s.Hosts() {
+		_, _ = fmt.Fprintf(w, "\nsummary of %v:\n", host.Host)
+		grid := &text.GridWriter{ColumnPadding: 2}
+		grid.WriteCells("metric", "samples", "min", "avg", "max", "p95")
+		grid.EndRow()
+		for _, m := range host.Metrics {
+			grid.WriteCells(
+				m.Metric,
+				fmt.Sprint(m.Samples),
+				formatNumber(m.Min),
+				formatNumber(m.Avg),
+				formatNumber(m.Max),
+				formatNumber(m.P95),
+			)
+			grid.EndRow()
+		}
+		grid.Flush(w)
+		_, _ = fmt.Fprintln(w)
+	}
+}
+
+// WriteJSON writes the summary as a JSON document with the summary of each
+// host.
+func (s *Summary) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(struct {
+		Hosts []HostSummary `json:"hosts"`
+	}{s.Hosts()})
+}
@@ -0,0 +1,40 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongostat
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mongodb/mongo-tools/common/util"
+	"github.com/mongodb/mongo-tools/mongostat/status"
+)
+
+// WriteSummary writes the --summary of a run to out, as JSON if asJSON is
+// set and as a table otherwise, and to file as JSON if it is not empty.
+func WriteSummary(summary *status.Summary, out io.Writer, asJSON bool, file string) error {
+	if asJSON {
+		if err := summary.WriteJSON(out); err != nil {
+			return err
+		}
+	} else {
+		summary.WriteText(out)
+	}
+	if file == "" {
+		return nil
+	}
+	f, err := os.Create(util.ToUniversalPath(file))
+	if err != nil {
+		return fmt.Errorf("error creating summary file: %v", err)
+	}
+	if err := summary.WriteJSON(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error writing summary file: %v", err)
+	}
+	return f.Close()
+}
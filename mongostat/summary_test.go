@@ -0,0 +1,95 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongostat
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	"github.com/mongodb/mongo-tools/mongostat/stat_consumer/line"
+	"github.com/mongodb/mongo-tools/mongostat/status"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSummary(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("--summaryFile should require --summary", t, func() {
+		_, err := ParseOptions([]string{"--summaryFile", "summary.json"}, "", "")
+		So(err, ShouldNotBeNil)
+
+		opts, err := ParseOptions([]string{"--summary", "--summaryFile", "summary.json"}, "", "")
+		So(err, ShouldBeNil)
+		So(opts.Summary, ShouldBeTrue)
+	})
+
+	Convey("The summary should accumulate each column over a run", t, func() {
+		summary := status.NewSummary(nil)
+		config := &status.ReaderConfig{Summary: summary}
+		columns := []string{"host", "insert", "qrw", "conn", "metrics.cursor.open.total"}
+
+		// 20 samples of 1..20 queued readers, with 10 inserts a second
+		samples := []*status.ServerStatus{alertSample("a:1", 0, 0, 0, 0)}
+		for i := int64(1); i <= 20; i++ {
+			samples = append(samples, alertSample("a:1", i, i, 100+i, 10*i))
+		}
+		for i := 1; i < len(samples); i++ {
+			line.NewStatLine(samples[i-1], samples[i], columns, config)
+		}
+		line.NewStatLine(alertSample("b:1", 0, 0, 5, 0), alertSample("b:1", 1, 3, 5, 0),
+			columns, config)
+
+		hosts := summary.Hosts()
+		So(hosts, ShouldHaveLength, 2)
+		So(hosts[0].Host, ShouldEqual, "a:1")
+		So(hosts[1].Host, ShouldEqual, "b:1")
+
+		metrics := map[string]status.MetricSummary{}
+		var names []string
+		for _, m := range hosts[0].Metrics {
+			metrics[m.Metric] = m
+			names = append(names, m.Metric)
+		}
+		So(names, ShouldResemble,
+			[]string{"insert", "qr", "qw", "conn", "metrics.cursor.open.total"})
+
+		So(metrics["qr"], ShouldResemble, status.MetricSummary{
+			Metric: "qr", Samples: 20, Min: 1, Avg: 10.5, Max: 20, P95: 19,
+		})
+		So(metrics["insert"].Min, ShouldEqual, 10)
+		So(metrics["insert"].Max, ShouldEqual, 10)
+		So(metrics["conn"].Avg, ShouldEqual, 110.5)
+		So(metrics["qw"].Max, ShouldEqual, 0)
+
+		Convey("and be written as a table and as JSON", func() {
+			var out bytes.Buffer
+			file := filepath.Join(t.TempDir(), "summary.json")
+			So(WriteSummary(summary, &out, false, file), ShouldBeNil)
+			text := out.String()
+			So(text, ShouldContainSubstring, "summary of a:1:")
+			So(text, ShouldContainSubstring, "summary of b:1:")
+			So(strings.Index(text, "a:1"), ShouldBeLessThan, strings.Index(text, "b:1"))
+
+			content, err := os.ReadFile(file)
+			So(err, ShouldBeNil)
+			var doc struct {
+				Hosts []status.HostSummary `json:"hosts"`
+			}
+			So(json.Unmarshal(content, &doc), ShouldBeNil)
+			So(doc.Hosts, ShouldResemble, hosts)
+
+			out.Reset()
+			So(WriteSummary(summary, &out, true, ""), ShouldBeNil)
+			So(strings.TrimSpace(out.String()), ShouldEqual, strings.TrimSpace(string(content)))
+		})
+	})
+}
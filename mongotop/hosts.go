@@ -0,0 +1,324 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongotop
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/db"
+	"github.com/mongodb/mongo-tools/common/log"
+	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/tui"
+)
+
+// MultiTop runs mongotop against several servers at once. Each server is
+// polled over its own direct connection, and the activity of all of them is
+// printed together, either grouped by host or merged by namespace.
+type MultiTop struct {
+	// Generic mongo tool options, shared by the connections to every host
+	Options *options.ToolOptions
+
+	// Mongotop-specific output options
+	OutputOptions *Output
+
+	// Length of time to sleep between each polling.
+	Sleeptime time.Duration
+
+	// Screen shows the samples in the terminal UI of --watch, if set.
+	Screen *tui.Screen
+
+	// monitored hosts, in the order they were added
+	hosts []string
+	tops  map[string]*MongoTop
+}
+
+// AddHost starts monitoring the server at host, which must not be a mongos.
+// Hosts that are already monitored are ignored.
+func (multi *MultiTop) AddHost(host string) error {
+	if _, ok := multi.tops[host]; ok {
+		return nil
+	}
+	optsCopy := *multi.Options
+	optsCopy.Direct = true
+	optsCopy.ReplicaSetName = ""
+	if multi.Options.URI != nil && multi.Options.URI.ConnString != nil {
+		cs := *multi.Options.URI.ConnString
+		cs.Hosts = []string{host}
+		uriCopy := *multi.Options.URI
+		uriCopy.ConnString = &cs
+		if u, err := url.Parse(uriCopy.ConnectionString); err == nil {
+			u.Host = host
+			uriCopy.ConnectionString = u.String()
+		}
+		optsCopy.URI = &uriCopy
+		optsCopy.ConnString = &cs
+	}
+
+	sessionProvider, err := db.NewSessionProvider(optsCopy)
+	if err != nil {
+		return fmt.Errorf("error connecting to %v: %v", host, err)
+	}
+	isMongos, err := sessionProvider.IsMongos()
+	if err != nil {
+		sessionProvider.Close()
+		return fmt.Errorf("error connecting to %v: %v", host, err)
+	}
+	if isMongos {
+		sessionProvider.Close()
+		return fmt.Errorf("cannot run mongotop against a mongos: %v", host)
+	}
+
+	if multi.tops == nil {
+		multi.tops = map[string]*MongoTop{}
+	}
+	multi.tops[host] = &MongoTop{
+		Options:         &optsCopy,
+		OutputOptions:   multi.OutputOptions,
+		SessionProvider: sessionProvider,
+		Sleeptime:       multi.Sleeptime,
+	}
+	multi.hosts = append(multi.hosts, host)
+	log.Logvf(log.DebugLow, "monitoring %v", host)
+	return nil
+}
+
+// DiscoverHosts adds the members of the replica sets of the monitored hosts
+// that can be read from, i.e. all but arbiters.
+func (multi *MultiTop) DiscoverHosts() error {
+	for _, host := range append([]string(nil), multi.hosts...) {
+		var hello struct {
+			Hosts    []string `bson:"hosts"`
+			Passives []string `bson:"passives"`
+		}
+		err := multi.tops[host].SessionProvider.RunString("ismaster", &hello, "admin")
+		if err != nil {
+			return fmt.Errorf("error discovering the members of the replica set of %v: %v", host, err)
+		}
+		for _, member := range append(hello.Hosts, hello.Passives...) {
+			if _, ok := multi.tops[member]; ok {
+				continue
+			}
+			log.Logvf(log.Info, "discovered %v from %v", member, host)
+			if err := multi.AddHost(member); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Hosts returns the monitored hosts, in the order they were added.
+func (multi *MultiTop) Hosts() []string {
+	return multi.hosts
+}
+
+// Close closes the connections to all the hosts.
+func (multi *MultiTop) Close() {
+	for _, top := range multi.tops {
+		top.SessionProvider.Close()
+	}
+}
+
+// poll takes a sample from every host at once.
+func (multi *MultiTop) poll() *MultiHostDiff {
+	diff := &MultiHostDiff{
+		Hosts:  multi.hosts,
+		Diffs:  make([]FormattableDiff, len(multi.hosts)),
+		Errors: make([]error, len(multi.hosts)),
+		Merge:  multi.OutputOptions.MergeNamespaces,
+	}
+	var wg sync.WaitGroup
+	for i, host := range multi.hosts {
+		wg.Add(1)
+		go func(i int, top *MongoTop) {
+			defer wg.Done()
+			diff.Diffs[i], diff.Errors[i] = top.runDiff()
+		}(i, multi.tops[host])
+	}
+	wg.Wait()
+	return diff
+}
+
+// Run executes mongotop against all the hosts until --rowcount samples have
+// been printed. It fails if any host cannot be polled the first time; later
+// errors are shown for their host.
+func (multi *MultiTop) Run() error {
+	hasData := false
+	numPrinted := 0
+
+	for {
+		if multi.OutputOptions.RowCount > 0 && numPrinted > multi.OutputOptions.RowCount {
+			return nil
+		}
+		numPrinted++
+		diff := multi.poll()
+		if !hasData {
+			for i, err := range diff.Errors {
+				if err != nil {
+					return fmt.Errorf("%v: %v", diff.Hosts[i], err)
+				}
+			}
+			if !multi.OutputOptions.Json && multi.Screen == nil {
+				log.Logvf(log.Always, "connected to: %v\n", multi.hosts)
+			}
+		}
+		hasData = true
+
+		if diff.hasData() {
+			if multi.Screen != nil {
+				multi.Screen.Show(diff.watchTable(multi.OutputOptions.Locks))
+			} else if multi.OutputOptions.Json {
+				fmt.Println(diff.JSON())
+			} else {
+				fmt.Println(diff.Grid())
+			}
+		}
+		if !sleep(multi.Sleeptime, multi.Screen) {
+			return nil
+		}
+	}
+}
+
+// MultiHostDiff holds a sample of each of several hosts. Diffs and Errors are
+// indexed like Hosts; the diff of a host is nil when it has no sample yet.
+type MultiHostDiff struct {
+	Hosts  []string
+	Diffs  []FormattableDiff
+	Errors []error
+
+	// Merge selects one view of all the namespaces, with the activity of
+	// every host added up, instead of one view per host.
+	Merge bool
+}
+
+func (mhd *MultiHostDiff) hasData() bool {
+	for i := range mhd.Hosts {
+		if mhd.Diffs[i] != nil || mhd.Errors[i] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// merged adds up the activity of each namespace over all the hosts that have
+// a sample.
+func (mhd *MultiHostDiff) merged() FormattableDiff {
+	var top *TopDiff
+	var locks *ServerStatusDiff
+	for _, diff := range mhd.Diffs {
+		switch diff := diff.(type) {
+		case TopDiff:
+			if top == nil {
+				top = &TopDiff{Totals: map[string]NSTopInfo{}, Time: diff.Time}
+			}
+			for ns, info := range diff.Totals {
+				sum := top.Totals[ns]
+				sum.Total = addTopFields(sum.Total, info.Total)
+				sum.Read = addTopFields(sum.Read, info.Read)
+				sum.Write = addTopFields(sum.Write, info.Write)
+				top.Totals[ns] = sum
+			}
+		case ServerStatusDiff:
+			if locks == nil {
+				locks = &ServerStatusDiff{Totals: map[string]LockDelta{}, Time: diff.Time}
+			}
+			for ns, delta := range diff.Totals {
+				sum := locks.Totals[ns]
+				sum.Read += delta.Read
+				sum.Write += delta.Write
+				locks.Totals[ns] = sum
+			}
+		}
+	}
+	switch {
+	case top != nil:
+		return *top
+	case locks != nil:
+		return *locks
+	}
+	return nil
+}
+
+func addTopFields(a, b TopField) TopField {
+	return TopField{Time: a.Time + b.Time, Count: a.Count + b.Count}
+}
+
+// errorLines lists the hosts that could not be polled, sorted.
+func (mhd *MultiHostDiff) errorLines() []string {
+	var lines []string
+	for i, err := range mhd.Errors {
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("%v: error: %v", mhd.Hosts[i], err))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// Grid returns a table per host, or a single table of the merged activity
+// of all the hosts.
+func (mhd *MultiHostDiff) Grid() string {
+	buf := &bytes.Buffer{}
+	if mhd.Merge {
+		fmt.Fprintf(buf, "%v hosts merged\n", len(mhd.Hosts))
+		for _, line := range mhd.errorLines() {
+			fmt.Fprintln(buf, line)
+		}
+		if merged := mhd.merged(); merged != nil {
+			buf.WriteString(merged.Grid())
+		}
+		return buf.String()
+	}
+	for i, host := range mhd.Hosts {
+		switch {
+		case mhd.Errors[i] != nil:
+			fmt.Fprintf(buf, "%v: error: %v\n\n", host, mhd.Errors[i])
+		case mhd.Diffs[i] != nil:
+			fmt.Fprintf(buf, "%v\n%v\n", host, mhd.Diffs[i].Grid())
+		}
+	}
+	return buf.String()
+}
+
+// JSON returns a JSON representation of the samples of each host, or of the
+// merged activity of all the hosts, with the errors of the hosts that could
+// not be polled.
+func (mhd *MultiHostDiff) JSON() string {
+	out := struct {
+		Hosts  map[string]FormattableDiff `json:"hosts,omitempty"`
+		Merged FormattableDiff            `json:"merged,omitempty"`
+		Errors map[string]string          `json:"errors,omitempty"`
+		Time   time.Time                  `json:"time"`
+	}{Time: time.Now()}
+	for i, host := range mhd.Hosts {
+		if mhd.Errors[i] != nil {
+			if out.Errors == nil {
+				out.Errors = map[string]string{}
+			}
+			out.Errors[host] = mhd.Errors[i].Error()
+		} else if mhd.Diffs[i] != nil && !mhd.Merge {
+			if out.Hosts == nil {
+				out.Hosts = map[string]FormattableDiff{}
+			}
+			out.Hosts[host] = mhd.Diffs[i]
+		}
+	}
+	if mhd.Merge {
+		out.Merged = mhd.merged()
+	}
+	bytes, err := json.Marshal(out)
+	if err != nil {
+		panic(err)
+	}
+	return string(bytes)
+}
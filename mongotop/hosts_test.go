@@ -0,0 +1,138 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongotop
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/testtype"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func testTopInfo(total, read, write int) NSTopInfo {
+	return NSTopInfo{
+		Total: TopField{Time: total, Count: 1},
+		Read:  TopField{Time: read, Count: 1},
+		Write: TopField{Time: write},
+	}
+}
+
+func TestMultiHostDiff(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("With samples of several hosts", t, func() {
+		diff := &MultiHostDiff{
+			Hosts: []string{"a:27017", "b:27017", "c:27017", "d:27017"},
+			Diffs: []FormattableDiff{
+				TopDiff{Totals: map[string]NSTopInfo{
+					"test.x": testTopInfo(10, 4, 6),
+					"test.y": testTopInfo(3, 3, 0),
+				}, Time: time.Now()},
+				TopDiff{Totals: map[string]NSTopInfo{
+					"test.x": testTopInfo(5, 5, 0),
+				}, Time: time.Now()},
+				nil,
+				nil,
+			},
+			Errors: []error{nil, nil, nil, errors.New("connection refused")},
+		}
+
+		Convey("the grid groups the namespaces by host", func() {
+			grid := diff.Grid()
+			So(grid, ShouldContainSubstring, "a:27017\n")
+			So(grid, ShouldContainSubstring, "b:27017\n")
+			So(grid, ShouldNotContainSubstring, "c:27017")
+			So(grid, ShouldContainSubstring, "d:27017: error: connection refused")
+			So(strings.Index(grid, "a:27017"), ShouldBeLessThan, strings.Index(grid, "b:27017"))
+		})
+
+		Convey("the JSON has the sample of each host", func() {
+			var out struct {
+				Hosts  map[string]TopDiff `json:"hosts"`
+				Merged *TopDiff           `json:"merged"`
+				Errors map[string]string  `json:"errors"`
+			}
+			So(json.Unmarshal([]byte(diff.JSON()), &out), ShouldBeNil)
+			So(out.Hosts, ShouldHaveLength, 2)
+			So(out.Hosts["a:27017"].Totals["test.x"].Total.Time, ShouldEqual, 10)
+			So(out.Merged, ShouldBeNil)
+			So(out.Errors, ShouldResemble, map[string]string{"d:27017": "connection refused"})
+		})
+
+		Convey("merging adds up the activity of each namespace", func() {
+			diff.Merge = true
+			merged := diff.merged().(TopDiff)
+			So(merged.Totals, ShouldResemble, map[string]NSTopInfo{
+				"test.x": {
+					Total: TopField{Time: 15, Count: 2},
+					Read:  TopField{Time: 9, Count: 2},
+					Write: TopField{Time: 6},
+				},
+				"test.y": testTopInfo(3, 3, 0),
+			})
+
+			grid := diff.Grid()
+			So(grid, ShouldStartWith, "4 hosts merged\n")
+			So(grid, ShouldContainSubstring, "15ms")
+			So(grid, ShouldNotContainSubstring, "a:27017")
+
+			var out struct {
+				Hosts  map[string]TopDiff `json:"hosts"`
+				Merged TopDiff            `json:"merged"`
+			}
+			So(json.Unmarshal([]byte(diff.JSON()), &out), ShouldBeNil)
+			So(out.Hosts, ShouldBeEmpty)
+			So(out.Merged.Totals["test.x"].Total.Time, ShouldEqual, 15)
+		})
+
+		Convey("lock samples are merged by database", func() {
+			diff.Diffs = []FormattableDiff{
+				ServerStatusDiff{Totals: map[string]LockDelta{"test": {Read: 1, Write: 2}}},
+				ServerStatusDiff{Totals: map[string]LockDelta{"test": {Read: 3}, "admin": {}}},
+				nil,
+				nil,
+			}
+			merged := diff.merged().(ServerStatusDiff)
+			So(merged.Totals, ShouldResemble, map[string]LockDelta{
+				"test":  {Read: 4, Write: 2},
+				"admin": {},
+			})
+		})
+
+		Convey("the watch table has a row per namespace of each host", func() {
+			table := diff.watchTable(false)
+			So(table.Columns, ShouldResemble, []string{"host", "ns", "total", "read", "write"})
+			So(table.FilterColumn, ShouldEqual, 1)
+			So(table.Rows, ShouldHaveLength, 3)
+			So(table.Rows[0].Host, ShouldEqual, "a:27017")
+			So(table.Rows[0].Cells, ShouldResemble, []string{"a:27017", "test.x", "10ms", "4ms", "6ms"})
+			So(table.Rows[1].Cells[1], ShouldEqual, "test.y")
+			So(table.Rows[2].Host, ShouldEqual, "b:27017")
+			So(table.Errors, ShouldResemble, []string{"d:27017: error: connection refused"})
+
+			diff.Merge = true
+			table = diff.watchTable(false)
+			So(table.Columns, ShouldResemble, []string{"ns", "total", "read", "write"})
+			So(table.Rows, ShouldHaveLength, 2)
+			So(table.Rows[0].Cells, ShouldResemble, []string{"test.x", "15ms", "9ms", "6ms"})
+		})
+
+		Convey("there is nothing to show before the second sample", func() {
+			first := &MultiHostDiff{
+				Hosts:  []string{"a:27017"},
+				Diffs:  []FormattableDiff{nil},
+				Errors: []error{nil},
+			}
+			So(first.hasData(), ShouldBeFalse)
+			So(diff.hasData(), ShouldBeTrue)
+		})
+	})
+}
@@ -8,12 +8,15 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/mongodb/mongo-tools/common/db"
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/signals"
+	"github.com/mongodb/mongo-tools/common/telemetry"
+	"github.com/mongodb/mongo-tools/common/tui"
 	"github.com/mongodb/mongo-tools/common/util"
 	"github.com/mongodb/mongo-tools/mongotop"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -43,6 +46,12 @@ func main() {
 		return
 	}
 
+	if err := telemetry.Init(opts.AppName, opts.VersionStr, opts.OTelEndpoint); err != nil {
+		log.Logvf(log.Always, "error starting telemetry: %v", err)
+		os.Exit(util.ExitFailure)
+	}
+	defer telemetry.Shutdown()
+
 	log.SetVerbosity(opts.Verbosity)
 	signals.Handle()
 
@@ -51,7 +60,7 @@ func main() {
 
 	if opts.RowCount < 0 {
 		log.Logvf(log.Always, "invalid value for --rowcount: %v", opts.RowCount)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 
 	if opts.Auth.Username != "" && opts.Auth.Source == "" && !opts.Auth.RequiresExternalDB() {
@@ -60,13 +69,28 @@ func main() {
 				log.Always,
 				"authSource is required when authenticating against a non $external database",
 			)
-			os.Exit(util.ExitFailure)
+			telemetry.Exit(util.ExitFailure)
 		}
 		log.Logvf(
 			log.Always,
 			"--authenticationDatabase is required when authenticating against a non $external database",
 		)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
+	}
+
+	// several hosts without a replica set name, or --discover, monitor each
+	// host over its own connection
+	hosts := opts.URI.GetConnectionAddrs()
+	if opts.Discover || (len(hosts) > 1 && opts.ReplicaSetName == "") {
+		if err := runMultiTop(opts, hosts); err != nil {
+			log.Logvf(log.Always, "Failed: %v", err)
+			telemetry.Exit(util.ExitFailure)
+		}
+		return
+	}
+	if opts.MergeNamespaces {
+		log.Logvf(log.Always, "--mergeNamespaces requires several hosts or --discover")
+		telemetry.Exit(util.ExitFailure)
 	}
 
 	if opts.ReplicaSetName == "" {
@@ -77,18 +101,18 @@ func main() {
 	sessionProvider, err := db.NewSessionProvider(*opts.ToolOptions)
 	if err != nil {
 		log.Logvf(log.Always, "error connecting to host: %v", err)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 
 	// fail fast if connecting to a mongos
 	isMongos, err := sessionProvider.IsMongos()
 	if err != nil {
 		log.Logvf(log.Always, "Failed: %v", err)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 	if isMongos {
 		log.Logvf(log.Always, "cannot run mongotop against a mongos")
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
 	}
 
 	// instantiate a mongotop instance
@@ -100,8 +124,49 @@ func main() {
 	}
 
 	// kick it off
-	if err := top.Run(); err != nil {
+	if opts.Watch {
+		if top.Screen, err = tui.NewScreen(mongotop.NewWatchView()); err != nil {
+			log.Logvf(log.Always, "error setting up terminal UI: %v", err)
+			telemetry.Exit(util.ExitFailure)
+		}
+	}
+	err = top.Run()
+	if top.Screen != nil {
+		top.Screen.Close()
+	}
+	if err != nil {
 		log.Logvf(log.Always, "Failed: %v", err)
-		os.Exit(util.ExitFailure)
+		telemetry.Exit(util.ExitFailure)
+	}
+}
+
+// runMultiTop runs mongotop against each of hosts, and with --discover the
+// other members of their replica sets.
+func runMultiTop(opts mongotop.Options, hosts []string) error {
+	top := &mongotop.MultiTop{
+		Options:       opts.ToolOptions,
+		OutputOptions: opts.Output,
+		Sleeptime:     time.Duration(opts.SleepTime) * time.Second,
+	}
+	defer top.Close()
+
+	for _, host := range hosts {
+		if err := top.AddHost(host); err != nil {
+			return err
+		}
+	}
+	if opts.Discover {
+		if err := top.DiscoverHosts(); err != nil {
+			return err
+		}
+	}
+	if opts.Watch {
+		screen, err := tui.NewScreen(mongotop.NewWatchView())
+		if err != nil {
+			return fmt.Errorf("error setting up terminal UI: %v", err)
+		}
+		defer screen.Close()
+		top.Screen = screen
 	}
+	return top.Run()
 }
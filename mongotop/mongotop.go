@@ -14,6 +14,7 @@ import (
 	"github.com/mongodb/mongo-tools/common/db"
 	"github.com/mongodb/mongo-tools/common/log"
 	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/tui"
 	"github.com/mongodb/mongo-tools/common/util"
 	"go.mongodb.org/mongo-driver/bson"
 )
@@ -33,6 +34,9 @@ type MongoTop struct {
 	// Length of time to sleep between each polling.
 	Sleeptime time.Duration
 
+	// Screen shows the samples in the terminal UI of --watch, if set.
+	Screen *tui.Screen
+
 	previousServerStatus *ServerStatus
 	previousTop          *Top
 }
@@ -132,13 +136,19 @@ func (mt *MongoTop) Run() error {
 				return err
 			}
 
-			log.Logvf(log.Always, "Error: %v\n", err)
-			time.Sleep(mt.Sleeptime)
+			if mt.Screen != nil {
+				mt.Screen.Show(mt.watchTable(nil, err))
+			} else {
+				log.Logvf(log.Always, "Error: %v\n", err)
+			}
+			if !sleep(mt.Sleeptime, mt.Screen) {
+				return nil
+			}
 		}
 
 		// if this is the first time and the connection is successful, print
 		// the connection message
-		if !hasData && !mt.OutputOptions.Json {
+		if !hasData && !mt.OutputOptions.Json && mt.Screen == nil {
 			log.Logvf(
 				log.Always,
 				"connected to: %v\n",
@@ -149,12 +159,16 @@ func (mt *MongoTop) Run() error {
 		hasData = true
 
 		if diff != nil {
-			if mt.OutputOptions.Json {
+			if mt.Screen != nil {
+				mt.Screen.Show(mt.watchTable(diff, nil))
+			} else if mt.OutputOptions.Json {
 				fmt.Println(diff.JSON())
 			} else {
 				fmt.Println(diff.Grid())
 			}
 		}
-		time.Sleep(mt.Sleeptime)
+		if !sleep(mt.Sleeptime, mt.Screen) {
+			return nil
+		}
 	}
 }
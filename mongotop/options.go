@@ -11,6 +11,7 @@ import (
 	"strconv"
 
 	"github.com/mongodb/mongo-tools/common/options"
+	"github.com/mongodb/mongo-tools/common/tui"
 )
 
 var Usage = `<options> <connection-string> <polling interval in seconds>
@@ -32,6 +33,11 @@ type Output struct {
 	Locks    bool `long:"locks" description:"report on use of per-database locks"`
 	RowCount int  `long:"rowcount" value-name:"<count>" short:"n" description:"number of stats lines to print (0 for indefinite)"`
 	Json     bool `long:"json" description:"format output as JSON"`
+
+	Discover        bool `long:"discover" description:"also monitor the other members of the replica set of each host, showing each member's activity separately"`
+	MergeNamespaces bool `long:"mergeNamespaces" description:"when monitoring several hosts, show one view of the activity of each namespace added up over all the hosts"`
+
+	Watch bool `long:"watch" description:"show the activity of every namespace in the interactive terminal UI shared with mongostat --watch, where rows can be sorted by any column, paused, filtered by namespace, and drilled down into to show only the namespaces of one host"`
 }
 
 // Name returns a human-readable group name for output options.
@@ -48,11 +54,20 @@ func ParseOptions(rawArgs []string, versionStr, gitCommit string) (Options, erro
 	outputOpts := &Output{}
 	opts.AddOptions(outputOpts)
 
+	if !tui.Supported {
+		// make --watch inaccessible
+		opts.FindOptionByLongName("watch").LongName = ""
+	}
+
 	extraArgs, err := opts.ParseArgs(rawArgs)
 	if err != nil {
 		return Options{}, err
 	}
 
+	if outputOpts.Watch && outputOpts.Json {
+		return Options{}, fmt.Errorf("cannot use --watch with --json")
+	}
+
 	if len(extraArgs) > 1 {
 		return Options{}, fmt.Errorf("error parsing positional arguments: " +
 			"provide only one polling interval in seconds and only one MongoDB connection string. " +
@@ -195,3 +195,15 @@ func TestPositionalArgumentParsing(t *testing.T) {
 		}
 	})
 }
+
+func TestWatchOption(t *testing.T) {
+	testtype.SkipUnlessTestType(t, testtype.UnitTestType)
+
+	Convey("--watch cannot be used with --json", t, func() {
+		opts, err := ParseOptions([]string{"--watch"}, "", "")
+		So(err, ShouldBeNil)
+		So(opts.Watch, ShouldBeTrue)
+		_, err = ParseOptions([]string{"--watch", "--json"}, "", "")
+		So(err, ShouldNotBeNil)
+	})
+}
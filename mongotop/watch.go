@@ -0,0 +1,113 @@
+// Copyright (C) MongoDB, Inc. 2025-present.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package mongotop
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mongodb/mongo-tools/common/tui"
+)
+
+// NewWatchView returns the View of the terminal UI of --watch, which starts
+// sorted by total time like the grid output.
+func NewWatchView() *tui.View {
+	return tui.NewView("total", true)
+}
+
+// watchColumns returns the columns of the terminal UI, with a host column
+// first if withHost is set.
+func watchColumns(locks, withHost bool) []string {
+	columns := []string{"ns", "total", "read", "write"}
+	if locks {
+		columns[0] = "db"
+	}
+	if withHost {
+		columns = append([]string{"host"}, columns...)
+	}
+	return columns
+}
+
+// watchRows returns a row of the terminal UI for each namespace of diff,
+// with the host as the first cell if withHost is set. Unlike the grid
+// output, every namespace is included, since the UI can sort and filter
+// them.
+func watchRows(diff FormattableDiff, host string, withHost bool) []tui.Row {
+	var rows []tui.Row
+	add := func(ns string, total, read, write int64) {
+		cells := []string{
+			ns,
+			fmt.Sprintf("%vms", total),
+			fmt.Sprintf("%vms", read),
+			fmt.Sprintf("%vms", write),
+		}
+		if withHost {
+			cells = append([]string{host}, cells...)
+		}
+		rows = append(rows, tui.Row{Host: host, Cells: cells})
+	}
+	switch diff := diff.(type) {
+	case TopDiff:
+		for ns, info := range diff.Totals {
+			add(ns, int64(info.Total.Time), int64(info.Read.Time), int64(info.Write.Time))
+		}
+	case ServerStatusDiff:
+		for ns, delta := range diff.Totals {
+			add(ns, delta.Read+delta.Write, delta.Read, delta.Write)
+		}
+	}
+	nsIndex := 0
+	if withHost {
+		nsIndex = 1
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Cells[nsIndex] < rows[j].Cells[nsIndex] })
+	return rows
+}
+
+// watchTable returns a sample of a single host for the terminal UI.
+func (mt *MongoTop) watchTable(diff FormattableDiff, err error) tui.Table {
+	table := tui.Table{Columns: watchColumns(mt.OutputOptions.Locks, false)}
+	if err != nil {
+		table.Errors = []string{fmt.Sprintf("error: %v", err)}
+	}
+	table.Rows = watchRows(diff, "", false)
+	return table
+}
+
+// watchTable returns the samples of every host for the terminal UI, with
+// the namespaces of each host as separate rows that can be drilled down
+// into, or merged with --mergeNamespaces.
+func (mhd *MultiHostDiff) watchTable(locks bool) tui.Table {
+	table := tui.Table{Columns: watchColumns(locks, !mhd.Merge), Errors: mhd.errorLines()}
+	if mhd.Merge {
+		table.Rows = watchRows(mhd.merged(), "", false)
+		return table
+	}
+	table.FilterColumn = 1
+	for i, host := range mhd.Hosts {
+		if mhd.Diffs[i] != nil {
+			table.Rows = append(table.Rows, watchRows(mhd.Diffs[i], host, true)...)
+		}
+	}
+	return table
+}
+
+// sleep waits for d, and returns false early if the user quits the terminal
+// UI.
+func sleep(d time.Duration, screen *tui.Screen) bool {
+	if screen == nil {
+		time.Sleep(d)
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-screen.Quit():
+		return false
+	}
+}